@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditActor        string
+	auditAction       string
+	auditResourceType string
+	auditResourceID   string
+	auditSince        string
+	auditLimit        int
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "See who created, changed or downloaded what",
+	Run: func(cmd *cobra.Command, args []string) {
+		RunAudit()
+	},
+}
+
+func init() {
+	auditCmd.Flags().StringVar(&auditActor, "actor", "", "Only show events by this subject")
+	auditCmd.Flags().StringVar(&auditAction, "action", "", "Only show this action (create, update, delete, promote, download)")
+	auditCmd.Flags().StringVar(&auditResourceType, "type", "", "Only show events for this resource type (dataset, model, upload)")
+	auditCmd.Flags().StringVar(&auditResourceID, "resource", "", "Only show events for this resource ID")
+	auditCmd.Flags().StringVar(&auditSince, "since", "", "Only show events since this long ago (e.g. 24h) or this RFC 3339 time")
+	auditCmd.Flags().IntVar(&auditLimit, "limit", audit.DefaultLimit, "Maximum number of events to show")
+	rootCmd.AddCommand(auditCmd)
+}
+
+func RunAudit() {
+	query := url.Values{}
+	for key, value := range map[string]string{
+		"actor":         auditActor,
+		"action":        auditAction,
+		"resource_type": auditResourceType,
+		"resource_id":   auditResourceID,
+	} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	query.Set("limit", strconv.Itoa(auditLimit))
+
+	if auditSince != "" {
+		since, err := parseSince(auditSince)
+		if err != nil {
+			fmt.Printf("invalid --since: %s\n", err)
+			os.Exit(1)
+		}
+		query.Set("since", since.Format(time.RFC3339))
+	}
+
+	var events []*audit.Event
+	if err := doJSON(http.MethodGet, "audit", query, nil, &events); err != nil {
+		fmt.Printf("couldn't fetch audit log: %s\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TIME\tACTOR\tACTION\tRESOURCE\tIP\tREQUEST")
+	for _, e := range events {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s %.8s\t%s\t%s\n",
+			e.OccurredAt.Local().Format(time.DateTime),
+			e.Actor,
+			e.Action,
+			e.ResourceType,
+			e.ResourceID,
+			e.IP,
+			e.RequestID,
+		)
+	}
+	w.Flush()
+}
+
+/*
+parseSince accepts either a duration, meaning that long ago, or an
+absolute RFC 3339 time.
+*/
+func parseSince(s string) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
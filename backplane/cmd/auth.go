@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/spf13/cobra"
+)
+
+var authCmd = &cobra.Command{
+	Use:   "auth",
+	Short: "Inspect the stored credentials",
+}
+
+var authStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the stored tokens and when they expire",
+	Run: func(cmd *cobra.Command, args []string) {
+		RunAuthStatus()
+	},
+}
+
+func init() {
+	authCmd.AddCommand(authStatusCmd)
+	rootCmd.AddCommand(authCmd)
+}
+
+func RunAuthStatus() {
+	tc := CurrentContext()
+
+	fmt.Println("Context:    ", tc.Name)
+	if conf, err := loadStoredConfig(tc.InstanceConfigPath()); err == nil {
+		fmt.Println("Instance:   ", conf.URL)
+	}
+	fmt.Println("Credentials:", tc.TokenPath())
+
+	token, err := auth.LoadToken(tc.TokenPath())
+	if err != nil {
+		fmt.Println()
+		fmt.Println("Not logged in.")
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("Access token: ", describeExpiry(token.Expiry))
+
+	if token.RefreshToken != "" {
+		fmt.Println("Refresh token: present")
+	} else {
+		fmt.Println("Refresh token: none, you will need to log in again when the access token expires")
+	}
+
+	if raw, ok := token.Extra("id_token").(string); ok {
+		claims, err := auth.UnverifiedClaims(raw)
+		if err != nil {
+			fmt.Println("ID token:      unreadable:", err)
+			return
+		}
+		exp, _ := claims["exp"].(float64)
+		fmt.Println("ID token:     ", describeExpiry(time.Unix(int64(exp), 0)))
+		fmt.Println("Subject:      ", claims["sub"])
+	}
+}
+
+func describeExpiry(expiry time.Time) string {
+	if expiry.IsZero() {
+		return "no expiry recorded"
+	}
+
+	remaining := time.Until(expiry).Round(time.Second)
+	if remaining <= 0 {
+		return fmt.Sprintf("expired %s ago (%s)", -remaining, expiry.Local().Format(time.RFC1123))
+	}
+	return fmt.Sprintf("expires in %s (%s)", remaining, expiry.Local().Format(time.RFC1123))
+}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/heldtogether/traintrack/internal/evaluations"
+	"github.com/spf13/cobra"
+)
+
+var (
+	benchmarkMetric string
+	benchmarkOrder  string
+)
+
+var modelsBenchmarkCmd = &cobra.Command{
+	Use:   "benchmark <model>...",
+	Short: "Compare models on every dataset they've been evaluated on",
+	Long: `Compare models on every dataset they've been evaluated on, as a matrix
+with a row for each model and a column for each dataset. Each model is
+given by name, for every version of it, or by id or id prefix. The best
+score on each dataset is marked with a *.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		RunModelsBenchmark(args)
+	},
+}
+
+func init() {
+	modelsBenchmarkCmd.Flags().StringVar(&benchmarkMetric, "metric", "", "Evaluation key to compare, with dots for nested keys")
+	modelsBenchmarkCmd.Flags().StringVar(&benchmarkOrder, "order", "desc", "asc if lower is better, or desc if higher is")
+	modelsBenchmarkCmd.MarkFlagRequired("metric")
+
+	modelsCmd.AddCommand(modelsBenchmarkCmd)
+}
+
+func RunModelsBenchmark(refs []string) {
+	if benchmarkOrder != "asc" && benchmarkOrder != "desc" {
+		fmt.Printf("order must be asc or desc, got %q\n", benchmarkOrder)
+		os.Exit(1)
+	}
+
+	ms, err := FetchModels(nil)
+	if err != nil {
+		fmt.Printf("couldn't fetch models: %s\n", err)
+		os.Exit(1)
+	}
+
+	// A name brings in every version, so it's sent as is. Anything else
+	// is taken to be an id.
+	names := map[string]bool{}
+	for _, m := range ms {
+		names[m.Name] = true
+	}
+
+	var ids []string
+	var matrix *evaluations.Matrix
+	for _, ref := range refs {
+		if !names[ref] {
+			m, err := resolveModel(ms, ref)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			ids = append(ids, m.ID)
+			continue
+		}
+
+		// The API takes one name at a time, so each is fetched on its own
+		// and the matrices merged.
+		named, err := FetchMatrix(nil, ref)
+		if err != nil {
+			fmt.Printf("couldn't fetch evaluations: %s\n", err)
+			os.Exit(1)
+		}
+		matrix = mergeMatrices(matrix, named)
+	}
+	if len(ids) > 0 {
+		byID, err := FetchMatrix(ids, "")
+		if err != nil {
+			fmt.Printf("couldn't fetch evaluations: %s\n", err)
+			os.Exit(1)
+		}
+		matrix = mergeMatrices(matrix, byID)
+	}
+
+	if len(matrix.Models) == 0 {
+		fmt.Printf("no evaluations record %s\n", benchmarkMetric)
+		return
+	}
+
+	p := tea.NewProgram(newBenchmarkModel(matrix, benchmarkOrder == "asc"), tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		fmt.Println("Error running program:", err)
+		os.Exit(1)
+	}
+}
+
+func FetchMatrix(ids []string, name string) (*evaluations.Matrix, error) {
+	query := url.Values{"metric": {benchmarkMetric}}
+	if len(ids) > 0 {
+		query.Set("models", strings.Join(ids, ","))
+	}
+	if name != "" {
+		query.Set("name", name)
+	}
+
+	var m evaluations.Matrix
+	if err := doJSON(http.MethodGet, "evaluations/matrix", query, nil, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+/*
+mergeMatrices adds the models and datasets of b that aren't already in
+a, which may be nil, keeping the order of each.
+*/
+func mergeMatrices(a, b *evaluations.Matrix) *evaluations.Matrix {
+	if a == nil {
+		return b
+	}
+
+	datasets := map[string]int{}
+	for i, d := range a.Datasets {
+		datasets[d.ID] = i
+	}
+	for _, d := range b.Datasets {
+		if _, ok := datasets[d.ID]; !ok {
+			datasets[d.ID] = len(a.Datasets)
+			a.Datasets = append(a.Datasets, d)
+		}
+	}
+
+	models := map[string]bool{}
+	for _, m := range a.Models {
+		models[m.ID] = true
+	}
+
+	for i := range a.Values {
+		a.Values[i] = append(a.Values[i], make([]*float64, len(a.Datasets)-len(a.Values[i]))...)
+	}
+	for i, m := range b.Models {
+		if models[m.ID] {
+			continue
+		}
+		row := make([]*float64, len(a.Datasets))
+		for j, d := range b.Datasets {
+			row[datasets[d.ID]] = b.Values[i][j]
+		}
+		a.Models = append(a.Models, m)
+		a.Values = append(a.Values, row)
+	}
+	return a
+}
+
+type benchmarkModel struct {
+	matrix *evaluations.Matrix
+	table  table.Model
+}
+
+func newBenchmarkModel(m *evaluations.Matrix, ascending bool) benchmarkModel {
+	columns := []table.Column{
+		{Title: "MODEL", Width: 10},
+		{Title: "NAME", Width: 24},
+		{Title: "VERSION", Width: 10},
+	}
+	for _, d := range m.Datasets {
+		title := d.Name + " " + d.Version
+		columns = append(columns, table.Column{Title: title, Width: max(12, len([]rune(title)))})
+	}
+
+	// Find the best score on each dataset, to mark it.
+	best := make([]*float64, len(m.Datasets))
+	for _, row := range m.Values {
+		for j, v := range row {
+			if v != nil && (best[j] == nil || (ascending && *v < *best[j]) || (!ascending && *v > *best[j])) {
+				best[j] = v
+			}
+		}
+	}
+
+	rows := make([]table.Row, len(m.Models))
+	for i, model := range m.Models {
+		row := table.Row{fmt.Sprintf("%.8s", model.ID), model.Name, model.Version}
+		for j, v := range m.Values[i] {
+			switch {
+			case v == nil:
+				row = append(row, "-")
+			case *v == *best[j] && len(m.Models) > 1:
+				row = append(row, strconv.FormatFloat(*v, 'g', 6, 64)+" *")
+			default:
+				row = append(row, strconv.FormatFloat(*v, 'g', 6, 64))
+			}
+		}
+		rows[i] = row
+	}
+
+	return benchmarkModel{
+		matrix: m,
+		table: table.New(
+			table.WithColumns(columns),
+			table.WithRows(rows),
+			table.WithFocused(true),
+		),
+	}
+}
+
+func (m benchmarkModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m benchmarkModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		}
+
+	case tea.WindowSizeMsg:
+		// Leave room for the title and status bar, each with a blank line.
+		m.table.SetWidth(msg.Width)
+		m.table.SetHeight(max(1, msg.Height-4))
+	}
+
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m benchmarkModel) View() string {
+	title := fmt.Sprintf("%s – %d models on %d datasets", m.matrix.Metric, len(m.matrix.Models), len(m.matrix.Datasets))
+	statusBar := "[↑ up] [↓ down] [q to quit]"
+	return title + "\n\n" + m.table.View() + "\n\n" + statusBar
+}
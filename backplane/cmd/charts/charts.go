@@ -0,0 +1,208 @@
+package charts
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+Point is one point on a line chart, such as a metric's value Y at step X.
+*/
+type Point struct {
+	X float64
+	Y float64
+}
+
+/*
+Bar is one bar of a bar chart.
+*/
+type Bar struct {
+	Label string
+	Value float64
+}
+
+// partials are the block characters for eighths of a cell, used to draw
+// the end of a bar.
+var partials = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+/*
+Line draws points, which should be in X order, as a line chart width by
+height characters including its axes, and returns it line by line. Where
+several points fall in one column their mean is drawn, and columns
+without any are interpolated from their neighbours.
+*/
+func Line(points []Point, width, height int) []string {
+	if len(points) == 0 {
+		return []string{"no points"}
+	}
+
+	xmin, xmax := points[0].X, points[0].X
+	ymin, ymax := points[0].Y, points[0].Y
+	for _, p := range points {
+		xmin, xmax = math.Min(xmin, p.X), math.Max(xmax, p.X)
+		ymin, ymax = math.Min(ymin, p.Y), math.Max(ymax, p.Y)
+	}
+
+	plotHeight := height - 2
+	if plotHeight < 2 {
+		return []string{"too small to draw"}
+	}
+
+	// The middle row is labelled with the value it stands for, which is
+	// only halfway between the others when there's an odd number of rows.
+	midRow := (plotHeight - 1) / 2
+	top, bottom := format(ymax), format(ymin)
+	middle := format(ymax - float64(midRow)/float64(plotHeight-1)*(ymax-ymin))
+	labelWidth := max(len(top), len(middle), len(bottom))
+	plotWidth := width - labelWidth - 2
+	if plotWidth < 2 {
+		return []string{"too small to draw"}
+	}
+
+	columns := resample(points, xmin, xmax, plotWidth)
+
+	rows := make([]int, plotWidth)
+	for c, y := range columns {
+		rows[c] = plotHeight / 2
+		if ymax > ymin {
+			rows[c] = int(math.Round((y - ymin) / (ymax - ymin) * float64(plotHeight-1)))
+		}
+	}
+
+	grid := make([][]rune, plotHeight)
+	for r := range grid {
+		grid[r] = []rune(strings.Repeat(" ", plotWidth))
+	}
+	for c, r := range rows {
+		if c > 0 {
+			// Join each point to the last with a vertical run.
+			from, to := rows[c-1], r
+			if from > to {
+				from, to = to, from
+			}
+			for between := from + 1; between < to; between++ {
+				grid[plotHeight-1-between][c] = '│'
+			}
+		}
+		grid[plotHeight-1-r][c] = '•'
+	}
+
+	lines := make([]string, 0, height)
+	for r, row := range grid {
+		label, axis := "", " │"
+		switch {
+		case r == 0:
+			label, axis = top, " ┤"
+		case r == plotHeight-1:
+			label, axis = bottom, " ┤"
+		case r == midRow && plotHeight > 2:
+			label, axis = middle, " ┤"
+		}
+		lines = append(lines, pad(label, labelWidth)+axis+string(row))
+	}
+
+	lines = append(lines, strings.Repeat(" ", labelWidth)+" └"+strings.Repeat("─", plotWidth))
+
+	first, last := format(xmin), format(xmax)
+	gap := plotWidth - len(first) - len(last)
+	if xmax == xmin || gap < 1 {
+		lines = append(lines, strings.Repeat(" ", labelWidth+2)+first)
+	} else {
+		lines = append(lines, strings.Repeat(" ", labelWidth+2)+first+strings.Repeat(" ", gap)+last)
+	}
+
+	return lines
+}
+
+/*
+resample returns the value of points in each of width columns spanning
+xmin to xmax.
+*/
+func resample(points []Point, xmin, xmax float64, width int) []float64 {
+	sums := make([]float64, width)
+	counts := make([]int, width)
+	for _, p := range points {
+		c := 0
+		if xmax > xmin {
+			c = int(math.Round((p.X - xmin) / (xmax - xmin) * float64(width-1)))
+		}
+		sums[c] += p.Y
+		counts[c]++
+	}
+
+	columns := make([]float64, width)
+	known := -1
+	for c := range columns {
+		if counts[c] == 0 {
+			continue
+		}
+		columns[c] = sums[c] / float64(counts[c])
+		if known >= 0 {
+			for between := known + 1; between < c; between++ {
+				t := float64(between-known) / float64(c-known)
+				columns[between] = columns[known] + t*(columns[c]-columns[known])
+			}
+		}
+		known = c
+	}
+
+	// With a single x, or after the last point, carry the last value on.
+	for c := known + 1; c < width && known >= 0; c++ {
+		columns[c] = columns[known]
+	}
+	return columns
+}
+
+/*
+Bars draws bars as a horizontal bar chart width characters wide, with
+each bar's label before it and its value after. Bars start from zero, or
+from the smallest value if any are negative.
+*/
+func Bars(bars []Bar, width int) []string {
+	if len(bars) == 0 {
+		return []string{"no values"}
+	}
+
+	labelWidth, valueWidth := 0, 0
+	lo, hi := 0.0, 0.0
+	values := make([]string, len(bars))
+	for i, b := range bars {
+		values[i] = format(b.Value)
+		labelWidth = max(labelWidth, len([]rune(b.Label)))
+		valueWidth = max(valueWidth, len(values[i]))
+		lo, hi = math.Min(lo, b.Value), math.Max(hi, b.Value)
+	}
+
+	barWidth := max(1, width-labelWidth-valueWidth-2)
+
+	lines := make([]string, len(bars))
+	for i, b := range bars {
+		eighths := 0
+		if hi > lo {
+			eighths = int(math.Round((b.Value - lo) / (hi - lo) * float64(barWidth*8)))
+		}
+		full, part := eighths/8, eighths%8
+
+		cells := full
+		bar := strings.Repeat("█", full) + partials[part]
+		if part > 0 {
+			cells++
+		}
+
+		label := b.Label + strings.Repeat(" ", labelWidth-len([]rune(b.Label)))
+		lines[i] = label + " " + bar + strings.Repeat(" ", barWidth-cells) + " " + pad(values[i], valueWidth)
+	}
+	return lines
+}
+
+func format(v float64) string {
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
+
+/*
+pad right aligns s in width characters.
+*/
+func pad(s string, width int) string {
+	return strings.Repeat(" ", max(0, width-len([]rune(s)))) + s
+}
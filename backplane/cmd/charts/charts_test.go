@@ -0,0 +1,53 @@
+package charts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLine(t *testing.T) {
+	points := []Point{{X: 0, Y: 1}, {X: 1, Y: 0.5}, {X: 2, Y: 0.25}, {X: 4, Y: 0.2}}
+
+	expected := `     1 ┤•     
+0.7333 ┤ │    
+       │ ••   
+   0.2 ┤   •••
+       └──────
+        0    4`
+
+	out := strings.Join(Line(points, 14, 6), "\n")
+	if out != expected {
+		t.Errorf("fail: wanted\n%s\ngot\n%s\n", expected, out)
+	}
+}
+
+func TestLineSinglePoint(t *testing.T) {
+	expected := `2 ┤•••••
+2 ┤     
+  └─────
+   3`
+
+	out := strings.Join(Line([]Point{{X: 3, Y: 2}}, 8, 4), "\n")
+	if out != expected {
+		t.Errorf("fail: wanted\n%s\ngot\n%s\n", expected, out)
+	}
+}
+
+func TestBars(t *testing.T) {
+	bars := []Bar{{Label: "1.0.0", Value: 0.8}, {Label: "1.0.1", Value: 1}, {Label: "2.0.0", Value: 0.5}}
+
+	expected := `1.0.0 ████████   0.8
+1.0.1 ██████████   1
+2.0.0 █████      0.5`
+
+	out := strings.Join(Bars(bars, 20), "\n")
+	if out != expected {
+		t.Errorf("fail: wanted\n%s\ngot\n%s\n", expected, out)
+	}
+}
+
+func TestBarsEmpty(t *testing.T) {
+	if out := Bars(nil, 20); len(out) != 1 || out[0] != "no values" {
+		t.Errorf("unexpected output for no bars: %q", out)
+	}
+}
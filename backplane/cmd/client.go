@@ -0,0 +1,139 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/auth"
+	"golang.org/x/oauth2"
+)
+
+/*
+apiURL resolves p against the URL of the configured instance.
+*/
+func apiURL(p string, query url.Values) (string, error) {
+	conf, err := LoadConfig(CurrentContext())
+	if err != nil {
+		return "", err
+	}
+
+	base, err := url.Parse(conf.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL in config: %w", err)
+	}
+	base.Path = path.Join(base.Path, p)
+	if query != nil {
+		base.RawQuery = query.Encode()
+	}
+
+	return base.String(), nil
+}
+
+/*
+newAPIClient returns an http.Client which authenticates every request with
+the stored credentials, refreshing them transparently when they expire.
+*/
+func newAPIClient(ctx context.Context) (*http.Client, error) {
+	tc := CurrentContext()
+
+	conf, err := auth.LoadConfig(tc.OAuthConfigPath())
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load config: %w", err)
+	}
+
+	ts, err := auth.NewTokenSource(ctx, tc.TokenPath(), conf)
+	if err != nil {
+		return nil, err
+	}
+
+	return oauth2.NewClient(ctx, ts), nil
+}
+
+/*
+doJSON sends body, if any, as JSON to the backplane and decodes the JSON
+response into out, if given. Error responses are turned into errors
+carrying the server's reason.
+*/
+func doJSON(method string, p string, query url.Values, body any, out any) error {
+	ctx := context.Background()
+
+	u, err := apiURL(p, query)
+	if err != nil {
+		return err
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	client, err := newAPIClient(ctx)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// Surface token problems directly rather than wrapped in the
+		// request URL, as the fix is always the same.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) && errors.Is(err, auth.ErrLoginRequired) {
+			return urlErr.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+/*
+checkResponse returns an error for any non-2xx response. Errors from the
+backplane are returned as *internal.Error.
+*/
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return auth.ErrLoginRequired
+	}
+
+	apiErr := &internal.Error{Code: resp.StatusCode}
+	if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil || apiErr.Reason == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+		apiErr.Reason = ""
+	}
+	// Trust the status line over whatever the body claimed.
+	apiErr.Code = resp.StatusCode
+
+	return apiErr
+}
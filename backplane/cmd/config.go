@@ -2,6 +2,8 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
@@ -14,16 +16,17 @@ import (
 	"github.com/heldtogether/traintrack/internal/auth"
 )
 
-var (
-	DefaultConfigPath = filepath.Join(os.Getenv("HOME"), ".traintrack", "instance-config.json")
-)
-
 type InstanceConfig struct {
 	URL         string    `json:"url"`
 	LastFetched time.Time `json:"last_fetched"`
 }
 
-func (c *InstanceConfig) refreshAuthConfig() *InstanceConfig {
+/*
+refreshAuthConfig fetches the OAuth client config advertised by the
+instance, at most every few hours, and stores it alongside the instance
+config in tc.
+*/
+func (c *InstanceConfig) refreshAuthConfig(tc *Context) *InstanceConfig {
 	if time.Since(c.LastFetched) >= 4*time.Hour {
 		base, err := url.Parse(c.URL)
 		if err != nil {
@@ -59,10 +62,10 @@ func (c *InstanceConfig) refreshAuthConfig() *InstanceConfig {
 			log.Printf("unable to refresh oauth client config: %s", err.Error())
 			return c
 		}
-		auth.SaveConfig(auth.DefaultConfigPath, &data)
+		auth.SaveConfig(tc.OAuthConfigPath(), &data)
 
 		c.LastFetched = time.Now()
-		err = SaveConfig(DefaultConfigPath, c)
+		err = SaveConfig(tc.InstanceConfigPath(), c)
 		if err != nil {
 			log.Printf("unable to refresh oauth client config: %s", err.Error())
 			return c
@@ -84,7 +87,28 @@ func SaveConfig(path string, conf *InstanceConfig) error {
 	return os.WriteFile(path, data, 0600)
 }
 
-func LoadConfig(path string) (*InstanceConfig, error) {
+/*
+LoadConfig loads the instance config for tc, refreshing the OAuth client
+config if it's stale.
+*/
+func LoadConfig(tc *Context) (*InstanceConfig, error) {
+	stored, err := loadStoredConfig(tc.InstanceConfigPath())
+	if errors.Is(err, os.ErrNotExist) && tc.Name != DefaultContextName {
+		return nil, fmt.Errorf("context %q does not exist, add it with `traintrack context add`", tc.Name)
+	}
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("no instance configured for context %q, run `traintrack set-instance <url>`", tc.Name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	config := stored.refreshAuthConfig(tc)
+
+	return config, nil
+}
+
+func loadStoredConfig(path string) (*InstanceConfig, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
@@ -95,7 +119,5 @@ func LoadConfig(path string) (*InstanceConfig, error) {
 		return nil, err
 	}
 
-	config := stored.refreshAuthConfig()
-
-	return config, nil
+	return &stored, nil
 }
@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+/*
+DefaultContextName is the context backed by the files directly under
+~/.traintrack, which is where everything lived before named contexts.
+*/
+const DefaultContextName = "default"
+
+var (
+	BaseDir          = filepath.Join(os.Getenv("HOME"), ".traintrack")
+	ContextsFilePath = filepath.Join(BaseDir, "contexts.json")
+
+	contextName     string
+	validContextRe  = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+	resolvedContext *Context
+)
+
+/*
+Context is a named traintrack instance together with its own instance
+config, OAuth client config and credentials, all stored in Dir.
+*/
+type Context struct {
+	Name string
+	Dir  string
+}
+
+func (c *Context) InstanceConfigPath() string {
+	return filepath.Join(c.Dir, "instance-config.json")
+}
+
+func (c *Context) OAuthConfigPath() string {
+	return filepath.Join(c.Dir, "oauth-client-config.json")
+}
+
+func (c *Context) TokenPath() string {
+	return filepath.Join(c.Dir, "credentials.json")
+}
+
+func (c *Context) exists() bool {
+	_, err := os.Stat(c.InstanceConfigPath())
+	return err == nil
+}
+
+type contextsFile struct {
+	Current string `json:"current"`
+}
+
+func newContext(name string) *Context {
+	if name == DefaultContextName {
+		return &Context{Name: name, Dir: BaseDir}
+	}
+	return &Context{Name: name, Dir: filepath.Join(BaseDir, "contexts", name)}
+}
+
+/*
+CurrentContext returns the context selected by, in order of precedence,
+the --context flag, the TRAINTRACK_CONTEXT environment variable, the last
+`traintrack context use`, or the default context. A name that isn't a
+valid context name ends the command, so that it can't point outside the
+contexts directory.
+*/
+func CurrentContext() *Context {
+	if resolvedContext != nil {
+		return resolvedContext
+	}
+
+	name := contextName
+	if name == "" {
+		name = os.Getenv("TRAINTRACK_CONTEXT")
+	}
+	if name == "" {
+		if stored, err := loadContextsFile(); err == nil {
+			name = stored.Current
+		}
+	}
+	if name == "" {
+		name = DefaultContextName
+	}
+	if err := validateContextName(name); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	resolvedContext = newContext(name)
+	return resolvedContext
+}
+
+/*
+ListContexts returns every context that has been configured.
+*/
+func ListContexts() ([]*Context, error) {
+	var contexts []*Context
+
+	if def := newContext(DefaultContextName); def.exists() {
+		contexts = append(contexts, def)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(BaseDir, "contexts"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if c := newContext(e.Name()); c.exists() {
+			contexts = append(contexts, c)
+		}
+	}
+
+	sort.Slice(contexts, func(i, j int) bool {
+		return contexts[i].Name < contexts[j].Name
+	})
+
+	return contexts, nil
+}
+
+func loadContextsFile() (*contextsFile, error) {
+	data, err := os.ReadFile(ContextsFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var stored contextsFile
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+
+	return &stored, nil
+}
+
+func saveContextsFile(stored *contextsFile) error {
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ContextsFilePath), 0700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(ContextsFilePath, data, 0600)
+}
+
+func validateContextName(name string) error {
+	if !validContextRe.MatchString(name) {
+		return fmt.Errorf("invalid context name %q: use letters, numbers, '-' and '_'", name)
+	}
+	return nil
+}
+
+var contextCmd = &cobra.Command{
+	Use:   "context",
+	Short: "Manage named traintrack instances, each with their own credentials",
+}
+
+var contextAddCmd = &cobra.Command{
+	Use:   "add <name> <url>",
+	Short: "Add a context for the instance at url",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return RunContextAdd(args[0], args[1])
+	},
+}
+
+var contextUseCmd = &cobra.Command{
+	Use:   "use <name>",
+	Short: "Make name the current context",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return RunContextUse(args[0])
+	},
+}
+
+var contextListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured contexts",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return RunContextList()
+	},
+}
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&contextName, "context", "", "Context to use for this command (overrides TRAINTRACK_CONTEXT)")
+
+	contextCmd.AddCommand(contextAddCmd)
+	contextCmd.AddCommand(contextUseCmd)
+	contextCmd.AddCommand(contextListCmd)
+	rootCmd.AddCommand(contextCmd)
+}
+
+func RunContextAdd(name string, url string) error {
+	if err := validateContextName(name); err != nil {
+		return err
+	}
+
+	c := newContext(name)
+	if c.exists() {
+		return fmt.Errorf("context %q already exists", name)
+	}
+
+	fmt.Printf("Adding context %s for %s\n", name, url)
+	conf := &InstanceConfig{
+		URL: url,
+	}
+	if err := SaveConfig(c.InstanceConfigPath(), conf); err != nil {
+		return err
+	}
+	conf.refreshAuthConfig(c)
+
+	fmt.Printf("Run `traintrack context use %s` and `traintrack login` to start using it\n", name)
+	return nil
+}
+
+func RunContextUse(name string) error {
+	if err := validateContextName(name); err != nil {
+		return err
+	}
+
+	c := newContext(name)
+	if !c.exists() {
+		return fmt.Errorf("context %q does not exist, add it with `traintrack context add`", name)
+	}
+
+	if err := saveContextsFile(&contextsFile{Current: name}); err != nil {
+		return err
+	}
+
+	fmt.Printf("Switched to context %s\n", name)
+	return nil
+}
+
+func RunContextList() error {
+	contexts, err := ListContexts()
+	if err != nil {
+		return err
+	}
+
+	current := CurrentContext()
+	for _, c := range contexts {
+		marker := " "
+		if c.Name == current.Name {
+			marker = "*"
+		}
+
+		url := ""
+		if conf, err := loadStoredConfig(c.InstanceConfigPath()); err == nil {
+			url = conf.URL
+		}
+
+		loggedIn := ""
+		if _, err := os.Stat(c.TokenPath()); err == nil {
+			loggedIn = " (logged in)"
+		}
+
+		fmt.Printf("%s %s\t%s%s\n", marker, c.Name, url, loggedIn)
+	}
+	return nil
+}
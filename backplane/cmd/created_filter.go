@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+/*
+createdFilter holds the --created-by, --since and --until flags shared by
+the datasets and models commands.
+*/
+type createdFilter struct {
+	by    string
+	since string
+	until string
+}
+
+func (f *createdFilter) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.by, "created-by", "", "Only show entries created by this subject")
+	cmd.Flags().StringVar(&f.since, "since", "", "Only show entries created since this long ago (e.g. 72h) or this RFC 3339 time")
+	cmd.Flags().StringVar(&f.until, "until", "", "Only show entries created before this long ago or this RFC 3339 time")
+}
+
+/*
+query converts the flags into the list query parameters understood by the
+backplane.
+*/
+func (f createdFilter) query() (url.Values, error) {
+	query := url.Values{}
+	if f.by != "" {
+		query.Set("created_by", f.by)
+	}
+	for _, bound := range []struct{ flag, value, param string }{
+		{"since", f.since, "created_after"},
+		{"until", f.until, "created_before"},
+	} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := parseSince(bound.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --%s: %s", bound.flag, err)
+		}
+		query.Set(bound.param, t.Format(time.RFC3339))
+	}
+	return query, nil
+}
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/heldtogether/traintrack/internal/datasets"
+	"github.com/heldtogether/traintrack/internal/diff"
+	"github.com/spf13/cobra"
+)
+
+var datasetsDiffNoColor bool
+
+var datasetsDiffCmd = &cobra.Command{
+	Use:   "diff <from> <to>",
+	Short: "Show what changed between two dataset versions",
+	Long: `Show what changed between two dataset versions: the artefacts added,
+removed and changed, their sizes and row counts, and for CSV and Parquet
+artefacts the columns and a sample of the rows which changed. Either may
+be an id, a prefix of one or name:version, such as house_prices:1.0.0.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		RunDatasetsDiff(args[0], args[1])
+	},
+}
+
+func init() {
+	datasetsDiffCmd.Flags().BoolVar(&datasetsDiffNoColor, "no-color", false, "Don't colour the diff")
+	datasetsCmd.AddCommand(datasetsDiffCmd)
+}
+
+func RunDatasetsDiff(from, to string) {
+	ds, err := FetchDatasets(nil)
+	if err != nil {
+		fmt.Printf("couldn't fetch datasets: %s\n", err)
+		os.Exit(1)
+	}
+
+	ids := make([]string, 2)
+	for i, ref := range []string{from, to} {
+		d, err := resolveDatasetVersion(ds, ref)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		ids[i] = d.ID
+	}
+
+	d, err := FetchDatasetDiff(ids[0], ids[1])
+	if err != nil {
+		fmt.Printf("couldn't diff datasets: %s\n", err)
+		os.Exit(1)
+	}
+
+	color := !datasetsDiffNoColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+	for _, line := range renderDatasetDiff(d, color) {
+		fmt.Println(line)
+	}
+}
+
+func FetchDatasetDiff(from, to string) (*diff.Diff, error) {
+	var d diff.Diff
+	query := url.Values{"from": {from}, "to": {to}}
+	if err := doJSON(http.MethodGet, "datasets/diff", query, nil, &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+/*
+resolveDatasetVersion finds the dataset given as name:version, or else
+by its id or a prefix of it.
+*/
+func resolveDatasetVersion(ds []*datasets.Dataset, ref string) (*datasets.Dataset, error) {
+	if name, version, ok := strings.Cut(ref, ":"); ok {
+		for _, d := range ds {
+			if d.Name == name && d.Version == version {
+				return d, nil
+			}
+		}
+		return nil, fmt.Errorf("no dataset is named %s with version %s", name, version)
+	}
+	return resolveDataset(ds, ref)
+}
+
+/*
+renderDatasetDiff draws the changes from one dataset version to another,
+artefact by artefact.
+*/
+func renderDatasetDiff(d *diff.Diff, color bool) []string {
+	paint := func(code, s string) string {
+		if !color {
+			return s
+		}
+		return code + s + ansiReset
+	}
+
+	lines := []string{
+		paint(ansiRed, fmt.Sprintf("--- %s %s (%.8s)", d.From.Name, d.From.Version, d.From.ID)),
+		paint(ansiGreen, fmt.Sprintf("+++ %s %s (%.8s)", d.To.Name, d.To.Version, d.To.ID)),
+	}
+	if len(d.Artefacts) == 0 {
+		return append(lines, "", "  (no artefacts)")
+	}
+
+	for _, a := range d.Artefacts {
+		lines = append(lines, "", paint(ansiBold, fmt.Sprintf("%s (%s)", a.Name, a.Status)))
+
+		switch a.Status {
+		case diff.StatusAdded:
+			lines = append(lines, paint(ansiGreen, "+ "+describeFile(a.To)))
+		case diff.StatusRemoved:
+			lines = append(lines, paint(ansiRed, "- "+describeFile(a.From)))
+		case diff.StatusUnchanged:
+			lines = append(lines, "  "+describeFile(a.To))
+		case diff.StatusChanged:
+			lines = append(lines, paint(ansiRed, "- "+describeFile(a.From)), paint(ansiGreen, "+ "+describeFile(a.To)))
+		}
+		if a.Problem != "" {
+			lines = append(lines, "  couldn't compare contents: "+a.Problem)
+		}
+
+		if s := a.Schema; s != nil {
+			lines = append(lines, "  columns")
+			if len(s.Added)+len(s.Removed)+len(s.Changed) == 0 {
+				lines = append(lines, "    (no differences)")
+			}
+			for _, c := range s.Removed {
+				lines = append(lines, paint(ansiRed, fmt.Sprintf("  - %s %s", c.Name, c.Type)))
+			}
+			for _, c := range s.Added {
+				lines = append(lines, paint(ansiGreen, fmt.Sprintf("  + %s %s", c.Name, c.Type)))
+			}
+			for _, c := range s.Changed {
+				lines = append(lines, paint(ansiRed, fmt.Sprintf("  - %s %s", c.Name, c.From)), paint(ansiGreen, fmt.Sprintf("  + %s %s", c.Name, c.To)))
+			}
+		}
+
+		if r := a.Rows; r != nil {
+			sampled := "all rows"
+			if r.SampleRate > 1 {
+				sampled = fmt.Sprintf("1 in %d rows", r.SampleRate)
+			}
+			lines = append(lines, fmt.Sprintf("  rows on %s (%s): %d removed, %d added", strings.Join(r.Columns, ", "), sampled, r.Removed, r.Added))
+			for _, row := range r.RemovedRows {
+				lines = append(lines, paint(ansiRed, "  - "+strings.Join(row, ",")))
+			}
+			if more := r.Removed - len(r.RemovedRows); more > 0 {
+				lines = append(lines, fmt.Sprintf("    … %d more removed", more))
+			}
+			for _, row := range r.AddedRows {
+				lines = append(lines, paint(ansiGreen, "  + "+strings.Join(row, ",")))
+			}
+			if more := r.Added - len(r.AddedRows); more > 0 {
+				lines = append(lines, fmt.Sprintf("    … %d more added", more))
+			}
+		}
+	}
+	return lines
+}
+
+/*
+describeFile gives an artefact's file name, size and, if they were
+counted, rows.
+*/
+func describeFile(f *diff.File) string {
+	s := fmt.Sprintf("%s, %s", f.FileName, byteSize(f.Size))
+	if f.Rows != nil {
+		s += fmt.Sprintf(", %d rows", *f.Rows)
+	}
+	return s
+}
+
+func byteSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
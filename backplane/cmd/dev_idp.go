@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/heldtogether/traintrack/internal/devidp"
+	"github.com/spf13/cobra"
+)
+
+var (
+	devIdPAddr   string
+	devIdPIssuer string
+	devIdPConfig string
+)
+
+var devIdPCmd = &cobra.Command{
+	Use:   "dev-idp",
+	Short: "Run a local OpenID Connect provider for development and testing",
+	Long: `Run a local OpenID Connect provider for development and testing.
+
+Every authorization request is approved without a password, so this must
+never be exposed beyond localhost. Users, clients and the API audience can
+be configured with a JSON file passed via --config.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		RunDevIdP()
+	},
+}
+
+func init() {
+	devIdPCmd.Flags().StringVar(&devIdPAddr, "addr", "localhost:9999", "Address to listen on")
+	devIdPCmd.Flags().StringVar(&devIdPIssuer, "issuer", "", "Externally visible issuer URL (defaults to http://<addr>)")
+	devIdPCmd.Flags().StringVar(&devIdPConfig, "config", "", "Path to a JSON file of users, clients and audience")
+	rootCmd.AddCommand(devIdPCmd)
+}
+
+func RunDevIdP() {
+	conf := devidp.DefaultConfig()
+	if devIdPConfig != "" {
+		var err error
+		conf, err = devidp.LoadConfig(devIdPConfig)
+		if err != nil {
+			log.Fatalf("couldn't load dev-idp config: %s", err.Error())
+		}
+	}
+
+	idp, err := devidp.New(conf)
+	if err != nil {
+		log.Fatalf("couldn't start dev-idp: %s", err.Error())
+	}
+
+	issuer := devIdPIssuer
+	if issuer == "" {
+		issuer = "http://" + devIdPAddr
+	}
+	idp.SetIssuer(issuer)
+
+	clientID := ""
+	if len(conf.Clients) > 0 {
+		clientID = conf.Clients[0].ID
+	}
+
+	fmt.Printf("Development identity provider listening on %s\n\n", issuer)
+	fmt.Println("Point the backplane and CLI at it with:")
+	fmt.Printf("  export TRAINTRACK_AUTH_NAME=%s\n", conf.Audience)
+	fmt.Printf("  export TRAINTRACK_CLIENT_ID=%s\n", clientID)
+	fmt.Printf("  export TRAINTRACK_AUTH_URL=%s\n\n", idp.Issuer())
+	for _, u := range conf.Users {
+		fmt.Printf("  user: %s <%s> (%s)\n", u.Name, u.Email, u.Subject)
+	}
+
+	log.Fatal(http.ListenAndServe(devIdPAddr, idp))
+}
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/erasure"
+	"github.com/spf13/cobra"
+)
+
+var (
+	erasureReason string
+	erasurePurge  bool
+	erasureDryRun bool
+)
+
+var erasuresCmd = &cobra.Command{
+	Use:   "erasures",
+	Short: "Erase a dataset version and everything derived from it",
+}
+
+var erasuresRequestCmd = &cobra.Command{
+	Use:   "request <dataset-id>",
+	Short: "Taint, and optionally purge, a dataset version and everything derived from it",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		RunErasureRequest(args[0], erasureReason, erasurePurge, erasureDryRun)
+	},
+}
+
+var erasuresListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List erasure requests",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		RunErasuresList()
+	},
+}
+
+var erasuresShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show what an erasure request affected",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		RunErasureShow(args[0])
+	},
+}
+
+func init() {
+	erasuresRequestCmd.Flags().StringVar(&erasureReason, "reason", "", "Why the data is being erased, such as a ticket number")
+	erasuresRequestCmd.Flags().BoolVar(&erasurePurge, "purge", false, "Also remove the files of every affected version")
+	erasuresRequestCmd.Flags().BoolVar(&erasureDryRun, "dry-run", false, "Only report what would be affected")
+
+	erasuresCmd.AddCommand(erasuresRequestCmd)
+	erasuresCmd.AddCommand(erasuresListCmd)
+	erasuresCmd.AddCommand(erasuresShowCmd)
+	rootCmd.AddCommand(erasuresCmd)
+}
+
+func RunErasureRequest(id string, reason string, purge bool, dryRun bool) {
+	if reason == "" && !dryRun {
+		fmt.Println("--reason is required")
+		os.Exit(1)
+	}
+
+	id, err := resolveVersionID(id)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var query url.Values
+	if dryRun {
+		query = url.Values{"dry_run": {"true"}}
+	}
+
+	var r erasure.Request
+	body := &erasure.Request{DatasetID: id, Reason: reason, Purge: purge}
+	if err := doJSON(http.MethodPost, "erasures", query, body, &r); err != nil {
+		fmt.Printf("couldn't erase %s: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	printImpact(r.Impact)
+	if dryRun {
+		fmt.Printf("\n%d versions would be affected, nothing was changed\n", len(r.Impact.Versions()))
+		return
+	}
+	fmt.Printf("\nerasure %.8s tainted %d versions\n", r.ID, len(r.Impact.Versions()))
+}
+
+func RunErasuresList() {
+	var rs []*erasure.Request
+	if err := doJSON(http.MethodGet, "erasures", nil, nil, &rs); err != nil {
+		fmt.Printf("couldn't fetch erasure requests: %s\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tDATASET\tREASON\tPURGE\tVERSIONS\tREQUESTED\tBY")
+	for _, r := range rs {
+		versions := 0
+		if r.Impact != nil {
+			versions = len(r.Impact.Versions())
+		}
+		fmt.Fprintf(w, "%.8s\t%.8s\t%s\t%t\t%d\t%s\t%s\n",
+			r.ID,
+			r.DatasetID,
+			r.Reason,
+			r.Purge,
+			versions,
+			r.RequestedAt.Local().Format(time.DateTime),
+			r.RequestedBy,
+		)
+	}
+	w.Flush()
+}
+
+func RunErasureShow(id string) {
+	var r erasure.Request
+	if err := doJSON(http.MethodGet, path.Join("erasures", id), nil, nil, &r); err != nil {
+		fmt.Printf("couldn't fetch erasure request %s: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Erasure %s of dataset %s\n", r.ID, r.DatasetID)
+	fmt.Printf("Requested %s by %s\n", r.RequestedAt.Local().Format(time.DateTime), r.RequestedBy)
+	fmt.Printf("Reason: %s\n\n", r.Reason)
+	printImpact(r.Impact)
+}
+
+/*
+printImpact lists every version in an impact report, with the files that
+were removed from it.
+*/
+func printImpact(i *erasure.Impact) {
+	if i == nil {
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tID\tNAME\tVERSION\tDERIVED FROM\tSTAGE\tPURGED\tFILES")
+	for _, v := range i.Versions() {
+		fmt.Fprintf(w, "%s\t%.8s\t%s\t%s\t%.8s\t%s\t%t\t%s\n",
+			v.Kind,
+			v.ID,
+			v.Name,
+			v.Version,
+			v.DerivedFrom,
+			v.Stage,
+			v.Purged,
+			strings.Join(v.Files, ", "),
+		)
+	}
+	w.Flush()
+}
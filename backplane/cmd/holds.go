@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/holds"
+	"github.com/spf13/cobra"
+)
+
+var (
+	holdReason string
+	holdHolder string
+	holdsAll   bool
+)
+
+var holdsCmd = &cobra.Command{
+	Use:   "holds",
+	Short: "List and release legal holds",
+}
+
+var holdsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List legal holds",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		RunHoldsList(holdsAll)
+	},
+}
+
+var holdsReleaseCmd = &cobra.Command{
+	Use:   "release <hold-id>",
+	Short: "Release a legal hold",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		RunHoldRelease(args[0])
+	},
+}
+
+/*
+holdCommand adds a hold command for kind, either "dataset" or "model", to
+parent.
+*/
+func holdCommand(parent *cobra.Command, kind holds.Kind) {
+	cmd := &cobra.Command{
+		Use:   "hold <id>",
+		Short: fmt.Sprintf("Put a %s version under a legal hold, so it can't be deleted or purged", kind),
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			RunHold(kind, args[0], holdReason, holdHolder)
+		},
+	}
+	cmd.Flags().StringVar(&holdReason, "reason", "", "Why the version is held, such as the matter it relates to")
+	cmd.Flags().StringVar(&holdHolder, "holder", "", "Who asked for the hold, such as the legal team")
+	cmd.MarkFlagRequired("reason")
+	cmd.MarkFlagRequired("holder")
+	parent.AddCommand(cmd)
+}
+
+func init() {
+	holdsListCmd.Flags().BoolVar(&holdsAll, "all", false, "Include released holds")
+
+	holdCommand(datasetsCmd, holds.KindDataset)
+	holdCommand(modelsCmd, holds.KindModel)
+	holdsCmd.AddCommand(holdsListCmd)
+	holdsCmd.AddCommand(holdsReleaseCmd)
+	rootCmd.AddCommand(holdsCmd)
+}
+
+func RunHold(kind holds.Kind, id string, reason string, holder string) {
+	id, err := resolveVersionID(id)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var h holds.Hold
+	body := &holds.Hold{ResourceType: kind, ResourceID: id, Reason: reason, Holder: holder}
+	if err := doJSON(http.MethodPost, "holds", nil, body, &h); err != nil {
+		fmt.Printf("couldn't hold %s: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("placed legal hold %.8s on %s %s (%.8s)\n", h.ID, h.Name, h.Version, h.ResourceID)
+}
+
+func RunHoldsList(all bool) {
+	hs, err := fetchHolds(all)
+	if err != nil {
+		fmt.Printf("couldn't fetch legal holds: %s\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tKIND\tVERSION ID\tNAME\tVERSION\tHOLDER\tREASON\tPLACED\tRELEASED")
+	for _, h := range hs {
+		released := ""
+		if h.ReleasedAt != nil {
+			released = h.ReleasedAt.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%.8s\t%s\t%.8s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			h.ID,
+			h.ResourceType,
+			h.ResourceID,
+			h.Name,
+			h.Version,
+			h.Holder,
+			h.Reason,
+			h.PlacedAt.Local().Format(time.DateTime),
+			released,
+		)
+	}
+	w.Flush()
+}
+
+/*
+RunHoldRelease releases the hold id, which may be a prefix of the id of
+an active hold.
+*/
+func RunHoldRelease(id string) {
+	id, err := resolveHoldID(id)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var h holds.Hold
+	if err := doJSON(http.MethodDelete, path.Join("holds", id), nil, nil, &h); err != nil {
+		fmt.Printf("couldn't release %s: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("released legal hold %.8s on %s %s (%.8s)\n", h.ID, h.Name, h.Version, h.ResourceID)
+}
+
+func fetchHolds(all bool) ([]*holds.Hold, error) {
+	var query url.Values
+	if all {
+		query = url.Values{"all": {"true"}}
+	}
+
+	var hs []*holds.Hold
+	if err := doJSON(http.MethodGet, "holds", query, nil, &hs); err != nil {
+		return nil, err
+	}
+	return hs, nil
+}
+
+func resolveHoldID(prefix string) (string, error) {
+	if len(prefix) == 36 {
+		return prefix, nil
+	}
+
+	hs, err := fetchHolds(false)
+	if err != nil {
+		return "", fmt.Errorf("couldn't fetch legal holds: %w", err)
+	}
+
+	var matches []string
+	for _, h := range hs {
+		if strings.HasPrefix(h.ID, prefix) {
+			matches = append(matches, h.ID)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no active legal hold matches %q", prefix)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%q is ambiguous, it matches %s", prefix, strings.Join(matches, ", "))
+	}
+}
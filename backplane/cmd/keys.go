@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"text/tabwriter"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/signing"
+	"github.com/spf13/cobra"
+)
+
+var keysGenerateOut string
+
+var keysCmd = &cobra.Command{
+	Use:   "keys",
+	Short: "Manage the keys trusted to sign your tenant's models",
+}
+
+var keysGenerateCmd = &cobra.Command{
+	Use:   "generate",
+	Short: "Generate a new signing key pair locally",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		RunKeysGenerate(keysGenerateOut)
+	},
+}
+
+var keysAddCmd = &cobra.Command{
+	Use:   "add <name> <public-key.pem>",
+	Short: "Trust a public key to sign models",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		RunKeysAdd(args[0], args[1])
+	},
+}
+
+var keysListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List trusted keys, including revoked ones",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		RunKeysList()
+	},
+}
+
+var keysRevokeCmd = &cobra.Command{
+	Use:   "revoke <id>",
+	Short: "Stop trusting a key",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		RunKeysRevoke(args[0])
+	},
+}
+
+func init() {
+	keysGenerateCmd.Flags().StringVar(&keysGenerateOut, "out", "traintrack-signing", "Write the key pair to <out>.key and <out>.pub")
+
+	keysCmd.AddCommand(keysGenerateCmd)
+	keysCmd.AddCommand(keysAddCmd)
+	keysCmd.AddCommand(keysListCmd)
+	keysCmd.AddCommand(keysRevokeCmd)
+	rootCmd.AddCommand(keysCmd)
+}
+
+func RunKeysGenerate(out string) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		fmt.Printf("couldn't generate key: %s\n", err)
+		os.Exit(1)
+	}
+
+	privPEM, err := signing.MarshalPrivateKey(priv)
+	if err != nil {
+		fmt.Printf("couldn't encode private key: %s\n", err)
+		os.Exit(1)
+	}
+	pubPEM, err := signing.MarshalPublicKey(pub)
+	if err != nil {
+		fmt.Printf("couldn't encode public key: %s\n", err)
+		os.Exit(1)
+	}
+
+	// Refuse to overwrite an existing private key; losing one is
+	// unrecoverable.
+	f, err := os.OpenFile(out+".key", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		fmt.Printf("couldn't write private key: %s\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	if _, err := f.Write(privPEM); err != nil {
+		fmt.Printf("couldn't write private key: %s\n", err)
+		os.Exit(1)
+	}
+
+	if err := os.WriteFile(out+".pub", pubPEM, 0644); err != nil {
+		fmt.Printf("couldn't write public key: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("generated key %s\n  private: %s.key (keep this secret)\n  public:  %s.pub\n", signing.KeyID(pub), out, out)
+	fmt.Printf("\nTrust it with: traintrack keys add <name> %s.pub\n", out)
+}
+
+func RunKeysAdd(name string, file string) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Printf("couldn't read public key: %s\n", err)
+		os.Exit(1)
+	}
+
+	var added signing.Key
+	if err := doJSON(http.MethodPost, "keys", nil, &signing.Key{Name: name, PublicKey: string(data)}, &added); err != nil {
+		fmt.Printf("couldn't add key: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("trusting key %s (%s)\n", added.ID, added.Name)
+}
+
+func RunKeysList() {
+	var keys []*signing.Key
+	if err := doJSON(http.MethodGet, "keys", nil, nil, &keys); err != nil {
+		fmt.Printf("couldn't fetch keys: %s\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tCREATED\tBY\tREVOKED")
+	for _, k := range keys {
+		revoked := ""
+		if k.Revoked() {
+			revoked = k.RevokedAt.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			k.ID,
+			k.Name,
+			k.CreatedAt.Local().Format(time.DateTime),
+			k.CreatedBy,
+			revoked,
+		)
+	}
+	w.Flush()
+}
+
+func RunKeysRevoke(id string) {
+	var revoked signing.Key
+	if err := doJSON(http.MethodDelete, path.Join("keys", id), nil, nil, &revoked); err != nil {
+		fmt.Printf("couldn't revoke key: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("revoked key %s (%s)\n", revoked.ID, revoked.Name)
+}
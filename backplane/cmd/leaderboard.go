@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/heldtogether/traintrack/internal/datasets"
+	"github.com/heldtogether/traintrack/internal/leaderboard"
+	"github.com/spf13/cobra"
+)
+
+var (
+	leaderboardMetric string
+	leaderboardOrder  string
+	leaderboardStages []string
+	leaderboardTags   []string
+	leaderboardLimit  int
+)
+
+var datasetsLeaderboardCmd = &cobra.Command{
+	Use:   "leaderboard <id>",
+	Short: "Rank the models trained on a dataset version by an evaluation metric",
+	Long: `Rank the models trained on a dataset version by a number in their
+evaluation. Use the arrow keys to move through the models and o to flip
+the order.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		RunDatasetsLeaderboard(args[0])
+	},
+}
+
+func init() {
+	datasetsLeaderboardCmd.Flags().StringVar(&leaderboardMetric, "metric", "", "Evaluation key to rank by, with dots for nested keys")
+	datasetsLeaderboardCmd.Flags().StringVar(&leaderboardOrder, "order", "desc", "asc if lower is better, or desc if higher is")
+	datasetsLeaderboardCmd.Flags().StringSliceVar(&leaderboardStages, "stage", nil, "Only rank models in these stages")
+	datasetsLeaderboardCmd.Flags().StringArrayVar(&leaderboardTags, "tag", nil, "Only rank models finalized from a run with this key:value tag (repeatable)")
+	datasetsLeaderboardCmd.Flags().IntVar(&leaderboardLimit, "limit", leaderboard.DefaultLimit, "How many models to rank")
+	datasetsLeaderboardCmd.MarkFlagRequired("metric")
+
+	datasetsCmd.AddCommand(datasetsLeaderboardCmd)
+}
+
+func RunDatasetsLeaderboard(id string) {
+	ds, err := FetchDatasets(nil)
+	if err != nil {
+		fmt.Printf("couldn't fetch datasets: %s\n", err)
+		os.Exit(1)
+	}
+
+	d, err := resolveDataset(ds, id)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	query := url.Values{
+		"metric": {leaderboardMetric},
+		"order":  {leaderboardOrder},
+		"limit":  {strconv.Itoa(leaderboardLimit)},
+		"tag":    leaderboardTags,
+	}
+	if len(leaderboardStages) > 0 {
+		query.Set("stage", strings.Join(leaderboardStages, ","))
+	}
+
+	board, err := FetchLeaderboard(d.ID, query)
+	if err != nil {
+		fmt.Printf("couldn't fetch leaderboard: %s\n", err)
+		os.Exit(1)
+	}
+
+	m := newLeaderboardModel(fmt.Sprintf("%s %s", d.Name, d.Version), query, board)
+	p := tea.NewProgram(m, tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		fmt.Println("Error running program:", err)
+		os.Exit(1)
+	}
+}
+
+func FetchLeaderboard(id string, query url.Values) (*leaderboard.Leaderboard, error) {
+	var board leaderboard.Leaderboard
+	if err := doJSON(http.MethodGet, path.Join("datasets", id, "leaderboard"), query, nil, &board); err != nil {
+		return nil, err
+	}
+	return &board, nil
+}
+
+/*
+resolveDataset finds the dataset whose id is, or starts with, prefix.
+*/
+func resolveDataset(ds []*datasets.Dataset, prefix string) (*datasets.Dataset, error) {
+	var matches []*datasets.Dataset
+	for _, d := range ds {
+		if strings.HasPrefix(d.ID, prefix) {
+			matches = append(matches, d)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no dataset matches %q", prefix)
+	case 1:
+		return matches[0], nil
+	default:
+		ids := make([]string, len(matches))
+		for i, d := range matches {
+			ids[i] = d.ID
+		}
+		return nil, fmt.Errorf("%q is ambiguous, it matches %s", prefix, strings.Join(ids, ", "))
+	}
+}
+
+/*
+leaderboardMsg carries a leaderboard fetched again after the order was
+flipped.
+*/
+type leaderboardMsg struct {
+	board *leaderboard.Leaderboard
+	err   error
+}
+
+type leaderboardModel struct {
+	dataset string
+	query   url.Values
+	board   *leaderboard.Leaderboard
+	table   table.Model
+	err     error
+}
+
+func newLeaderboardModel(dataset string, query url.Values, board *leaderboard.Leaderboard) leaderboardModel {
+	m := leaderboardModel{
+		dataset: dataset,
+		query:   query,
+		table: table.New(
+			table.WithColumns([]table.Column{
+				{Title: "#", Width: 4},
+				{Title: "MODEL", Width: 10},
+				{Title: "NAME", Width: 24},
+				{Title: "VERSION", Width: 10},
+				{Title: "STAGE", Width: 12},
+				{Title: strings.ToUpper(board.Metric), Width: 12},
+				{Title: "CREATED", Width: 16},
+			}),
+			table.WithFocused(true),
+		),
+	}
+	m.setBoard(board)
+	return m
+}
+
+func (m *leaderboardModel) setBoard(board *leaderboard.Leaderboard) {
+	m.board = board
+
+	rows := make([]table.Row, len(board.Entries))
+	for i, e := range board.Entries {
+		rows[i] = table.Row{
+			strconv.Itoa(e.Rank),
+			fmt.Sprintf("%.8s", e.ModelID),
+			e.Name,
+			e.Version,
+			e.Stage,
+			strconv.FormatFloat(e.Score, 'g', 6, 64),
+			e.CreatedAt.Local().Format("2006-01-02 15:04"),
+		}
+	}
+	m.table.SetRows(rows)
+	m.table.GotoTop()
+}
+
+func (m leaderboardModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m leaderboardModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		case "o":
+			order := "asc"
+			if m.board.Order == "asc" {
+				order = "desc"
+			}
+			m.query.Set("order", order)
+			return m, m.fetch()
+		}
+
+	case leaderboardMsg:
+		m.err = msg.err
+		if msg.err == nil {
+			m.setBoard(msg.board)
+		}
+		return m, nil
+
+	case tea.WindowSizeMsg:
+		// Leave room for the title and status bar, each with a blank line.
+		m.table.SetWidth(msg.Width)
+		m.table.SetHeight(max(1, msg.Height-4))
+	}
+
+	m.table, cmd = m.table.Update(msg)
+	return m, cmd
+}
+
+func (m leaderboardModel) fetch() tea.Cmd {
+	id, query := m.board.Dataset, m.query
+	return func() tea.Msg {
+		board, err := FetchLeaderboard(id, query)
+		return leaderboardMsg{board: board, err: err}
+	}
+}
+
+func (m leaderboardModel) View() string {
+	better := "higher is better"
+	if m.board.Order == "asc" {
+		better = "lower is better"
+	}
+	title := fmt.Sprintf("%s on %s – %d models, %s", m.board.Metric, m.dataset, len(m.board.Entries), better)
+
+	statusBar := "[↑ up] [↓ down] [o flip order] [q to quit]"
+	if m.err != nil {
+		statusBar = fmt.Sprintf("couldn't fetch leaderboard: %s", m.err)
+	}
+	return title + "\n\n" + m.table.View() + "\n\n" + statusBar
+}
@@ -18,7 +18,10 @@ import (
 	"golang.org/x/oauth2"
 )
 
-var verbose bool
+var (
+	verbose bool
+	device  bool
+)
 
 var loginCmd = &cobra.Command{
 	Use:   "login",
@@ -35,6 +38,7 @@ const (
 
 func init() {
 	loginCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Output additional details after login")
+	loginCmd.Flags().BoolVar(&device, "device", false, "Log in using a code entered on another device, for headless machines")
 	rootCmd.AddCommand(loginCmd)
 }
 
@@ -45,10 +49,11 @@ type OAuthResult struct {
 }
 
 func RunLogin() {
-	state := uuid.NewString()
 	ctx := context.Background()
 
-	loadedConfig, err := auth.LoadConfig(auth.DefaultConfigPath)
+	tc := CurrentContext()
+
+	loadedConfig, err := auth.LoadConfig(tc.OAuthConfigPath())
 	if err != nil {
 		log.Fatalf("couldn't load config: %s", err.Error())
 	}
@@ -59,40 +64,22 @@ func RunLogin() {
 	}
 
 	config := &oauth2.Config{
-		ClientID:    loadedConfig.ClientID,
-		RedirectURL: fmt.Sprintf("http://localhost:%d/auth/callback", port),
-		Scopes:      []string{"openid", "profile", "email", "offline_access"},
-		Endpoint:    p.Endpoint(),
+		ClientID: loadedConfig.ClientID,
+		Scopes:   []string{"openid", "profile", "email", "offline_access"},
+		Endpoint: p.Endpoint(),
 	}
 
-	authURL := config.AuthCodeURL(
-		state,
-		oauth2.AccessTypeOffline,
-		oauth2.SetAuthURLParam("audience", loadedConfig.Name),
-		oauth2.SetAuthURLParam("prompt", "consent"),
-	)
-
-	resultChan := startCallbackServer(port, timeout)
-
-	if err := openBrowser(authURL); err != nil {
-		log.Fatalf("failed to open browser: %s", err.Error())
+	var token *oauth2.Token
+	if device {
+		token, err = deviceLogin(ctx, config, loadedConfig.Name)
+	} else {
+		token, err = browserLogin(ctx, config, loadedConfig.Name)
 	}
-
-	result := <-resultChan
-	if result.Err != nil {
-		log.Fatalf("login failed: %s", result.Err.Error())
-	}
-
-	if result.State != state {
-		log.Fatalf("state mismatch: potential CSRF")
-	}
-
-	token, err := config.Exchange(ctx, result.Code)
 	if err != nil {
-		log.Fatalf("token exchange failed: %s", err.Error())
+		log.Fatalf("login failed: %s", err.Error())
 	}
 
-	auth.SaveToken(auth.DefaultTokenPath, token)
+	auth.SaveToken(tc.TokenPath(), token)
 
 	if verbose {
 		fmt.Println("Access Token:", token.AccessToken)
@@ -120,6 +107,79 @@ func RunLogin() {
 	}
 }
 
+/*
+browserLogin runs the authorization code flow, opening the user's browser
+and waiting for the identity provider to redirect back to a local
+callback server.
+*/
+func browserLogin(ctx context.Context, config *oauth2.Config, audience string) (*oauth2.Token, error) {
+	state := uuid.NewString()
+	config.RedirectURL = fmt.Sprintf("http://localhost:%d/auth/callback", port)
+
+	authURL := config.AuthCodeURL(
+		state,
+		oauth2.AccessTypeOffline,
+		oauth2.SetAuthURLParam("audience", audience),
+		oauth2.SetAuthURLParam("prompt", "consent"),
+	)
+
+	resultChan := startCallbackServer(port, timeout)
+
+	if err := openBrowser(authURL); err != nil {
+		return nil, fmt.Errorf("failed to open browser: %w", err)
+	}
+
+	result := <-resultChan
+	if result.Err != nil {
+		return nil, result.Err
+	}
+
+	if result.State != state {
+		return nil, errors.New("state mismatch: potential CSRF")
+	}
+
+	token, err := config.Exchange(ctx, result.Code)
+	if err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+	return token, nil
+}
+
+/*
+deviceLogin runs the OAuth 2.0 device authorization grant (RFC 8628). The
+user is shown a URL and code to enter on any device with a browser, while
+we poll the identity provider until the token is issued. It works over
+SSH and inside containers where no browser or callback port is available.
+*/
+func deviceLogin(ctx context.Context, config *oauth2.Config, audience string) (*oauth2.Token, error) {
+	if config.Endpoint.DeviceAuthURL == "" {
+		return nil, errors.New("identity provider does not support the device authorization grant")
+	}
+
+	resp, err := config.DeviceAuth(ctx, oauth2.SetAuthURLParam("audience", audience))
+	if err != nil {
+		return nil, fmt.Errorf("device authorization failed: %w", err)
+	}
+
+	fmt.Println("To log in, visit:")
+	fmt.Println()
+	fmt.Println("  " + resp.VerificationURI)
+	fmt.Println()
+	fmt.Println("and enter the code:", resp.UserCode)
+	if resp.VerificationURIComplete != "" {
+		fmt.Println()
+		fmt.Println("Or open this link directly:", resp.VerificationURIComplete)
+	}
+	fmt.Println()
+	fmt.Println("Waiting for authorization...")
+
+	token, err := config.DeviceAccessToken(ctx, resp)
+	if err != nil {
+		return nil, fmt.Errorf("waiting for device authorization: %w", err)
+	}
+	return token, nil
+}
+
 func startCallbackServer(port int, timeout time.Duration) <-chan OAuthResult {
 	resultChan := make(chan OAuthResult, 1)
 	mux := http.NewServeMux()
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/spf13/cobra"
+)
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Revoke and remove the stored credentials",
+	Run: func(cmd *cobra.Command, args []string) {
+		RunLogout()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(logoutCmd)
+}
+
+func RunLogout() {
+	ctx := context.Background()
+	tc := CurrentContext()
+
+	token, err := auth.LoadToken(tc.TokenPath())
+	if err != nil {
+		fmt.Println("Not logged in.")
+		return
+	}
+
+	// Revocation is best effort: the local credentials are removed
+	// whatever the identity provider says.
+	if token.RefreshToken != "" {
+		conf, err := auth.LoadConfig(tc.OAuthConfigPath())
+		if err != nil {
+			log.Printf("couldn't load config, skipping revocation: %s", err)
+		} else if err := auth.RevokeToken(ctx, conf, token.RefreshToken, "refresh_token"); errors.Is(err, auth.ErrRevocationUnsupported) {
+			fmt.Println("Identity provider does not support revocation; the refresh token will expire on its own.")
+		} else if err != nil {
+			log.Printf("couldn't revoke refresh token: %s", err)
+		} else {
+			fmt.Println("Refresh token revoked.")
+		}
+	}
+
+	if err := auth.DeleteToken(tc.TokenPath()); err != nil {
+		log.Fatalf("couldn't remove credentials: %s", err)
+	}
+
+	fmt.Printf("Logged out of context %s.\n", tc.Name)
+}
@@ -0,0 +1,279 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/heldtogether/traintrack/cmd/charts"
+	"github.com/heldtogether/traintrack/internal/metrics"
+	"github.com/heldtogether/traintrack/internal/models"
+	"github.com/spf13/cobra"
+)
+
+var metricsPoints int
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics <model>",
+	Short: "Chart a model's metrics and compare its versions' evaluations",
+	Long: `Chart a model's metrics and compare its versions' evaluations.
+
+The model is given by name, for its latest version, or by id or id
+prefix. Each metric logged against it, or against the run it was
+finalized from, is drawn as a line chart, and each number in the
+evaluations of its versions as a bar chart across them. Use the arrow
+keys or tab to move between charts.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		RunMetrics(args[0])
+	},
+}
+
+func init() {
+	metricsCmd.Flags().IntVar(&metricsPoints, "points", metrics.DefaultPoints, "How many points to downsample each metric to")
+	rootCmd.AddCommand(metricsCmd)
+}
+
+func RunMetrics(model string) {
+	ms, err := FetchModels(nil)
+	if err != nil {
+		fmt.Printf("couldn't fetch models: %s\n", err)
+		os.Exit(1)
+	}
+
+	m, err := resolveModel(ms, model)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	series, err := fetchModelSeries(m.ID)
+	if err != nil {
+		fmt.Printf("couldn't fetch metrics: %s\n", err)
+		os.Exit(1)
+	}
+
+	var views []chartView
+	for _, s := range series {
+		views = append(views, chartView{title: s.Key, series: s})
+	}
+	views = append(views, evaluationViews(ms, m.Name)...)
+
+	if len(views) == 0 {
+		fmt.Printf("%s %s has no metrics or evaluations to chart\n", m.Name, m.Version)
+		return
+	}
+
+	p := tea.NewProgram(metricsModel{
+		subject: fmt.Sprintf("%s %s", m.Name, m.Version),
+		views:   views,
+	}, tea.WithAltScreen())
+	if _, err := p.Run(); err != nil {
+		fmt.Println("Error running program:", err)
+		os.Exit(1)
+	}
+}
+
+/*
+resolveModel finds the model named by ref, which is either a name, for
+its most recently created version, or an id or prefix of one.
+*/
+func resolveModel(ms []*models.Model, ref string) (*models.Model, error) {
+	var latest *models.Model
+	for _, m := range ms {
+		if m.Name == ref && (latest == nil || m.CreatedAt.After(latest.CreatedAt)) {
+			latest = m
+		}
+	}
+	if latest != nil {
+		return latest, nil
+	}
+
+	var matches []*models.Model
+	for _, m := range ms {
+		if strings.HasPrefix(m.ID, ref) {
+			matches = append(matches, m)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("no model is named or matches %q", ref)
+	case 1:
+		return matches[0], nil
+	default:
+		ids := make([]string, len(matches))
+		for i, m := range matches {
+			ids[i] = m.ID
+		}
+		return nil, fmt.Errorf("%q is ambiguous, it matches %s", ref, strings.Join(ids, ", "))
+	}
+}
+
+/*
+fetchModelSeries returns the metrics logged against the model id, along
+with those of the run it was finalized from that weren't also logged
+against the model.
+*/
+func fetchModelSeries(id string) ([]*metrics.Series, error) {
+	query := url.Values{"points": {strconv.Itoa(metricsPoints)}}
+
+	var series []*metrics.Series
+	if err := doJSON(http.MethodGet, path.Join("models", id, "metrics"), query, nil, &series); err != nil {
+		return nil, err
+	}
+
+	rs, err := fetchRuns(nil)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't fetch runs: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for _, s := range series {
+		seen[s.Key] = true
+	}
+	for _, r := range rs {
+		if r.ModelID == nil || *r.ModelID != id {
+			continue
+		}
+
+		var logged []*metrics.Series
+		if err := doJSON(http.MethodGet, path.Join("runs", r.ID, "metrics"), query, nil, &logged); err != nil {
+			return nil, err
+		}
+		for _, s := range logged {
+			if !seen[s.Key] {
+				seen[s.Key] = true
+				series = append(series, s)
+			}
+		}
+	}
+
+	sort.Slice(series, func(i, j int) bool { return series[i].Key < series[j].Key })
+	return series, nil
+}
+
+/*
+evaluationViews returns a bar chart for each number in the evaluations of
+the versions of the model name, comparing them in the order they were
+created. Versions without that number are left out of its chart.
+*/
+func evaluationViews(ms []*models.Model, name string) []chartView {
+	var versions []*models.Model
+	for _, m := range ms {
+		if m.Name == name {
+			versions = append(versions, m)
+		}
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].CreatedAt.Before(versions[j].CreatedAt) })
+
+	bars := map[string][]charts.Bar{}
+	for _, m := range versions {
+		var evaluation map[string]any
+		if err := json.Unmarshal(m.Evaluation, &evaluation); err != nil {
+			continue
+		}
+		for key, v := range evaluation {
+			if n, ok := v.(float64); ok {
+				bars[key] = append(bars[key], charts.Bar{Label: m.Version, Value: n})
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(bars))
+	for key := range bars {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	views := make([]chartView, len(keys))
+	for i, key := range keys {
+		views[i] = chartView{title: key + " by version", bars: bars[key]}
+	}
+	return views
+}
+
+/*
+chartView is one screen of the metrics viewer, either a metric's series
+drawn as a line or an evaluation compared across versions as bars.
+*/
+type chartView struct {
+	title  string
+	series *metrics.Series
+	bars   []charts.Bar
+}
+
+func (v chartView) render(width, height int) []string {
+	if v.series == nil {
+		return charts.Bars(v.bars, width)
+	}
+
+	points := make([]charts.Point, len(v.series.Points))
+	for i, p := range v.series.Points {
+		points[i] = charts.Point{X: float64(p.Step), Y: p.Value}
+	}
+	return charts.Line(points, width, height)
+}
+
+type metricsModel struct {
+	subject string
+	views   []chartView
+	current int
+	width   int
+	height  int
+}
+
+func (m metricsModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m metricsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "q", "ctrl+c":
+			return m, tea.Quit
+		case "right", "l", "tab":
+			m.current = (m.current + 1) % len(m.views)
+		case "left", "h", "shift+tab":
+			m.current = (m.current + len(m.views) - 1) % len(m.views)
+		}
+
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+	}
+
+	return m, nil
+}
+
+func (m metricsModel) View() string {
+	if m.width == 0 {
+		return ""
+	}
+
+	v := m.views[m.current]
+	title := fmt.Sprintf("%s (%d of %d) – %s", v.title, m.current+1, len(m.views), m.subject)
+	if v.series != nil && v.series.Count > int64(len(v.series.Points)) {
+		title += fmt.Sprintf(", %d of %d points shown", len(v.series.Points), v.series.Count)
+	}
+
+	// Leave room for the title and status bar, each with a blank line.
+	height := max(0, m.height-4)
+	lines := v.render(m.width, height)
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+
+	statusBar := "[← previous] [→ next] [q to quit]"
+	return title + "\n\n" + strings.Join(lines, "\n") + "\n\n" + statusBar
+}
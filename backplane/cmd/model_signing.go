@@ -0,0 +1,270 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/heldtogether/traintrack/internal/models"
+	"github.com/heldtogether/traintrack/internal/seal"
+	"github.com/heldtogether/traintrack/internal/signing"
+	"github.com/spf13/cobra"
+)
+
+var (
+	signKeyFile string
+
+	verifyKeyFiles []string
+	verifyBundle   string
+	verifyDir      string
+	verifySaveTo   string
+)
+
+var modelsSignCmd = &cobra.Command{
+	Use:   "sign <id>",
+	Short: "Sign a model's artefacts with your private key",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		RunModelsSign(args[0], signKeyFile)
+	},
+}
+
+var modelsVerifyCmd = &cobra.Command{
+	Use:   "verify [id]",
+	Short: "Check a model's signatures locally, against keys you trust",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id := ""
+		if len(args) == 1 {
+			id = args[0]
+		}
+		RunModelsVerify(id)
+	},
+}
+
+var modelsPromoteCmd = &cobra.Command{
+	Use:   "promote <id> <stage>",
+	Short: "Move a model to development, staging, production or archived",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		RunModelsPromote(args[0], models.Stage(args[1]))
+	},
+}
+
+func init() {
+	modelsSignCmd.Flags().StringVar(&signKeyFile, "key", "", "PEM encoded ed25519 private key to sign with")
+	modelsSignCmd.MarkFlagRequired("key")
+
+	modelsVerifyCmd.Flags().StringArrayVar(&verifyKeyFiles, "key", nil, "PEM encoded public key to trust (repeatable). Without one, the keys the server trusts are used")
+	modelsVerifyCmd.Flags().StringVar(&verifyBundle, "bundle", "", "Verify a saved bundle instead of fetching one")
+	modelsVerifyCmd.Flags().StringVar(&verifyDir, "dir", "", "Also check the artefact files in this directory match what was signed")
+	modelsVerifyCmd.Flags().StringVar(&verifySaveTo, "save", "", "Save the bundle to this file for verifying offline later")
+
+	modelsCmd.AddCommand(modelsSignCmd)
+	modelsCmd.AddCommand(modelsVerifyCmd)
+	modelsCmd.AddCommand(modelsPromoteCmd)
+}
+
+func RunModelsSign(id string, keyFile string) {
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		fmt.Printf("couldn't read private key: %s\n", err)
+		os.Exit(1)
+	}
+	priv, err := signing.ParsePrivateKey(data)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	id, err = resolveVersionID(id)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	bundle, err := FetchSignatureBundle(id)
+	if err != nil {
+		fmt.Printf("couldn't fetch model %s: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	sig, err := signing.Sign(priv, bundle.Artefacts)
+	if err != nil {
+		fmt.Printf("couldn't sign model %s: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	var signed signing.Signature
+	if err := doJSON(http.MethodPost, path.Join("models", id, "signatures"), nil, sig, &signed); err != nil {
+		fmt.Printf("couldn't sign model %s: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("signed model %.8s with key %s\n", id, signed.KeyID)
+}
+
+/*
+RunModelsVerify checks a model's signatures on this machine, so a
+compromised server can't vouch for a model itself. Only the keys given
+with --key are trusted, unless none are given.
+*/
+func RunModelsVerify(id string) {
+	var bundle *signing.Bundle
+	var err error
+
+	switch {
+	case verifyBundle != "":
+		bundle, err = readBundle(verifyBundle)
+	case id != "":
+		if id, err = resolveVersionID(id); err == nil {
+			bundle, err = FetchSignatureBundle(id)
+		}
+	default:
+		err = fmt.Errorf("give a model id or --bundle")
+	}
+	if err != nil {
+		fmt.Printf("couldn't load signatures: %s\n", err)
+		os.Exit(1)
+	}
+
+	if verifySaveTo != "" {
+		if err := writeBundle(verifySaveTo, bundle); err != nil {
+			fmt.Printf("couldn't save bundle: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	trusted := bundle.Keys
+	if len(verifyKeyFiles) > 0 {
+		if trusted, err = readTrustedKeys(verifyKeyFiles); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	} else {
+		fmt.Println("no --key given, trusting the keys listed in the bundle")
+	}
+
+	ok := true
+
+	valid, problems := bundle.Verify(trusted)
+	for _, sig := range valid {
+		fmt.Printf("%-6s signature by %s\n", "ok", sig.KeyID)
+	}
+	for _, problem := range problems {
+		fmt.Printf("%-6s %s\n", "FAILED", problem)
+	}
+	if len(valid) == 0 {
+		ok = false
+	}
+
+	if verifyDir != "" {
+		names := make([]string, 0, len(bundle.Artefacts))
+		for name := range bundle.Artefacts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			a := bundle.Artefacts[name]
+			if problem := checkLocalArtefact(verifyDir, a); problem != "" {
+				ok = false
+				fmt.Printf("%-6s artefact %s: %s\n", "FAILED", name, problem)
+				continue
+			}
+			fmt.Printf("%-6s artefact %s %s\n", "ok", name, a.Digest)
+		}
+	}
+
+	if !ok {
+		fmt.Printf("\nverification failed: model %.8s has no valid signature from a trusted key matching its artefacts\n", bundle.ModelID)
+		os.Exit(1)
+	}
+	fmt.Printf("\nmodel %.8s is signed by %d trusted key(s)\n", bundle.ModelID, len(valid))
+}
+
+func RunModelsPromote(id string, stage models.Stage) {
+	id, err := resolveVersionID(id)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var promoted models.Model
+	if err := doJSON(http.MethodPost, path.Join("models", id, "promote"), nil, map[string]models.Stage{"stage": stage}, &promoted); err != nil {
+		fmt.Printf("couldn't promote model %s: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("promoted %s %s (%.8s) to %s\n", promoted.Name, promoted.Version, promoted.ID, promoted.Stage)
+}
+
+func FetchSignatureBundle(id string) (*signing.Bundle, error) {
+	var bundle signing.Bundle
+	if err := doJSON(http.MethodGet, path.Join("models", id, "signatures"), nil, nil, &bundle); err != nil {
+		return nil, err
+	}
+	return &bundle, nil
+}
+
+func readBundle(file string) (*signing.Bundle, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var bundle signing.Bundle
+	if err := json.Unmarshal(data, &bundle); err != nil {
+		return nil, fmt.Errorf("invalid bundle %s: %w", file, err)
+	}
+	return &bundle, nil
+}
+
+func writeBundle(file string, bundle *signing.Bundle) error {
+	data, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+func readTrustedKeys(files []string) ([]*signing.Key, error) {
+	var keys []*signing.Key
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read key: %w", err)
+		}
+		pub, err := signing.ParsePublicKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		keys = append(keys, &signing.Key{ID: signing.KeyID(pub), Name: file, PublicKey: string(data)})
+	}
+	return keys, nil
+}
+
+/*
+checkLocalArtefact re-hashes the copy of a in dir, returning what's wrong
+with it, if anything.
+*/
+func checkLocalArtefact(dir string, a signing.Artefact) string {
+	f, err := os.Open(filepath.Join(dir, a.FileName))
+	if err != nil {
+		return err.Error()
+	}
+	defer f.Close()
+
+	digest, _, err := seal.Digest(f)
+	if err != nil {
+		return err.Error()
+	}
+	if digest != a.Digest {
+		return fmt.Sprintf("%s has digest %s, but %s was signed", a.FileName, digest, a.Digest)
+	}
+	return ""
+}
@@ -1,25 +1,23 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
 	"net/url"
 	"os"
-	"path"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/heldtogether/traintrack/cmd/trees"
-	"github.com/heldtogether/traintrack/internal/auth"
 	"github.com/heldtogether/traintrack/internal/models"
 	"github.com/spf13/cobra"
 )
 
+var modelsFilter createdFilter
+
 func init() {
+	modelsFilter.addFlags(modelsCmd)
 	rootCmd.AddCommand(modelsCmd)
 }
 
@@ -33,7 +31,13 @@ var modelsCmd = &cobra.Command{
 
 func RunModelsLog() {
 
-	commits, err := FetchModels()
+	query, err := modelsFilter.query()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	commits, err := FetchModels(query)
 	if err != nil {
 		fmt.Printf("couldn't fetch data: %s\n", err)
 		os.Exit(1)
@@ -49,50 +53,12 @@ func RunModelsLog() {
 	}
 }
 
-func FetchModels() ([]*models.Model, error) {
-	conf, err := LoadConfig(DefaultConfigPath)
-	if err != nil {
-		return nil, err
-	}
-
-	base, err := url.Parse(conf.URL)
-	if err != nil {
-		log.Fatalf("invalid base URL in config: %s", err)
-	}
-	base.Path = path.Join(base.Path, "models")
-	url := base.String()
-
-	token, err := auth.LoadToken(auth.DefaultTokenPath)
-	if err != nil {
-		return nil, err
-	}
-	bearer := "Bearer " + token.AccessToken
-
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Add("Authorization", bearer)
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%d - %s", resp.StatusCode, http.StatusText(resp.StatusCode))
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
-	}
-
+func FetchModels(query url.Values) ([]*models.Model, error) {
 	var data []*models.Model
-	if err := json.Unmarshal(body, &data); err != nil {
+	if err := doJSON(http.MethodGet, "models", query, nil, &data); err != nil {
 		return nil, err
 	}
-
-	return data, err
+	return data, nil
 }
 
 type modelsModel struct {
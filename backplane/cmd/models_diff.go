@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/heldtogether/traintrack/internal/compare"
+	"github.com/spf13/cobra"
+)
+
+const (
+	ansiReset = "\033[0m"
+	ansiBold  = "\033[1m"
+	ansiRed   = "\033[31m"
+	ansiGreen = "\033[32m"
+)
+
+var diffNoColor bool
+
+var modelsDiffCmd = &cobra.Command{
+	Use:   "diff <a> <b>",
+	Short: "Show what changed between two model versions",
+	Long: `Show what changed between two model versions: their config, evaluation,
+the packages in their environments which differ and the lineage of the
+datasets they were trained on. Either may be an id or a prefix of one.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		RunModelsDiff(args[0], args[1])
+	},
+}
+
+func init() {
+	modelsDiffCmd.Flags().BoolVar(&diffNoColor, "no-color", false, "Don't colour the diff")
+	modelsCmd.AddCommand(modelsDiffCmd)
+}
+
+func RunModelsDiff(a, b string) {
+	ids := make([]string, 2)
+	for i, id := range []string{a, b} {
+		var err error
+		if ids[i], err = resolveVersionID(id); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+
+	c, err := FetchComparison(ids)
+	if err != nil {
+		fmt.Printf("couldn't compare models: %s\n", err)
+		os.Exit(1)
+	}
+
+	color := !diffNoColor && os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+	for _, line := range renderDiff(c, color) {
+		fmt.Println(line)
+	}
+}
+
+func FetchComparison(ids []string) (*compare.Comparison, error) {
+	var c compare.Comparison
+	query := url.Values{"ids": {strings.Join(ids, ",")}}
+	if err := doJSON(http.MethodGet, "models/compare", query, nil, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+/*
+renderDiff draws the comparison of two models as a unified diff, from
+the first to the second.
+*/
+func renderDiff(c *compare.Comparison, color bool) []string {
+	paint := func(code, s string) string {
+		if !color {
+			return s
+		}
+		return code + s + ansiReset
+	}
+
+	from, to := c.Models[0], c.Models[1]
+	lines := []string{
+		paint(ansiRed, fmt.Sprintf("--- %s %s (%.8s)", from.Name, from.Version, from.ID)),
+		paint(ansiGreen, fmt.Sprintf("+++ %s %s (%.8s)", to.Name, to.Version, to.ID)),
+	}
+
+	section := func(title string, diffs []compare.Difference, format func(key string, v any) string) {
+		lines = append(lines, "", paint(ansiBold, title))
+		if len(diffs) == 0 {
+			lines = append(lines, "  (no differences)")
+			return
+		}
+		for _, d := range diffs {
+			before, after := d.Values[0], d.Values[1]
+			if !d.Changed {
+				lines = append(lines, "  "+format(d.Key, before))
+				continue
+			}
+			if before != nil {
+				lines = append(lines, paint(ansiRed, "- "+format(d.Key, before)))
+			}
+			if after != nil {
+				lines = append(lines, paint(ansiGreen, "+ "+format(d.Key, after)))
+			}
+		}
+	}
+
+	keyValue := func(key string, v any) string {
+		return key + ": " + diffValue(v)
+	}
+	section("config", c.Config, keyValue)
+	section("evaluation", c.Evaluation, keyValue)
+	section("dependencies", c.Dependencies, func(key string, v any) string {
+		return key + "==" + diffValue(v)
+	})
+
+	lines = append(lines, "", paint(ansiBold, "dataset lineage"))
+	before, after := lineage(from.Lineage), lineage(to.Lineage)
+	if before == after {
+		lines = append(lines, "  "+before)
+	} else {
+		lines = append(lines, paint(ansiRed, "- "+before), paint(ansiGreen, "+ "+after))
+	}
+	if c.CommonDataset != nil && before != after {
+		lines = append(lines, fmt.Sprintf("  both derive from %s %s", c.CommonDataset.Name, c.CommonDataset.Version))
+	}
+
+	return lines
+}
+
+/*
+lineage describes a dataset and its ancestors, newest first.
+*/
+func lineage(versions []compare.DatasetVersion) string {
+	if len(versions) == 0 {
+		return "(no dataset)"
+	}
+
+	steps := make([]string, len(versions))
+	for i, d := range versions {
+		steps[i] = d.Name + " " + d.Version
+	}
+	return strings.Join(steps, " ← ")
+}
+
+func diffValue(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/heldtogether/traintrack/internal/provenance"
+	"github.com/heldtogether/traintrack/internal/seal"
+	"github.com/heldtogether/traintrack/internal/signing"
+	"github.com/spf13/cobra"
+)
+
+var (
+	provenanceKeyFiles []string
+	provenanceEnvelope string
+	provenanceDir      string
+	provenanceSaveTo   string
+	provenanceShow     bool
+)
+
+var modelsProvenanceCmd = &cobra.Command{
+	Use:   "provenance [id]",
+	Short: "Check how a model was made, from its signed SLSA provenance",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id := ""
+		if len(args) == 1 {
+			id = args[0]
+		}
+		RunModelsProvenance(id)
+	},
+}
+
+func init() {
+	modelsProvenanceCmd.Flags().StringArrayVar(&provenanceKeyFiles, "key", nil, "PEM encoded public key to trust (repeatable). Without one, the server's attestation key is used")
+	modelsProvenanceCmd.Flags().StringVar(&provenanceEnvelope, "envelope", "", "Verify a saved DSSE envelope instead of fetching one")
+	modelsProvenanceCmd.Flags().StringVar(&provenanceDir, "dir", "", "Also check the artefact files in this directory are the ones attested to")
+	modelsProvenanceCmd.Flags().StringVar(&provenanceSaveTo, "save", "", "Save the DSSE envelope to this file")
+	modelsProvenanceCmd.Flags().BoolVar(&provenanceShow, "show", false, "Print the attested statement")
+
+	modelsCmd.AddCommand(modelsProvenanceCmd)
+}
+
+func RunModelsProvenance(id string) {
+	var env *provenance.Envelope
+	var err error
+
+	switch {
+	case provenanceEnvelope != "":
+		env, err = readEnvelope(provenanceEnvelope)
+	case id != "":
+		if id, err = resolveVersionID(id); err == nil {
+			env, err = FetchProvenance(id)
+		}
+	default:
+		err = fmt.Errorf("give a model id or --envelope")
+	}
+	if err != nil {
+		fmt.Printf("couldn't load provenance: %s\n", err)
+		os.Exit(1)
+	}
+
+	if provenanceSaveTo != "" {
+		data, _ := json.MarshalIndent(env, "", "  ")
+		if err := os.WriteFile(provenanceSaveTo, data, 0644); err != nil {
+			fmt.Printf("couldn't save envelope: %s\n", err)
+			os.Exit(1)
+		}
+	}
+
+	stmt, err := env.Statement()
+	if err != nil {
+		fmt.Printf("couldn't read provenance: %s\n", err)
+		os.Exit(1)
+	}
+
+	if provenanceShow {
+		data, _ := json.MarshalIndent(stmt, "", "  ")
+		fmt.Println(string(data))
+	}
+
+	ok := true
+	fail := func(format string, args ...any) {
+		ok = false
+		fmt.Printf("%-6s %s\n", "FAILED", fmt.Sprintf(format, args...))
+	}
+
+	trusted, err := provenanceTrustRoots()
+	if err != nil {
+		fail("%s", err)
+	} else if signer, err := verifyEnvelope(env, trusted); err != nil {
+		fail("signature: %s", err)
+	} else {
+		fmt.Printf("%-6s signed by %s\n", "ok", signer)
+	}
+
+	run := stmt.Predicate.RunDetails
+	if id != "" && run.Metadata.InvocationID != id {
+		fail("attestation is for model %s, not %s", run.Metadata.InvocationID, id)
+	}
+	if run.Builder.ID != provenance.BuilderID {
+		fail("attestation was built by %s", run.Builder.ID)
+	}
+
+	if provenanceDir != "" {
+		for _, s := range stmt.Subject {
+			if problem := checkSubject(provenanceDir, s); problem != "" {
+				fail("artefact %s: %s", s.Name, problem)
+				continue
+			}
+			fmt.Printf("%-6s artefact %s sha256:%s\n", "ok", s.Name, s.Digest["sha256"])
+		}
+	}
+
+	printMaterials(stmt)
+
+	if !ok {
+		fmt.Printf("\nverification failed: the provenance of model %.8s can't be trusted\n", run.Metadata.InvocationID)
+		os.Exit(1)
+	}
+	fmt.Printf("\nverified the provenance of model %.8s\n", run.Metadata.InvocationID)
+}
+
+func FetchProvenance(id string) (*provenance.Envelope, error) {
+	var env provenance.Envelope
+	if err := doJSON(http.MethodGet, path.Join("models", id, "provenance"), nil, nil, &env); err != nil {
+		return nil, err
+	}
+	return &env, nil
+}
+
+/*
+provenanceTrustRoots returns the keys given with --key or, failing that,
+the key the server says it signs provenance with.
+*/
+func provenanceTrustRoots() ([]ed25519.PublicKey, error) {
+	if len(provenanceKeyFiles) == 0 {
+		fmt.Println("no --key given, trusting the server's attestation key")
+
+		var k signing.Key
+		if err := doJSON(http.MethodGet, "provenance/key", nil, nil, &k); err != nil {
+			return nil, fmt.Errorf("couldn't fetch the attestation key: %w", err)
+		}
+		pub, err := signing.ParsePublicKey([]byte(k.PublicKey))
+		if err != nil {
+			return nil, err
+		}
+		return []ed25519.PublicKey{pub}, nil
+	}
+
+	keys, err := readTrustedKeys(provenanceKeyFiles)
+	if err != nil {
+		return nil, err
+	}
+	var pubs []ed25519.PublicKey
+	for _, k := range keys {
+		pub, _ := signing.ParsePublicKey([]byte(k.PublicKey))
+		pubs = append(pubs, pub)
+	}
+	return pubs, nil
+}
+
+func verifyEnvelope(env *provenance.Envelope, trusted []ed25519.PublicKey) (string, error) {
+	err := provenance.ErrUnsigned
+	for _, pub := range trusted {
+		if err = env.Verify(pub); err == nil {
+			return signing.KeyID(pub), nil
+		}
+	}
+	return "", err
+}
+
+func readEnvelope(file string) (*provenance.Envelope, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var env provenance.Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, fmt.Errorf("invalid envelope %s: %w", file, err)
+	}
+	return &env, nil
+}
+
+/*
+checkSubject re-hashes the copy of s in dir, returning what's wrong with
+it, if anything.
+*/
+func checkSubject(dir string, s provenance.ResourceDescriptor) string {
+	f, err := os.Open(filepath.Join(dir, s.Name))
+	if err != nil {
+		return err.Error()
+	}
+	defer f.Close()
+
+	digest, _, err := seal.Digest(f)
+	if err != nil {
+		return err.Error()
+	}
+	want := seal.Algorithm + ":" + s.Digest[seal.Algorithm]
+	if digest != want {
+		return fmt.Sprintf("has digest %s, but %s was attested to", digest, want)
+	}
+	return ""
+}
+
+func printMaterials(stmt *provenance.Statement) {
+	var code, packages []string
+	fmt.Println()
+	for _, d := range stmt.Predicate.BuildDefinition.ResolvedDependencies {
+		switch {
+		case d.Name == "dataset":
+			fmt.Printf("dataset   %v %v (%s)\n", d.Annotations["name"], d.Annotations["version"], strings.TrimPrefix(d.URI, "traintrack:datasets/"))
+		case strings.HasPrefix(d.URI, "traintrack:datasets/"):
+			fmt.Printf("  input   %s sha256:%s\n", d.Name, d.Digest["sha256"])
+		case strings.HasPrefix(d.URI, "pkg:"):
+			packages = append(packages, strings.TrimPrefix(d.URI, "pkg:pypi/"))
+		case d.MediaType == "text/x-python":
+			code = append(code, d.Name)
+		}
+	}
+	if len(code) > 0 {
+		fmt.Printf("code      %s\n", strings.Join(code, ", "))
+	}
+	if len(packages) > 0 {
+		fmt.Printf("packages  %d pinned\n", len(packages))
+	}
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/runs"
+	"github.com/spf13/cobra"
+)
+
+/*
+staleAfter is how long a running run can go without a heartbeat before
+it's shown as stale, as its trainer has probably died.
+*/
+const staleAfter = 10 * time.Minute
+
+var (
+	runsStatus []string
+	runsAll    bool
+)
+
+var runsCmd = &cobra.Command{
+	Use:   "runs",
+	Short: "List and inspect training runs",
+}
+
+var runsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List running and failed runs",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		statuses := runsStatus
+		if runsAll {
+			statuses = nil
+		}
+		RunRunsList(statuses)
+	},
+}
+
+var runsShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a run's params, tags and artefacts",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		RunRunShow(args[0])
+	},
+}
+
+var runsFailCmd = &cobra.Command{
+	Use:   "fail <id>",
+	Short: "Mark a run whose trainer died as failed",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		RunRunFail(args[0])
+	},
+}
+
+func init() {
+	runsListCmd.Flags().StringSliceVar(&runsStatus, "status", []string{string(runs.StatusRunning), string(runs.StatusFailed)}, "Only list runs with these statuses")
+	runsListCmd.Flags().BoolVar(&runsAll, "all", false, "List runs with any status")
+
+	runsCmd.AddCommand(runsListCmd)
+	runsCmd.AddCommand(runsShowCmd)
+	runsCmd.AddCommand(runsFailCmd)
+	rootCmd.AddCommand(runsCmd)
+}
+
+func RunRunsList(statuses []string) {
+	rs, err := fetchRuns(statuses)
+	if err != nil {
+		fmt.Printf("couldn't fetch runs: %s\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tSTATUS\tSTARTED\tLAST HEARTBEAT\tARTEFACTS\tMODEL\tBY")
+	for _, r := range rs {
+		model := ""
+		if r.ModelID != nil {
+			model = *r.ModelID
+		}
+		fmt.Fprintf(w, "%.8s\t%s\t%s\t%s\t%s\t%d\t%.8s\t%s\n",
+			r.ID,
+			r.Name,
+			runStatus(r),
+			r.StartedAt.Local().Format(time.DateTime),
+			r.HeartbeatAt.Local().Format(time.DateTime),
+			len(r.UploadIds),
+			model,
+			r.CreatedBy,
+		)
+	}
+	w.Flush()
+}
+
+func RunRunShow(id string) {
+	id, err := resolveRunID(id)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var r runs.Run
+	if err := doJSON(http.MethodGet, path.Join("runs", id), nil, nil, &r); err != nil {
+		fmt.Printf("couldn't fetch run %s: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Run %s (%s)\n", r.ID, r.Name)
+	fmt.Printf("Status: %s\n", runStatus(&r))
+	fmt.Printf("Started %s by %s\n", r.StartedAt.Local().Format(time.DateTime), r.CreatedBy)
+	if r.EndedAt != nil {
+		fmt.Printf("Ended %s\n", r.EndedAt.Local().Format(time.DateTime))
+	} else {
+		fmt.Printf("Last heartbeat %s\n", r.HeartbeatAt.Local().Format(time.DateTime))
+	}
+	if r.ModelID != nil {
+		fmt.Printf("Finalized as model %s\n", *r.ModelID)
+	}
+
+	if len(r.Params) > 0 {
+		var params any
+		if err := json.Unmarshal(r.Params, &params); err == nil {
+			data, _ := json.MarshalIndent(params, "", "  ")
+			fmt.Printf("\nParams:\n%s\n", data)
+		}
+	}
+
+	if len(r.Tags) > 0 {
+		fmt.Println("\nTags:")
+		for _, k := range sortedKeys(r.Tags) {
+			fmt.Printf("  %s=%s\n", k, r.Tags[k])
+		}
+	}
+
+	if len(r.UploadIds) > 0 {
+		fmt.Println("\nArtefacts:")
+		for _, k := range sortedKeys(r.UploadIds) {
+			fmt.Printf("  %s (upload %s)\n", k, r.UploadIds[k])
+		}
+	}
+}
+
+func RunRunFail(id string) {
+	id, err := resolveRunID(id)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var r runs.Run
+	body := map[string]runs.Status{"status": runs.StatusFailed}
+	if err := doJSON(http.MethodPatch, path.Join("runs", id), nil, body, &r); err != nil {
+		fmt.Printf("couldn't fail run %s: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("marked run %.8s (%s) as failed\n", r.ID, r.Name)
+}
+
+/*
+runStatus returns r's status, noting when a running run has stopped
+sending heartbeats.
+*/
+func runStatus(r *runs.Run) string {
+	if r.Status == runs.StatusRunning && time.Since(r.HeartbeatAt) > staleAfter {
+		return "running (stale)"
+	}
+	return string(r.Status)
+}
+
+func fetchRuns(statuses []string) ([]*runs.Run, error) {
+	var query url.Values
+	if len(statuses) > 0 {
+		query = url.Values{"status": {strings.Join(statuses, ",")}}
+	}
+
+	var rs []*runs.Run
+	if err := doJSON(http.MethodGet, "runs", query, nil, &rs); err != nil {
+		return nil, err
+	}
+	return rs, nil
+}
+
+func resolveRunID(prefix string) (string, error) {
+	if len(prefix) == 36 {
+		return prefix, nil
+	}
+
+	rs, err := fetchRuns(nil)
+	if err != nil {
+		return "", fmt.Errorf("couldn't fetch runs: %w", err)
+	}
+
+	var matches []string
+	for _, r := range rs {
+		if strings.HasPrefix(r.ID, prefix) {
+			matches = append(matches, r.ID)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no run matches %q", prefix)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%q is ambiguous, it matches %s", prefix, strings.Join(matches, ", "))
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
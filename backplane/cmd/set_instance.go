@@ -31,6 +31,7 @@ func RunSetBackend(url string) {
 	conf := &InstanceConfig{
 		URL: url,
 	}
-	conf = conf.refreshAuthConfig()
-	SaveConfig(DefaultConfigPath, conf)
+	tc := CurrentContext()
+	SaveConfig(tc.InstanceConfigPath(), conf)
+	conf.refreshAuthConfig(tc)
 }
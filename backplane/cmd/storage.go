@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/heldtogether/traintrack/internal/kms"
+	"github.com/heldtogether/traintrack/internal/uploads"
+	"github.com/joho/godotenv"
+	"github.com/spf13/cobra"
+)
+
+var (
+	storageKeyOut string
+	storageDir    string
+)
+
+var storageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "Manage how the backplane stores artefacts at rest",
+}
+
+var storageGenerateKeyCmd = &cobra.Command{
+	Use:   "generate-key",
+	Short: "Generate a master key to encrypt artefacts with",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		RunStorageGenerateKey(storageKeyOut)
+	},
+}
+
+var storageRewrapCmd = &cobra.Command{
+	Use:   "rewrap",
+	Short: "Re-wrap every artefact's data key with the current master key, encrypting any stored in plain text",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		RunStorageRewrap(storageDir)
+	},
+}
+
+func init() {
+	storageGenerateKeyCmd.Flags().StringVar(&storageKeyOut, "out", "traintrack-master.key", "File to write the key to")
+	storageRewrapCmd.Flags().StringVar(&storageDir, "dir", "./files/", "Directory the backplane stores artefacts in")
+
+	storageCmd.AddCommand(storageGenerateKeyCmd)
+	storageCmd.AddCommand(storageRewrapCmd)
+	rootCmd.AddCommand(storageCmd)
+}
+
+func RunStorageGenerateKey(out string) {
+	key, err := kms.GenerateKey()
+	if err != nil {
+		fmt.Printf("couldn't generate key: %s\n", err)
+		os.Exit(1)
+	}
+
+	// Refuse to overwrite an existing master key; anything it wrapped
+	// would be lost.
+	f, err := os.OpenFile(out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		fmt.Printf("couldn't write master key: %s\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+	if _, err := f.Write(key); err != nil {
+		fmt.Printf("couldn't write master key: %s\n", err)
+		os.Exit(1)
+	}
+
+	keyring, err := kms.LoadKeyring(out)
+	if err != nil {
+		fmt.Printf("couldn't read back master key: %s\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("generated master key %s in %s (keep this secret)\n", keyring.CurrentKeyID(), out)
+	fmt.Printf("\nRotate to it by putting it first in TRAINTRACK_MASTER_KEYS, then run: traintrack storage rewrap\n")
+}
+
+func RunStorageRewrap(dir string) {
+	// Pick up the backplane's configuration the same way serve does.
+	if os.Getenv("TRAINTRACK_MASTER_KEYS") == "" && os.Getenv("TRAINTRACK_KMS_PLUGIN") == "" {
+		godotenv.Load()
+	}
+
+	keys, err := kms.FromEnv()
+	if err != nil {
+		fmt.Printf("couldn't load master keys: %s\n", err)
+		os.Exit(1)
+	}
+	if keys == nil {
+		fmt.Println("no master key is configured: set TRAINTRACK_MASTER_KEYS or TRAINTRACK_KMS_PLUGIN")
+		os.Exit(1)
+	}
+
+	store := &uploads.EncryptedStore{
+		FileSystemStore: &uploads.FileSystemStore{BaseDir: dir},
+		Keys:            keys,
+	}
+
+	counts := map[uploads.RewrapResult]int{}
+	failed := 0
+	err = store.RewrapAll(func(path string, result uploads.RewrapResult, err error) {
+		if err != nil {
+			failed++
+			fmt.Printf("%-9s %s: %s\n", "FAILED", path, err)
+			return
+		}
+		counts[result]++
+		if result != uploads.RewrapSkipped {
+			fmt.Printf("%-9s %s\n", result, path)
+		}
+	})
+
+	fmt.Printf("\n%d rewrapped, %d encrypted, %d already current with %s\n",
+		counts[uploads.RewrapRewrapped], counts[uploads.RewrapEncrypted], counts[uploads.RewrapSkipped], keys.CurrentKeyID())
+	if failed > 0 {
+		fmt.Printf("%d file(s) could not be rewrapped\n", failed)
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Printf("couldn't rewrap %s: %s\n", dir, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var deleteForce bool
+
+/*
+trashCommands adds delete, trash, restore and purge commands for kind,
+either "datasets" or "models", to parent.
+*/
+func trashCommands(parent *cobra.Command, kind string) {
+	deleteCmd := &cobra.Command{
+		Use:   "delete <id>",
+		Short: fmt.Sprintf("Move one of the %s to the trash", kind),
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			RunDelete(kind, args[0], deleteForce)
+		},
+	}
+	deleteCmd.Flags().BoolVar(&deleteForce, "force", false, "Delete it even though other versions depend on it")
+
+	parent.AddCommand(deleteCmd)
+	parent.AddCommand(&cobra.Command{
+		Use:   "trash",
+		Short: fmt.Sprintf("List the %s in the trash", kind),
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			RunTrash(kind)
+		},
+	})
+	parent.AddCommand(&cobra.Command{
+		Use:   "restore <id>",
+		Short: "Take a version back out of the trash",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			RunTrashAction(kind, "restore", args[0])
+		},
+	})
+	parent.AddCommand(&cobra.Command{
+		Use:   "purge <id>",
+		Short: "Permanently remove the files of a version in the trash",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			RunTrashAction(kind, "purge", args[0])
+		},
+	})
+}
+
+func init() {
+	trashCommands(datasetsCmd, "datasets")
+	trashCommands(modelsCmd, "models")
+}
+
+/*
+trashed is what the trash commands need of a dataset or model.
+*/
+type trashed struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Version   string     `json:"version"`
+	DeletedAt *time.Time `json:"deleted_at"`
+	PurgedAt  *time.Time `json:"purged_at"`
+}
+
+func RunDelete(kind string, id string, force bool) {
+	id, err := resolveVersionID(id)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var query url.Values
+	if force {
+		query = url.Values{"force": {"true"}}
+	}
+
+	var deleted trashed
+	if err := doJSON(http.MethodDelete, path.Join(kind, id), query, nil, &deleted); err != nil {
+		fmt.Printf("couldn't delete %s: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("moved %s %s (%.8s) to the trash\n", deleted.Name, deleted.Version, deleted.ID)
+}
+
+func RunTrash(kind string) {
+	items, err := fetchTrash(kind)
+	if err != nil {
+		fmt.Printf("couldn't fetch the trash: %s\n", err)
+		os.Exit(1)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tVERSION\tDELETED\tPURGED")
+	for _, t := range items {
+		deleted, purged := "", ""
+		if t.DeletedAt != nil {
+			deleted = t.DeletedAt.Local().Format(time.DateTime)
+		}
+		if t.PurgedAt != nil {
+			purged = t.PurgedAt.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%.8s\t%s\t%s\t%s\t%s\n", t.ID, t.Name, t.Version, deleted, purged)
+	}
+	w.Flush()
+}
+
+/*
+RunTrashAction restores or purges the version id, which may be a prefix
+of the id of something in the trash.
+*/
+func RunTrashAction(kind string, action string, id string) {
+	id, err := resolveTrashedID(kind, id)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	var changed trashed
+	if err := doJSON(http.MethodPost, path.Join(kind, id, action), nil, nil, &changed); err != nil {
+		fmt.Printf("couldn't %s %s: %s\n", action, id, err)
+		os.Exit(1)
+	}
+
+	if action == "purge" {
+		fmt.Printf("purged the files of %s %s (%.8s)\n", changed.Name, changed.Version, changed.ID)
+		return
+	}
+	fmt.Printf("restored %s %s (%.8s)\n", changed.Name, changed.Version, changed.ID)
+}
+
+func fetchTrash(kind string) ([]*trashed, error) {
+	var data []*trashed
+	if err := doJSON(http.MethodGet, path.Join(kind, "trash"), nil, nil, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+/*
+resolveTrashedID is resolveVersionID for things in the trash, which
+aren't listed anywhere else.
+*/
+func resolveTrashedID(kind string, prefix string) (string, error) {
+	if len(prefix) == 36 {
+		return prefix, nil
+	}
+
+	items, err := fetchTrash(kind)
+	if err != nil {
+		return "", fmt.Errorf("couldn't fetch the trash: %w", err)
+	}
+
+	var matches []string
+	for _, t := range items {
+		if strings.HasPrefix(t.ID, prefix) {
+			matches = append(matches, t.ID)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("nothing in the %s trash matches %q", strings.TrimSuffix(kind, "s"), prefix)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%q is ambiguous, it matches %s", prefix, strings.Join(matches, ", "))
+	}
+}
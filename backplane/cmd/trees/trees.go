@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 )
 
 type Treeable interface {
@@ -12,6 +13,9 @@ type Treeable interface {
 	GetDescription() string
 	GetVersion() string
 	GetParent() *string
+	GetCreatedAt() time.Time
+	GetCreatedBy() string
+	GetMarkers() []string
 }
 
 type treeNode[T Treeable] struct {
@@ -72,26 +76,58 @@ func RenderTree[T Treeable](commits []*treeNode[T], prefix string, suffix string
 				tree = append(tree, "\n---\n")
 			}
 		}
-		tree = append(
-			tree,
-			fmt.Sprintf(
-				"%s%s* %.8s - %s %s: %s",
-				prefix, pre,
-				c.Commit.GetID(),
-				c.Commit.GetName(),
-				c.Commit.GetVersion(),
-				c.Commit.GetDescription(),
-			),
-		)
+		tree = append(tree, prefix+pre+"* "+label(c.Commit)+provenance(c.Commit)+markers(c.Commit))
 		if len(c.Children) > 0 {
 			if len(c.Children) > 1 {
-				tree = append(tree, fmt.Sprintf("%s|%s", pre, strings.Repeat("\\ ", len(c.Children)-1)))
+				tree = append(tree, fmt.Sprintf("%s|%s", pre, strings.TrimRight(strings.Repeat("\\ ", len(c.Children)-1), " ")))
 			}
 			tree = append(tree, RenderTree(c.Children, fmt.Sprintf("%s%s", prefix, pre), suffix)...)
 		}
 	}
-	for i, line := range tree {
-		tree[i] = strings.Trim(line, " -:")
-	}
 	return tree
 }
+
+/*
+label names a commit, e.g. "1a2b3c4d - prices 1.0.1: clean", leaving out
+whatever isn't set. The description is kept exactly as given.
+*/
+func label(c Treeable) string {
+	l := fmt.Sprintf("%.8s", c.GetID())
+	if nv := strings.TrimSpace(c.GetName() + " " + c.GetVersion()); nv != "" {
+		l += " - " + nv
+	}
+	if d := c.GetDescription(); d != "" {
+		l += ": " + d
+	}
+	return l
+}
+
+/*
+provenance describes when and by whom a commit was created, e.g.
+" (2025-07-02 by dev|1)", leaving out whatever isn't known.
+*/
+func provenance(c Treeable) string {
+	var parts []string
+	if at := c.GetCreatedAt(); !at.IsZero() {
+		parts = append(parts, at.Local().Format(time.DateOnly))
+	}
+	if by := c.GetCreatedBy(); by != "" {
+		parts = append(parts, "by "+by)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " (" + strings.Join(parts, " ") + ")"
+}
+
+/*
+markers flags anything needing attention about a commit, e.g.
+" [tainted]".
+*/
+func markers(c Treeable) string {
+	m := c.GetMarkers()
+	if len(m) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(m, ", ") + "]"
+}
@@ -3,6 +3,7 @@ package trees
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/heldtogether/traintrack/internal/datasets"
 )
@@ -132,3 +133,60 @@ func TestRenderTree2Roots(t *testing.T) {
 		t.Errorf("fail: wanted\n%s\ngot\n%s\n", expected, out)
 	}
 }
+
+func TestRenderTreeProvenance(t *testing.T) {
+	created := time.Date(2025, 7, 2, 12, 0, 0, 0, time.Local)
+	c := []*datasets.Dataset{
+		{ID: "A", Name: "prices", Version: "1.0.0", Description: "raw", CreatedAt: created, CreatedBy: "dev|1"},
+		{ID: "B", Parent: stringPtr("A"), Name: "prices", Version: "1.0.1", Description: "clean", CreatedAt: created},
+		{ID: "C", Parent: stringPtr("B"), CreatedBy: "dev|2"},
+	}
+
+	expected :=
+		`* A - prices 1.0.0: raw (2025-07-02 by dev|1)
+* B - prices 1.0.1: clean (2025-07-02)
+* C (by dev|2)`
+
+	tree := BuildTree(c)
+	out := strings.Join(RenderTree(tree, "", ""), "\n")
+	if out != expected {
+		t.Errorf("fail: wanted\n%s\ngot\n%s\n", expected, out)
+	}
+}
+
+func TestRenderTreeMarkers(t *testing.T) {
+	erasure := "e1"
+	c := []*datasets.Dataset{
+		{ID: "A", Name: "prices", Version: "1.0.0", TaintedBy: &erasure},
+		{ID: "B", Parent: stringPtr("A"), Name: "prices", Version: "1.0.1", Held: true},
+		{ID: "C", Parent: stringPtr("B"), Name: "prices", Version: "1.0.2", TaintedBy: &erasure, Held: true},
+	}
+
+	expected :=
+		`* A - prices 1.0.0 [tainted]
+* B - prices 1.0.1 [held]
+* C - prices 1.0.2 [tainted, held]`
+
+	tree := BuildTree(c)
+	out := strings.Join(RenderTree(tree, "", ""), "\n")
+	if out != expected {
+		t.Errorf("fail: wanted\n%s\ngot\n%s\n", expected, out)
+	}
+}
+
+func TestRenderTreeKeepsDescription(t *testing.T) {
+	c := []*datasets.Dataset{
+		{ID: "A", Name: "prices", Version: "1.0.0", Description: "v2 - fix:"},
+		{ID: "B", Parent: stringPtr("A"), Name: "prices", Version: "1.0.1", Description: "-- trailing -- ", Held: true},
+	}
+
+	expected :=
+		`* A - prices 1.0.0: v2 - fix:
+* B - prices 1.0.1: -- trailing --  [held]`
+
+	tree := BuildTree(c)
+	out := strings.Join(RenderTree(tree, "", ""), "\n")
+	if out != expected {
+		t.Errorf("fail: wanted\n%s\ngot\n%s\n", expected, out)
+	}
+}
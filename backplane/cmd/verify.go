@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/seal"
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <id>",
+	Short: "Check a dataset or model version, and its history, hasn't been tampered with",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		RunVerify(args[0])
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+}
+
+func RunVerify(id string) {
+	id, err := resolveVersionID(id)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	report, err := FetchVerification(id)
+	if err != nil {
+		fmt.Printf("couldn't verify %s: %s\n", id, err)
+		os.Exit(1)
+	}
+
+	for _, res := range report.Chain {
+		status := "ok"
+		switch {
+		case !res.Valid:
+			status = "FAILED"
+		case res.Purged:
+			status = "purged"
+		}
+		fmt.Printf("%-6s %s %.8s %s\n", status, res.Kind, res.ID, res.Seal)
+		for _, problem := range res.Problems {
+			fmt.Printf("         - %s\n", problem)
+		}
+	}
+
+	if !report.Valid {
+		fmt.Println("\nverification failed: the history has been modified since it was sealed")
+		os.Exit(1)
+	}
+	fmt.Printf("\nverified %d version(s)\n", len(report.Chain))
+}
+
+/*
+FetchVerification asks the backplane to verify id, trying it as a dataset
+first and then as a model.
+*/
+func FetchVerification(id string) (*seal.Report, error) {
+	var report seal.Report
+
+	err := doJSON(http.MethodGet, path.Join("datasets", id, "verify"), nil, nil, &report)
+	var apiErr *internal.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusNotFound {
+		err = doJSON(http.MethodGet, path.Join("models", id, "verify"), nil, nil, &report)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+/*
+resolveVersionID expands an abbreviated ID, as shown by `traintrack
+datasets` and `traintrack models`, to the full ID of the version.
+*/
+func resolveVersionID(prefix string) (string, error) {
+	if len(prefix) == 36 {
+		return prefix, nil
+	}
+
+	var matches []string
+
+	ds, err := FetchDatasets(nil)
+	if err != nil {
+		return "", fmt.Errorf("couldn't fetch datasets: %w", err)
+	}
+	for _, d := range ds {
+		if strings.HasPrefix(d.ID, prefix) {
+			matches = append(matches, d.ID)
+		}
+	}
+
+	ms, err := FetchModels(nil)
+	if err != nil {
+		return "", fmt.Errorf("couldn't fetch models: %w", err)
+	}
+	for _, m := range ms {
+		if strings.HasPrefix(m.ID, prefix) {
+			matches = append(matches, m.ID)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no dataset or model matches %q", prefix)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("%q is ambiguous, it matches %s", prefix, strings.Join(matches, ", "))
+	}
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/spf13/cobra"
+)
+
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show who the CLI is logged in as",
+	Run: func(cmd *cobra.Command, args []string) {
+		RunWhoami()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(whoamiCmd)
+}
+
+func RunWhoami() {
+	tc := CurrentContext()
+
+	token, err := auth.LoadToken(tc.TokenPath())
+	if err != nil {
+		fmt.Println(auth.ErrLoginRequired)
+		os.Exit(1)
+	}
+
+	fmt.Println("Context:", tc.Name)
+
+	if raw, ok := token.Extra("id_token").(string); ok {
+		claims, err := auth.UnverifiedClaims(raw)
+		if err != nil {
+			fmt.Printf("couldn't read ID token: %s\n", err)
+		} else {
+			name, _ := claims["name"].(string)
+			email, _ := claims["email"].(string)
+			sub, _ := claims["sub"].(string)
+			fmt.Printf("Logged in as: %s <%s> (%s)\n", name, email, sub)
+		}
+	}
+
+	var me auth.Identity
+	if err := doJSON(http.MethodGet, "me", nil, nil, &me); err != nil {
+		fmt.Printf("couldn't confirm identity with the backplane: %s\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("Backplane sees:", me.Subject)
+	if me.Tenant != "" {
+		fmt.Println("Tenant:", me.Tenant)
+	}
+	roles := "(none)"
+	if len(me.Roles) > 0 {
+		roles = strings.Join(me.Roles, ", ")
+	}
+	fmt.Println("Roles:", roles)
+}
@@ -0,0 +1,18 @@
+/*
+Package audit records an append-only log of who did what to which
+resource, and serves it back over HTTP.
+
+Events are built from the request context, which carries the verified
+identity (see auth.NewContext) and request details like IP address and
+request ID (see WithRequestInfo):
+
+	e := audit.NewEvent(ctx, audit.ActionCreate, audit.ResourceDataset, id)
+	err := store.RecordWithQuerier(ctx, tx, e)
+
+Mutations should be recorded in the same transaction as the change they
+describe, so the log can't miss or invent one. The log is listed with:
+
+	handler := NewHandler(store)
+	http.HandleFunc("/audit", handler.Audit)
+*/
+package audit
@@ -0,0 +1,113 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/auth"
+)
+
+type Action string
+
+const (
+	ActionCreate   Action = "create"
+	ActionUpdate   Action = "update"
+	ActionDelete   Action = "delete"
+	ActionPromote  Action = "promote"
+	ActionDownload Action = "download"
+	ActionSign     Action = "sign"
+	ActionRevoke   Action = "revoke"
+	ActionPurge    Action = "purge"
+	ActionRestore  Action = "restore"
+	ActionErase    Action = "erase"
+	ActionHold     Action = "hold"
+	ActionRelease  Action = "release"
+	ActionFinalize Action = "finalize"
+)
+
+type ResourceType string
+
+const (
+	ResourceDataset    ResourceType = "dataset"
+	ResourceModel      ResourceType = "model"
+	ResourceUpload     ResourceType = "upload"
+	ResourceSigningKey ResourceType = "signing_key"
+	ResourceRun        ResourceType = "run"
+)
+
+type Event struct {
+	ID           int64           `json:"id"`
+	OccurredAt   time.Time       `json:"occurred_at"`
+	Action       Action          `json:"action"`
+	ResourceType ResourceType    `json:"resource_type"`
+	ResourceID   string          `json:"resource_id"`
+	Actor        string          `json:"actor"`
+	Tenant       string          `json:"tenant,omitempty"`
+	IP           string          `json:"ip,omitempty"`
+	UserAgent    string          `json:"user_agent,omitempty"`
+	RequestID    string          `json:"request_id,omitempty"`
+	Details      json.RawMessage `json:"details,omitempty"`
+}
+
+/*
+RequestInfo holds the details of the HTTP request that caused an event.
+*/
+type RequestInfo struct {
+	RequestID string
+	IP        string
+	UserAgent string
+}
+
+type requestInfoKey struct{}
+
+/*
+WithRequestInfo returns a copy of ctx carrying info.
+*/
+func WithRequestInfo(ctx context.Context, info RequestInfo) context.Context {
+	return context.WithValue(ctx, requestInfoKey{}, info)
+}
+
+/*
+RequestInfoFromContext returns the RequestInfo stored in ctx, if any.
+*/
+func RequestInfoFromContext(ctx context.Context) (RequestInfo, bool) {
+	info, ok := ctx.Value(requestInfoKey{}).(RequestInfo)
+	return info, ok
+}
+
+/*
+NewEvent builds an Event for action on the given resource, filling in the
+actor, tenant and request details from ctx.
+*/
+func NewEvent(ctx context.Context, action Action, resourceType ResourceType, resourceID string) *Event {
+	e := &Event{
+		Action:       action,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+	}
+
+	if id, ok := auth.IdentityFromContext(ctx); ok {
+		e.Actor = id.Subject
+		e.Tenant = id.Tenant
+	}
+
+	if info, ok := RequestInfoFromContext(ctx); ok {
+		e.IP = info.IP
+		e.UserAgent = info.UserAgent
+		e.RequestID = info.RequestID
+	}
+
+	return e
+}
+
+/*
+WithDetails attaches arbitrary details to the event. Details that can't be
+marshalled are dropped rather than failing the event.
+*/
+func (e *Event) WithDetails(details any) *Event {
+	if data, err := json.Marshal(details); err == nil {
+		e.Details = data
+	}
+	return e
+}
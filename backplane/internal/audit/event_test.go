@@ -0,0 +1,43 @@
+package audit
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/heldtogether/traintrack/internal/auth"
+)
+
+func TestNewEvent(t *testing.T) {
+	ctx := auth.NewContext(context.Background(), &auth.Identity{Subject: "dev|1", Tenant: "acme"})
+	ctx = WithRequestInfo(ctx, RequestInfo{RequestID: "req-1", IP: "10.0.0.1", UserAgent: "python-requests"})
+
+	e := NewEvent(ctx, ActionCreate, ResourceModel, "m1").WithDetails(map[string]string{"version": "1.0.0"})
+
+	want := Event{
+		Action:       ActionCreate,
+		ResourceType: ResourceModel,
+		ResourceID:   "m1",
+		Actor:        "dev|1",
+		Tenant:       "acme",
+		IP:           "10.0.0.1",
+		UserAgent:    "python-requests",
+		RequestID:    "req-1",
+	}
+	got := *e
+	got.Details = nil
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+	if string(e.Details) != `{"version":"1.0.0"}` {
+		t.Errorf("unexpected details %s", e.Details)
+	}
+}
+
+func TestNewEventWithoutContext(t *testing.T) {
+	e := NewEvent(context.Background(), ActionDownload, ResourceUpload, "u1")
+
+	if e.Actor != "" || e.RequestID != "" {
+		t.Errorf("expected empty actor and request details, got %+v", e)
+	}
+}
@@ -0,0 +1,130 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/auth"
+)
+
+/*
+Lister allows audit events to be listed.
+*/
+type Lister interface {
+	List(ctx context.Context, f Filter) ([]*Event, error)
+}
+
+type Handler struct {
+	l Lister
+}
+
+func NewHandler(l Lister) *Handler {
+	return &Handler{
+		l: l,
+	}
+}
+
+/*
+Audit routes and handles all requests for the audit log. It should be
+registered on the router under something sensible, like /audit.
+*/
+func (h *Handler) Audit(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.List(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusMethodNotAllowed,
+			Message: "Method not allowed",
+			Reason:  "",
+		})
+	}
+}
+
+/*
+List returns audit events, most recent first, filtered by the query
+parameters actor, action, resource_type, resource_id, since and until
+(RFC 3339), limit and offset. Only auditors and admins may read the log,
+and they only ever see their own tenant's events, or the events recorded
+without a tenant if they don't belong to one.
+*/
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	id, ok := auth.IdentityFromContext(r.Context())
+	if !ok || !id.HasRole(auth.RoleAuditor, auth.RoleAdmin) {
+		log.Printf("refused to list audit events: caller isn't an auditor or admin")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusForbidden,
+			Message: "Failed to list audit events",
+			Reason:  "requires the auditor or admin role",
+		})
+		return
+	}
+
+	f, err := parseFilter(r)
+	if err != nil {
+		log.Printf("failed to parse audit filter: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to list audit events",
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	f.Tenant = &id.Tenant
+
+	es, err := h.l.List(r.Context(), f)
+	if err != nil {
+		log.Printf("failed to list audit events: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to list audit events",
+			Reason:  err.Error(),
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(es)
+}
+
+func parseFilter(r *http.Request) (Filter, error) {
+	q := r.URL.Query()
+
+	f := Filter{
+		Actor:        q.Get("actor"),
+		Action:       Action(q.Get("action")),
+		ResourceType: ResourceType(q.Get("resource_type")),
+		ResourceID:   q.Get("resource_id"),
+	}
+
+	for name, dst := range map[string]**time.Time{"since": &f.Since, "until": &f.Until} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, fmt.Errorf("invalid %s: %s", name, err)
+			}
+			*dst = &t
+		}
+	}
+
+	for name, dst := range map[string]*int{"limit": &f.Limit, "offset": &f.Offset} {
+		if v := q.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return f, fmt.Errorf("invalid %s: %q", name, v)
+			}
+			*dst = n
+		}
+	}
+
+	return f, nil
+}
@@ -0,0 +1,160 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/auth"
+)
+
+type mockLister struct {
+	ListFn func(f Filter) ([]*Event, error)
+}
+
+func (m *mockLister) List(_ context.Context, f Filter) ([]*Event, error) {
+	return m.ListFn(f)
+}
+
+func TestAuditHandler(t *testing.T) {
+	occurred := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	auditor := &auth.Identity{Subject: "dev|1", Roles: []string{"auditor"}}
+
+	tests := []struct {
+		name             string
+		method           string
+		url              string
+		identity         *auth.Identity
+		listFn           func(f Filter) ([]*Event, error)
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:     "GET success with filters",
+			method:   http.MethodGet,
+			url:      "/audit?actor=dev%7C1&action=download&since=2025-07-01T00:00:00Z&limit=5",
+			identity: auditor,
+			listFn: func(f Filter) ([]*Event, error) {
+				if f.Actor != "dev|1" || f.Action != ActionDownload || f.Since == nil || f.Limit != 5 {
+					return nil, errors.New("filter not parsed")
+				}
+				return []*Event{{ID: 1, OccurredAt: occurred, Action: ActionDownload, ResourceType: ResourceUpload, ResourceID: "u1", Actor: "dev|1"}}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `[{"id": 1, "occurred_at": "2025-07-01T12:00:00Z", "action": "download", "resource_type": "upload", "resource_id": "u1", "actor": "dev|1"}]`,
+		},
+		{
+			name:     "GET is scoped to the caller's tenant",
+			method:   http.MethodGet,
+			url:      "/audit",
+			identity: &auth.Identity{Subject: "dev|1", Tenant: "acme", Roles: []string{"admin"}},
+			listFn: func(f Filter) ([]*Event, error) {
+				if f.Tenant == nil || *f.Tenant != "acme" {
+					return nil, errors.New("not scoped to tenant")
+				}
+				return []*Event{}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `[]`,
+		},
+		{
+			name:     "GET without a tenant only sees events without one",
+			method:   http.MethodGet,
+			url:      "/audit",
+			identity: auditor,
+			listFn: func(f Filter) ([]*Event, error) {
+				if f.Tenant == nil || *f.Tenant != "" {
+					return nil, errors.New("not scoped to tenant")
+				}
+				return []*Event{}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `[]`,
+		},
+		{
+			name:             "GET failure - not an auditor",
+			method:           http.MethodGet,
+			url:              "/audit",
+			identity:         &auth.Identity{Subject: "dev|1", Tenant: "acme", Roles: []string{"trainer"}},
+			expectedStatus:   http.StatusForbidden,
+			expectedContains: `{"code": 403, "error": "Failed to list audit events", "reason": "requires the auditor or admin role"}`,
+		},
+		{
+			name:             "GET failure - no identity",
+			method:           http.MethodGet,
+			url:              "/audit",
+			expectedStatus:   http.StatusForbidden,
+			expectedContains: `{"code": 403, "error": "Failed to list audit events", "reason": "requires the auditor or admin role"}`,
+		},
+		{
+			name:             "GET failure - bad since",
+			method:           http.MethodGet,
+			identity:         auditor,
+			url:              "/audit?since=yesterday",
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to list audit events", "reason": "invalid since: parsing time \"yesterday\" as \"2006-01-02T15:04:05Z07:00\": cannot parse \"yesterday\" as \"2006\""}`,
+		},
+		{
+			name:             "GET failure - bad limit",
+			method:           http.MethodGet,
+			identity:         auditor,
+			url:              "/audit?limit=-1",
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to list audit events", "reason": "invalid limit: \"-1\""}`,
+		},
+		{
+			name:     "GET failure - store failed",
+			method:   http.MethodGet,
+			url:      "/audit",
+			identity: auditor,
+			listFn: func(f Filter) ([]*Event, error) {
+				return nil, errors.New("boom")
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedContains: `{"code": 500, "error": "Failed to list audit events", "reason": "boom"}`,
+		},
+		{
+			name:             "METHOD failure",
+			method:           http.MethodPost,
+			url:              "/audit",
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewHandler(&mockLister{ListFn: tc.listFn})
+
+			req := httptest.NewRequest(tc.method, tc.url, nil)
+			if tc.identity != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), tc.identity))
+			}
+			rr := httptest.NewRecorder()
+
+			handler.Audit(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("status mismatch - wanted %d, got %d", tc.expectedStatus, rr.Code)
+			}
+
+			var got, want any
+			if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+				t.Fatalf("failed to unmarshal response body: %v\nbody: %s", err, rr.Body.String())
+			}
+			if err := json.Unmarshal([]byte(tc.expectedContains), &want); err != nil {
+				t.Fatalf("failed to unmarshal expected value: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("JSON mismatch:\nexpected: %+v\ngot: %+v", want, got)
+			}
+		})
+	}
+}
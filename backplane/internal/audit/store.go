@@ -0,0 +1,198 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	recordQuery = `INSERT INTO audit_events 
+(action, resource_type, resource_id, actor, tenant, ip, user_agent, request_id, details) 
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) 
+RETURNING id, occurred_at`
+	listQuery = `SELECT 
+  id,
+  occurred_at,
+  action,
+  resource_type,
+  COALESCE(resource_id, ''),
+  COALESCE(actor, ''),
+  COALESCE(tenant, ''),
+  COALESCE(ip, ''),
+  COALESCE(user_agent, ''),
+  COALESCE(request_id, ''),
+  details
+FROM audit_events`
+
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+/*
+Filter narrows down the events returned by List. Zero values match
+everything. A non-nil Tenant matches only that tenant's events, with ""
+matching events recorded without a tenant.
+*/
+type Filter struct {
+	Actor        string
+	Tenant       *string
+	Action       Action
+	ResourceType ResourceType
+	ResourceID   string
+	Since        *time.Time
+	Until        *time.Time
+	Limit        int
+	Offset       int
+}
+
+/*
+sql builds the WHERE, ORDER and LIMIT clauses for the filter, returning
+them with their positional arguments.
+*/
+func (f Filter) sql() (string, []any) {
+	var conds []string
+	var args []any
+
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.Actor != "" {
+		add("actor = $%d", f.Actor)
+	}
+	if f.Tenant != nil {
+		add("COALESCE(tenant, '') = $%d", *f.Tenant)
+	}
+	if f.Action != "" {
+		add("action = $%d", string(f.Action))
+	}
+	if f.ResourceType != "" {
+		add("resource_type = $%d", string(f.ResourceType))
+	}
+	if f.ResourceID != "" {
+		add("resource_id = $%d", f.ResourceID)
+	}
+	if f.Since != nil {
+		add("occurred_at >= $%d", *f.Since)
+	}
+	if f.Until != nil {
+		add("occurred_at < $%d", *f.Until)
+	}
+
+	clause := ""
+	if len(conds) > 0 {
+		clause = "\nWHERE " + strings.Join(conds, " AND ")
+	}
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	args = append(args, limit, f.Offset)
+	clause += fmt.Sprintf("\nORDER BY occurred_at DESC, id DESC\nLIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	return clause, args
+}
+
+type Store struct {
+	q Querier
+}
+
+func NewStore(q Querier) *Store {
+	return &Store{
+		q: q,
+	}
+}
+
+/*
+Record appends e to the log outside of any transaction. Prefer
+RecordWithQuerier for events describing a change to the database.
+*/
+func (s *Store) Record(ctx context.Context, e *Event) error {
+	return s.RecordWithQuerier(ctx, s.q, e)
+}
+
+/*
+RecordWithQuerier appends e to the log using q, which may be a
+transaction. The event's ID and OccurredAt are filled in.
+*/
+func (s *Store) RecordWithQuerier(ctx context.Context, q Querier, e *Event) error {
+	var details any
+	if len(e.Details) > 0 {
+		details = e.Details
+	}
+
+	row := q.QueryRow(
+		ctx,
+		recordQuery,
+		string(e.Action),
+		string(e.ResourceType),
+		e.ResourceID,
+		e.Actor,
+		e.Tenant,
+		e.IP,
+		e.UserAgent,
+		e.RequestID,
+		details,
+	)
+
+	if err := row.Scan(&e.ID, &e.OccurredAt); err != nil {
+		return fmt.Errorf("record audit event: %w", err)
+	}
+
+	return nil
+}
+
+/*
+List returns events matching f, most recent first.
+*/
+func (s *Store) List(ctx context.Context, f Filter) ([]*Event, error) {
+	clause, args := f.sql()
+
+	rows, err := s.q.Query(ctx, listQuery+clause, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query audit events: %s", err)
+	}
+	defer rows.Close()
+
+	es := []*Event{}
+	for rows.Next() {
+		e := &Event{}
+		var action, resourceType string
+		if err := rows.Scan(
+			&e.ID,
+			&e.OccurredAt,
+			&action,
+			&resourceType,
+			&e.ResourceID,
+			&e.Actor,
+			&e.Tenant,
+			&e.IP,
+			&e.UserAgent,
+			&e.RequestID,
+			&e.Details,
+		); err != nil {
+			return nil, err
+		}
+		e.Action = Action(action)
+		e.ResourceType = ResourceType(resourceType)
+		es = append(es, e)
+	}
+
+	return es, rows.Err()
+}
@@ -0,0 +1,133 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func TestRecord(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	db.ExpectQuery(regexp.QuoteMeta(recordQuery)).
+		WithArgs("create", "dataset", "ds1", "dev|1", "acme", "127.0.0.1", "curl", "req-1", json.RawMessage(`{"name":"x"}`)).
+		WillReturnRows(pgxmock.NewRows([]string{"id", "occurred_at"}).AddRow(int64(7), now))
+
+	store := NewStore(db)
+
+	e := &Event{
+		Action:       ActionCreate,
+		ResourceType: ResourceDataset,
+		ResourceID:   "ds1",
+		Actor:        "dev|1",
+		Tenant:       "acme",
+		IP:           "127.0.0.1",
+		UserAgent:    "curl",
+		RequestID:    "req-1",
+		Details:      json.RawMessage(`{"name":"x"}`),
+	}
+	if err := store.Record(context.Background(), e); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if e.ID != 7 || !e.OccurredAt.Equal(now) {
+		t.Errorf("expected id and timestamp to be filled in, got %+v", e)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRecordFailOnScan(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.ExpectQuery(regexp.QuoteMeta(recordQuery)).
+		WillReturnError(errors.New("boom"))
+
+	store := NewStore(db)
+
+	if err := store.Record(context.Background(), &Event{}); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestList(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	since := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+	tenant := ""
+	expectedQuery := listQuery + `
+WHERE actor = $1 AND COALESCE(tenant, '') = $2 AND resource_type = $3 AND occurred_at >= $4
+ORDER BY occurred_at DESC, id DESC
+LIMIT $5 OFFSET $6`
+
+	rows := db.NewRows([]string{"id", "occurred_at", "action", "resource_type", "resource_id", "actor", "tenant", "ip", "user_agent", "request_id", "details"}).
+		AddRow(int64(1), since, "download", "upload", "u1", "dev|1", "", "10.0.0.1", "python", "req", []byte(nil))
+
+	db.ExpectQuery(regexp.QuoteMeta(expectedQuery)).
+		WithArgs("dev|1", "", "upload", since, DefaultLimit, 0).
+		WillReturnRows(rows)
+
+	store := NewStore(db)
+
+	es, err := store.List(context.Background(), Filter{
+		Actor:        "dev|1",
+		Tenant:       &tenant,
+		ResourceType: ResourceUpload,
+		Since:        &since,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(es) != 1 || es[0].Action != ActionDownload || es[0].ResourceID != "u1" {
+		t.Errorf("unexpected events: %+v", es)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestListCapsLimit(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	expectedQuery := listQuery + `
+ORDER BY occurred_at DESC, id DESC
+LIMIT $1 OFFSET $2`
+
+	db.ExpectQuery(regexp.QuoteMeta(expectedQuery)).
+		WithArgs(MaxLimit, 20).
+		WillReturnError(errors.New("boom"))
+
+	store := NewStore(db)
+
+	if _, err := store.List(context.Background(), Filter{Limit: 1_000_000, Offset: 20}); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -50,8 +50,8 @@ func LoadConfig(path string) (*OAuthProviderConfig, error) {
 		return conf, nil
 	}
 
-	// 3. Check home-level config
-	if conf, err := loadFromFile(DefaultConfigPath); err == nil {
+	// 3. Check home-level (or context-level) config
+	if conf, err := loadFromFile(path); err == nil {
 		return conf, nil
 	}
 
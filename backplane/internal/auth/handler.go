@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/heldtogether/traintrack/internal"
+)
+
+/*
+HandleMe echoes back the verified identity of the caller, including
+the roles the backplane sees for them. It should be registered behind the
+auth middleware under something like /me.
+*/
+func HandleMe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusMethodNotAllowed,
+			Message: "Method not allowed",
+			Reason:  "",
+		})
+		return
+	}
+
+	id, ok := IdentityFromContext(r.Context())
+	if !ok {
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusUnauthorized,
+			Message: "Unauthorized",
+			Reason:  "no verified identity",
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(id)
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestHandleMe(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		identity         *Identity
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:             "GET success",
+			method:           http.MethodGet,
+			identity:         &Identity{Subject: "dev|1", Email: "dev@example.com", Roles: []string{"admin"}},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"sub": "dev|1", "email": "dev@example.com", "roles": ["admin"], "issuer": "", "expiry": "0001-01-01T00:00:00Z"}`,
+		},
+		{
+			name:             "GET without identity",
+			method:           http.MethodGet,
+			expectedStatus:   http.StatusUnauthorized,
+			expectedContains: `{"code": 401, "error": "Unauthorized", "reason": "no verified identity"}`,
+		},
+		{
+			name:             "METHOD failure",
+			method:           http.MethodPost,
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/me", nil)
+			if tc.identity != nil {
+				req = req.WithContext(NewContext(req.Context(), tc.identity))
+			}
+			rr := httptest.NewRecorder()
+
+			HandleMe(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("status mismatch - wanted %d, got %d", tc.expectedStatus, rr.Code)
+			}
+
+			var got, want any
+			if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+				t.Fatalf("failed to unmarshal response body: %v", err)
+			}
+			if err := json.Unmarshal([]byte(tc.expectedContains), &want); err != nil {
+				t.Fatalf("failed to unmarshal expected value: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("JSON mismatch:\nexpected: %+v\ngot: %+v", want, got)
+			}
+		})
+	}
+}
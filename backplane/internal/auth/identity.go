@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+type identityKey struct{}
+
+/*
+Roles the backplane itself checks for. Any other roles a provider hands out
+are passed through untouched.
+*/
+const (
+	RoleAdmin   = "admin"
+	RoleAuditor = "auditor"
+)
+
+/*
+Identity is who a verified token says the caller is.
+*/
+type Identity struct {
+	Subject string    `json:"sub"`
+	Email   string    `json:"email,omitempty"`
+	Name    string    `json:"name,omitempty"`
+	Tenant  string    `json:"tenant,omitempty"`
+	Roles   []string  `json:"roles"`
+	Issuer  string    `json:"issuer"`
+	Expiry  time.Time `json:"expiry"`
+}
+
+/*
+HasRole reports whether the identity holds any of roles.
+*/
+func (id *Identity) HasRole(roles ...string) bool {
+	for _, have := range id.Roles {
+		for _, want := range roles {
+			if have == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*
+IdentityFromToken extracts an Identity from a verified token. Roles and
+tenant are read from the claims named by TRAINTRACK_ROLES_CLAIM and
+TRAINTRACK_TENANT_CLAIM, which default to "roles" and "org_id". Providers
+like Auth0 require custom claims to be namespaced, so these usually need
+setting to something like "https://traintrack.example.com/roles".
+*/
+func IdentityFromToken(tok *oidc.IDToken) (*Identity, error) {
+	var claims map[string]any
+	if err := tok.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to parse token claims: %w", err)
+	}
+
+	id := &Identity{
+		Subject: tok.Subject,
+		Issuer:  tok.Issuer,
+		Expiry:  tok.Expiry,
+		Roles:   []string{},
+	}
+	id.Email, _ = claims["email"].(string)
+	id.Name, _ = claims["name"].(string)
+	id.Tenant, _ = claims[claimName("TRAINTRACK_TENANT_CLAIM", "org_id")].(string)
+
+	switch roles := claims[claimName("TRAINTRACK_ROLES_CLAIM", "roles")].(type) {
+	case []any:
+		for _, r := range roles {
+			if s, ok := r.(string); ok {
+				id.Roles = append(id.Roles, s)
+			}
+		}
+	case string:
+		id.Roles = append(id.Roles, roles)
+	}
+
+	return id, nil
+}
+
+/*
+NewContext returns a copy of ctx carrying id.
+*/
+func NewContext(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+/*
+IdentityFromContext returns the Identity stored in ctx by the auth
+middleware, if any.
+*/
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok && id != nil
+}
+
+/*
+TenantFromContext returns the tenant of the Identity stored in ctx, or ""
+if there is none.
+*/
+func TenantFromContext(ctx context.Context) string {
+	if id, ok := IdentityFromContext(ctx); ok {
+		return id.Tenant
+	}
+	return ""
+}
+
+func claimName(env string, fallback string) string {
+	if name := os.Getenv(env); name != "" {
+		return name
+	}
+	return fallback
+}
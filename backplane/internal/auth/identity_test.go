@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/heldtogether/traintrack/internal/devidp"
+)
+
+func verifiedToken(t *testing.T, idp *devidp.TestServer, user string) *oidc.IDToken {
+	t.Helper()
+
+	p, err := oidc.NewProvider(context.Background(), idp.Issuer())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tok, err := p.Verifier(&oidc.Config{ClientID: idp.Audience()}).Verify(context.Background(), idp.MustAccessToken(t, user))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+func TestIdentityFromToken(t *testing.T) {
+	conf := devidp.DefaultConfig()
+	conf.Users[0].Claims = map[string]any{
+		"roles":  []string{"admin", "trainer"},
+		"org_id": "org_123",
+	}
+	idp := devidp.NewTestServer(t, conf)
+
+	id, err := IdentityFromToken(verifiedToken(t, idp, "dev|1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if id.Subject != "dev|1" || id.Tenant != "org_123" || id.Issuer != idp.Issuer() {
+		t.Errorf("unexpected identity: %+v", id)
+	}
+	if !reflect.DeepEqual(id.Roles, []string{"admin", "trainer"}) {
+		t.Errorf("unexpected roles: %v", id.Roles)
+	}
+}
+
+func TestIdentityFromTokenCustomClaims(t *testing.T) {
+	conf := devidp.DefaultConfig()
+	conf.Users[0].Claims = map[string]any{
+		"https://traintrack.example.com/roles":  "viewer",
+		"https://traintrack.example.com/tenant": "acme",
+	}
+	idp := devidp.NewTestServer(t, conf)
+
+	t.Setenv("TRAINTRACK_ROLES_CLAIM", "https://traintrack.example.com/roles")
+	t.Setenv("TRAINTRACK_TENANT_CLAIM", "https://traintrack.example.com/tenant")
+
+	id, err := IdentityFromToken(verifiedToken(t, idp, "dev|1"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if id.Tenant != "acme" || !reflect.DeepEqual(id.Roles, []string{"viewer"}) {
+		t.Errorf("unexpected identity: %+v", id)
+	}
+}
+
+func TestIdentityContext(t *testing.T) {
+	if _, ok := IdentityFromContext(context.Background()); ok {
+		t.Errorf("expected no identity in empty context")
+	}
+
+	want := &Identity{Subject: "abc"}
+	got, ok := IdentityFromContext(NewContext(context.Background(), want))
+	if !ok || got != want {
+		t.Errorf("got %+v, wanted %+v", got, want)
+	}
+}
+
+func TestTenantFromContext(t *testing.T) {
+	if got := TenantFromContext(context.Background()); got != "" {
+		t.Errorf("got tenant %q from empty context", got)
+	}
+
+	ctx := NewContext(context.Background(), &Identity{Subject: "abc", Tenant: "acme"})
+	if got := TenantFromContext(ctx); got != "acme" {
+		t.Errorf("got tenant %q, wanted %q", got, "acme")
+	}
+}
+
+func TestIdentityHasRole(t *testing.T) {
+	id := &Identity{Subject: "abc", Roles: []string{"trainer", "auditor"}}
+
+	if !id.HasRole(RoleAuditor, RoleAdmin) {
+		t.Errorf("expected %v to hold the auditor role", id.Roles)
+	}
+	if id.HasRole(RoleAdmin) {
+		t.Errorf("expected %v not to hold the admin role", id.Roles)
+	}
+}
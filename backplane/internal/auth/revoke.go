@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+/*
+ErrRevocationUnsupported is returned when the identity provider doesn't
+advertise a revocation endpoint.
+*/
+var ErrRevocationUnsupported = errors.New("identity provider does not support token revocation")
+
+/*
+RevokeToken asks the identity provider to revoke token (RFC 7009). hint
+should be "refresh_token" or "access_token".
+*/
+func RevokeToken(ctx context.Context, conf *OAuthProviderConfig, token string, hint string) error {
+	p, err := oidc.NewProvider(ctx, conf.AuthURL)
+	if err != nil {
+		return fmt.Errorf("failed to create OIDC provider: %w", err)
+	}
+
+	var endpoints struct {
+		RevocationURL string `json:"revocation_endpoint"`
+	}
+	if err := p.Claims(&endpoints); err != nil {
+		return fmt.Errorf("failed to read provider metadata: %w", err)
+	}
+	if endpoints.RevocationURL == "" {
+		return ErrRevocationUnsupported
+	}
+
+	form := url.Values{
+		"token":           {token},
+		"token_type_hint": {hint},
+		"client_id":       {conf.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoints.RevocationURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("revocation failed: %d - %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+
+	return nil
+}
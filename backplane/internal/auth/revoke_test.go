@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/devidp"
+)
+
+func TestRevokeToken(t *testing.T) {
+	ctx := context.Background()
+	idp := devidp.NewTestServer(t, devidp.DefaultConfig())
+	conf := &OAuthProviderConfig{ClientID: "traintrack-cli", AuthURL: idp.Issuer()}
+
+	tok := idp.MustToken(t, "dev|1")
+	if err := RevokeToken(ctx, conf, tok.RefreshToken, "refresh_token"); err != nil {
+		t.Fatalf("revoke failed: %s", err)
+	}
+
+	// A revoked refresh token can no longer be used.
+	path := t.TempDir() + "/credentials.json"
+	tok.Expiry = time.Now().Add(-time.Minute)
+	if err := SaveToken(path, tok); err != nil {
+		t.Fatal(err)
+	}
+	ts, err := NewTokenSource(ctx, path, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ts.Token(); !errors.Is(err, ErrLoginRequired) {
+		t.Errorf("expected revoked refresh token to be rejected, got %v", err)
+	}
+}
+
+func TestUnverifiedClaims(t *testing.T) {
+	idp := devidp.NewTestServer(t, devidp.DefaultConfig())
+	tok := idp.MustToken(t, "dev|1")
+
+	claims, err := UnverifiedClaims(tok.Extra("id_token").(string))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if claims["email"] != "dev@traintrack.local" {
+		t.Errorf("unexpected claims: %v", claims)
+	}
+
+	if _, err := UnverifiedClaims("not.a-token"); err == nil {
+		t.Errorf("expected malformed token to fail")
+	}
+}
+
+func TestDeleteToken(t *testing.T) {
+	path := t.TempDir() + "/credentials.json"
+
+	if err := DeleteToken(path); err != nil {
+		t.Errorf("deleting missing credentials should not fail: %s", err)
+	}
+}
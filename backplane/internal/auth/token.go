@@ -1,9 +1,13 @@
 package auth
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -53,10 +57,52 @@ func LoadToken(path string) (*oauth2.Token, error) {
 		return nil, err
 	}
 
-	return &oauth2.Token{
+	tok := &oauth2.Token{
 		AccessToken:  stored.AccessToken,
 		RefreshToken: stored.RefreshToken,
 		Expiry:       stored.Expiry,
 		TokenType:    "Bearer",
-	}, nil
+	}
+
+	if stored.IDToken != "" {
+		tok = tok.WithExtra(map[string]any{"id_token": stored.IDToken})
+	}
+
+	return tok, nil
+}
+
+/*
+DeleteToken removes the credentials stored at path. It is not an error
+if there are none.
+*/
+func DeleteToken(path string) error {
+	err := os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+/*
+UnverifiedClaims decodes the claims of a JWT without checking its
+signature. Only use it to display details of a token we obtained
+ourselves; never to make authorization decisions.
+*/
+func UnverifiedClaims(raw string) (map[string]any, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token: expected 3 parts, got %d", len(parts))
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token payload: %w", err)
+	}
+
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+
+	return claims, nil
 }
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+/*
+ErrLoginRequired is returned when there is no usable token and it can't be
+refreshed, so the user has to log in again.
+*/
+var ErrLoginRequired = errors.New("not logged in or session expired, please run `traintrack login`")
+
+/*
+NewTokenSource returns a TokenSource for the credentials stored at path.
+When the access token expires it is refreshed using the stored refresh
+token, and the rotated token is written back to path so the next
+invocation picks it up.
+*/
+func NewTokenSource(ctx context.Context, path string, conf *OAuthProviderConfig) (oauth2.TokenSource, error) {
+	tok, err := LoadToken(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrLoginRequired, err)
+	}
+
+	return &persistingTokenSource{
+		ctx:     ctx,
+		path:    path,
+		conf:    conf,
+		current: tok,
+	}, nil
+}
+
+type persistingTokenSource struct {
+	ctx  context.Context
+	path string
+	conf *OAuthProviderConfig
+
+	mu      sync.Mutex
+	current *oauth2.Token
+}
+
+func (s *persistingTokenSource) Token() (*oauth2.Token, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.current.Valid() {
+		return s.current, nil
+	}
+
+	if s.current.RefreshToken == "" {
+		return nil, ErrLoginRequired
+	}
+
+	// Only discover the provider when we actually need to refresh, so
+	// the common case doesn't cost an extra round trip.
+	p, err := oidc.NewProvider(s.ctx, s.conf.AuthURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OIDC provider: %w", err)
+	}
+
+	config := &oauth2.Config{
+		ClientID: s.conf.ClientID,
+		Endpoint: p.Endpoint(),
+	}
+
+	// Hand over a copy without the expiry so the refresh happens
+	// regardless of clock skew between us and the provider.
+	expired := &oauth2.Token{RefreshToken: s.current.RefreshToken}
+	tok, err := config.TokenSource(s.ctx, expired).Token()
+	if err != nil {
+		return nil, fmt.Errorf("%w: refresh failed: %s", ErrLoginRequired, err)
+	}
+
+	if _, ok := tok.Extra("id_token").(string); !ok {
+		if idToken, ok := s.current.Extra("id_token").(string); ok {
+			tok = tok.WithExtra(map[string]any{"id_token": idToken})
+		}
+	}
+
+	if err := SaveToken(s.path, tok); err != nil {
+		return nil, fmt.Errorf("could not save refreshed token: %w", err)
+	}
+
+	s.current = tok
+	return tok, nil
+}
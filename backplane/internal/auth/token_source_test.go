@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/devidp"
+	"golang.org/x/oauth2"
+)
+
+func TestTokenSourceReturnsValidToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+
+	stored := &oauth2.Token{
+		AccessToken:  "still-good",
+		RefreshToken: "refresh",
+		Expiry:       time.Now().Add(time.Hour),
+	}
+	if err := SaveToken(path, stored); err != nil {
+		t.Fatal(err)
+	}
+
+	// An unreachable provider proves we don't refresh unnecessarily.
+	ts, err := NewTokenSource(context.Background(), path, &OAuthProviderConfig{AuthURL: "http://127.0.0.1:0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tok, err := ts.Token()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if tok.AccessToken != "still-good" {
+		t.Errorf("got access token %q, wanted the stored one", tok.AccessToken)
+	}
+}
+
+func TestTokenSourceRefreshesAndPersists(t *testing.T) {
+	idp := devidp.NewTestServer(t, devidp.DefaultConfig())
+	path := filepath.Join(t.TempDir(), "credentials.json")
+
+	issued := idp.MustToken(t, "dev|1")
+	issued.Expiry = time.Now().Add(-time.Minute)
+	if err := SaveToken(path, issued); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := &OAuthProviderConfig{
+		Name:     idp.Audience(),
+		ClientID: "traintrack-cli",
+		AuthURL:  idp.Issuer(),
+	}
+	ts, err := NewTokenSource(context.Background(), path, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tok, err := ts.Token()
+	if err != nil {
+		t.Fatalf("refresh failed: %s", err)
+	}
+	if tok.AccessToken == issued.AccessToken {
+		t.Errorf("expected a new access token")
+	}
+
+	persisted, err := LoadToken(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if persisted.AccessToken != tok.AccessToken || persisted.RefreshToken != tok.RefreshToken {
+		t.Errorf("refreshed token was not persisted")
+	}
+	if persisted.RefreshToken == issued.RefreshToken {
+		t.Errorf("expected rotated refresh token to be persisted")
+	}
+	if persisted.Extra("id_token") != issued.Extra("id_token") {
+		t.Errorf("expected id token to be kept when refresh doesn't return one")
+	}
+}
+
+func TestTokenSourceRefreshFailureAsksForLogin(t *testing.T) {
+	idp := devidp.NewTestServer(t, devidp.DefaultConfig())
+	path := filepath.Join(t.TempDir(), "credentials.json")
+
+	if err := SaveToken(path, &oauth2.Token{
+		AccessToken:  "expired",
+		RefreshToken: "revoked",
+		Expiry:       time.Now().Add(-time.Minute),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	conf := &OAuthProviderConfig{ClientID: "traintrack-cli", AuthURL: idp.Issuer()}
+	ts, err := NewTokenSource(context.Background(), path, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ts.Token(); !errors.Is(err, ErrLoginRequired) {
+		t.Errorf("expected ErrLoginRequired, got %v", err)
+	}
+}
+
+func TestTokenSourceMissingCredentials(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.json")
+
+	if _, err := NewTokenSource(context.Background(), path, &OAuthProviderConfig{}); !errors.Is(err, ErrLoginRequired) {
+		t.Errorf("expected ErrLoginRequired, got %v", err)
+	}
+}
@@ -0,0 +1,317 @@
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/heldtogether/traintrack/internal/seal"
+)
+
+// dependencyKey is where the SDK records the frozen package list in a
+// model's environment.
+const dependencyKey = "dependencies"
+
+/*
+Comparison lines up two or more models. Each Difference has one value
+per model, in the order of Models.
+*/
+type Comparison struct {
+	Models       []Version    `json:"models"`
+	Config       []Difference `json:"config"`
+	Evaluation   []Difference `json:"evaluation"`
+	Dependencies []Difference `json:"dependencies"`
+
+	// CommonDataset is the newest dataset version in every model's
+	// lineage, or nil if they share none.
+	CommonDataset *DatasetVersion `json:"common_dataset"`
+}
+
+/*
+Version is one of the models compared, with the dataset it was trained
+on followed by that dataset's ancestors, newest first.
+*/
+type Version struct {
+	ID      string           `json:"id"`
+	Name    string           `json:"name"`
+	Version string           `json:"version"`
+	Lineage []DatasetVersion `json:"lineage"`
+}
+
+type DatasetVersion struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+/*
+Difference is a single key's value in each model, nil where a model
+doesn't have it. Changed is set unless every model has the same value.
+*/
+type Difference struct {
+	Key     string `json:"key"`
+	Values  []any  `json:"values"`
+	Changed bool   `json:"changed"`
+}
+
+type modelFields struct {
+	Name        string          `json:"name"`
+	Version     string          `json:"version"`
+	Dataset     string          `json:"dataset"`
+	Config      json.RawMessage `json:"config"`
+	Environment json.RawMessage `json:"environment"`
+	Evaluation  json.RawMessage `json:"evaluation"`
+}
+
+type datasetFields struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type Service struct {
+	models   seal.Getter
+	datasets seal.Getter
+}
+
+/*
+NewService compares models using the sealed records returned by models
+and datasets.
+*/
+func NewService(models seal.Getter, datasets seal.Getter) *Service {
+	return &Service{
+		models:   models,
+		datasets: datasets,
+	}
+}
+
+/*
+Compare lines up the models ids, in the order given.
+*/
+func (s *Service) Compare(ids []string) (*Comparison, error) {
+	c := &Comparison{Models: make([]Version, len(ids))}
+	configs := make([]map[string]any, len(ids))
+	evaluations := make([]map[string]any, len(ids))
+	dependencies := make([]map[string]any, len(ids))
+
+	// Models trained on related datasets share most of their lineage, so
+	// each dataset is only loaded once.
+	datasets := map[string]*seal.Record{}
+
+	// Load every model before any dataset, so a missing model is
+	// reported as such rather than as a problem with another's dataset.
+	records := make([]*seal.Record, len(ids))
+	for i, id := range ids {
+		var err error
+		if records[i], err = s.models(id); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, r := range records {
+		id := ids[i]
+
+		var f modelFields
+		if err := r.DecodeFields(&f); err != nil {
+			return nil, err
+		}
+
+		lineage, err := s.lineage(f.Dataset, datasets)
+		if err != nil {
+			return nil, err
+		}
+		c.Models[i] = Version{ID: r.ID, Name: f.Name, Version: f.Version, Lineage: lineage}
+
+		if configs[i], err = flattened(f.Config); err != nil {
+			return nil, fmt.Errorf("model %s has invalid config: %w", id, err)
+		}
+		if evaluations[i], err = flattened(f.Evaluation); err != nil {
+			return nil, fmt.Errorf("model %s has invalid evaluation: %w", id, err)
+		}
+		if dependencies[i], err = packages(f.Environment); err != nil {
+			return nil, fmt.Errorf("model %s has invalid environment: %w", id, err)
+		}
+	}
+
+	c.Config = differences(configs)
+	c.Evaluation = differences(evaluations)
+
+	// Models usually share hundreds of packages, so only the ones that
+	// differ are worth showing.
+	c.Dependencies = []Difference{}
+	for _, d := range differences(dependencies) {
+		if d.Changed {
+			c.Dependencies = append(c.Dependencies, d)
+		}
+	}
+
+	c.CommonDataset = commonDataset(c.Models)
+	return c, nil
+}
+
+/*
+lineage returns the dataset id followed by its ancestors, loading any
+not already in loaded.
+*/
+func (s *Service) lineage(id string, loaded map[string]*seal.Record) ([]DatasetVersion, error) {
+	lineage := []DatasetVersion{}
+	seen := map[string]bool{}
+
+	for id != "" && !seen[id] {
+		seen[id] = true
+
+		r, ok := loaded[id]
+		if !ok {
+			var err error
+			if r, err = s.datasets(id); err != nil {
+				// Not %w: a missing dataset isn't a missing model.
+				return nil, fmt.Errorf("could not load dataset %s: %s", id, err)
+			}
+			loaded[id] = r
+		}
+
+		var f datasetFields
+		if err := r.DecodeFields(&f); err != nil {
+			return nil, err
+		}
+		lineage = append(lineage, DatasetVersion{ID: r.ID, Name: f.Name, Version: f.Version})
+		id = r.ParentID
+	}
+	return lineage, nil
+}
+
+/*
+commonDataset returns the newest dataset version in the lineage of every
+model, or nil if there isn't one.
+*/
+func commonDataset(models []Version) *DatasetVersion {
+	if len(models) == 0 {
+		return nil
+	}
+
+	for _, candidate := range models[0].Lineage {
+		shared := true
+		for _, m := range models[1:] {
+			if !slices.ContainsFunc(m.Lineage, func(d DatasetVersion) bool { return d.ID == candidate.ID }) {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			return &candidate
+		}
+	}
+	return nil
+}
+
+/*
+differences lines up the values of every key found in any of values,
+sorted by key.
+*/
+func differences(values []map[string]any) []Difference {
+	keys := map[string]bool{}
+	for _, v := range values {
+		for key := range v {
+			keys[key] = true
+		}
+	}
+
+	diffs := make([]Difference, 0, len(keys))
+	for _, key := range sortedKeys(keys) {
+		d := Difference{Key: key, Values: make([]any, len(values))}
+		for i, v := range values {
+			d.Values[i] = v[key]
+			if i > 0 && !equal(d.Values[0], d.Values[i]) {
+				d.Changed = true
+			}
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+/*
+flattened decodes a JSON object into its leaf values, keyed by their
+dotted path. Arrays are leaves, as are objects with nothing in them.
+*/
+func flattened(data json.RawMessage) (map[string]any, error) {
+	out := map[string]any{}
+	if len(data) == 0 {
+		return out, nil
+	}
+
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	flatten("", v, out)
+	return out, nil
+}
+
+func flatten(prefix string, v any, out map[string]any) {
+	obj, ok := v.(map[string]any)
+	if !ok || len(obj) == 0 {
+		// An empty object is only a value inside another.
+		if v != nil && (prefix != "" || !ok) {
+			out[prefix] = v
+		}
+		return
+	}
+
+	for key, child := range obj {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		flatten(key, child, out)
+	}
+}
+
+/*
+packages returns the version of each package in the frozen package list
+in environment. Packages installed from somewhere other than an index
+have where they came from as their version.
+*/
+func packages(environment json.RawMessage) (map[string]any, error) {
+	out := map[string]any{}
+	if len(environment) == 0 {
+		return out, nil
+	}
+
+	var env map[string]any
+	if err := json.Unmarshal(environment, &env); err != nil {
+		return nil, err
+	}
+	frozen, _ := env[dependencyKey].(string)
+
+	for _, line := range strings.Split(frozen, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		name, version, ok := strings.Cut(line, "==")
+		if !ok {
+			name, version, ok = strings.Cut(line, " @ ")
+		}
+		if !ok {
+			continue
+		}
+		out[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(version)
+	}
+	return out, nil
+}
+
+func equal(a, b any) bool {
+	x, errA := json.Marshal(a)
+	y, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(x) == string(y)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
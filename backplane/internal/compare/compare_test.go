@@ -0,0 +1,142 @@
+package compare
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/seal"
+)
+
+func testModels() map[string]*seal.Record {
+	return map[string]*seal.Record{
+		"m1": {
+			Kind: "model",
+			ID:   "m1",
+			Fields: map[string]any{
+				"name":       "regressor",
+				"version":    "1.0.0",
+				"dataset":    "ds2",
+				"config":     map[string]any{"epochs": 10, "optimizer": map[string]any{"lr": 0.1}},
+				"evaluation": map[string]any{"r2": 0.8},
+				"environment": map[string]any{
+					"runtime":      "CPython",
+					"dependencies": "numpy==2.0.0\nScikit-Learn==1.5.0\n",
+				},
+			},
+		},
+		"m2": {
+			Kind: "model",
+			ID:   "m2",
+			Fields: map[string]any{
+				"name":       "regressor",
+				"version":    "1.1.0",
+				"dataset":    "ds3",
+				"config":     map[string]any{"epochs": 10, "optimizer": map[string]any{"lr": 0.01}},
+				"evaluation": map[string]any{"r2": 0.85, "mae": 1.2},
+				"environment": map[string]any{
+					"runtime":      "CPython",
+					"dependencies": "numpy==2.0.0\nscikit-learn==1.5.1\ntorch==2.3.0\n-e git+https://example.com/repo\n",
+				},
+			},
+		},
+	}
+}
+
+func testDatasets() map[string]*seal.Record {
+	return map[string]*seal.Record{
+		"ds1": {Kind: "dataset", ID: "ds1", Fields: map[string]any{"name": "house_prices", "version": "1.0.0"}},
+		"ds2": {Kind: "dataset", ID: "ds2", Fields: map[string]any{"name": "house_prices", "version": "1.0.1"}, ParentID: "ds1"},
+		"ds3": {Kind: "dataset", ID: "ds3", Fields: map[string]any{"name": "house_prices", "version": "1.1.0"}, ParentID: "ds1"},
+	}
+}
+
+func getter(kind string, records map[string]*seal.Record, calls map[string]int) seal.Getter {
+	return func(id string) (*seal.Record, error) {
+		calls[id]++
+		r, ok := records[id]
+		if !ok {
+			return nil, fmt.Errorf("%s %s: %w", kind, id, internal.ErrNotFound)
+		}
+		return r, nil
+	}
+}
+
+func TestCompare(t *testing.T) {
+	calls := map[string]int{}
+	s := NewService(getter("model", testModels(), calls), getter("dataset", testDatasets(), calls))
+
+	c, err := s.Compare([]string{"m1", "m2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := &Comparison{
+		Models: []Version{
+			{ID: "m1", Name: "regressor", Version: "1.0.0", Lineage: []DatasetVersion{
+				{ID: "ds2", Name: "house_prices", Version: "1.0.1"},
+				{ID: "ds1", Name: "house_prices", Version: "1.0.0"},
+			}},
+			{ID: "m2", Name: "regressor", Version: "1.1.0", Lineage: []DatasetVersion{
+				{ID: "ds3", Name: "house_prices", Version: "1.1.0"},
+				{ID: "ds1", Name: "house_prices", Version: "1.0.0"},
+			}},
+		},
+		Config: []Difference{
+			{Key: "epochs", Values: []any{10.0, 10.0}},
+			{Key: "optimizer.lr", Values: []any{0.1, 0.01}, Changed: true},
+		},
+		Evaluation: []Difference{
+			{Key: "mae", Values: []any{nil, 1.2}, Changed: true},
+			{Key: "r2", Values: []any{0.8, 0.85}, Changed: true},
+		},
+		Dependencies: []Difference{
+			{Key: "scikit-learn", Values: []any{"1.5.0", "1.5.1"}, Changed: true},
+			{Key: "torch", Values: []any{nil, "2.3.0"}, Changed: true},
+		},
+		CommonDataset: &DatasetVersion{ID: "ds1", Name: "house_prices", Version: "1.0.0"},
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("got comparison %+v, wanted %+v", c, want)
+	}
+
+	if calls["ds1"] != 1 {
+		t.Errorf("expected the shared dataset to be loaded once, got %d", calls["ds1"])
+	}
+}
+
+func TestCompareNoCommonDataset(t *testing.T) {
+	models := testModels()
+	models["m2"].Fields.(map[string]any)["dataset"] = ""
+
+	calls := map[string]int{}
+	c, err := NewService(getter("model", models, calls), getter("dataset", testDatasets(), calls)).Compare([]string{"m1", "m2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if c.CommonDataset != nil {
+		t.Errorf("expected no common dataset, got %+v", c.CommonDataset)
+	}
+	if len(c.Models[1].Lineage) != 0 {
+		t.Errorf("expected no lineage for a model without a dataset, got %+v", c.Models[1].Lineage)
+	}
+}
+
+func TestCompareErrors(t *testing.T) {
+	calls := map[string]int{}
+	datasets := testDatasets()
+	delete(datasets, "ds1")
+	s := NewService(getter("model", testModels(), calls), getter("dataset", datasets, calls))
+
+	if _, err := s.Compare([]string{"m1", "missing"}); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing model, got %v", err)
+	}
+
+	_, err := s.Compare([]string{"m1", "m2"})
+	if err == nil || errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected a missing dataset not to be reported as a missing model, got %v", err)
+	}
+}
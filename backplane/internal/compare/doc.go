@@ -0,0 +1,16 @@
+/*
+Package compare lines model versions up side by side.
+
+A comparison is built from exactly what was sealed for each model (see
+package seal), so it shows what the model was made with rather than
+anything edited since. Config and evaluation are flattened into dotted
+keys, such as "optimizer.lr", and each key is given with its value in
+every model. Dependencies are read from the frozen package list in each
+model's environment, and only packages whose version differs are given.
+Each model's dataset lineage is followed back through the dataset's
+parents, so that models trained on different versions of a dataset can
+be traced back to the version they have in common:
+
+	c, err := compare.NewService(modelsStore.Record, datasetsStore.Record).Compare(ids)
+*/
+package compare
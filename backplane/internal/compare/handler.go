@@ -0,0 +1,93 @@
+package compare
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/heldtogether/traintrack/internal"
+)
+
+// MaxModels is the most models that can be compared at once.
+const MaxModels = 10
+
+/*
+Comparer lines model versions up side by side.
+*/
+type Comparer interface {
+	Compare(ids []string) (*Comparison, error)
+}
+
+type Handler struct {
+	c Comparer
+}
+
+func NewHandler(c Comparer) *Handler {
+	return &Handler{
+		c: c,
+	}
+}
+
+/*
+Compare compares the models named by the ids query parameter, which may
+be repeated or comma separated. It should be registered under
+/models/compare, before /models/{id}.
+*/
+func (h *Handler) Compare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	var ids []string
+	for _, param := range r.URL.Query()["ids"] {
+		for _, id := range strings.Split(param, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	if len(ids) < 2 || len(ids) > MaxModels {
+		log.Printf("failed to compare models: got %d ids", len(ids))
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to compare models",
+			Reason:  fmt.Sprintf("ids must name from 2 to %d models, got %d", MaxModels, len(ids)),
+		})
+		return
+	}
+
+	c, err := h.c.Compare(ids)
+	if err != nil {
+		code := http.StatusInternalServerError
+		message := "Failed to compare models"
+		if errors.Is(err, internal.ErrNotFound) {
+			code = http.StatusNotFound
+			message = "Model not found"
+		}
+		log.Printf("failed to compare models %s: %s", strings.Join(ids, ", "), err)
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    code,
+			Message: message,
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(c)
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    http.StatusMethodNotAllowed,
+		Message: "Method not allowed",
+		Reason:  "",
+	})
+}
@@ -0,0 +1,134 @@
+package compare
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/heldtogether/traintrack/internal"
+)
+
+type mockComparer struct {
+	CompareFn func(ids []string) (*Comparison, error)
+}
+
+func (m *mockComparer) Compare(ids []string) (*Comparison, error) {
+	return m.CompareFn(ids)
+}
+
+func TestCompareHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		compareFn        func(ids []string) (*Comparison, error)
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "success",
+			method: http.MethodGet,
+			path:   "/models/compare?ids=m1,m2&ids=m3",
+			compareFn: func(ids []string) (*Comparison, error) {
+				if !reflect.DeepEqual(ids, []string{"m1", "m2", "m3"}) {
+					return nil, fmt.Errorf("unexpected ids %v", ids)
+				}
+				return &Comparison{
+					Models:       []Version{{ID: "m1", Lineage: []DatasetVersion{}}},
+					Config:       []Difference{{Key: "lr", Values: []any{0.1, 0.01}, Changed: true}},
+					Evaluation:   []Difference{},
+					Dependencies: []Difference{},
+				}, nil
+			},
+			expectedStatus: http.StatusOK,
+			expectedContains: `{
+				"models": [{"id": "m1", "name": "", "version": "", "lineage": []}],
+				"config": [{"key": "lr", "values": [0.1, 0.01], "changed": true}],
+				"evaluation": [],
+				"dependencies": [],
+				"common_dataset": null
+			}`,
+		},
+		{
+			name:             "too few ids",
+			method:           http.MethodGet,
+			path:             "/models/compare?ids=m1",
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to compare models", "reason": "ids must name from 2 to 10 models, got 1"}`,
+		},
+		{
+			name:   "not found",
+			method: http.MethodGet,
+			path:   "/models/compare?ids=m1,m2",
+			compareFn: func(ids []string) (*Comparison, error) {
+				return nil, fmt.Errorf("model m2: %w", internal.ErrNotFound)
+			},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Model not found", "reason": "model m2: not found"}`,
+		},
+		{
+			name:   "failure",
+			method: http.MethodGet,
+			path:   "/models/compare?ids=m1,m2",
+			compareFn: func(ids []string) (*Comparison, error) {
+				return nil, errors.New("could not load dataset ds1: boom")
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedContains: `{"code": 500, "error": "Failed to compare models", "reason": "could not load dataset ds1: boom"}`,
+		},
+		{
+			name:             "METHOD failure",
+			method:           http.MethodPost,
+			path:             "/models/compare",
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewHandler(&mockComparer{CompareFn: tc.compareFn})
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			rr := httptest.NewRecorder()
+
+			handler.Compare(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func checkResponse(t *testing.T, got *http.Response, expectedStatus int, expected string) {
+	defer got.Body.Close()
+
+	body, err := io.ReadAll(got.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %s", err)
+	}
+
+	if expectedStatus != got.StatusCode {
+		t.Errorf("status mismatch - wanted %d, got %d", expectedStatus, got.StatusCode)
+	}
+
+	var gotData any
+	if err := json.Unmarshal(body, &gotData); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v\nbody: %s", err, string(body))
+	}
+
+	var expectedData any
+	if err := json.Unmarshal([]byte(expected), &expectedData); err != nil {
+		t.Fatalf("failed to unmarshal expected value: %v\njson: %s", err, expected)
+	}
+
+	if !reflect.DeepEqual(expectedData, gotData) {
+		t.Errorf("JSON mismatch:\nexpected: %+v\ngot: %+v", expectedData, gotData)
+	}
+}
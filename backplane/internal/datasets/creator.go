@@ -5,13 +5,19 @@ import (
 	"fmt"
 	"path/filepath"
 
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/heldtogether/traintrack/internal/seal"
+	"github.com/heldtogether/traintrack/internal/tabular"
 	"github.com/heldtogether/traintrack/internal/uploads"
 	"github.com/jackc/pgx/v5"
 )
 
 type datasetsStore interface {
 	createWithQuerier(q Querier, d *Dataset) (*Dataset, error)
-	List() ([]*Dataset, error)
+	recordWithQuerier(q Querier, id string) (*seal.Record, error)
+	sealWithQuerier(q Querier, id string, sealed string) error
+	List(f ListFilter) ([]*Dataset, error)
 }
 
 /*
@@ -27,6 +33,14 @@ type FileMover interface {
 	MoveFile(srcPath, dstPath string) error
 }
 
+/*
+AuditRecorder records an audit event using the provided Querier, which
+may be a transaction.
+*/
+type AuditRecorder interface {
+	RecordWithQuerier(ctx context.Context, q audit.Querier, e *audit.Event) error
+}
+
 type TxBeginner interface {
 	Begin(ctx context.Context) (pgx.Tx, error)
 }
@@ -36,20 +50,25 @@ type DefaultCreator struct {
 	uploadMover UploadMover
 	fileMover   FileMover
 	db          TxBeginner
+	audit       AuditRecorder
 }
 
-func NewCreator(s *Store, u UploadMover, f FileMover, db TxBeginner) *DefaultCreator {
+func NewCreator(s *Store, u UploadMover, f FileMover, db TxBeginner, a AuditRecorder) *DefaultCreator {
 	return &DefaultCreator{
 		s:           s,
 		uploadMover: u,
 		fileMover:   f,
 		db:          db,
+		audit:       a,
 	}
 }
 
 /*
 Create a new dataset and move any artefacts from temporary storage
-to a sensible forever home.
+to a sensible forever home. Once everything is in place the version is
+sealed, and the creation is recorded in the audit log, as part of the same
+transaction. The creator is taken from the verified
+identity on ctx, never from the request body.
 */
 func (c *DefaultCreator) Create(ctx context.Context, d *Dataset) (created *Dataset, err error) {
 	tx, err := c.db.Begin(ctx)
@@ -62,6 +81,8 @@ func (c *DefaultCreator) Create(ctx context.Context, d *Dataset) (created *Datas
 		}
 	}()
 
+	stampCreator(ctx, d)
+
 	created, err = c.s.createWithQuerier(tx, d)
 	if err != nil {
 		return nil, err
@@ -72,18 +93,31 @@ func (c *DefaultCreator) Create(ctx context.Context, d *Dataset) (created *Datas
 		if err != nil {
 			return nil, fmt.Errorf("get upload %s: %w", id, err)
 		}
+		if upload.Quarantined {
+			return nil, fmt.Errorf("upload %s: %w", id, uploads.ErrQuarantined)
+		}
 
 		newFiles := make(map[string]uploads.FileRef, len(upload.Files))
 		for name, file := range upload.Files {
 			origPath := filepath.Join(file.Path, file.FileName)
 			newPath := filepath.Join("datasets", created.ID)
 			if err := c.fileMover.MoveFile(origPath, filepath.Join(newPath, file.FileName)); err != nil {
-				return nil, fmt.Errorf("move file %s: %w", file, err)
+				return nil, fmt.Errorf("move file %s: %w", file.FileName, err)
 			}
 			newFiles[name] = uploads.FileRef{
 				Provider: file.Provider,
 				FileName: file.FileName,
 				Path:     newPath,
+				Digest:   file.Digest,
+				Size:     file.Size,
+				Scans:    file.Scans,
+				Profile:  file.Profile,
+			}
+			if file.Profile != nil {
+				if created.Profiles == nil {
+					created.Profiles = map[string]*tabular.Profile{}
+				}
+				created.Profiles[name] = file.Profile
 			}
 		}
 
@@ -94,6 +128,18 @@ func (c *DefaultCreator) Create(ctx context.Context, d *Dataset) (created *Datas
 		}
 	}
 
+	if created.Seal, err = c.seal(tx, created.ID); err != nil {
+		return nil, err
+	}
+
+	if c.audit != nil {
+		e := audit.NewEvent(ctx, audit.ActionCreate, audit.ResourceDataset, created.ID).
+			WithDetails(map[string]string{"name": created.Name, "version": created.Version, "seal": created.Seal})
+		if err = c.audit.RecordWithQuerier(ctx, tx, e); err != nil {
+			return nil, fmt.Errorf("record audit event: %w", err)
+		}
+	}
+
 	if err = tx.Commit(ctx); err != nil {
 		return nil, err
 	}
@@ -101,6 +147,41 @@ func (c *DefaultCreator) Create(ctx context.Context, d *Dataset) (created *Datas
 	return created, nil
 }
 
+/*
+seal hashes the dataset as it now stands in the database, so that exactly
+what will be read back later is what gets sealed, and stores the result.
+*/
+func (c *DefaultCreator) seal(q Querier, id string) (string, error) {
+	r, err := c.s.recordWithQuerier(q, id)
+	if err != nil {
+		return "", fmt.Errorf("load dataset to seal: %w", err)
+	}
+
+	sealed, err := r.Compute()
+	if err != nil {
+		return "", fmt.Errorf("compute seal: %w", err)
+	}
+
+	if err := c.s.sealWithQuerier(q, id, sealed); err != nil {
+		return "", fmt.Errorf("store seal: %w", err)
+	}
+
+	return sealed, nil
+}
+
+/*
+stampCreator overwrites the provenance fields of d with the identity
+on ctx, discarding anything the client sent.
+*/
+func stampCreator(ctx context.Context, d *Dataset) {
+	d.CreatedBy = ""
+	d.Tenant = ""
+	if id, ok := auth.IdentityFromContext(ctx); ok {
+		d.CreatedBy = id.Subject
+		d.Tenant = id.Tenant
+	}
+}
+
 func pointerTo[T any](v T) *T {
 	return &v
 }
@@ -6,6 +6,10 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/heldtogether/traintrack/internal/seal"
+	"github.com/heldtogether/traintrack/internal/tabular"
 	"github.com/heldtogether/traintrack/internal/uploads"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -26,15 +30,33 @@ func (m *MockUploadsStore) MoveWithQuerier(_ uploads.Querier, u *uploads.Upload)
 
 type MockDatasetsStore struct {
 	CreateFunc func(ctx context.Context, d *Dataset) (*Dataset, error)
-	ListFunc   func() ([]*Dataset, error)
+	ListFunc   func(f ListFilter) ([]*Dataset, error)
+	RecordFunc func(id string) (*seal.Record, error)
+	SealFunc   func(id string, sealed string) error
 }
 
 func (m *MockDatasetsStore) createWithQuerier(_ Querier, d *Dataset) (*Dataset, error) {
 	return m.CreateFunc(context.Background(), d)
 }
 
-func (m *MockDatasetsStore) List() ([]*Dataset, error) {
-	return m.ListFunc()
+func (m *MockDatasetsStore) List(f ListFilter) ([]*Dataset, error) {
+	return m.ListFunc(f)
+}
+
+func (m *MockDatasetsStore) recordWithQuerier(_ Querier, id string) (*seal.Record, error) {
+	return m.RecordFunc(id)
+}
+
+func (m *MockDatasetsStore) sealWithQuerier(_ Querier, id string, sealed string) error {
+	return m.SealFunc(id, sealed)
+}
+
+type MockAuditRecorder struct {
+	RecordFunc func(e *audit.Event) error
+}
+
+func (m *MockAuditRecorder) RecordWithQuerier(_ context.Context, _ audit.Querier, e *audit.Event) error {
+	return m.RecordFunc(e)
 }
 
 type mockDB struct {
@@ -98,8 +120,11 @@ func TestService_Create(t *testing.T) {
 		name              string
 		failCreate        bool
 		failGetUpload     bool
+		quarantined       bool
 		failMoveFile      bool
 		failMoveUpload    bool
+		failSeal          bool
+		failAudit         bool
 		failCommit        bool
 		wantCalled        []string
 		expectCreateError bool
@@ -111,6 +136,9 @@ func TestService_Create(t *testing.T) {
 				"get-upload",
 				"move-file temp/path/artifact.txt -> datasets/ds456/artifact.txt",
 				"move-upload",
+				"load-record",
+				"seal",
+				"record-audit",
 				"commit",
 			},
 		},
@@ -126,6 +154,12 @@ func TestService_Create(t *testing.T) {
 			wantCalled:        []string{"create-dataset", "get-upload", "rollback"},
 			expectCreateError: true,
 		},
+		{
+			name:              "upload quarantined",
+			quarantined:       true,
+			wantCalled:        []string{"create-dataset", "get-upload", "rollback"},
+			expectCreateError: true,
+		},
 		{
 			name:              "move file fails",
 			failMoveFile:      true,
@@ -138,10 +172,22 @@ func TestService_Create(t *testing.T) {
 			wantCalled:        []string{"create-dataset", "get-upload", "move-file temp/path/artifact.txt -> datasets/ds456/artifact.txt", "move-upload", "rollback"},
 			expectCreateError: true,
 		},
+		{
+			name:              "seal fails",
+			failSeal:          true,
+			wantCalled:        []string{"create-dataset", "get-upload", "move-file temp/path/artifact.txt -> datasets/ds456/artifact.txt", "move-upload", "load-record", "seal", "rollback"},
+			expectCreateError: true,
+		},
+		{
+			name:              "audit fails",
+			failAudit:         true,
+			wantCalled:        []string{"create-dataset", "get-upload", "move-file temp/path/artifact.txt -> datasets/ds456/artifact.txt", "move-upload", "load-record", "seal", "record-audit", "rollback"},
+			expectCreateError: true,
+		},
 		{
 			name:              "commit fails",
 			failCommit:        true,
-			wantCalled:        []string{"create-dataset", "get-upload", "move-file temp/path/artifact.txt -> datasets/ds456/artifact.txt", "move-upload", "commit", "rollback"},
+			wantCalled:        []string{"create-dataset", "get-upload", "move-file temp/path/artifact.txt -> datasets/ds456/artifact.txt", "move-upload", "load-record", "seal", "record-audit", "commit", "rollback"},
 			expectCreateError: true,
 		},
 	}
@@ -179,6 +225,17 @@ func TestService_Create(t *testing.T) {
 						UploadIds: d.UploadIds,
 					}, nil
 				},
+				RecordFunc: func(id string) (*seal.Record, error) {
+					called = append(called, "load-record")
+					return &seal.Record{Kind: "test", ID: id}, nil
+				},
+				SealFunc: func(id string, sealed string) error {
+					called = append(called, "seal")
+					if tc.failSeal {
+						return errors.New("boom")
+					}
+					return nil
+				},
 			}
 
 			mockUploadStore := &MockUploadsStore{
@@ -188,17 +245,27 @@ func TestService_Create(t *testing.T) {
 						return nil, errors.New("boom")
 					}
 					return &uploads.Upload{
-						ID: uploadID,
+						ID:          uploadID,
+						Quarantined: tc.quarantined,
 						Files: map[string]uploads.FileRef{
 							"artefact": {
 								Provider: uploads.ProviderFileSystem,
 								FileName: fileName,
 								Path:     "temp/path/",
+								Digest:   "sha256:abc",
+								Size:     3,
+								Profile:  &tabular.Profile{Format: tabular.FormatCSV, Rows: 2, Columns: 1},
 							}},
 					}, nil
 				},
 				MoveFunc: func(ctx context.Context, u *uploads.Upload) error {
 					called = append(called, "move-upload")
+					if f := u.Files["artefact"]; f.Digest != "sha256:abc" || f.Size != 3 {
+						return fmt.Errorf("digest not carried over: %+v", f)
+					}
+					if f := u.Files["artefact"]; f.Profile == nil || f.Profile.Rows != 2 {
+						return fmt.Errorf("profile not carried over: %+v", f)
+					}
 					if tc.failMoveUpload {
 						return errors.New("boom")
 					}
@@ -221,10 +288,19 @@ func TestService_Create(t *testing.T) {
 				uploadMover: mockUploadStore,
 				fileMover:   mockStorage,
 				db:          mockDB,
+				audit: &MockAuditRecorder{
+					RecordFunc: func(e *audit.Event) error {
+						called = append(called, "record-audit")
+						if tc.failAudit {
+							return errors.New("boom")
+						}
+						return nil
+					},
+				},
 			}
 
 			ctx := context.Background()
-			_, err := creator.Create(ctx, &Dataset{UploadIds: map[string]string{"file1": uploadID}})
+			created, err := creator.Create(ctx, &Dataset{UploadIds: map[string]string{"file1": uploadID}})
 
 			if tc.expectCreateError && err == nil {
 				t.Fatalf("expected error, got nil")
@@ -232,6 +308,9 @@ func TestService_Create(t *testing.T) {
 			if !tc.expectCreateError && err != nil {
 				t.Fatalf("unexpected error: %v", err)
 			}
+			if err == nil && created.Profiles["artefact"] == nil {
+				t.Errorf("expected the artefact's profile on the created dataset, got %+v", created.Profiles)
+			}
 
 			for i, step := range tc.wantCalled {
 				if i >= len(called) || called[i] != step {
@@ -245,3 +324,62 @@ func TestService_Create(t *testing.T) {
 		})
 	}
 }
+
+func TestService_CreateStampsCreator(t *testing.T) {
+	tests := []struct {
+		name          string
+		identity      *auth.Identity
+		wantCreatedBy string
+		wantTenant    string
+	}{
+		{
+			name:          "from token",
+			identity:      &auth.Identity{Subject: "dev|1", Tenant: "acme"},
+			wantCreatedBy: "dev|1",
+			wantTenant:    "acme",
+		},
+		{
+			name: "no identity",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var called []string
+			mockPgx, _ := pgxmock.NewConn()
+			baseTx, _ := mockPgx.Begin(context.Background())
+			tx := &loggingTx{Tx: baseTx, log: &called}
+
+			var stored *Dataset
+			creator := &DefaultCreator{
+				s: &MockDatasetsStore{
+					CreateFunc: func(ctx context.Context, d *Dataset) (*Dataset, error) {
+						stored = d
+						return &Dataset{ID: "1", CreatedBy: d.CreatedBy, Tenant: d.Tenant}, nil
+					},
+					RecordFunc: func(id string) (*seal.Record, error) {
+						return &seal.Record{ID: id}, nil
+					},
+					SealFunc: func(id string, sealed string) error {
+						return nil
+					},
+				},
+				db: &mockDB{tx: tx},
+			}
+
+			ctx := context.Background()
+			if tc.identity != nil {
+				ctx = auth.NewContext(ctx, tc.identity)
+			}
+
+			_, err := creator.Create(ctx, &Dataset{CreatedBy: "mallory", Tenant: "evil"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if stored.CreatedBy != tc.wantCreatedBy || stored.Tenant != tc.wantTenant {
+				t.Errorf("got created_by %q tenant %q, want %q %q", stored.CreatedBy, stored.Tenant, tc.wantCreatedBy, tc.wantTenant)
+			}
+		})
+	}
+}
@@ -0,0 +1,270 @@
+package datasets
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/heldtogether/traintrack/internal/uploads"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+type MockDeleterStore struct {
+	tenant   string
+	deleted  bool
+	purged   bool
+	users    []string
+	children []string
+	holders  []string
+	files    []uploads.FileRef
+	called   *[]string
+}
+
+func (m *MockDeleterStore) getWithQuerier(_ Querier, id string) (*Dataset, error) {
+	*m.called = append(*m.called, "get")
+	return &Dataset{ID: id, Name: "prices", Version: "1.0.0"}, nil
+}
+
+func (m *MockDeleterStore) lockWithQuerier(_ Querier, tenant string, id string) (bool, bool, error) {
+	*m.called = append(*m.called, "lock")
+	if tenant != m.tenant {
+		return false, false, internal.ErrNotFound
+	}
+	return m.deleted, m.purged, nil
+}
+
+func (m *MockDeleterStore) usersWithQuerier(_ Querier, id string) ([]string, error) {
+	return m.users, nil
+}
+
+func (m *MockDeleterStore) childrenWithQuerier(_ Querier, id string) ([]string, error) {
+	return m.children, nil
+}
+
+func (m *MockDeleterStore) holdsWithQuerier(_ Querier, id string) ([]string, error) {
+	return m.holders, nil
+}
+
+func (m *MockDeleterStore) setDeletedWithQuerier(_ Querier, id string, deleted bool) error {
+	if deleted {
+		*m.called = append(*m.called, "trash")
+	} else {
+		*m.called = append(*m.called, "restore")
+	}
+	return nil
+}
+
+func (m *MockDeleterStore) purgeWithQuerier(_ Querier, id string) error {
+	*m.called = append(*m.called, "purge")
+	return nil
+}
+
+func (m *MockDeleterStore) filesWithQuerier(_ Querier, id string) ([]uploads.FileRef, error) {
+	return m.files, nil
+}
+
+type MockFileRemover struct {
+	RemoveFunc func(path string) error
+}
+
+func (m *MockFileRemover) RemoveFile(path string) error {
+	return m.RemoveFunc(path)
+}
+
+func TestDeleter(t *testing.T) {
+	tests := []struct {
+		name        string
+		do          func(d *DefaultDeleter) (*Dataset, error)
+		deleted     bool
+		purged      bool
+		users       []string
+		children    []string
+		holders     []string
+		failRemove  bool
+		wantCalled  []string
+		wantErr     error
+		wantAction  audit.Action
+		wantDetails string
+	}{
+		{
+			name:        "delete",
+			do:          func(d *DefaultDeleter) (*Dataset, error) { return d.Delete(context.Background(), "1", false) },
+			wantCalled:  []string{"lock", "trash", "get", "record-audit", "commit"},
+			wantAction:  audit.ActionDelete,
+			wantDetails: `{"force":false,"name":"prices","references":[],"version":"1.0.0"}`,
+		},
+		{
+			name:       "delete in use",
+			do:         func(d *DefaultDeleter) (*Dataset, error) { return d.Delete(context.Background(), "1", false) },
+			users:      []string{"model regressor 1.0.0"},
+			children:   []string{"dataset prices 1.0.1"},
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrInUse,
+		},
+		{
+			name:        "delete in use with force",
+			do:          func(d *DefaultDeleter) (*Dataset, error) { return d.Delete(context.Background(), "1", true) },
+			users:       []string{"model regressor 1.0.0"},
+			wantCalled:  []string{"lock", "trash", "get", "record-audit", "commit"},
+			wantAction:  audit.ActionDelete,
+			wantDetails: `{"force":true,"name":"prices","references":["model regressor 1.0.0"],"version":"1.0.0"}`,
+		},
+		{
+			name:       "delete held with force",
+			do:         func(d *DefaultDeleter) (*Dataset, error) { return d.Delete(context.Background(), "1", true) },
+			holders:    []string{"legal"},
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrHeld,
+		},
+		{
+			name:       "delete twice",
+			do:         func(d *DefaultDeleter) (*Dataset, error) { return d.Delete(context.Background(), "1", false) },
+			deleted:    true,
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrInTrash,
+		},
+		{
+			name:        "restore",
+			do:          func(d *DefaultDeleter) (*Dataset, error) { return d.Restore(context.Background(), "1") },
+			deleted:     true,
+			wantCalled:  []string{"lock", "restore", "get", "record-audit", "commit"},
+			wantAction:  audit.ActionRestore,
+			wantDetails: `{"name":"prices","version":"1.0.0"}`,
+		},
+		{
+			name:       "restore not in trash",
+			do:         func(d *DefaultDeleter) (*Dataset, error) { return d.Restore(context.Background(), "1") },
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrNotInTrash,
+		},
+		{
+			name:       "restore purged",
+			do:         func(d *DefaultDeleter) (*Dataset, error) { return d.Restore(context.Background(), "1") },
+			deleted:    true,
+			purged:     true,
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrPurged,
+		},
+		{
+			name:        "purge",
+			do:          func(d *DefaultDeleter) (*Dataset, error) { return d.Purge(context.Background(), "1") },
+			deleted:     true,
+			children:    []string{"dataset prices 1.0.1"},
+			wantCalled:  []string{"lock", "purge", "get", "record-audit", "commit", "remove datasets/1/train.csv"},
+			wantAction:  audit.ActionPurge,
+			wantDetails: `{"files":["datasets/1/train.csv"],"name":"prices","version":"1.0.0"}`,
+		},
+		{
+			name:       "purge not in trash",
+			do:         func(d *DefaultDeleter) (*Dataset, error) { return d.Purge(context.Background(), "1") },
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrNotInTrash,
+		},
+		{
+			name:       "purge held",
+			do:         func(d *DefaultDeleter) (*Dataset, error) { return d.Purge(context.Background(), "1") },
+			deleted:    true,
+			holders:    []string{"legal", "audit"},
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrHeld,
+		},
+		{
+			name:       "purge used by a model",
+			do:         func(d *DefaultDeleter) (*Dataset, error) { return d.Purge(context.Background(), "1") },
+			deleted:    true,
+			users:      []string{"model regressor 1.0.0"},
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrInUse,
+		},
+		{
+			name:       "purge used by a trashed model",
+			do:         func(d *DefaultDeleter) (*Dataset, error) { return d.Purge(context.Background(), "1") },
+			deleted:    true,
+			users:      []string{"model regressor 1.0.0 (in the trash)"},
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrInUse,
+		},
+		{
+			name:       "purge fails to remove files",
+			do:         func(d *DefaultDeleter) (*Dataset, error) { return d.Purge(context.Background(), "1") },
+			deleted:    true,
+			failRemove: true,
+			wantCalled: []string{"lock", "purge", "get", "record-audit", "commit", "remove datasets/1/train.csv"},
+		},
+		{
+			name: "another tenant's dataset",
+			do: func(d *DefaultDeleter) (*Dataset, error) {
+				ctx := auth.NewContext(context.Background(), &auth.Identity{Subject: "dev|2", Tenant: "globex"})
+				return d.Purge(ctx, "1")
+			},
+			deleted:    true,
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var called []string
+
+			mockPgx, _ := pgxmock.NewConn()
+			baseTx, _ := mockPgx.Begin(context.Background())
+			tx := &loggingTx{Tx: baseTx, log: &called}
+
+			var recorded *audit.Event
+			d := &DefaultDeleter{
+				s: &MockDeleterStore{
+					deleted:  tc.deleted,
+					purged:   tc.purged,
+					users:    tc.users,
+					children: tc.children,
+					holders:  tc.holders,
+					files:    []uploads.FileRef{{FileName: "train.csv", Path: "datasets/1"}},
+					called:   &called,
+				},
+				files: &MockFileRemover{
+					RemoveFunc: func(path string) error {
+						called = append(called, "remove "+path)
+						if tc.failRemove {
+							return errors.New("remove boom")
+						}
+						return nil
+					},
+				},
+				db: &mockDB{tx: tx},
+				audit: &MockAuditRecorder{
+					RecordFunc: func(e *audit.Event) error {
+						called = append(called, "record-audit")
+						recorded = e
+						return nil
+					},
+				},
+			}
+
+			got, err := tc.do(d)
+			if !reflect.DeepEqual(called, tc.wantCalled) {
+				t.Errorf("got calls %v, wanted %v", called, tc.wantCalled)
+			}
+			if tc.wantErr != nil || tc.failRemove {
+				if err == nil || (tc.wantErr != nil && !errors.Is(err, tc.wantErr)) {
+					t.Fatalf("got error %v, wanted %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if got.ID != "1" {
+				t.Errorf("got dataset %+v", got)
+			}
+			if recorded == nil || recorded.Action != tc.wantAction || recorded.ResourceType != audit.ResourceDataset || string(recorded.Details) != tc.wantDetails {
+				t.Errorf("unexpected audit event: %+v", recorded)
+			}
+		})
+	}
+}
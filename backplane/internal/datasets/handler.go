@@ -3,17 +3,22 @@ package datasets
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	en_translations "github.com/go-playground/validator/v10/translations/en"
+	"github.com/gorilla/mux"
 	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/seal"
+	"github.com/heldtogether/traintrack/internal/uploads"
 )
 
 /*
@@ -27,18 +32,37 @@ type Creator interface {
 Lister allows Datasets to be listed.
 */
 type Lister interface {
-	List() ([]*Dataset, error)
+	List(f ListFilter) ([]*Dataset, error)
+}
+
+/*
+Verifier checks a Dataset version, and those it was derived from, against
+their seals.
+*/
+type Verifier interface {
+	Verify(id string) (*seal.Report, error)
+}
+
+/*
+Deleter moves Datasets to and from the trash, and purges them.
+*/
+type Deleter interface {
+	Delete(ctx context.Context, id string, force bool) (*Dataset, error)
+	Restore(ctx context.Context, id string) (*Dataset, error)
+	Purge(ctx context.Context, id string) (*Dataset, error)
 }
 
 type Handler struct {
 	c Creator
 	l Lister
+	v Verifier
+	d Deleter
 
 	validator *validator.Validate
 	trans     ut.Translator
 }
 
-func NewHandler(c Creator, l Lister) *Handler {
+func NewHandler(c Creator, l Lister, v Verifier, d Deleter) *Handler {
 	validator := validator.New(validator.WithRequiredStructEnabled())
 	validator.RegisterTagNameFunc(func(fld reflect.StructField) string {
 		tag := fld.Tag.Get("json")
@@ -57,6 +81,8 @@ func NewHandler(c Creator, l Lister) *Handler {
 	return &Handler{
 		c:         c,
 		l:         l,
+		v:         v,
+		d:         d,
 		validator: validator,
 		trans:     trans,
 	}
@@ -114,10 +140,14 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 
 	created, err := h.c.Create(r.Context(), d)
 	if err != nil {
+		code := http.StatusInternalServerError
+		if errors.Is(err, uploads.ErrQuarantined) {
+			code = http.StatusConflict
+		}
 		log.Printf("failed to create dataset: %s", err)
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(code)
 		json.NewEncoder(w).Encode(&internal.Error{
-			Code:    http.StatusInternalServerError,
+			Code:    code,
 			Message: "Failed to create dataset",
 			Reason:  err.Error(),
 		})
@@ -127,8 +157,24 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(created)
 }
 
+/*
+List returns datasets, optionally filtered by the query parameters
+created_by, created_after and created_before (RFC 3339).
+*/
 func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
-	ds, err := h.l.List()
+	f, err := parseListFilter(r)
+	if err != nil {
+		log.Printf("failed to parse list filter: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to list datasets",
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	ds, err := h.l.List(f)
 	if err != nil {
 		log.Printf("failed to list datasets: %s", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -141,3 +187,195 @@ func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
 	}
 	json.NewEncoder(w).Encode(ds)
 }
+
+/*
+Verify recomputes the seal of the dataset `id` in the URL, and of every
+version it was derived from, reporting whether any of them, or their
+artefacts, have been tampered with since they were created. It should be
+registered under something like /datasets/{id}/verify.
+*/
+func (h *Handler) Verify(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusMethodNotAllowed,
+			Message: "Method not allowed",
+			Reason:  "",
+		})
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	report, err := h.v.Verify(id)
+	if errors.Is(err, internal.ErrNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusNotFound,
+			Message: "Dataset not found",
+			Reason:  err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("failed to verify dataset %s: %s", id, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to verify dataset",
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	if !report.Valid {
+		log.Printf("dataset %s failed verification", id)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
+func parseListFilter(r *http.Request) (ListFilter, error) {
+	q := r.URL.Query()
+
+	f := ListFilter{
+		CreatedBy: q.Get("created_by"),
+	}
+
+	for name, dst := range map[string]**time.Time{"created_after": &f.CreatedAfter, "created_before": &f.CreatedBefore} {
+		if v := q.Get(name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, fmt.Errorf("invalid %s: %s", name, err)
+			}
+			*dst = &t
+		}
+	}
+
+	return f, nil
+}
+
+/*
+Dataset handles requests for the dataset `id` in the URL. Only DELETE is
+supported, which moves the dataset to the trash; pass force=true to
+delete it even though other versions depend on it. It should be
+registered under something like /datasets/{id}.
+*/
+func (h *Handler) Dataset(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		methodNotAllowed(w)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	force := r.URL.Query().Get("force") == "true"
+
+	deleted, err := h.d.Delete(r.Context(), id, force)
+	if err != nil {
+		writeDeleteError(w, "Failed to delete dataset", id, err)
+		return
+	}
+	json.NewEncoder(w).Encode(deleted)
+}
+
+/*
+Trash lists the datasets which have been deleted, including those which
+have been purged. It should be registered under something like
+/datasets/trash.
+*/
+func (h *Handler) Trash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	ds, err := h.l.List(ListFilter{Deleted: true})
+	if err != nil {
+		log.Printf("failed to list deleted datasets: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to list deleted datasets",
+			Reason:  err.Error(),
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(ds)
+}
+
+/*
+Restore takes the dataset `id` in the URL out of the trash. It should be
+registered under something like /datasets/{id}/restore.
+*/
+func (h *Handler) Restore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	restored, err := h.d.Restore(r.Context(), id)
+	if err != nil {
+		writeDeleteError(w, "Failed to restore dataset", id, err)
+		return
+	}
+	json.NewEncoder(w).Encode(restored)
+}
+
+/*
+Purge permanently removes the artefacts of the dataset `id` in the URL,
+which must already be in the trash. It should be registered under
+something like /datasets/{id}/purge.
+*/
+func (h *Handler) Purge(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	purged, err := h.d.Purge(r.Context(), id)
+	if err != nil {
+		writeDeleteError(w, "Failed to purge dataset", id, err)
+		return
+	}
+	json.NewEncoder(w).Encode(purged)
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    http.StatusMethodNotAllowed,
+		Message: "Method not allowed",
+		Reason:  "",
+	})
+}
+
+/*
+writeDeleteError answers a failed delete, restore or purge: a 404 if the
+dataset doesn't exist, a 409 if it isn't in a state to be changed that
+way, and a 500 otherwise.
+*/
+func writeDeleteError(w http.ResponseWriter, message string, id string, err error) {
+	log.Printf("%s %s: %s", strings.ToLower(message), id, err)
+
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		code = http.StatusNotFound
+		message = "Dataset not found"
+	case errors.Is(err, internal.ErrInUse),
+		errors.Is(err, internal.ErrInTrash),
+		errors.Is(err, internal.ErrNotInTrash),
+		errors.Is(err, internal.ErrPurged),
+		errors.Is(err, internal.ErrHeld):
+		code = http.StatusConflict
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    code,
+		Message: message,
+		Reason:  err.Error(),
+	})
+}
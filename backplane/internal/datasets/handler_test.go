@@ -4,33 +4,61 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/seal"
 )
 
 type mockCreatorAndLister struct {
 	CreateFn func(ctx context.Context, d *Dataset) (*Dataset, error)
-	ListFn   func() ([]*Dataset, error)
+	ListFn   func(f ListFilter) ([]*Dataset, error)
+	VerifyFn func(id string) (*seal.Report, error)
+
+	DeleteFn  func(ctx context.Context, id string, force bool) (*Dataset, error)
+	RestoreFn func(ctx context.Context, id string) (*Dataset, error)
+	PurgeFn   func(ctx context.Context, id string) (*Dataset, error)
 }
 
 func (m *mockCreatorAndLister) Create(ctx context.Context, d *Dataset) (*Dataset, error) {
 	return m.CreateFn(ctx, d)
 }
 
-func (m *mockCreatorAndLister) List() ([]*Dataset, error) {
-	return m.ListFn()
+func (m *mockCreatorAndLister) List(f ListFilter) ([]*Dataset, error) {
+	return m.ListFn(f)
+}
+
+func (m *mockCreatorAndLister) Verify(id string) (*seal.Report, error) {
+	return m.VerifyFn(id)
+}
+
+func (m *mockCreatorAndLister) Delete(ctx context.Context, id string, force bool) (*Dataset, error) {
+	return m.DeleteFn(ctx, id, force)
+}
+
+func (m *mockCreatorAndLister) Restore(ctx context.Context, id string) (*Dataset, error) {
+	return m.RestoreFn(ctx, id)
+}
+
+func (m *mockCreatorAndLister) Purge(ctx context.Context, id string) (*Dataset, error) {
+	return m.PurgeFn(ctx, id)
 }
 
 func TestRouter(t *testing.T) {
 	tests := []struct {
 		name             string
 		method           string
+		query            string
 		body             string
-		listDatasetsFn   func() ([]*Dataset, error)
+		listDatasetsFn   func(f ListFilter) ([]*Dataset, error)
 		createDatasetFn  func(ctx context.Context, d *Dataset) (*Dataset, error)
 		expectedStatus   int
 		expectedContains string
@@ -38,31 +66,52 @@ func TestRouter(t *testing.T) {
 		{
 			name:   "GET success",
 			method: http.MethodGet,
-			listDatasetsFn: func() ([]*Dataset, error) {
+			listDatasetsFn: func(f ListFilter) ([]*Dataset, error) {
 				return []*Dataset{{ID: "1", UploadIds: map[string]string{"file1": "abc"}}}, nil
 			},
 			expectedStatus:   http.StatusOK,
-			expectedContains: `[{"id": "1", "name": "", "parent": null, "version":"", "description":"", "artefacts": {"file1": "abc"}}]`,
+			expectedContains: `[{"id": "1", "name": "", "parent": null, "version":"", "description":"", "artefacts": {"file1": "abc"}, "created_at": "0001-01-01T00:00:00Z", "created_by": ""}]`,
 		},
 		{
 			name:   "GET failure",
 			method: http.MethodGet,
-			listDatasetsFn: func() ([]*Dataset, error) {
+			listDatasetsFn: func(f ListFilter) ([]*Dataset, error) {
 				return nil, errors.New("boom")
 			},
 			expectedStatus:   http.StatusInternalServerError,
 			expectedContains: `{"code": 500, "error": "Failed to list datasets", "reason": "boom"}`,
 		},
+		{
+			name:   "GET success - filtered",
+			method: http.MethodGet,
+			query:  "?created_by=dev%7C1&created_after=2025-07-01T00:00:00Z",
+			listDatasetsFn: func(f ListFilter) ([]*Dataset, error) {
+				if f.CreatedBy != "dev|1" || f.CreatedAfter == nil || f.CreatedBefore != nil {
+					return nil, errors.New("unexpected filter")
+				}
+				return []*Dataset{}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `[]`,
+		},
+		{
+			name:             "GET failure - bad filter",
+			method:           http.MethodGet,
+			query:            "?created_before=yesterday",
+			listDatasetsFn:   nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to list datasets", "reason": "invalid created_before: parsing time \"yesterday\" as \"2006-01-02T15:04:05Z07:00\": cannot parse \"yesterday\" as \"2006\""}`,
+		},
 		{
 			name:           "POST success",
 			method:         http.MethodPost,
 			body:           `{"id": "", "name": "name", "parent": null, "version": "version", "description": "description"}`,
 			listDatasetsFn: nil,
 			createDatasetFn: func(_ context.Context, r *Dataset) (*Dataset, error) {
-				return &Dataset{ID: "123", Name: "name", Parent: nil, Version: "version", Description: "description", UploadIds: map[string]string{}}, nil
+				return &Dataset{ID: "123", Name: "name", Parent: nil, Version: "version", Description: "description", UploadIds: map[string]string{}, CreatedAt: time.Date(2025, 7, 2, 9, 0, 0, 0, time.UTC), CreatedBy: "dev|1"}, nil
 			},
 			expectedStatus:   http.StatusCreated,
-			expectedContains: `{"id": "123", "name": "name", "parent": null, "version": "version", "description": "description", "artefacts": {}}`,
+			expectedContains: `{"id": "123", "name": "name", "parent": null, "version": "version", "description": "description", "artefacts": {}, "created_at": "2025-07-02T09:00:00Z", "created_by": "dev|1"}`,
 		},
 		{
 			name:           "POST failure - unparseable request",
@@ -120,14 +169,14 @@ func TestRouter(t *testing.T) {
 				CreateFn: tc.createDatasetFn,
 				ListFn:   tc.listDatasetsFn,
 			}
-			handler := NewHandler(mockService, mockService)
+			handler := NewHandler(mockService, mockService, mockService, mockService)
 
 			var bodyReader io.Reader
 			if tc.body != "" {
 				bodyReader = strings.NewReader(tc.body)
 			}
 
-			req := httptest.NewRequest(tc.method, "/datasets", bodyReader)
+			req := httptest.NewRequest(tc.method, "/datasets"+tc.query, bodyReader)
 			rr := httptest.NewRecorder()
 
 			handler.Datasets(rr, req)
@@ -138,6 +187,210 @@ func TestRouter(t *testing.T) {
 	}
 }
 
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		verifyFn         func(id string) (*seal.Report, error)
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "valid",
+			method: http.MethodGet,
+			verifyFn: func(id string) (*seal.Report, error) {
+				return &seal.Report{ID: id, Valid: true, Chain: []*seal.Result{{Kind: "dataset", ID: id, Seal: "sha256:aa", Computed: "sha256:aa", Valid: true}}}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"id": "1", "valid": true, "chain": [{"kind": "dataset", "id": "1", "seal": "sha256:aa", "computed": "sha256:aa", "valid": true}]}`,
+		},
+		{
+			name:   "tampered",
+			method: http.MethodGet,
+			verifyFn: func(id string) (*seal.Report, error) {
+				return &seal.Report{ID: id, Valid: false, Chain: []*seal.Result{{Kind: "dataset", ID: id, Seal: "sha256:aa", Computed: "sha256:bb", Problems: []string{"stored seal does not match"}}}}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"id": "1", "valid": false, "chain": [{"kind": "dataset", "id": "1", "seal": "sha256:aa", "computed": "sha256:bb", "valid": false, "problems": ["stored seal does not match"]}]}`,
+		},
+		{
+			name:   "not found",
+			method: http.MethodGet,
+			verifyFn: func(id string) (*seal.Report, error) {
+				return nil, fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+			},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Dataset not found", "reason": "dataset 1: not found"}`,
+		},
+		{
+			name:   "failure",
+			method: http.MethodGet,
+			verifyFn: func(id string) (*seal.Report, error) {
+				return nil, errors.New("boom")
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedContains: `{"code": 500, "error": "Failed to verify dataset", "reason": "boom"}`,
+		},
+		{
+			name:             "METHOD failure",
+			method:           http.MethodPost,
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockService := &mockCreatorAndLister{VerifyFn: tc.verifyFn}
+			handler := NewHandler(mockService, mockService, mockService, mockService)
+
+			req := httptest.NewRequest(tc.method, "/datasets/1/verify", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			rr := httptest.NewRecorder()
+
+			handler.Verify(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func TestTrash(t *testing.T) {
+	deletedAt := time.Date(2025, 7, 7, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		handle           func(h *Handler) http.HandlerFunc
+		deleteFn         func(ctx context.Context, id string, force bool) (*Dataset, error)
+		restoreFn        func(ctx context.Context, id string) (*Dataset, error)
+		purgeFn          func(ctx context.Context, id string) (*Dataset, error)
+		listFn           func(f ListFilter) ([]*Dataset, error)
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "DELETE success",
+			method: http.MethodDelete,
+			path:   "/datasets/1",
+			handle: func(h *Handler) http.HandlerFunc { return h.Dataset },
+			deleteFn: func(_ context.Context, id string, force bool) (*Dataset, error) {
+				if force {
+					return nil, errors.New("unexpected force")
+				}
+				return &Dataset{ID: id, UploadIds: map[string]string{}, DeletedAt: &deletedAt}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"id": "1", "name": "", "parent": null, "version": "", "description": "", "artefacts": {}, "created_at": "0001-01-01T00:00:00Z", "created_by": "", "deleted_at": "2025-07-07T09:00:00Z"}`,
+		},
+		{
+			name:   "DELETE in use",
+			method: http.MethodDelete,
+			path:   "/datasets/1",
+			handle: func(h *Handler) http.HandlerFunc { return h.Dataset },
+			deleteFn: func(_ context.Context, id string, force bool) (*Dataset, error) {
+				return nil, fmt.Errorf("dataset %s is %w by model 2", id, internal.ErrInUse)
+			},
+			expectedStatus:   http.StatusConflict,
+			expectedContains: `{"code": 409, "error": "Failed to delete dataset", "reason": "dataset 1 is still in use by model 2"}`,
+		},
+		{
+			name:   "DELETE force",
+			method: http.MethodDelete,
+			path:   "/datasets/1?force=true",
+			handle: func(h *Handler) http.HandlerFunc { return h.Dataset },
+			deleteFn: func(_ context.Context, id string, force bool) (*Dataset, error) {
+				if !force {
+					return nil, errors.New("expected force")
+				}
+				return &Dataset{ID: id, UploadIds: map[string]string{}}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"id": "1", "name": "", "parent": null, "version": "", "description": "", "artefacts": {}, "created_at": "0001-01-01T00:00:00Z", "created_by": ""}`,
+		},
+		{
+			name:   "DELETE not found",
+			method: http.MethodDelete,
+			path:   "/datasets/1",
+			handle: func(h *Handler) http.HandlerFunc { return h.Dataset },
+			deleteFn: func(_ context.Context, id string, force bool) (*Dataset, error) {
+				return nil, fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+			},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Dataset not found", "reason": "dataset 1: not found"}`,
+		},
+		{
+			name:             "DELETE METHOD failure",
+			method:           http.MethodGet,
+			path:             "/datasets/1",
+			handle:           func(h *Handler) http.HandlerFunc { return h.Dataset },
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+		{
+			name:   "GET trash",
+			method: http.MethodGet,
+			path:   "/datasets/trash",
+			handle: func(h *Handler) http.HandlerFunc { return h.Trash },
+			listFn: func(f ListFilter) ([]*Dataset, error) {
+				if !f.Deleted {
+					return nil, errors.New("expected deleted filter")
+				}
+				return []*Dataset{}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `[]`,
+		},
+		{
+			name:   "restore not in trash",
+			method: http.MethodPost,
+			path:   "/datasets/1/restore",
+			handle: func(h *Handler) http.HandlerFunc { return h.Restore },
+			restoreFn: func(_ context.Context, id string) (*Dataset, error) {
+				return nil, fmt.Errorf("dataset %s is %w", id, internal.ErrNotInTrash)
+			},
+			expectedStatus:   http.StatusConflict,
+			expectedContains: `{"code": 409, "error": "Failed to restore dataset", "reason": "dataset 1 is not in the trash"}`,
+		},
+		{
+			name:   "purge failure",
+			method: http.MethodPost,
+			path:   "/datasets/1/purge",
+			handle: func(h *Handler) http.HandlerFunc { return h.Purge },
+			purgeFn: func(_ context.Context, id string) (*Dataset, error) {
+				return nil, errors.New("boom")
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedContains: `{"code": 500, "error": "Failed to purge dataset", "reason": "boom"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockService := &mockCreatorAndLister{
+				ListFn:    tc.listFn,
+				DeleteFn:  tc.deleteFn,
+				RestoreFn: tc.restoreFn,
+				PurgeFn:   tc.purgeFn,
+			}
+			handler := NewHandler(mockService, mockService, mockService, mockService)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			rr := httptest.NewRecorder()
+
+			tc.handle(handler)(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
 func checkResponse(t *testing.T, got *http.Response, expectedStatus int, expected string) {
 
 	defer got.Body.Close()
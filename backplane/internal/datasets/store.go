@@ -2,9 +2,19 @@ package datasets
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/seal"
+	"github.com/heldtogether/traintrack/internal/tabular"
+	"github.com/heldtogether/traintrack/internal/uploads"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
 type Dataset struct {
@@ -15,38 +25,190 @@ type Dataset struct {
 	Description string  `json:"description" validate:"required"`
 
 	UploadIds map[string]string `json:"artefacts"`
+	// Profiles summarise the CSV and Parquet artefacts, by name. They're
+	// worked out by the server when the artefacts are uploaded. See
+	// package tabular.
+	Profiles map[string]*tabular.Profile `json:"profiles,omitempty"`
+
+	// CreatedAt, CreatedBy and Tenant are set by the server from the
+	// verified token when the dataset is created. Any values sent by the
+	// client are ignored.
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+	Tenant    string    `json:"tenant,omitempty"`
+
+	// Seal is the tamper-evident hash of this version, chained to its
+	// parent's. See package seal.
+	Seal string `json:"seal,omitempty"`
+
+	// DeletedAt is set while the dataset is in the trash, and PurgedAt
+	// once its artefacts have been permanently removed.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	PurgedAt  *time.Time `json:"purged_at,omitempty"`
+
+	// TaintedBy is the first erasure request which found this dataset
+	// holds, or was derived from, data which had to be erased. See
+	// package erasure.
+	TaintedBy *string `json:"tainted_by,omitempty"`
+
+	// Held is set while the dataset is under a legal hold, which stops it
+	// being deleted or purged. See package holds.
+	Held bool `json:"held,omitempty"`
 }
 
-func (m *Dataset) GetID() string          { return m.ID }
-func (m *Dataset) GetName() string        { return m.Name }
-func (m *Dataset) GetDescription() string { return m.Description }
-func (m *Dataset) GetVersion() string     { return m.Version }
-func (m *Dataset) GetParent() *string     { return m.Parent }
+func (m *Dataset) GetID() string           { return m.ID }
+func (m *Dataset) GetName() string         { return m.Name }
+func (m *Dataset) GetDescription() string  { return m.Description }
+func (m *Dataset) GetVersion() string      { return m.Version }
+func (m *Dataset) GetParent() *string      { return m.Parent }
+func (m *Dataset) GetCreatedAt() time.Time { return m.CreatedAt }
+func (m *Dataset) GetCreatedBy() string    { return m.CreatedBy }
+
+/*
+GetMarkers returns the flags shown next to the dataset in trees: "tainted"
+once an erasure request has reached it, and "held" while it is under a
+legal hold.
+*/
+func (m *Dataset) GetMarkers() []string {
+	var markers []string
+	if m.TaintedBy != nil {
+		markers = append(markers, "tainted")
+	}
+	if m.Held {
+		markers = append(markers, "held")
+	}
+	return markers
+}
 
 const (
 	createQuery = `INSERT INTO datasets 
-(name, parent, version, description) 
-VALUES ($1, $2, $3, $4) 
-RETURNING id`
+(name, parent, version, description, created_by, tenant) 
+VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, '')) 
+RETURNING id, created_at`
 	listQuery = `SELECT 
   d.id,
   d.name,
   d.parent,
   d.version,
   d.description,
+  d.created_at,
+  COALESCE(d.created_by, ''),
+  COALESCE(d.tenant, ''),
+  COALESCE(d.seal, ''),
+  d.deleted_at,
+  d.purged_at,
+  d.tainted_by::text,
+  EXISTS (
+    SELECT 1 FROM legal_holds h
+    WHERE h.resource_type = 'dataset' AND h.resource_id = d.id AND h.released_at IS NULL
+  ),
   COALESCE(
     jsonb_object_agg(file_key, u.id) FILTER (WHERE file_key IS NOT NULL),
     '{}'::jsonb
-  ) AS artefacts
+  ) AS artefacts,
+  COALESCE(
+    jsonb_object_agg(file_key, u.files->file_key->'profile') FILTER (WHERE u.files->file_key ? 'profile'),
+    '{}'::jsonb
+  ) AS profiles
 FROM datasets d
 LEFT JOIN uploads u ON u.dataset_id = d.id
-LEFT JOIN LATERAL jsonb_object_keys(u.files) AS file_key ON true
-GROUP BY d.id, d.name, d.parent, d.version, d.description;`
+LEFT JOIN LATERAL jsonb_object_keys(u.files) AS file_key ON true`
+	listGroupBy = `
+GROUP BY d.id, d.name, d.parent, d.version, d.description, d.created_at, d.created_by, d.tenant, d.seal, d.deleted_at, d.purged_at, d.tainted_by
+ORDER BY d.created_at, d.id;`
+	recordQuery = `SELECT 
+  d.id,
+  d.name,
+  d.parent,
+  d.version,
+  d.description,
+  d.created_at,
+  COALESCE(d.created_by, ''),
+  COALESCE(d.tenant, ''),
+  COALESCE(d.seal, ''),
+  COALESCE(p.seal, ''),
+  d.purged_at IS NOT NULL
+FROM datasets d
+LEFT JOIN datasets p ON p.id = d.parent
+WHERE d.id = $1`
+	artefactsQuery = `SELECT id, files FROM uploads WHERE dataset_id = $1`
+	sealQuery      = `UPDATE datasets SET seal = $1 WHERE id = $2 AND seal IS NULL`
+	getClause      = `
+WHERE d.id = $1`
+	lockQuery  = `SELECT deleted_at IS NOT NULL, purged_at IS NOT NULL FROM datasets WHERE id = $1 AND COALESCE(tenant, '') = $2 FOR UPDATE`
+	usersQuery = `SELECT 'model ' || name || ' ' || version || CASE WHEN deleted_at IS NOT NULL THEN ' (in the trash)' ELSE '' END
+FROM models
+WHERE dataset = $1 AND purged_at IS NULL
+ORDER BY created_at, id`
+	childrenQuery = `SELECT 'dataset ' || name || ' ' || version FROM datasets
+WHERE parent = $1 AND deleted_at IS NULL
+ORDER BY created_at, id`
+	holdsQuery = `SELECT holder FROM legal_holds
+WHERE resource_type = 'dataset' AND resource_id = $1 AND released_at IS NULL
+ORDER BY placed_at, id`
+	trashQuery   = `UPDATE datasets SET deleted_at = now() WHERE id = $1`
+	restoreQuery = `UPDATE datasets SET deleted_at = NULL WHERE id = $1`
+	purgeQuery   = `UPDATE datasets SET purged_at = now() WHERE id = $1`
 )
 
+/*
+sealedFields is the dataset metadata covered by its seal.
+*/
+type sealedFields struct {
+	Name        string    `json:"name"`
+	Version     string    `json:"version"`
+	Description string    `json:"description"`
+	Parent      *string   `json:"parent"`
+	CreatedAt   time.Time `json:"created_at"`
+	CreatedBy   string    `json:"created_by"`
+	Tenant      string    `json:"tenant"`
+}
+
+/*
+ListFilter narrows down the datasets returned by List. Zero values match
+everything that hasn't been deleted. Deleted lists the trash instead.
+*/
+type ListFilter struct {
+	CreatedBy     string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Deleted       bool
+}
+
+/*
+sql builds the WHERE clause for the filter, returning it with its
+positional arguments.
+*/
+func (f ListFilter) sql() (string, []any) {
+	// Deleted versions are hidden everywhere but the trash.
+	conds := []string{"d.deleted_at IS NULL"}
+	if f.Deleted {
+		conds = []string{"d.deleted_at IS NOT NULL"}
+	}
+	var args []any
+
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.CreatedBy != "" {
+		add("d.created_by = $%d", f.CreatedBy)
+	}
+	if f.CreatedAfter != nil {
+		add("d.created_at >= $%d", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		add("d.created_at < $%d", *f.CreatedBefore)
+	}
+
+	return "\nWHERE " + strings.Join(conds, " AND "), args
+}
+
 type Querier interface {
 	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
 }
 
 type Store struct {
@@ -76,10 +238,13 @@ func (s *Store) createWithQuerier(q Querier, d *Dataset) (*Dataset, error) {
 		d.Parent,
 		d.Version,
 		d.Description,
+		d.CreatedBy,
+		d.Tenant,
 	)
 
 	var id string
-	if err := row.Scan(&id); err != nil {
+	var createdAt time.Time
+	if err := row.Scan(&id, &createdAt); err != nil {
 		return nil, err
 	}
 
@@ -89,16 +254,22 @@ func (s *Store) createWithQuerier(q Querier, d *Dataset) (*Dataset, error) {
 		Parent:      d.Parent,
 		Version:     d.Version,
 		Description: d.Description,
+		CreatedAt:   createdAt,
+		CreatedBy:   d.CreatedBy,
+		Tenant:      d.Tenant,
 	}, nil
 }
 
 /*
-List returns a list of all known Datasets.
+List returns a list of all known Datasets matching f, oldest first.
 */
-func (s *Store) List() ([]*Dataset, error) {
+func (s *Store) List(f ListFilter) ([]*Dataset, error) {
+	clause, args := f.sql()
+
 	rows, err := s.q.Query(
 		context.TODO(),
-		listQuery,
+		listQuery+clause+listGroupBy,
+		args...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("could not query datasets: %s", err)
@@ -108,15 +279,8 @@ func (s *Store) List() ([]*Dataset, error) {
 
 	ds := []*Dataset{}
 	for rows.Next() {
-		d := &Dataset{}
-		if err := rows.Scan(
-			&d.ID,
-			&d.Name,
-			&d.Parent,
-			&d.Version,
-			&d.Description,
-			&d.UploadIds,
-		); err != nil {
+		d, err := scanDataset(rows)
+		if err != nil {
 			return nil, err
 		}
 		ds = append(ds, d)
@@ -125,3 +289,252 @@ func (s *Store) List() ([]*Dataset, error) {
 	return ds, nil
 
 }
+
+/*
+Get returns the dataset id, deleted or not, or an error wrapping
+internal.ErrNotFound if there is no such dataset.
+*/
+func (s *Store) Get(id string) (*Dataset, error) {
+	return s.getWithQuerier(s.q, id)
+}
+
+/*
+Profiles returns the profiles of the dataset id's artefacts, by name.
+Artefacts which weren't profiled are left out.
+*/
+func (s *Store) Profiles(id string) (map[string]*tabular.Profile, error) {
+	d, err := s.getWithQuerier(s.q, id)
+	if err != nil {
+		return nil, err
+	}
+	return d.Profiles, nil
+}
+
+func (s *Store) getWithQuerier(q Querier, id string) (*Dataset, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+	}
+
+	row := q.QueryRow(context.Background(), listQuery+getClause+listGroupBy, id)
+	d, err := scanDataset(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not query dataset: %w", err)
+	}
+	return d, nil
+}
+
+func scanDataset(row pgx.Row) (*Dataset, error) {
+	d := &Dataset{}
+	if err := row.Scan(
+		&d.ID,
+		&d.Name,
+		&d.Parent,
+		&d.Version,
+		&d.Description,
+		&d.CreatedAt,
+		&d.CreatedBy,
+		&d.Tenant,
+		&d.Seal,
+		&d.DeletedAt,
+		&d.PurgedAt,
+		&d.TaintedBy,
+		&d.Held,
+		&d.UploadIds,
+		&d.Profiles,
+	); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+/*
+Record returns everything covered by the seal of the dataset id, along
+with the seal stored for it.
+*/
+func (s *Store) Record(id string) (*seal.Record, error) {
+	return s.recordWithQuerier(s.q, id)
+}
+
+func (s *Store) recordWithQuerier(q Querier, id string) (*seal.Record, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+	}
+
+	var f sealedFields
+	r := &seal.Record{
+		Kind:      "dataset",
+		Fields:    &f,
+		Artefacts: map[string]seal.Artefact{},
+	}
+
+	row := q.QueryRow(context.Background(), recordQuery, id)
+	if err := row.Scan(
+		&r.ID,
+		&f.Name,
+		&f.Parent,
+		&f.Version,
+		&f.Description,
+		&f.CreatedAt,
+		&f.CreatedBy,
+		&f.Tenant,
+		&r.Seal,
+		&r.ParentSeal,
+		&r.Purged,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not query dataset: %w", err)
+	}
+	// Seal in UTC so the hash doesn't depend on the server's time zone.
+	f.CreatedAt = f.CreatedAt.UTC()
+	if f.Parent != nil {
+		r.ParentID = *f.Parent
+	}
+
+	rows, err := q.Query(context.Background(), artefactsQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not query artefacts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var uploadID string
+		var files map[string]uploads.FileRef
+		if err := rows.Scan(&uploadID, &files); err != nil {
+			return nil, err
+		}
+		for name, file := range files {
+			r.Artefacts[name] = seal.Artefact{
+				Upload:   uploadID,
+				FileName: file.FileName,
+				Digest:   file.Digest,
+				Size:     file.Size,
+				Path:     file.Path,
+			}
+		}
+	}
+
+	return r, rows.Err()
+}
+
+// Don't export, sealing is part of creating a dataset.
+func (s *Store) sealWithQuerier(q Querier, id string, sealed string) error {
+	tag, err := q.Exec(context.Background(), sealQuery, sealed, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		return fmt.Errorf("dataset %s is already sealed", id)
+	}
+	return nil
+}
+
+// Don't export, we only want people using the designated deleter
+// struct to ensure that references are checked. Another tenant's dataset
+// is reported as not found.
+func (s *Store) lockWithQuerier(q Querier, tenant string, id string) (deleted bool, purged bool, err error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return false, false, fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+	}
+	if err := q.QueryRow(context.Background(), lockQuery, id, tenant).Scan(&deleted, &purged); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, false, fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+		}
+		return false, false, err
+	}
+	return deleted, purged, nil
+}
+
+/*
+usersWithQuerier describes the models, which haven't been purged, trained
+on the dataset id. A model in the trash still counts, as it can be
+restored.
+*/
+func (s *Store) usersWithQuerier(q Querier, id string) ([]string, error) {
+	return describeWithQuerier(q, usersQuery, id)
+}
+
+/*
+childrenWithQuerier describes the datasets, which haven't been deleted,
+derived from the dataset id.
+*/
+func (s *Store) childrenWithQuerier(q Querier, id string) ([]string, error) {
+	return describeWithQuerier(q, childrenQuery, id)
+}
+
+/*
+holdsWithQuerier returns who holds each active legal hold on the dataset
+id.
+*/
+func (s *Store) holdsWithQuerier(q Querier, id string) ([]string, error) {
+	return describeWithQuerier(q, holdsQuery, id)
+}
+
+func describeWithQuerier(q Querier, query string, id string) ([]string, error) {
+	rows, err := q.Query(context.Background(), query, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not query references: %w", err)
+	}
+	defer rows.Close()
+
+	var refs []string
+	for rows.Next() {
+		var ref string
+		if err := rows.Scan(&ref); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// Don't export, we only want people using the designated deleter
+// struct to ensure that references are checked.
+func (s *Store) setDeletedWithQuerier(q Querier, id string, deleted bool) error {
+	query := restoreQuery
+	if deleted {
+		query = trashQuery
+	}
+	_, err := q.Exec(context.Background(), query, id)
+	return err
+}
+
+// Don't export, we only want people using the designated deleter
+// struct to ensure that the artefacts are removed.
+func (s *Store) purgeWithQuerier(q Querier, id string) error {
+	_, err := q.Exec(context.Background(), purgeQuery, id)
+	return err
+}
+
+/*
+filesWithQuerier returns every artefact stored for the dataset id.
+*/
+func (s *Store) filesWithQuerier(q Querier, id string) ([]uploads.FileRef, error) {
+	rows, err := q.Query(context.Background(), artefactsQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not query artefacts: %w", err)
+	}
+	defer rows.Close()
+
+	var refs []uploads.FileRef
+	for rows.Next() {
+		var uploadID string
+		var files map[string]uploads.FileRef
+		if err := rows.Scan(&uploadID, &files); err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(files))
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			refs = append(refs, files[name])
+		}
+	}
+	return refs, rows.Err()
+}
@@ -1,11 +1,18 @@
 package datasets
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
 	"testing"
+	"time"
 
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/seal"
+	"github.com/heldtogether/traintrack/internal/tabular"
+	"github.com/heldtogether/traintrack/internal/uploads"
+	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v4"
 )
 
@@ -18,8 +25,8 @@ func TestList(t *testing.T) {
 	}
 	defer db.Close()
 
-	rows := db.NewRows([]string{"id", "name", "parent", "version", "description", "artefacts"}).
-		AddRow("1", "", nil, "", "", make(map[string]string))
+	rows := db.NewRows([]string{"id", "name", "parent", "version", "description", "created_at", "created_by", "tenant", "seal", "deleted_at", "purged_at", "tainted_by", "held", "artefacts", "profiles"}).
+		AddRow("1", "", nil, "", "", time.Time{}, "", "", "", nil, nil, nil, false, make(map[string]string), map[string]*tabular.Profile{})
 
 	db.ExpectQuery(
 		regexp.QuoteMeta(listQuery),
@@ -28,7 +35,7 @@ func TestList(t *testing.T) {
 
 	service := NewStore(db)
 
-	ds, err := service.List()
+	ds, err := service.List(ListFilter{})
 	if err != nil {
 		t.Errorf("could not list: %s", err)
 	}
@@ -41,6 +48,69 @@ func TestList(t *testing.T) {
 	}
 }
 
+func TestListWithFilter(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	after := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	rows := db.NewRows([]string{"id", "name", "parent", "version", "description", "created_at", "created_by", "tenant", "seal", "deleted_at", "purged_at", "tainted_by", "held", "artefacts", "profiles"}).
+		AddRow("1", "", nil, "", "", after, "dev|1", "", "sha256:aa", nil, nil, nil, false, map[string]string{}, map[string]*tabular.Profile{})
+
+	db.ExpectQuery(
+		regexp.QuoteMeta(listQuery+"\nWHERE d.deleted_at IS NULL AND d.created_by = $1 AND d.created_at >= $2"+listGroupBy),
+	).
+		WithArgs("dev|1", after).
+		WillReturnRows(rows)
+
+	service := NewStore(db)
+
+	got, err := service.List(ListFilter{CreatedBy: "dev|1", CreatedAfter: &after})
+	if err != nil {
+		t.Fatalf("could not list: %s", err)
+	}
+	if len(got) != 1 || got[0].CreatedBy != "dev|1" || !got[0].CreatedAt.Equal(after) {
+		t.Errorf("unexpected result: %+v", got)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestListDeleted(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	deletedAt := time.Date(2025, 7, 7, 9, 0, 0, 0, time.UTC)
+
+	rows := db.NewRows([]string{"id", "name", "parent", "version", "description", "created_at", "created_by", "tenant", "seal", "deleted_at", "purged_at", "tainted_by", "held", "artefacts", "profiles"}).
+		AddRow("1", "", nil, "", "", time.Time{}, "", "", "", &deletedAt, nil, nil, false, map[string]string{}, map[string]*tabular.Profile{})
+
+	db.ExpectQuery(
+		regexp.QuoteMeta(listQuery + "\nWHERE d.deleted_at IS NOT NULL" + listGroupBy),
+	).
+		WillReturnRows(rows)
+
+	got, err := NewStore(db).List(ListFilter{Deleted: true})
+	if err != nil {
+		t.Fatalf("could not list: %s", err)
+	}
+	if len(got) != 1 || got[0].DeletedAt == nil || !got[0].DeletedAt.Equal(deletedAt) || got[0].PurgedAt != nil {
+		t.Errorf("unexpected result: %+v", got)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestListFailOnQuery(t *testing.T) {
 	db, err := pgxmock.NewPool()
 	if err != nil {
@@ -55,7 +125,7 @@ func TestListFailOnQuery(t *testing.T) {
 
 	service := NewStore(db)
 
-	ds, err := service.List()
+	ds, err := service.List(ListFilter{})
 	if err == nil {
 		t.Errorf("could not list: %s", err)
 	}
@@ -85,7 +155,7 @@ func TestListFailOnScan(t *testing.T) {
 
 	service := NewStore(db)
 
-	ds, err := service.List()
+	ds, err := service.List(ListFilter{})
 	if err == nil {
 		t.Errorf("expected error from Scan, got nil")
 	}
@@ -105,11 +175,13 @@ func TestCreate(t *testing.T) {
 	}
 	defer db.Close()
 
+	createdAt := time.Date(2025, 7, 2, 9, 0, 0, 0, time.UTC)
+
 	db.ExpectQuery(
 		regexp.QuoteMeta(createQuery),
 	).
-		WithArgs("name", nilStr, "1.0.0", "description").
-		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("1"))
+		WithArgs("name", nilStr, "1.0.0", "description", "dev|1", "acme").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow("1", createdAt))
 
 	service := NewStore(db)
 
@@ -119,6 +191,9 @@ func TestCreate(t *testing.T) {
 		Parent:      nil,
 		Version:     "1.0.0",
 		Description: "description",
+		CreatedAt:   createdAt,
+		CreatedBy:   "dev|1",
+		Tenant:      "acme",
 	}
 	got, err := service.create(
 		&Dataset{
@@ -126,6 +201,8 @@ func TestCreate(t *testing.T) {
 			Parent:      nil,
 			Version:     "1.0.0",
 			Description: "description",
+			CreatedBy:   "dev|1",
+			Tenant:      "acme",
 		})
 	if err != nil {
 		t.Errorf("unexpected error: %s", err)
@@ -152,8 +229,8 @@ func TestCreateFailOnScan(t *testing.T) {
 	db.ExpectQuery(
 		regexp.QuoteMeta(createQuery),
 	).
-		WithArgs("name", nilStr, "1.0.0", "description").
-		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(unscannable{}))
+		WithArgs("name", nilStr, "1.0.0", "description", "dev|1", "acme").
+		WillReturnRows(pgxmock.NewRows([]string{"id", "created_at"}).AddRow(unscannable{}, time.Time{}))
 
 	service := NewStore(db)
 
@@ -163,6 +240,8 @@ func TestCreateFailOnScan(t *testing.T) {
 			Parent:      nil,
 			Version:     "1.0.0",
 			Description: "description",
+			CreatedBy:   "dev|1",
+			Tenant:      "acme",
 		})
 	if err == nil {
 		t.Errorf("expected error from Scan, got nil")
@@ -175,3 +254,256 @@ func TestCreateFailOnScan(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestRecord(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	id := "9f9b8055-0000-4000-8000-000000000001"
+	parent := "9f9b8055-0000-4000-8000-000000000000"
+	createdAt := time.Date(2025, 7, 2, 9, 0, 0, 0, time.FixedZone("AEST", 10*60*60))
+
+	db.ExpectQuery(regexp.QuoteMeta(recordQuery)).
+		WithArgs(id).
+		WillReturnRows(db.NewRows([]string{"id", "name", "parent", "version", "description", "created_at", "created_by", "tenant", "seal", "parent_seal", "purged"}).
+			AddRow(id, "prices", &parent, "1.0.1", "clean", createdAt, "dev|1", "acme", "sha256:child", "sha256:parent", true))
+
+	db.ExpectQuery(regexp.QuoteMeta(artefactsQuery)).
+		WithArgs(id).
+		WillReturnRows(db.NewRows([]string{"id", "files"}).
+			AddRow("u1", map[string]uploads.FileRef{
+				"train": {FileName: "train.csv", Path: "datasets/1", Digest: "sha256:ff", Size: 8},
+			}))
+
+	r, err := NewStore(db).Record(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if r.Kind != "dataset" || r.ID != id || r.ParentID != parent || r.Seal != "sha256:child" || r.ParentSeal != "sha256:parent" || !r.Purged {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	fields := r.Fields.(*sealedFields)
+	if fields.Name != "prices" || fields.CreatedAt.Location() != time.UTC {
+		t.Errorf("unexpected fields: %+v", fields)
+	}
+	want := seal.Artefact{Upload: "u1", FileName: "train.csv", Digest: "sha256:ff", Size: 8, Path: "datasets/1"}
+	if r.Artefacts["train"] != want {
+		t.Errorf("got artefact %+v, wanted %+v", r.Artefacts["train"], want)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRecordNotFound(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	id := "9f9b8055-0000-4000-8000-000000000001"
+	db.ExpectQuery(regexp.QuoteMeta(recordQuery)).
+		WithArgs(id).
+		WillReturnError(pgx.ErrNoRows)
+
+	service := NewStore(db)
+
+	if _, err := service.Record(id); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := service.Record("not-a-uuid"); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for malformed id, got %v", err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSeal(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.ExpectExec(regexp.QuoteMeta(sealQuery)).
+		WithArgs("sha256:aa", "1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	db.ExpectExec(regexp.QuoteMeta(sealQuery)).
+		WithArgs("sha256:bb", "1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	service := NewStore(db)
+
+	if err := service.sealWithQuerier(db, "1", "sha256:aa"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := service.sealWithQuerier(db, "1", "sha256:bb"); err == nil {
+		t.Errorf("expected resealing to fail")
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGet(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	id := "9f9b8055-0000-4000-8000-000000000001"
+	db.ExpectQuery(regexp.QuoteMeta(listQuery + getClause + listGroupBy)).
+		WithArgs(id).
+		WillReturnRows(db.NewRows([]string{"id", "name", "parent", "version", "description", "created_at", "created_by", "tenant", "seal", "deleted_at", "purged_at", "tainted_by", "held", "artefacts", "profiles"}).
+			AddRow(id, "prices", nil, "1.0.0", "clean", time.Time{}, "dev|1", "acme", "", nil, nil, nil, true,
+				map[string]string{"X_train": "u1"}, map[string]*tabular.Profile{"X_train": {Format: tabular.FormatCSV, Rows: 20640, Columns: 8}}))
+	db.ExpectQuery(regexp.QuoteMeta(listQuery + getClause + listGroupBy)).
+		WithArgs(id).
+		WillReturnError(pgx.ErrNoRows)
+
+	service := NewStore(db)
+
+	d, err := service.Get(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if d.ID != id || d.Name != "prices" || !d.Held || d.Profiles["X_train"] == nil || d.Profiles["X_train"].Rows != 20640 {
+		t.Errorf("unexpected dataset: %+v", d)
+	}
+	if _, err := service.Get(id); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := service.Get("not-a-uuid"); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for malformed id, got %v", err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestProfiles(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	id := "9f9b8055-0000-4000-8000-000000000001"
+	db.ExpectQuery(regexp.QuoteMeta(listQuery + getClause + listGroupBy)).
+		WithArgs(id).
+		WillReturnRows(db.NewRows([]string{"id", "name", "parent", "version", "description", "created_at", "created_by", "tenant", "seal", "deleted_at", "purged_at", "tainted_by", "held", "artefacts", "profiles"}).
+			AddRow(id, "prices", nil, "1.0.0", "clean", time.Time{}, "dev|1", "acme", "", nil, nil, nil, false,
+				map[string]string{"X_train": "u1", "notes": "u1"}, map[string]*tabular.Profile{"X_train": {Format: tabular.FormatCSV, Rows: 20640, Columns: 8}}))
+
+	profiles, err := NewStore(db).Profiles(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(profiles) != 1 || profiles["X_train"] == nil || profiles["X_train"].Rows != 20640 {
+		t.Errorf("unexpected profiles: %+v", profiles)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTrashQueries(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	id := "9f9b8055-0000-4000-8000-000000000001"
+	db.ExpectQuery(regexp.QuoteMeta(lockQuery)).
+		WithArgs(id, "acme").
+		WillReturnRows(db.NewRows([]string{"deleted", "purged"}).AddRow(true, false))
+	db.ExpectQuery(regexp.QuoteMeta(usersQuery)).
+		WithArgs(id).
+		WillReturnRows(db.NewRows([]string{"ref"}).AddRow("model regressor 1.0.0"))
+	db.ExpectQuery(regexp.QuoteMeta(childrenQuery)).
+		WithArgs(id).
+		WillReturnRows(db.NewRows([]string{"ref"}))
+	db.ExpectQuery(regexp.QuoteMeta(holdsQuery)).
+		WithArgs(id).
+		WillReturnRows(db.NewRows([]string{"holder"}).AddRow("legal"))
+	db.ExpectExec(regexp.QuoteMeta(trashQuery)).
+		WithArgs(id).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	db.ExpectExec(regexp.QuoteMeta(restoreQuery)).
+		WithArgs(id).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	db.ExpectExec(regexp.QuoteMeta(purgeQuery)).
+		WithArgs(id).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	db.ExpectQuery(regexp.QuoteMeta(artefactsQuery)).
+		WithArgs(id).
+		WillReturnRows(db.NewRows([]string{"id", "files"}).
+			AddRow("u1", map[string]uploads.FileRef{
+				"train": {FileName: "train.csv", Path: "datasets/1"},
+				"test":  {FileName: "test.csv", Path: "datasets/1"},
+			}))
+	db.ExpectQuery(regexp.QuoteMeta(lockQuery)).
+		WithArgs(id, "globex").
+		WillReturnRows(db.NewRows([]string{"deleted", "purged"}))
+
+	service := NewStore(db)
+
+	deleted, purged, err := service.lockWithQuerier(db, "acme", id)
+	if err != nil || !deleted || purged {
+		t.Errorf("got %t, %t, %v, wanted deleted and not purged", deleted, purged, err)
+	}
+	users, err := service.usersWithQuerier(db, id)
+	if err != nil || !reflect.DeepEqual(users, []string{"model regressor 1.0.0"}) {
+		t.Errorf("got users %v, %v", users, err)
+	}
+	children, err := service.childrenWithQuerier(db, id)
+	if err != nil || len(children) != 0 {
+		t.Errorf("got children %v, %v", children, err)
+	}
+	holders, err := service.holdsWithQuerier(db, id)
+	if err != nil || !reflect.DeepEqual(holders, []string{"legal"}) {
+		t.Errorf("got holders %v, %v", holders, err)
+	}
+	if err := service.setDeletedWithQuerier(db, id, true); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := service.setDeletedWithQuerier(db, id, false); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := service.purgeWithQuerier(db, id); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	files, err := service.filesWithQuerier(db, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	// Files are listed by name, so purges are recorded the same way each time.
+	want := []uploads.FileRef{{FileName: "test.csv", Path: "datasets/1"}, {FileName: "train.csv", Path: "datasets/1"}}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("got files %+v, wanted %+v", files, want)
+	}
+
+	if _, _, err := service.lockWithQuerier(db, "globex", id); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for another tenant's dataset, got %v", err)
+	}
+	if _, _, err := service.lockWithQuerier(db, "acme", "not-a-uuid"); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for malformed id, got %v", err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
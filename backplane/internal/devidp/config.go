@@ -0,0 +1,92 @@
+package devidp
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+/*
+User is an identity that can log in via the authorization code flow. Any
+Claims are merged into both the ID token and the access token.
+*/
+type User struct {
+	Subject string         `json:"sub"`
+	Email   string         `json:"email"`
+	Name    string         `json:"name"`
+	Claims  map[string]any `json:"claims,omitempty"`
+}
+
+/*
+Client is an OAuth client known to the provider. Clients without a Secret
+are public clients, like the CLI, and may only use the authorization code
+flow. Clients with a Secret may also use the client credentials flow, in
+which case Claims are added to the access token.
+*/
+type Client struct {
+	ID     string         `json:"client_id"`
+	Secret string         `json:"client_secret,omitempty"`
+	Claims map[string]any `json:"claims,omitempty"`
+}
+
+type Config struct {
+	// Audience is the API identifier access tokens are issued for. It
+	// should match the backplane's TRAINTRACK_AUTH_NAME.
+	Audience string   `json:"audience"`
+	Clients  []Client `json:"clients"`
+	// Users are the identities that can log in. The first user is used
+	// when an authorization request doesn't carry a login_hint.
+	Users    []User        `json:"users"`
+	TokenTTL time.Duration `json:"-"`
+}
+
+/*
+DefaultConfig returns a configuration with a single public client for the
+CLI and a single user, which is enough to run `traintrack login` against.
+*/
+func DefaultConfig() Config {
+	return Config{
+		Audience: "https://traintrack.local/api",
+		Clients: []Client{
+			{ID: "traintrack-cli"},
+		},
+		Users: []User{
+			{
+				Subject: "dev|1",
+				Email:   "dev@traintrack.local",
+				Name:    "Dev User",
+			},
+		},
+		TokenTTL: time.Hour,
+	}
+}
+
+/*
+LoadConfig reads a Config from a JSON file. Fields that are missing from
+the file are taken from DefaultConfig.
+*/
+func LoadConfig(path string) (Config, error) {
+	conf := DefaultConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return conf, err
+	}
+
+	var stored Config
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return conf, err
+	}
+
+	if stored.Audience != "" {
+		conf.Audience = stored.Audience
+	}
+	if len(stored.Clients) > 0 {
+		conf.Clients = stored.Clients
+	}
+	if len(stored.Users) > 0 {
+		conf.Users = stored.Users
+	}
+
+	return conf, nil
+}
@@ -0,0 +1,25 @@
+/*
+Package devidp provides a small, self-contained OpenID Connect identity
+provider for local development and tests.
+
+It serves just enough of the protocol for the traintrack CLI and the
+backplane's auth middleware to be exercised without reaching a real
+tenant: discovery, JWKS, the authorization code and device authorization
+flows (auto-approved for a configured user) and the client credentials
+flow.
+
+	idp, err := devidp.New(devidp.DefaultConfig())
+	...
+	idp.SetIssuer("http://localhost:9999")
+	http.ListenAndServe("localhost:9999", idp)
+
+In tests, NewTestServer starts the provider on a random port:
+
+	ts := devidp.NewTestServer(t, devidp.DefaultConfig())
+	ts.SetEnv(t)
+	token := ts.MustAccessToken(t, "dev|1")
+
+It is not intended to be exposed beyond localhost; every authorization
+request is approved without a password.
+*/
+package devidp
@@ -0,0 +1,707 @@
+package devidp
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+)
+
+const (
+	authorizePath = "/authorize"
+	tokenPath     = "/oauth/token"
+	jwksPath      = "/.well-known/jwks.json"
+	discoveryPath = "/.well-known/openid-configuration"
+	userInfoPath  = "/userinfo"
+	devicePath    = "/oauth/device/code"
+	activatePath  = "/activate"
+	revokePath    = "/oauth/revoke"
+
+	deviceCodeGrantType = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+/*
+Server is an in-memory OpenID Connect provider. It implements http.Handler.
+*/
+type Server struct {
+	conf   Config
+	issuer string
+
+	key    *rsa.PrivateKey
+	keyID  string
+	signer jose.Signer
+
+	mux *http.ServeMux
+	now func() time.Time
+
+	mu            sync.Mutex
+	codes         map[string]*grant
+	refreshTokens map[string]*grant
+	deviceCodes   map[string]*deviceAuthorization
+}
+
+/*
+grant records who was authenticated, for which client, and with what
+scope, so that tokens can be issued for it later.
+*/
+type grant struct {
+	user        *User
+	clientID    string
+	redirectURI string
+	scope       string
+	audience    string
+	nonce       string
+	expires     time.Time
+}
+
+/*
+deviceAuthorization is a pending device authorization grant. It is
+approved once someone visits the activation page with its user code.
+*/
+type deviceAuthorization struct {
+	grant
+	userCode string
+	approved bool
+}
+
+/*
+New creates a Server with a freshly generated signing key. The issuer
+must be set with SetIssuer before any tokens are handed out.
+*/
+func New(conf Config) (*Server, error) {
+	if conf.TokenTTL == 0 {
+		conf.TokenTTL = time.Hour
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("generate signing key: %w", err)
+	}
+	keyID := randomString(8)
+
+	signer, err := jose.NewSigner(
+		jose.SigningKey{
+			Algorithm: jose.RS256,
+			Key:       jose.JSONWebKey{Key: key, KeyID: keyID, Algorithm: string(jose.RS256)},
+		},
+		(&jose.SignerOptions{}).WithType("JWT"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create signer: %w", err)
+	}
+
+	s := &Server{
+		conf:          conf,
+		key:           key,
+		keyID:         keyID,
+		signer:        signer,
+		mux:           http.NewServeMux(),
+		now:           time.Now,
+		codes:         map[string]*grant{},
+		refreshTokens: map[string]*grant{},
+		deviceCodes:   map[string]*deviceAuthorization{},
+	}
+
+	s.mux.HandleFunc(discoveryPath, s.discovery)
+	s.mux.HandleFunc(jwksPath, s.jwks)
+	s.mux.HandleFunc(authorizePath, s.authorize)
+	s.mux.HandleFunc(tokenPath, s.token)
+	s.mux.HandleFunc(userInfoPath, s.userInfo)
+	s.mux.HandleFunc(devicePath, s.deviceAuthorize)
+	s.mux.HandleFunc(activatePath, s.activate)
+	s.mux.HandleFunc(revokePath, s.revoke)
+
+	return s, nil
+}
+
+/*
+SetIssuer sets the externally visible base URL of the provider. It must
+exactly match the URL clients use for discovery.
+*/
+func (s *Server) SetIssuer(issuer string) {
+	s.issuer = strings.TrimSuffix(issuer, "/")
+}
+
+func (s *Server) Issuer() string            { return s.issuer }
+func (s *Server) Audience() string          { return s.conf.Audience }
+func (s *Server) Config() Config            { return s.conf }
+func (s *Server) PublicKey() *rsa.PublicKey { return &s.key.PublicKey }
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+/*
+AccessToken mints an access token for the user identified by subject or
+email without going through an authorization flow. It is intended for
+tests and scripts.
+*/
+func (s *Server) AccessToken(user string) (string, error) {
+	u := s.findUser(user)
+	if u == nil {
+		return "", fmt.Errorf("unknown user %q", user)
+	}
+
+	clientID := ""
+	if len(s.conf.Clients) > 0 {
+		clientID = s.conf.Clients[0].ID
+	}
+
+	resp, err := s.issue(&grant{
+		user:     u,
+		clientID: clientID,
+		scope:    "openid profile email",
+		audience: s.conf.Audience,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.AccessToken, nil
+}
+
+func (s *Server) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                s.issuer,
+		"authorization_endpoint":                s.issuer + authorizePath,
+		"token_endpoint":                        s.issuer + tokenPath,
+		"jwks_uri":                              s.issuer + jwksPath,
+		"userinfo_endpoint":                     s.issuer + userInfoPath,
+		"device_authorization_endpoint":         s.issuer + devicePath,
+		"revocation_endpoint":                   s.issuer + revokePath,
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "client_credentials", "refresh_token", deviceCodeGrantType},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{string(jose.RS256)},
+		"scopes_supported":                      []string{"openid", "profile", "email", "offline_access"},
+	})
+}
+
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, jose.JSONWebKeySet{
+		Keys: []jose.JSONWebKey{{
+			Key:       &s.key.PublicKey,
+			KeyID:     s.keyID,
+			Algorithm: string(jose.RS256),
+			Use:       "sig",
+		}},
+	})
+}
+
+/*
+authorize approves every request immediately, redirecting back to the
+client with a code for the user named in login_hint, or the first
+configured user.
+*/
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+
+	if q.Get("response_type") != "code" {
+		http.Error(w, "unsupported response_type", http.StatusBadRequest)
+		return
+	}
+
+	client := s.findClient(q.Get("client_id"))
+	if client == nil {
+		http.Error(w, "unknown client_id", http.StatusBadRequest)
+		return
+	}
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirect.Scheme == "" || redirect.Host == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	user := s.defaultUser()
+	if hint := q.Get("login_hint"); hint != "" {
+		user = s.findUser(hint)
+	}
+	if user == nil {
+		http.Error(w, "unknown user", http.StatusBadRequest)
+		return
+	}
+
+	audience := q.Get("audience")
+	if audience == "" {
+		audience = s.conf.Audience
+	}
+
+	code := randomString(24)
+	s.mu.Lock()
+	s.codes[code] = &grant{
+		user:        user,
+		clientID:    client.ID,
+		redirectURI: q.Get("redirect_uri"),
+		scope:       q.Get("scope"),
+		audience:    audience,
+		nonce:       q.Get("nonce"),
+		expires:     s.now().Add(5 * time.Minute),
+	}
+	s.mu.Unlock()
+
+	params := redirect.Query()
+	params.Set("code", code)
+	if state := q.Get("state"); state != "" {
+		params.Set("state", state)
+	}
+	redirect.RawQuery = params.Encode()
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (s *Server) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeOAuthError(w, http.StatusMethodNotAllowed, "invalid_request", "method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	client, ok := s.authenticateClient(r)
+	if !ok {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	var g *grant
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		g = s.takeGrant(s.codes, r.PostForm.Get("code"))
+		if g == nil || g.clientID != client.ID || g.redirectURI != r.PostForm.Get("redirect_uri") {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "invalid or expired code")
+			return
+		}
+	case "refresh_token":
+		g = s.takeGrant(s.refreshTokens, r.PostForm.Get("refresh_token"))
+		if g == nil || g.clientID != client.ID {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_grant", "invalid refresh token")
+			return
+		}
+	case deviceCodeGrantType:
+		var errCode string
+		g, errCode = s.takeDeviceGrant(r.PostForm.Get("device_code"), client.ID)
+		if errCode != "" {
+			writeOAuthError(w, http.StatusBadRequest, errCode, "")
+			return
+		}
+	case "client_credentials":
+		if client.Secret == "" {
+			writeOAuthError(w, http.StatusUnauthorized, "unauthorized_client", "public clients cannot use client_credentials")
+			return
+		}
+		audience := r.PostForm.Get("audience")
+		if audience == "" {
+			audience = s.conf.Audience
+		}
+		g = &grant{
+			user: &User{
+				Subject: client.ID + "@clients",
+				Claims:  client.Claims,
+			},
+			clientID: client.ID,
+			scope:    r.PostForm.Get("scope"),
+			audience: audience,
+		}
+	default:
+		writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "")
+		return
+	}
+
+	resp, err := s.issue(g)
+	if err != nil {
+		writeOAuthError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, resp)
+}
+
+/*
+deviceAuthorize starts a device authorization grant, handing back a device
+code for the client to poll with and a user code for a person to enter at
+the activation page.
+*/
+func (s *Server) deviceAuthorize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeOAuthError(w, http.StatusMethodNotAllowed, "invalid_request", "method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	client, ok := s.authenticateClient(r)
+	if !ok {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	audience := r.PostForm.Get("audience")
+	if audience == "" {
+		audience = s.conf.Audience
+	}
+
+	deviceCode := randomString(32)
+	userCode := randomUserCode()
+	expiresIn := 10 * time.Minute
+
+	s.mu.Lock()
+	s.deviceCodes[deviceCode] = &deviceAuthorization{
+		grant: grant{
+			clientID: client.ID,
+			scope:    r.PostForm.Get("scope"),
+			audience: audience,
+			expires:  s.now().Add(expiresIn),
+		},
+		userCode: userCode,
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"device_code":               deviceCode,
+		"user_code":                 userCode,
+		"verification_uri":          s.issuer + activatePath,
+		"verification_uri_complete": s.issuer + activatePath + "?user_code=" + url.QueryEscape(userCode),
+		"expires_in":                int(expiresIn.Seconds()),
+		"interval":                  1,
+	})
+}
+
+/*
+activate approves the device authorization matching user_code for the
+user named in login_hint, or the first configured user. Without a code it
+shows a form to enter one.
+*/
+func (s *Server) activate(w http.ResponseWriter, r *http.Request) {
+	userCode := strings.ToUpper(strings.TrimSpace(r.FormValue("user_code")))
+	if userCode == "" {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<form method="get"><label>Code <input name="user_code"></label><button>Approve</button></form>`)
+		return
+	}
+
+	user := s.defaultUser()
+	if hint := r.FormValue("login_hint"); hint != "" {
+		user = s.findUser(hint)
+	}
+	if user == nil {
+		http.Error(w, "unknown user", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, d := range s.deviceCodes {
+		if d.userCode == userCode && s.now().Before(d.expires) {
+			d.user = user
+			d.approved = true
+			fmt.Fprintf(w, "Device approved for %s. You may close this window.", user.Subject)
+			return
+		}
+	}
+
+	http.Error(w, "unknown or expired code", http.StatusBadRequest)
+}
+
+/*
+takeDeviceGrant returns the grant for an approved device code, or the
+OAuth error code the polling client should see.
+*/
+func (s *Server) takeDeviceGrant(deviceCode string, clientID string) (*grant, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	d, ok := s.deviceCodes[deviceCode]
+	if !ok || d.clientID != clientID {
+		return nil, "invalid_grant"
+	}
+	if s.now().After(d.expires) {
+		delete(s.deviceCodes, deviceCode)
+		return nil, "expired_token"
+	}
+	if !d.approved {
+		return nil, "authorization_pending"
+	}
+
+	delete(s.deviceCodes, deviceCode)
+	return &d.grant, ""
+}
+
+/*
+revoke invalidates a refresh token (RFC 7009). Access tokens are
+self-contained JWTs and can't be revoked, so revoking one is a no-op, as
+is revoking an unknown token.
+*/
+func (s *Server) revoke(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeOAuthError(w, http.StatusMethodNotAllowed, "invalid_request", "method not allowed")
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+
+	client, ok := s.authenticateClient(r)
+	if !ok {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", "client authentication failed")
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	s.mu.Lock()
+	if g, ok := s.refreshTokens[token]; ok && g.clientID == client.ID {
+		delete(s.refreshTokens, token)
+	}
+	s.mu.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) userInfo(w http.ResponseWriter, r *http.Request) {
+	raw := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	jws, err := jose.ParseSigned(raw, []jose.SignatureAlgorithm{jose.RS256})
+	if err != nil {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_token", err.Error())
+		return
+	}
+	payload, err := jws.Verify(&s.key.PublicKey)
+	if err != nil {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_token", err.Error())
+		return
+	}
+
+	var claims struct {
+		Subject string `json:"sub"`
+		Expiry  int64  `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_token", err.Error())
+		return
+	}
+	if !s.now().Before(time.Unix(claims.Expiry, 0)) {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_token", "token has expired")
+		return
+	}
+
+	user := s.findUser(claims.Subject)
+	if user == nil {
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_token", "unknown subject")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, userClaims(user))
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+func (s *Server) issue(g *grant) (*tokenResponse, error) {
+	now := s.now()
+	exp := now.Add(s.conf.TokenTTL)
+
+	access := map[string]any{}
+	for k, v := range g.user.Claims {
+		access[k] = v
+	}
+	access["iss"] = s.issuer
+	access["sub"] = g.user.Subject
+	access["aud"] = g.audience
+	access["azp"] = g.clientID
+	access["iat"] = now.Unix()
+	access["exp"] = exp.Unix()
+	access["jti"] = randomString(12)
+	if g.scope != "" {
+		access["scope"] = g.scope
+	}
+
+	accessToken, err := s.sign(access)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &tokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(s.conf.TokenTTL.Seconds()),
+		Scope:       g.scope,
+	}
+
+	scopes := strings.Fields(g.scope)
+	if slices.Contains(scopes, "openid") {
+		id := userClaims(g.user)
+		id["iss"] = s.issuer
+		id["aud"] = g.clientID
+		id["iat"] = now.Unix()
+		id["exp"] = exp.Unix()
+		if g.nonce != "" {
+			id["nonce"] = g.nonce
+		}
+
+		resp.IDToken, err = s.sign(id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if slices.Contains(scopes, "offline_access") {
+		refresh := randomString(32)
+		s.mu.Lock()
+		s.refreshTokens[refresh] = &grant{
+			user:     g.user,
+			clientID: g.clientID,
+			scope:    g.scope,
+			audience: g.audience,
+		}
+		s.mu.Unlock()
+		resp.RefreshToken = refresh
+	}
+
+	return resp, nil
+}
+
+func (s *Server) sign(claims map[string]any) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	jws, err := s.signer.Sign(payload)
+	if err != nil {
+		return "", fmt.Errorf("sign token: %w", err)
+	}
+	return jws.CompactSerialize()
+}
+
+/*
+takeGrant removes and returns the grant stored under key, so codes and
+refresh tokens are single use.
+*/
+func (s *Server) takeGrant(grants map[string]*grant, key string) *grant {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := grants[key]
+	if !ok {
+		return nil
+	}
+	delete(grants, key)
+
+	if !g.expires.IsZero() && s.now().After(g.expires) {
+		return nil
+	}
+	return g
+}
+
+func (s *Server) authenticateClient(r *http.Request) (*Client, bool) {
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	client := s.findClient(id)
+	if client == nil {
+		return nil, false
+	}
+	if client.Secret != "" && client.Secret != secret {
+		return nil, false
+	}
+	return client, true
+}
+
+func (s *Server) findClient(id string) *Client {
+	for i := range s.conf.Clients {
+		if s.conf.Clients[i].ID == id {
+			return &s.conf.Clients[i]
+		}
+	}
+	return nil
+}
+
+func (s *Server) findUser(hint string) *User {
+	for i := range s.conf.Users {
+		u := &s.conf.Users[i]
+		if u.Subject == hint || (u.Email != "" && u.Email == hint) {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *Server) defaultUser() *User {
+	if len(s.conf.Users) == 0 {
+		return nil
+	}
+	return &s.conf.Users[0]
+}
+
+func userClaims(u *User) map[string]any {
+	claims := map[string]any{}
+	for k, v := range u.Claims {
+		claims[k] = v
+	}
+	claims["sub"] = u.Subject
+	if u.Email != "" {
+		claims["email"] = u.Email
+		claims["email_verified"] = true
+	}
+	if u.Name != "" {
+		claims["name"] = u.Name
+	}
+	return claims
+}
+
+func randomUserCode() string {
+	const alphabet = "BCDFGHJKLMNPQRSTVWXZ"
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	for i := range b {
+		b[i] = alphabet[int(b[i])%len(alphabet)]
+	}
+	return string(b[:4]) + "-" + string(b[4:])
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeOAuthError(w http.ResponseWriter, status int, code string, description string) {
+	writeJSON(w, status, map[string]string{
+		"error":             code,
+		"error_description": description,
+	})
+}
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
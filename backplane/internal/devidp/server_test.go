@@ -0,0 +1,294 @@
+package devidp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+func testConfig() Config {
+	conf := DefaultConfig()
+	conf.Clients = append(conf.Clients, Client{
+		ID:     "pipeline",
+		Secret: "s3cret",
+		Claims: map[string]any{"roles": []string{"trainer"}},
+	})
+	conf.Users = append(conf.Users, User{
+		Subject: "dev|2",
+		Email:   "second@traintrack.local",
+		Name:    "Second User",
+	})
+	return conf
+}
+
+func authorize(t *testing.T, ts *TestServer, config *oauth2.Config, opts ...oauth2.AuthCodeOption) url.Values {
+	t.Helper()
+
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	resp, err := client.Get(config.AuthCodeURL("state-123", opts...))
+	if err != nil {
+		t.Fatalf("authorize request failed: %s", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected redirect, got %d", resp.StatusCode)
+	}
+
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("invalid redirect: %s", err)
+	}
+	return location.Query()
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTestServer(t, testConfig())
+
+	p, err := oidc.NewProvider(ctx, ts.Issuer())
+	if err != nil {
+		t.Fatalf("discovery failed: %s", err)
+	}
+
+	config := &oauth2.Config{
+		ClientID:    "traintrack-cli",
+		RedirectURL: "http://localhost:42069/auth/callback",
+		Scopes:      []string{"openid", "profile", "email", "offline_access"},
+		Endpoint:    p.Endpoint(),
+	}
+
+	params := authorize(t, ts, config, oauth2.SetAuthURLParam("login_hint", "second@traintrack.local"))
+	if params.Get("state") != "state-123" {
+		t.Errorf("state not echoed, got %q", params.Get("state"))
+	}
+
+	token, err := config.Exchange(ctx, params.Get("code"))
+	if err != nil {
+		t.Fatalf("exchange failed: %s", err)
+	}
+	if token.RefreshToken == "" {
+		t.Errorf("expected a refresh token for offline_access")
+	}
+
+	rawIDToken, _ := token.Extra("id_token").(string)
+	idToken, err := p.Verifier(&oidc.Config{ClientID: "traintrack-cli"}).Verify(ctx, rawIDToken)
+	if err != nil {
+		t.Fatalf("id token did not verify: %s", err)
+	}
+
+	var claims struct {
+		Email string `json:"email"`
+		Name  string `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Email != "second@traintrack.local" || claims.Name != "Second User" {
+		t.Errorf("unexpected claims: %+v", claims)
+	}
+
+	_, err = p.Verifier(&oidc.Config{ClientID: ts.Audience()}).Verify(ctx, token.AccessToken)
+	if err != nil {
+		t.Errorf("access token did not verify against audience: %s", err)
+	}
+
+	if _, err := config.Exchange(ctx, params.Get("code")); err == nil {
+		t.Errorf("expected reused code to be rejected")
+	}
+
+	refreshed, err := config.TokenSource(ctx, &oauth2.Token{RefreshToken: token.RefreshToken}).Token()
+	if err != nil {
+		t.Fatalf("refresh failed: %s", err)
+	}
+	if refreshed.AccessToken == "" || refreshed.RefreshToken == token.RefreshToken {
+		t.Errorf("expected a new access token and rotated refresh token")
+	}
+}
+
+func TestAuthorizeRejectsUnknownClient(t *testing.T) {
+	ts := NewTestServer(t, testConfig())
+
+	resp, err := http.Get(ts.Issuer() + "/authorize?response_type=code&client_id=nope&redirect_uri=http://localhost/cb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", resp.StatusCode)
+	}
+}
+
+func TestClientCredentialsFlow(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTestServer(t, testConfig())
+
+	config := &clientcredentials.Config{
+		ClientID:     "pipeline",
+		ClientSecret: "s3cret",
+		TokenURL:     ts.Issuer() + "/oauth/token",
+	}
+
+	token, err := config.Token(ctx)
+	if err != nil {
+		t.Fatalf("client credentials failed: %s", err)
+	}
+
+	p, err := oidc.NewProvider(ctx, ts.Issuer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	accessToken, err := p.Verifier(&oidc.Config{ClientID: ts.Audience()}).Verify(ctx, token.AccessToken)
+	if err != nil {
+		t.Fatalf("access token did not verify: %s", err)
+	}
+	if accessToken.Subject != "pipeline@clients" {
+		t.Errorf("unexpected subject %q", accessToken.Subject)
+	}
+
+	config.ClientSecret = "wrong"
+	if _, err := config.Token(ctx); err == nil {
+		t.Errorf("expected bad secret to be rejected")
+	}
+}
+
+func TestPublicClientCannotUseClientCredentials(t *testing.T) {
+	ts := NewTestServer(t, testConfig())
+
+	config := &clientcredentials.Config{
+		ClientID: "traintrack-cli",
+		TokenURL: ts.Issuer() + "/oauth/token",
+	}
+
+	if _, err := config.Token(context.Background()); err == nil {
+		t.Errorf("expected public client to be rejected")
+	}
+}
+
+func TestAccessTokenUnknownUser(t *testing.T) {
+	ts := NewTestServer(t, testConfig())
+
+	if _, err := ts.AccessToken("nobody"); err == nil {
+		t.Errorf("expected error for unknown user")
+	}
+}
+
+func TestUserInfoRejectsExpiredTokens(t *testing.T) {
+	ts := NewTestServer(t, testConfig())
+	token := ts.MustAccessToken(t, "dev|1")
+
+	userInfo := func() int {
+		req, _ := http.NewRequest(http.MethodGet, ts.Issuer()+userInfoPath, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("userinfo request failed: %s", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if code := userInfo(); code != http.StatusOK {
+		t.Fatalf("expected 200 for a fresh token, got %d", code)
+	}
+
+	ts.now = func() time.Time { return time.Now().Add(2 * ts.conf.TokenTTL) }
+	if code := userInfo(); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for an expired token, got %d", code)
+	}
+}
+
+func TestDeviceAuthorizationFlow(t *testing.T) {
+	ctx := context.Background()
+	ts := NewTestServer(t, testConfig())
+
+	p, err := oidc.NewProvider(ctx, ts.Issuer())
+	if err != nil {
+		t.Fatalf("discovery failed: %s", err)
+	}
+	if p.Endpoint().DeviceAuthURL == "" {
+		t.Fatalf("expected device authorization endpoint to be advertised")
+	}
+
+	config := &oauth2.Config{
+		ClientID: "traintrack-cli",
+		Scopes:   []string{"openid", "email"},
+		Endpoint: p.Endpoint(),
+	}
+
+	resp, err := config.DeviceAuth(ctx)
+	if err != nil {
+		t.Fatalf("device authorization failed: %s", err)
+	}
+	if resp.UserCode == "" || resp.VerificationURI == "" {
+		t.Fatalf("expected user code and verification uri, got %+v", resp)
+	}
+
+	approval, err := http.Get(resp.VerificationURIComplete + "&login_hint=dev%7C2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	approval.Body.Close()
+	if approval.StatusCode != http.StatusOK {
+		t.Fatalf("expected approval to succeed, got %d", approval.StatusCode)
+	}
+
+	token, err := config.DeviceAccessToken(ctx, resp)
+	if err != nil {
+		t.Fatalf("polling for token failed: %s", err)
+	}
+
+	rawIDToken, _ := token.Extra("id_token").(string)
+	idToken, err := p.Verifier(&oidc.Config{ClientID: "traintrack-cli"}).Verify(ctx, rawIDToken)
+	if err != nil {
+		t.Fatalf("id token did not verify: %s", err)
+	}
+	if idToken.Subject != "dev|2" {
+		t.Errorf("unexpected subject %q", idToken.Subject)
+	}
+}
+
+func TestDeviceAuthorizationPending(t *testing.T) {
+	ts := NewTestServer(t, testConfig())
+
+	resp, err := http.PostForm(ts.Issuer()+"/oauth/device/code", url.Values{"client_id": {"traintrack-cli"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var device struct {
+		DeviceCode string `json:"device_code"`
+	}
+	json.NewDecoder(resp.Body).Decode(&device)
+	resp.Body.Close()
+
+	resp, err = http.PostForm(ts.Issuer()+"/oauth/token", url.Values{
+		"client_id":   {"traintrack-cli"},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"device_code": {device.DeviceCode},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	if body.Error != "authorization_pending" {
+		t.Errorf("expected authorization_pending, got %q", body.Error)
+	}
+}
@@ -0,0 +1,105 @@
+package devidp
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"golang.org/x/oauth2"
+)
+
+/*
+TestServer is a Server listening on a random local port, for use in tests.
+*/
+type TestServer struct {
+	*Server
+	HTTP *httptest.Server
+}
+
+/*
+NewTestServer starts a provider for the duration of the test. The server is
+closed automatically via tb.Cleanup.
+*/
+func NewTestServer(tb testing.TB, conf Config) *TestServer {
+	tb.Helper()
+
+	idp, err := New(conf)
+	if err != nil {
+		tb.Fatalf("could not create dev identity provider: %s", err)
+	}
+
+	srv := httptest.NewServer(idp)
+	idp.SetIssuer(srv.URL)
+	tb.Cleanup(srv.Close)
+
+	return &TestServer{
+		Server: idp,
+		HTTP:   srv,
+	}
+}
+
+/*
+SetEnv points auth.LoadConfig at this provider using the TRAINTRACK_AUTH_*
+environment variables, for the duration of the test.
+*/
+func (ts *TestServer) SetEnv(tb testing.TB) {
+	tb.Helper()
+
+	clientID := ""
+	if len(ts.conf.Clients) > 0 {
+		clientID = ts.conf.Clients[0].ID
+	}
+
+	tb.Setenv("TRAINTRACK_AUTH_NAME", ts.conf.Audience)
+	tb.Setenv("TRAINTRACK_CLIENT_ID", clientID)
+	tb.Setenv("TRAINTRACK_AUTH_URL", ts.Issuer())
+}
+
+/*
+MustAccessToken mints an access token for user, failing the test if it
+can't.
+*/
+func (ts *TestServer) MustAccessToken(tb testing.TB, user string) string {
+	tb.Helper()
+
+	token, err := ts.AccessToken(user)
+	if err != nil {
+		tb.Fatalf("could not mint access token: %s", err)
+	}
+	return token
+}
+
+/*
+MustToken issues a full set of tokens for user, as if they had logged in
+with the first configured client, including a refresh token.
+*/
+func (ts *TestServer) MustToken(tb testing.TB, user string) *oauth2.Token {
+	tb.Helper()
+
+	u := ts.findUser(user)
+	if u == nil {
+		tb.Fatalf("unknown user %q", user)
+	}
+
+	clientID := ""
+	if len(ts.conf.Clients) > 0 {
+		clientID = ts.conf.Clients[0].ID
+	}
+
+	resp, err := ts.issue(&grant{
+		user:     u,
+		clientID: clientID,
+		scope:    "openid profile email offline_access",
+		audience: ts.conf.Audience,
+	})
+	if err != nil {
+		tb.Fatalf("could not issue tokens: %s", err)
+	}
+
+	tok := &oauth2.Token{
+		AccessToken:  resp.AccessToken,
+		TokenType:    resp.TokenType,
+		RefreshToken: resp.RefreshToken,
+		Expiry:       ts.now().Add(ts.conf.TokenTTL),
+	}
+	return tok.WithExtra(map[string]any{"id_token": resp.IDToken})
+}
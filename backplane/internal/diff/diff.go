@@ -0,0 +1,367 @@
+package diff
+
+import (
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/heldtogether/traintrack/internal/seal"
+	"github.com/heldtogether/traintrack/internal/tabular"
+)
+
+const (
+	// SampleRows is roughly how many rows of each version are compared.
+	SampleRows = 10000
+	// MaxExamples is the most added and removed rows given.
+	MaxExamples = 10
+	// MaxTableSize is the largest artefact, in bytes, which is read as a
+	// table. It's the same bound as uploads.MaxProfileSize, and larger
+	// artefacts fall back on their sizes, along with any row counts
+	// profiled when they were uploaded.
+	MaxTableSize = 64 << 20
+)
+
+/*
+Status says how an artefact changed between two versions.
+*/
+type Status string
+
+const (
+	StatusAdded     Status = "added"
+	StatusRemoved   Status = "removed"
+	StatusChanged   Status = "changed"
+	StatusUnchanged Status = "unchanged"
+)
+
+/*
+Diff is what changed from one dataset version to another.
+*/
+type Diff struct {
+	From      Version    `json:"from"`
+	To        Version    `json:"to"`
+	Artefacts []Artefact `json:"artefacts"`
+}
+
+type Version struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+/*
+Artefact is how a single artefact changed, by name. From is nil if it
+was added and To if it was removed. Schema and Rows are only given for
+changed CSV and Parquet artefacts.
+*/
+type Artefact struct {
+	Name   string      `json:"name"`
+	Status Status      `json:"status"`
+	From   *File       `json:"from,omitempty"`
+	To     *File       `json:"to,omitempty"`
+	Schema *SchemaDiff `json:"schema,omitempty"`
+	Rows   *RowDiff    `json:"rows,omitempty"`
+
+	// Problem is set if the artefact couldn't be read as a table, or was
+	// too large to be.
+	Problem string `json:"problem,omitempty"`
+}
+
+/*
+File is an artefact in one version. Rows is only given for CSV and
+Parquet artefacts which were read, or were profiled when uploaded.
+*/
+type File struct {
+	FileName string `json:"filename"`
+	Digest   string `json:"digest"`
+	Size     int64  `json:"size"`
+	Rows     *int   `json:"rows,omitempty"`
+}
+
+/*
+SchemaDiff is the columns added and removed, and those whose type
+changed.
+*/
+type SchemaDiff struct {
+	Added   []tabular.Column `json:"added"`
+	Removed []tabular.Column `json:"removed"`
+	Changed []ColumnChange   `json:"changed"`
+}
+
+type ColumnChange struct {
+	Name string `json:"name"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+/*
+RowDiff compares a sample of the rows of two versions of a table, over
+the columns they share. One in every SampleRate rows is sampled, so
+Added and Removed count sampled rows and scale up by it. A few of the
+rows added and removed are given, in file order.
+*/
+type RowDiff struct {
+	Columns     []string   `json:"columns"`
+	SampleRate  int        `json:"sample_rate"`
+	Added       int        `json:"added"`
+	Removed     int        `json:"removed"`
+	AddedRows   [][]string `json:"added_rows"`
+	RemovedRows [][]string `json:"removed_rows"`
+}
+
+type datasetFields struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+/*
+Profiler returns the profiles stored for the artefacts of the dataset id,
+by artefact name.
+*/
+type Profiler func(id string) (map[string]*tabular.Profile, error)
+
+type Service struct {
+	datasets seal.Getter
+	profiles Profiler
+	files    seal.FileReader
+}
+
+/*
+NewService diffs datasets using the sealed records returned by datasets,
+reading changed artefacts from files. Artefacts too large to read are
+described by their profiles instead.
+*/
+func NewService(datasets seal.Getter, profiles Profiler, files seal.FileReader) *Service {
+	return &Service{
+		datasets: datasets,
+		profiles: profiles,
+		files:    files,
+	}
+}
+
+/*
+Diff compares the dataset version from with the version to. They needn't
+be related.
+*/
+func (s *Service) Diff(from, to string) (*Diff, error) {
+	records := make([]*seal.Record, 2)
+	versions := make([]Version, 2)
+	for i, id := range []string{from, to} {
+		r, err := s.datasets(id)
+		if err != nil {
+			return nil, err
+		}
+
+		var f datasetFields
+		if err := r.DecodeFields(&f); err != nil {
+			return nil, err
+		}
+		records[i] = r
+		versions[i] = Version{ID: r.ID, Name: f.Name, Version: f.Version}
+	}
+
+	d := &Diff{From: versions[0], To: versions[1], Artefacts: []Artefact{}}
+
+	names := slices.Sorted(maps.Keys(records[0].Artefacts))
+	for name := range records[1].Artefacts {
+		if _, ok := records[0].Artefacts[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	for _, name := range names {
+		d.Artefacts = append(d.Artefacts, s.artefact(name, records[0], records[1]))
+	}
+	return d, nil
+}
+
+/*
+artefact compares the artefact name in two records, reading it as a
+table from either which has it unless it's the same in both.
+*/
+func (s *Service) artefact(name string, from, to *seal.Record) Artefact {
+	a := Artefact{Name: name}
+
+	before, inFrom := from.Artefacts[name]
+	after, inTo := to.Artefacts[name]
+	switch {
+	case !inTo:
+		a.Status = StatusRemoved
+	case !inFrom:
+		a.Status = StatusAdded
+	case before.Digest != "" && before.Digest == after.Digest:
+		a.Status = StatusUnchanged
+	default:
+		a.Status = StatusChanged
+	}
+
+	if inFrom {
+		a.From = &File{FileName: before.FileName, Digest: before.Digest, Size: before.Size}
+	}
+	if inTo {
+		a.To = &File{FileName: after.FileName, Digest: after.Digest, Size: after.Size}
+	}
+	if a.Status == StatusUnchanged {
+		return a
+	}
+
+	var tables []*tabular.Table
+	var problems []string
+	for _, f := range []struct {
+		record   *seal.Record
+		artefact seal.Artefact
+		file     *File
+	}{{from, before, a.From}, {to, after, a.To}} {
+		if f.file == nil || !tabular.Supported(f.artefact.FileName) {
+			continue
+		}
+		if f.artefact.Size > MaxTableSize {
+			problems = append(problems, fmt.Sprintf("%s is too large to diff", f.artefact.FileName))
+			f.file.Rows = s.profiledRows(f.record.ID, name)
+			continue
+		}
+		t, err := s.table(f.artefact)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		rows := len(t.Rows)
+		f.file.Rows = &rows
+		tables = append(tables, t)
+	}
+	a.Problem = strings.Join(problems, "; ")
+
+	if a.Status == StatusChanged && len(tables) == 2 {
+		a.Schema = compareSchemas(tables[0].Columns, tables[1].Columns)
+		a.Rows = compareRows(tables[0], tables[1])
+	}
+	return a
+}
+
+func (s *Service) table(a seal.Artefact) (*tabular.Table, error) {
+	content, err := s.files.ReadFile(filepath.Join(a.Path, a.FileName))
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %w", a.FileName, err)
+	}
+	return tabular.Read(a.FileName, content)
+}
+
+/*
+profiledRows returns the rows counted when the artefact name of the
+dataset id was uploaded, or nil if it wasn't profiled.
+*/
+func (s *Service) profiledRows(id, name string) *int {
+	if s.profiles == nil {
+		return nil
+	}
+	profiles, err := s.profiles(id)
+	if err != nil || profiles[name] == nil {
+		return nil
+	}
+	rows := profiles[name].Rows
+	return &rows
+}
+
+func compareSchemas(from, to []tabular.Column) *SchemaDiff {
+	d := &SchemaDiff{Added: []tabular.Column{}, Removed: []tabular.Column{}, Changed: []ColumnChange{}}
+
+	types := map[string]string{}
+	for _, c := range from {
+		types[c.Name] = c.Type
+	}
+	for _, c := range to {
+		before, ok := types[c.Name]
+		switch {
+		case !ok:
+			d.Added = append(d.Added, c)
+		case before != c.Type:
+			d.Changed = append(d.Changed, ColumnChange{Name: c.Name, From: before, To: c.Type})
+		}
+		delete(types, c.Name)
+	}
+	for _, c := range from {
+		if _, ok := types[c.Name]; ok {
+			d.Removed = append(d.Removed, c)
+		}
+	}
+	return d
+}
+
+/*
+compareRows compares a sample of the rows of from and to over the
+columns they share, in the order of to. A row removed and added back
+the same number of times isn't a change.
+*/
+func compareRows(from, to *tabular.Table) *RowDiff {
+	d := &RowDiff{Columns: []string{}, AddedRows: [][]string{}, RemovedRows: [][]string{}}
+
+	index := map[string]int{}
+	for i, c := range from.Columns {
+		index[c.Name] = i
+	}
+	var fromCols, toCols []int
+	for i, c := range to.Columns {
+		if j, ok := index[c.Name]; ok {
+			d.Columns = append(d.Columns, c.Name)
+			fromCols = append(fromCols, j)
+			toCols = append(toCols, i)
+		}
+	}
+
+	d.SampleRate = max(1, (max(len(from.Rows), len(to.Rows))+SampleRows-1)/SampleRows)
+
+	type sampled struct {
+		key    string
+		values []string
+	}
+	sample := func(t *tabular.Table, cols []int) []sampled {
+		var out []sampled
+		for _, row := range t.Rows {
+			values := make([]string, len(cols))
+			for i, c := range cols {
+				values[i] = row[c]
+			}
+			key := strings.Join(values, "\x1f")
+
+			h := fnv.New32a()
+			h.Write([]byte(key))
+			if int(h.Sum32()%uint32(d.SampleRate)) == 0 {
+				out = append(out, sampled{key: key, values: values})
+			}
+		}
+		return out
+	}
+	before, after := sample(from, fromCols), sample(to, toCols)
+
+	counts := map[string]int{}
+	for _, r := range before {
+		counts[r.key]++
+	}
+	for _, r := range after {
+		counts[r.key]--
+	}
+
+	// Whatever is left over was removed if positive, or added if negative.
+	for _, r := range before {
+		if counts[r.key] > 0 {
+			counts[r.key]--
+			d.Removed++
+			if len(d.RemovedRows) < MaxExamples {
+				d.RemovedRows = append(d.RemovedRows, r.values)
+			}
+		}
+	}
+	for _, r := range after {
+		if counts[r.key] < 0 {
+			counts[r.key]++
+			d.Added++
+			if len(d.AddedRows) < MaxExamples {
+				d.AddedRows = append(d.AddedRows, r.values)
+			}
+		}
+	}
+	return d
+}
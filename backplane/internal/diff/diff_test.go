@@ -0,0 +1,204 @@
+package diff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/seal"
+	"github.com/heldtogether/traintrack/internal/tabular"
+)
+
+type mockFiles map[string]string
+
+func (m mockFiles) ReadFile(path string) ([]byte, error) {
+	content, ok := m[path]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return []byte(content), nil
+}
+
+func testDatasets() map[string]*seal.Record {
+	return map[string]*seal.Record{
+		"ds1": {
+			Kind:   "dataset",
+			ID:     "ds1",
+			Fields: map[string]any{"name": "house_prices", "version": "1.0.0"},
+			Artefacts: map[string]seal.Artefact{
+				"X_train": {FileName: "X_train.csv", Digest: "sha256:aaa", Size: 52, Path: "ds1"},
+				"notes":   {FileName: "notes.txt", Digest: "sha256:bbb", Size: 5, Path: "ds1"},
+				"readme":  {FileName: "README.md", Digest: "sha256:ccc", Size: 8, Path: "ds1"},
+			},
+		},
+		"ds2": {
+			Kind:     "dataset",
+			ID:       "ds2",
+			Fields:   map[string]any{"name": "house_prices", "version": "1.0.1"},
+			ParentID: "ds1",
+			Artefacts: map[string]seal.Artefact{
+				"X_train": {FileName: "X_train.csv", Digest: "sha256:ddd", Size: 47, Path: "ds2"},
+				"readme":  {FileName: "README.md", Digest: "sha256:ccc", Size: 8, Path: "ds2"},
+				"y_train": {FileName: "y_train.csv", Digest: "sha256:eee", Size: 9, Path: "ds2"},
+			},
+		},
+	}
+}
+
+func testFiles() mockFiles {
+	return mockFiles{
+		filepath.Join("ds1", "X_train.csv"): "id,rooms,city\n1,3,Leeds\n2,,York\n2,,York\n3,4,Hull\n",
+		filepath.Join("ds2", "X_train.csv"): "id,city,rooms,area\n1,Leeds,3,80\n2,York,,95\n3,Hull,4.5,70\n",
+		filepath.Join("ds2", "y_train.csv"): "price\n1\n2\n",
+	}
+}
+
+func getter(records map[string]*seal.Record) seal.Getter {
+	return func(id string) (*seal.Record, error) {
+		r, ok := records[id]
+		if !ok {
+			return nil, fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+		}
+		return r, nil
+	}
+}
+
+func intp(i int) *int { return &i }
+
+func TestDiff(t *testing.T) {
+	d, err := NewService(getter(testDatasets()), nil, testFiles()).Diff("ds1", "ds2")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := &Diff{
+		From: Version{ID: "ds1", Name: "house_prices", Version: "1.0.0"},
+		To:   Version{ID: "ds2", Name: "house_prices", Version: "1.0.1"},
+		Artefacts: []Artefact{
+			{
+				Name:   "X_train",
+				Status: StatusChanged,
+				From:   &File{FileName: "X_train.csv", Digest: "sha256:aaa", Size: 52, Rows: intp(4)},
+				To:     &File{FileName: "X_train.csv", Digest: "sha256:ddd", Size: 47, Rows: intp(3)},
+				Schema: &SchemaDiff{
+					Added:   []tabular.Column{{Name: "area", Type: "integer"}},
+					Removed: []tabular.Column{},
+					Changed: []ColumnChange{{Name: "rooms", From: "integer", To: "float"}},
+				},
+				Rows: &RowDiff{
+					Columns:     []string{"id", "city", "rooms"},
+					SampleRate:  1,
+					Added:       1,
+					Removed:     2,
+					AddedRows:   [][]string{{"3", "Hull", "4.5"}},
+					RemovedRows: [][]string{{"2", "York", ""}, {"3", "Hull", "4"}},
+				},
+			},
+			{
+				Name:   "notes",
+				Status: StatusRemoved,
+				From:   &File{FileName: "notes.txt", Digest: "sha256:bbb", Size: 5},
+			},
+			{
+				Name:   "readme",
+				Status: StatusUnchanged,
+				From:   &File{FileName: "README.md", Digest: "sha256:ccc", Size: 8},
+				To:     &File{FileName: "README.md", Digest: "sha256:ccc", Size: 8},
+			},
+			{
+				Name:   "y_train",
+				Status: StatusAdded,
+				To:     &File{FileName: "y_train.csv", Digest: "sha256:eee", Size: 9, Rows: intp(2)},
+			},
+		},
+	}
+	if !reflect.DeepEqual(d, want) {
+		got, _ := json.MarshalIndent(d, "", "  ")
+		expected, _ := json.MarshalIndent(want, "", "  ")
+		t.Errorf("got diff %s\nwanted %s", got, expected)
+	}
+}
+
+func TestDiffUnreadable(t *testing.T) {
+	files := testFiles()
+	delete(files, filepath.Join("ds1", "X_train.csv"))
+
+	d, err := NewService(getter(testDatasets()), nil, files).Diff("ds1", "ds2")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	a := d.Artefacts[0]
+	if a.Problem == "" || a.Schema != nil || a.Rows != nil {
+		t.Errorf("expected only a problem for an unreadable artefact, got %+v", a)
+	}
+	if a.From.Rows != nil || a.To.Rows == nil || *a.To.Rows != 3 {
+		t.Errorf("expected the readable version's rows to be counted, got %+v and %+v", a.From, a.To)
+	}
+}
+
+func TestDiffTooLarge(t *testing.T) {
+	datasets := testDatasets()
+	large := datasets["ds2"].Artefacts["X_train"]
+	large.Size = MaxTableSize + 1
+	datasets["ds2"].Artefacts["X_train"] = large
+
+	files := testFiles()
+	// Reading it would fail the test, as the problem would be different.
+	delete(files, filepath.Join("ds2", "X_train.csv"))
+
+	profiles := func(id string) (map[string]*tabular.Profile, error) {
+		if id != "ds2" {
+			return nil, fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+		}
+		return map[string]*tabular.Profile{"X_train": {Format: tabular.FormatCSV, Rows: 250000, Columns: 4}}, nil
+	}
+
+	d, err := NewService(getter(datasets), profiles, files).Diff("ds1", "ds2")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	a := d.Artefacts[0]
+	if a.Problem != "X_train.csv is too large to diff" || a.Schema != nil || a.Rows != nil {
+		t.Errorf("expected only a problem for a table too large to diff, got %+v", a)
+	}
+	if a.From.Rows == nil || *a.From.Rows != 4 || a.To.Rows == nil || *a.To.Rows != 250000 {
+		t.Errorf("expected rows read and profiled, got %+v and %+v", a.From, a.To)
+	}
+	if a.To.Size != MaxTableSize+1 {
+		t.Errorf("expected the sealed size, got %d", a.To.Size)
+	}
+}
+
+func TestDiffNotFound(t *testing.T) {
+	if _, err := NewService(getter(testDatasets()), nil, testFiles()).Diff("ds1", "missing"); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCompareRowsSamples(t *testing.T) {
+	from := &tabular.Table{Columns: []tabular.Column{{Name: "id"}}}
+	to := &tabular.Table{Columns: []tabular.Column{{Name: "id"}}}
+	for i := range 3 * SampleRows {
+		from.Rows = append(from.Rows, []string{fmt.Sprint(i)})
+		to.Rows = append(to.Rows, []string{fmt.Sprint(i + SampleRows)})
+	}
+
+	d := compareRows(from, to)
+	if d.SampleRate != 3 {
+		t.Errorf("expected a sample rate of 3, got %d", d.SampleRate)
+	}
+	// A third of the rows changed, so about a third of those sampled.
+	if d.Added < SampleRows/4 || d.Added > SampleRows/2 || d.Removed < SampleRows/4 || d.Removed > SampleRows/2 {
+		t.Errorf("expected about %d rows added and removed in the sample, got %d and %d", SampleRows/3, d.Added, d.Removed)
+	}
+	if len(d.AddedRows) != MaxExamples || len(d.RemovedRows) != MaxExamples {
+		t.Errorf("expected %d examples, got %d and %d", MaxExamples, len(d.AddedRows), len(d.RemovedRows))
+	}
+}
@@ -0,0 +1,19 @@
+/*
+Package diff shows what changed between two versions of a dataset.
+
+Artefacts are matched by name and compared by the digest sealed for them
+(see package seal), so an artefact is only read back from storage if it
+changed. CSV and Parquet artefacts are read as tables (see package
+tabular) to count their rows, compare their schemas and compare their
+rows.
+
+Rows are compared over the columns both versions have. Rather than
+every row, a sample is compared: a row is in the sample if a hash of
+its values falls in the sampled fraction, so the same row is sampled in
+both versions wherever it appears, and the counts of added and removed
+rows scale up by the sample rate. Artefacts over MaxTableSize aren't read
+at all, and only their sizes and any profiled row counts are compared:
+
+	d, err := diff.NewService(datasetsStore.Record, datasetsStore.Profiles, files).Diff(fromID, toID)
+*/
+package diff
@@ -0,0 +1,82 @@
+package diff
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/heldtogether/traintrack/internal"
+)
+
+/*
+Differ shows what changed between two dataset versions.
+*/
+type Differ interface {
+	Diff(from, to string) (*Diff, error)
+}
+
+type Handler struct {
+	d Differ
+}
+
+func NewHandler(d Differ) *Handler {
+	return &Handler{
+		d: d,
+	}
+}
+
+/*
+Diff compares the dataset versions named by the from and to query
+parameters. It should be registered under /datasets/diff, before
+/datasets/{id}.
+*/
+func (h *Handler) Diff(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	from := strings.TrimSpace(r.URL.Query().Get("from"))
+	to := strings.TrimSpace(r.URL.Query().Get("to"))
+	if from == "" || to == "" {
+		log.Printf("failed to diff datasets: from %q, to %q", from, to)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to diff datasets",
+			Reason:  "from and to are both required",
+		})
+		return
+	}
+
+	d, err := h.d.Diff(from, to)
+	if err != nil {
+		code := http.StatusInternalServerError
+		message := "Failed to diff datasets"
+		if errors.Is(err, internal.ErrNotFound) {
+			code = http.StatusNotFound
+			message = "Dataset not found"
+		}
+		log.Printf("failed to diff datasets %s and %s: %s", from, to, err)
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    code,
+			Message: message,
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(d)
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    http.StatusMethodNotAllowed,
+		Message: "Method not allowed",
+		Reason:  "",
+	})
+}
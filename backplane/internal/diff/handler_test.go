@@ -0,0 +1,135 @@
+package diff
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/heldtogether/traintrack/internal"
+)
+
+type mockDiffer struct {
+	DiffFn func(from, to string) (*Diff, error)
+}
+
+func (m *mockDiffer) Diff(from, to string) (*Diff, error) {
+	return m.DiffFn(from, to)
+}
+
+func TestDiffHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		diffFn           func(from, to string) (*Diff, error)
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "success",
+			method: http.MethodGet,
+			path:   "/datasets/diff?from=ds1&to=ds2",
+			diffFn: func(from, to string) (*Diff, error) {
+				if from != "ds1" || to != "ds2" {
+					return nil, fmt.Errorf("unexpected diff from %s to %s", from, to)
+				}
+				return &Diff{
+					From: Version{ID: "ds1", Name: "house_prices", Version: "1.0.0"},
+					To:   Version{ID: "ds2", Name: "house_prices", Version: "1.0.1"},
+					Artefacts: []Artefact{{
+						Name:   "notes",
+						Status: StatusRemoved,
+						From:   &File{FileName: "notes.txt", Digest: "sha256:bbb", Size: 5},
+					}},
+				}, nil
+			},
+			expectedStatus: http.StatusOK,
+			expectedContains: `{
+				"from": {"id": "ds1", "name": "house_prices", "version": "1.0.0"},
+				"to": {"id": "ds2", "name": "house_prices", "version": "1.0.1"},
+				"artefacts": [{"name": "notes", "status": "removed", "from": {"filename": "notes.txt", "digest": "sha256:bbb", "size": 5}}]
+			}`,
+		},
+		{
+			name:             "missing to",
+			method:           http.MethodGet,
+			path:             "/datasets/diff?from=ds1",
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to diff datasets", "reason": "from and to are both required"}`,
+		},
+		{
+			name:   "not found",
+			method: http.MethodGet,
+			path:   "/datasets/diff?from=ds1&to=ds9",
+			diffFn: func(from, to string) (*Diff, error) {
+				return nil, fmt.Errorf("dataset ds9: %w", internal.ErrNotFound)
+			},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Dataset not found", "reason": "dataset ds9: not found"}`,
+		},
+		{
+			name:   "failure",
+			method: http.MethodGet,
+			path:   "/datasets/diff?from=ds1&to=ds2",
+			diffFn: func(from, to string) (*Diff, error) {
+				return nil, errors.New("could not query dataset: boom")
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedContains: `{"code": 500, "error": "Failed to diff datasets", "reason": "could not query dataset: boom"}`,
+		},
+		{
+			name:             "METHOD failure",
+			method:           http.MethodPost,
+			path:             "/datasets/diff",
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewHandler(&mockDiffer{DiffFn: tc.diffFn})
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			rr := httptest.NewRecorder()
+
+			handler.Diff(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func checkResponse(t *testing.T, got *http.Response, expectedStatus int, expected string) {
+	defer got.Body.Close()
+
+	body, err := io.ReadAll(got.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %s", err)
+	}
+
+	if expectedStatus != got.StatusCode {
+		t.Errorf("status mismatch - wanted %d, got %d", expectedStatus, got.StatusCode)
+	}
+
+	var gotData any
+	if err := json.Unmarshal(body, &gotData); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v\nbody: %s", err, string(body))
+	}
+
+	var expectedData any
+	if err := json.Unmarshal([]byte(expected), &expectedData); err != nil {
+		t.Fatalf("failed to unmarshal expected value: %v\njson: %s", err, expected)
+	}
+
+	if !reflect.DeepEqual(expectedData, gotData) {
+		t.Errorf("JSON mismatch:\nexpected: %+v\ngot: %+v", expectedData, gotData)
+	}
+}
@@ -0,0 +1,22 @@
+/*
+Package erasure handles requests to erase a data subject's data, as the
+GDPR's right to erasure requires.
+
+Erasing a dataset version isn't enough on its own: every dataset version
+derived from it, through its parent links, may carry the same data, and
+so may every model trained on any of them, and every model derived from
+those. A Request names the dataset version which held the data, and the
+Service walks those links to find everything affected:
+
+	impact, err := service.Impact(ctx, datasetID)
+
+Submitting the request marks every affected version as tainted by it,
+which stops tainted models being promoted, and can also purge their
+artefacts from storage:
+
+	r, err := service.Erase(ctx, &Request{DatasetID: id, Reason: "ticket 123", Purge: true})
+
+The versions' records are kept, and the request, with its impact report,
+is stored permanently as the tombstone of what was erased.
+*/
+package erasure
@@ -0,0 +1,79 @@
+package erasure
+
+import "time"
+
+type Kind string
+
+const (
+	KindDataset Kind = "dataset"
+	KindModel   Kind = "model"
+)
+
+/*
+Request asks for the data held in the dataset version DatasetID to be
+erased, along with everything derived from it.
+*/
+type Request struct {
+	ID        string `json:"id"`
+	DatasetID string `json:"dataset_id"`
+
+	// Reason records why the data was erased, such as a ticket number. It
+	// is kept forever, so it shouldn't identify the data subject.
+	Reason string `json:"reason"`
+
+	// Purge removes the artefacts of every affected version from storage,
+	// rather than only marking them as tainted.
+	Purge bool `json:"purge"`
+
+	// RequestedAt, RequestedBy and Tenant are set by the server from the
+	// verified token. Any values sent by the client are ignored.
+	RequestedAt time.Time `json:"requested_at"`
+	RequestedBy string    `json:"requested_by"`
+	Tenant      string    `json:"tenant,omitempty"`
+
+	Impact *Impact `json:"impact"`
+}
+
+/*
+Impact reports every version affected by erasing a dataset version,
+starting with the version itself.
+*/
+type Impact struct {
+	Datasets []*Version `json:"datasets"`
+	Models   []*Version `json:"models"`
+}
+
+/*
+Versions returns every affected version, datasets first.
+*/
+func (i *Impact) Versions() []*Version {
+	return append(append([]*Version{}, i.Datasets...), i.Models...)
+}
+
+/*
+Version is a dataset or model version affected by an erasure.
+*/
+type Version struct {
+	Kind    Kind   `json:"kind"`
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Tenant  string `json:"tenant,omitempty"`
+
+	// DerivedFrom is the affected version this one was derived from or,
+	// for a model, trained on. It is empty for the erased version itself.
+	DerivedFrom string `json:"derived_from,omitempty"`
+
+	// Stage is the stage a model was in when the erasure was requested.
+	Stage string `json:"stage,omitempty"`
+
+	// Purged is set if the version's artefacts have been purged, whether
+	// by this erasure or before it, and Files lists those this erasure
+	// removed.
+	Purged bool     `json:"purged"`
+	Files  []string `json:"files,omitempty"`
+
+	// Held is set if the version is under a legal hold, which stops it
+	// being purged.
+	Held bool `json:"held,omitempty"`
+}
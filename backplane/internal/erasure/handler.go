@@ -0,0 +1,206 @@
+package erasure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/auth"
+)
+
+/*
+Eraser allows erasure requests to be previewed, carried out and listed.
+*/
+type Eraser interface {
+	Requests(ctx context.Context, tenant string) ([]*Request, error)
+	Request(ctx context.Context, tenant string, id string) (*Request, error)
+	Impact(ctx context.Context, tenant string, id string) (*Impact, error)
+	Erase(ctx context.Context, r *Request) (*Request, error)
+}
+
+type Handler struct {
+	e Eraser
+}
+
+func NewHandler(e Eraser) *Handler {
+	return &Handler{
+		e: e,
+	}
+}
+
+/*
+Erasures routes and handles requests for the caller's tenant's erasure
+requests. It should be registered on the router under something
+sensible, like /erasures.
+*/
+func (h *Handler) Erasures(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.List(w, r)
+	case http.MethodPost:
+		h.Create(w, r)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+/*
+Erasure returns a single erasure request, registered under
+/erasures/{id}.
+*/
+func (h *Handler) Erasure(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	req, err := h.e.Request(r.Context(), auth.TenantFromContext(r.Context()), id)
+	if err != nil {
+		code := http.StatusInternalServerError
+		message := "Failed to fetch erasure request"
+		if errors.Is(err, internal.ErrNotFound) {
+			code = http.StatusNotFound
+			message = "Erasure request not found"
+		}
+		log.Printf("failed to fetch erasure request %s: %s", id, err)
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    code,
+			Message: message,
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(req)
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	rs, err := h.e.Requests(r.Context(), auth.TenantFromContext(r.Context()))
+	if err != nil {
+		log.Printf("failed to list erasure requests: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to list erasure requests",
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(rs)
+}
+
+/*
+Create carries out an erasure request for one of the caller's tenant's
+datasets. With dry_run=true, it only reports what the request would
+affect, changing nothing. Either way, it requires the admin role.
+*/
+func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("failed to decode body: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to erase dataset",
+			Reason:  fmt.Sprintf("could not parse body: %s", err),
+		})
+		return
+	}
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	details := map[string]string{}
+	if req.DatasetID == "" {
+		details["dataset_id"] = "dataset_id is a required field"
+	}
+	if req.Reason == "" && !dryRun {
+		details["reason"] = "reason is a required field"
+	}
+	if len(details) > 0 {
+		log.Printf("failed to validate input: %v", details)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to erase dataset",
+			Reason:  "bad input",
+			Details: details,
+		})
+		return
+	}
+
+	var erased *Request
+	var err error
+	if dryRun {
+		var impact *Impact
+		if impact, err = h.e.Impact(r.Context(), auth.TenantFromContext(r.Context()), req.DatasetID); err == nil {
+			erased = &Request{DatasetID: req.DatasetID, Reason: req.Reason, Purge: req.Purge, Impact: impact}
+		}
+	} else {
+		erased, err = h.e.Erase(r.Context(), &req)
+	}
+	if err != nil {
+		code := http.StatusInternalServerError
+		message := "Failed to erase dataset"
+		switch {
+		case errors.Is(err, internal.ErrNotFound):
+			code = http.StatusNotFound
+			message = "Dataset not found"
+		case errors.Is(err, internal.ErrHeld):
+			code = http.StatusConflict
+		}
+		log.Printf("failed to erase dataset %s: %s", req.DatasetID, err)
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    code,
+			Message: message,
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	if !dryRun {
+		w.WriteHeader(http.StatusCreated)
+	}
+	json.NewEncoder(w).Encode(erased)
+}
+
+/*
+requireAdmin reports whether the caller has the admin role, writing a 403
+if they don't.
+*/
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if id, ok := auth.IdentityFromContext(r.Context()); ok && id.HasRole(auth.RoleAdmin) {
+		return true
+	}
+
+	log.Printf("refused to erase dataset: caller isn't an admin")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    http.StatusForbidden,
+		Message: "Failed to erase dataset",
+		Reason:  "requires the admin role",
+	})
+	return false
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    http.StatusMethodNotAllowed,
+		Message: "Method not allowed",
+		Reason:  "",
+	})
+}
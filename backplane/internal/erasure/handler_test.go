@@ -0,0 +1,265 @@
+package erasure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/auth"
+)
+
+type mockEraser struct {
+	RequestsFn func(ctx context.Context, tenant string) ([]*Request, error)
+	RequestFn  func(ctx context.Context, tenant string, id string) (*Request, error)
+	ImpactFn   func(ctx context.Context, tenant string, id string) (*Impact, error)
+	EraseFn    func(ctx context.Context, r *Request) (*Request, error)
+}
+
+func (m *mockEraser) Requests(ctx context.Context, tenant string) ([]*Request, error) {
+	return m.RequestsFn(ctx, tenant)
+}
+
+func (m *mockEraser) Request(ctx context.Context, tenant string, id string) (*Request, error) {
+	return m.RequestFn(ctx, tenant, id)
+}
+
+func (m *mockEraser) Impact(ctx context.Context, tenant string, id string) (*Impact, error) {
+	return m.ImpactFn(ctx, tenant, id)
+}
+
+func (m *mockEraser) Erase(ctx context.Context, r *Request) (*Request, error) {
+	return m.EraseFn(ctx, r)
+}
+
+var handlerTime = time.Date(2025, 7, 7, 9, 0, 0, 0, time.UTC)
+
+func TestErasuresHandler(t *testing.T) {
+	impact := &Impact{
+		Datasets: []*Version{{Kind: KindDataset, ID: "d1", Name: "prices", Version: "1.0.0"}},
+		Models:   []*Version{{Kind: KindModel, ID: "m1", Name: "regressor", Version: "1.0.0", DerivedFrom: "d1", Stage: "production"}},
+	}
+	admin := &auth.Identity{Subject: "dev|1", Tenant: "acme", Roles: []string{auth.RoleAdmin}}
+	impactJSON := `{"datasets": [{"kind": "dataset", "id": "d1", "name": "prices", "version": "1.0.0", "purged": false}], "models": [{"kind": "model", "id": "m1", "name": "regressor", "version": "1.0.0", "derived_from": "d1", "stage": "production", "purged": false}]}`
+
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		body             string
+		identity         *auth.Identity
+		eraser           *mockEraser
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "GET success",
+			method: http.MethodGet,
+			path:   "/erasures",
+			eraser: &mockEraser{RequestsFn: func(_ context.Context, tenant string) ([]*Request, error) {
+				return []*Request{}, nil
+			}},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `[]`,
+		},
+		{
+			name:     "POST success",
+			method:   http.MethodPost,
+			path:     "/erasures",
+			identity: admin,
+			body:     `{"dataset_id": "d1", "reason": "ticket 123", "purge": true}`,
+			eraser: &mockEraser{EraseFn: func(_ context.Context, r *Request) (*Request, error) {
+				if !r.Purge {
+					return nil, errors.New("expected purge")
+				}
+				return &Request{ID: "e1", DatasetID: r.DatasetID, Reason: r.Reason, Purge: true, RequestedAt: handlerTime, RequestedBy: "dev|1", Impact: impact}, nil
+			}},
+			expectedStatus:   http.StatusCreated,
+			expectedContains: `{"id": "e1", "dataset_id": "d1", "reason": "ticket 123", "purge": true, "requested_at": "2025-07-07T09:00:00Z", "requested_by": "dev|1", "impact": ` + impactJSON + `}`,
+		},
+		{
+			name:     "POST dry run",
+			method:   http.MethodPost,
+			path:     "/erasures?dry_run=true",
+			body:     `{"dataset_id": "d1"}`,
+			identity: admin,
+			eraser: &mockEraser{ImpactFn: func(_ context.Context, tenant string, id string) (*Impact, error) {
+				if tenant != "acme" {
+					return nil, fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+				}
+				return impact, nil
+			}},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"id": "", "dataset_id": "d1", "reason": "", "purge": false, "requested_at": "0001-01-01T00:00:00Z", "requested_by": "", "impact": ` + impactJSON + `}`,
+		},
+		{
+			name:             "POST failure - failed validation",
+			method:           http.MethodPost,
+			path:             "/erasures",
+			body:             `{}`,
+			identity:         admin,
+			eraser:           &mockEraser{},
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to erase dataset", "reason": "bad input", "details": {"dataset_id": "dataset_id is a required field", "reason": "reason is a required field"}}`,
+		},
+		{
+			name:     "POST failure - not found",
+			method:   http.MethodPost,
+			path:     "/erasures",
+			identity: admin,
+			body:     `{"dataset_id": "d1", "reason": "ticket 123"}`,
+			eraser: &mockEraser{EraseFn: func(_ context.Context, r *Request) (*Request, error) {
+				return nil, fmt.Errorf("dataset %s: %w", r.DatasetID, internal.ErrNotFound)
+			}},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Dataset not found", "reason": "dataset d1: not found"}`,
+		},
+		{
+			name:     "POST failure - held",
+			method:   http.MethodPost,
+			path:     "/erasures",
+			identity: admin,
+			body:     `{"dataset_id": "d1", "reason": "ticket 123", "purge": true}`,
+			eraser: &mockEraser{EraseFn: func(_ context.Context, r *Request) (*Request, error) {
+				return nil, fmt.Errorf("can't purge model regressor 1.0.0, which are %w", internal.ErrHeld)
+			}},
+			expectedStatus:   http.StatusConflict,
+			expectedContains: `{"code": 409, "error": "Failed to erase dataset", "reason": "can't purge model regressor 1.0.0, which are under legal hold"}`,
+		},
+		{
+			name:             "POST failure - not an admin",
+			method:           http.MethodPost,
+			path:             "/erasures",
+			body:             `{"dataset_id": "d1", "reason": "ticket 123"}`,
+			identity:         &auth.Identity{Subject: "dev|2", Tenant: "acme", Roles: []string{"trainer"}},
+			eraser:           &mockEraser{},
+			expectedStatus:   http.StatusForbidden,
+			expectedContains: `{"code": 403, "error": "Failed to erase dataset", "reason": "requires the admin role"}`,
+		},
+		{
+			name:             "POST dry run failure - not an admin",
+			method:           http.MethodPost,
+			path:             "/erasures?dry_run=true",
+			body:             `{"dataset_id": "d1"}`,
+			eraser:           &mockEraser{},
+			expectedStatus:   http.StatusForbidden,
+			expectedContains: `{"code": 403, "error": "Failed to erase dataset", "reason": "requires the admin role"}`,
+		},
+		{
+			name:     "POST dry run failure - another tenant's dataset",
+			method:   http.MethodPost,
+			path:     "/erasures?dry_run=true",
+			body:     `{"dataset_id": "d1"}`,
+			identity: &auth.Identity{Subject: "dev|3", Tenant: "globex", Roles: []string{auth.RoleAdmin}},
+			eraser: &mockEraser{ImpactFn: func(_ context.Context, tenant string, id string) (*Impact, error) {
+				return nil, fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+			}},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Dataset not found", "reason": "dataset d1: not found"}`,
+		},
+		{
+			name:             "METHOD failure",
+			method:           http.MethodPut,
+			path:             "/erasures",
+			eraser:           &mockEraser{},
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+			req := httptest.NewRequest(tc.method, tc.path, body)
+			if tc.identity != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), tc.identity))
+			}
+			rr := httptest.NewRecorder()
+
+			NewHandler(tc.eraser).Erasures(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func TestErasureHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		requestFn        func(ctx context.Context, tenant string, id string) (*Request, error)
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name: "found",
+			requestFn: func(_ context.Context, tenant string, id string) (*Request, error) {
+				return &Request{ID: id, DatasetID: "d1", Reason: "ticket 123", RequestedAt: handlerTime, Impact: &Impact{Datasets: []*Version{}, Models: []*Version{}}}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"id": "e1", "dataset_id": "d1", "reason": "ticket 123", "purge": false, "requested_at": "2025-07-07T09:00:00Z", "requested_by": "", "impact": {"datasets": [], "models": []}}`,
+		},
+		{
+			name: "not found",
+			requestFn: func(_ context.Context, tenant string, id string) (*Request, error) {
+				return nil, fmt.Errorf("erasure request %s: %w", id, internal.ErrNotFound)
+			},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Erasure request not found", "reason": "erasure request e1: not found"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/erasures/e1", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "e1"})
+			rr := httptest.NewRecorder()
+
+			NewHandler(&mockEraser{RequestFn: tc.requestFn}).Erasure(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func checkResponse(t *testing.T, got *http.Response, expectedStatus int, expected string) {
+	defer got.Body.Close()
+
+	body, err := io.ReadAll(got.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %s", err)
+	}
+
+	if expectedStatus != got.StatusCode {
+		t.Errorf("status mismatch - wanted %d, got %d", expectedStatus, got.StatusCode)
+	}
+
+	var gotData any
+	if err := json.Unmarshal(body, &gotData); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v\nbody: %s", err, string(body))
+	}
+
+	var expectedData any
+	if err := json.Unmarshal([]byte(expected), &expectedData); err != nil {
+		t.Fatalf("failed to unmarshal expected value: %v\njson: %s", err, expected)
+	}
+
+	if !reflect.DeepEqual(expectedData, gotData) {
+		t.Errorf("JSON mismatch:\nexpected: %+v\ngot: %+v", expectedData, gotData)
+	}
+}
@@ -0,0 +1,232 @@
+package erasure
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/heldtogether/traintrack/internal/uploads"
+	"github.com/jackc/pgx/v5"
+)
+
+type erasureStore interface {
+	Requests(ctx context.Context, tenant string) ([]*Request, error)
+	Request(ctx context.Context, tenant string, id string) (*Request, error)
+	Impact(ctx context.Context, tenant string, id string) (*Impact, error)
+	impactWithQuerier(ctx context.Context, q Querier, tenant string, id string) (*Impact, error)
+	lockWithQuerier(ctx context.Context, q Querier, tenant string, id string) error
+	createWithQuerier(ctx context.Context, q Querier, r *Request) error
+	taintWithQuerier(ctx context.Context, q Querier, kind Kind, requestID string, ids []string) error
+	purgeWithQuerier(ctx context.Context, q Querier, kind Kind, id string) (bool, error)
+	filesWithQuerier(ctx context.Context, q Querier, kind Kind, id string) ([]uploads.FileRef, error)
+}
+
+/*
+FileRemover permanently removes artefacts from storage.
+*/
+type FileRemover interface {
+	RemoveFile(path string) error
+}
+
+/*
+AuditRecorder records an audit event using the provided Querier, which
+may be a transaction.
+*/
+type AuditRecorder interface {
+	RecordWithQuerier(ctx context.Context, q audit.Querier, e *audit.Event) error
+}
+
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type Service struct {
+	s     erasureStore
+	files FileRemover
+	db    TxBeginner
+	audit AuditRecorder
+}
+
+func NewService(s *Store, f FileRemover, db TxBeginner, a AuditRecorder) *Service {
+	return &Service{
+		s:     s,
+		files: f,
+		db:    db,
+		audit: a,
+	}
+}
+
+/*
+Requests returns tenant's erasure requests.
+*/
+func (s *Service) Requests(ctx context.Context, tenant string) ([]*Request, error) {
+	return s.s.Requests(ctx, tenant)
+}
+
+/*
+Request returns tenant's erasure request id.
+*/
+func (s *Service) Request(ctx context.Context, tenant string, id string) (*Request, error) {
+	return s.s.Request(ctx, tenant, id)
+}
+
+/*
+Impact reports what erasing tenant's dataset version id would affect,
+without changing anything.
+*/
+func (s *Service) Impact(ctx context.Context, tenant string, id string) (*Impact, error) {
+	return s.s.Impact(ctx, tenant, id)
+}
+
+/*
+Erase carries out the erasure request r: every version it affects is
+marked as tainted by it and, if r.Purge is set, has its artefacts removed
+from storage. Purging ignores the checks a purge from the trash makes,
+since the data has to go regardless of what depends on it; purged models
+are archived. Legal holds aren't ignored, though: nothing is purged while
+any affected version is held. Only the caller's tenant's versions are
+affected, and another tenant's dataset isn't found. The request is
+stored, with its impact report, as a tombstone, and an audit event is
+recorded for every affected version.
+*/
+func (s *Service) Erase(ctx context.Context, r *Request) (erased *Request, err error) {
+	erased = &Request{
+		DatasetID: r.DatasetID,
+		Reason:    r.Reason,
+		Purge:     r.Purge,
+	}
+	if id, ok := auth.IdentityFromContext(ctx); ok {
+		erased.Tenant = id.Tenant
+		erased.RequestedBy = id.Subject
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	// Stops new versions being derived from the dataset while its lineage
+	// is walked.
+	if err = s.s.lockWithQuerier(ctx, tx, erased.Tenant, r.DatasetID); err != nil {
+		return nil, err
+	}
+
+	if erased.Impact, err = s.s.impactWithQuerier(ctx, tx, erased.Tenant, r.DatasetID); err != nil {
+		return nil, err
+	}
+
+	if r.Purge {
+		if err = checkHolds(erased.Impact); err != nil {
+			return nil, err
+		}
+		for _, v := range erased.Impact.Versions() {
+			if err = s.purge(ctx, tx, v); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err = s.s.createWithQuerier(ctx, tx, erased); err != nil {
+		return nil, err
+	}
+	if err = s.s.taintWithQuerier(ctx, tx, KindDataset, erased.ID, ids(erased.Impact.Datasets)); err != nil {
+		return nil, err
+	}
+	if err = s.s.taintWithQuerier(ctx, tx, KindModel, erased.ID, ids(erased.Impact.Models)); err != nil {
+		return nil, err
+	}
+
+	if s.audit != nil {
+		for _, v := range erased.Impact.Versions() {
+			e := audit.NewEvent(ctx, audit.ActionErase, resourceType(v.Kind), v.ID).
+				WithDetails(map[string]any{
+					"erasure": erased.ID,
+					"dataset": erased.DatasetID,
+					"name":    v.Name,
+					"version": v.Version,
+					"purged":  v.Purged,
+					"files":   v.Files,
+				})
+			if err = s.audit.RecordWithQuerier(ctx, tx, e); err != nil {
+				return nil, fmt.Errorf("record audit event: %w", err)
+			}
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return erased, nil
+}
+
+/*
+purge marks the version v as purged and removes its artefacts, noting
+them on v. Files already gone are skipped, so an erasure which failed
+part way can be retried.
+*/
+func (s *Service) purge(ctx context.Context, q Querier, v *Version) error {
+	if v.Purged {
+		return nil
+	}
+
+	if _, err := s.s.purgeWithQuerier(ctx, q, v.Kind, v.ID); err != nil {
+		return err
+	}
+
+	files, err := s.s.filesWithQuerier(ctx, q, v.Kind, v.ID)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		p := filepath.Join(f.Path, f.FileName)
+		if err := s.files.RemoveFile(p); err != nil {
+			return fmt.Errorf("remove %s: %w", p, err)
+		}
+		v.Files = append(v.Files, p)
+	}
+
+	v.Purged = true
+	return nil
+}
+
+/*
+checkHolds refuses to purge anything unless every affected version is
+free of legal holds. Those have to be released first, and then the
+erasure requested again.
+*/
+func checkHolds(i *Impact) error {
+	var held []string
+	for _, v := range i.Versions() {
+		if v.Held {
+			held = append(held, fmt.Sprintf("%s %s %s", v.Kind, v.Name, v.Version))
+		}
+	}
+	if len(held) > 0 {
+		return fmt.Errorf("can't purge %s, which are %w", strings.Join(held, ", "), internal.ErrHeld)
+	}
+	return nil
+}
+
+func ids(vs []*Version) []string {
+	out := make([]string, 0, len(vs))
+	for _, v := range vs {
+		out = append(out, v.ID)
+	}
+	return out
+}
+
+func resourceType(k Kind) audit.ResourceType {
+	if k == KindModel {
+		return audit.ResourceModel
+	}
+	return audit.ResourceDataset
+}
@@ -0,0 +1,247 @@
+package erasure
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/heldtogether/traintrack/internal/uploads"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+type mockStore struct {
+	impact *Impact
+	files  map[string][]uploads.FileRef
+	calls  *[]string
+}
+
+func (m *mockStore) Requests(_ context.Context, tenant string) ([]*Request, error) {
+	return nil, nil
+}
+
+func (m *mockStore) Request(_ context.Context, tenant string, id string) (*Request, error) {
+	return nil, nil
+}
+
+func (m *mockStore) Impact(_ context.Context, tenant string, id string) (*Impact, error) {
+	return m.impact, nil
+}
+
+func (m *mockStore) impactWithQuerier(_ context.Context, _ Querier, tenant string, id string) (*Impact, error) {
+	*m.calls = append(*m.calls, "impact "+tenant+" "+id)
+	return m.impact, nil
+}
+
+func (m *mockStore) lockWithQuerier(_ context.Context, _ Querier, tenant string, id string) error {
+	*m.calls = append(*m.calls, "lock "+tenant+" "+id)
+	return nil
+}
+
+func (m *mockStore) createWithQuerier(_ context.Context, _ Querier, r *Request) error {
+	*m.calls = append(*m.calls, "create")
+	r.ID = "e1"
+	return nil
+}
+
+func (m *mockStore) taintWithQuerier(_ context.Context, _ Querier, kind Kind, requestID string, ids []string) error {
+	*m.calls = append(*m.calls, "taint "+string(kind)+" "+requestID+" "+strings.Join(ids, ","))
+	return nil
+}
+
+func (m *mockStore) purgeWithQuerier(_ context.Context, _ Querier, kind Kind, id string) (bool, error) {
+	*m.calls = append(*m.calls, "purge "+string(kind)+" "+id)
+	return true, nil
+}
+
+func (m *mockStore) filesWithQuerier(_ context.Context, _ Querier, kind Kind, id string) ([]uploads.FileRef, error) {
+	return m.files[id], nil
+}
+
+type mockFiles struct {
+	fail  string
+	calls *[]string
+}
+
+func (m *mockFiles) RemoveFile(path string) error {
+	*m.calls = append(*m.calls, "remove "+path)
+	if path == m.fail {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+type mockAudit struct {
+	events []*audit.Event
+}
+
+func (m *mockAudit) RecordWithQuerier(_ context.Context, _ audit.Querier, e *audit.Event) error {
+	m.events = append(m.events, e)
+	return nil
+}
+
+type mockDB struct {
+	calls *[]string
+}
+
+func (m *mockDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	conn, _ := pgxmock.NewConn()
+	tx, _ := conn.Begin(ctx)
+	return &loggingTx{Tx: tx, log: m.calls}, nil
+}
+
+type loggingTx struct {
+	pgx.Tx
+	log *[]string
+}
+
+func (l *loggingTx) Commit(ctx context.Context) error {
+	*l.log = append(*l.log, "commit")
+	return nil
+}
+
+func (l *loggingTx) Rollback(ctx context.Context) error {
+	*l.log = append(*l.log, "rollback")
+	return nil
+}
+
+/*
+impactOf returns the impact of erasing a dataset with a child dataset,
+which a model was trained on. The child was purged before.
+*/
+func impactOf() *Impact {
+	return &Impact{
+		Datasets: []*Version{
+			{Kind: KindDataset, ID: "d1", Name: "prices", Version: "1.0.0"},
+			{Kind: KindDataset, ID: "d2", Name: "prices", Version: "1.0.1", DerivedFrom: "d1", Purged: true},
+		},
+		Models: []*Version{
+			{Kind: KindModel, ID: "m1", Name: "regressor", Version: "1.0.0", DerivedFrom: "d2", Stage: "production"},
+		},
+	}
+}
+
+func TestService_Erase(t *testing.T) {
+	tests := []struct {
+		name          string
+		purge         bool
+		failRemove    string
+		held          bool
+		expectedCalls []string
+		expectedFiles []string
+		wantErr       bool
+	}{
+		{
+			name: "taints",
+			expectedCalls: []string{
+				"lock acme d1", "impact acme d1",
+				"create", "taint dataset e1 d1,d2", "taint model e1 m1",
+				"commit",
+			},
+		},
+		{
+			name:  "taints and purges",
+			purge: true,
+			expectedCalls: []string{
+				"lock acme d1", "impact acme d1",
+				"purge dataset d1", "remove datasets/d1/train.csv",
+				"purge model m1", "remove models/m1/model.pkl",
+				"create", "taint dataset e1 d1,d2", "taint model e1 m1",
+				"commit",
+			},
+			expectedFiles: []string{"datasets/d1/train.csv", "models/m1/model.pkl"},
+		},
+		{
+			name:  "purge held",
+			purge: true,
+			held:  true,
+			expectedCalls: []string{
+				"lock acme d1", "impact acme d1",
+				"rollback",
+			},
+			wantErr: true,
+		},
+		{
+			name:       "purge fails",
+			purge:      true,
+			failRemove: "models/m1/model.pkl",
+			expectedCalls: []string{
+				"lock acme d1", "impact acme d1",
+				"purge dataset d1", "remove datasets/d1/train.csv",
+				"purge model m1", "remove models/m1/model.pkl",
+				"rollback",
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			recorder := &mockAudit{}
+			impact := impactOf()
+			impact.Models[0].Held = tc.held
+
+			s := &Service{
+				s: &mockStore{
+					impact: impact,
+					files: map[string][]uploads.FileRef{
+						"d1": {{FileName: "train.csv", Path: "datasets/d1"}},
+						"m1": {{FileName: "model.pkl", Path: "models/m1"}},
+					},
+					calls: &calls,
+				},
+				files: &mockFiles{fail: tc.failRemove, calls: &calls},
+				db:    &mockDB{calls: &calls},
+				audit: recorder,
+			}
+
+			ctx := auth.NewContext(context.Background(), &auth.Identity{Subject: "dev|1", Tenant: "acme"})
+			erased, err := s.Erase(ctx, &Request{DatasetID: "d1", Reason: "ticket 123", Purge: tc.purge, RequestedBy: "spoofed"})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, wanted error: %t", err, tc.wantErr)
+			}
+			if tc.held && !errors.Is(err, internal.ErrHeld) {
+				t.Errorf("expected ErrHeld, got %v", err)
+			}
+			if !reflect.DeepEqual(calls, tc.expectedCalls) {
+				t.Errorf("calls %v, want %v", calls, tc.expectedCalls)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			if erased.ID != "e1" || erased.RequestedBy != "dev|1" || erased.Tenant != "acme" || erased.Reason != "ticket 123" {
+				t.Errorf("unexpected request: %+v", erased)
+			}
+
+			var files []string
+			for _, v := range erased.Impact.Versions() {
+				if tc.purge && !v.Purged {
+					t.Errorf("expected %s to be purged", v.ID)
+				}
+				files = append(files, v.Files...)
+			}
+			if !reflect.DeepEqual(files, tc.expectedFiles) {
+				t.Errorf("files %v, want %v", files, tc.expectedFiles)
+			}
+
+			if len(recorder.events) != 3 {
+				t.Fatalf("expected an event per affected version, got %d", len(recorder.events))
+			}
+			for _, e := range recorder.events {
+				if e.Action != audit.ActionErase || e.Actor != "dev|1" {
+					t.Errorf("unexpected event: %+v", e)
+				}
+			}
+			if recorder.events[2].ResourceType != audit.ResourceModel || recorder.events[2].ResourceID != "m1" {
+				t.Errorf("expected the last event to be for the model, got %+v", recorder.events[2])
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package erasure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/uploads"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	lockDatasetQuery = `SELECT id FROM datasets WHERE id = $1 AND COALESCE(tenant, '') = $2 FOR UPDATE`
+
+	// The lineage queries follow every link, whether or not the versions
+	// along it have been deleted, since deleted versions still hold their
+	// data until they are purged. They never leave the caller's tenant.
+	datasetLineageQuery = `WITH RECURSIVE lineage (id, derived_from) AS (
+  SELECT id, NULL::uuid FROM datasets WHERE id = $1 AND COALESCE(tenant, '') = $2
+  UNION
+  SELECT d.id, d.parent FROM datasets d JOIN lineage l ON d.parent = l.id
+  WHERE COALESCE(d.tenant, '') = $2
+)
+SELECT
+  d.id::text,
+  d.name,
+  d.version,
+  COALESCE(d.tenant, ''),
+  COALESCE(l.derived_from::text, ''),
+  d.purged_at IS NOT NULL,
+  EXISTS (
+    SELECT 1 FROM legal_holds h
+    WHERE h.resource_type = 'dataset' AND h.resource_id = d.id AND h.released_at IS NULL
+  )
+FROM lineage l
+JOIN datasets d ON d.id = l.id
+ORDER BY d.created_at, d.id`
+	modelLineageQuery = `WITH RECURSIVE lineage (id, derived_from) AS (
+  SELECT id, dataset FROM models WHERE dataset = ANY($1) AND COALESCE(tenant, '') = $2
+  UNION
+  SELECT m.id, m.parent::text FROM models m JOIN lineage l ON m.parent = l.id
+  WHERE COALESCE(m.tenant, '') = $2
+)
+SELECT
+  m.id::text,
+  m.name,
+  m.version,
+  COALESCE(m.tenant, ''),
+  l.derived_from,
+  m.stage,
+  m.purged_at IS NOT NULL,
+  EXISTS (
+    SELECT 1 FROM legal_holds h
+    WHERE h.resource_type = 'model' AND h.resource_id = m.id AND h.released_at IS NULL
+  )
+FROM lineage l
+JOIN models m ON m.id = l.id
+ORDER BY m.created_at, m.id`
+
+	// Only the first erasure to affect a version is recorded against it;
+	// later ones still list it in their impact.
+	taintDatasetsQuery = `UPDATE datasets SET tainted_by = COALESCE(tainted_by, $1) WHERE id = ANY($2::uuid[])`
+	taintModelsQuery   = `UPDATE models SET tainted_by = COALESCE(tainted_by, $1) WHERE id = ANY($2::uuid[])`
+
+	// Purged versions are put in the trash too, and purged models are
+	// archived, since they can no longer be served.
+	purgeDatasetQuery = `UPDATE datasets SET deleted_at = COALESCE(deleted_at, now()), purged_at = now()
+WHERE id = $1 AND purged_at IS NULL`
+	purgeModelQuery = `UPDATE models SET deleted_at = COALESCE(deleted_at, now()), purged_at = now(), stage = 'archived'
+WHERE id = $1 AND purged_at IS NULL`
+	datasetFilesQuery = `SELECT files FROM uploads WHERE dataset_id = $1`
+	modelFilesQuery   = `SELECT files FROM uploads WHERE model_id = $1`
+
+	createQuery = `INSERT INTO erasure_requests
+(dataset_id, reason, purge, impact, requested_by, tenant)
+VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6)
+RETURNING id, requested_at`
+	requestsQuery = `SELECT
+  id::text,
+  dataset_id::text,
+  reason,
+  purge,
+  impact,
+  requested_at,
+  COALESCE(requested_by, ''),
+  tenant
+FROM erasure_requests
+WHERE tenant = $1`
+	requestsOrder = `
+ORDER BY requested_at, id`
+	requestClause = ` AND id = $2`
+)
+
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+type Store struct {
+	q Querier
+}
+
+func NewStore(q Querier) *Store {
+	return &Store{
+		q: q,
+	}
+}
+
+/*
+Requests returns every erasure request made by tenant, oldest first.
+*/
+func (s *Store) Requests(ctx context.Context, tenant string) ([]*Request, error) {
+	rows, err := s.q.Query(ctx, requestsQuery+requestsOrder, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("could not query erasure requests: %w", err)
+	}
+	defer rows.Close()
+
+	rs := []*Request{}
+	for rows.Next() {
+		r, err := scanRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, r)
+	}
+	return rs, rows.Err()
+}
+
+/*
+Request returns tenant's erasure request id, or an error wrapping
+internal.ErrNotFound if there is no such request.
+*/
+func (s *Store) Request(ctx context.Context, tenant string, id string) (*Request, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("erasure request %s: %w", id, internal.ErrNotFound)
+	}
+
+	r, err := scanRequest(s.q.QueryRow(ctx, requestsQuery+requestClause, tenant, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("erasure request %s: %w", id, internal.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not query erasure request: %w", err)
+	}
+	return r, nil
+}
+
+func scanRequest(row pgx.Row) (*Request, error) {
+	r := &Request{}
+	if err := row.Scan(
+		&r.ID,
+		&r.DatasetID,
+		&r.Reason,
+		&r.Purge,
+		&r.Impact,
+		&r.RequestedAt,
+		&r.RequestedBy,
+		&r.Tenant,
+	); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+/*
+Impact finds every version of tenant's affected by erasing their dataset
+version id, without changing anything. Another tenant's dataset is
+reported as not found.
+*/
+func (s *Store) Impact(ctx context.Context, tenant string, id string) (*Impact, error) {
+	return s.impactWithQuerier(ctx, s.q, tenant, id)
+}
+
+func (s *Store) impactWithQuerier(ctx context.Context, q Querier, tenant string, id string) (*Impact, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+	}
+
+	rows, err := q.Query(ctx, datasetLineageQuery, id, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("could not query dataset lineage: %w", err)
+	}
+	defer rows.Close()
+
+	impact := &Impact{Datasets: []*Version{}, Models: []*Version{}}
+	var ids []string
+	for rows.Next() {
+		v := &Version{Kind: KindDataset}
+		if err := rows.Scan(&v.ID, &v.Name, &v.Version, &v.Tenant, &v.DerivedFrom, &v.Purged, &v.Held); err != nil {
+			return nil, err
+		}
+		impact.Datasets = append(impact.Datasets, v)
+		ids = append(ids, v.ID)
+	}
+	// Closed before the next query, which may share a transaction.
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+	}
+
+	rows, err = q.Query(ctx, modelLineageQuery, ids, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("could not query model lineage: %w", err)
+	}
+	defer rows.Close()
+
+	// A model can be reached more than once, such as when it was both
+	// trained on an affected dataset and derived from an affected model.
+	seen := map[string]bool{}
+	for rows.Next() {
+		v := &Version{Kind: KindModel}
+		if err := rows.Scan(&v.ID, &v.Name, &v.Version, &v.Tenant, &v.DerivedFrom, &v.Stage, &v.Purged, &v.Held); err != nil {
+			return nil, err
+		}
+		if seen[v.ID] {
+			continue
+		}
+		seen[v.ID] = true
+		impact.Models = append(impact.Models, v)
+	}
+	return impact, rows.Err()
+}
+
+func (s *Store) lockWithQuerier(ctx context.Context, q Querier, tenant string, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+	}
+
+	var locked string
+	if err := q.QueryRow(ctx, lockDatasetQuery, id, tenant).Scan(&locked); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Store) createWithQuerier(ctx context.Context, q Querier, r *Request) error {
+	row := q.QueryRow(ctx, createQuery, r.DatasetID, r.Reason, r.Purge, r.Impact, r.RequestedBy, r.Tenant)
+	if err := row.Scan(&r.ID, &r.RequestedAt); err != nil {
+		return fmt.Errorf("could not create erasure request: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) taintWithQuerier(ctx context.Context, q Querier, kind Kind, requestID string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	query := taintDatasetsQuery
+	if kind == KindModel {
+		query = taintModelsQuery
+	}
+	if _, err := q.Exec(ctx, query, requestID, ids); err != nil {
+		return fmt.Errorf("could not taint %ss: %w", kind, err)
+	}
+	return nil
+}
+
+/*
+purgeWithQuerier marks the version id as purged, reporting false if it
+already was.
+*/
+func (s *Store) purgeWithQuerier(ctx context.Context, q Querier, kind Kind, id string) (bool, error) {
+	query := purgeDatasetQuery
+	if kind == KindModel {
+		query = purgeModelQuery
+	}
+	tag, err := q.Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("could not purge %s %s: %w", kind, id, err)
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+/*
+filesWithQuerier returns every artefact stored for the version id, in
+order of artefact name.
+*/
+func (s *Store) filesWithQuerier(ctx context.Context, q Querier, kind Kind, id string) ([]uploads.FileRef, error) {
+	query := datasetFilesQuery
+	if kind == KindModel {
+		query = modelFilesQuery
+	}
+	rows, err := q.Query(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not query artefacts: %w", err)
+	}
+	defer rows.Close()
+
+	var refs []uploads.FileRef
+	for rows.Next() {
+		var files map[string]uploads.FileRef
+		if err := rows.Scan(&files); err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(files))
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			refs = append(refs, files[name])
+		}
+	}
+	return refs, rows.Err()
+}
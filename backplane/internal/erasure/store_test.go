@@ -0,0 +1,201 @@
+package erasure
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/uploads"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+const (
+	root    = "9f9b8055-0000-4000-8000-000000000001"
+	child   = "9f9b8055-0000-4000-8000-000000000002"
+	model   = "9f9b8055-0000-4000-8000-000000000003"
+	derived = "9f9b8055-0000-4000-8000-000000000004"
+)
+
+func TestImpact(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.ExpectQuery(regexp.QuoteMeta(datasetLineageQuery)).
+		WithArgs(root, "acme").
+		WillReturnRows(db.NewRows([]string{"id", "name", "version", "tenant", "derived_from", "purged", "held"}).
+			AddRow(root, "prices", "1.0.0", "acme", "", false, false).
+			AddRow(child, "prices", "1.0.1", "acme", root, true, true))
+	db.ExpectQuery(regexp.QuoteMeta(modelLineageQuery)).
+		WithArgs([]string{root, child}, "acme").
+		WillReturnRows(db.NewRows([]string{"id", "name", "version", "tenant", "derived_from", "stage", "purged", "held"}).
+			AddRow(model, "regressor", "1.0.0", "acme", child, "production", false, false).
+			AddRow(derived, "regressor", "1.1.0", "acme", model, "development", false, false).
+			// Trained on an affected dataset, as well as derived from model.
+			AddRow(derived, "regressor", "1.1.0", "acme", root, "development", false, false))
+
+	impact, err := NewStore(db).Impact(context.Background(), "acme", root)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := &Impact{
+		Datasets: []*Version{
+			{Kind: KindDataset, ID: root, Name: "prices", Version: "1.0.0", Tenant: "acme"},
+			{Kind: KindDataset, ID: child, Name: "prices", Version: "1.0.1", Tenant: "acme", DerivedFrom: root, Purged: true, Held: true},
+		},
+		Models: []*Version{
+			{Kind: KindModel, ID: model, Name: "regressor", Version: "1.0.0", Tenant: "acme", DerivedFrom: child, Stage: "production"},
+			{Kind: KindModel, ID: derived, Name: "regressor", Version: "1.1.0", Tenant: "acme", DerivedFrom: model, Stage: "development"},
+		},
+	}
+	if !reflect.DeepEqual(impact, want) {
+		t.Errorf("got impact %+v, wanted %+v", impact, want)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestImpactNotFound(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.ExpectQuery(regexp.QuoteMeta(datasetLineageQuery)).
+		WithArgs(root, "acme").
+		WillReturnRows(db.NewRows([]string{"id", "name", "version", "tenant", "derived_from", "purged", "held"}))
+
+	store := NewStore(db)
+
+	if _, err := store.Impact(context.Background(), "acme", root); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := store.Impact(context.Background(), "acme", "not-a-uuid"); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for malformed id, got %v", err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRequests(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	requestedAt := time.Date(2025, 7, 7, 9, 0, 0, 0, time.UTC)
+	impact := &Impact{Datasets: []*Version{{Kind: KindDataset, ID: root}}, Models: []*Version{}}
+	columns := []string{"id", "dataset_id", "reason", "purge", "impact", "requested_at", "requested_by", "tenant"}
+
+	db.ExpectQuery(regexp.QuoteMeta(requestsQuery + requestsOrder)).
+		WithArgs("acme").
+		WillReturnRows(db.NewRows(columns).
+			AddRow("e1", root, "ticket 123", true, impact, requestedAt, "dev|1", "acme"))
+	db.ExpectQuery(regexp.QuoteMeta(requestsQuery+requestClause)).
+		WithArgs("acme", child).
+		WillReturnError(pgx.ErrNoRows)
+
+	store := NewStore(db)
+
+	rs, err := store.Requests(context.Background(), "acme")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []*Request{{ID: "e1", DatasetID: root, Reason: "ticket 123", Purge: true, Impact: impact, RequestedAt: requestedAt, RequestedBy: "dev|1", Tenant: "acme"}}
+	if !reflect.DeepEqual(rs, want) {
+		t.Errorf("got requests %+v, wanted %+v", rs, want)
+	}
+
+	if _, err := store.Request(context.Background(), "acme", child); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestErasureWrites(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	requestedAt := time.Date(2025, 7, 7, 9, 0, 0, 0, time.UTC)
+	impact := &Impact{Datasets: []*Version{{Kind: KindDataset, ID: root}}, Models: []*Version{}}
+
+	db.ExpectQuery(regexp.QuoteMeta(lockDatasetQuery)).
+		WithArgs(root, "acme").
+		WillReturnRows(db.NewRows([]string{"id"}).AddRow(root))
+	db.ExpectQuery(regexp.QuoteMeta(createQuery)).
+		WithArgs(root, "ticket 123", true, impact, "dev|1", "acme").
+		WillReturnRows(db.NewRows([]string{"id", "requested_at"}).AddRow("e1", requestedAt))
+	db.ExpectExec(regexp.QuoteMeta(taintDatasetsQuery)).
+		WithArgs("e1", []string{root, child}).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 2))
+	db.ExpectExec(regexp.QuoteMeta(purgeModelQuery)).
+		WithArgs(model).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	db.ExpectQuery(regexp.QuoteMeta(modelFilesQuery)).
+		WithArgs(model).
+		WillReturnRows(db.NewRows([]string{"files"}).
+			AddRow(map[string]uploads.FileRef{
+				"trained_model": {FileName: "model.pkl", Path: "models/3"},
+				"config":        {FileName: "config.json", Path: "models/3"},
+			}))
+
+	store := NewStore(db)
+	ctx := context.Background()
+
+	if err := store.lockWithQuerier(ctx, db, "acme", root); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	r := &Request{DatasetID: root, Reason: "ticket 123", Purge: true, Impact: impact, RequestedBy: "dev|1", Tenant: "acme"}
+	if err := store.createWithQuerier(ctx, db, r); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if r.ID != "e1" || !r.RequestedAt.Equal(requestedAt) {
+		t.Errorf("unexpected request: %+v", r)
+	}
+
+	if err := store.taintWithQuerier(ctx, db, KindDataset, "e1", []string{root, child}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	// Nothing to taint, so no query.
+	if err := store.taintWithQuerier(ctx, db, KindModel, "e1", nil); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if purged, err := store.purgeWithQuerier(ctx, db, KindModel, model); err != nil || purged {
+		t.Errorf("expected already purged model to be skipped, got %t, %v", purged, err)
+	}
+
+	files, err := store.filesWithQuerier(ctx, db, KindModel, model)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []uploads.FileRef{{FileName: "config.json", Path: "models/3"}, {FileName: "model.pkl", Path: "models/3"}}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("got files %+v, wanted %+v", files, want)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -1,8 +1,40 @@
 package internal
 
+import (
+	"errors"
+	"fmt"
+)
+
+/*
+ErrNotFound is wrapped by stores when the thing asked for doesn't exist,
+so that handlers can answer with a 404.
+*/
+var ErrNotFound = errors.New("not found")
+
 type Error struct {
 	Code    int    `json:"code"`
 	Message string `json:"error"`
 	Reason  string `json:"reason"`
 	Details any    `json:"details,omitempty"`
 }
+
+func (e *Error) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("%d - %s", e.Code, e.Message)
+	}
+	return fmt.Sprintf("%d - %s: %s", e.Code, e.Message, e.Reason)
+}
+
+/*
+Errors for removing versions, all of which handlers answer with a 409.
+ErrInUse is wrapped when other versions still depend on the one being
+removed, and ErrHeld when it is under a legal hold, which force doesn't
+override.
+*/
+var (
+	ErrInUse      = errors.New("still in use")
+	ErrInTrash    = errors.New("already in the trash")
+	ErrNotInTrash = errors.New("not in the trash")
+	ErrPurged     = errors.New("already purged")
+	ErrHeld       = errors.New("under legal hold")
+)
@@ -0,0 +1,22 @@
+/*
+Package evaluations records how model versions did on datasets other
+than the one they were trained on, such as holdout sets or slices of
+production traffic.
+
+Each evaluation links a model and a dataset to the metrics the model
+scored on it, the evaluator code which produced them and when. A model
+can be evaluated on the same dataset many times, as evaluators change:
+
+	e, err := store.Add(ctx, &evaluations.Evaluation{
+		ModelID:   modelID,
+		DatasetID: holdoutID,
+		Metrics:   json.RawMessage(`{"r2": 0.81}`),
+		Evaluator: json.RawMessage(`{"name": "evaluate", "source": "def evaluate(m, d): ..."}`),
+	})
+
+A benchmark Matrix lines models up against the datasets they were
+evaluated on, with each model's latest score for one metric on each.
+
+Evaluations aren't recorded in the audit log.
+*/
+package evaluations
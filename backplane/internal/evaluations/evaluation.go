@@ -0,0 +1,60 @@
+package evaluations
+
+import (
+	"encoding/json"
+	"time"
+)
+
+/*
+Kind is what evaluations are listed for.
+*/
+type Kind string
+
+const (
+	KindModel   Kind = "model"
+	KindDataset Kind = "dataset"
+)
+
+/*
+Evaluation is the metrics a model scored on a dataset.
+*/
+type Evaluation struct {
+	ID        string          `json:"id"`
+	ModelID   string          `json:"model"`
+	DatasetID string          `json:"dataset"`
+	Metrics   json.RawMessage `json:"metrics"`
+
+	// Evaluator describes the code which produced the metrics, such as
+	// its name and source.
+	Evaluator json.RawMessage `json:"evaluator"`
+
+	// EvaluatedAt may be given when the evaluation is added, if it was
+	// run earlier. Otherwise it's when the evaluation was added.
+	EvaluatedAt time.Time `json:"evaluated_at"`
+
+	// CreatedBy and Tenant are set by the server from the verified token.
+	CreatedBy string `json:"created_by"`
+	Tenant    string `json:"tenant,omitempty"`
+}
+
+/*
+Subject is a model or dataset version in a Matrix.
+*/
+type Subject struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+/*
+Matrix is a benchmark of models against datasets. Values has a row for
+each of Models and a column for each of Datasets, holding the latest
+value of Metric the model scored on the dataset, or nil if it hasn't
+been evaluated on it.
+*/
+type Matrix struct {
+	Metric   string       `json:"metric"`
+	Models   []Subject    `json:"models"`
+	Datasets []Subject    `json:"datasets"`
+	Values   [][]*float64 `json:"values"`
+}
@@ -0,0 +1,225 @@
+package evaluations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/auth"
+)
+
+// MaxModels is the most models a matrix can be asked for by id.
+const MaxModels = 100
+
+/*
+Recorder allows evaluations to be added, listed and benchmarked.
+*/
+type Recorder interface {
+	Add(ctx context.Context, e *Evaluation) (*Evaluation, error)
+	List(ctx context.Context, tenant string, kind Kind, id string) ([]*Evaluation, error)
+	Matrix(ctx context.Context, tenant string, metric string, ids []string, name string) (*Matrix, error)
+}
+
+type Handler struct {
+	r Recorder
+}
+
+func NewHandler(r Recorder) *Handler {
+	return &Handler{
+		r: r,
+	}
+}
+
+/*
+ModelEvaluations handles requests for a model's evaluations. It should
+be registered under /models/{id}/evaluations.
+*/
+func (h *Handler) ModelEvaluations(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.List(w, r, KindModel)
+	case http.MethodPost:
+		h.Add(w, r)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+/*
+DatasetEvaluations lists the evaluations made on a dataset. It should be
+registered under /datasets/{id}/evaluations.
+*/
+func (h *Handler) DatasetEvaluations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+	h.List(w, r, KindDataset)
+}
+
+/*
+Add records an evaluation of the model, from a body like {"dataset":
+"...", "metrics": {"r2": 0.8}, "evaluator": {"name": "evaluate"}}.
+*/
+func (h *Handler) Add(w http.ResponseWriter, r *http.Request) {
+	var e Evaluation
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		log.Printf("failed to decode body: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to add evaluation",
+			Reason:  fmt.Sprintf("could not parse body: %s", err),
+		})
+		return
+	}
+
+	details := map[string]string{}
+	if e.DatasetID == "" {
+		details["dataset"] = "dataset is a required field"
+	}
+	if !isObject(e.Metrics) {
+		details["metrics"] = "metrics must be an object"
+	}
+	if len(e.Evaluator) > 0 && string(e.Evaluator) != "null" && !isObject(e.Evaluator) {
+		details["evaluator"] = "evaluator must be an object"
+	}
+	if len(details) > 0 {
+		log.Printf("failed to validate input: %v", details)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to add evaluation",
+			Reason:  "bad input",
+			Details: details,
+		})
+		return
+	}
+
+	// Only the server says which model, and who evaluated it.
+	e.ID = ""
+	e.ModelID = mux.Vars(r)["id"]
+	e.CreatedBy, e.Tenant = "", ""
+	if id, ok := auth.IdentityFromContext(r.Context()); ok {
+		e.CreatedBy, e.Tenant = id.Subject, id.Tenant
+	}
+
+	added, err := h.r.Add(r.Context(), &e)
+	if err != nil {
+		writeError(w, "Failed to add evaluation", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(added)
+}
+
+/*
+List returns the evaluations of a model, or made on a dataset, oldest
+first.
+*/
+func (h *Handler) List(w http.ResponseWriter, r *http.Request, kind Kind) {
+	id := mux.Vars(r)["id"]
+
+	es, err := h.r.List(r.Context(), auth.TenantFromContext(r.Context()), kind, id)
+	if err != nil {
+		writeError(w, "Failed to list evaluations", err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(es)
+}
+
+/*
+Matrix benchmarks models against the datasets they were evaluated on,
+by the number in their metrics named by the metric query parameter. The
+models are those given by the models parameter, which may be repeated or
+comma separated, and every version of the model named by name. It should
+be registered under /evaluations/matrix.
+*/
+func (h *Handler) Matrix(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	q := r.URL.Query()
+
+	var ids []string
+	for _, param := range q["models"] {
+		for _, id := range strings.Split(param, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	metric := strings.TrimSpace(q.Get("metric"))
+	name := strings.TrimSpace(q.Get("name"))
+
+	var problem string
+	switch {
+	case metric == "":
+		problem = "metric is required"
+	case len(ids) == 0 && name == "":
+		problem = "give models or a name"
+	case len(ids) > MaxModels:
+		problem = fmt.Sprintf("at most %d models can be given, got %d", MaxModels, len(ids))
+	}
+	if problem != "" {
+		log.Printf("failed to benchmark models: %s", problem)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to benchmark models",
+			Reason:  problem,
+		})
+		return
+	}
+
+	m, err := h.r.Matrix(r.Context(), auth.TenantFromContext(r.Context()), metric, ids, name)
+	if err != nil {
+		writeError(w, "Failed to benchmark models", err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(m)
+}
+
+func isObject(data json.RawMessage) bool {
+	var v map[string]any
+	return json.Unmarshal(data, &v) == nil && v != nil
+}
+
+/*
+writeError logs err and answers with it, as a 404 if the model or
+dataset wasn't found.
+*/
+func writeError(w http.ResponseWriter, message string, err error) {
+	log.Printf("%s: %s", strings.ToLower(message), err)
+
+	code := http.StatusInternalServerError
+	if errors.Is(err, internal.ErrNotFound) {
+		code = http.StatusNotFound
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    code,
+		Message: message,
+		Reason:  err.Error(),
+	})
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    http.StatusMethodNotAllowed,
+		Message: "Method not allowed",
+		Reason:  "",
+	})
+}
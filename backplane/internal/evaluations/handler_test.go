@@ -0,0 +1,193 @@
+package evaluations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/auth"
+)
+
+type mockRecorder struct {
+	AddFn    func(ctx context.Context, e *Evaluation) (*Evaluation, error)
+	ListFn   func(ctx context.Context, tenant string, kind Kind, id string) ([]*Evaluation, error)
+	MatrixFn func(ctx context.Context, tenant string, metric string, ids []string, name string) (*Matrix, error)
+}
+
+func (m *mockRecorder) Add(ctx context.Context, e *Evaluation) (*Evaluation, error) {
+	return m.AddFn(ctx, e)
+}
+
+func (m *mockRecorder) List(ctx context.Context, tenant string, kind Kind, id string) ([]*Evaluation, error) {
+	return m.ListFn(ctx, tenant, kind, id)
+}
+
+func (m *mockRecorder) Matrix(ctx context.Context, tenant string, metric string, ids []string, name string) (*Matrix, error) {
+	return m.MatrixFn(ctx, tenant, metric, ids, name)
+}
+
+func TestHandler(t *testing.T) {
+	evaluatedAt := time.Date(2025, 7, 12, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		body             string
+		route            func(h *Handler) http.HandlerFunc
+		recorder         *mockRecorder
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "POST success",
+			method: http.MethodPost,
+			path:   "/models/m1/evaluations",
+			body:   `{"id": "ignored", "model": "ignored", "dataset": "d1", "metrics": {"r2": 0.81}, "evaluator": {"name": "evaluate"}, "created_by": "ignored"}`,
+			route:  func(h *Handler) http.HandlerFunc { return h.ModelEvaluations },
+			recorder: &mockRecorder{AddFn: func(_ context.Context, e *Evaluation) (*Evaluation, error) {
+				if e.ID != "" || e.ModelID != "m1" || e.CreatedBy != "dev|1" || e.Tenant != "acme" {
+					return nil, fmt.Errorf("unexpected evaluation %+v", e)
+				}
+				added := *e
+				added.ID, added.EvaluatedAt = "e1", evaluatedAt
+				return &added, nil
+			}},
+			expectedStatus: http.StatusCreated,
+			expectedContains: `{"id": "e1", "model": "m1", "dataset": "d1", "metrics": {"r2": 0.81}, "evaluator": {"name": "evaluate"},
+				"evaluated_at": "2025-07-12T09:00:00Z", "created_by": "dev|1", "tenant": "acme"}`,
+		},
+		{
+			name:             "POST failure - bad input",
+			method:           http.MethodPost,
+			path:             "/models/m1/evaluations",
+			body:             `{"metrics": [0.81], "evaluator": "evaluate"}`,
+			route:            func(h *Handler) http.HandlerFunc { return h.ModelEvaluations },
+			recorder:         &mockRecorder{},
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to add evaluation", "reason": "bad input", "details": {"dataset": "dataset is a required field", "metrics": "metrics must be an object", "evaluator": "evaluator must be an object"}}`,
+		},
+		{
+			name:   "POST failure - not found",
+			method: http.MethodPost,
+			path:   "/models/m1/evaluations",
+			body:   `{"dataset": "d1", "metrics": {"r2": 0.81}}`,
+			route:  func(h *Handler) http.HandlerFunc { return h.ModelEvaluations },
+			recorder: &mockRecorder{AddFn: func(_ context.Context, e *Evaluation) (*Evaluation, error) {
+				return nil, fmt.Errorf("dataset d1: %w", internal.ErrNotFound)
+			}},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Failed to add evaluation", "reason": "dataset d1: not found"}`,
+		},
+		{
+			name:   "GET dataset success",
+			method: http.MethodGet,
+			path:   "/datasets/m1/evaluations",
+			route:  func(h *Handler) http.HandlerFunc { return h.DatasetEvaluations },
+			recorder: &mockRecorder{ListFn: func(_ context.Context, tenant string, kind Kind, id string) ([]*Evaluation, error) {
+				if tenant != "acme" || kind != KindDataset || id != "m1" {
+					return nil, fmt.Errorf("unexpected list of %s %s in %s", kind, id, tenant)
+				}
+				return []*Evaluation{}, nil
+			}},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `[]`,
+		},
+		{
+			name:   "matrix success",
+			method: http.MethodGet,
+			path:   "/evaluations/matrix?metric=r2&models=m1,m2&name=regressor",
+			route:  func(h *Handler) http.HandlerFunc { return h.Matrix },
+			recorder: &mockRecorder{MatrixFn: func(_ context.Context, tenant string, metric string, ids []string, name string) (*Matrix, error) {
+				if metric != "r2" || !reflect.DeepEqual(ids, []string{"m1", "m2"}) || name != "regressor" {
+					return nil, fmt.Errorf("unexpected matrix of %s for %v and %q", metric, ids, name)
+				}
+				v := 0.81
+				return &Matrix{
+					Metric:   metric,
+					Models:   []Subject{{ID: "m1", Name: "regressor", Version: "1.0.0"}, {ID: "m2", Name: "regressor", Version: "1.1.0"}},
+					Datasets: []Subject{{ID: "d1", Name: "holdout", Version: "1.0.0"}},
+					Values:   [][]*float64{{&v}, {nil}},
+				}, nil
+			}},
+			expectedStatus: http.StatusOK,
+			expectedContains: `{"metric": "r2",
+				"models": [{"id": "m1", "name": "regressor", "version": "1.0.0"}, {"id": "m2", "name": "regressor", "version": "1.1.0"}],
+				"datasets": [{"id": "d1", "name": "holdout", "version": "1.0.0"}],
+				"values": [[0.81], [null]]}`,
+		},
+		{
+			name:             "matrix failure - no models",
+			method:           http.MethodGet,
+			path:             "/evaluations/matrix?metric=r2",
+			route:            func(h *Handler) http.HandlerFunc { return h.Matrix },
+			recorder:         &mockRecorder{},
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to benchmark models", "reason": "give models or a name"}`,
+		},
+		{
+			name:             "METHOD failure",
+			method:           http.MethodPost,
+			path:             "/datasets/m1/evaluations",
+			route:            func(h *Handler) http.HandlerFunc { return h.DatasetEvaluations },
+			recorder:         &mockRecorder{},
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+			req := httptest.NewRequest(tc.method, tc.path, body)
+			req = mux.SetURLVars(req, map[string]string{"id": "m1"})
+			req = req.WithContext(auth.NewContext(req.Context(), &auth.Identity{Subject: "dev|1", Tenant: "acme"}))
+			rr := httptest.NewRecorder()
+
+			tc.route(NewHandler(tc.recorder))(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func checkResponse(t *testing.T, got *http.Response, expectedStatus int, expected string) {
+	defer got.Body.Close()
+
+	body, err := io.ReadAll(got.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %s", err)
+	}
+
+	if expectedStatus != got.StatusCode {
+		t.Errorf("status mismatch - wanted %d, got %d", expectedStatus, got.StatusCode)
+	}
+
+	var gotData any
+	if err := json.Unmarshal(body, &gotData); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v\nbody: %s", err, string(body))
+	}
+
+	var expectedData any
+	if err := json.Unmarshal([]byte(expected), &expectedData); err != nil {
+		t.Fatalf("failed to unmarshal expected value: %v\njson: %s", err, expected)
+	}
+
+	if !reflect.DeepEqual(expectedData, gotData) {
+		t.Errorf("JSON mismatch:\nexpected: %+v\ngot: %+v", expectedData, gotData)
+	}
+}
@@ -0,0 +1,246 @@
+package evaluations
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	modelExistsQuery   = `SELECT EXISTS (SELECT 1 FROM models WHERE id = $1)`
+	datasetExistsQuery = `SELECT EXISTS (SELECT 1 FROM datasets WHERE id = $1)`
+
+	createQuery = `INSERT INTO evaluations (model_id, dataset_id, metrics, evaluator, evaluated_at, created_by, tenant)
+VALUES ($1, $2, $3, COALESCE($4, '{}'::jsonb), COALESCE($5, now()), NULLIF($6, ''), $7)
+RETURNING id::text, evaluator, evaluated_at`
+
+	listQuery = `SELECT
+  id::text,
+  model_id::text,
+  dataset_id::text,
+  metrics,
+  evaluator,
+  evaluated_at,
+  COALESCE(created_by, ''),
+  tenant
+FROM evaluations
+WHERE tenant = $1`
+	modelClause   = ` AND model_id = $2`
+	datasetClause = ` AND dataset_id = $2`
+	listOrderBy   = `
+ORDER BY evaluated_at, id`
+
+	// The evaluation a model was created with counts as an evaluation on
+	// the dataset it was trained on, from when it was created, so models
+	// have a score on their training dataset without being re-evaluated.
+	matrixQuery = `WITH scores AS (
+  SELECT e.model_id, e.dataset_id, e.metrics, e.evaluated_at
+  FROM evaluations e
+  WHERE e.tenant = $1
+  UNION ALL
+  SELECT m.id, d.id, m.evaluation, m.created_at
+  FROM models m
+  JOIN datasets d ON d.id::text = m.dataset
+  WHERE m.evaluation IS NOT NULL AND COALESCE(m.tenant, '') = $1
+)
+SELECT DISTINCT ON (s.model_id, s.dataset_id)
+  m.id::text,
+  m.name,
+  m.version,
+  m.created_at,
+  d.id::text,
+  d.name,
+  d.version,
+  d.created_at,
+  (s.metrics #>> $2)::float8
+FROM scores s
+JOIN models m ON m.id = s.model_id
+JOIN datasets d ON d.id = s.dataset_id
+WHERE jsonb_typeof(s.metrics #> $2) = 'number'
+  AND (m.id::text = ANY($3) OR m.name = $4)
+  AND m.deleted_at IS NULL
+  AND d.deleted_at IS NULL
+ORDER BY s.model_id, s.dataset_id, s.evaluated_at DESC`
+)
+
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+type Store struct {
+	q Querier
+}
+
+func NewStore(q Querier) *Store {
+	return &Store{
+		q: q,
+	}
+}
+
+/*
+Add records e, returning it as stored. It wraps internal.ErrNotFound if
+there is no such model or dataset.
+*/
+func (s *Store) Add(ctx context.Context, e *Evaluation) (*Evaluation, error) {
+	if err := s.exists(ctx, KindModel, e.ModelID); err != nil {
+		return nil, err
+	}
+	if err := s.exists(ctx, KindDataset, e.DatasetID); err != nil {
+		return nil, err
+	}
+
+	var evaluatedAt *time.Time
+	if !e.EvaluatedAt.IsZero() {
+		evaluatedAt = &e.EvaluatedAt
+	}
+
+	added := *e
+	row := s.q.QueryRow(ctx, createQuery, e.ModelID, e.DatasetID, e.Metrics, e.Evaluator, evaluatedAt, e.CreatedBy, e.Tenant)
+	if err := row.Scan(&added.ID, &added.Evaluator, &added.EvaluatedAt); err != nil {
+		return nil, fmt.Errorf("could not add evaluation: %w", err)
+	}
+	return &added, nil
+}
+
+/*
+List returns the evaluations in tenant of the model or dataset id, oldest
+first. It wraps internal.ErrNotFound if there is no such model or
+dataset.
+*/
+func (s *Store) List(ctx context.Context, tenant string, kind Kind, id string) ([]*Evaluation, error) {
+	if err := s.exists(ctx, kind, id); err != nil {
+		return nil, err
+	}
+
+	query := listQuery + modelClause + listOrderBy
+	if kind == KindDataset {
+		query = listQuery + datasetClause + listOrderBy
+	}
+
+	rows, err := s.q.Query(ctx, query, tenant, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not query evaluations: %w", err)
+	}
+	defer rows.Close()
+
+	es := []*Evaluation{}
+	for rows.Next() {
+		e := &Evaluation{}
+		if err := rows.Scan(&e.ID, &e.ModelID, &e.DatasetID, &e.Metrics, &e.Evaluator, &e.EvaluatedAt, &e.CreatedBy, &e.Tenant); err != nil {
+			return nil, err
+		}
+		es = append(es, e)
+	}
+	return es, rows.Err()
+}
+
+/*
+Matrix benchmarks the models ids, and every version of the model name,
+against every dataset they've been evaluated on in tenant, by metric.
+Models and datasets are ordered by when they were created, and those in
+the trash are left out. metric may name a nested key with dots, such as
+"test.r2".
+*/
+func (s *Store) Matrix(ctx context.Context, tenant string, metric string, ids []string, name string) (*Matrix, error) {
+	if ids == nil {
+		ids = []string{}
+	}
+
+	rows, err := s.q.Query(ctx, matrixQuery, tenant, strings.Split(metric, "."), ids, name)
+	if err != nil {
+		return nil, fmt.Errorf("could not query evaluations: %w", err)
+	}
+	defer rows.Close()
+
+	type subject struct {
+		Subject
+		createdAt time.Time
+	}
+	type score struct {
+		model, dataset string
+		value          float64
+	}
+
+	models := map[string]subject{}
+	datasets := map[string]subject{}
+	var scores []score
+	for rows.Next() {
+		var m, d subject
+		var value float64
+		if err := rows.Scan(&m.ID, &m.Name, &m.Version, &m.createdAt, &d.ID, &d.Name, &d.Version, &d.createdAt, &value); err != nil {
+			return nil, err
+		}
+		models[m.ID] = m
+		datasets[d.ID] = d
+		scores = append(scores, score{model: m.ID, dataset: d.ID, value: value})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Lines subjects up by when they were created, noting where each is.
+	order := func(subjects map[string]subject) ([]Subject, map[string]int) {
+		sorted := make([]subject, 0, len(subjects))
+		for _, sub := range subjects {
+			sorted = append(sorted, sub)
+		}
+		slices.SortFunc(sorted, func(a, b subject) int {
+			if c := a.createdAt.Compare(b.createdAt); c != 0 {
+				return c
+			}
+			return strings.Compare(a.ID, b.ID)
+		})
+
+		out := make([]Subject, len(sorted))
+		index := make(map[string]int, len(sorted))
+		for i, sub := range sorted {
+			out[i] = sub.Subject
+			index[sub.ID] = i
+		}
+		return out, index
+	}
+
+	m := &Matrix{Metric: metric}
+	var modelIndex, datasetIndex map[string]int
+	m.Models, modelIndex = order(models)
+	m.Datasets, datasetIndex = order(datasets)
+
+	m.Values = make([][]*float64, len(m.Models))
+	for i := range m.Values {
+		m.Values[i] = make([]*float64, len(m.Datasets))
+	}
+	for _, sc := range scores {
+		value := sc.value
+		m.Values[modelIndex[sc.model]][datasetIndex[sc.dataset]] = &value
+	}
+	return m, nil
+}
+
+func (s *Store) exists(ctx context.Context, kind Kind, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("%s %s: %w", kind, id, internal.ErrNotFound)
+	}
+
+	query := modelExistsQuery
+	if kind == KindDataset {
+		query = datasetExistsQuery
+	}
+
+	var exists bool
+	if err := s.q.QueryRow(ctx, query, id).Scan(&exists); err != nil {
+		return fmt.Errorf("could not query %s: %w", kind, err)
+	}
+	if !exists {
+		return fmt.Errorf("%s %s: %w", kind, id, internal.ErrNotFound)
+	}
+	return nil
+}
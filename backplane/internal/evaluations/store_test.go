@@ -0,0 +1,188 @@
+package evaluations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+const (
+	modelID   = "7a1e2f30-0000-4000-8000-000000000001"
+	datasetID = "7a1e2f30-0000-4000-8000-000000000002"
+)
+
+func TestAdd(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	evaluatedAt := time.Date(2025, 7, 12, 9, 0, 0, 0, time.UTC)
+	metrics := json.RawMessage(`{"r2": 0.81}`)
+
+	db.ExpectQuery(regexp.QuoteMeta(modelExistsQuery)).
+		WithArgs(modelID).
+		WillReturnRows(db.NewRows([]string{"exists"}).AddRow(true))
+	db.ExpectQuery(regexp.QuoteMeta(datasetExistsQuery)).
+		WithArgs(datasetID).
+		WillReturnRows(db.NewRows([]string{"exists"}).AddRow(true))
+	db.ExpectQuery(regexp.QuoteMeta(createQuery)).
+		WithArgs(modelID, datasetID, metrics, json.RawMessage(nil), (*time.Time)(nil), "dev|1", "acme").
+		WillReturnRows(db.NewRows([]string{"id", "evaluator", "evaluated_at"}).
+			AddRow("e1", json.RawMessage(`{}`), evaluatedAt))
+
+	added, err := NewStore(db).Add(context.Background(), &Evaluation{
+		ModelID:   modelID,
+		DatasetID: datasetID,
+		Metrics:   metrics,
+		CreatedBy: "dev|1",
+		Tenant:    "acme",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := &Evaluation{
+		ID:          "e1",
+		ModelID:     modelID,
+		DatasetID:   datasetID,
+		Metrics:     metrics,
+		Evaluator:   json.RawMessage(`{}`),
+		EvaluatedAt: evaluatedAt,
+		CreatedBy:   "dev|1",
+		Tenant:      "acme",
+	}
+	if !reflect.DeepEqual(added, want) {
+		t.Errorf("got evaluation %+v, wanted %+v", added, want)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAddNotFound(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.ExpectQuery(regexp.QuoteMeta(modelExistsQuery)).
+		WithArgs(modelID).
+		WillReturnRows(db.NewRows([]string{"exists"}).AddRow(true))
+	db.ExpectQuery(regexp.QuoteMeta(datasetExistsQuery)).
+		WithArgs(datasetID).
+		WillReturnRows(db.NewRows([]string{"exists"}).AddRow(false))
+
+	store := NewStore(db)
+	ctx := context.Background()
+
+	if _, err := store.Add(ctx, &Evaluation{ModelID: modelID, DatasetID: datasetID}); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing dataset, got %v", err)
+	}
+	if _, err := store.Add(ctx, &Evaluation{ModelID: "not-a-uuid", DatasetID: datasetID}); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for malformed id, got %v", err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	evaluatedAt := time.Date(2025, 7, 12, 9, 0, 0, 0, time.UTC)
+
+	db.ExpectQuery(regexp.QuoteMeta(datasetExistsQuery)).
+		WithArgs(datasetID).
+		WillReturnRows(db.NewRows([]string{"exists"}).AddRow(true))
+	db.ExpectQuery(regexp.QuoteMeta(listQuery+datasetClause+listOrderBy)).
+		WithArgs("acme", datasetID).
+		WillReturnRows(db.NewRows([]string{"id", "model_id", "dataset_id", "metrics", "evaluator", "evaluated_at", "created_by", "tenant"}).
+			AddRow("e1", modelID, datasetID, json.RawMessage(`{"r2": 0.81}`), json.RawMessage(`{"name": "evaluate"}`), evaluatedAt, "dev|1", "acme"))
+
+	es, err := NewStore(db).List(context.Background(), "acme", KindDataset, datasetID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := []*Evaluation{{
+		ID:          "e1",
+		ModelID:     modelID,
+		DatasetID:   datasetID,
+		Metrics:     json.RawMessage(`{"r2": 0.81}`),
+		Evaluator:   json.RawMessage(`{"name": "evaluate"}`),
+		EvaluatedAt: evaluatedAt,
+		CreatedBy:   "dev|1",
+		Tenant:      "acme",
+	}}
+	if !reflect.DeepEqual(es, want) {
+		t.Errorf("got evaluations %+v, wanted %+v", es, want)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestMatrix(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	day := func(d int) time.Time { return time.Date(2025, 7, d, 9, 0, 0, 0, time.UTC) }
+	columns := []string{"model_id", "model_name", "model_version", "model_created_at", "dataset_id", "dataset_name", "dataset_version", "dataset_created_at", "value"}
+
+	// Rows come back ordered by id, not by when things were created.
+	db.ExpectQuery(regexp.QuoteMeta(matrixQuery)).
+		WithArgs("acme", []string{"test", "r2"}, []string{}, "regressor").
+		WillReturnRows(db.NewRows(columns).
+			AddRow("m1", "regressor", "1.1.0", day(5), "d1", "holdout", "1.0.0", day(3), 0.79).
+			AddRow("m2", "regressor", "1.0.0", day(4), "d1", "holdout", "1.0.0", day(3), 0.74).
+			AddRow("m2", "regressor", "1.0.0", day(4), "d2", "house_prices", "1.0.0", day(1), 0.81))
+
+	m, err := NewStore(db).Matrix(context.Background(), "acme", "test.r2", nil, "regressor")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	a, b, c := 0.81, 0.74, 0.79
+	want := &Matrix{
+		Metric: "test.r2",
+		Models: []Subject{
+			{ID: "m2", Name: "regressor", Version: "1.0.0"},
+			{ID: "m1", Name: "regressor", Version: "1.1.0"},
+		},
+		Datasets: []Subject{
+			{ID: "d2", Name: "house_prices", Version: "1.0.0"},
+			{ID: "d1", Name: "holdout", Version: "1.0.0"},
+		},
+		Values: [][]*float64{
+			{&a, &b},
+			{nil, &c},
+		},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Errorf("got matrix %+v, wanted %+v", m, want)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
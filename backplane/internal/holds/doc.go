@@ -0,0 +1,17 @@
+/*
+Package holds places legal holds on dataset and model versions.
+
+A version under an active hold is frozen: it can't be moved to the
+trash or purged, whether by a user, the retention job or an erasure
+request, until every hold on it has been released. The deleters and
+other stores check for holds themselves, and the database refuses the
+change as well, but artefacts are also guarded at the storage layer by
+wrapping the file store in a Guard:
+
+	fs = holds.NewGuard(holds.NewStore(conn), fs)
+
+Holds are placed and released through the Service, which records both in
+the audit log. Released holds are kept, as a record of what was held,
+by whom and why.
+*/
+package holds
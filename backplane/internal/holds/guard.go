@@ -0,0 +1,47 @@
+package holds
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/uploads"
+)
+
+type fileHeldChecker interface {
+	FileHeld(ctx context.Context, path string) (bool, error)
+}
+
+/*
+Guard is a file store which refuses to remove the artefacts of versions
+under an active legal hold. Everything else is passed straight through
+to the store it wraps.
+*/
+type Guard struct {
+	uploads.FileStore
+	holds fileHeldChecker
+}
+
+func NewGuard(s *Store, fs uploads.FileStore) *Guard {
+	return &Guard{
+		FileStore: fs,
+		holds:     s,
+	}
+}
+
+/*
+RemoveFile removes the file at path, unless it belongs to a held version,
+in which case an error wrapping internal.ErrHeld is returned. If the
+holds can't be checked, nothing is removed.
+*/
+func (g *Guard) RemoveFile(path string) error {
+	held, err := g.holds.FileHeld(context.Background(), filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("could not check legal holds on %s: %w", path, err)
+	}
+	if held {
+		return fmt.Errorf("%s is %w", path, internal.ErrHeld)
+	}
+	return g.FileStore.RemoveFile(path)
+}
@@ -0,0 +1,49 @@
+package holds
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/uploads"
+)
+
+type mockFileStore struct {
+	uploads.FileStore
+	removed []string
+}
+
+func (m *mockFileStore) RemoveFile(path string) error {
+	m.removed = append(m.removed, path)
+	return nil
+}
+
+type mockChecker map[string]bool
+
+func (m mockChecker) FileHeld(_ context.Context, path string) (bool, error) {
+	if path == "broken" {
+		return false, errors.New("boom")
+	}
+	return m[path], nil
+}
+
+func TestGuard(t *testing.T) {
+	fs := &mockFileStore{}
+	g := &Guard{FileStore: fs, holds: mockChecker{"models/1/model.pkl": true}}
+
+	if err := g.RemoveFile("models/1/../1/model.pkl"); !errors.Is(err, internal.ErrHeld) {
+		t.Errorf("expected ErrHeld, got %v", err)
+	}
+	if err := g.RemoveFile("broken"); err == nil {
+		t.Errorf("expected an error when holds can't be checked")
+	}
+	if err := g.RemoveFile("models/2/model.pkl"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if !reflect.DeepEqual(fs.removed, []string{"models/2/model.pkl"}) {
+		t.Errorf("removed %v, wanted only the file which isn't held", fs.removed)
+	}
+}
@@ -0,0 +1,237 @@
+package holds
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/auth"
+)
+
+/*
+Holder allows legal holds to be listed, placed and released.
+*/
+type Holder interface {
+	Holds(ctx context.Context, tenant string, all bool) ([]*Hold, error)
+	Hold(ctx context.Context, tenant string, id string) (*Hold, error)
+	Place(ctx context.Context, h *Hold) (*Hold, error)
+	Release(ctx context.Context, tenant string, id string) (*Hold, error)
+}
+
+type Handler struct {
+	h Holder
+}
+
+func NewHandler(h Holder) *Handler {
+	return &Handler{
+		h: h,
+	}
+}
+
+/*
+Holds routes and handles requests for the caller's tenant's legal holds.
+It should be registered on the router under something sensible, like
+/holds.
+*/
+func (h *Handler) Holds(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.List(w, r)
+	case http.MethodPost:
+		h.Place(w, r)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+/*
+Hold handles requests for a single hold, registered under /holds/{id}.
+Holds can only be released, never removed.
+*/
+func (h *Handler) Hold(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.Get(w, r)
+	case http.MethodDelete:
+		h.Release(w, r)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+/*
+List returns the caller's tenant's active holds, or every hold there has
+been with all=true.
+*/
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	hs, err := h.h.Holds(r.Context(), auth.TenantFromContext(r.Context()), r.URL.Query().Get("all") == "true")
+	if err != nil {
+		log.Printf("failed to list legal holds: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to list legal holds",
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(hs)
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	hold, err := h.h.Hold(r.Context(), auth.TenantFromContext(r.Context()), id)
+	if err != nil {
+		writeError(w, "Failed to fetch legal hold", id, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(hold)
+}
+
+/*
+Place puts one of the caller's tenant's versions under a legal hold. It
+requires the admin role.
+*/
+func (h *Handler) Place(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, "Failed to place legal hold") {
+		return
+	}
+
+	var hold Hold
+	if err := json.NewDecoder(r.Body).Decode(&hold); err != nil {
+		log.Printf("failed to decode body: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to place legal hold",
+			Reason:  fmt.Sprintf("could not parse body: %s", err),
+		})
+		return
+	}
+
+	details := map[string]string{}
+	if hold.ResourceType != KindDataset && hold.ResourceType != KindModel {
+		details["resource_type"] = "resource_type must be dataset or model"
+	}
+	if hold.ResourceID == "" {
+		details["resource_id"] = "resource_id is a required field"
+	}
+	if hold.Reason == "" {
+		details["reason"] = "reason is a required field"
+	}
+	if hold.Holder == "" {
+		details["holder"] = "holder is a required field"
+	}
+	if len(details) > 0 {
+		log.Printf("failed to validate input: %v", details)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to place legal hold",
+			Reason:  "bad input",
+			Details: details,
+		})
+		return
+	}
+
+	placed, err := h.h.Place(r.Context(), &hold)
+	if err != nil {
+		code := http.StatusInternalServerError
+		message := "Failed to place legal hold"
+		if errors.Is(err, internal.ErrNotFound) {
+			code = http.StatusNotFound
+			message = "Version not found"
+		}
+		log.Printf("failed to place legal hold on %s %s: %s", hold.ResourceType, hold.ResourceID, err)
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    code,
+			Message: message,
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(placed)
+}
+
+/*
+Release lifts one of the caller's tenant's holds. It requires the admin
+role.
+*/
+func (h *Handler) Release(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, "Failed to release legal hold") {
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	released, err := h.h.Release(r.Context(), auth.TenantFromContext(r.Context()), id)
+	if err != nil {
+		writeError(w, "Failed to release legal hold", id, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(released)
+}
+
+/*
+writeError logs err and answers with it, as a 404 if the hold id wasn't
+found or a 409 if it was already released.
+*/
+func writeError(w http.ResponseWriter, message string, id string, err error) {
+	log.Printf("%s %s: %s", strings.ToLower(message), id, err)
+
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		code = http.StatusNotFound
+		message = "Legal hold not found"
+	case errors.Is(err, ErrReleased):
+		code = http.StatusConflict
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    code,
+		Message: message,
+		Reason:  err.Error(),
+	})
+}
+
+/*
+requireAdmin reports whether the caller has the admin role, writing a 403
+with message if they don't.
+*/
+func requireAdmin(w http.ResponseWriter, r *http.Request, message string) bool {
+	if id, ok := auth.IdentityFromContext(r.Context()); ok && id.HasRole(auth.RoleAdmin) {
+		return true
+	}
+
+	log.Printf("refused to change legal holds: caller isn't an admin")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    http.StatusForbidden,
+		Message: message,
+		Reason:  "requires the admin role",
+	})
+	return false
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    http.StatusMethodNotAllowed,
+		Message: "Method not allowed",
+		Reason:  "",
+	})
+}
@@ -0,0 +1,240 @@
+package holds
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/auth"
+)
+
+type mockHolder struct {
+	HoldsFn   func(ctx context.Context, tenant string, all bool) ([]*Hold, error)
+	HoldFn    func(ctx context.Context, tenant string, id string) (*Hold, error)
+	PlaceFn   func(ctx context.Context, h *Hold) (*Hold, error)
+	ReleaseFn func(ctx context.Context, tenant string, id string) (*Hold, error)
+}
+
+func (m *mockHolder) Holds(ctx context.Context, tenant string, all bool) ([]*Hold, error) {
+	return m.HoldsFn(ctx, tenant, all)
+}
+
+func (m *mockHolder) Hold(ctx context.Context, tenant string, id string) (*Hold, error) {
+	return m.HoldFn(ctx, tenant, id)
+}
+
+func (m *mockHolder) Place(ctx context.Context, h *Hold) (*Hold, error) {
+	return m.PlaceFn(ctx, h)
+}
+
+func (m *mockHolder) Release(ctx context.Context, tenant string, id string) (*Hold, error) {
+	return m.ReleaseFn(ctx, tenant, id)
+}
+
+var handlerTime = time.Date(2025, 7, 8, 9, 0, 0, 0, time.UTC)
+
+var admin = &auth.Identity{Subject: "dev|1", Tenant: "acme", Roles: []string{auth.RoleAdmin}}
+
+func TestHoldsHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		body             string
+		identity         *auth.Identity
+		holder           *mockHolder
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "GET success",
+			method: http.MethodGet,
+			path:   "/holds?all=true",
+			holder: &mockHolder{HoldsFn: func(_ context.Context, tenant string, all bool) ([]*Hold, error) {
+				if !all {
+					return nil, fmt.Errorf("expected all holds")
+				}
+				return []*Hold{}, nil
+			}},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `[]`,
+		},
+		{
+			name:     "POST success",
+			method:   http.MethodPost,
+			path:     "/holds",
+			body:     `{"resource_type": "model", "resource_id": "m1", "reason": "matter 42", "holder": "legal"}`,
+			identity: admin,
+			holder: &mockHolder{PlaceFn: func(_ context.Context, h *Hold) (*Hold, error) {
+				placed := *h
+				placed.ID = "h1"
+				placed.PlacedAt = handlerTime
+				placed.PlacedBy = "dev|1"
+				return &placed, nil
+			}},
+			expectedStatus:   http.StatusCreated,
+			expectedContains: `{"id": "h1", "resource_type": "model", "resource_id": "m1", "reason": "matter 42", "holder": "legal", "placed_at": "2025-07-08T09:00:00Z", "placed_by": "dev|1"}`,
+		},
+		{
+			name:             "POST failure - failed validation",
+			method:           http.MethodPost,
+			path:             "/holds",
+			body:             `{"resource_type": "upload"}`,
+			identity:         admin,
+			holder:           &mockHolder{},
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to place legal hold", "reason": "bad input", "details": {"resource_type": "resource_type must be dataset or model", "resource_id": "resource_id is a required field", "reason": "reason is a required field", "holder": "holder is a required field"}}`,
+		},
+		{
+			name:     "POST failure - not found",
+			method:   http.MethodPost,
+			path:     "/holds",
+			body:     `{"resource_type": "dataset", "resource_id": "d1", "reason": "matter 42", "holder": "legal"}`,
+			identity: admin,
+			holder: &mockHolder{PlaceFn: func(_ context.Context, h *Hold) (*Hold, error) {
+				return nil, fmt.Errorf("dataset d1: %w", internal.ErrNotFound)
+			}},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Version not found", "reason": "dataset d1: not found"}`,
+		},
+		{
+			name:             "POST failure - not an admin",
+			method:           http.MethodPost,
+			path:             "/holds",
+			body:             `{"resource_type": "dataset", "resource_id": "d1", "reason": "matter 42", "holder": "legal"}`,
+			identity:         &auth.Identity{Subject: "dev|2", Tenant: "acme", Roles: []string{auth.RoleAuditor}},
+			holder:           &mockHolder{},
+			expectedStatus:   http.StatusForbidden,
+			expectedContains: `{"code": 403, "error": "Failed to place legal hold", "reason": "requires the admin role"}`,
+		},
+		{
+			name:             "METHOD failure",
+			method:           http.MethodPut,
+			path:             "/holds",
+			holder:           &mockHolder{},
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+			req := httptest.NewRequest(tc.method, tc.path, body)
+			if tc.identity != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), tc.identity))
+			}
+			rr := httptest.NewRecorder()
+
+			NewHandler(tc.holder).Holds(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func TestHoldHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		identity         *auth.Identity
+		holder           *mockHolder
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "GET not found",
+			method: http.MethodGet,
+			holder: &mockHolder{HoldFn: func(_ context.Context, tenant string, id string) (*Hold, error) {
+				return nil, fmt.Errorf("legal hold %s: %w", id, internal.ErrNotFound)
+			}},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Legal hold not found", "reason": "legal hold h1: not found"}`,
+		},
+		{
+			name:     "DELETE success",
+			method:   http.MethodDelete,
+			identity: admin,
+			holder: &mockHolder{ReleaseFn: func(_ context.Context, tenant string, id string) (*Hold, error) {
+				return &Hold{ID: id, ResourceType: KindDataset, ResourceID: "d1", Reason: "matter 42", Holder: "legal", PlacedAt: handlerTime, ReleasedAt: &handlerTime, ReleasedBy: "dev|1"}, nil
+			}},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"id": "h1", "resource_type": "dataset", "resource_id": "d1", "reason": "matter 42", "holder": "legal", "placed_at": "2025-07-08T09:00:00Z", "placed_by": "", "released_at": "2025-07-08T09:00:00Z", "released_by": "dev|1"}`,
+		},
+		{
+			name:     "DELETE failure - already released",
+			method:   http.MethodDelete,
+			identity: admin,
+			holder: &mockHolder{ReleaseFn: func(_ context.Context, tenant string, id string) (*Hold, error) {
+				return nil, fmt.Errorf("legal hold %s is %w", id, ErrReleased)
+			}},
+			expectedStatus:   http.StatusConflict,
+			expectedContains: `{"code": 409, "error": "Failed to release legal hold", "reason": "legal hold h1 is already released"}`,
+		},
+		{
+			name:             "DELETE failure - not an admin",
+			method:           http.MethodDelete,
+			holder:           &mockHolder{},
+			expectedStatus:   http.StatusForbidden,
+			expectedContains: `{"code": 403, "error": "Failed to release legal hold", "reason": "requires the admin role"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(tc.method, "/holds/h1", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "h1"})
+			if tc.identity != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), tc.identity))
+			}
+			rr := httptest.NewRecorder()
+
+			NewHandler(tc.holder).Hold(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func checkResponse(t *testing.T, got *http.Response, expectedStatus int, expected string) {
+	defer got.Body.Close()
+
+	body, err := io.ReadAll(got.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %s", err)
+	}
+
+	if expectedStatus != got.StatusCode {
+		t.Errorf("status mismatch - wanted %d, got %d", expectedStatus, got.StatusCode)
+	}
+
+	var gotData any
+	if err := json.Unmarshal(body, &gotData); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v\nbody: %s", err, string(body))
+	}
+
+	var expectedData any
+	if err := json.Unmarshal([]byte(expected), &expectedData); err != nil {
+		t.Fatalf("failed to unmarshal expected value: %v\njson: %s", err, expected)
+	}
+
+	if !reflect.DeepEqual(expectedData, gotData) {
+		t.Errorf("JSON mismatch:\nexpected: %+v\ngot: %+v", expectedData, gotData)
+	}
+}
@@ -0,0 +1,45 @@
+package holds
+
+import "time"
+
+type Kind string
+
+const (
+	KindDataset Kind = "dataset"
+	KindModel   Kind = "model"
+)
+
+/*
+Hold freezes the dataset or model version ResourceID until it is
+released.
+*/
+type Hold struct {
+	ID           string `json:"id"`
+	ResourceType Kind   `json:"resource_type"`
+	ResourceID   string `json:"resource_id"`
+
+	// Name and Version are those of the held version, for display.
+	Name    string `json:"name,omitempty"`
+	Version string `json:"version,omitempty"`
+
+	// Reason is why the version is held, such as the matter it relates
+	// to, and Holder who asked for it, such as the legal team.
+	Reason string `json:"reason"`
+	Holder string `json:"holder"`
+
+	// PlacedAt, PlacedBy, ReleasedAt, ReleasedBy and Tenant are set by
+	// the server from the verified token. Any values sent by the client
+	// are ignored.
+	PlacedAt   time.Time  `json:"placed_at"`
+	PlacedBy   string     `json:"placed_by"`
+	ReleasedAt *time.Time `json:"released_at,omitempty"`
+	ReleasedBy string     `json:"released_by,omitempty"`
+	Tenant     string     `json:"tenant,omitempty"`
+}
+
+/*
+Active reports whether the hold is still in force.
+*/
+func (h *Hold) Active() bool {
+	return h.ReleasedAt == nil
+}
@@ -0,0 +1,156 @@
+package holds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/jackc/pgx/v5"
+)
+
+/*
+ErrReleased is wrapped when releasing a hold which has already been
+released. Handlers answer it with a 409.
+*/
+var ErrReleased = errors.New("already released")
+
+type holdsStore interface {
+	Holds(ctx context.Context, tenant string, all bool) ([]*Hold, error)
+	Hold(ctx context.Context, tenant string, id string) (*Hold, error)
+	holdWithQuerier(ctx context.Context, q Querier, tenant string, id string, lock bool) (*Hold, error)
+	placeWithQuerier(ctx context.Context, q Querier, h *Hold) error
+	releaseWithQuerier(ctx context.Context, q Querier, id string, by string) error
+}
+
+/*
+AuditRecorder records an audit event using the provided Querier, which
+may be a transaction.
+*/
+type AuditRecorder interface {
+	RecordWithQuerier(ctx context.Context, q audit.Querier, e *audit.Event) error
+}
+
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type Service struct {
+	s     holdsStore
+	db    TxBeginner
+	audit AuditRecorder
+}
+
+func NewService(s *Store, db TxBeginner, a AuditRecorder) *Service {
+	return &Service{
+		s:     s,
+		db:    db,
+		audit: a,
+	}
+}
+
+/*
+Holds returns tenant's active holds, or all of them if all is set.
+*/
+func (s *Service) Holds(ctx context.Context, tenant string, all bool) ([]*Hold, error) {
+	return s.s.Holds(ctx, tenant, all)
+}
+
+/*
+Hold returns tenant's hold id.
+*/
+func (s *Service) Hold(ctx context.Context, tenant string, id string) (*Hold, error) {
+	return s.s.Hold(ctx, tenant, id)
+}
+
+/*
+Place puts the version named by h under a legal hold, recording it in the
+audit log against the version.
+*/
+func (s *Service) Place(ctx context.Context, h *Hold) (*Hold, error) {
+	placed := &Hold{
+		ResourceType: h.ResourceType,
+		ResourceID:   h.ResourceID,
+		Reason:       h.Reason,
+		Holder:       h.Holder,
+	}
+	if id, ok := auth.IdentityFromContext(ctx); ok {
+		placed.Tenant = id.Tenant
+		placed.PlacedBy = id.Subject
+	}
+
+	return s.change(ctx, audit.ActionHold, placed.Tenant, func(q Querier) (string, error) {
+		if err := s.s.placeWithQuerier(ctx, q, placed); err != nil {
+			return "", err
+		}
+		return placed.ID, nil
+	})
+}
+
+/*
+Release lifts tenant's hold id. The version stays frozen while it has
+other active holds.
+*/
+func (s *Service) Release(ctx context.Context, tenant string, id string) (*Hold, error) {
+	var by string
+	if identity, ok := auth.IdentityFromContext(ctx); ok {
+		by = identity.Subject
+	}
+
+	return s.change(ctx, audit.ActionRelease, tenant, func(q Querier) (string, error) {
+		h, err := s.s.holdWithQuerier(ctx, q, tenant, id, true)
+		if err != nil {
+			return "", err
+		}
+		if !h.Active() {
+			return "", fmt.Errorf("legal hold %s is %w", id, ErrReleased)
+		}
+		return id, s.s.releaseWithQuerier(ctx, q, id, by)
+	})
+}
+
+/*
+change makes a change to one of tenant's holds with fn, which returns the
+hold's id, and records that in the audit log against the held version,
+all in one transaction.
+*/
+func (s *Service) change(ctx context.Context, action audit.Action, tenant string, fn func(q Querier) (string, error)) (changed *Hold, err error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	id, err := fn(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	if changed, err = s.s.holdWithQuerier(ctx, tx, tenant, id, false); err != nil {
+		return nil, err
+	}
+
+	if s.audit != nil {
+		e := audit.NewEvent(ctx, action, audit.ResourceType(changed.ResourceType), changed.ResourceID).
+			WithDetails(map[string]any{
+				"hold":    changed.ID,
+				"reason":  changed.Reason,
+				"holder":  changed.Holder,
+				"name":    changed.Name,
+				"version": changed.Version,
+			})
+		if err = s.audit.RecordWithQuerier(ctx, tx, e); err != nil {
+			return nil, fmt.Errorf("record audit event: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
@@ -0,0 +1,158 @@
+package holds
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+type mockStore struct {
+	hold  *Hold
+	calls *[]string
+}
+
+func (m *mockStore) Holds(_ context.Context, tenant string, all bool) ([]*Hold, error) {
+	return nil, nil
+}
+
+func (m *mockStore) Hold(_ context.Context, tenant string, id string) (*Hold, error) {
+	return m.hold, nil
+}
+
+func (m *mockStore) holdWithQuerier(_ context.Context, _ Querier, tenant string, id string, lock bool) (*Hold, error) {
+	if lock {
+		*m.calls = append(*m.calls, "lock "+id)
+	}
+	return m.hold, nil
+}
+
+func (m *mockStore) placeWithQuerier(_ context.Context, _ Querier, h *Hold) error {
+	*m.calls = append(*m.calls, "place "+h.Tenant+" "+h.PlacedBy)
+	h.ID = "h1"
+	m.hold = h
+	return nil
+}
+
+func (m *mockStore) releaseWithQuerier(_ context.Context, _ Querier, id string, by string) error {
+	*m.calls = append(*m.calls, "release "+id+" "+by)
+	return nil
+}
+
+type mockAudit struct {
+	events []*audit.Event
+}
+
+func (m *mockAudit) RecordWithQuerier(_ context.Context, _ audit.Querier, e *audit.Event) error {
+	m.events = append(m.events, e)
+	return nil
+}
+
+type mockDB struct {
+	calls *[]string
+}
+
+func (m *mockDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	conn, _ := pgxmock.NewConn()
+	tx, _ := conn.Begin(ctx)
+	return &loggingTx{Tx: tx, log: m.calls}, nil
+}
+
+type loggingTx struct {
+	pgx.Tx
+	log *[]string
+}
+
+func (l *loggingTx) Commit(ctx context.Context) error {
+	*l.log = append(*l.log, "commit")
+	return nil
+}
+
+func (l *loggingTx) Rollback(ctx context.Context) error {
+	*l.log = append(*l.log, "rollback")
+	return nil
+}
+
+func TestService(t *testing.T) {
+	releasedAt := time.Date(2025, 7, 8, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		do            func(s *Service, ctx context.Context) (*Hold, error)
+		hold          *Hold
+		expectedCalls []string
+		wantErr       error
+		wantAction    audit.Action
+	}{
+		{
+			name: "place",
+			do: func(s *Service, ctx context.Context) (*Hold, error) {
+				return s.Place(ctx, &Hold{ResourceType: KindModel, ResourceID: "m1", Reason: "matter 42", Holder: "legal", PlacedBy: "spoofed"})
+			},
+			expectedCalls: []string{"place acme dev|1", "commit"},
+			wantAction:    audit.ActionHold,
+		},
+		{
+			name: "release",
+			do: func(s *Service, ctx context.Context) (*Hold, error) {
+				return s.Release(ctx, "acme", "h1")
+			},
+			hold:          &Hold{ID: "h1", ResourceType: KindModel, ResourceID: "m1", Reason: "matter 42", Holder: "legal"},
+			expectedCalls: []string{"lock h1", "release h1 dev|1", "commit"},
+			wantAction:    audit.ActionRelease,
+		},
+		{
+			name: "release twice",
+			do: func(s *Service, ctx context.Context) (*Hold, error) {
+				return s.Release(ctx, "acme", "h1")
+			},
+			hold:          &Hold{ID: "h1", ResourceType: KindModel, ResourceID: "m1", ReleasedAt: &releasedAt},
+			expectedCalls: []string{"lock h1", "rollback"},
+			wantErr:       ErrReleased,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			recorder := &mockAudit{}
+			s := &Service{
+				s:     &mockStore{hold: tc.hold, calls: &calls},
+				db:    &mockDB{calls: &calls},
+				audit: recorder,
+			}
+
+			ctx := auth.NewContext(context.Background(), &auth.Identity{Subject: "dev|1", Tenant: "acme"})
+			h, err := tc.do(s, ctx)
+			if !reflect.DeepEqual(calls, tc.expectedCalls) {
+				t.Errorf("calls %v, want %v", calls, tc.expectedCalls)
+			}
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got error %v, wanted %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if h.ID != "h1" {
+				t.Errorf("unexpected hold: %+v", h)
+			}
+			if len(recorder.events) != 1 {
+				t.Fatalf("expected one audit event, got %d", len(recorder.events))
+			}
+			e := recorder.events[0]
+			if e.Action != tc.wantAction || e.ResourceType != audit.ResourceModel || e.ResourceID != "m1" || e.Actor != "dev|1" {
+				t.Errorf("unexpected event: %+v", e)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package holds
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	holdsQuery = `SELECT
+  h.id::text,
+  h.resource_type,
+  h.resource_id::text,
+  COALESCE(d.name, m.name, ''),
+  COALESCE(d.version, m.version, ''),
+  h.reason,
+  h.holder,
+  h.placed_at,
+  COALESCE(h.placed_by, ''),
+  h.released_at,
+  COALESCE(h.released_by, ''),
+  h.tenant
+FROM legal_holds h
+LEFT JOIN datasets d ON h.resource_type = 'dataset' AND d.id = h.resource_id
+LEFT JOIN models m ON h.resource_type = 'model' AND m.id = h.resource_id
+WHERE h.tenant = $1`
+	activeClause = ` AND h.released_at IS NULL`
+	holdsOrder   = `
+ORDER BY h.placed_at, h.id`
+	holdClause = ` AND h.id = $2`
+	lockClause = `
+FOR UPDATE OF h`
+
+	// Placing a hold checks the version exists, and belongs to the
+	// caller's tenant, since resource_id can't reference both tables.
+	placeDatasetQuery = `INSERT INTO legal_holds
+(resource_type, resource_id, reason, holder, placed_by, tenant)
+SELECT 'dataset', id, $2, $3, NULLIF($4, ''), $5 FROM datasets WHERE id = $1 AND COALESCE(tenant, '') = $5
+RETURNING id::text`
+	placeModelQuery = `INSERT INTO legal_holds
+(resource_type, resource_id, reason, holder, placed_by, tenant)
+SELECT 'model', id, $2, $3, NULLIF($4, ''), $5 FROM models WHERE id = $1 AND COALESCE(tenant, '') = $5
+RETURNING id::text`
+	releaseQuery = `UPDATE legal_holds SET released_at = now(), released_by = NULLIF($2, '')
+WHERE id = $1 AND released_at IS NULL`
+
+	// Artefacts are stored at their path followed by their file name, and
+	// paths are written with a trailing slash.
+	fileHeldQuery = `SELECT EXISTS (
+  SELECT 1
+  FROM legal_holds h
+  JOIN uploads u ON (h.resource_type = 'dataset' AND u.dataset_id = h.resource_id)
+    OR (h.resource_type = 'model' AND u.model_id = h.resource_id::text)
+  CROSS JOIN LATERAL jsonb_each(u.files) AS f (name, ref)
+  WHERE h.released_at IS NULL
+    AND rtrim(f.ref->>'path', '/') || '/' || (f.ref->>'filename') = $1
+)`
+)
+
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+type Store struct {
+	q Querier
+}
+
+func NewStore(q Querier) *Store {
+	return &Store{
+		q: q,
+	}
+}
+
+/*
+Holds returns tenant's active holds, oldest first, or every hold there
+has been if all is set.
+*/
+func (s *Store) Holds(ctx context.Context, tenant string, all bool) ([]*Hold, error) {
+	query := holdsQuery + activeClause + holdsOrder
+	if all {
+		query = holdsQuery + holdsOrder
+	}
+
+	rows, err := s.q.Query(ctx, query, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("could not query legal holds: %w", err)
+	}
+	defer rows.Close()
+
+	hs := []*Hold{}
+	for rows.Next() {
+		h, err := scanHold(rows)
+		if err != nil {
+			return nil, err
+		}
+		hs = append(hs, h)
+	}
+	return hs, rows.Err()
+}
+
+/*
+Hold returns tenant's hold id, or an error wrapping internal.ErrNotFound
+if there is no such hold.
+*/
+func (s *Store) Hold(ctx context.Context, tenant string, id string) (*Hold, error) {
+	return s.holdWithQuerier(ctx, s.q, tenant, id, false)
+}
+
+/*
+holdWithQuerier returns tenant's hold id, locking it until the end of the
+transaction if lock is set.
+*/
+func (s *Store) holdWithQuerier(ctx context.Context, q Querier, tenant string, id string, lock bool) (*Hold, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("legal hold %s: %w", id, internal.ErrNotFound)
+	}
+
+	query := holdsQuery + holdClause
+	if lock {
+		query += lockClause
+	}
+
+	h, err := scanHold(q.QueryRow(ctx, query, tenant, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("legal hold %s: %w", id, internal.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not query legal hold: %w", err)
+	}
+	return h, nil
+}
+
+func scanHold(row pgx.Row) (*Hold, error) {
+	h := &Hold{}
+	if err := row.Scan(
+		&h.ID,
+		&h.ResourceType,
+		&h.ResourceID,
+		&h.Name,
+		&h.Version,
+		&h.Reason,
+		&h.Holder,
+		&h.PlacedAt,
+		&h.PlacedBy,
+		&h.ReleasedAt,
+		&h.ReleasedBy,
+		&h.Tenant,
+	); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+/*
+placeWithQuerier stores the hold h, setting its ID. It wraps
+internal.ErrNotFound if the version to hold doesn't exist.
+*/
+func (s *Store) placeWithQuerier(ctx context.Context, q Querier, h *Hold) error {
+	if _, err := uuid.Parse(h.ResourceID); err != nil {
+		return fmt.Errorf("%s %s: %w", h.ResourceType, h.ResourceID, internal.ErrNotFound)
+	}
+
+	query := placeDatasetQuery
+	if h.ResourceType == KindModel {
+		query = placeModelQuery
+	}
+
+	row := q.QueryRow(ctx, query, h.ResourceID, h.Reason, h.Holder, h.PlacedBy, h.Tenant)
+	if err := row.Scan(&h.ID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("%s %s: %w", h.ResourceType, h.ResourceID, internal.ErrNotFound)
+		}
+		return fmt.Errorf("could not place legal hold: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) releaseWithQuerier(ctx context.Context, q Querier, id string, by string) error {
+	if _, err := q.Exec(ctx, releaseQuery, id, by); err != nil {
+		return fmt.Errorf("could not release legal hold: %w", err)
+	}
+	return nil
+}
+
+/*
+FileHeld reports whether the artefact stored at path belongs to a version
+under an active hold.
+*/
+func (s *Store) FileHeld(ctx context.Context, path string) (bool, error) {
+	var held bool
+	if err := s.q.QueryRow(ctx, fileHeldQuery, path).Scan(&held); err != nil {
+		return false, fmt.Errorf("could not query legal holds: %w", err)
+	}
+	return held, nil
+}
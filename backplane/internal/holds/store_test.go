@@ -0,0 +1,150 @@
+package holds
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+const (
+	holdID    = "9f9b8055-0000-4000-8000-000000000001"
+	datasetID = "9f9b8055-0000-4000-8000-000000000002"
+)
+
+var holdColumns = []string{"id", "resource_type", "resource_id", "name", "version", "reason", "holder", "placed_at", "placed_by", "released_at", "released_by", "tenant"}
+
+func TestHolds(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	placedAt := time.Date(2025, 7, 8, 9, 0, 0, 0, time.UTC)
+	releasedAt := placedAt.Add(time.Hour)
+
+	db.ExpectQuery(regexp.QuoteMeta(holdsQuery + activeClause + holdsOrder)).
+		WithArgs("acme").
+		WillReturnRows(db.NewRows(holdColumns).
+			AddRow(holdID, "dataset", datasetID, "prices", "1.0.0", "matter 42", "legal", placedAt, "dev|1", nil, "", "acme"))
+	db.ExpectQuery(regexp.QuoteMeta(holdsQuery + holdsOrder)).
+		WithArgs("acme").
+		WillReturnRows(db.NewRows(holdColumns).
+			AddRow(holdID, "dataset", datasetID, "prices", "1.0.0", "matter 42", "legal", placedAt, "dev|1", &releasedAt, "dev|2", "acme"))
+
+	store := NewStore(db)
+
+	hs, err := store.Holds(context.Background(), "acme", false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []*Hold{{
+		ID:           holdID,
+		ResourceType: KindDataset,
+		ResourceID:   datasetID,
+		Name:         "prices",
+		Version:      "1.0.0",
+		Reason:       "matter 42",
+		Holder:       "legal",
+		PlacedAt:     placedAt,
+		PlacedBy:     "dev|1",
+		Tenant:       "acme",
+	}}
+	if !reflect.DeepEqual(hs, want) {
+		t.Errorf("got holds %+v, wanted %+v", hs, want)
+	}
+
+	hs, err = store.Holds(context.Background(), "acme", true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(hs) != 1 || hs[0].Active() || hs[0].ReleasedBy != "dev|2" {
+		t.Errorf("expected a released hold, got %+v", hs)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestHoldNotFound(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.ExpectQuery(regexp.QuoteMeta(holdsQuery+holdClause+lockClause)).
+		WithArgs("acme", holdID).
+		WillReturnError(pgx.ErrNoRows)
+
+	store := NewStore(db)
+
+	if _, err := store.holdWithQuerier(context.Background(), db, "acme", holdID, true); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := store.Hold(context.Background(), "acme", "not-a-uuid"); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for malformed id, got %v", err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestHoldWrites(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.ExpectQuery(regexp.QuoteMeta(placeModelQuery)).
+		WithArgs(datasetID, "matter 42", "legal", "dev|1", "acme").
+		WillReturnRows(db.NewRows([]string{"id"}).AddRow(holdID))
+	db.ExpectQuery(regexp.QuoteMeta(placeDatasetQuery)).
+		WithArgs(datasetID, "matter 42", "legal", "dev|1", "acme").
+		WillReturnError(pgx.ErrNoRows)
+	db.ExpectExec(regexp.QuoteMeta(releaseQuery)).
+		WithArgs(holdID, "dev|2").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	db.ExpectQuery(regexp.QuoteMeta(fileHeldQuery)).
+		WithArgs("models/1/model.pkl").
+		WillReturnRows(db.NewRows([]string{"held"}).AddRow(true))
+
+	store := NewStore(db)
+	ctx := context.Background()
+
+	h := &Hold{ResourceType: KindModel, ResourceID: datasetID, Reason: "matter 42", Holder: "legal", PlacedBy: "dev|1", Tenant: "acme"}
+	if err := store.placeWithQuerier(ctx, db, h); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if h.ID != holdID {
+		t.Errorf("expected the hold's id to be set, got %+v", h)
+	}
+
+	// The version doesn't exist, so nothing is inserted.
+	h = &Hold{ResourceType: KindDataset, ResourceID: datasetID, Reason: "matter 42", Holder: "legal", PlacedBy: "dev|1", Tenant: "acme"}
+	if err := store.placeWithQuerier(ctx, db, h); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := store.releaseWithQuerier(ctx, db, holdID, "dev|2"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if held, err := store.FileHeld(ctx, "models/1/model.pkl"); err != nil || !held {
+		t.Errorf("got %t, %v, wanted the file to be held", held, err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,122 @@
+package kms
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+/*
+Keyring is a KeyProvider backed by local key files. Each file holds a
+base64 encoded 256 bit key. Data keys are wrapped with AES-GCM.
+*/
+type Keyring struct {
+	current string
+	keys    map[string][]byte
+}
+
+/*
+LoadKeyring reads the key files given. The first is the current key; the
+rest are only used to unwrap data keys they wrapped in the past.
+*/
+func LoadKeyring(files ...string) (*Keyring, error) {
+	if len(files) == 0 {
+		return nil, errors.New("no master key files given")
+	}
+
+	k := &Keyring{keys: map[string][]byte{}}
+	for i, file := range files {
+		data, err := os.ReadFile(strings.TrimSpace(file))
+		if err != nil {
+			return nil, fmt.Errorf("could not read master key: %w", err)
+		}
+		key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(data)))
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("master key %s must be 32 base64 encoded bytes", file)
+		}
+
+		id := KeyID(key)
+		k.keys[id] = key
+		if i == 0 {
+			k.current = id
+		}
+	}
+
+	return k, nil
+}
+
+/*
+GenerateKey returns a new master key, base64 encoded as it should be
+written to a key file.
+*/
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return []byte(base64.StdEncoding.EncodeToString(key) + "\n"), nil
+}
+
+/*
+KeyID returns the fingerprint used to identify a master key.
+*/
+func KeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return "local:" + hex.EncodeToString(sum[:8])
+}
+
+func (k *Keyring) CurrentKeyID() string {
+	return k.current
+}
+
+func (k *Keyring) Wrap(dataKey []byte) (string, []byte, error) {
+	aead, err := newGCM(k.keys[k.current])
+	if err != nil {
+		return "", nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+
+	return k.current, aead.Seal(nonce, nonce, dataKey, []byte(k.current)), nil
+}
+
+func (k *Keyring) Unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	key, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, errors.New("wrapped key is too short")
+	}
+
+	nonce, ciphertext := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, ciphertext, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("could not unwrap data key with %s: %w", keyID, err)
+	}
+	return dataKey, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,114 @@
+package kms
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeKey(t *testing.T, dir, name string) string {
+	t.Helper()
+
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+	file := filepath.Join(dir, name)
+	if err := os.WriteFile(file, key, 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return file
+}
+
+func TestKeyring_WrapUnwrap(t *testing.T) {
+	dir := t.TempDir()
+	keys, err := LoadKeyring(writeKey(t, dir, "new.key"), writeKey(t, dir, "old.key"))
+	if err != nil {
+		t.Fatalf("LoadKeyring failed: %v", err)
+	}
+
+	dataKey := bytes.Repeat([]byte{7}, DataKeySize)
+	keyID, wrapped, err := keys.Wrap(dataKey)
+	if err != nil {
+		t.Fatalf("Wrap failed: %v", err)
+	}
+	if keyID != keys.CurrentKeyID() {
+		t.Errorf("wrapped with %s, want the current key %s", keyID, keys.CurrentKeyID())
+	}
+	if bytes.Contains(wrapped, dataKey) {
+		t.Error("wrapped key contains the data key")
+	}
+
+	got, err := keys.Unwrap(keyID, wrapped)
+	if err != nil {
+		t.Fatalf("Unwrap failed: %v", err)
+	}
+	if !bytes.Equal(got, dataKey) {
+		t.Errorf("unwrapped %x, want %x", got, dataKey)
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	dir := t.TempDir()
+	oldFile := writeKey(t, dir, "old.key")
+	newFile := writeKey(t, dir, "new.key")
+
+	old, err := LoadKeyring(oldFile)
+	if err != nil {
+		t.Fatalf("LoadKeyring failed: %v", err)
+	}
+	dataKey := bytes.Repeat([]byte{1}, DataKeySize)
+	keyID, wrapped, _ := old.Wrap(dataKey)
+
+	rotated, err := LoadKeyring(newFile, oldFile)
+	if err != nil {
+		t.Fatalf("LoadKeyring failed: %v", err)
+	}
+	if rotated.CurrentKeyID() == keyID {
+		t.Fatal("current key did not change after rotation")
+	}
+	if got, err := rotated.Unwrap(keyID, wrapped); err != nil || !bytes.Equal(got, dataKey) {
+		t.Errorf("could not unwrap with a retired key: %v", err)
+	}
+
+	retired, _ := LoadKeyring(newFile)
+	if _, err := retired.Unwrap(keyID, wrapped); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey once the old key is dropped, got %v", err)
+	}
+}
+
+func TestKeyring_UnwrapTampered(t *testing.T) {
+	keys, _ := LoadKeyring(writeKey(t, t.TempDir(), "k.key"))
+
+	keyID, wrapped, _ := keys.Wrap(bytes.Repeat([]byte{2}, DataKeySize))
+	wrapped[len(wrapped)-1] ^= 1
+
+	if _, err := keys.Unwrap(keyID, wrapped); err == nil {
+		t.Error("expected an error unwrapping a tampered key")
+	}
+}
+
+func TestLoadKeyring_Invalid(t *testing.T) {
+	dir := t.TempDir()
+	short := filepath.Join(dir, "short.key")
+	os.WriteFile(short, []byte("c2hvcnQ=\n"), 0600)
+
+	tests := []struct {
+		name  string
+		files []string
+	}{
+		{name: "no files"},
+		{name: "missing file", files: []string{filepath.Join(dir, "missing.key")}},
+		{name: "wrong size", files: []string{short}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := LoadKeyring(tc.files...); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Package kms provides the master keys used to wrap the data keys that
+artefacts are encrypted with at rest.
+
+A KeyProvider never hands out its master keys; it only wraps and unwraps
+data keys. Keys can come from local key files (see Keyring) or from an
+external key management service through a Plugin. Either is configured
+from the environment with FromEnv.
+*/
+package kms
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+/*
+DataKeySize is the size, in bytes, of the data keys which are wrapped.
+*/
+const DataKeySize = 32
+
+var ErrUnknownKey = errors.New("unknown master key")
+
+/*
+KeyProvider wraps data keys with a master key. New data keys are always
+wrapped with the current master key; older master keys are kept so that
+what they wrapped can still be unwrapped until it has been re-wrapped.
+*/
+type KeyProvider interface {
+	CurrentKeyID() string
+	Wrap(dataKey []byte) (keyID string, wrapped []byte, err error)
+	Unwrap(keyID string, wrapped []byte) ([]byte, error)
+}
+
+/*
+FromEnv returns the KeyProvider configured by TRAINTRACK_MASTER_KEYS, a
+comma separated list of key files with the current key first, or
+TRAINTRACK_KMS_PLUGIN, the path to a plugin. It returns nil if neither is
+set.
+*/
+func FromEnv() (KeyProvider, error) {
+	files := os.Getenv("TRAINTRACK_MASTER_KEYS")
+	plugin := os.Getenv("TRAINTRACK_KMS_PLUGIN")
+
+	switch {
+	case files != "" && plugin != "":
+		return nil, fmt.Errorf("set only one of TRAINTRACK_MASTER_KEYS and TRAINTRACK_KMS_PLUGIN")
+	case files != "":
+		return LoadKeyring(strings.Split(files, ",")...)
+	case plugin != "":
+		return NewPlugin(plugin)
+	default:
+		return nil, nil
+	}
+}
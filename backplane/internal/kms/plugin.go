@@ -0,0 +1,106 @@
+package kms
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+/*
+Plugin is a KeyProvider which hands wrapping to an external program, so
+master keys can stay in a key management service. The program is run
+with a single argument, the operation, and exchanges JSON on stdin and
+stdout:
+
+	key-id                     -> {"key_id": "..."}
+	wrap   {"plaintext": "..."} -> {"key_id": "...", "ciphertext": "..."}
+	unwrap {"key_id": "...", "ciphertext": "..."} -> {"plaintext": "..."}
+
+Keys are base64 encoded. A non-zero exit fails the operation, with the
+program's stderr as the reason.
+*/
+type Plugin struct {
+	Path    string
+	current string
+}
+
+type pluginMessage struct {
+	KeyID      string `json:"key_id,omitempty"`
+	Plaintext  []byte `json:"plaintext,omitempty"`
+	Ciphertext []byte `json:"ciphertext,omitempty"`
+}
+
+/*
+NewPlugin asks the program at path which master key is current.
+*/
+func NewPlugin(path string) (*Plugin, error) {
+	p := &Plugin{Path: path}
+
+	var res pluginMessage
+	if err := p.run("key-id", nil, &res); err != nil {
+		return nil, err
+	}
+	if res.KeyID == "" {
+		return nil, fmt.Errorf("kms plugin %s did not name its current key", path)
+	}
+	p.current = res.KeyID
+
+	return p, nil
+}
+
+func (p *Plugin) CurrentKeyID() string {
+	return p.current
+}
+
+func (p *Plugin) Wrap(dataKey []byte) (string, []byte, error) {
+	var res pluginMessage
+	if err := p.run("wrap", &pluginMessage{Plaintext: dataKey}, &res); err != nil {
+		return "", nil, err
+	}
+	if res.KeyID == "" || len(res.Ciphertext) == 0 {
+		return "", nil, errors.New("kms plugin returned an incomplete wrapped key")
+	}
+	return res.KeyID, res.Ciphertext, nil
+}
+
+func (p *Plugin) Unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	var res pluginMessage
+	if err := p.run("unwrap", &pluginMessage{KeyID: keyID, Ciphertext: wrapped}, &res); err != nil {
+		return nil, err
+	}
+	if len(res.Plaintext) != DataKeySize {
+		return nil, fmt.Errorf("kms plugin returned a %d byte data key", len(res.Plaintext))
+	}
+	return res.Plaintext, nil
+}
+
+func (p *Plugin) run(op string, req *pluginMessage, res *pluginMessage) error {
+	cmd := exec.Command(p.Path, op)
+
+	if req != nil {
+		body, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		cmd.Stdin = bytes.NewReader(body)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if reason := strings.TrimSpace(stderr.String()); reason != "" {
+			return fmt.Errorf("kms plugin %s failed: %s", op, reason)
+		}
+		return fmt.Errorf("kms plugin %s failed: %w", op, err)
+	}
+
+	if err := json.Unmarshal(stdout.Bytes(), res); err != nil {
+		return fmt.Errorf("kms plugin %s returned invalid JSON: %w", op, err)
+	}
+	return nil
+}
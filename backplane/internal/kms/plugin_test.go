@@ -0,0 +1,81 @@
+package kms
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+/*
+writePlugin writes script out as an executable shell plugin.
+*/
+func writePlugin(t *testing.T, script string) string {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), "kms-plugin")
+	if err := os.WriteFile(file, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatalf("failed to write plugin: %v", err)
+	}
+	return file
+}
+
+// testPlugin always wraps to "wrapped" and unwraps to a key of sevens.
+const testPlugin = `
+case "$1" in
+key-id) echo '{"key_id": "test:1"}' ;;
+wrap) echo '{"key_id": "test:1", "ciphertext": "d3JhcHBlZA=="}' ;;
+unwrap) echo '{"plaintext": "BwcHBwcHBwcHBwcHBwcHBwcHBwcHBwcHBwcHBwcHBwc="}' ;;
+*) echo "unknown operation $1" >&2; exit 1 ;;
+esac
+`
+
+func TestPlugin(t *testing.T) {
+	p, err := NewPlugin(writePlugin(t, testPlugin))
+	if err != nil {
+		t.Fatalf("NewPlugin failed: %v", err)
+	}
+	if p.CurrentKeyID() != "test:1" {
+		t.Errorf("current key %q, want test:1", p.CurrentKeyID())
+	}
+
+	keyID, wrapped, err := p.Wrap(bytes.Repeat([]byte{7}, DataKeySize))
+	if err != nil {
+		t.Fatalf("Wrap failed: %v", err)
+	}
+	if keyID != "test:1" || string(wrapped) != "wrapped" {
+		t.Errorf("got %s %q", keyID, wrapped)
+	}
+
+	dataKey, err := p.Unwrap(keyID, wrapped)
+	if err != nil {
+		t.Fatalf("Unwrap failed: %v", err)
+	}
+	if !bytes.Equal(dataKey, bytes.Repeat([]byte{7}, DataKeySize)) {
+		t.Errorf("unwrapped %x", dataKey)
+	}
+}
+
+func TestPlugin_Failure(t *testing.T) {
+	p, err := NewPlugin(writePlugin(t, `
+case "$1" in
+key-id) echo '{"key_id": "test:1"}' ;;
+*) echo "access denied" >&2; exit 1 ;;
+esac
+`))
+	if err != nil {
+		t.Fatalf("NewPlugin failed: %v", err)
+	}
+
+	_, err = p.Unwrap("test:1", []byte("wrapped"))
+	if err == nil || !strings.Contains(err.Error(), "access denied") {
+		t.Errorf("expected the plugin's stderr as the error, got %v", err)
+	}
+}
+
+func TestNewPlugin_NoKeyID(t *testing.T) {
+	if _, err := NewPlugin(writePlugin(t, "echo '{}'\n")); err == nil {
+		t.Error("expected an error when the plugin names no key")
+	}
+}
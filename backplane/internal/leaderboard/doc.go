@@ -0,0 +1,18 @@
+/*
+Package leaderboard ranks the models trained on a dataset version by a
+number in their evaluation, such as "r2" or, for nested evaluations,
+"test.r2".
+
+Only models which recorded the number are ranked, and deleted models are
+left out. A leaderboard can be narrowed to models in some stages, or to
+models finalized from a run with some tags:
+
+	entries, err := store.Leaderboard(ctx, datasetID, leaderboard.Query{
+		Metric:     "r2",
+		Descending: true,
+		Stages:     []string{"staging", "production"},
+		Tags:       map[string]string{"team": "pricing"},
+		Limit:      10,
+	})
+*/
+package leaderboard
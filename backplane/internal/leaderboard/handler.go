@@ -0,0 +1,172 @@
+package leaderboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/models"
+)
+
+const (
+	// DefaultLimit is how many models are ranked unless the limit query
+	// parameter says otherwise, and MaxLimit the most that can be asked
+	// for.
+	DefaultLimit = 50
+	MaxLimit     = 500
+)
+
+/*
+Ranker ranks the models trained on a dataset.
+*/
+type Ranker interface {
+	Leaderboard(ctx context.Context, id string, q Query) ([]Entry, error)
+}
+
+/*
+Leaderboard is the response to a leaderboard request.
+*/
+type Leaderboard struct {
+	Dataset string  `json:"dataset"`
+	Metric  string  `json:"metric"`
+	Order   string  `json:"order"`
+	Entries []Entry `json:"entries"`
+}
+
+type Handler struct {
+	r Ranker
+}
+
+func NewHandler(r Ranker) *Handler {
+	return &Handler{
+		r: r,
+	}
+}
+
+/*
+Leaderboard ranks the models trained on a dataset by the number in their
+evaluation named by the metric query parameter, in the order given by
+order, asc or desc (the default). stage, which may be repeated or comma
+separated, and tag, which is key:value and may be repeated, narrow down
+the models ranked, and limit caps how many are. It should be registered
+under /datasets/{id}/leaderboard.
+*/
+func (h *Handler) Leaderboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	q, order, err := parseQuery(r)
+	if err != nil {
+		log.Printf("failed to rank models for dataset %s: %s", id, err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to rank models",
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	entries, err := h.r.Leaderboard(r.Context(), id, q)
+	if err != nil {
+		code := http.StatusInternalServerError
+		message := "Failed to rank models"
+		if errors.Is(err, internal.ErrNotFound) {
+			code = http.StatusNotFound
+			message = "Dataset not found"
+		}
+		log.Printf("failed to rank models for dataset %s: %s", id, err)
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    code,
+			Message: message,
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(&Leaderboard{
+		Dataset: id,
+		Metric:  q.Metric,
+		Order:   order,
+		Entries: entries,
+	})
+}
+
+func parseQuery(r *http.Request) (Query, string, error) {
+	params := r.URL.Query()
+	q := Query{Limit: DefaultLimit, Descending: true}
+
+	q.Metric = strings.TrimSpace(params.Get("metric"))
+	if q.Metric == "" {
+		return q, "", fmt.Errorf("metric is required")
+	}
+	for _, key := range strings.Split(q.Metric, ".") {
+		if key == "" {
+			return q, "", fmt.Errorf("invalid metric %q", q.Metric)
+		}
+	}
+
+	order := params.Get("order")
+	switch order {
+	case "", "desc":
+		order = "desc"
+	case "asc":
+		q.Descending = false
+	default:
+		return q, "", fmt.Errorf("order must be asc or desc, got %q", order)
+	}
+
+	for _, param := range params["stage"] {
+		for _, stage := range strings.Split(param, ",") {
+			if stage = strings.TrimSpace(stage); stage == "" {
+				continue
+			}
+			if !models.Stage(stage).Valid() {
+				return q, "", fmt.Errorf("invalid stage %q", stage)
+			}
+			q.Stages = append(q.Stages, stage)
+		}
+	}
+
+	for _, tag := range params["tag"] {
+		key, value, ok := strings.Cut(tag, ":")
+		if !ok || key == "" {
+			return q, "", fmt.Errorf("tag must be key:value, got %q", tag)
+		}
+		if q.Tags == nil {
+			q.Tags = map[string]string{}
+		}
+		q.Tags[key] = value
+	}
+
+	if s := params.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > MaxLimit {
+			return q, "", fmt.Errorf("limit must be a number from 1 to %d", MaxLimit)
+		}
+		q.Limit = n
+	}
+
+	return q, order, nil
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    http.StatusMethodNotAllowed,
+		Message: "Method not allowed",
+		Reason:  "",
+	})
+}
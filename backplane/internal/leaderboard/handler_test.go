@@ -0,0 +1,158 @@
+package leaderboard
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+)
+
+type mockRanker struct {
+	LeaderboardFn func(ctx context.Context, id string, q Query) ([]Entry, error)
+}
+
+func (m *mockRanker) Leaderboard(ctx context.Context, id string, q Query) ([]Entry, error) {
+	return m.LeaderboardFn(ctx, id, q)
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		leaderboardFn    func(ctx context.Context, id string, q Query) ([]Entry, error)
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "success",
+			method: http.MethodGet,
+			path:   "/datasets/d1/leaderboard?metric=r2&stage=staging,production&tag=team:pricing&limit=5",
+			leaderboardFn: func(_ context.Context, id string, q Query) ([]Entry, error) {
+				want := Query{
+					Metric:     "r2",
+					Descending: true,
+					Stages:     []string{"staging", "production"},
+					Tags:       map[string]string{"team": "pricing"},
+					Limit:      5,
+				}
+				if id != "d1" || !reflect.DeepEqual(q, want) {
+					return nil, fmt.Errorf("unexpected query %+v for %s", q, id)
+				}
+				return []Entry{{Rank: 1, ModelID: "m1", Name: "regressor", Version: "1.0.0", Stage: "production", Score: 0.84}}, nil
+			},
+			expectedStatus: http.StatusOK,
+			expectedContains: `{"dataset": "d1", "metric": "r2", "order": "desc", "entries": [
+				{"rank": 1, "model": "m1", "name": "regressor", "version": "1.0.0", "stage": "production", "score": 0.84, "created_at": "0001-01-01T00:00:00Z", "created_by": ""}
+			]}`,
+		},
+		{
+			name:   "success - ascending",
+			method: http.MethodGet,
+			path:   "/datasets/d1/leaderboard?metric=mae&order=asc",
+			leaderboardFn: func(_ context.Context, id string, q Query) ([]Entry, error) {
+				if q.Descending || q.Limit != DefaultLimit {
+					return nil, fmt.Errorf("unexpected query %+v", q)
+				}
+				return []Entry{}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"dataset": "d1", "metric": "mae", "order": "asc", "entries": []}`,
+		},
+		{
+			name:             "failure - no metric",
+			method:           http.MethodGet,
+			path:             "/datasets/d1/leaderboard",
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to rank models", "reason": "metric is required"}`,
+		},
+		{
+			name:             "failure - bad order",
+			method:           http.MethodGet,
+			path:             "/datasets/d1/leaderboard?metric=r2&order=up",
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to rank models", "reason": "order must be asc or desc, got \"up\""}`,
+		},
+		{
+			name:             "failure - bad stage",
+			method:           http.MethodGet,
+			path:             "/datasets/d1/leaderboard?metric=r2&stage=live",
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to rank models", "reason": "invalid stage \"live\""}`,
+		},
+		{
+			name:             "failure - bad tag",
+			method:           http.MethodGet,
+			path:             "/datasets/d1/leaderboard?metric=r2&tag=pricing",
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to rank models", "reason": "tag must be key:value, got \"pricing\""}`,
+		},
+		{
+			name:   "failure - not found",
+			method: http.MethodGet,
+			path:   "/datasets/d1/leaderboard?metric=r2",
+			leaderboardFn: func(_ context.Context, id string, q Query) ([]Entry, error) {
+				return nil, fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+			},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Dataset not found", "reason": "dataset d1: not found"}`,
+		},
+		{
+			name:             "METHOD failure",
+			method:           http.MethodPost,
+			path:             "/datasets/d1/leaderboard",
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewHandler(&mockRanker{LeaderboardFn: tc.leaderboardFn})
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "d1"})
+			rr := httptest.NewRecorder()
+
+			handler.Leaderboard(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func checkResponse(t *testing.T, got *http.Response, expectedStatus int, expected string) {
+	defer got.Body.Close()
+
+	body, err := io.ReadAll(got.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %s", err)
+	}
+
+	if expectedStatus != got.StatusCode {
+		t.Errorf("status mismatch - wanted %d, got %d", expectedStatus, got.StatusCode)
+	}
+
+	var gotData any
+	if err := json.Unmarshal(body, &gotData); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v\nbody: %s", err, string(body))
+	}
+
+	var expectedData any
+	if err := json.Unmarshal([]byte(expected), &expectedData); err != nil {
+		t.Fatalf("failed to unmarshal expected value: %v\njson: %s", err, expected)
+	}
+
+	if !reflect.DeepEqual(expectedData, gotData) {
+		t.Errorf("JSON mismatch:\nexpected: %+v\ngot: %+v", expectedData, gotData)
+	}
+}
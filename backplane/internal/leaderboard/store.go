@@ -0,0 +1,147 @@
+package leaderboard
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	datasetExistsQuery = `SELECT EXISTS (SELECT 1 FROM datasets WHERE id = $1)`
+
+	// The ? on the metric's top level key lets the GIN index on
+	// evaluation skip models which never recorded it.
+	leaderboardQuery = `SELECT
+  m.id::text,
+  m.name,
+  m.version,
+  m.stage,
+  (m.evaluation #>> $2)::float8 AS score,
+  m.created_at,
+  COALESCE(m.created_by, '')
+FROM models m
+WHERE m.dataset = $1
+  AND m.deleted_at IS NULL
+  AND m.evaluation ? $3
+  AND jsonb_typeof(m.evaluation #> $2) = 'number'`
+	stageClause = `
+  AND m.stage = ANY($%d)`
+	tagsClause = `
+  AND EXISTS (SELECT 1 FROM runs r WHERE r.model_id = m.id AND r.tags @> $%d)`
+	orderClause = `
+ORDER BY score %s, m.created_at, m.id
+LIMIT $%d`
+)
+
+/*
+Query picks the number models are ranked by and which models are ranked.
+Zero values for Stages and Tags match every model.
+*/
+type Query struct {
+	// Metric is the key of the number in each model's evaluation, with
+	// dots separating the keys of nested objects.
+	Metric     string
+	Descending bool
+	Stages     []string
+	Tags       map[string]string
+	Limit      int
+}
+
+/*
+sql builds the query for q on the dataset id, returning it with its
+positional arguments.
+*/
+func (q Query) sql(id string) (string, []any) {
+	path := strings.Split(q.Metric, ".")
+	query := leaderboardQuery
+	args := []any{id, path, path[0]}
+
+	if len(q.Stages) > 0 {
+		args = append(args, q.Stages)
+		query += fmt.Sprintf(stageClause, len(args))
+	}
+	if len(q.Tags) > 0 {
+		args = append(args, q.Tags)
+		query += fmt.Sprintf(tagsClause, len(args))
+	}
+
+	direction := "ASC"
+	if q.Descending {
+		direction = "DESC"
+	}
+	args = append(args, q.Limit)
+	query += fmt.Sprintf(orderClause, direction, len(args))
+
+	return query, args
+}
+
+/*
+Entry is a model's place on a leaderboard.
+*/
+type Entry struct {
+	Rank      int       `json:"rank"`
+	ModelID   string    `json:"model"`
+	Name      string    `json:"name"`
+	Version   string    `json:"version"`
+	Stage     string    `json:"stage"`
+	Score     float64   `json:"score"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+}
+
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+type Store struct {
+	q Querier
+}
+
+func NewStore(q Querier) *Store {
+	return &Store{
+		q: q,
+	}
+}
+
+/*
+Leaderboard ranks the models trained on the dataset id as q asks. It
+wraps internal.ErrNotFound if there is no such dataset.
+*/
+func (s *Store) Leaderboard(ctx context.Context, id string, q Query) ([]Entry, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+	}
+
+	var exists bool
+	if err := s.q.QueryRow(ctx, datasetExistsQuery, id).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("could not query dataset: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("dataset %s: %w", id, internal.ErrNotFound)
+	}
+
+	query, args := q.sql(id)
+	rows, err := s.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		e := Entry{Rank: len(entries) + 1}
+		if err := rows.Scan(&e.ModelID, &e.Name, &e.Version, &e.Stage, &e.Score, &e.CreatedAt, &e.CreatedBy); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
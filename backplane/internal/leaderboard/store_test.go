@@ -0,0 +1,113 @@
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+const datasetID = "7a1e2f30-0000-4000-8000-000000000001"
+
+func TestLeaderboard(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2025, 7, 11, 9, 0, 0, 0, time.UTC)
+	tags := map[string]string{"team": "pricing"}
+	columns := []string{"id", "name", "version", "stage", "score", "created_at", "created_by"}
+
+	db.ExpectQuery(regexp.QuoteMeta(datasetExistsQuery)).
+		WithArgs(datasetID).
+		WillReturnRows(db.NewRows([]string{"exists"}).AddRow(true))
+	db.ExpectQuery(regexp.QuoteMeta(leaderboardQuery+fmt.Sprintf(stageClause, 4)+fmt.Sprintf(tagsClause, 5)+fmt.Sprintf(orderClause, "DESC", 6))).
+		WithArgs(datasetID, []string{"test", "r2"}, "test", []string{"production"}, tags, 10).
+		WillReturnRows(db.NewRows(columns).
+			AddRow("m2", "regressor", "1.1.0", "production", 0.91, createdAt, "dev|1").
+			AddRow("m1", "regressor", "1.0.0", "production", 0.84, createdAt, ""))
+
+	entries, err := NewStore(db).Leaderboard(context.Background(), datasetID, Query{
+		Metric:     "test.r2",
+		Descending: true,
+		Stages:     []string{"production"},
+		Tags:       tags,
+		Limit:      10,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := []Entry{
+		{Rank: 1, ModelID: "m2", Name: "regressor", Version: "1.1.0", Stage: "production", Score: 0.91, CreatedAt: createdAt, CreatedBy: "dev|1"},
+		{Rank: 2, ModelID: "m1", Name: "regressor", Version: "1.0.0", Stage: "production", Score: 0.84, CreatedAt: createdAt},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("got entries %+v, wanted %+v", entries, want)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestLeaderboardAscending(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.ExpectQuery(regexp.QuoteMeta(datasetExistsQuery)).
+		WithArgs(datasetID).
+		WillReturnRows(db.NewRows([]string{"exists"}).AddRow(true))
+	db.ExpectQuery(regexp.QuoteMeta(leaderboardQuery+fmt.Sprintf(orderClause, "ASC", 4))).
+		WithArgs(datasetID, []string{"mae"}, "mae", 50).
+		WillReturnRows(db.NewRows([]string{"id", "name", "version", "stage", "score", "created_at", "created_by"}))
+
+	entries, err := NewStore(db).Leaderboard(context.Background(), datasetID, Query{Metric: "mae", Limit: 50})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no entries, got %+v", entries)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestLeaderboardNotFound(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.ExpectQuery(regexp.QuoteMeta(datasetExistsQuery)).
+		WithArgs(datasetID).
+		WillReturnRows(db.NewRows([]string{"exists"}).AddRow(false))
+
+	store := NewStore(db)
+	q := Query{Metric: "r2", Limit: 10}
+
+	if _, err := store.Leaderboard(context.Background(), datasetID, q); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := store.Leaderboard(context.Background(), "not-a-uuid", q); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for malformed id, got %v", err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
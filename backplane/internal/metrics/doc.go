@@ -0,0 +1,20 @@
+/*
+Package metrics stores step-wise metrics, such as a model's loss after
+every batch, so training curves can be charted rather than only the
+final evaluation.
+
+Points are logged against a model version or a training run, in batches,
+and appended with COPY, as a long run can log millions of them:
+
+	n, err := store.Append(ctx, tenant, metrics.KindRun, runID, points)
+
+Reading them back downsamples each series to a fixed number of points,
+splitting its steps into equal buckets and returning the mean, minimum
+and maximum of each, so a chart costs the same however long training
+ran:
+
+	series, err := store.Series(ctx, tenant, metrics.KindRun, runID, []string{"loss"}, 500)
+
+Metrics aren't recorded in the audit log.
+*/
+package metrics
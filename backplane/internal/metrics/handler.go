@@ -0,0 +1,198 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/auth"
+)
+
+const (
+	// MaxBatch is the most points that can be logged in one request.
+	MaxBatch = 50000
+	// DefaultPoints is how many points each series is downsampled to
+	// unless the points query parameter says otherwise, and MaxPoints
+	// the most that can be asked for.
+	DefaultPoints = 500
+	MaxPoints     = 10000
+)
+
+/*
+Logger allows metrics to be appended to and read back from models and
+runs.
+*/
+type Logger interface {
+	Append(ctx context.Context, tenant string, kind Kind, id string, points []Point) (int64, error)
+	Series(ctx context.Context, tenant string, kind Kind, id string, keys []string, buckets int) ([]*Series, error)
+}
+
+type Handler struct {
+	l Logger
+}
+
+func NewHandler(l Logger) *Handler {
+	return &Handler{
+		l: l,
+	}
+}
+
+/*
+ModelMetrics handles requests for a model's metrics. It should be
+registered under /models/{id}/metrics.
+*/
+func (h *Handler) ModelMetrics(w http.ResponseWriter, r *http.Request) {
+	h.metrics(w, r, KindModel)
+}
+
+/*
+RunMetrics handles requests for a run's metrics. It should be registered
+under /runs/{id}/metrics.
+*/
+func (h *Handler) RunMetrics(w http.ResponseWriter, r *http.Request) {
+	h.metrics(w, r, KindRun)
+}
+
+func (h *Handler) metrics(w http.ResponseWriter, r *http.Request, kind Kind) {
+	switch r.Method {
+	case http.MethodGet:
+		h.Series(w, r, kind)
+	case http.MethodPost:
+		h.Append(w, r, kind)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusMethodNotAllowed,
+			Message: "Method not allowed",
+			Reason:  "",
+		})
+	}
+}
+
+/*
+Append logs a batch of points, sent as a JSON array of objects with a
+key, step, value and, optionally, timestamp.
+*/
+func (h *Handler) Append(w http.ResponseWriter, r *http.Request, kind Kind) {
+	id := mux.Vars(r)["id"]
+
+	var points []Point
+	if err := json.NewDecoder(r.Body).Decode(&points); err != nil {
+		log.Printf("failed to decode body: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to log metrics",
+			Reason:  fmt.Sprintf("could not parse body: %s", err),
+		})
+		return
+	}
+
+	if err := validate(points); err != nil {
+		log.Printf("failed to validate metrics: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to log metrics",
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	n, err := h.l.Append(r.Context(), auth.TenantFromContext(r.Context()), kind, id, points)
+	if err != nil {
+		writeError(w, "Failed to log metrics", kind, id, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]int64{"appended": n})
+}
+
+/*
+Series returns each metric's downsampled series, optionally only those
+named by the key query parameter, which may be repeated or comma
+separated. The points parameter sets how many points each series is
+downsampled to.
+*/
+func (h *Handler) Series(w http.ResponseWriter, r *http.Request, kind Kind) {
+	id := mux.Vars(r)["id"]
+	q := r.URL.Query()
+
+	var keys []string
+	for _, param := range q["key"] {
+		for _, key := range strings.Split(param, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	buckets := DefaultPoints
+	if s := q.Get("points"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > MaxPoints {
+			log.Printf("failed to read metrics: invalid points %q", s)
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(&internal.Error{
+				Code:    http.StatusBadRequest,
+				Message: "Failed to read metrics",
+				Reason:  fmt.Sprintf("points must be a number from 1 to %d", MaxPoints),
+			})
+			return
+		}
+		buckets = n
+	}
+
+	series, err := h.l.Series(r.Context(), auth.TenantFromContext(r.Context()), kind, id, keys, buckets)
+	if err != nil {
+		writeError(w, "Failed to read metrics", kind, id, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(series)
+}
+
+func validate(points []Point) error {
+	if len(points) == 0 {
+		return fmt.Errorf("no metrics were sent")
+	}
+	if len(points) > MaxBatch {
+		return fmt.Errorf("at most %d metrics can be logged at once, got %d", MaxBatch, len(points))
+	}
+	for i, p := range points {
+		if p.Key == "" {
+			return fmt.Errorf("metric %d has no key", i)
+		}
+		if p.Step < 0 {
+			return fmt.Errorf("metric %d has a negative step", i)
+		}
+	}
+	return nil
+}
+
+/*
+writeError logs err and answers with it, as a 404 if the model or run
+wasn't found.
+*/
+func writeError(w http.ResponseWriter, message string, kind Kind, id string, err error) {
+	log.Printf("%s for %s %s: %s", strings.ToLower(message), kind, id, err)
+
+	code := http.StatusInternalServerError
+	if errors.Is(err, internal.ErrNotFound) {
+		code = http.StatusNotFound
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    code,
+		Message: message,
+		Reason:  err.Error(),
+	})
+}
@@ -0,0 +1,167 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+)
+
+type mockLogger struct {
+	AppendFn func(ctx context.Context, tenant string, kind Kind, id string, points []Point) (int64, error)
+	SeriesFn func(ctx context.Context, tenant string, kind Kind, id string, keys []string, buckets int) ([]*Series, error)
+}
+
+func (m *mockLogger) Append(ctx context.Context, tenant string, kind Kind, id string, points []Point) (int64, error) {
+	return m.AppendFn(ctx, tenant, kind, id, points)
+}
+
+func (m *mockLogger) Series(ctx context.Context, tenant string, kind Kind, id string, keys []string, buckets int) ([]*Series, error) {
+	return m.SeriesFn(ctx, tenant, kind, id, keys, buckets)
+}
+
+func TestHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		body             string
+		route            func(h *Handler) http.HandlerFunc
+		logger           *mockLogger
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "POST success",
+			method: http.MethodPost,
+			path:   "/runs/r1/metrics",
+			body:   `[{"key": "loss", "step": 1, "value": 0.9}, {"key": "loss", "step": 2, "value": 0.7}]`,
+			route:  func(h *Handler) http.HandlerFunc { return h.RunMetrics },
+			logger: &mockLogger{AppendFn: func(_ context.Context, tenant string, kind Kind, id string, points []Point) (int64, error) {
+				if kind != KindRun || id != "r1" {
+					return 0, fmt.Errorf("unexpected %s %s", kind, id)
+				}
+				return int64(len(points)), nil
+			}},
+			expectedStatus:   http.StatusCreated,
+			expectedContains: `{"appended": 2}`,
+		},
+		{
+			name:             "POST failure - no key",
+			method:           http.MethodPost,
+			path:             "/runs/r1/metrics",
+			body:             `[{"key": "loss", "step": 1, "value": 0.9}, {"step": 2, "value": 0.7}]`,
+			route:            func(h *Handler) http.HandlerFunc { return h.RunMetrics },
+			logger:           &mockLogger{},
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to log metrics", "reason": "metric 1 has no key"}`,
+		},
+		{
+			name:             "POST failure - empty",
+			method:           http.MethodPost,
+			path:             "/runs/r1/metrics",
+			body:             `[]`,
+			route:            func(h *Handler) http.HandlerFunc { return h.RunMetrics },
+			logger:           &mockLogger{},
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to log metrics", "reason": "no metrics were sent"}`,
+		},
+		{
+			name:   "POST failure - not found",
+			method: http.MethodPost,
+			path:   "/models/r1/metrics",
+			body:   `[{"key": "loss", "step": 1, "value": 0.9}]`,
+			route:  func(h *Handler) http.HandlerFunc { return h.ModelMetrics },
+			logger: &mockLogger{AppendFn: func(_ context.Context, tenant string, kind Kind, id string, points []Point) (int64, error) {
+				return 0, fmt.Errorf("%s %s: %w", kind, id, internal.ErrNotFound)
+			}},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Failed to log metrics", "reason": "model r1: not found"}`,
+		},
+		{
+			name:   "GET success",
+			method: http.MethodGet,
+			path:   "/models/r1/metrics?key=loss,acc&points=100",
+			route:  func(h *Handler) http.HandlerFunc { return h.ModelMetrics },
+			logger: &mockLogger{SeriesFn: func(_ context.Context, tenant string, kind Kind, id string, keys []string, buckets int) ([]*Series, error) {
+				if !reflect.DeepEqual(keys, []string{"loss", "acc"}) || buckets != 100 {
+					return nil, fmt.Errorf("unexpected keys %v and buckets %d", keys, buckets)
+				}
+				return []*Series{{Key: "loss", Count: 1, Points: []Point{{Step: 1, Value: 0.9}}}}, nil
+			}},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `[{"key": "loss", "count": 1, "points": [{"step": 1, "timestamp": "0001-01-01T00:00:00Z", "value": 0.9}]}]`,
+		},
+		{
+			name:             "GET failure - bad points",
+			method:           http.MethodGet,
+			path:             "/models/r1/metrics?points=0",
+			route:            func(h *Handler) http.HandlerFunc { return h.ModelMetrics },
+			logger:           &mockLogger{},
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to read metrics", "reason": "points must be a number from 1 to 10000"}`,
+		},
+		{
+			name:             "METHOD failure",
+			method:           http.MethodDelete,
+			path:             "/models/r1/metrics",
+			route:            func(h *Handler) http.HandlerFunc { return h.ModelMetrics },
+			logger:           &mockLogger{},
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+			req := httptest.NewRequest(tc.method, tc.path, body)
+			req = mux.SetURLVars(req, map[string]string{"id": "r1"})
+			rr := httptest.NewRecorder()
+
+			tc.route(NewHandler(tc.logger))(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func checkResponse(t *testing.T, got *http.Response, expectedStatus int, expected string) {
+	defer got.Body.Close()
+
+	body, err := io.ReadAll(got.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %s", err)
+	}
+
+	if expectedStatus != got.StatusCode {
+		t.Errorf("status mismatch - wanted %d, got %d", expectedStatus, got.StatusCode)
+	}
+
+	var gotData any
+	if err := json.Unmarshal(body, &gotData); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v\nbody: %s", err, string(body))
+	}
+
+	var expectedData any
+	if err := json.Unmarshal([]byte(expected), &expectedData); err != nil {
+		t.Fatalf("failed to unmarshal expected value: %v\njson: %s", err, expected)
+	}
+
+	if !reflect.DeepEqual(expectedData, gotData) {
+		t.Errorf("JSON mismatch:\nexpected: %+v\ngot: %+v", expectedData, gotData)
+	}
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import "time"
+
+/*
+Kind is what metrics are logged against.
+*/
+type Kind string
+
+const (
+	KindModel Kind = "model"
+	KindRun   Kind = "run"
+)
+
+/*
+Point is the value of the metric Key at Step. When logging, a zero
+Timestamp is taken to mean now.
+*/
+type Point struct {
+	Key       string    `json:"key,omitempty"`
+	Step      int64     `json:"step"`
+	Timestamp time.Time `json:"timestamp"`
+	Value     float64   `json:"value"`
+
+	// Min and Max are set on downsampled points which summarise more
+	// than one logged point, whose mean is Value. Step and Timestamp are
+	// then those of the last of them.
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+/*
+Series is the downsampled values of one metric, in step order.
+*/
+type Series struct {
+	Key string `json:"key"`
+
+	// Count is how many points were logged, however many are returned.
+	Count int64 `json:"count"`
+
+	Points []Point `json:"points"`
+}
@@ -0,0 +1,171 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	modelExistsQuery = `SELECT EXISTS (SELECT 1 FROM models WHERE id = $1)`
+	runExistsQuery   = `SELECT EXISTS (SELECT 1 FROM runs WHERE id = $1 AND tenant = $2)`
+
+	// Each series' steps are split into $4 equal buckets, so a series
+	// with fewer distinct steps than that comes back as it was logged.
+	seriesQuery = `WITH points AS (
+  SELECT
+    key,
+    step,
+    logged_at,
+    value,
+    min(step) OVER w AS first_step,
+    max(step) OVER w AS last_step,
+    count(*) OVER w AS total
+  FROM metrics
+  WHERE tenant = $1 AND resource_type = $2 AND resource_id = $3`
+	keysClause  = ` AND key = ANY($5)`
+	seriesGroup = `
+  WINDOW w AS (PARTITION BY key)
+)
+SELECT
+  key,
+  total,
+  count(*),
+  max(step),
+  max(logged_at),
+  avg(value),
+  min(value),
+  max(value)
+FROM points
+GROUP BY key, total, floor((step - first_step)::float8 * $4::int / (last_step - first_step + 1))
+ORDER BY key, max(step)`
+)
+
+/*
+columns are those of the metrics table, in the order rows are copied.
+*/
+var columns = []string{"resource_type", "resource_id", "key", "step", "logged_at", "value", "tenant"}
+
+/*
+Querier is what the Store needs from the database. Unlike other stores it
+needs CopyFrom, to append points in bulk.
+*/
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	CopyFrom(ctx context.Context, tableName pgx.Identifier, columnNames []string, rowSrc pgx.CopyFromSource) (int64, error)
+}
+
+type Store struct {
+	q Querier
+}
+
+func NewStore(q Querier) *Store {
+	return &Store{
+		q: q,
+	}
+}
+
+/*
+Append logs points against the model or run id, returning how many were
+stored. It wraps internal.ErrNotFound if there is no such model, or no
+such run in tenant.
+*/
+func (s *Store) Append(ctx context.Context, tenant string, kind Kind, id string, points []Point) (int64, error) {
+	if err := s.exists(ctx, tenant, kind, id); err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	rows := make([][]any, len(points))
+	for i, p := range points {
+		at := p.Timestamp
+		if at.IsZero() {
+			at = now
+		}
+		rows[i] = []any{string(kind), id, p.Key, p.Step, at, p.Value, tenant}
+	}
+
+	n, err := s.q.CopyFrom(ctx, pgx.Identifier{"metrics"}, columns, pgx.CopyFromRows(rows))
+	if err != nil {
+		return 0, fmt.Errorf("could not append metrics: %w", err)
+	}
+	return n, nil
+}
+
+/*
+Series returns the metrics logged in tenant against the model or run id,
+each downsampled to at most buckets points, optionally only those named
+by keys.
+*/
+func (s *Store) Series(ctx context.Context, tenant string, kind Kind, id string, keys []string, buckets int) ([]*Series, error) {
+	if err := s.exists(ctx, tenant, kind, id); err != nil {
+		return nil, err
+	}
+
+	query := seriesQuery + seriesGroup
+	args := []any{tenant, string(kind), id, buckets}
+	if len(keys) > 0 {
+		query = seriesQuery + keysClause + seriesGroup
+		args = append(args, keys)
+	}
+
+	rows, err := s.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query metrics: %w", err)
+	}
+	defer rows.Close()
+
+	series := []*Series{}
+	var current *Series
+	for rows.Next() {
+		var key string
+		var total, n int64
+		var p Point
+		var low, high float64
+		if err := rows.Scan(&key, &total, &n, &p.Step, &p.Timestamp, &p.Value, &low, &high); err != nil {
+			return nil, err
+		}
+		if n > 1 {
+			p.Min, p.Max = &low, &high
+		}
+
+		if current == nil || current.Key != key {
+			current = &Series{Key: key, Count: total, Points: []Point{}}
+			series = append(series, current)
+		}
+		current.Points = append(current.Points, p)
+	}
+	return series, rows.Err()
+}
+
+func (s *Store) exists(ctx context.Context, tenant string, kind Kind, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return fmt.Errorf("%s %s: %w", kind, id, internal.ErrNotFound)
+	}
+
+	var row pgx.Row
+	switch kind {
+	case KindModel:
+		row = s.q.QueryRow(ctx, modelExistsQuery, id)
+	case KindRun:
+		row = s.q.QueryRow(ctx, runExistsQuery, id, tenant)
+	default:
+		return fmt.Errorf("unknown kind %q", kind)
+	}
+
+	var exists bool
+	if err := row.Scan(&exists); err != nil {
+		return fmt.Errorf("could not query %s: %w", kind, err)
+	}
+	if !exists {
+		return fmt.Errorf("%s %s: %w", kind, id, internal.ErrNotFound)
+	}
+	return nil
+}
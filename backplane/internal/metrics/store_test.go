@@ -0,0 +1,120 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+const (
+	modelID = "7a1e2f30-0000-4000-8000-000000000001"
+	runID   = "7a1e2f30-0000-4000-8000-000000000002"
+)
+
+func TestAppend(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.ExpectQuery(regexp.QuoteMeta(runExistsQuery)).
+		WithArgs(runID, "acme").
+		WillReturnRows(db.NewRows([]string{"exists"}).AddRow(true))
+	db.ExpectCopyFrom(pgx.Identifier{"metrics"}, columns).
+		WillReturnResult(2)
+
+	loggedAt := time.Date(2025, 7, 10, 9, 0, 0, 0, time.UTC)
+	points := []Point{
+		{Key: "loss", Step: 1, Timestamp: loggedAt, Value: 0.9},
+		{Key: "loss", Step: 2, Value: 0.7},
+	}
+
+	n, err := NewStore(db).Append(context.Background(), "acme", KindRun, runID, points)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if n != 2 {
+		t.Errorf("got %d points appended, wanted 2", n)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAppendNotFound(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.ExpectQuery(regexp.QuoteMeta(modelExistsQuery)).
+		WithArgs(modelID).
+		WillReturnRows(db.NewRows([]string{"exists"}).AddRow(false))
+
+	store := NewStore(db)
+	ctx := context.Background()
+	points := []Point{{Key: "loss", Step: 1, Value: 0.9}}
+
+	if _, err := store.Append(ctx, "acme", KindModel, modelID, points); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := store.Append(ctx, "acme", KindModel, "not-a-uuid", points); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for malformed id, got %v", err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSeries(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	loggedAt := time.Date(2025, 7, 10, 9, 0, 0, 0, time.UTC)
+	columns := []string{"key", "total", "count", "step", "logged_at", "avg", "min", "max"}
+
+	db.ExpectQuery(regexp.QuoteMeta(modelExistsQuery)).
+		WithArgs(modelID).
+		WillReturnRows(db.NewRows([]string{"exists"}).AddRow(true))
+	db.ExpectQuery(regexp.QuoteMeta(seriesQuery+keysClause+seriesGroup)).
+		WithArgs("acme", "model", modelID, 2, []string{"acc", "loss"}).
+		WillReturnRows(db.NewRows(columns).
+			AddRow("acc", int64(1), int64(1), int64(10), loggedAt, 0.8, 0.8, 0.8).
+			AddRow("loss", int64(4), int64(2), int64(2), loggedAt, 0.8, 0.7, 0.9).
+			AddRow("loss", int64(4), int64(2), int64(4), loggedAt, 0.4, 0.3, 0.5))
+
+	series, err := NewStore(db).Series(context.Background(), "acme", KindModel, modelID, []string{"acc", "loss"}, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	min1, max1, min2, max2 := 0.7, 0.9, 0.3, 0.5
+	want := []*Series{
+		{Key: "acc", Count: 1, Points: []Point{{Step: 10, Timestamp: loggedAt, Value: 0.8}}},
+		{Key: "loss", Count: 4, Points: []Point{
+			{Step: 2, Timestamp: loggedAt, Value: 0.8, Min: &min1, Max: &max1},
+			{Step: 4, Timestamp: loggedAt, Value: 0.4, Min: &min2, Max: &max2},
+		}},
+	}
+	if !reflect.DeepEqual(series, want) {
+		t.Errorf("got series %+v, wanted %+v", series, want)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -5,13 +5,18 @@ import (
 	"fmt"
 	"path/filepath"
 
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/heldtogether/traintrack/internal/seal"
 	"github.com/heldtogether/traintrack/internal/uploads"
 	"github.com/jackc/pgx/v5"
 )
 
 type modelsStore interface {
 	createWithQuerier(q Querier, m *Model) (*Model, error)
-	List() ([]*Model, error)
+	recordWithQuerier(q Querier, id string) (*seal.Record, error)
+	sealWithQuerier(q Querier, id string, sealed string) error
+	List(f ListFilter) ([]*Model, error)
 }
 
 /*
@@ -27,6 +32,14 @@ type FileMover interface {
 	MoveFile(srcPath, dstPath string) error
 }
 
+/*
+AuditRecorder records an audit event using the provided Querier, which
+may be a transaction.
+*/
+type AuditRecorder interface {
+	RecordWithQuerier(ctx context.Context, q audit.Querier, e *audit.Event) error
+}
+
 type TxBeginner interface {
 	Begin(ctx context.Context) (pgx.Tx, error)
 }
@@ -36,20 +49,25 @@ type DefaultCreator struct {
 	uploadMover UploadMover
 	fileMover   FileMover
 	db          TxBeginner
+	audit       AuditRecorder
 }
 
-func NewCreator(s *Store, u UploadMover, f FileMover, db TxBeginner) *DefaultCreator {
+func NewCreator(s *Store, u UploadMover, f FileMover, db TxBeginner, a AuditRecorder) *DefaultCreator {
 	return &DefaultCreator{
 		s:           s,
 		uploadMover: u,
 		fileMover:   f,
 		db:          db,
+		audit:       a,
 	}
 }
 
 /*
 Create a new model and move any artefacts from temporary storage
-to a sensible forever home.
+to a sensible forever home. Once everything is in place the version is
+sealed, and the creation is recorded in the audit log, as part of the same
+transaction. The creator is taken from the verified
+identity on ctx, never from the request body.
 */
 func (c *DefaultCreator) Create(ctx context.Context, m *Model) (created *Model, err error) {
 	tx, err := c.db.Begin(ctx)
@@ -62,45 +80,120 @@ func (c *DefaultCreator) Create(ctx context.Context, m *Model) (created *Model,
 		}
 	}()
 
-	created, err = c.s.createWithQuerier(tx, m)
+	if created, err = c.CreateWithQuerier(ctx, tx, m); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return created, nil
+}
+
+/*
+CreateWithQuerier creates the model as Create does, using q, which should
+be a transaction the caller commits. It lets a model be created as part
+of a larger change, such as finalizing a run.
+*/
+func (c *DefaultCreator) CreateWithQuerier(ctx context.Context, q Querier, m *Model) (created *Model, err error) {
+	stampCreator(ctx, m)
+
+	created, err = c.s.createWithQuerier(q, m)
 	if err != nil {
 		return nil, err
 	}
 
 	for _, id := range m.UploadIds {
-		upload, err := c.uploadMover.GetByIDWithQuerier(tx, id)
+		upload, err := c.uploadMover.GetByIDWithQuerier(q, id)
 		if err != nil {
 			return nil, fmt.Errorf("get upload %s: %w", id, err)
 		}
+		if upload.Quarantined {
+			return nil, fmt.Errorf("upload %s: %w", id, uploads.ErrQuarantined)
+		}
 
 		newFiles := make(map[string]uploads.FileRef, len(upload.Files))
 		for name, file := range upload.Files {
 			origPath := filepath.Join(file.Path, file.FileName)
 			newPath := filepath.Join("models", created.ID)
 			if err := c.fileMover.MoveFile(origPath, filepath.Join(newPath, file.FileName)); err != nil {
-				return nil, fmt.Errorf("move file %s: %w", file, err)
+				return nil, fmt.Errorf("move file %s: %w", file.FileName, err)
 			}
 			newFiles[name] = uploads.FileRef{
 				Provider: file.Provider,
 				FileName: file.FileName,
 				Path:     newPath,
+				Digest:   file.Digest,
+				Size:     file.Size,
+				Scans:    file.Scans,
+				Profile:  file.Profile,
+			}
+			if len(file.Scans) > 0 {
+				if created.Scans == nil {
+					created.Scans = map[string][]uploads.ScanResult{}
+				}
+				created.Scans[name] = file.Scans
 			}
 		}
 
 		upload.Files = newFiles
 		upload.ModelID = pointerTo(created.ID)
-		if err := c.uploadMover.MoveWithQuerier(tx, upload); err != nil {
+		if err := c.uploadMover.MoveWithQuerier(q, upload); err != nil {
 			return nil, fmt.Errorf("update upload %s: %w", id, err)
 		}
 	}
 
-	if err = tx.Commit(ctx); err != nil {
+	if created.Seal, err = c.seal(q, created.ID); err != nil {
 		return nil, err
 	}
 
+	if c.audit != nil {
+		e := audit.NewEvent(ctx, audit.ActionCreate, audit.ResourceModel, created.ID).
+			WithDetails(map[string]string{"name": created.Name, "version": created.Version, "seal": created.Seal})
+		if err = c.audit.RecordWithQuerier(ctx, q, e); err != nil {
+			return nil, fmt.Errorf("record audit event: %w", err)
+		}
+	}
+
 	return created, nil
 }
 
+/*
+seal hashes the model as it now stands in the database, so that exactly
+what will be read back later is what gets sealed, and stores the result.
+*/
+func (c *DefaultCreator) seal(q Querier, id string) (string, error) {
+	r, err := c.s.recordWithQuerier(q, id)
+	if err != nil {
+		return "", fmt.Errorf("load model to seal: %w", err)
+	}
+
+	sealed, err := r.Compute()
+	if err != nil {
+		return "", fmt.Errorf("compute seal: %w", err)
+	}
+
+	if err := c.s.sealWithQuerier(q, id, sealed); err != nil {
+		return "", fmt.Errorf("store seal: %w", err)
+	}
+
+	return sealed, nil
+}
+
+/*
+stampCreator overwrites the provenance fields of m with the identity
+on ctx, discarding anything the client sent.
+*/
+func stampCreator(ctx context.Context, m *Model) {
+	m.CreatedBy = ""
+	m.Tenant = ""
+	if id, ok := auth.IdentityFromContext(ctx); ok {
+		m.CreatedBy = id.Subject
+		m.Tenant = id.Tenant
+	}
+}
+
 func pointerTo[T any](v T) *T {
 	return &v
 }
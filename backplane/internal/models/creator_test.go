@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/heldtogether/traintrack/internal/seal"
 	"github.com/heldtogether/traintrack/internal/uploads"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
@@ -26,15 +29,33 @@ func (m *MockUploadsRepo) MoveWithQuerier(_ uploads.Querier, u *uploads.Upload)
 
 type MockModelsRepo struct {
 	CreateFunc func(ctx context.Context, d *Model) (*Model, error)
-	ListFunc   func() ([]*Model, error)
+	ListFunc   func(f ListFilter) ([]*Model, error)
+	RecordFunc func(id string) (*seal.Record, error)
+	SealFunc   func(id string, sealed string) error
 }
 
 func (m *MockModelsRepo) createWithQuerier(_ Querier, d *Model) (*Model, error) {
 	return m.CreateFunc(context.Background(), d)
 }
 
-func (m *MockModelsRepo) List() ([]*Model, error) {
-	return m.ListFunc()
+func (m *MockModelsRepo) List(f ListFilter) ([]*Model, error) {
+	return m.ListFunc(f)
+}
+
+func (m *MockModelsRepo) recordWithQuerier(_ Querier, id string) (*seal.Record, error) {
+	return m.RecordFunc(id)
+}
+
+func (m *MockModelsRepo) sealWithQuerier(_ Querier, id string, sealed string) error {
+	return m.SealFunc(id, sealed)
+}
+
+type MockAuditRecorder struct {
+	RecordFunc func(e *audit.Event) error
+}
+
+func (m *MockAuditRecorder) RecordWithQuerier(_ context.Context, _ audit.Querier, e *audit.Event) error {
+	return m.RecordFunc(e)
 }
 
 type mockDB struct {
@@ -98,8 +119,11 @@ func TestService_Create(t *testing.T) {
 		name              string
 		failCreate        bool
 		failGetUpload     bool
+		quarantined       bool
 		failMoveFile      bool
 		failMoveUpload    bool
+		failSeal          bool
+		failAudit         bool
 		failCommit        bool
 		wantCalled        []string
 		expectCreateError bool
@@ -111,6 +135,9 @@ func TestService_Create(t *testing.T) {
 				"get-upload",
 				"move-file temp/path/artifact.txt -> models/ds456/artifact.txt",
 				"move-upload",
+				"load-record",
+				"seal",
+				"record-audit",
 				"commit",
 			},
 		},
@@ -126,6 +153,12 @@ func TestService_Create(t *testing.T) {
 			wantCalled:        []string{"create-model", "get-upload", "rollback"},
 			expectCreateError: true,
 		},
+		{
+			name:              "upload quarantined",
+			quarantined:       true,
+			wantCalled:        []string{"create-model", "get-upload", "rollback"},
+			expectCreateError: true,
+		},
 		{
 			name:              "move file fails",
 			failMoveFile:      true,
@@ -138,10 +171,22 @@ func TestService_Create(t *testing.T) {
 			wantCalled:        []string{"create-model", "get-upload", "move-file temp/path/artifact.txt -> models/ds456/artifact.txt", "move-upload", "rollback"},
 			expectCreateError: true,
 		},
+		{
+			name:              "seal fails",
+			failSeal:          true,
+			wantCalled:        []string{"create-model", "get-upload", "move-file temp/path/artifact.txt -> models/ds456/artifact.txt", "move-upload", "load-record", "seal", "rollback"},
+			expectCreateError: true,
+		},
+		{
+			name:              "audit fails",
+			failAudit:         true,
+			wantCalled:        []string{"create-model", "get-upload", "move-file temp/path/artifact.txt -> models/ds456/artifact.txt", "move-upload", "load-record", "seal", "record-audit", "rollback"},
+			expectCreateError: true,
+		},
 		{
 			name:              "commit fails",
 			failCommit:        true,
-			wantCalled:        []string{"create-model", "get-upload", "move-file temp/path/artifact.txt -> models/ds456/artifact.txt", "move-upload", "commit", "rollback"},
+			wantCalled:        []string{"create-model", "get-upload", "move-file temp/path/artifact.txt -> models/ds456/artifact.txt", "move-upload", "load-record", "seal", "record-audit", "commit", "rollback"},
 			expectCreateError: true,
 		},
 	}
@@ -179,6 +224,17 @@ func TestService_Create(t *testing.T) {
 						UploadIds: d.UploadIds,
 					}, nil
 				},
+				RecordFunc: func(id string) (*seal.Record, error) {
+					called = append(called, "load-record")
+					return &seal.Record{Kind: "test", ID: id}, nil
+				},
+				SealFunc: func(id string, sealed string) error {
+					called = append(called, "seal")
+					if tc.failSeal {
+						return errors.New("boom")
+					}
+					return nil
+				},
 			}
 
 			mockUploadRepo := &MockUploadsRepo{
@@ -188,17 +244,24 @@ func TestService_Create(t *testing.T) {
 						return nil, errors.New("boom")
 					}
 					return &uploads.Upload{
-						ID: uploadID,
+						ID:          uploadID,
+						Quarantined: tc.quarantined,
 						Files: map[string]uploads.FileRef{
 							"artefact": {
 								Provider: uploads.ProviderFileSystem,
 								FileName: fileName,
 								Path:     "temp/path/",
+								Digest:   "sha256:abc",
+								Size:     3,
+								Scans:    []uploads.ScanResult{{Scanner: "pickle", Verdict: uploads.ScanClean}},
 							}},
 					}, nil
 				},
 				MoveFunc: func(ctx context.Context, u *uploads.Upload) error {
 					called = append(called, "move-upload")
+					if f := u.Files["artefact"]; f.Digest != "sha256:abc" || f.Size != 3 || len(f.Scans) != 1 {
+						return fmt.Errorf("digest and scans not carried over: %+v", f)
+					}
 					if tc.failMoveUpload {
 						return errors.New("boom")
 					}
@@ -221,6 +284,15 @@ func TestService_Create(t *testing.T) {
 				uploadMover: mockUploadRepo,
 				fileMover:   mockStorage,
 				db:          mockDB,
+				audit: &MockAuditRecorder{
+					RecordFunc: func(e *audit.Event) error {
+						called = append(called, "record-audit")
+						if tc.failAudit {
+							return errors.New("boom")
+						}
+						return nil
+					},
+				},
 			}
 
 			ctx := context.Background()
@@ -245,3 +317,62 @@ func TestService_Create(t *testing.T) {
 		})
 	}
 }
+
+func TestService_CreateStampsCreator(t *testing.T) {
+	tests := []struct {
+		name          string
+		identity      *auth.Identity
+		wantCreatedBy string
+		wantTenant    string
+	}{
+		{
+			name:          "from token",
+			identity:      &auth.Identity{Subject: "dev|1", Tenant: "acme"},
+			wantCreatedBy: "dev|1",
+			wantTenant:    "acme",
+		},
+		{
+			name: "no identity",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var called []string
+			mockPgx, _ := pgxmock.NewConn()
+			baseTx, _ := mockPgx.Begin(context.Background())
+			tx := &loggingTx{Tx: baseTx, log: &called}
+
+			var stored *Model
+			creator := &DefaultCreator{
+				s: &MockModelsRepo{
+					CreateFunc: func(ctx context.Context, m *Model) (*Model, error) {
+						stored = m
+						return &Model{ID: "1", CreatedBy: m.CreatedBy, Tenant: m.Tenant}, nil
+					},
+					RecordFunc: func(id string) (*seal.Record, error) {
+						return &seal.Record{ID: id}, nil
+					},
+					SealFunc: func(id string, sealed string) error {
+						return nil
+					},
+				},
+				db: &mockDB{tx: tx},
+			}
+
+			ctx := context.Background()
+			if tc.identity != nil {
+				ctx = auth.NewContext(ctx, tc.identity)
+			}
+
+			_, err := creator.Create(ctx, &Model{CreatedBy: "mallory", Tenant: "evil"})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if stored.CreatedBy != tc.wantCreatedBy || stored.Tenant != tc.wantTenant {
+				t.Errorf("got created_by %q tenant %q, want %q %q", stored.CreatedBy, stored.Tenant, tc.wantCreatedBy, tc.wantTenant)
+			}
+		})
+	}
+}
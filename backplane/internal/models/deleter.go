@@ -0,0 +1,244 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/heldtogether/traintrack/internal/uploads"
+)
+
+type modelsDeleter interface {
+	getWithQuerier(q Querier, id string) (*Model, error)
+	lockDeletedWithQuerier(q Querier, tenant string, id string) (deleted bool, purged bool, stage Stage, err error)
+	childrenWithQuerier(q Querier, id string) ([]string, error)
+	holdsWithQuerier(q Querier, id string) ([]string, error)
+	setDeletedWithQuerier(q Querier, id string, deleted bool) error
+	purgeWithQuerier(q Querier, id string) error
+	filesWithQuerier(q Querier, id string) ([]uploads.FileRef, error)
+}
+
+/*
+FileRemover permanently removes artefacts from storage.
+*/
+type FileRemover interface {
+	RemoveFile(path string) error
+}
+
+type DefaultDeleter struct {
+	s     modelsDeleter
+	files FileRemover
+	db    TxBeginner
+	audit AuditRecorder
+}
+
+func NewDeleter(s *Store, f FileRemover, db TxBeginner, a AuditRecorder) *DefaultDeleter {
+	return &DefaultDeleter{
+		s:     s,
+		files: f,
+		db:    db,
+		audit: a,
+	}
+}
+
+/*
+Delete moves the model id to the trash, hiding it from lists. It is
+refused while the model is in staging or production, or newer versions
+of it depend on it, unless force is set, and while it is under a legal
+hold. Nothing is removed until the model is purged, and until then it can
+be restored.
+*/
+func (d *DefaultDeleter) Delete(ctx context.Context, id string, force bool) (*Model, error) {
+	return d.change(ctx, id, audit.ActionDelete, func(q Querier, deleted, purged bool, stage Stage) (map[string]any, error) {
+		if deleted {
+			return nil, fmt.Errorf("model %s is %w", id, internal.ErrInTrash)
+		}
+		if err := checkHolds(d.s, q, id); err != nil {
+			return nil, err
+		}
+
+		children, err := d.s.childrenWithQuerier(q, id)
+		if err != nil {
+			return nil, err
+		}
+		refs := []string{}
+		if live(stage) {
+			refs = append(refs, "stage "+string(stage))
+		}
+		refs = append(refs, children...)
+		if len(refs) > 0 && !force {
+			return nil, fmt.Errorf("model %s is %w by %s", id, internal.ErrInUse, strings.Join(refs, ", "))
+		}
+
+		if err := d.s.setDeletedWithQuerier(q, id, true); err != nil {
+			return nil, err
+		}
+		return map[string]any{"force": force, "references": refs}, nil
+	})
+}
+
+/*
+Restore takes the model id back out of the trash, provided it hasn't been
+purged.
+*/
+func (d *DefaultDeleter) Restore(ctx context.Context, id string) (*Model, error) {
+	return d.change(ctx, id, audit.ActionRestore, func(q Querier, deleted, purged bool, stage Stage) (map[string]any, error) {
+		if !deleted {
+			return nil, fmt.Errorf("model %s is %w", id, internal.ErrNotInTrash)
+		}
+		if purged {
+			return nil, fmt.Errorf("model %s is %w and can't be restored", id, internal.ErrPurged)
+		}
+
+		return nil, d.s.setDeletedWithQuerier(q, id, false)
+	})
+}
+
+/*
+Purge permanently removes the artefacts of the model id, which must be in
+the trash, from storage. A model still in staging or production has to be
+archived first, even if it was deleted with force, and it can't be purged
+while it is under a legal hold. The model's record is kept.
+*/
+func (d *DefaultDeleter) Purge(ctx context.Context, id string) (*Model, error) {
+	var paths []string
+	changed, err := d.change(ctx, id, audit.ActionPurge, func(q Querier, deleted, purged bool, stage Stage) (map[string]any, error) {
+		if !deleted {
+			return nil, fmt.Errorf("model %s is %w", id, internal.ErrNotInTrash)
+		}
+		if purged {
+			return nil, fmt.Errorf("model %s is %w", id, internal.ErrPurged)
+		}
+		if err := checkHolds(d.s, q, id); err != nil {
+			return nil, err
+		}
+		if live(stage) {
+			return nil, fmt.Errorf("model %s is %w by stage %s", id, internal.ErrInUse, stage)
+		}
+
+		if err := d.s.purgeWithQuerier(q, id); err != nil {
+			return nil, err
+		}
+		var err error
+		if paths, err = artefactPaths(d.s, q, id); err != nil {
+			return nil, err
+		}
+		return map[string]any{"files": paths}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// The purge is committed before anything is removed, so a model is
+	// never left looking intact with its artefacts gone.
+	if err := removeFiles(d.files, paths); err != nil {
+		return nil, fmt.Errorf("model %s is purged, but not every artefact was removed: %w", id, err)
+	}
+	return changed, nil
+}
+
+/*
+live reports whether models in stage are being served, and so mustn't be
+removed.
+*/
+func live(stage Stage) bool {
+	return stage == StageStaging || stage == StageProduction
+}
+
+/*
+change locks the model id, makes a change to it with fn, and records that
+in the audit log, all in one transaction. fn is given whether the model is
+deleted and purged, and its stage, and returns the details to record.
+Only the caller's tenant's models can be changed, others aren't found.
+*/
+func (d *DefaultDeleter) change(ctx context.Context, id string, action audit.Action, fn func(q Querier, deleted, purged bool, stage Stage) (map[string]any, error)) (changed *Model, err error) {
+	tx, err := d.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	deleted, purged, stage, err := d.s.lockDeletedWithQuerier(tx, auth.TenantFromContext(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+
+	details, err := fn(tx, deleted, purged, stage)
+	if err != nil {
+		return nil, err
+	}
+
+	changed, err = d.s.getWithQuerier(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.audit != nil {
+		if details == nil {
+			details = map[string]any{}
+		}
+		details["name"] = changed.Name
+		details["version"] = changed.Version
+		e := audit.NewEvent(ctx, action, audit.ResourceModel, id).WithDetails(details)
+		if err = d.audit.RecordWithQuerier(ctx, tx, e); err != nil {
+			return nil, fmt.Errorf("record audit event: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+/*
+checkHolds refuses to remove the model id while it is under a legal hold,
+however much else is forced.
+*/
+func checkHolds(s modelsDeleter, q Querier, id string) error {
+	holders, err := s.holdsWithQuerier(q, id)
+	if err != nil {
+		return err
+	}
+	if len(holders) > 0 {
+		return fmt.Errorf("model %s is %w by %s", id, internal.ErrHeld, strings.Join(holders, ", "))
+	}
+	return nil
+}
+
+/*
+artefactPaths lists where every artefact of the model id is stored.
+*/
+func artefactPaths(s modelsDeleter, q Querier, id string) ([]string, error) {
+	files, err := s.filesWithQuerier(q, id)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{}
+	for _, file := range files {
+		paths = append(paths, filepath.Join(file.Path, file.FileName))
+	}
+	return paths, nil
+}
+
+/*
+removeFiles removes each of paths from storage. Files already gone are
+skipped, so a removal which failed part way can be retried.
+*/
+func removeFiles(f FileRemover, paths []string) error {
+	for _, p := range paths {
+		if err := f.RemoveFile(p); err != nil {
+			return fmt.Errorf("remove %s: %w", p, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/heldtogether/traintrack/internal/uploads"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+type MockDeleterRepo struct {
+	tenant   string
+	deleted  bool
+	purged   bool
+	stage    Stage
+	children []string
+	holders  []string
+	files    []uploads.FileRef
+	called   *[]string
+}
+
+func (m *MockDeleterRepo) getWithQuerier(_ Querier, id string) (*Model, error) {
+	*m.called = append(*m.called, "get")
+	return &Model{ID: id, Name: "regressor", Version: "1.0.0", Stage: m.stage}, nil
+}
+
+func (m *MockDeleterRepo) lockDeletedWithQuerier(_ Querier, tenant string, id string) (bool, bool, Stage, error) {
+	*m.called = append(*m.called, "lock")
+	if tenant != m.tenant {
+		return false, false, "", internal.ErrNotFound
+	}
+	return m.deleted, m.purged, m.stage, nil
+}
+
+func (m *MockDeleterRepo) childrenWithQuerier(_ Querier, id string) ([]string, error) {
+	return m.children, nil
+}
+
+func (m *MockDeleterRepo) holdsWithQuerier(_ Querier, id string) ([]string, error) {
+	return m.holders, nil
+}
+
+func (m *MockDeleterRepo) setDeletedWithQuerier(_ Querier, id string, deleted bool) error {
+	if deleted {
+		*m.called = append(*m.called, "trash")
+	} else {
+		*m.called = append(*m.called, "restore")
+	}
+	return nil
+}
+
+func (m *MockDeleterRepo) purgeWithQuerier(_ Querier, id string) error {
+	*m.called = append(*m.called, "purge")
+	return nil
+}
+
+func (m *MockDeleterRepo) filesWithQuerier(_ Querier, id string) ([]uploads.FileRef, error) {
+	return m.files, nil
+}
+
+type MockFileRemover struct {
+	RemoveFunc func(path string) error
+}
+
+func (m *MockFileRemover) RemoveFile(path string) error {
+	return m.RemoveFunc(path)
+}
+
+func TestDeleter(t *testing.T) {
+	tests := []struct {
+		name        string
+		do          func(d *DefaultDeleter) (*Model, error)
+		deleted     bool
+		purged      bool
+		stage       Stage
+		children    []string
+		holders     []string
+		failRemove  bool
+		wantCalled  []string
+		wantErr     error
+		wantAction  audit.Action
+		wantDetails string
+	}{
+		{
+			name:        "delete",
+			do:          func(d *DefaultDeleter) (*Model, error) { return d.Delete(context.Background(), "1", false) },
+			stage:       StageDevelopment,
+			wantCalled:  []string{"lock", "trash", "get", "record-audit", "commit"},
+			wantAction:  audit.ActionDelete,
+			wantDetails: `{"force":false,"name":"regressor","references":[],"version":"1.0.0"}`,
+		},
+		{
+			name:       "delete in production",
+			do:         func(d *DefaultDeleter) (*Model, error) { return d.Delete(context.Background(), "1", false) },
+			stage:      StageProduction,
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrInUse,
+		},
+		{
+			name:        "delete in production with force",
+			do:          func(d *DefaultDeleter) (*Model, error) { return d.Delete(context.Background(), "1", true) },
+			stage:       StageProduction,
+			children:    []string{"model regressor 1.0.1"},
+			wantCalled:  []string{"lock", "trash", "get", "record-audit", "commit"},
+			wantAction:  audit.ActionDelete,
+			wantDetails: `{"force":true,"name":"regressor","references":["stage production","model regressor 1.0.1"],"version":"1.0.0"}`,
+		},
+		{
+			name:       "delete held with force",
+			do:         func(d *DefaultDeleter) (*Model, error) { return d.Delete(context.Background(), "1", true) },
+			stage:      StageDevelopment,
+			holders:    []string{"legal"},
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrHeld,
+		},
+		{
+			name:       "delete twice",
+			do:         func(d *DefaultDeleter) (*Model, error) { return d.Delete(context.Background(), "1", false) },
+			deleted:    true,
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrInTrash,
+		},
+		{
+			name:        "restore",
+			do:          func(d *DefaultDeleter) (*Model, error) { return d.Restore(context.Background(), "1") },
+			deleted:     true,
+			stage:       StageProduction,
+			wantCalled:  []string{"lock", "restore", "get", "record-audit", "commit"},
+			wantAction:  audit.ActionRestore,
+			wantDetails: `{"name":"regressor","version":"1.0.0"}`,
+		},
+		{
+			name:       "restore purged",
+			do:         func(d *DefaultDeleter) (*Model, error) { return d.Restore(context.Background(), "1") },
+			deleted:    true,
+			purged:     true,
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrPurged,
+		},
+		{
+			name:        "purge",
+			do:          func(d *DefaultDeleter) (*Model, error) { return d.Purge(context.Background(), "1") },
+			deleted:     true,
+			stage:       StageArchived,
+			wantCalled:  []string{"lock", "purge", "get", "record-audit", "commit", "remove models/1/model.pkl"},
+			wantAction:  audit.ActionPurge,
+			wantDetails: `{"files":["models/1/model.pkl"],"name":"regressor","version":"1.0.0"}`,
+		},
+		{
+			name:       "purge in staging",
+			do:         func(d *DefaultDeleter) (*Model, error) { return d.Purge(context.Background(), "1") },
+			deleted:    true,
+			stage:      StageStaging,
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrInUse,
+		},
+		{
+			name:       "purge held",
+			do:         func(d *DefaultDeleter) (*Model, error) { return d.Purge(context.Background(), "1") },
+			deleted:    true,
+			stage:      StageArchived,
+			holders:    []string{"legal"},
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrHeld,
+		},
+		{
+			name:       "purge twice",
+			do:         func(d *DefaultDeleter) (*Model, error) { return d.Purge(context.Background(), "1") },
+			deleted:    true,
+			purged:     true,
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrPurged,
+		},
+		{
+			name:       "purge fails to remove files",
+			do:         func(d *DefaultDeleter) (*Model, error) { return d.Purge(context.Background(), "1") },
+			deleted:    true,
+			failRemove: true,
+			wantCalled: []string{"lock", "purge", "get", "record-audit", "commit", "remove models/1/model.pkl"},
+		},
+		{
+			name: "another tenant's model",
+			do: func(d *DefaultDeleter) (*Model, error) {
+				ctx := auth.NewContext(context.Background(), &auth.Identity{Subject: "dev|2", Tenant: "globex"})
+				return d.Purge(ctx, "1")
+			},
+			deleted:    true,
+			wantCalled: []string{"lock", "rollback"},
+			wantErr:    internal.ErrNotFound,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var called []string
+
+			mockPgx, _ := pgxmock.NewConn()
+			baseTx, _ := mockPgx.Begin(context.Background())
+			tx := &loggingTx{Tx: baseTx, log: &called}
+
+			var recorded *audit.Event
+			d := &DefaultDeleter{
+				s: &MockDeleterRepo{
+					deleted:  tc.deleted,
+					purged:   tc.purged,
+					stage:    tc.stage,
+					children: tc.children,
+					holders:  tc.holders,
+					files:    []uploads.FileRef{{FileName: "model.pkl", Path: "models/1"}},
+					called:   &called,
+				},
+				files: &MockFileRemover{
+					RemoveFunc: func(path string) error {
+						called = append(called, "remove "+path)
+						if tc.failRemove {
+							return errors.New("remove boom")
+						}
+						return nil
+					},
+				},
+				db: &mockDB{tx: tx},
+				audit: &MockAuditRecorder{
+					RecordFunc: func(e *audit.Event) error {
+						called = append(called, "record-audit")
+						recorded = e
+						return nil
+					},
+				},
+			}
+
+			got, err := tc.do(d)
+			if !reflect.DeepEqual(called, tc.wantCalled) {
+				t.Errorf("got calls %v, wanted %v", called, tc.wantCalled)
+			}
+			if tc.wantErr != nil || tc.failRemove {
+				if err == nil || (tc.wantErr != nil && !errors.Is(err, tc.wantErr)) {
+					t.Fatalf("got error %v, wanted %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			if got.ID != "1" {
+				t.Errorf("got model %+v", got)
+			}
+			if recorded == nil || recorded.Action != tc.wantAction || recorded.ResourceType != audit.ResourceModel || string(recorded.Details) != tc.wantDetails {
+				t.Errorf("unexpected audit event: %+v", recorded)
+			}
+		})
+	}
+}
@@ -4,33 +4,66 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/seal"
 )
 
 type mockService struct {
-	CreateFn func(ctx context.Context, m *Model) (*Model, error)
-	ListFn   func() ([]*Model, error)
+	CreateFn  func(ctx context.Context, m *Model) (*Model, error)
+	ListFn    func(f ListFilter) ([]*Model, error)
+	VerifyFn  func(id string) (*seal.Report, error)
+	PromoteFn func(ctx context.Context, id string, to Stage) (*Model, error)
+
+	DeleteFn  func(ctx context.Context, id string, force bool) (*Model, error)
+	RestoreFn func(ctx context.Context, id string) (*Model, error)
+	PurgeFn   func(ctx context.Context, id string) (*Model, error)
 }
 
 func (m *mockService) Create(ctx context.Context, d *Model) (*Model, error) {
 	return m.CreateFn(ctx, d)
 }
 
-func (m *mockService) List() ([]*Model, error) {
-	return m.ListFn()
+func (m *mockService) List(f ListFilter) ([]*Model, error) {
+	return m.ListFn(f)
+}
+
+func (m *mockService) Verify(id string) (*seal.Report, error) {
+	return m.VerifyFn(id)
+}
+
+func (m *mockService) Promote(ctx context.Context, id string, to Stage) (*Model, error) {
+	return m.PromoteFn(ctx, id, to)
+}
+
+func (m *mockService) Delete(ctx context.Context, id string, force bool) (*Model, error) {
+	return m.DeleteFn(ctx, id, force)
+}
+
+func (m *mockService) Restore(ctx context.Context, id string) (*Model, error) {
+	return m.RestoreFn(ctx, id)
+}
+
+func (m *mockService) Purge(ctx context.Context, id string) (*Model, error) {
+	return m.PurgeFn(ctx, id)
 }
 
 func TestRouter(t *testing.T) {
 	tests := []struct {
 		name             string
 		method           string
+		query            string
 		body             string
-		listModelsFn     func() ([]*Model, error)
+		listModelsFn     func(f ListFilter) ([]*Model, error)
 		createModelFn    func(ctx context.Context, m *Model) (*Model, error)
 		expectedStatus   int
 		expectedContains string
@@ -38,31 +71,52 @@ func TestRouter(t *testing.T) {
 		{
 			name:   "GET success",
 			method: http.MethodGet,
-			listModelsFn: func() ([]*Model, error) {
+			listModelsFn: func(f ListFilter) ([]*Model, error) {
 				return []*Model{{ID: "1", UploadIds: map[string]string{"file1": "abc"}}}, nil
 			},
 			expectedStatus:   http.StatusOK,
-			expectedContains: `[{"id": "1", "name": "", "parent": null, "version":"", "description":"", "artefacts": {"file1": "abc"}, "config":null, "environment":null, "evaluation": null, "metadata": null, "dataset": ""}]`,
+			expectedContains: `[{"id": "1", "name": "", "parent": null, "version":"", "description":"", "artefacts": {"file1": "abc"}, "created_at": "0001-01-01T00:00:00Z", "created_by": "", "config":null, "environment":null, "evaluation": null, "metadata": null, "dataset": ""}]`,
 		},
 		{
 			name:   "GET failure",
 			method: http.MethodGet,
-			listModelsFn: func() ([]*Model, error) {
+			listModelsFn: func(f ListFilter) ([]*Model, error) {
 				return nil, errors.New("boom")
 			},
 			expectedStatus:   http.StatusInternalServerError,
 			expectedContains: `{"code": 500, "error": "Failed to list models", "reason": "boom"}`,
 		},
+		{
+			name:   "GET success - filtered",
+			method: http.MethodGet,
+			query:  "?created_by=dev%7C1&created_after=2025-07-01T00:00:00Z",
+			listModelsFn: func(f ListFilter) ([]*Model, error) {
+				if f.CreatedBy != "dev|1" || f.CreatedAfter == nil || f.CreatedBefore != nil {
+					return nil, errors.New("unexpected filter")
+				}
+				return []*Model{}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `[]`,
+		},
+		{
+			name:             "GET failure - bad filter",
+			method:           http.MethodGet,
+			query:            "?created_before=yesterday",
+			listModelsFn:     nil,
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to list models", "reason": "invalid created_before: parsing time \"yesterday\" as \"2006-01-02T15:04:05Z07:00\": cannot parse \"yesterday\" as \"2006\""}`,
+		},
 		{
 			name:         "POST success",
 			method:       http.MethodPost,
 			body:         `{"id": "", "name": "name", "parent": null, "version": "version", "description": "description"}`,
 			listModelsFn: nil,
 			createModelFn: func(_ context.Context, r *Model) (*Model, error) {
-				return &Model{ID: "123", Name: "name", Parent: nil, Version: "version", Description: "description", UploadIds: map[string]string{}}, nil
+				return &Model{ID: "123", Name: "name", Parent: nil, Version: "version", Description: "description", UploadIds: map[string]string{}, CreatedAt: time.Date(2025, 7, 2, 9, 0, 0, 0, time.UTC), CreatedBy: "dev|1"}, nil
 			},
 			expectedStatus:   http.StatusCreated,
-			expectedContains: `{"id": "123", "name": "name", "parent": null, "version": "version", "description": "description", "artefacts": {}, "config":null, "environment":null, "evaluation": null, "metadata": null, "dataset": ""}`,
+			expectedContains: `{"id": "123", "name": "name", "parent": null, "version": "version", "description": "description", "artefacts": {}, "created_at": "2025-07-02T09:00:00Z", "created_by": "dev|1", "config":null, "environment":null, "evaluation": null, "metadata": null, "dataset": ""}`,
 		},
 		{
 			name:         "POST failure - unparseable request",
@@ -120,14 +174,14 @@ func TestRouter(t *testing.T) {
 				CreateFn: tc.createModelFn,
 				ListFn:   tc.listModelsFn,
 			}
-			handler := NewHandler(mockService, mockService)
+			handler := NewHandler(mockService, mockService, mockService, mockService, mockService)
 
 			var bodyReader io.Reader
 			if tc.body != "" {
 				bodyReader = strings.NewReader(tc.body)
 			}
 
-			req := httptest.NewRequest(tc.method, "/models", bodyReader)
+			req := httptest.NewRequest(tc.method, "/models"+tc.query, bodyReader)
 			rr := httptest.NewRecorder()
 
 			handler.Models(rr, req)
@@ -138,6 +192,270 @@ func TestRouter(t *testing.T) {
 	}
 }
 
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		verifyFn         func(id string) (*seal.Report, error)
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "valid",
+			method: http.MethodGet,
+			verifyFn: func(id string) (*seal.Report, error) {
+				return &seal.Report{ID: id, Valid: true, Chain: []*seal.Result{{Kind: "model", ID: id, Seal: "sha256:aa", Computed: "sha256:aa", Valid: true}}}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"id": "1", "valid": true, "chain": [{"kind": "model", "id": "1", "seal": "sha256:aa", "computed": "sha256:aa", "valid": true}]}`,
+		},
+		{
+			name:   "tampered",
+			method: http.MethodGet,
+			verifyFn: func(id string) (*seal.Report, error) {
+				return &seal.Report{ID: id, Valid: false, Chain: []*seal.Result{{Kind: "model", ID: id, Seal: "sha256:aa", Computed: "sha256:bb", Problems: []string{"stored seal does not match"}}}}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"id": "1", "valid": false, "chain": [{"kind": "model", "id": "1", "seal": "sha256:aa", "computed": "sha256:bb", "valid": false, "problems": ["stored seal does not match"]}]}`,
+		},
+		{
+			name:   "not found",
+			method: http.MethodGet,
+			verifyFn: func(id string) (*seal.Report, error) {
+				return nil, fmt.Errorf("model %s: %w", id, internal.ErrNotFound)
+			},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Model not found", "reason": "model 1: not found"}`,
+		},
+		{
+			name:   "failure",
+			method: http.MethodGet,
+			verifyFn: func(id string) (*seal.Report, error) {
+				return nil, errors.New("boom")
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedContains: `{"code": 500, "error": "Failed to verify model", "reason": "boom"}`,
+		},
+		{
+			name:             "METHOD failure",
+			method:           http.MethodPost,
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockService := &mockService{VerifyFn: tc.verifyFn}
+			handler := NewHandler(mockService, mockService, mockService, mockService, mockService)
+
+			req := httptest.NewRequest(tc.method, "/models/1/verify", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			rr := httptest.NewRecorder()
+
+			handler.Verify(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func TestPromoteHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		body             string
+		promoteFn        func(ctx context.Context, id string, to Stage) (*Model, error)
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "success",
+			method: http.MethodPost,
+			body:   `{"stage": "staging"}`,
+			promoteFn: func(ctx context.Context, id string, to Stage) (*Model, error) {
+				return &Model{ID: id, Stage: to}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"id": "1", "name": "", "parent": null, "version": "", "description": "", "artefacts": null, "created_at": "0001-01-01T00:00:00Z", "created_by": "", "config": null, "environment": null, "evaluation": null, "metadata": null, "dataset": "", "stage": "staging"}`,
+		},
+		{
+			name:             "unknown stage",
+			method:           http.MethodPost,
+			body:             `{"stage": "live"}`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to promote model", "reason": "unknown stage \"live\""}`,
+		},
+		{
+			name:             "bad body",
+			method:           http.MethodPost,
+			body:             `{`,
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to promote model", "reason": "could not parse body: unexpected EOF"}`,
+		},
+		{
+			name:   "not found",
+			method: http.MethodPost,
+			body:   `{"stage": "staging"}`,
+			promoteFn: func(ctx context.Context, id string, to Stage) (*Model, error) {
+				return nil, fmt.Errorf("model %s: %w", id, internal.ErrNotFound)
+			},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Model not found", "reason": "model 1: not found"}`,
+		},
+		{
+			name:   "refused",
+			method: http.MethodPost,
+			body:   `{"stage": "production"}`,
+			promoteFn: func(ctx context.Context, id string, to Stage) (*Model, error) {
+				return nil, fmt.Errorf("%w: unsigned", ErrPromotionRefused)
+			},
+			expectedStatus:   http.StatusConflict,
+			expectedContains: `{"code": 409, "error": "Failed to promote model", "reason": "promotion refused: unsigned"}`,
+		},
+		{
+			name:   "failure",
+			method: http.MethodPost,
+			body:   `{"stage": "production"}`,
+			promoteFn: func(ctx context.Context, id string, to Stage) (*Model, error) {
+				return nil, errors.New("boom")
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedContains: `{"code": 500, "error": "Failed to promote model", "reason": "boom"}`,
+		},
+		{
+			name:             "METHOD failure",
+			method:           http.MethodGet,
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockService := &mockService{PromoteFn: tc.promoteFn}
+			handler := NewHandler(mockService, mockService, mockService, mockService, mockService)
+
+			req := httptest.NewRequest(tc.method, "/models/1/promote", strings.NewReader(tc.body))
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			rr := httptest.NewRecorder()
+
+			handler.Promote(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func TestTrash(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		handle           func(h *Handler) http.HandlerFunc
+		deleteFn         func(ctx context.Context, id string, force bool) (*Model, error)
+		restoreFn        func(ctx context.Context, id string) (*Model, error)
+		purgeFn          func(ctx context.Context, id string) (*Model, error)
+		listFn           func(f ListFilter) ([]*Model, error)
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "DELETE in production",
+			method: http.MethodDelete,
+			path:   "/models/1",
+			handle: func(h *Handler) http.HandlerFunc { return h.Model },
+			deleteFn: func(_ context.Context, id string, force bool) (*Model, error) {
+				return nil, fmt.Errorf("model %s is %w by stage production", id, internal.ErrInUse)
+			},
+			expectedStatus:   http.StatusConflict,
+			expectedContains: `{"code": 409, "error": "Failed to delete model", "reason": "model 1 is still in use by stage production"}`,
+		},
+		{
+			name:   "DELETE not found",
+			method: http.MethodDelete,
+			path:   "/models/1?force=true",
+			handle: func(h *Handler) http.HandlerFunc { return h.Model },
+			deleteFn: func(_ context.Context, id string, force bool) (*Model, error) {
+				if !force {
+					return nil, errors.New("expected force")
+				}
+				return nil, fmt.Errorf("model %s: %w", id, internal.ErrNotFound)
+			},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Model not found", "reason": "model 1: not found"}`,
+		},
+		{
+			name:             "DELETE METHOD failure",
+			method:           http.MethodPut,
+			path:             "/models/1",
+			handle:           func(h *Handler) http.HandlerFunc { return h.Model },
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+		{
+			name:   "GET trash failure",
+			method: http.MethodGet,
+			path:   "/models/trash",
+			handle: func(h *Handler) http.HandlerFunc { return h.Trash },
+			listFn: func(f ListFilter) ([]*Model, error) {
+				return nil, errors.New("boom")
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedContains: `{"code": 500, "error": "Failed to list deleted models", "reason": "boom"}`,
+		},
+		{
+			name:   "restore purged",
+			method: http.MethodPost,
+			path:   "/models/1/restore",
+			handle: func(h *Handler) http.HandlerFunc { return h.Restore },
+			restoreFn: func(_ context.Context, id string) (*Model, error) {
+				return nil, fmt.Errorf("model %s is %w and can't be restored", id, internal.ErrPurged)
+			},
+			expectedStatus:   http.StatusConflict,
+			expectedContains: `{"code": 409, "error": "Failed to restore model", "reason": "model 1 is already purged and can't be restored"}`,
+		},
+		{
+			name:   "purge success",
+			method: http.MethodPost,
+			path:   "/models/1/purge",
+			handle: func(h *Handler) http.HandlerFunc { return h.Purge },
+			purgeFn: func(_ context.Context, id string) (*Model, error) {
+				purgedAt := time.Date(2025, 7, 7, 9, 0, 0, 0, time.UTC)
+				return &Model{ID: id, Stage: StageArchived, DeletedAt: &purgedAt, PurgedAt: &purgedAt}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"id": "1", "name": "", "parent": null, "version": "", "description": "", "artefacts": null, "created_at": "0001-01-01T00:00:00Z", "created_by": "", "config": null, "environment": null, "evaluation": null, "metadata": null, "dataset": "", "stage": "archived", "deleted_at": "2025-07-07T09:00:00Z", "purged_at": "2025-07-07T09:00:00Z"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockService := &mockService{
+				ListFn:    tc.listFn,
+				DeleteFn:  tc.deleteFn,
+				RestoreFn: tc.restoreFn,
+				PurgeFn:   tc.purgeFn,
+			}
+			handler := NewHandler(mockService, mockService, mockService, mockService, mockService)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			rr := httptest.NewRecorder()
+
+			tc.handle(handler)(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
 func checkResponse(t *testing.T, got *http.Response, expectedStatus int, expected string) {
 
 	defer got.Body.Close()
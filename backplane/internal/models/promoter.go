@@ -0,0 +1,115 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/heldtogether/traintrack/internal/audit"
+)
+
+/*
+ErrPromotionRefused is wrapped by a PromotionGuard, or the Promoter,
+when a model isn't allowed to move to the stage asked for.
+*/
+var ErrPromotionRefused = errors.New("promotion refused")
+
+/*
+PromotionGuard decides whether a model may be promoted. Guards return an
+error wrapping ErrPromotionRefused to block a promotion, and any other
+error if they couldn't decide.
+*/
+type PromotionGuard interface {
+	CheckPromotion(ctx context.Context, m *Model, to Stage) error
+}
+
+type modelsPromoter interface {
+	getWithQuerier(q Querier, id string) (*Model, error)
+	lockWithQuerier(q Querier, id string) (Stage, error)
+	promoteWithQuerier(q Querier, id string, from, to Stage) error
+}
+
+type DefaultPromoter struct {
+	s      modelsPromoter
+	db     TxBeginner
+	audit  AuditRecorder
+	guards []PromotionGuard
+}
+
+func NewPromoter(s *Store, db TxBeginner, a AuditRecorder, guards ...PromotionGuard) *DefaultPromoter {
+	return &DefaultPromoter{
+		s:      s,
+		db:     db,
+		audit:  a,
+		guards: guards,
+	}
+}
+
+/*
+Promote moves the model id to stage to, provided every guard allows it.
+The model is locked while the guards run, and the promotion is recorded in
+the audit log as part of the same transaction.
+*/
+func (p *DefaultPromoter) Promote(ctx context.Context, id string, to Stage) (promoted *Model, err error) {
+	if !to.Valid() {
+		return nil, fmt.Errorf("%w: unknown stage %q", ErrPromotionRefused, to)
+	}
+
+	tx, err := p.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	m, err := p.s.getWithQuerier(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	from, err := p.s.lockWithQuerier(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	m.Stage = from
+
+	if from == to {
+		return nil, fmt.Errorf("%w: model is already in %s", ErrPromotionRefused, to)
+	}
+	// A deleted model can still be archived, so that it can be purged.
+	if m.DeletedAt != nil && to != StageArchived {
+		return nil, fmt.Errorf("%w: model is in the trash", ErrPromotionRefused)
+	}
+	// As can a tainted one, so that it can be taken out of service.
+	if m.TaintedBy != nil && to != StageArchived {
+		return nil, fmt.Errorf("%w: model was tainted by erasure request %s", ErrPromotionRefused, *m.TaintedBy)
+	}
+
+	for _, g := range p.guards {
+		if err = g.CheckPromotion(ctx, m, to); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = p.s.promoteWithQuerier(tx, id, from, to); err != nil {
+		return nil, err
+	}
+
+	if p.audit != nil {
+		e := audit.NewEvent(ctx, audit.ActionPromote, audit.ResourceModel, id).
+			WithDetails(map[string]string{"from": string(from), "to": string(to)})
+		if err = p.audit.RecordWithQuerier(ctx, tx, e); err != nil {
+			return nil, fmt.Errorf("record audit event: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	m.Stage = to
+	return m, nil
+}
@@ -0,0 +1,204 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+type MockPromoterRepo struct {
+	GetFunc     func(id string) (*Model, error)
+	LockFunc    func(id string) (Stage, error)
+	PromoteFunc func(id string, from, to Stage) error
+}
+
+func (m *MockPromoterRepo) getWithQuerier(_ Querier, id string) (*Model, error) {
+	return m.GetFunc(id)
+}
+
+func (m *MockPromoterRepo) lockWithQuerier(_ Querier, id string) (Stage, error) {
+	return m.LockFunc(id)
+}
+
+func (m *MockPromoterRepo) promoteWithQuerier(_ Querier, id string, from, to Stage) error {
+	return m.PromoteFunc(id, from, to)
+}
+
+type guardFunc func(ctx context.Context, m *Model, to Stage) error
+
+func (f guardFunc) CheckPromotion(ctx context.Context, m *Model, to Stage) error {
+	return f(ctx, m, to)
+}
+
+func TestPromoter_Promote(t *testing.T) {
+	tests := []struct {
+		name        string
+		to          Stage
+		from        Stage
+		failGet     bool
+		deleted     bool
+		tainted     bool
+		failPromote bool
+		guardErr    error
+		wantCalled  []string
+		wantRefused bool
+		wantErr     bool
+	}{
+		{
+			name:       "success",
+			to:         StageStaging,
+			from:       StageDevelopment,
+			wantCalled: []string{"get", "lock", "guard", "promote development -> staging", "record-audit", "commit"},
+		},
+		{
+			name:        "unknown stage",
+			to:          Stage("live"),
+			wantRefused: true,
+			wantErr:     true,
+		},
+		{
+			name:       "model not found",
+			to:         StageStaging,
+			failGet:    true,
+			wantCalled: []string{"get", "rollback"},
+			wantErr:    true,
+		},
+		{
+			name:        "already in stage",
+			to:          StageStaging,
+			from:        StageStaging,
+			wantCalled:  []string{"get", "lock", "rollback"},
+			wantRefused: true,
+			wantErr:     true,
+		},
+		{
+			name:        "guard refuses",
+			to:          StageProduction,
+			from:        StageStaging,
+			guardErr:    fmt.Errorf("%w: unsigned", ErrPromotionRefused),
+			wantCalled:  []string{"get", "lock", "guard", "rollback"},
+			wantRefused: true,
+			wantErr:     true,
+		},
+		{
+			name:        "deleted model",
+			to:          StageProduction,
+			from:        StageStaging,
+			deleted:     true,
+			wantCalled:  []string{"get", "lock", "rollback"},
+			wantRefused: true,
+			wantErr:     true,
+		},
+		{
+			name:       "deleted model archived",
+			to:         StageArchived,
+			from:       StageProduction,
+			deleted:    true,
+			wantCalled: []string{"get", "lock", "guard", "promote production -> archived", "record-audit", "commit"},
+		},
+		{
+			name:        "tainted model",
+			to:          StageStaging,
+			from:        StageDevelopment,
+			tainted:     true,
+			wantCalled:  []string{"get", "lock", "rollback"},
+			wantRefused: true,
+			wantErr:     true,
+		},
+		{
+			name:        "promote fails",
+			to:          StageProduction,
+			from:        StageStaging,
+			failPromote: true,
+			wantCalled:  []string{"get", "lock", "guard", "promote staging -> production", "rollback"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var called []string
+
+			mockPgx, _ := pgxmock.NewConn()
+			baseTx, _ := mockPgx.Begin(context.Background())
+			tx := &loggingTx{Tx: baseTx, log: &called}
+
+			repo := &MockPromoterRepo{
+				GetFunc: func(id string) (*Model, error) {
+					called = append(called, "get")
+					if tc.failGet {
+						return nil, errors.New("get boom")
+					}
+					m := &Model{ID: id, Name: "regressor"}
+					if tc.deleted {
+						now := time.Now()
+						m.DeletedAt = &now
+					}
+					if tc.tainted {
+						erasure := "e1"
+						m.TaintedBy = &erasure
+					}
+					return m, nil
+				},
+				LockFunc: func(id string) (Stage, error) {
+					called = append(called, "lock")
+					return tc.from, nil
+				},
+				PromoteFunc: func(id string, from, to Stage) error {
+					called = append(called, fmt.Sprintf("promote %s -> %s", from, to))
+					if tc.failPromote {
+						return errors.New("promote boom")
+					}
+					return nil
+				},
+			}
+
+			var recorded *audit.Event
+			p := &DefaultPromoter{
+				s:  repo,
+				db: &mockDB{tx: tx},
+				audit: &MockAuditRecorder{
+					RecordFunc: func(e *audit.Event) error {
+						called = append(called, "record-audit")
+						recorded = e
+						return nil
+					},
+				},
+				guards: []PromotionGuard{guardFunc(func(ctx context.Context, m *Model, to Stage) error {
+					called = append(called, "guard")
+					if m.Stage != tc.from {
+						t.Errorf("guard saw stage %q, wanted %q", m.Stage, tc.from)
+					}
+					return tc.guardErr
+				})},
+			}
+
+			m, err := p.Promote(context.Background(), "1", tc.to)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got error %v, wanted error: %v", err, tc.wantErr)
+			}
+			if errors.Is(err, ErrPromotionRefused) != tc.wantRefused {
+				t.Errorf("got error %v, wanted refused: %v", err, tc.wantRefused)
+			}
+			if !reflect.DeepEqual(called, tc.wantCalled) {
+				t.Errorf("got calls %v, wanted %v", called, tc.wantCalled)
+			}
+
+			if tc.wantErr {
+				return
+			}
+			if m.Stage != tc.to {
+				t.Errorf("got stage %q, wanted %q", m.Stage, tc.to)
+			}
+			if recorded == nil || recorded.Action != audit.ActionPromote || string(recorded.Details) != fmt.Sprintf(`{"from":%q,"to":%q}`, tc.from, tc.to) {
+				t.Errorf("unexpected audit event: %+v", recorded)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+/*
+ScanGuard is a PromotionGuard which refuses to promote a model to any of
+its stages while one of its artefacts has been flagged by a scanner, or
+couldn't be scanned.
+*/
+type ScanGuard struct {
+	stages []Stage
+}
+
+/*
+NewScanGuard guards promotion to stages, or to staging and production if
+none are given.
+*/
+func NewScanGuard(stages ...Stage) *ScanGuard {
+	if len(stages) == 0 {
+		stages = []Stage{StageStaging, StageProduction}
+	}
+	return &ScanGuard{
+		stages: stages,
+	}
+}
+
+func (g *ScanGuard) CheckPromotion(ctx context.Context, m *Model, to Stage) error {
+	if !slices.Contains(g.stages, to) {
+		return nil
+	}
+
+	var problems []string
+	for name, results := range m.Scans {
+		for _, r := range results {
+			if r.Blocking() {
+				problems = append(problems, fmt.Sprintf("%s is %s by the %s scanner", name, r.Verdict, r.Scanner))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		slices.Sort(problems)
+		return fmt.Errorf("%w: %s", ErrPromotionRefused, strings.Join(problems, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/heldtogether/traintrack/internal/uploads"
+)
+
+func TestScanGuard(t *testing.T) {
+	clean := map[string][]uploads.ScanResult{"trained_model": {{Scanner: "pickle", Verdict: uploads.ScanClean}}}
+	flagged := map[string][]uploads.ScanResult{"trained_model": {{Scanner: "pickle", Verdict: uploads.ScanFlagged}}}
+	unscannable := map[string][]uploads.ScanResult{"trained_model": {{Scanner: "pickle", Verdict: uploads.ScanUnscannable}}}
+
+	tests := []struct {
+		name          string
+		scans         map[string][]uploads.ScanResult
+		to            Stage
+		expectedError string
+	}{
+		{name: "clean", scans: clean, to: StageProduction},
+		{name: "not scanned", to: StageProduction},
+		{name: "flagged", scans: flagged, to: StageProduction, expectedError: "promotion refused: trained_model is flagged by the pickle scanner"},
+		{name: "unscannable", scans: unscannable, to: StageStaging, expectedError: "promotion refused: trained_model is unscannable by the pickle scanner"},
+		{name: "unguarded stage", scans: flagged, to: StageArchived},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewScanGuard().CheckPromotion(context.Background(), &Model{ID: "1", Scans: tc.scans}, tc.to)
+
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.expectedError {
+				t.Errorf("expected error %q, got %v", tc.expectedError, err)
+			}
+			if !errors.Is(err, ErrPromotionRefused) {
+				t.Errorf("expected ErrPromotionRefused, got %v", err)
+			}
+		})
+	}
+}
@@ -3,11 +3,41 @@ package models
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/seal"
+	"github.com/heldtogether/traintrack/internal/uploads"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+/*
+Stage is where a model is in its release lifecycle. New models start in
+StageDevelopment and are moved on with a Promoter.
+*/
+type Stage string
+
+const (
+	StageDevelopment Stage = "development"
+	StageStaging     Stage = "staging"
+	StageProduction  Stage = "production"
+	StageArchived    Stage = "archived"
+)
+
+func (s Stage) Valid() bool {
+	switch s {
+	case StageDevelopment, StageStaging, StageProduction, StageArchived:
+		return true
+	}
+	return false
+}
+
 type Model struct {
 	ID          string  `json:"id"`
 	Name        string  `json:"name" validate:"required"`
@@ -23,37 +53,207 @@ type Model struct {
 	Metadata    json.RawMessage `json:"metadata"`
 	Environment json.RawMessage `json:"environment"`
 	Evaluation  json.RawMessage `json:"evaluation"`
+
+	// CreatedAt, CreatedBy and Tenant are set by the server from the
+	// verified token when the model is created. Any values sent by the
+	// client are ignored.
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+	Tenant    string    `json:"tenant,omitempty"`
+
+	// Seal is the tamper-evident hash of this version, chained to its
+	// parent's. See package seal.
+	Seal string `json:"seal,omitempty"`
+
+	// Stage is set by the server and can only be changed by promotion.
+	Stage Stage `json:"stage,omitempty"`
+
+	// Scans are the results of scanning each artefact when it was
+	// uploaded, keyed by artefact name.
+	Scans map[string][]uploads.ScanResult `json:"scans,omitempty"`
+
+	// DeletedAt is set while the model is in the trash, and PurgedAt once
+	// its artefacts have been permanently removed.
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	PurgedAt  *time.Time `json:"purged_at,omitempty"`
+
+	// TaintedBy is the first erasure request which found this model
+	// holds, or was derived from, data which had to be erased. See
+	// package erasure.
+	TaintedBy *string `json:"tainted_by,omitempty"`
+
+	// Held is set while the model is under a legal hold, which stops it
+	// being deleted or purged. See package holds.
+	Held bool `json:"held,omitempty"`
 }
 
-func (m *Model) GetID() string          { return m.ID }
-func (m *Model) GetName() string        { return m.Name }
-func (m *Model) GetDescription() string { return m.Description }
-func (m *Model) GetVersion() string     { return m.Version }
-func (m *Model) GetParent() *string     { return m.Parent }
+func (m *Model) GetID() string           { return m.ID }
+func (m *Model) GetName() string         { return m.Name }
+func (m *Model) GetDescription() string  { return m.Description }
+func (m *Model) GetVersion() string      { return m.Version }
+func (m *Model) GetParent() *string      { return m.Parent }
+func (m *Model) GetCreatedAt() time.Time { return m.CreatedAt }
+func (m *Model) GetCreatedBy() string    { return m.CreatedBy }
+
+/*
+GetMarkers returns the flags shown next to the model in trees: "tainted"
+once an erasure request has reached it, and "held" while it is under a
+legal hold.
+*/
+func (m *Model) GetMarkers() []string {
+	var markers []string
+	if m.TaintedBy != nil {
+		markers = append(markers, "tainted")
+	}
+	if m.Held {
+		markers = append(markers, "held")
+	}
+	return markers
+}
 
 const (
 	createQuery = `INSERT INTO 
-models (name, parent, version, description, dataset, config, metadata, environment, evaluation) 
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+models (name, parent, version, description, dataset, config, metadata, environment, evaluation, created_by, tenant) 
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), NULLIF($11, '')) RETURNING id, created_at, stage`
 	listQuery = `SELECT 
   m.id,
   m.name,
   m.parent,
   m.version,
   m.description,
+  m.created_at,
+  COALESCE(m.created_by, ''),
+  COALESCE(m.tenant, ''),
+  COALESCE(m.seal, ''),
+  m.stage,
+  m.deleted_at,
+  m.purged_at,
+  m.tainted_by::text,
+  m.evaluation,
+  EXISTS (
+    SELECT 1 FROM legal_holds h
+    WHERE h.resource_type = 'model' AND h.resource_id = m.id AND h.released_at IS NULL
+  ),
 	COALESCE(
     jsonb_object_agg(file_key, u.id) FILTER (WHERE file_key IS NOT NULL),
     '{}'::jsonb
-  ) AS artefacts
+  ) AS artefacts,
+	COALESCE(
+    jsonb_object_agg(file_key, u.files->file_key->'scans') FILTER (WHERE u.files->file_key ? 'scans'),
+    '{}'::jsonb
+  ) AS scans
 FROM models m
 LEFT JOIN uploads u ON u.model_id = m.id
-LEFT JOIN LATERAL jsonb_object_keys(u.files) AS file_key ON true
-GROUP BY m.id, m.name, m.parent, m.version, m.description;`
+LEFT JOIN LATERAL jsonb_object_keys(u.files) AS file_key ON true`
+	listGroupBy = `
+GROUP BY m.id, m.name, m.parent, m.version, m.description, m.created_at, m.created_by, m.tenant, m.seal, m.stage, m.deleted_at, m.purged_at, m.tainted_by, m.evaluation
+ORDER BY m.created_at, m.id;`
+	recordQuery = `SELECT 
+  m.id,
+  m.name,
+  m.parent,
+  m.version,
+  m.description,
+  COALESCE(m.dataset, ''),
+  COALESCE(ds.seal, ''),
+  m.config,
+  m.metadata,
+  m.environment,
+  m.evaluation,
+  m.created_at,
+  COALESCE(m.created_by, ''),
+  COALESCE(m.tenant, ''),
+  COALESCE(m.seal, ''),
+  COALESCE(p.seal, ''),
+  m.purged_at IS NOT NULL
+FROM models m
+LEFT JOIN models p ON p.id = m.parent
+LEFT JOIN datasets ds ON ds.id::text = m.dataset
+WHERE m.id = $1`
+	artefactsQuery = `SELECT id, files FROM uploads WHERE model_id = $1`
+	sealQuery      = `UPDATE models SET seal = $1 WHERE id = $2 AND seal IS NULL`
+	getClause      = `
+WHERE m.id = $1`
+	lockQuery        = `SELECT stage FROM models WHERE id = $1 FOR UPDATE`
+	promoteQuery     = `UPDATE models SET stage = $1 WHERE id = $2 AND stage = $3`
+	lockDeletedQuery = `SELECT deleted_at IS NOT NULL, purged_at IS NOT NULL, stage FROM models WHERE id = $1 AND COALESCE(tenant, '') = $2 FOR UPDATE`
+	childrenQuery    = `SELECT 'model ' || name || ' ' || version FROM models
+WHERE parent = $1 AND deleted_at IS NULL
+ORDER BY created_at, id`
+	holdsQuery = `SELECT holder FROM legal_holds
+WHERE resource_type = 'model' AND resource_id = $1 AND released_at IS NULL
+ORDER BY placed_at, id`
+	trashQuery   = `UPDATE models SET deleted_at = now() WHERE id = $1`
+	restoreQuery = `UPDATE models SET deleted_at = NULL WHERE id = $1`
+	purgeQuery   = `UPDATE models SET purged_at = now() WHERE id = $1`
 )
 
+/*
+sealedFields is the model metadata covered by its seal. The seal of the
+dataset it was trained on is included, pinning the model to that exact
+dataset version.
+*/
+type sealedFields struct {
+	Name        string          `json:"name"`
+	Version     string          `json:"version"`
+	Description string          `json:"description"`
+	Parent      *string         `json:"parent"`
+	Dataset     string          `json:"dataset"`
+	DatasetSeal string          `json:"dataset_seal"`
+	Config      json.RawMessage `json:"config"`
+	Metadata    json.RawMessage `json:"metadata"`
+	Environment json.RawMessage `json:"environment"`
+	Evaluation  json.RawMessage `json:"evaluation"`
+	CreatedAt   time.Time       `json:"created_at"`
+	CreatedBy   string          `json:"created_by"`
+	Tenant      string          `json:"tenant"`
+}
+
+/*
+ListFilter narrows down the models returned by List. Zero values match
+everything that hasn't been deleted. Deleted lists the trash instead.
+*/
+type ListFilter struct {
+	CreatedBy     string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Deleted       bool
+}
+
+/*
+sql builds the WHERE clause for the filter, returning it with its
+positional arguments.
+*/
+func (f ListFilter) sql() (string, []any) {
+	// Deleted versions are hidden everywhere but the trash.
+	conds := []string{"m.deleted_at IS NULL"}
+	if f.Deleted {
+		conds = []string{"m.deleted_at IS NOT NULL"}
+	}
+	var args []any
+
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if f.CreatedBy != "" {
+		add("m.created_by = $%d", f.CreatedBy)
+	}
+	if f.CreatedAfter != nil {
+		add("m.created_at >= $%d", *f.CreatedAfter)
+	}
+	if f.CreatedBefore != nil {
+		add("m.created_at < $%d", *f.CreatedBefore)
+	}
+
+	return "\nWHERE " + strings.Join(conds, " AND "), args
+}
+
 type Querier interface {
 	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
 }
 
 type Store struct {
@@ -88,10 +288,14 @@ func (s *Store) createWithQuerier(conn Querier, m *Model) (*Model, error) {
 		m.Metadata,
 		m.Environment,
 		m.Evaluation,
+		m.CreatedBy,
+		m.Tenant,
 	)
 
 	var id string
-	if err := row.Scan(&id); err != nil {
+	var createdAt time.Time
+	var stage Stage
+	if err := row.Scan(&id, &createdAt, &stage); err != nil {
 		return nil, err
 	}
 
@@ -101,16 +305,23 @@ func (s *Store) createWithQuerier(conn Querier, m *Model) (*Model, error) {
 		Parent:      m.Parent,
 		Version:     m.Version,
 		Description: m.Description,
+		CreatedAt:   createdAt,
+		CreatedBy:   m.CreatedBy,
+		Tenant:      m.Tenant,
+		Stage:       stage,
 	}, nil
 }
 
 /*
-List returns a list of all known Models.
+List returns a list of all known Models matching f, oldest first.
 */
-func (s *Store) List() ([]*Model, error) {
+func (s *Store) List(f ListFilter) ([]*Model, error) {
+	clause, args := f.sql()
+
 	rows, err := s.q.Query(
 		context.TODO(),
-		listQuery,
+		listQuery+clause+listGroupBy,
+		args...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("could not query models: %s", err)
@@ -120,15 +331,8 @@ func (s *Store) List() ([]*Model, error) {
 
 	ms := []*Model{}
 	for rows.Next() {
-		m := &Model{}
-		if err := rows.Scan(
-			&m.ID,
-			&m.Name,
-			&m.Parent,
-			&m.Version,
-			&m.Description,
-			&m.UploadIds,
-		); err != nil {
+		m, err := scanModel(rows)
+		if err != nil {
 			return nil, err
 		}
 		ms = append(ms, m)
@@ -137,3 +341,264 @@ func (s *Store) List() ([]*Model, error) {
 	return ms, nil
 
 }
+
+/*
+Get returns the model id, deleted or not, or an error wrapping internal.ErrNotFound if
+there is no such model.
+*/
+func (s *Store) Get(id string) (*Model, error) {
+	return s.getWithQuerier(s.q, id)
+}
+
+func (s *Store) getWithQuerier(q Querier, id string) (*Model, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("model %s: %w", id, internal.ErrNotFound)
+	}
+
+	row := q.QueryRow(context.Background(), listQuery+getClause+listGroupBy, id)
+	m, err := scanModel(row)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("model %s: %w", id, internal.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not query model: %w", err)
+	}
+	return m, nil
+}
+
+func scanModel(row pgx.Row) (*Model, error) {
+	m := &Model{}
+	if err := row.Scan(
+		&m.ID,
+		&m.Name,
+		&m.Parent,
+		&m.Version,
+		&m.Description,
+		&m.CreatedAt,
+		&m.CreatedBy,
+		&m.Tenant,
+		&m.Seal,
+		&m.Stage,
+		&m.DeletedAt,
+		&m.PurgedAt,
+		&m.TaintedBy,
+		&m.Evaluation,
+		&m.Held,
+		&m.UploadIds,
+		&m.Scans,
+	); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+/*
+Record returns everything covered by the seal of the model id, along with
+the seal stored for it.
+*/
+func (s *Store) Record(id string) (*seal.Record, error) {
+	return s.recordWithQuerier(s.q, id)
+}
+
+func (s *Store) recordWithQuerier(q Querier, id string) (*seal.Record, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("model %s: %w", id, internal.ErrNotFound)
+	}
+
+	var f sealedFields
+	r := &seal.Record{
+		Kind:      "model",
+		Fields:    &f,
+		Artefacts: map[string]seal.Artefact{},
+	}
+
+	row := q.QueryRow(context.Background(), recordQuery, id)
+	if err := row.Scan(
+		&r.ID,
+		&f.Name,
+		&f.Parent,
+		&f.Version,
+		&f.Description,
+		&f.Dataset,
+		&f.DatasetSeal,
+		&f.Config,
+		&f.Metadata,
+		&f.Environment,
+		&f.Evaluation,
+		&f.CreatedAt,
+		&f.CreatedBy,
+		&f.Tenant,
+		&r.Seal,
+		&r.ParentSeal,
+		&r.Purged,
+	); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("model %s: %w", id, internal.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not query model: %w", err)
+	}
+	// Seal in UTC so the hash doesn't depend on the server's time zone.
+	f.CreatedAt = f.CreatedAt.UTC()
+	if f.Parent != nil {
+		r.ParentID = *f.Parent
+	}
+
+	rows, err := q.Query(context.Background(), artefactsQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not query artefacts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var uploadID string
+		var files map[string]uploads.FileRef
+		if err := rows.Scan(&uploadID, &files); err != nil {
+			return nil, err
+		}
+		for name, file := range files {
+			r.Artefacts[name] = seal.Artefact{
+				Upload:   uploadID,
+				FileName: file.FileName,
+				Digest:   file.Digest,
+				Size:     file.Size,
+				Path:     file.Path,
+			}
+		}
+	}
+
+	return r, rows.Err()
+}
+
+// Don't export, sealing is part of creating a model.
+func (s *Store) sealWithQuerier(q Querier, id string, sealed string) error {
+	tag, err := q.Exec(context.Background(), sealQuery, sealed, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		return fmt.Errorf("model %s is already sealed", id)
+	}
+	return nil
+}
+
+// Don't export, we only want people using the designated promoter
+// struct to ensure that promotion guards are applied.
+func (s *Store) lockWithQuerier(q Querier, id string) (Stage, error) {
+	var stage Stage
+	if err := q.QueryRow(context.Background(), lockQuery, id).Scan(&stage); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("model %s: %w", id, internal.ErrNotFound)
+		}
+		return "", err
+	}
+	return stage, nil
+}
+
+// Don't export, we only want people using the designated promoter
+// struct to ensure that promotion guards are applied.
+func (s *Store) promoteWithQuerier(q Querier, id string, from, to Stage) error {
+	tag, err := q.Exec(context.Background(), promoteQuery, string(to), id, string(from))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() != 1 {
+		return fmt.Errorf("model %s is no longer in stage %s", id, from)
+	}
+	return nil
+}
+
+// Don't export, we only want people using the designated deleter
+// struct to ensure that references are checked. Another tenant's model is
+// reported as not found.
+func (s *Store) lockDeletedWithQuerier(q Querier, tenant string, id string) (deleted bool, purged bool, stage Stage, err error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return false, false, "", fmt.Errorf("model %s: %w", id, internal.ErrNotFound)
+	}
+	if err := q.QueryRow(context.Background(), lockDeletedQuery, id, tenant).Scan(&deleted, &purged, &stage); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, false, "", fmt.Errorf("model %s: %w", id, internal.ErrNotFound)
+		}
+		return false, false, "", err
+	}
+	return deleted, purged, stage, nil
+}
+
+/*
+childrenWithQuerier describes the models, which haven't been deleted,
+derived from the model id.
+*/
+func (s *Store) childrenWithQuerier(q Querier, id string) ([]string, error) {
+	return describeWithQuerier(q, childrenQuery, id)
+}
+
+/*
+holdsWithQuerier returns who holds each active legal hold on the model id.
+*/
+func (s *Store) holdsWithQuerier(q Querier, id string) ([]string, error) {
+	return describeWithQuerier(q, holdsQuery, id)
+}
+
+func describeWithQuerier(q Querier, query string, id string) ([]string, error) {
+	rows, err := q.Query(context.Background(), query, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not query references: %w", err)
+	}
+	defer rows.Close()
+
+	var refs []string
+	for rows.Next() {
+		var ref string
+		if err := rows.Scan(&ref); err != nil {
+			return nil, err
+		}
+		refs = append(refs, ref)
+	}
+	return refs, rows.Err()
+}
+
+// Don't export, we only want people using the designated deleter
+// struct to ensure that references are checked.
+func (s *Store) setDeletedWithQuerier(q Querier, id string, deleted bool) error {
+	query := restoreQuery
+	if deleted {
+		query = trashQuery
+	}
+	_, err := q.Exec(context.Background(), query, id)
+	return err
+}
+
+// Don't export, we only want people using the designated deleter
+// struct to ensure that the artefacts are removed.
+func (s *Store) purgeWithQuerier(q Querier, id string) error {
+	_, err := q.Exec(context.Background(), purgeQuery, id)
+	return err
+}
+
+/*
+filesWithQuerier returns every artefact stored for the model id.
+*/
+func (s *Store) filesWithQuerier(q Querier, id string) ([]uploads.FileRef, error) {
+	rows, err := q.Query(context.Background(), artefactsQuery, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not query artefacts: %w", err)
+	}
+	defer rows.Close()
+
+	var refs []uploads.FileRef
+	for rows.Next() {
+		var uploadID string
+		var files map[string]uploads.FileRef
+		if err := rows.Scan(&uploadID, &files); err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(files))
+		for name := range files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			refs = append(refs, files[name])
+		}
+	}
+	return refs, rows.Err()
+}
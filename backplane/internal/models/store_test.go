@@ -2,11 +2,17 @@ package models
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"reflect"
 	"regexp"
 	"testing"
+	"time"
 
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/seal"
+	"github.com/heldtogether/traintrack/internal/uploads"
+	"github.com/jackc/pgx/v5"
 	"github.com/pashagolub/pgxmock/v4"
 )
 
@@ -20,8 +26,8 @@ func TestList(t *testing.T) {
 	}
 	defer db.Close()
 
-	rows := db.NewRows([]string{"id", "name", "parent", "version", "description", "artefacts"}).
-		AddRow("1", "", nil, "", "", map[string]string{})
+	rows := db.NewRows([]string{"id", "name", "parent", "version", "description", "created_at", "created_by", "tenant", "seal", "stage", "deleted_at", "purged_at", "tainted_by", "evaluation", "held", "artefacts", "scans"}).
+		AddRow("1", "", nil, "", "", time.Time{}, "", "", "", "development", nil, nil, nil, nil, false, map[string]string{}, map[string][]uploads.ScanResult{})
 
 	db.ExpectQuery(
 		regexp.QuoteMeta(listQuery),
@@ -30,7 +36,7 @@ func TestList(t *testing.T) {
 
 	service := NewStore(db)
 
-	ms, err := service.List()
+	ms, err := service.List(ListFilter{})
 	if err != nil {
 		t.Errorf("could not list: %s", err)
 	}
@@ -43,6 +49,39 @@ func TestList(t *testing.T) {
 	}
 }
 
+func TestListWithFilter(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	after := time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	rows := db.NewRows([]string{"id", "name", "parent", "version", "description", "created_at", "created_by", "tenant", "seal", "stage", "deleted_at", "purged_at", "tainted_by", "evaluation", "held", "artefacts", "scans"}).
+		AddRow("1", "", nil, "", "", after, "dev|1", "", "sha256:aa", "development", nil, nil, nil, nil, false, map[string]string{}, map[string][]uploads.ScanResult{})
+
+	db.ExpectQuery(
+		regexp.QuoteMeta(listQuery+"\nWHERE m.deleted_at IS NULL AND m.created_by = $1 AND m.created_at >= $2"+listGroupBy),
+	).
+		WithArgs("dev|1", after).
+		WillReturnRows(rows)
+
+	service := NewStore(db)
+
+	got, err := service.List(ListFilter{CreatedBy: "dev|1", CreatedAfter: &after})
+	if err != nil {
+		t.Fatalf("could not list: %s", err)
+	}
+	if len(got) != 1 || got[0].CreatedBy != "dev|1" || !got[0].CreatedAt.Equal(after) {
+		t.Errorf("unexpected result: %+v", got)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestListFailOnQuery(t *testing.T) {
 	db, err := pgxmock.NewPool()
 	if err != nil {
@@ -57,7 +96,7 @@ func TestListFailOnQuery(t *testing.T) {
 
 	service := NewStore(db)
 
-	ms, err := service.List()
+	ms, err := service.List(ListFilter{})
 	if err == nil {
 		t.Errorf("could not list: %s", err)
 	}
@@ -87,7 +126,7 @@ func TestListFailOnScan(t *testing.T) {
 
 	service := NewStore(db)
 
-	ms, err := service.List()
+	ms, err := service.List(ListFilter{})
 	if err == nil {
 		t.Errorf("expected error from Scan, got nil")
 	}
@@ -107,6 +146,8 @@ func TestCreate(t *testing.T) {
 	}
 	defer db.Close()
 
+	createdAt := time.Date(2025, 7, 2, 9, 0, 0, 0, time.UTC)
+
 	db.ExpectQuery(
 		regexp.QuoteMeta(createQuery),
 	).
@@ -120,8 +161,10 @@ func TestCreate(t *testing.T) {
 			nilJSONBlob,
 			nilJSONBlob,
 			nilJSONBlob,
+			"dev|1",
+			"acme",
 		).
-		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("1"))
+		WillReturnRows(pgxmock.NewRows([]string{"id", "created_at", "stage"}).AddRow("1", createdAt, "development"))
 
 	service := NewStore(db)
 
@@ -131,6 +174,10 @@ func TestCreate(t *testing.T) {
 		Parent:      nil,
 		Version:     "1.0.0",
 		Description: "description",
+		CreatedAt:   createdAt,
+		CreatedBy:   "dev|1",
+		Tenant:      "acme",
+		Stage:       StageDevelopment,
 	}
 	got, err := service.create(
 		&Model{
@@ -138,6 +185,8 @@ func TestCreate(t *testing.T) {
 			Parent:      nil,
 			Version:     "1.0.0",
 			Description: "description",
+			CreatedBy:   "dev|1",
+			Tenant:      "acme",
 		})
 	if err != nil {
 		t.Errorf("unexpected error: %s", err)
@@ -174,8 +223,10 @@ func TestCreateFailOnScan(t *testing.T) {
 			nilJSONBlob,
 			nilJSONBlob,
 			nilJSONBlob,
+			"dev|1",
+			"acme",
 		).
-		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow(unscannable{}))
+		WillReturnRows(pgxmock.NewRows([]string{"id", "created_at", "stage"}).AddRow(unscannable{}, time.Time{}, "development"))
 
 	service := NewStore(db)
 
@@ -185,6 +236,8 @@ func TestCreateFailOnScan(t *testing.T) {
 			Parent:      nil,
 			Version:     "1.0.0",
 			Description: "description",
+			CreatedBy:   "dev|1",
+			Tenant:      "acme",
 		})
 	if err == nil {
 		t.Errorf("expected error from Scan, got nil")
@@ -197,3 +250,257 @@ func TestCreateFailOnScan(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestRecord(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	id := "9f9b8055-0000-4000-8000-000000000001"
+	parent := "9f9b8055-0000-4000-8000-000000000000"
+	createdAt := time.Date(2025, 7, 2, 9, 0, 0, 0, time.FixedZone("AEST", 10*60*60))
+
+	db.ExpectQuery(regexp.QuoteMeta(recordQuery)).
+		WithArgs(id).
+		WillReturnRows(db.NewRows([]string{"id", "name", "parent", "version", "description", "dataset", "dataset_seal", "config", "metadata", "environment", "evaluation", "created_at", "created_by", "tenant", "seal", "parent_seal", "purged"}).
+			AddRow(id, "prices", &parent, "1.0.1", "clean", "ds1", "sha256:ds", json.RawMessage(`{"a": 1}`), nilJSONBlob, nilJSONBlob, nilJSONBlob, createdAt, "dev|1", "acme", "sha256:child", "sha256:parent", false))
+
+	db.ExpectQuery(regexp.QuoteMeta(artefactsQuery)).
+		WithArgs(id).
+		WillReturnRows(db.NewRows([]string{"id", "files"}).
+			AddRow("u1", map[string]uploads.FileRef{
+				"train": {FileName: "train.csv", Path: "models/1", Digest: "sha256:ff", Size: 8},
+			}))
+
+	r, err := NewStore(db).Record(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if r.Kind != "model" || r.ID != id || r.ParentID != parent || r.Seal != "sha256:child" || r.ParentSeal != "sha256:parent" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	fields := r.Fields.(*sealedFields)
+	if fields.Name != "prices" || fields.DatasetSeal != "sha256:ds" || fields.CreatedAt.Location() != time.UTC {
+		t.Errorf("unexpected fields: %+v", fields)
+	}
+	want := seal.Artefact{Upload: "u1", FileName: "train.csv", Digest: "sha256:ff", Size: 8, Path: "models/1"}
+	if r.Artefacts["train"] != want {
+		t.Errorf("got artefact %+v, wanted %+v", r.Artefacts["train"], want)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRecordNotFound(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	id := "9f9b8055-0000-4000-8000-000000000001"
+	db.ExpectQuery(regexp.QuoteMeta(recordQuery)).
+		WithArgs(id).
+		WillReturnError(pgx.ErrNoRows)
+
+	service := NewStore(db)
+
+	if _, err := service.Record(id); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := service.Record("not-a-uuid"); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for malformed id, got %v", err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSeal(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.ExpectExec(regexp.QuoteMeta(sealQuery)).
+		WithArgs("sha256:aa", "1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	db.ExpectExec(regexp.QuoteMeta(sealQuery)).
+		WithArgs("sha256:bb", "1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	service := NewStore(db)
+
+	if err := service.sealWithQuerier(db, "1", "sha256:aa"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := service.sealWithQuerier(db, "1", "sha256:bb"); err == nil {
+		t.Errorf("expected resealing to fail")
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestGet(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	id := "9f9b8055-0000-4000-8000-000000000001"
+	db.ExpectQuery(regexp.QuoteMeta(listQuery + getClause + listGroupBy)).
+		WithArgs(id).
+		WillReturnRows(db.NewRows([]string{"id", "name", "parent", "version", "description", "created_at", "created_by", "tenant", "seal", "stage", "deleted_at", "purged_at", "tainted_by", "evaluation", "held", "artefacts", "scans"}).
+			AddRow(id, "regressor", nil, "1.0.0", "", time.Time{}, "dev|1", "acme", "sha256:aa", "staging", nil, nil, nil, nil, false, map[string]string{}, map[string][]uploads.ScanResult{}))
+	db.ExpectQuery(regexp.QuoteMeta(listQuery + getClause + listGroupBy)).
+		WithArgs(id).
+		WillReturnError(pgx.ErrNoRows)
+
+	service := NewStore(db)
+
+	m, err := service.Get(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if m.Stage != StageStaging || m.Tenant != "acme" {
+		t.Errorf("unexpected model: %+v", m)
+	}
+
+	if _, err := service.Get(id); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := service.Get("not-a-uuid"); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for malformed id, got %v", err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPromote(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.ExpectQuery(regexp.QuoteMeta(lockQuery)).
+		WithArgs("1").
+		WillReturnRows(db.NewRows([]string{"stage"}).AddRow("development"))
+	db.ExpectExec(regexp.QuoteMeta(promoteQuery)).
+		WithArgs("staging", "1", "development").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	db.ExpectExec(regexp.QuoteMeta(promoteQuery)).
+		WithArgs("production", "1", "development").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	service := NewStore(db)
+
+	stage, err := service.lockWithQuerier(db, "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if stage != StageDevelopment {
+		t.Errorf("got stage %q, wanted development", stage)
+	}
+	if err := service.promoteWithQuerier(db, "1", StageDevelopment, StageStaging); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := service.promoteWithQuerier(db, "1", StageDevelopment, StageProduction); err == nil {
+		t.Errorf("expected promotion from a stale stage to fail")
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestTrashQueries(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	id := "9f9b8055-0000-4000-8000-000000000001"
+	db.ExpectQuery(regexp.QuoteMeta(listQuery + "\nWHERE m.deleted_at IS NOT NULL" + listGroupBy)).
+		WillReturnRows(db.NewRows([]string{"id", "name", "parent", "version", "description", "created_at", "created_by", "tenant", "seal", "stage", "deleted_at", "purged_at", "tainted_by", "evaluation", "held", "artefacts", "scans"}))
+	db.ExpectQuery(regexp.QuoteMeta(lockDeletedQuery)).
+		WithArgs(id, "acme").
+		WillReturnRows(db.NewRows([]string{"deleted", "purged", "stage"}).AddRow(true, false, "production"))
+	db.ExpectQuery(regexp.QuoteMeta(childrenQuery)).
+		WithArgs(id).
+		WillReturnRows(db.NewRows([]string{"ref"}).AddRow("model regressor 1.0.1"))
+	db.ExpectExec(regexp.QuoteMeta(trashQuery)).
+		WithArgs(id).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	db.ExpectExec(regexp.QuoteMeta(restoreQuery)).
+		WithArgs(id).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	db.ExpectExec(regexp.QuoteMeta(purgeQuery)).
+		WithArgs(id).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	db.ExpectQuery(regexp.QuoteMeta(artefactsQuery)).
+		WithArgs(id).
+		WillReturnRows(db.NewRows([]string{"id", "files"}).
+			AddRow("u1", map[string]uploads.FileRef{
+				"trained_model": {FileName: "model.pkl", Path: "models/1"},
+				"config":        {FileName: "config.json", Path: "models/1"},
+			}))
+	db.ExpectQuery(regexp.QuoteMeta(lockDeletedQuery)).
+		WithArgs(id, "globex").
+		WillReturnRows(db.NewRows([]string{"deleted", "purged", "stage"}))
+
+	service := NewStore(db)
+
+	if ms, err := service.List(ListFilter{Deleted: true}); err != nil || len(ms) != 0 {
+		t.Errorf("got deleted models %+v, %v", ms, err)
+	}
+	deleted, purged, stage, err := service.lockDeletedWithQuerier(db, "acme", id)
+	if err != nil || !deleted || purged || stage != StageProduction {
+		t.Errorf("got %t, %t, %q, %v, wanted deleted production model", deleted, purged, stage, err)
+	}
+	children, err := service.childrenWithQuerier(db, id)
+	if err != nil || !reflect.DeepEqual(children, []string{"model regressor 1.0.1"}) {
+		t.Errorf("got children %v, %v", children, err)
+	}
+	if err := service.setDeletedWithQuerier(db, id, true); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := service.setDeletedWithQuerier(db, id, false); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := service.purgeWithQuerier(db, id); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	files, err := service.filesWithQuerier(db, id)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []uploads.FileRef{{FileName: "config.json", Path: "models/1"}, {FileName: "model.pkl", Path: "models/1"}}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("got files %+v, wanted %+v", files, want)
+	}
+
+	if _, _, _, err := service.lockDeletedWithQuerier(db, "globex", id); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for another tenant's model, got %v", err)
+	}
+	if _, _, _, err := service.lockDeletedWithQuerier(db, "acme", "not-a-uuid"); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for malformed id, got %v", err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"github.com/heldtogether/traintrack/internal/seal"
+)
+
+/*
+DefaultVerifier verifies models against the seals stored for them,
+re-reading their artefacts from storage.
+*/
+type DefaultVerifier struct {
+	s     *Store
+	files seal.FileReader
+}
+
+func NewVerifier(s *Store, f seal.FileReader) *DefaultVerifier {
+	return &DefaultVerifier{
+		s:     s,
+		files: f,
+	}
+}
+
+/*
+Verify checks the model id and each of its ancestors.
+*/
+func (v *DefaultVerifier) Verify(id string) (*seal.Report, error) {
+	return seal.VerifyChain(v.s.Record, v.files, id)
+}
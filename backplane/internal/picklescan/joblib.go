@@ -0,0 +1,129 @@
+package picklescan
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*
+isArrayWrapper reports whether obj is one of joblib's NumpyArrayWrappers.
+joblib writes each numpy array's data straight into the stream after the
+wrapper is built, rather than as pickle opcodes.
+*/
+func isArrayWrapper(obj *instance) bool {
+	class := obj.class
+	if class == (global{module: "copyreg", name: "_reconstructor"}) {
+		if args, ok := obj.args.(tuple); ok && len(args) > 0 {
+			class = args[0]
+		}
+	}
+	g, ok := class.(global)
+	return ok && g.name == "NumpyArrayWrapper" && strings.HasSuffix(g.module, "joblib.numpy_pickle")
+}
+
+/*
+skipArray steps over the array data following a NumpyArrayWrapper. Plain
+arrays are raw bytes, whose length comes from the wrapper's shape and
+dtype. Arrays of Python objects are a nested pickle, which is scanned.
+*/
+func (m *machine) skipArray(wrapper *instance) error {
+	m.joblib = true
+
+	state, ok := wrapper.state.(*dict)
+	if !ok {
+		return errors.New("joblib array has no state")
+	}
+
+	kind, itemSize, err := dtypeSize(state.get("dtype"))
+	if err != nil {
+		return err
+	}
+
+	if kind == 'O' {
+		if m.depth >= maxDepth {
+			return errors.New("joblib arrays are nested too deeply")
+		}
+		nested := newMachine(m.r, m.report)
+		nested.depth = m.depth + 1
+		if err := nested.runPickle(); err != nil {
+			return fmt.Errorf("joblib object array: %w", err)
+		}
+		return nil
+	}
+
+	if state.get("numpy_array_alignment_bytes") != nil {
+		padding, err := m.uint(1)
+		if err != nil {
+			return err
+		}
+		if err := m.skip(padding); err != nil {
+			return err
+		}
+	}
+
+	count := uint64(1)
+	shape, ok := state.get("shape").(tuple)
+	if !ok {
+		return errors.New("joblib array has no shape")
+	}
+	for _, d := range shape {
+		n, ok := d.(int64)
+		if !ok || n < 0 {
+			return errors.New("joblib array has an invalid shape")
+		}
+		if n != 0 && count > math.MaxUint64/uint64(n) {
+			return errors.New("joblib array is too large")
+		}
+		count *= uint64(n)
+	}
+	if itemSize != 0 && count > math.MaxUint64/itemSize {
+		return errors.New("joblib array is too large")
+	}
+
+	return m.skip(count * itemSize)
+}
+
+/*
+dtypeSize returns the kind and item size of a pickled numpy dtype, which
+is built from a type string such as "f8", "<U10" or "V24".
+*/
+func dtypeSize(v any) (byte, uint64, error) {
+	obj, ok := v.(*instance)
+	if !ok {
+		return 0, 0, errors.New("joblib array has no dtype")
+	}
+	args, ok := obj.args.(tuple)
+	if !ok || len(args) == 0 {
+		return 0, 0, errors.New("joblib array has no dtype")
+	}
+	s, ok := args[0].(string)
+	if !ok {
+		return 0, 0, errors.New("joblib array has an unreadable dtype")
+	}
+
+	s = strings.TrimLeft(s, "<>|=")
+	if s == "" {
+		return 0, 0, fmt.Errorf("unknown dtype %q", s)
+	}
+	kind := s[0]
+	if kind == 'O' {
+		return kind, 0, nil
+	}
+
+	n, err := strconv.ParseUint(s[1:], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("unknown dtype %q", s)
+	}
+	switch kind {
+	case 'U':
+		// Unicode strings are stored as UCS-4.
+		return kind, n * 4, nil
+	case 'b', 'i', 'u', 'f', 'c', 'S', 'a', 'V', 'M', 'm':
+		return kind, n, nil
+	default:
+		return 0, 0, fmt.Errorf("unknown dtype %q", s)
+	}
+}
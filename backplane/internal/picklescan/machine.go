@@ -0,0 +1,709 @@
+package picklescan
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const highestProtocol = 5
+
+const (
+	opMark           = '('
+	opStop           = '.'
+	opPop            = '0'
+	opPopMark        = '1'
+	opDup            = '2'
+	opFloat          = 'F'
+	opInt            = 'I'
+	opBinInt         = 'J'
+	opBinInt1        = 'K'
+	opLong           = 'L'
+	opBinInt2        = 'M'
+	opNone           = 'N'
+	opPersID         = 'P'
+	opBinPersID      = 'Q'
+	opReduce         = 'R'
+	opString         = 'S'
+	opBinString      = 'T'
+	opShortBinString = 'U'
+	opUnicode        = 'V'
+	opBinUnicode     = 'X'
+	opAppend         = 'a'
+	opBuild          = 'b'
+	opGlobal         = 'c'
+	opDict           = 'd'
+	opEmptyDict      = '}'
+	opAppends        = 'e'
+	opGet            = 'g'
+	opBinGet         = 'h'
+	opInst           = 'i'
+	opLongBinGet     = 'j'
+	opList           = 'l'
+	opEmptyList      = ']'
+	opObj            = 'o'
+	opPut            = 'p'
+	opBinPut         = 'q'
+	opLongBinPut     = 'r'
+	opSetItem        = 's'
+	opTuple          = 't'
+	opEmptyTuple     = ')'
+	opSetItems       = 'u'
+	opBinFloat       = 'G'
+	opBinBytes       = 'B'
+	opShortBinBytes  = 'C'
+	opProto          = 0x80
+	opNewObj         = 0x81
+	opExt1           = 0x82
+	opExt2           = 0x83
+	opExt4           = 0x84
+	opTuple1         = 0x85
+	opTuple2         = 0x86
+	opTuple3         = 0x87
+	opNewTrue        = 0x88
+	opNewFalse       = 0x89
+	opLong1          = 0x8a
+	opLong4          = 0x8b
+	opShortBinUni    = 0x8c
+	opBinUnicode8    = 0x8d
+	opBinBytes8      = 0x8e
+	opEmptySet       = 0x8f
+	opAddItems       = 0x90
+	opFrozenSet      = 0x91
+	opNewObjEx       = 0x92
+	opStackGlobal    = 0x93
+	opMemoize        = 0x94
+	opFrame          = 0x95
+	opByteArray8     = 0x96
+	opNextBuffer     = 0x97
+	opReadonlyBuffer = 0x98
+)
+
+const (
+	// maxKept is the longest string or bytes value kept on the stack.
+	// Longer ones are skipped; only short ones name globals or dtypes.
+	maxKept = 1 << 16
+	// maxDepth bounds how deeply object arrays may nest pickles.
+	maxDepth = 8
+)
+
+var errTruncated = errors.New("pickle stream is truncated")
+
+/*
+The machine's values. Only what's needed to follow imports, calls and
+joblib's array wrappers is modelled; everything else is opaque.
+*/
+type (
+	mark     struct{}
+	opaque   struct{}
+	tuple    []any
+	list     struct{ items []any }
+	dict     struct{ items [][2]any }
+	global   struct{ module, name string }
+	instance struct {
+		class any
+		args  any
+		state any
+	}
+)
+
+func (g global) String() string {
+	return g.module + "." + g.name
+}
+
+func (d *dict) get(key string) any {
+	for _, kv := range d.items {
+		if k, ok := kv[0].(string); ok && k == key {
+			return kv[1]
+		}
+	}
+	return nil
+}
+
+type machine struct {
+	r      *bufio.Reader
+	report *Report
+	stack  []any
+	memo   map[uint64]any
+	depth  int
+	joblib bool
+}
+
+func newMachine(r *bufio.Reader, report *Report) *machine {
+	return &machine{r: r, report: report, memo: map[uint64]any{}}
+}
+
+/*
+run runs every pickle in the stream until it ends. Python will load
+pickles written back to back one after the other, so what follows the
+first STOP is scanned too, and anything there that isn't a pickle makes
+the stream unscannable.
+*/
+func (m *machine) run() error {
+	for n := 1; ; n++ {
+		if err := m.runPickle(); err != nil {
+			if n == 1 {
+				return err
+			}
+			return fmt.Errorf("pickle %d in the stream: %w", n, err)
+		}
+
+		if _, err := m.r.Peek(1); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		m.stack = nil
+		m.memo = map[uint64]any{}
+	}
+}
+
+/*
+runPickle runs a single pickle, up to and including its STOP.
+*/
+func (m *machine) runPickle() error {
+	for {
+		op, err := m.r.ReadByte()
+		if err == io.EOF {
+			return errTruncated
+		}
+		if err != nil {
+			return err
+		}
+		if op == opStop {
+			return nil
+		}
+		if err := m.step(op); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return errTruncated
+			}
+			return err
+		}
+	}
+}
+
+func (m *machine) step(op byte) error {
+	switch op {
+	case opProto:
+		v, err := m.r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if v > highestProtocol {
+			return fmt.Errorf("unsupported pickle protocol %d", v)
+		}
+	case opFrame:
+		_, err := m.uint(8)
+		return err
+
+	case opMark:
+		m.push(mark{})
+	case opPop:
+		_, err := m.pop()
+		return err
+	case opPopMark:
+		_, err := m.popMark()
+		return err
+	case opDup:
+		v, err := m.top()
+		if err != nil {
+			return err
+		}
+		m.push(v)
+
+	case opNone:
+		m.push(nil)
+	case opNewTrue:
+		m.push(true)
+	case opNewFalse:
+		m.push(false)
+	case opInt, opLong:
+		line, err := m.line()
+		if err != nil {
+			return err
+		}
+		switch line {
+		case "00":
+			m.push(false)
+		case "01":
+			m.push(true)
+		default:
+			n, err := strconv.ParseInt(strings.TrimSuffix(line, "L"), 10, 64)
+			if err != nil {
+				m.push(opaque{})
+			} else {
+				m.push(n)
+			}
+		}
+	case opBinInt:
+		n, err := m.uint(4)
+		if err != nil {
+			return err
+		}
+		m.push(int64(int32(n)))
+	case opBinInt1:
+		n, err := m.uint(1)
+		if err != nil {
+			return err
+		}
+		m.push(int64(n))
+	case opBinInt2:
+		n, err := m.uint(2)
+		if err != nil {
+			return err
+		}
+		m.push(int64(n))
+	case opLong1, opLong4:
+		size := 1
+		if op == opLong4 {
+			size = 4
+		}
+		n, err := m.uint(size)
+		if err != nil {
+			return err
+		}
+		data, err := m.bytes(n)
+		if err != nil {
+			return err
+		}
+		m.push(littleEndianInt(data))
+	case opFloat:
+		if _, err := m.line(); err != nil {
+			return err
+		}
+		m.push(opaque{})
+	case opBinFloat:
+		if _, err := m.bytes(8); err != nil {
+			return err
+		}
+		m.push(opaque{})
+
+	case opString, opUnicode:
+		line, err := m.line()
+		if err != nil {
+			return err
+		}
+		if op == opString {
+			if s, err := strconv.Unquote(line); err == nil {
+				line = s
+			} else {
+				line = strings.Trim(line, `'"`)
+			}
+		}
+		m.push(line)
+	case opShortBinString, opShortBinBytes, opShortBinUni:
+		return m.pushBytes(op, 1)
+	case opBinString, opBinBytes, opBinUnicode:
+		return m.pushBytes(op, 4)
+	case opBinUnicode8, opBinBytes8, opByteArray8:
+		return m.pushBytes(op, 8)
+	case opNextBuffer, opReadonlyBuffer:
+		if op == opNextBuffer {
+			m.push(opaque{})
+		}
+
+	case opEmptyTuple:
+		m.push(tuple{})
+	case opTuple:
+		items, err := m.popMark()
+		if err != nil {
+			return err
+		}
+		m.push(tuple(items))
+	case opTuple1, opTuple2, opTuple3:
+		n := int(op-opTuple1) + 1
+		if len(m.stack) < n {
+			return errors.New("stack underflow")
+		}
+		items := append(tuple{}, m.stack[len(m.stack)-n:]...)
+		m.stack = m.stack[:len(m.stack)-n]
+		m.push(items)
+
+	case opEmptyList:
+		m.push(&list{})
+	case opList:
+		items, err := m.popMark()
+		if err != nil {
+			return err
+		}
+		m.push(&list{items: items})
+	case opAppend:
+		v, err := m.pop()
+		if err != nil {
+			return err
+		}
+		return m.extend([]any{v})
+	case opAppends:
+		items, err := m.popMark()
+		if err != nil {
+			return err
+		}
+		return m.extend(items)
+
+	case opEmptyDict:
+		m.push(&dict{})
+	case opDict:
+		items, err := m.popMark()
+		if err != nil {
+			return err
+		}
+		d := &dict{}
+		for i := 0; i+1 < len(items); i += 2 {
+			d.items = append(d.items, [2]any{items[i], items[i+1]})
+		}
+		m.push(d)
+	case opSetItem:
+		v, err := m.pop()
+		if err != nil {
+			return err
+		}
+		k, err := m.pop()
+		if err != nil {
+			return err
+		}
+		return m.setItems([]any{k, v})
+	case opSetItems:
+		items, err := m.popMark()
+		if err != nil {
+			return err
+		}
+		return m.setItems(items)
+
+	case opEmptySet:
+		m.push(opaque{})
+	case opAddItems:
+		if _, err := m.popMark(); err != nil {
+			return err
+		}
+	case opFrozenSet:
+		if _, err := m.popMark(); err != nil {
+			return err
+		}
+		m.push(opaque{})
+
+	case opGet, opBinGet, opLongBinGet:
+		idx, err := m.memoIndex(op, opGet, opBinGet)
+		if err != nil {
+			return err
+		}
+		v, ok := m.memo[idx]
+		if !ok {
+			return fmt.Errorf("memo %d is used before it is set", idx)
+		}
+		m.push(v)
+	case opPut, opBinPut, opLongBinPut:
+		idx, err := m.memoIndex(op, opPut, opBinPut)
+		if err != nil {
+			return err
+		}
+		v, err := m.top()
+		if err != nil {
+			return err
+		}
+		m.memo[idx] = v
+	case opMemoize:
+		v, err := m.top()
+		if err != nil {
+			return err
+		}
+		m.memo[uint64(len(m.memo))] = v
+
+	case opGlobal:
+		module, err := m.line()
+		if err != nil {
+			return err
+		}
+		name, err := m.line()
+		if err != nil {
+			return err
+		}
+		m.push(m.global(module, name))
+	case opStackGlobal:
+		name, err := m.pop()
+		if err != nil {
+			return err
+		}
+		module, err := m.pop()
+		if err != nil {
+			return err
+		}
+		ms, ok1 := module.(string)
+		ns, ok2 := name.(string)
+		if !ok1 || !ok2 {
+			m.flag("", "imports a global whose name is computed at load time")
+			m.push(opaque{})
+			return nil
+		}
+		m.push(m.global(ms, ns))
+	case opExt1, opExt2, opExt4:
+		size := map[byte]int{opExt1: 1, opExt2: 2, opExt4: 4}[op]
+		code, err := m.uint(size)
+		if err != nil {
+			return err
+		}
+		m.flag("", fmt.Sprintf("imports extension %d, which can't be resolved without running Python", code))
+		m.push(opaque{})
+
+	case opReduce:
+		args, err := m.pop()
+		if err != nil {
+			return err
+		}
+		callable, err := m.pop()
+		if err != nil {
+			return err
+		}
+		m.push(m.call(callable, args))
+	case opNewObj:
+		args, err := m.pop()
+		if err != nil {
+			return err
+		}
+		class, err := m.pop()
+		if err != nil {
+			return err
+		}
+		m.push(m.call(class, args))
+	case opNewObjEx:
+		if _, err := m.pop(); err != nil {
+			return err
+		}
+		args, err := m.pop()
+		if err != nil {
+			return err
+		}
+		class, err := m.pop()
+		if err != nil {
+			return err
+		}
+		m.push(m.call(class, args))
+	case opInst:
+		module, err := m.line()
+		if err != nil {
+			return err
+		}
+		name, err := m.line()
+		if err != nil {
+			return err
+		}
+		args, err := m.popMark()
+		if err != nil {
+			return err
+		}
+		m.push(m.call(m.global(module, name), tuple(args)))
+	case opObj:
+		items, err := m.popMark()
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return errors.New("stack underflow")
+		}
+		m.push(m.call(items[0], tuple(items[1:])))
+	case opBuild:
+		state, err := m.pop()
+		if err != nil {
+			return err
+		}
+		v, err := m.top()
+		if err != nil {
+			return err
+		}
+		if obj, ok := v.(*instance); ok {
+			obj.state = state
+			if isArrayWrapper(obj) {
+				return m.skipArray(obj)
+			}
+		}
+
+	case opPersID:
+		if _, err := m.line(); err != nil {
+			return err
+		}
+		m.push(opaque{})
+	case opBinPersID:
+		if _, err := m.pop(); err != nil {
+			return err
+		}
+		m.push(opaque{})
+
+	default:
+		return fmt.Errorf("unknown opcode 0x%02x", op)
+	}
+	return nil
+}
+
+func (m *machine) global(module, name string) global {
+	g := global{module: module, name: name}
+	m.report.Imports = append(m.report.Imports, g.String())
+	if reason := dangerous(module, name); reason != "" {
+		m.report.Findings = append(m.report.Findings, Finding{Global: g.String(), Reason: reason})
+	}
+	return g
+}
+
+/*
+call records what calling callable with args would make. Calls of
+anything other than an imported global, such as the result of another
+call, are how gadget chains reach dangerous functions without importing
+them, so they're flagged.
+*/
+func (m *machine) call(callable any, args any) *instance {
+	switch callable.(type) {
+	case global:
+	case *instance:
+		m.flag("", "calls the result of another call")
+	default:
+		m.flag("", "calls something which isn't an imported global")
+	}
+	return &instance{class: callable, args: args}
+}
+
+func (m *machine) flag(global, reason string) {
+	m.report.Findings = append(m.report.Findings, Finding{Global: global, Reason: reason})
+}
+
+func (m *machine) push(v any) {
+	m.stack = append(m.stack, v)
+}
+
+func (m *machine) pop() (any, error) {
+	if len(m.stack) == 0 {
+		return nil, errors.New("stack underflow")
+	}
+	v := m.stack[len(m.stack)-1]
+	m.stack = m.stack[:len(m.stack)-1]
+	if _, ok := v.(mark); ok {
+		return nil, errors.New("unexpected mark")
+	}
+	return v, nil
+}
+
+func (m *machine) top() (any, error) {
+	if len(m.stack) == 0 {
+		return nil, errors.New("stack underflow")
+	}
+	return m.stack[len(m.stack)-1], nil
+}
+
+func (m *machine) popMark() ([]any, error) {
+	for i := len(m.stack) - 1; i >= 0; i-- {
+		if _, ok := m.stack[i].(mark); ok {
+			items := append([]any{}, m.stack[i+1:]...)
+			m.stack = m.stack[:i]
+			return items, nil
+		}
+	}
+	return nil, errors.New("no mark on the stack")
+}
+
+func (m *machine) extend(items []any) error {
+	v, err := m.top()
+	if err != nil {
+		return err
+	}
+	if l, ok := v.(*list); ok {
+		l.items = append(l.items, items...)
+	}
+	return nil
+}
+
+func (m *machine) setItems(items []any) error {
+	v, err := m.top()
+	if err != nil {
+		return err
+	}
+	if d, ok := v.(*dict); ok {
+		for i := 0; i+1 < len(items); i += 2 {
+			d.items = append(d.items, [2]any{items[i], items[i+1]})
+		}
+	}
+	return nil
+}
+
+func (m *machine) line() (string, error) {
+	line, err := m.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+func (m *machine) uint(size int) (uint64, error) {
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(m.r, buf[:size]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(buf), nil
+}
+
+func (m *machine) bytes(n uint64) ([]byte, error) {
+	if n > maxKept {
+		return nil, m.skip(n)
+	}
+	buf := make([]byte, n)
+	_, err := io.ReadFull(m.r, buf)
+	return buf, err
+}
+
+func (m *machine) skip(n uint64) error {
+	skipped, err := io.CopyN(io.Discard, m.r, int64(n))
+	if err == io.EOF || (err == nil && uint64(skipped) < n) {
+		return errTruncated
+	}
+	return err
+}
+
+func (m *machine) pushBytes(op byte, size int) error {
+	n, err := m.uint(size)
+	if err != nil {
+		return err
+	}
+	data, err := m.bytes(n)
+	if err != nil {
+		return err
+	}
+	switch {
+	case data == nil && n > 0:
+		m.push(opaque{})
+	case op == opShortBinUni || op == opBinUnicode || op == opBinUnicode8 ||
+		op == opShortBinString || op == opBinString:
+		m.push(string(data))
+	default:
+		m.push(opaque{})
+	}
+	return nil
+}
+
+func (m *machine) memoIndex(op, textOp, shortOp byte) (uint64, error) {
+	switch op {
+	case textOp:
+		line, err := m.line()
+		if err != nil {
+			return 0, err
+		}
+		return strconv.ParseUint(line, 10, 64)
+	case shortOp:
+		return m.uint(1)
+	default:
+		return m.uint(4)
+	}
+}
+
+func littleEndianInt(data []byte) any {
+	if len(data) > 8 {
+		return opaque{}
+	}
+	buf := make([]byte, 8)
+	copy(buf, data)
+	if len(data) > 0 && data[len(data)-1]&0x80 != 0 {
+		for i := len(data); i < 8; i++ {
+			buf[i] = 0xff
+		}
+	}
+	return int64(binary.LittleEndian.Uint64(buf))
+}
@@ -0,0 +1,195 @@
+/*
+Package picklescan statically inspects pickle streams, such as the
+pickle and joblib files the Python SDK stores trained models in, for
+imports and opcodes which would let loading the file run arbitrary code.
+
+Nothing is ever unpickled. The stream is walked by a small stack machine
+which records every global the pickle would import and every call it
+would make, so joblib's raw numpy buffers can be skipped and PyTorch's
+zip archives opened, without evaluating anything.
+*/
+package picklescan
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+)
+
+/*
+Verdict is the outcome of a scan.
+*/
+type Verdict string
+
+const (
+	// VerdictClean means nothing dangerous was found.
+	VerdictClean Verdict = "clean"
+	// VerdictFlagged means loading the file could run arbitrary code.
+	VerdictFlagged Verdict = "flagged"
+	// VerdictUnscannable means the file couldn't be fully parsed, so
+	// nothing can be said about it.
+	VerdictUnscannable Verdict = "unscannable"
+)
+
+/*
+Finding is one reason a file was flagged.
+*/
+type Finding struct {
+	// Global is the module.name involved, if any.
+	Global string
+	Reason string
+}
+
+func (f Finding) String() string {
+	if f.Global == "" {
+		return f.Reason
+	}
+	return f.Global + ": " + f.Reason
+}
+
+/*
+Report is what a scan found.
+*/
+type Report struct {
+	// Format is what the file was read as: pickle, joblib or torch, with
+	// any compression it was wrapped in.
+	Format   string
+	Imports  []string
+	Findings []Finding
+	// Err is why the file couldn't be scanned, if it couldn't.
+	Err error
+}
+
+func (r *Report) Verdict() Verdict {
+	switch {
+	case len(r.Findings) > 0:
+		return VerdictFlagged
+	case r.Err != nil:
+		return VerdictUnscannable
+	default:
+		return VerdictClean
+	}
+}
+
+var ErrNotPickle = errors.New("not a pickle stream")
+
+var extensions = []string{".pkl", ".pickle", ".joblib", ".jl", ".pt", ".pth", ".ckpt", ".dill", ".sav"}
+
+/*
+Applies reports whether a file should be scanned, from its name or, when
+that doesn't say, the first bytes of its contents.
+*/
+func Applies(filename string, head []byte) bool {
+	if slices.Contains(extensions, strings.ToLower(path.Ext(filename))) {
+		return true
+	}
+	// Pickle protocols 2 and above start with PROTO.
+	return len(head) >= 2 && head[0] == opProto && head[1] >= 2 && head[1] <= highestProtocol
+}
+
+/*
+Scan inspects the size bytes of r. Compressed joblib files and PyTorch
+zip archives are unwrapped first.
+*/
+func Scan(r io.ReaderAt, size int64) *Report {
+	report := &Report{}
+	report.Err = scan(report, io.NewSectionReader(r, 0, size), size)
+
+	slices.Sort(report.Imports)
+	report.Imports = slices.Compact(report.Imports)
+	return report
+}
+
+func scan(report *Report, r *io.SectionReader, size int64) error {
+	head := make([]byte, 6)
+	n, _ := io.ReadFull(r, head)
+	head = head[:n]
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		report.Format = "torch"
+		return scanZip(report, r, size)
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		report.Format = "joblib+gzip"
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		return scanStream(report, zr)
+	case bytes.HasPrefix(head, []byte("BZh")):
+		report.Format = "joblib+bz2"
+		return scanStream(report, bzip2.NewReader(r))
+	case len(head) >= 2 && head[0] == 0x78 && (uint16(head[0])<<8|uint16(head[1]))%31 == 0:
+		report.Format = "joblib+zlib"
+		zr, err := zlib.NewReader(r)
+		if err != nil {
+			return err
+		}
+		return scanStream(report, zr)
+	case bytes.HasPrefix(head, []byte{0xfd, '7', 'z', 'X', 'Z', 0}):
+		report.Format = "joblib+xz"
+		return errors.New("xz compressed files can't be scanned")
+	case bytes.HasPrefix(head, []byte{0x04, 0x22, 0x4d, 0x18}):
+		report.Format = "joblib+lz4"
+		return errors.New("lz4 compressed files can't be scanned")
+	default:
+		report.Format = "pickle"
+		return scanStream(report, r)
+	}
+}
+
+/*
+scanZip scans every pickle in a PyTorch archive. Tensor data is stored
+alongside them and is never loaded as a pickle.
+*/
+func scanZip(report *Report, r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, f := range zr.File {
+		if path.Ext(f.Name) != ".pkl" {
+			continue
+		}
+		found = true
+
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = scanStream(report, rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+	}
+	if !found {
+		return fmt.Errorf("archive has no pickles: %w", ErrNotPickle)
+	}
+	return nil
+}
+
+func scanStream(report *Report, r io.Reader) error {
+	m := newMachine(bufio.NewReader(r), report)
+	if err := m.run(); err != nil {
+		return err
+	}
+	if m.joblib {
+		report.Format = strings.Replace(report.Format, "pickle", "joblib", 1)
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package picklescan
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/binary"
+	"slices"
+	"testing"
+)
+
+/*
+Helpers to write pickle opcodes by hand, so the tests don't need Python.
+*/
+func unicode(s string) []byte {
+	b := []byte{opBinUnicode}
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(s)))
+	return append(b, s...)
+}
+
+func stackGlobal(module, name string) []byte {
+	return cat(unicode(module), unicode(name), []byte{opStackGlobal})
+}
+
+func cat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+/*
+joblibArray is what joblib writes for a float64 numpy array: a pickled
+NumpyArrayWrapper, then alignment padding and the raw data.
+*/
+func joblibArray(data []byte) []byte {
+	dtype := cat(
+		[]byte("cnumpy\ndtype\n"),
+		unicode("f8"), []byte{opNewFalse, opNewTrue, opTuple3, opReduce},
+		[]byte{opMark, opBinInt1, 3}, unicode("<"), []byte{opNone, opNone, opNone},
+		[]byte{opBinInt, 0xff, 0xff, 0xff, 0xff, opBinInt, 0xff, 0xff, 0xff, 0xff, opBinInt1, 0, opTuple, opBuild},
+	)
+	state := cat(
+		[]byte{opEmptyDict, opMark},
+		unicode("subclass"), []byte("cnumpy\nndarray\n"),
+		unicode("shape"), []byte{opBinInt1, byte(len(data) / 8), opTuple1},
+		unicode("order"), unicode("C"),
+		unicode("dtype"), dtype,
+		unicode("allow_mmap"), []byte{opNewTrue},
+		unicode("numpy_array_alignment_bytes"), []byte{opBinInt1, 16},
+		[]byte{opSetItems},
+	)
+	return cat(
+		[]byte("cjoblib.numpy_pickle\nNumpyArrayWrapper\n"),
+		[]byte{opEmptyTuple, opNewObj},
+		state,
+		[]byte{opBuild},
+		[]byte{3, 0xff, 0xff, 0xff},
+		data,
+	)
+}
+
+var (
+	cleanPickle = cat(
+		[]byte{opProto, 4},
+		stackGlobal("sklearn.linear_model._base", "LinearRegression"),
+		[]byte{opEmptyTuple, opNewObj, opEmptyDict},
+		unicode("fit_intercept"), []byte{opNewTrue, opSetItem, opBuild, opStop},
+	)
+
+	// Looks like "cos\nsystem\n..." if the array data isn't skipped.
+	joblibPickle = cat(
+		[]byte{opProto, 2, opEmptyDict},
+		unicode("coef_"),
+		joblibArray([]byte("cos\nsystem\n(S'id'\ntR.\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")),
+		[]byte{opSetItem, opStop},
+	)
+)
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name            string
+		data            []byte
+		expectedVerdict Verdict
+		expectedFormat  string
+		expectedFinding string
+	}{
+		{
+			name:            "clean",
+			data:            cleanPickle,
+			expectedVerdict: VerdictClean,
+			expectedFormat:  "pickle",
+		},
+		{
+			name:            "os.system, protocol 0",
+			data:            []byte("cos\nsystem\n(S'echo pwned'\ntR."),
+			expectedVerdict: VerdictFlagged,
+			expectedFormat:  "pickle",
+			expectedFinding: "os.system: can run shell commands and change files",
+		},
+		{
+			name:            "posix.system, protocol 4",
+			data:            cat([]byte{opProto, 4}, stackGlobal("posix", "system"), unicode("id"), []byte{opTuple1, opReduce, opStop}),
+			expectedVerdict: VerdictFlagged,
+			expectedFormat:  "pickle",
+			expectedFinding: "posix.system: can run shell commands and change files",
+		},
+		{
+			name:            "subprocess",
+			data:            cat([]byte{opProto, 2}, []byte("csubprocess\nPopen\n"), unicode("id"), []byte{opTuple1, opReduce, opStop}),
+			expectedVerdict: VerdictFlagged,
+			expectedFormat:  "pickle",
+			expectedFinding: "subprocess.Popen: runs other programs",
+		},
+		{
+			name:            "builtins.eval",
+			data:            cat([]byte{opProto, 2}, []byte("c__builtin__\neval\n"), unicode("1+1"), []byte{opTuple1, opReduce, opStop}),
+			expectedVerdict: VerdictFlagged,
+			expectedFormat:  "pickle",
+			expectedFinding: "__builtin__.eval: runs Python code",
+		},
+		{
+			name:            "INST",
+			data:            []byte("(S'id'\nios\nsystem\n."),
+			expectedVerdict: VerdictFlagged,
+			expectedFormat:  "pickle",
+			expectedFinding: "os.system: can run shell commands and change files",
+		},
+		{
+			name:            "computed import",
+			data:            cat([]byte{opProto, 4}, stackGlobal("builtins", "str"), []byte{opEmptyTuple, opReduce}, unicode("system"), []byte{opStackGlobal, opStop}),
+			expectedVerdict: VerdictFlagged,
+			expectedFormat:  "pickle",
+			expectedFinding: "imports a global whose name is computed at load time",
+		},
+		{
+			name:            "extension registry",
+			data:            []byte{opProto, 2, opExt1, 7, opEmptyTuple, opReduce, opStop},
+			expectedVerdict: VerdictFlagged,
+			expectedFormat:  "pickle",
+			expectedFinding: "imports extension 7, which can't be resolved without running Python",
+		},
+		{
+			name:            "calling a call",
+			data:            cat([]byte{opProto, 2}, []byte("cbuiltins\nstr\n"), []byte{opEmptyTuple, opReduce, opEmptyTuple, opReduce, opStop}),
+			expectedVerdict: VerdictFlagged,
+			expectedFormat:  "pickle",
+			expectedFinding: "calls the result of another call",
+		},
+		{
+			name:            "joblib array data is skipped",
+			data:            joblibPickle,
+			expectedVerdict: VerdictClean,
+			expectedFormat:  "joblib",
+		},
+		{
+			name:            "dangerous second pickle",
+			data:            cat(cleanPickle, []byte("cos\nsystem\n(S'id'\ntR.")),
+			expectedVerdict: VerdictFlagged,
+			expectedFormat:  "pickle",
+			expectedFinding: "os.system: can run shell commands and change files",
+		},
+		{
+			name:            "trailing garbage",
+			data:            cat(cleanPickle, []byte{0xff, 0xfe}),
+			expectedVerdict: VerdictUnscannable,
+			expectedFormat:  "pickle",
+		},
+		{
+			name:            "truncated",
+			data:            cleanPickle[:len(cleanPickle)-1],
+			expectedVerdict: VerdictUnscannable,
+			expectedFormat:  "pickle",
+		},
+		{
+			name:            "not a pickle",
+			data:            []byte("a,b\n1,2\n"),
+			expectedVerdict: VerdictUnscannable,
+			expectedFormat:  "pickle",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			report := Scan(bytes.NewReader(tc.data), int64(len(tc.data)))
+
+			if report.Verdict() != tc.expectedVerdict {
+				t.Errorf("verdict %s, want %s (findings %v, error %v)", report.Verdict(), tc.expectedVerdict, report.Findings, report.Err)
+			}
+			if report.Format != tc.expectedFormat {
+				t.Errorf("format %q, want %q", report.Format, tc.expectedFormat)
+			}
+			if tc.expectedFinding != "" && !slices.ContainsFunc(report.Findings, func(f Finding) bool { return f.String() == tc.expectedFinding }) {
+				t.Errorf("expected finding %q in %v", tc.expectedFinding, report.Findings)
+			}
+		})
+	}
+}
+
+func TestScan_Imports(t *testing.T) {
+	report := Scan(bytes.NewReader(joblibPickle), int64(len(joblibPickle)))
+
+	expected := []string{"joblib.numpy_pickle.NumpyArrayWrapper", "numpy.dtype", "numpy.ndarray"}
+	if !slices.Equal(report.Imports, expected) {
+		t.Errorf("imports %v, want %v", report.Imports, expected)
+	}
+}
+
+func TestScan_Compressed(t *testing.T) {
+	evil := []byte("cos\nsystem\n(S'id'\ntR.")
+
+	var gz, zl bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write(evil)
+	w.Close()
+	z := zlib.NewWriter(&zl)
+	z.Write(joblibPickle)
+	z.Close()
+
+	tests := []struct {
+		name            string
+		data            []byte
+		expectedVerdict Verdict
+		expectedFormat  string
+	}{
+		{name: "gzip", data: gz.Bytes(), expectedVerdict: VerdictFlagged, expectedFormat: "joblib+gzip"},
+		{name: "zlib", data: zl.Bytes(), expectedVerdict: VerdictClean, expectedFormat: "joblib+zlib"},
+		{name: "xz", data: []byte{0xfd, '7', 'z', 'X', 'Z', 0, 1, 2}, expectedVerdict: VerdictUnscannable, expectedFormat: "joblib+xz"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			report := Scan(bytes.NewReader(tc.data), int64(len(tc.data)))
+			if report.Verdict() != tc.expectedVerdict {
+				t.Errorf("verdict %s, want %s (findings %v, error %v)", report.Verdict(), tc.expectedVerdict, report.Findings, report.Err)
+			}
+			if report.Format != tc.expectedFormat {
+				t.Errorf("format %q, want %q", report.Format, tc.expectedFormat)
+			}
+		})
+	}
+}
+
+func TestScan_Torch(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, _ := zw.Create("model/data.pkl")
+	f.Write([]byte("cos\nsystem\n(S'id'\ntR."))
+	f, _ = zw.Create("model/data/0")
+	f.Write([]byte("cos\nsystem\n"))
+	zw.Close()
+
+	report := Scan(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if report.Format != "torch" || report.Verdict() != VerdictFlagged {
+		t.Errorf("got %s %s, want a flagged torch file", report.Format, report.Verdict())
+	}
+	if len(report.Findings) != 1 {
+		t.Errorf("expected only data.pkl to be scanned, got findings %v", report.Findings)
+	}
+}
+
+func TestApplies(t *testing.T) {
+	tests := []struct {
+		filename string
+		head     []byte
+		expected bool
+	}{
+		{filename: "model.joblib", expected: true},
+		{filename: "model.PKL", expected: true},
+		{filename: "weights.pt", expected: true},
+		{filename: "model", head: []byte{opProto, 4, opFrame}, expected: true},
+		{filename: "train.csv", head: []byte("a,b")},
+		{filename: "blob.bin", head: []byte{opProto, 9}},
+	}
+
+	for _, tc := range tests {
+		if got := Applies(tc.filename, tc.head); got != tc.expected {
+			t.Errorf("Applies(%q, %x) = %t, want %t", tc.filename, tc.head, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package picklescan
+
+import "strings"
+
+/*
+dangerousModules are modules any import from which is flagged, along with
+their submodules.
+*/
+var dangerousModules = map[string]string{
+	"os":                  "can run shell commands and change files",
+	"posix":               "can run shell commands and change files",
+	"nt":                  "can run shell commands and change files",
+	"subprocess":          "runs other programs",
+	"pty":                 "runs other programs",
+	"commands":            "runs shell commands",
+	"popen2":              "runs shell commands",
+	"sys":                 "can change the running interpreter",
+	"shutil":              "can change files",
+	"socket":              "opens network connections",
+	"ssl":                 "opens network connections",
+	"http":                "opens network connections",
+	"urllib":              "opens network connections",
+	"urllib2":             "opens network connections",
+	"requests":            "opens network connections",
+	"httplib":             "opens network connections",
+	"ftplib":              "opens network connections",
+	"smtplib":             "opens network connections",
+	"telnetlib":           "opens network connections",
+	"webbrowser":          "opens URLs",
+	"runpy":               "runs Python modules",
+	"code":                "runs Python code",
+	"codeop":              "compiles Python code",
+	"importlib":           "imports arbitrary modules",
+	"imp":                 "imports arbitrary modules",
+	"pickle":              "loads a nested pickle",
+	"_pickle":             "loads a nested pickle",
+	"cPickle":             "loads a nested pickle",
+	"dill":                "loads a nested pickle",
+	"marshal":             "loads compiled code",
+	"ctypes":              "calls native code",
+	"cffi":                "calls native code",
+	"multiprocessing":     "runs other processes",
+	"asyncio":             "runs other programs and opens network connections",
+	"signal":              "changes how the process handles signals",
+	"tempfile":            "creates files",
+	"glob":                "reads the file system",
+	"pdb":                 "runs Python code",
+	"bdb":                 "runs Python code",
+	"timeit":              "runs Python code",
+	"trace":               "runs Python code",
+	"profile":             "runs Python code",
+	"cProfile":            "runs Python code",
+	"pydoc":               "runs shell commands",
+	"venv":                "runs other programs",
+	"ensurepip":           "installs packages",
+	"pip":                 "installs packages",
+	"setuptools":          "runs build scripts",
+	"distutils":           "runs build scripts",
+	"torch.hub":           "downloads and runs code",
+	"torch.serialization": "loads a nested model file",
+	"numpy.testing":       "runs Python code",
+}
+
+/*
+dangerousGlobals are single functions which are flagged, where the rest
+of their module is commonly and legitimately imported by pickles.
+*/
+var dangerousGlobals = map[string]string{
+	"builtins.eval":         "runs Python code",
+	"builtins.exec":         "runs Python code",
+	"builtins.compile":      "compiles Python code",
+	"builtins.open":         "opens files",
+	"builtins.__import__":   "imports arbitrary modules",
+	"builtins.getattr":      "looks up arbitrary attributes, such as os.system",
+	"builtins.setattr":      "changes arbitrary attributes",
+	"builtins.delattr":      "changes arbitrary attributes",
+	"builtins.globals":      "exposes the interpreter's globals",
+	"builtins.locals":       "exposes the interpreter's locals",
+	"builtins.vars":         "exposes arbitrary objects' attributes",
+	"builtins.input":        "reads from the terminal",
+	"builtins.breakpoint":   "starts a debugger",
+	"builtins.help":         "starts a pager",
+	"builtins.exit":         "exits the interpreter",
+	"builtins.quit":         "exits the interpreter",
+	"builtins.execfile":     "runs Python code",
+	"builtins.file":         "opens files",
+	"builtins.apply":        "calls arbitrary functions",
+	"builtins.reload":       "imports arbitrary modules",
+	"operator.attrgetter":   "looks up arbitrary attributes, such as os.system",
+	"operator.methodcaller": "calls arbitrary methods",
+	"functools.reduce":      "calls arbitrary functions",
+	"types.CodeType":        "builds Python code",
+	"types.FunctionType":    "builds Python functions",
+	"types.ModuleType":      "builds Python modules",
+	"platform.popen":        "runs shell commands",
+	"numpy.load":            "loads a nested pickle",
+	"joblib.load":           "loads a nested pickle",
+	"torch.load":            "loads a nested pickle",
+	"pandas.read_pickle":    "loads a nested pickle",
+	"copyreg.add_extension": "changes how other imports resolve",
+	"shelve.open":           "loads nested pickles",
+	"linecache.getline":     "reads files",
+	"io.open":               "opens files",
+	"io.FileIO":             "opens files",
+	"zipimport.zipimporter": "imports arbitrary modules",
+}
+
+/*
+aliases maps modules to the names the checks use for them.
+*/
+var aliases = map[string]string{
+	"__builtin__": "builtins",
+	"_operator":   "operator",
+	"_functools":  "functools",
+	"copy_reg":    "copyreg",
+	"_io":         "io",
+}
+
+/*
+dangerous returns why importing module.name is dangerous, or "" if it
+isn't known to be.
+*/
+func dangerous(module, name string) string {
+	if a, ok := aliases[module]; ok {
+		module = a
+	}
+
+	if reason, ok := dangerousGlobals[module+"."+name]; ok {
+		return reason
+	}
+
+	for m := module; m != ""; {
+		if reason, ok := dangerousModules[m]; ok {
+			return reason
+		}
+		i := strings.LastIndex(m, ".")
+		if i < 0 {
+			break
+		}
+		m = m[:i]
+	}
+	return ""
+}
@@ -0,0 +1,20 @@
+/*
+Package provenance describes how a model version was made, as an in-toto
+attestation carrying a SLSA provenance predicate.
+
+The attestation is generated from exactly what was sealed for the model
+and the dataset it was trained on (see package seal), so it can be
+reproduced at any time and never disagrees with the seal chain. Its
+subjects are the model's artefacts and its resolved dependencies are the
+dataset's artefacts, the training code captured in the model's metadata
+and the packages installed in its environment:
+
+	stmt, err := provenance.Generate(model, dataset)
+	...
+	env, err := provenance.Sign(stmt, key)
+
+Attestations are wrapped in a DSSE envelope, signed with the backplane's
+attestation key when one is configured, so that they can be checked away
+from the server.
+*/
+package provenance
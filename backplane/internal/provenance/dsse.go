@@ -0,0 +1,117 @@
+package provenance
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/heldtogether/traintrack/internal/signing"
+)
+
+const PayloadType = "application/vnd.in-toto+json"
+
+var ErrUnsigned = errors.New("attestation is not signed")
+
+/*
+Envelope is a DSSE envelope carrying a Statement.
+*/
+type Envelope struct {
+	PayloadType string              `json:"payloadType"`
+	Payload     string              `json:"payload"`
+	Signatures  []EnvelopeSignature `json:"signatures"`
+}
+
+type EnvelopeSignature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+/*
+Sign wraps stmt in an envelope signed with key. A nil key gives an
+unsigned envelope.
+*/
+func Sign(stmt *Statement, key ed25519.PrivateKey) (*Envelope, error) {
+	payload, err := json.Marshal(stmt)
+	if err != nil {
+		return nil, err
+	}
+
+	env := &Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures:  []EnvelopeSignature{},
+	}
+	if key != nil {
+		env.Signatures = append(env.Signatures, EnvelopeSignature{
+			KeyID: signing.KeyID(key.Public().(ed25519.PublicKey)),
+			Sig:   base64.StdEncoding.EncodeToString(ed25519.Sign(key, pae(PayloadType, payload))),
+		})
+	}
+
+	return env, nil
+}
+
+/*
+Verify checks e was signed by pub.
+*/
+func (e *Envelope) Verify(pub ed25519.PublicKey) error {
+	if len(e.Signatures) == 0 {
+		return ErrUnsigned
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(e.Payload)
+	if err != nil {
+		return fmt.Errorf("invalid payload: %w", err)
+	}
+
+	keyID := signing.KeyID(pub)
+	for _, s := range e.Signatures {
+		if s.KeyID != keyID {
+			continue
+		}
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err != nil {
+			return fmt.Errorf("%w: %s", signing.ErrInvalidSignature, err)
+		}
+		if ed25519.Verify(pub, pae(e.PayloadType, payload), sig) {
+			return nil
+		}
+		return fmt.Errorf("%w: does not match the attestation", signing.ErrInvalidSignature)
+	}
+
+	return fmt.Errorf("%w: no signature by key %s", signing.ErrUntrustedKey, keyID)
+}
+
+/*
+Statement decodes the statement carried by e. It doesn't check the
+signature.
+*/
+func (e *Envelope) Statement() (*Statement, error) {
+	if e.PayloadType != PayloadType {
+		return nil, fmt.Errorf("unexpected payload type %q", e.PayloadType)
+	}
+
+	payload, err := base64.StdEncoding.DecodeString(e.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid payload: %w", err)
+	}
+
+	var stmt Statement
+	if err := json.Unmarshal(payload, &stmt); err != nil {
+		return nil, fmt.Errorf("invalid statement: %w", err)
+	}
+	if stmt.Type != StatementType || stmt.PredicateType != PredicateType {
+		return nil, fmt.Errorf("unexpected statement %s with predicate %s", stmt.Type, stmt.PredicateType)
+	}
+	return &stmt, nil
+}
+
+/*
+pae is DSSE's pre-authentication encoding, which is what's actually
+signed.
+*/
+func pae(payloadType string, payload []byte) []byte {
+	return fmt.Appendf(nil, "DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload)
+}
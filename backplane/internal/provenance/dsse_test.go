@@ -0,0 +1,61 @@
+package provenance
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/heldtogether/traintrack/internal/signing"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stmt, err := Generate(testModel(), testDataset())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := Sign(stmt, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := env.Verify(pub); err != nil {
+		t.Errorf("expected envelope to verify: %s", err)
+	}
+	if err := env.Verify(other); !errors.Is(err, signing.ErrUntrustedKey) {
+		t.Errorf("expected another key to be rejected, got %v", err)
+	}
+
+	decoded, err := env.Statement()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Predicate.RunDetails.Metadata.InvocationID != "m1" {
+		t.Errorf("statement did not round trip: %+v", decoded)
+	}
+
+	tampered := *env
+	tampered.Payload = base64.StdEncoding.EncodeToString([]byte(`{"_type":"` + StatementType + `"}`))
+	if err := tampered.Verify(pub); !errors.Is(err, signing.ErrInvalidSignature) {
+		t.Errorf("expected tampered payload to fail, got %v", err)
+	}
+
+	unsigned, err := Sign(stmt, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := unsigned.Verify(pub); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("expected ErrUnsigned, got %v", err)
+	}
+}
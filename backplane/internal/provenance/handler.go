@@ -0,0 +1,108 @@
+package provenance
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/signing"
+)
+
+/*
+Attester produces signed provenance for a model.
+*/
+type Attester interface {
+	Attest(id string) (*Envelope, error)
+	PublicKey() (*signing.Key, error)
+}
+
+type Handler struct {
+	a Attester
+}
+
+func NewHandler(a Attester) *Handler {
+	return &Handler{
+		a: a,
+	}
+}
+
+/*
+Provenance returns the model's provenance as a DSSE envelope. It should
+be registered under /models/{id}/provenance.
+*/
+func (h *Handler) Provenance(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	env, err := h.a.Attest(id)
+	if err != nil {
+		code := http.StatusInternalServerError
+		message := "Failed to generate provenance"
+		if errors.Is(err, internal.ErrNotFound) {
+			code = http.StatusNotFound
+			message = "Model not found"
+		}
+		log.Printf("failed to generate provenance for model %s: %s", id, err)
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    code,
+			Message: message,
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.dsse.envelope.v1+json")
+	json.NewEncoder(w).Encode(env)
+}
+
+/*
+Key returns the public key attestations are signed with. It should be
+registered under /provenance/key.
+*/
+func (h *Handler) Key(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w)
+		return
+	}
+
+	k, err := h.a.PublicKey()
+	if err != nil {
+		log.Printf("failed to read attestation key: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to read attestation key",
+			Reason:  err.Error(),
+		})
+		return
+	}
+	if k == nil {
+		log.Printf("no attestation key configured")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusNotFound,
+			Message: "Attestations are not signed",
+			Reason:  "no attestation key is configured",
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(k)
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    http.StatusMethodNotAllowed,
+		Message: "Method not allowed",
+		Reason:  "",
+	})
+}
@@ -0,0 +1,161 @@
+package provenance
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/signing"
+)
+
+type mockAttester struct {
+	AttestFn    func(id string) (*Envelope, error)
+	PublicKeyFn func() (*signing.Key, error)
+}
+
+func (m *mockAttester) Attest(id string) (*Envelope, error) {
+	return m.AttestFn(id)
+}
+
+func (m *mockAttester) PublicKey() (*signing.Key, error) {
+	return m.PublicKeyFn()
+}
+
+func TestProvenanceHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		attestFn         func(id string) (*Envelope, error)
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "success",
+			method: http.MethodGet,
+			attestFn: func(id string) (*Envelope, error) {
+				return &Envelope{PayloadType: PayloadType, Payload: "e30=", Signatures: []EnvelopeSignature{{KeyID: "abc", Sig: "c2ln"}}}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"payloadType": "application/vnd.in-toto+json", "payload": "e30=", "signatures": [{"keyid": "abc", "sig": "c2ln"}]}`,
+		},
+		{
+			name:   "not found",
+			method: http.MethodGet,
+			attestFn: func(id string) (*Envelope, error) {
+				return nil, fmt.Errorf("model %s: %w", id, internal.ErrNotFound)
+			},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Model not found", "reason": "model 1: not found"}`,
+		},
+		{
+			name:   "failure",
+			method: http.MethodGet,
+			attestFn: func(id string) (*Envelope, error) {
+				return nil, errors.New("model 1 has not been sealed")
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedContains: `{"code": 500, "error": "Failed to generate provenance", "reason": "model 1 has not been sealed"}`,
+		},
+		{
+			name:             "METHOD failure",
+			method:           http.MethodPost,
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewHandler(&mockAttester{AttestFn: tc.attestFn})
+
+			req := httptest.NewRequest(tc.method, "/models/1/provenance", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			rr := httptest.NewRecorder()
+
+			handler.Provenance(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func TestKeyHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		publicKeyFn      func() (*signing.Key, error)
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name: "configured",
+			publicKeyFn: func() (*signing.Key, error) {
+				return &signing.Key{ID: "abc", Name: "attestation", PublicKey: "PEM"}, nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"id": "abc", "name": "attestation", "public_key": "PEM", "created_at": "0001-01-01T00:00:00Z", "created_by": ""}`,
+		},
+		{
+			name: "not configured",
+			publicKeyFn: func() (*signing.Key, error) {
+				return nil, nil
+			},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Attestations are not signed", "reason": "no attestation key is configured"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewHandler(&mockAttester{PublicKeyFn: tc.publicKeyFn})
+
+			req := httptest.NewRequest(http.MethodGet, "/provenance/key", nil)
+			rr := httptest.NewRecorder()
+
+			handler.Key(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func checkResponse(t *testing.T, got *http.Response, expectedStatus int, expected string) {
+
+	defer got.Body.Close()
+
+	body, err := io.ReadAll(got.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %s", err)
+	}
+
+	if expectedStatus != got.StatusCode {
+		t.Errorf("status mismatch - wanted %d, got %d",
+			expectedStatus,
+			got.StatusCode,
+		)
+	}
+
+	var gotData any
+	if err := json.Unmarshal(body, &gotData); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v\nbody: %s", err, string(body))
+	}
+
+	var expectedData any
+	if err := json.Unmarshal([]byte(expected), &expectedData); err != nil {
+		t.Fatalf("failed to unmarshal expected value: %v\njson: %s", err, string(expected))
+	}
+
+	if !reflect.DeepEqual(expectedData, gotData) {
+		t.Errorf("JSON mismatch:\nexpected: %+v\ngot: %+v", expectedData, gotData)
+	}
+}
@@ -0,0 +1,79 @@
+package provenance
+
+import (
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/heldtogether/traintrack/internal/seal"
+	"github.com/heldtogether/traintrack/internal/signing"
+)
+
+type Service struct {
+	models   seal.Getter
+	datasets seal.Getter
+	key      ed25519.PrivateKey
+}
+
+/*
+NewService attests to models using the sealed records returned by models
+and datasets. Attestations are signed with key, unless it is nil.
+*/
+func NewService(models seal.Getter, datasets seal.Getter, key ed25519.PrivateKey) *Service {
+	return &Service{
+		models:   models,
+		datasets: datasets,
+		key:      key,
+	}
+}
+
+/*
+Attest returns the signed provenance of the model id.
+*/
+func (s *Service) Attest(id string) (*Envelope, error) {
+	m, err := s.models(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var f modelFields
+	if err := m.DecodeFields(&f); err != nil {
+		return nil, err
+	}
+
+	var ds *seal.Record
+	if f.Dataset != "" {
+		if ds, err = s.datasets(f.Dataset); err != nil {
+			// Not %w: a missing dataset isn't a missing model.
+			return nil, fmt.Errorf("could not load dataset %s: %s", f.Dataset, err)
+		}
+	}
+
+	stmt, err := Generate(m, ds)
+	if err != nil {
+		return nil, err
+	}
+
+	return Sign(stmt, s.key)
+}
+
+/*
+PublicKey returns the key attestations are signed with, or nil if they
+aren't signed.
+*/
+func (s *Service) PublicKey() (*signing.Key, error) {
+	if s.key == nil {
+		return nil, nil
+	}
+
+	pub := s.key.Public().(ed25519.PublicKey)
+	data, err := signing.MarshalPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	return &signing.Key{
+		ID:        signing.KeyID(pub),
+		Name:      "attestation",
+		PublicKey: string(data),
+	}, nil
+}
@@ -0,0 +1,331 @@
+package provenance
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/seal"
+)
+
+const (
+	StatementType  = "https://in-toto.io/Statement/v1"
+	PredicateType  = "https://slsa.dev/provenance/v1"
+	BuildType      = "https://github.com/heldtogether/traintrack/model-training/v1"
+	BuilderID      = "https://github.com/heldtogether/traintrack/backplane"
+	sourceSuffix   = "_source"
+	dependencyKey  = "dependencies"
+	sealDigestName = "traintrack-seal"
+)
+
+/*
+Statement is an in-toto v1 statement whose predicate is SLSA provenance.
+*/
+type Statement struct {
+	Type          string               `json:"_type"`
+	Subject       []ResourceDescriptor `json:"subject"`
+	PredicateType string               `json:"predicateType"`
+	Predicate     Provenance           `json:"predicate"`
+}
+
+/*
+ResourceDescriptor identifies an artefact, or anything else consumed or
+produced by a build, by name, URI and digest.
+*/
+type ResourceDescriptor struct {
+	Name        string            `json:"name,omitempty"`
+	URI         string            `json:"uri,omitempty"`
+	Digest      map[string]string `json:"digest,omitempty"`
+	MediaType   string            `json:"mediaType,omitempty"`
+	Annotations map[string]any    `json:"annotations,omitempty"`
+}
+
+type Provenance struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+type BuildDefinition struct {
+	BuildType            string               `json:"buildType"`
+	ExternalParameters   map[string]any       `json:"externalParameters"`
+	InternalParameters   map[string]any       `json:"internalParameters,omitempty"`
+	ResolvedDependencies []ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+type RunDetails struct {
+	Builder    Builder              `json:"builder"`
+	Metadata   BuildMetadata        `json:"metadata"`
+	Byproducts []ResourceDescriptor `json:"byproducts,omitempty"`
+}
+
+type Builder struct {
+	ID string `json:"id"`
+}
+
+type BuildMetadata struct {
+	InvocationID string     `json:"invocationId"`
+	FinishedOn   *time.Time `json:"finishedOn,omitempty"`
+}
+
+/*
+modelFields is the part of a model's sealed fields the provenance is
+built from.
+*/
+type modelFields struct {
+	Name        string          `json:"name"`
+	Version     string          `json:"version"`
+	Dataset     string          `json:"dataset"`
+	DatasetSeal string          `json:"dataset_seal"`
+	Config      json.RawMessage `json:"config"`
+	Metadata    json.RawMessage `json:"metadata"`
+	Environment json.RawMessage `json:"environment"`
+	CreatedAt   time.Time       `json:"created_at"`
+	CreatedBy   string          `json:"created_by"`
+}
+
+type datasetFields struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+/*
+Generate builds the provenance of the sealed model from its record and
+that of the dataset it was trained on, which may be nil if it wasn't
+trained on one.
+*/
+func Generate(model *seal.Record, dataset *seal.Record) (*Statement, error) {
+	if model.Kind != "model" {
+		return nil, fmt.Errorf("%s %s is not a model", model.Kind, model.ID)
+	}
+	if model.Seal == "" {
+		return nil, fmt.Errorf("model %s has not been sealed", model.ID)
+	}
+
+	var f modelFields
+	if err := model.DecodeFields(&f); err != nil {
+		return nil, err
+	}
+
+	subjects, err := descriptors(model.Artefacts, "traintrack:models/"+model.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	external := map[string]any{
+		"model": map[string]string{
+			"id":      model.ID,
+			"name":    f.Name,
+			"version": f.Version,
+		},
+		"config": raw(f.Config),
+	}
+	if f.Dataset != "" {
+		external["dataset"] = f.Dataset
+	}
+
+	var deps []ResourceDescriptor
+
+	if f.Dataset != "" {
+		dsDeps, err := datasetDependencies(f, dataset)
+		if err != nil {
+			return nil, err
+		}
+		deps = append(deps, dsDeps...)
+	}
+
+	var metadata map[string]any
+	if len(f.Metadata) > 0 {
+		if err := json.Unmarshal(f.Metadata, &metadata); err != nil {
+			return nil, fmt.Errorf("model %s has invalid metadata: %w", model.ID, err)
+		}
+	}
+	deps = append(deps, codeDependencies(metadata)...)
+
+	var environment map[string]any
+	if len(f.Environment) > 0 {
+		if err := json.Unmarshal(f.Environment, &environment); err != nil {
+			return nil, fmt.Errorf("model %s has invalid environment: %w", model.ID, err)
+		}
+	}
+	deps = append(deps, environmentDependencies(environment)...)
+
+	internal := map[string]any{}
+	for k, v := range environment {
+		if k != dependencyKey {
+			internal[k] = v
+		}
+	}
+
+	createdAt := f.CreatedAt.UTC()
+
+	return &Statement{
+		Type:          StatementType,
+		Subject:       subjects,
+		PredicateType: PredicateType,
+		Predicate: Provenance{
+			BuildDefinition: BuildDefinition{
+				BuildType:            BuildType,
+				ExternalParameters:   external,
+				InternalParameters:   internal,
+				ResolvedDependencies: deps,
+			},
+			RunDetails: RunDetails{
+				Builder: Builder{ID: BuilderID},
+				Metadata: BuildMetadata{
+					InvocationID: model.ID,
+					FinishedOn:   &createdAt,
+				},
+				Byproducts: []ResourceDescriptor{{
+					Name:   "seal",
+					URI:    "traintrack:models/" + model.ID,
+					Digest: map[string]string{sealDigestName: model.Seal},
+					Annotations: map[string]any{
+						"created_by": f.CreatedBy,
+					},
+				}},
+			},
+		},
+	}, nil
+}
+
+/*
+datasetDependencies pins the dataset the model was trained on, both by the
+seal the model was sealed against and by each of its artefacts.
+*/
+func datasetDependencies(f modelFields, dataset *seal.Record) ([]ResourceDescriptor, error) {
+	if dataset == nil || dataset.ID != f.Dataset {
+		return nil, fmt.Errorf("dataset %s was not provided", f.Dataset)
+	}
+	if dataset.Seal != f.DatasetSeal {
+		return nil, fmt.Errorf("dataset %s is sealed as %s, but the model was trained on %s", dataset.ID, dataset.Seal, f.DatasetSeal)
+	}
+
+	var df datasetFields
+	if err := dataset.DecodeFields(&df); err != nil {
+		return nil, err
+	}
+
+	uri := "traintrack:datasets/" + dataset.ID
+	deps := []ResourceDescriptor{{
+		Name:   "dataset",
+		URI:    uri,
+		Digest: map[string]string{sealDigestName: dataset.Seal},
+		Annotations: map[string]any{
+			"name":    df.Name,
+			"version": df.Version,
+		},
+	}}
+
+	artefacts, err := descriptors(dataset.Artefacts, uri)
+	if err != nil {
+		return nil, err
+	}
+	return append(deps, artefacts...), nil
+}
+
+/*
+codeDependencies records the source of each function captured in the
+model's metadata, such as train_fn_source, by its digest.
+*/
+func codeDependencies(metadata map[string]any) []ResourceDescriptor {
+	var deps []ResourceDescriptor
+	for _, key := range sortedKeys(metadata) {
+		source, ok := metadata[key].(string)
+		if !ok || !strings.HasSuffix(key, sourceSuffix) {
+			continue
+		}
+		deps = append(deps, ResourceDescriptor{
+			Name:      key,
+			Digest:    map[string]string{"sha256": sha256Hex(source)},
+			MediaType: "text/x-python",
+		})
+	}
+	return deps
+}
+
+/*
+environmentDependencies records the frozen package list as a whole, and
+each pinned package in it by its package URL.
+*/
+func environmentDependencies(environment map[string]any) []ResourceDescriptor {
+	frozen, ok := environment[dependencyKey].(string)
+	if !ok || frozen == "" {
+		return nil
+	}
+
+	deps := []ResourceDescriptor{{
+		Name:      "environment." + dependencyKey,
+		Digest:    map[string]string{"sha256": sha256Hex(frozen)},
+		MediaType: "text/plain",
+	}}
+
+	for _, line := range strings.Split(frozen, "\n") {
+		name, version, ok := strings.Cut(strings.TrimSpace(line), "==")
+		if !ok || name == "" || version == "" {
+			continue
+		}
+		deps = append(deps, ResourceDescriptor{
+			Name: name,
+			URI:  fmt.Sprintf("pkg:pypi/%s@%s", strings.ToLower(name), version),
+		})
+	}
+	return deps
+}
+
+func descriptors(artefacts map[string]seal.Artefact, uri string) ([]ResourceDescriptor, error) {
+	var ds []ResourceDescriptor
+	for _, name := range sortedKeys(artefacts) {
+		a := artefacts[name]
+		digest, err := DigestSet(a.Digest)
+		if err != nil {
+			return nil, fmt.Errorf("artefact %s: %w", name, err)
+		}
+		ds = append(ds, ResourceDescriptor{
+			Name:   a.FileName,
+			URI:    uri + "/artefacts/" + name,
+			Digest: digest,
+			Annotations: map[string]any{
+				"artefact": name,
+				"size":     a.Size,
+			},
+		})
+	}
+	return ds, nil
+}
+
+/*
+DigestSet converts a digest of the form "sha256:<hex>", as used by package
+seal, to an in-toto digest set.
+*/
+func DigestSet(digest string) (map[string]string, error) {
+	algorithm, value, ok := strings.Cut(digest, ":")
+	if !ok || algorithm == "" || value == "" {
+		return nil, fmt.Errorf("invalid digest %q", digest)
+	}
+	return map[string]string{algorithm: value}, nil
+}
+
+func raw(m json.RawMessage) any {
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,148 @@
+package provenance
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/seal"
+)
+
+func testDataset() *seal.Record {
+	return &seal.Record{
+		Kind: "dataset",
+		ID:   "ds1",
+		Fields: map[string]any{
+			"name":    "house_prices",
+			"version": "1.0.1",
+		},
+		Artefacts: map[string]seal.Artefact{
+			"train": {FileName: "train.parquet", Digest: "sha256:d1", Size: 10},
+		},
+		Seal: "sha256:dseal",
+	}
+}
+
+func testModel() *seal.Record {
+	return &seal.Record{
+		Kind: "model",
+		ID:   "m1",
+		Fields: map[string]any{
+			"name":         "regressor",
+			"version":      "1.0.0",
+			"dataset":      "ds1",
+			"dataset_seal": "sha256:dseal",
+			"config":       map[string]any{"n_estimators": 100},
+			"metadata": map[string]any{
+				"model_class":     "RandomForestRegressor",
+				"train_fn_source": "def train(m, d):\n    return m\n",
+			},
+			"environment": map[string]any{
+				"runtime":      "CPython",
+				"dependencies": "numpy==2.0.0\nScikit-Learn==1.5.0\n-e git+https://example.com/repo\n",
+			},
+			"created_at": time.Date(2025, 7, 2, 9, 0, 0, 0, time.UTC),
+			"created_by": "dev|1",
+		},
+		Artefacts: map[string]seal.Artefact{
+			"model": {FileName: "model.pkl", Digest: "sha256:m1", Size: 20},
+		},
+		Seal: "sha256:mseal",
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	stmt, err := Generate(testModel(), testDataset())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if stmt.Type != StatementType || stmt.PredicateType != PredicateType {
+		t.Errorf("unexpected statement type: %+v", stmt)
+	}
+
+	wantSubject := []ResourceDescriptor{{
+		Name:        "model.pkl",
+		URI:         "traintrack:models/m1/artefacts/model",
+		Digest:      map[string]string{"sha256": "m1"},
+		Annotations: map[string]any{"artefact": "model", "size": int64(20)},
+	}}
+	if !reflect.DeepEqual(stmt.Subject, wantSubject) {
+		t.Errorf("got subject %+v, wanted %+v", stmt.Subject, wantSubject)
+	}
+
+	var names []string
+	for _, d := range stmt.Predicate.BuildDefinition.ResolvedDependencies {
+		names = append(names, d.Name+" "+d.URI)
+	}
+	want := []string{
+		"dataset traintrack:datasets/ds1",
+		"train.parquet traintrack:datasets/ds1/artefacts/train",
+		"train_fn_source ",
+		"environment.dependencies ",
+		"numpy pkg:pypi/numpy@2.0.0",
+		"Scikit-Learn pkg:pypi/scikit-learn@1.5.0",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got dependencies %q, wanted %q", names, want)
+	}
+
+	if got := stmt.Predicate.BuildDefinition.InternalParameters; !reflect.DeepEqual(got, map[string]any{"runtime": "CPython"}) {
+		t.Errorf("unexpected internal parameters: %+v", got)
+	}
+	if stmt.Predicate.RunDetails.Metadata.InvocationID != "m1" {
+		t.Errorf("unexpected run details: %+v", stmt.Predicate.RunDetails)
+	}
+}
+
+func TestGenerateIsReproducible(t *testing.T) {
+	a, err := Generate(testModel(), testDataset())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Generate(testModel(), testDataset())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aj, _ := json.Marshal(a)
+	bj, _ := json.Marshal(b)
+	if string(aj) != string(bj) {
+		t.Errorf("expected identical statements:\n%s\n%s", aj, bj)
+	}
+}
+
+func TestGenerateFailures(t *testing.T) {
+	changed := testDataset()
+	changed.Seal = "sha256:other"
+
+	unsealed := testModel()
+	unsealed.Seal = ""
+
+	undigested := testModel()
+	undigested.Artefacts["model"] = seal.Artefact{FileName: "model.pkl"}
+
+	tests := []struct {
+		name    string
+		model   *seal.Record
+		dataset *seal.Record
+		want    string
+	}{
+		{name: "dataset changed", model: testModel(), dataset: changed, want: "but the model was trained on"},
+		{name: "dataset missing", model: testModel(), want: "was not provided"},
+		{name: "unsealed", model: unsealed, dataset: testDataset(), want: "has not been sealed"},
+		{name: "no digest", model: undigested, dataset: testDataset(), want: "invalid digest"},
+		{name: "not a model", model: testDataset(), want: "is not a model"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Generate(tc.model, tc.dataset)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("got %v, wanted an error containing %q", err, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+/*
+Package retention removes old dataset and model versions according to a
+Policy of per-tenant and per-name rules, such as keeping the last ten
+versions of each dataset.
+
+A Job evaluates the policy on a schedule. Expired versions are first
+soft-deleted, which hides them from lists, and their artefacts are purged
+from storage once the policy's grace period has passed. A version is
+never removed while it is still needed:
+
+  - datasets used by a model which hasn't been deleted are kept, so a
+    model's lineage can always be followed back to its training data
+  - models in staging or production are kept
+  - versions under a legal hold are neither deleted nor, if they are
+    already in the trash, purged
+
+To run it alongside the server:
+
+	policy, err := LoadPolicy("retention.json")
+	job := NewJob(policy, NewStore(db), fileStore, db, auditStore)
+	go job.Schedule(ctx, 24*time.Hour)
+*/
+package retention
@@ -0,0 +1,253 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/uploads"
+	"github.com/jackc/pgx/v5"
+)
+
+/*
+Actor is who retention deletes and purges are recorded as in the audit
+log.
+*/
+const Actor = "system:retention"
+
+type retentionStore interface {
+	Versions(ctx context.Context, kind Kind) ([]Version, error)
+	Purgeable(ctx context.Context, kind Kind, deletedBefore time.Time) ([]Version, error)
+	deleteWithQuerier(ctx context.Context, q Querier, kind Kind, id string) (bool, error)
+	purgeWithQuerier(ctx context.Context, q Querier, kind Kind, id string) (bool, error)
+	filesWithQuerier(ctx context.Context, q Querier, kind Kind, id string) ([]uploads.FileRef, error)
+}
+
+/*
+FileRemover permanently removes artefacts from storage.
+*/
+type FileRemover interface {
+	RemoveFile(path string) error
+}
+
+/*
+AuditRecorder records an audit event using the provided Querier, which
+may be a transaction.
+*/
+type AuditRecorder interface {
+	RecordWithQuerier(ctx context.Context, q audit.Querier, e *audit.Event) error
+}
+
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type Job struct {
+	policy *Policy
+	s      retentionStore
+	files  FileRemover
+	db     TxBeginner
+	audit  AuditRecorder
+
+	// DryRun logs what would be deleted and purged without doing it.
+	DryRun bool
+
+	now func() time.Time
+}
+
+func NewJob(p *Policy, s *Store, f FileRemover, db TxBeginner, a AuditRecorder) *Job {
+	return &Job{
+		policy: p,
+		s:      s,
+		files:  f,
+		db:     db,
+		audit:  a,
+		now:    time.Now,
+	}
+}
+
+/*
+Report is what one run of the job did.
+*/
+type Report struct {
+	Deleted []Expiry
+	Purged  []Version
+}
+
+/*
+Run evaluates the policy once: expired versions are soft-deleted, then
+the artefacts of versions deleted longer ago than the grace period are
+purged. A version which fails is logged and skipped, to be tried again on
+the next run, rather than stopping the rest.
+*/
+func (j *Job) Run(ctx context.Context) (*Report, error) {
+	report := &Report{}
+	now := j.now()
+
+	for _, kind := range []Kind{KindDataset, KindModel} {
+		versions, err := j.s.Versions(ctx, kind)
+		if err != nil {
+			return report, err
+		}
+
+		for _, e := range j.policy.Expired(versions, now) {
+			if j.DryRun {
+				log.Printf("retention: would delete %s %s (%s %s, rule %d)", e.Kind, e.ID, e.Name, e.CreatedAt.Format(time.RFC3339), e.Rule)
+				report.Deleted = append(report.Deleted, e)
+				continue
+			}
+
+			deleted, err := j.delete(ctx, e)
+			if err != nil {
+				log.Printf("retention: could not delete %s %s: %s", e.Kind, e.ID, err)
+				continue
+			}
+			if deleted {
+				log.Printf("retention: deleted %s %s (%s, rule %d)", e.Kind, e.ID, e.Name, e.Rule)
+				report.Deleted = append(report.Deleted, e)
+			}
+		}
+	}
+
+	for _, kind := range []Kind{KindDataset, KindModel} {
+		due, err := j.s.Purgeable(ctx, kind, now.Add(-j.policy.GracePeriod))
+		if err != nil {
+			return report, err
+		}
+
+		for _, v := range due {
+			if j.DryRun {
+				log.Printf("retention: would purge %s %s", v.Kind, v.ID)
+				report.Purged = append(report.Purged, v)
+				continue
+			}
+
+			purged, err := j.purge(ctx, v)
+			if err != nil {
+				log.Printf("retention: could not purge %s %s: %s", v.Kind, v.ID, err)
+				continue
+			}
+			if purged {
+				log.Printf("retention: purged %s %s", v.Kind, v.ID)
+				report.Purged = append(report.Purged, v)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+/*
+delete soft-deletes an expired version, unless it has become protected
+since it was listed.
+*/
+func (j *Job) delete(ctx context.Context, e Expiry) (deleted bool, err error) {
+	tx, err := j.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil || !deleted {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if deleted, err = j.s.deleteWithQuerier(ctx, tx, e.Kind, e.ID); err != nil || !deleted {
+		return false, err
+	}
+
+	if j.audit != nil {
+		event := j.event(ctx, audit.ActionDelete, e.Version).WithDetails(map[string]any{
+			"reason": "retention",
+			"rule":   e.Rule,
+		})
+		if err := j.audit.RecordWithQuerier(ctx, tx, event); err != nil {
+			return false, fmt.Errorf("could not record audit event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit: %w", err)
+	}
+	return true, nil
+}
+
+/*
+purge removes the artefacts of a deleted version. The version is marked
+as purged first, within the transaction, so it is only committed once
+every file is gone and a failure leaves it to be tried again.
+*/
+func (j *Job) purge(ctx context.Context, v Version) (purged bool, err error) {
+	tx, err := j.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil || !purged {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if purged, err = j.s.purgeWithQuerier(ctx, tx, v.Kind, v.ID); err != nil || !purged {
+		return false, err
+	}
+
+	files, err := j.s.filesWithQuerier(ctx, tx, v.Kind, v.ID)
+	if err != nil {
+		return false, err
+	}
+	var removed []string
+	for _, f := range files {
+		p := filepath.Join(f.Path, f.FileName)
+		if err := j.files.RemoveFile(p); err != nil {
+			return false, fmt.Errorf("could not remove %s: %w", p, err)
+		}
+		removed = append(removed, p)
+	}
+
+	if j.audit != nil {
+		event := j.event(ctx, audit.ActionPurge, v).WithDetails(map[string]any{
+			"reason": "retention",
+			"files":  removed,
+		})
+		if err := j.audit.RecordWithQuerier(ctx, tx, event); err != nil {
+			return false, fmt.Errorf("could not record audit event: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("commit: %w", err)
+	}
+	return true, nil
+}
+
+func (j *Job) event(ctx context.Context, action audit.Action, v Version) *audit.Event {
+	e := audit.NewEvent(ctx, action, audit.ResourceType(v.Kind), v.ID)
+	e.Actor = Actor
+	e.Tenant = v.Tenant
+	return e
+}
+
+/*
+Schedule runs the job straight away and then every interval until ctx is
+cancelled.
+*/
+func (j *Job) Schedule(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := j.Run(ctx); err != nil {
+			log.Printf("retention: run failed: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/uploads"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+type mockStore struct {
+	versions  map[Kind][]Version
+	purgeable map[Kind][]Version
+	files     map[string][]uploads.FileRef
+	// kept are ids the guarded delete and purge queries refuse.
+	kept  map[string]bool
+	calls *[]string
+}
+
+func (m *mockStore) Versions(_ context.Context, kind Kind) ([]Version, error) {
+	return m.versions[kind], nil
+}
+
+func (m *mockStore) Purgeable(_ context.Context, kind Kind, _ time.Time) ([]Version, error) {
+	return m.purgeable[kind], nil
+}
+
+func (m *mockStore) deleteWithQuerier(_ context.Context, _ Querier, kind Kind, id string) (bool, error) {
+	*m.calls = append(*m.calls, "delete "+string(kind)+" "+id)
+	return !m.kept[id], nil
+}
+
+func (m *mockStore) purgeWithQuerier(_ context.Context, _ Querier, kind Kind, id string) (bool, error) {
+	*m.calls = append(*m.calls, "purge "+string(kind)+" "+id)
+	return !m.kept[id], nil
+}
+
+func (m *mockStore) filesWithQuerier(_ context.Context, _ Querier, _ Kind, id string) ([]uploads.FileRef, error) {
+	return m.files[id], nil
+}
+
+type mockFiles struct {
+	fail  string
+	calls *[]string
+}
+
+func (m *mockFiles) RemoveFile(path string) error {
+	*m.calls = append(*m.calls, "remove "+path)
+	if path == m.fail {
+		return errors.New("boom")
+	}
+	return nil
+}
+
+type mockAudit struct {
+	events []*audit.Event
+}
+
+func (m *mockAudit) RecordWithQuerier(_ context.Context, _ audit.Querier, e *audit.Event) error {
+	m.events = append(m.events, e)
+	return nil
+}
+
+type mockDB struct {
+	calls *[]string
+}
+
+func (m *mockDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	conn, _ := pgxmock.NewConn()
+	tx, _ := conn.Begin(ctx)
+	return &loggingTx{Tx: tx, log: m.calls}, nil
+}
+
+type loggingTx struct {
+	pgx.Tx
+	log *[]string
+}
+
+func (l *loggingTx) Commit(ctx context.Context) error {
+	*l.log = append(*l.log, "commit")
+	return nil
+}
+
+func (l *loggingTx) Rollback(ctx context.Context) error {
+	*l.log = append(*l.log, "rollback")
+	return nil
+}
+
+func TestJob_Run(t *testing.T) {
+	datasets := versionsOf(KindDataset, "acme", "prices", 3)
+
+	tests := []struct {
+		name            string
+		dryRun          bool
+		protected       map[string]string
+		kept            map[string]bool
+		failRemove      string
+		noAudit         bool
+		expectedCalls   []string
+		expectedDeleted []string
+		expectedPurged  []string
+		expectedEvents  []audit.Action
+	}{
+		{
+			name: "deletes and purges",
+			expectedCalls: []string{
+				"delete dataset prices-c", "commit",
+				"purge model old", "remove models/old/model.pkl", "remove models/old/config.json", "commit",
+			},
+			expectedDeleted: []string{"prices-c"},
+			expectedPurged:  []string{"old"},
+			expectedEvents:  []audit.Action{audit.ActionDelete, audit.ActionPurge},
+		},
+		{
+			name:   "dry run",
+			dryRun: true,
+			// Dry runs report what they would have done.
+			expectedDeleted: []string{"prices-c"},
+			expectedPurged:  []string{"old"},
+		},
+		{
+			name:      "old parent with a live child survives",
+			protected: map[string]string{"prices-c": protectedByChild},
+			expectedCalls: []string{
+				"purge model old", "remove models/old/model.pkl", "remove models/old/config.json", "commit",
+			},
+			expectedPurged: []string{"old"},
+			expectedEvents: []audit.Action{audit.ActionPurge},
+		},
+		{
+			name: "protected since listed",
+			kept: map[string]bool{"prices-c": true, "old": true},
+			expectedCalls: []string{
+				"delete dataset prices-c", "rollback",
+				"purge model old", "rollback",
+			},
+		},
+		{
+			name:       "purge fails",
+			failRemove: "models/old/config.json",
+			expectedCalls: []string{
+				"delete dataset prices-c", "commit",
+				"purge model old", "remove models/old/model.pkl", "remove models/old/config.json", "rollback",
+			},
+			expectedDeleted: []string{"prices-c"},
+			expectedEvents:  []audit.Action{audit.ActionDelete},
+		},
+		{
+			name:    "without an audit log",
+			noAudit: true,
+			expectedCalls: []string{
+				"delete dataset prices-c", "commit",
+				"purge model old", "remove models/old/model.pkl", "remove models/old/config.json", "commit",
+			},
+			expectedDeleted: []string{"prices-c"},
+			expectedPurged:  []string{"old"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			recorder := &mockAudit{}
+
+			versions := make([]Version, len(datasets))
+			for i, v := range datasets {
+				v.Protected = tc.protected[v.ID]
+				versions[i] = v
+			}
+
+			job := &Job{
+				policy: &Policy{Rules: []Rule{{KeepLast: 2}}, GracePeriod: time.Hour},
+				s: &mockStore{
+					versions:  map[Kind][]Version{KindDataset: versions},
+					purgeable: map[Kind][]Version{KindModel: {{Kind: KindModel, ID: "old", Tenant: "acme"}}},
+					files: map[string][]uploads.FileRef{
+						"old": {{FileName: "model.pkl", Path: "models/old"}, {FileName: "config.json", Path: "models/old"}},
+					},
+					kept:  tc.kept,
+					calls: &calls,
+				},
+				files:  &mockFiles{fail: tc.failRemove, calls: &calls},
+				db:     &mockDB{calls: &calls},
+				audit:  recorder,
+				DryRun: tc.dryRun,
+				now:    func() time.Time { return now },
+			}
+			if tc.noAudit {
+				job.audit = nil
+			}
+
+			report, err := job.Run(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(calls, tc.expectedCalls) {
+				t.Errorf("calls %v, want %v", calls, tc.expectedCalls)
+			}
+
+			var deleted, purged []string
+			for _, e := range report.Deleted {
+				deleted = append(deleted, e.ID)
+			}
+			for _, v := range report.Purged {
+				purged = append(purged, v.ID)
+			}
+			if !reflect.DeepEqual(deleted, tc.expectedDeleted) {
+				t.Errorf("deleted %v, want %v", deleted, tc.expectedDeleted)
+			}
+			if !reflect.DeepEqual(purged, tc.expectedPurged) {
+				t.Errorf("purged %v, want %v", purged, tc.expectedPurged)
+			}
+
+			var actions []audit.Action
+			for _, e := range recorder.events {
+				actions = append(actions, e.Action)
+				if e.Actor != Actor || e.Tenant != "acme" {
+					t.Errorf("expected event by %s for acme, got %+v", Actor, e)
+				}
+			}
+			if !reflect.DeepEqual(actions, tc.expectedEvents) {
+				t.Errorf("events %v, want %v", actions, tc.expectedEvents)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package retention
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+/*
+DefaultGracePeriod is how long soft-deleted versions are kept before
+their artefacts are purged, if the policy doesn't say.
+*/
+const DefaultGracePeriod = 30 * 24 * time.Hour
+
+/*
+Kind is the type of version a rule applies to. The values match the
+audit resource types.
+*/
+type Kind string
+
+const (
+	KindDataset Kind = "dataset"
+	KindModel   Kind = "model"
+)
+
+/*
+Rule says how many versions of each dataset or model to keep. Kind,
+Tenant and Name narrow down what it applies to; left empty they match
+everything. Name is a pattern, as for path.Match.
+
+A version expires when it isn't one of the newest KeepLast versions of
+its name and it is older than MaxAge. Either may be left as zero to only
+use the other.
+*/
+type Rule struct {
+	Kind     Kind
+	Tenant   string
+	Name     string
+	KeepLast int
+	MaxAge   time.Duration
+}
+
+func (r *Rule) matches(v *Version) bool {
+	if r.Kind != "" && r.Kind != v.Kind {
+		return false
+	}
+	if r.Tenant != "" && r.Tenant != v.Tenant {
+		return false
+	}
+	if r.Name == "" {
+		return true
+	}
+	ok, _ := path.Match(r.Name, v.Name)
+	return ok
+}
+
+/*
+expired reports whether v should be removed under this rule, given how
+many newer versions of its name there are.
+*/
+func (r *Rule) expired(v *Version, newer int, now time.Time) bool {
+	if r.KeepLast > 0 && newer < r.KeepLast {
+		return false
+	}
+	if r.MaxAge > 0 && now.Sub(v.CreatedAt) < r.MaxAge {
+		return false
+	}
+	return true
+}
+
+/*
+Policy is an ordered list of rules. Each version is governed by the first
+rule it matches, so more specific rules should come first. Versions no
+rule matches are kept forever.
+*/
+type Policy struct {
+	Rules       []Rule
+	GracePeriod time.Duration
+}
+
+/*
+Version is a dataset or model version as the policy sees it. Protected
+says why it must be kept regardless of the rules, if it must.
+*/
+type Version struct {
+	Kind      Kind
+	ID        string
+	Name      string
+	Tenant    string
+	CreatedAt time.Time
+	Protected string
+}
+
+/*
+Expiry is a version to remove, and the index of the rule which expired
+it.
+*/
+type Expiry struct {
+	Version
+	Rule int
+}
+
+/*
+Expired returns the versions to remove. versions must be newest first.
+Protected versions are never returned, but still count towards how many
+versions of their name are kept.
+*/
+func (p *Policy) Expired(versions []Version, now time.Time) []Expiry {
+	type group struct {
+		kind         Kind
+		tenant, name string
+	}
+	seen := map[group]int{}
+
+	var expired []Expiry
+	for _, v := range versions {
+		g := group{v.Kind, v.Tenant, v.Name}
+		newer := seen[g]
+		seen[g]++
+
+		for i := range p.Rules {
+			rule := &p.Rules[i]
+			if !rule.matches(&v) {
+				continue
+			}
+			if v.Protected == "" && rule.expired(&v, newer, now) {
+				expired = append(expired, Expiry{Version: v, Rule: i})
+			}
+			break
+		}
+	}
+	return expired
+}
+
+/*
+ruleConfig is how a rule is written in a policy file.
+*/
+type ruleConfig struct {
+	Kind     Kind   `json:"kind"`
+	Tenant   string `json:"tenant"`
+	Name     string `json:"name"`
+	KeepLast int    `json:"keep_last"`
+	MaxAge   string `json:"max_age"`
+}
+
+/*
+LoadPolicy reads a policy file, a JSON object holding a list of "rules"
+and optionally the "grace_period" before soft-deleted versions are
+purged. Durations are written as for time.ParseDuration, such as "720h".
+*/
+func LoadPolicy(file string) (*Policy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var config struct {
+		GracePeriod string       `json:"grace_period"`
+		Rules       []ruleConfig `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid retention policy %s: %w", file, err)
+	}
+
+	p := &Policy{GracePeriod: DefaultGracePeriod}
+	if config.GracePeriod != "" {
+		if p.GracePeriod, err = time.ParseDuration(config.GracePeriod); err != nil {
+			return nil, fmt.Errorf("invalid grace period: %w", err)
+		}
+		if p.GracePeriod < 0 {
+			return nil, errors.New("invalid grace period: must not be negative")
+		}
+	}
+
+	for i, c := range config.Rules {
+		rule := Rule{Kind: c.Kind, Tenant: c.Tenant, Name: c.Name, KeepLast: c.KeepLast}
+
+		switch c.Kind {
+		case "", KindDataset, KindModel:
+		default:
+			return nil, fmt.Errorf("rule %d: unknown kind %q", i, c.Kind)
+		}
+		if _, err := path.Match(c.Name, ""); err != nil {
+			return nil, fmt.Errorf("rule %d: invalid name pattern %q", i, c.Name)
+		}
+		if c.MaxAge != "" {
+			if rule.MaxAge, err = time.ParseDuration(c.MaxAge); err != nil {
+				return nil, fmt.Errorf("rule %d: invalid max age: %w", i, err)
+			}
+		}
+		if rule.KeepLast < 0 || rule.MaxAge < 0 {
+			return nil, fmt.Errorf("rule %d: keep_last and max_age must not be negative", i)
+		}
+		if rule.KeepLast == 0 && rule.MaxAge == 0 {
+			return nil, fmt.Errorf("rule %d: needs keep_last or max_age", i)
+		}
+
+		p.Rules = append(p.Rules, rule)
+	}
+	return p, nil
+}
@@ -0,0 +1,199 @@
+package retention
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2025, 7, 6, 12, 0, 0, 0, time.UTC)
+
+/*
+versionsOf returns n versions of name, newest first, a day apart.
+*/
+func versionsOf(kind Kind, tenant, name string, n int) []Version {
+	var vs []Version
+	for i := 0; i < n; i++ {
+		vs = append(vs, Version{
+			Kind:      kind,
+			ID:        name + "-" + string(rune('a'+i)),
+			Name:      name,
+			Tenant:    tenant,
+			CreatedAt: now.Add(-time.Duration(i) * 24 * time.Hour),
+		})
+	}
+	return vs
+}
+
+func ids(es []Expiry) []string {
+	out := []string{}
+	for _, e := range es {
+		out = append(out, e.ID)
+	}
+	return out
+}
+
+func TestPolicy_Expired(t *testing.T) {
+	protected := versionsOf(KindDataset, "", "prices", 4)
+	protected[3].Protected = protectedByModel
+
+	tests := []struct {
+		name     string
+		rules    []Rule
+		versions []Version
+		expected []string
+	}{
+		{
+			name:     "keep last",
+			rules:    []Rule{{KeepLast: 2}},
+			versions: versionsOf(KindDataset, "", "prices", 4),
+			expected: []string{"prices-c", "prices-d"},
+		},
+		{
+			name:     "max age",
+			rules:    []Rule{{MaxAge: 36 * time.Hour}},
+			versions: versionsOf(KindDataset, "", "prices", 4),
+			expected: []string{"prices-c", "prices-d"},
+		},
+		{
+			name:     "keep last and max age",
+			rules:    []Rule{{KeepLast: 3, MaxAge: 36 * time.Hour}},
+			versions: versionsOf(KindDataset, "", "prices", 4),
+			expected: []string{"prices-d"},
+		},
+		{
+			name:     "protected versions are kept",
+			rules:    []Rule{{KeepLast: 2}},
+			versions: protected,
+			expected: []string{"prices-c"},
+		},
+		{
+			name:     "no matching rule",
+			rules:    []Rule{{Kind: KindModel, KeepLast: 1}},
+			versions: versionsOf(KindDataset, "", "prices", 4),
+			expected: []string{},
+		},
+		{
+			name:     "names are counted separately",
+			rules:    []Rule{{KeepLast: 1}},
+			versions: append(versionsOf(KindDataset, "", "prices", 2), versionsOf(KindDataset, "", "rents", 2)...),
+			expected: []string{"prices-b", "rents-b"},
+		},
+		{
+			name:     "tenants are counted separately",
+			rules:    []Rule{{KeepLast: 1}},
+			versions: append(versionsOf(KindDataset, "acme", "prices", 2), versionsOf(KindDataset, "globex", "prices", 2)...),
+			expected: []string{"prices-b", "prices-b"},
+		},
+		{
+			name: "first matching rule wins",
+			rules: []Rule{
+				{Tenant: "acme", Name: "price*", KeepLast: 3},
+				{KeepLast: 1},
+			},
+			versions: append(versionsOf(KindDataset, "acme", "prices", 4), versionsOf(KindDataset, "globex", "prices", 2)...),
+			expected: []string{"prices-d", "prices-b"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &Policy{Rules: tc.rules}
+			got := ids(p.Expired(tc.versions, now))
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expired %v, want %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        string
+		expected      *Policy
+		expectedError string
+	}{
+		{
+			name: "valid",
+			config: `{"grace_period": "48h", "rules": [
+				{"kind": "dataset", "tenant": "acme", "name": "house_*", "keep_last": 10},
+				{"kind": "model", "max_age": "2160h"}
+			]}`,
+			expected: &Policy{
+				GracePeriod: 48 * time.Hour,
+				Rules: []Rule{
+					{Kind: KindDataset, Tenant: "acme", Name: "house_*", KeepLast: 10},
+					{Kind: KindModel, MaxAge: 2160 * time.Hour},
+				},
+			},
+		},
+		{
+			name:     "default grace period",
+			config:   `{"rules": [{"keep_last": 5}]}`,
+			expected: &Policy{GracePeriod: DefaultGracePeriod, Rules: []Rule{{KeepLast: 5}}},
+		},
+		{
+			name:          "invalid json",
+			config:        `{"rules": [`,
+			expectedError: "invalid retention policy",
+		},
+		{
+			name:          "unknown kind",
+			config:        `{"rules": [{"kind": "upload", "keep_last": 1}]}`,
+			expectedError: `rule 0: unknown kind "upload"`,
+		},
+		{
+			name:          "invalid name",
+			config:        `{"rules": [{"name": "[a", "keep_last": 1}]}`,
+			expectedError: `rule 0: invalid name pattern "[a"`,
+		},
+		{
+			name:          "invalid max age",
+			config:        `{"rules": [{"max_age": "90d"}]}`,
+			expectedError: "rule 0: invalid max age",
+		},
+		{
+			name:          "negative keep last",
+			config:        `{"rules": [{"keep_last": -1}]}`,
+			expectedError: "rule 0: keep_last and max_age must not be negative",
+		},
+		{
+			name:          "keeps everything",
+			config:        `{"rules": [{"kind": "model"}]}`,
+			expectedError: "rule 0: needs keep_last or max_age",
+		},
+		{
+			name:          "invalid grace period",
+			config:        `{"grace_period": "-1h", "rules": []}`,
+			expectedError: "invalid grace period",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "retention.json")
+			if err := os.WriteFile(file, []byte(tc.config), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			p, err := LoadPolicy(file)
+
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(p, tc.expected) {
+				t.Errorf("policy %+v, want %+v", p, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/uploads"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+/*
+Protection reasons, as the version queries report them.
+*/
+const (
+	protectedByModel = "used by a model"
+	protectedByStage = "in staging or production"
+	protectedByHold  = "under legal hold"
+	protectedByChild = "has versions derived from it"
+)
+
+/*
+Conditions for a version being under an active legal hold, having a
+version derived from it which hasn't been deleted, or, for a dataset, having
+a model trained on it which hasn't been purged. Removing a parent would
+leave its children's seals unverifiable, and a trashed model can still be
+restored.
+*/
+const (
+	datasetHeld = `EXISTS (
+    SELECT 1 FROM legal_holds h
+    WHERE h.resource_type = 'dataset' AND h.resource_id = d.id AND h.released_at IS NULL
+  )`
+	modelHeld = `EXISTS (
+    SELECT 1 FROM legal_holds h
+    WHERE h.resource_type = 'model' AND h.resource_id = m.id AND h.released_at IS NULL
+  )`
+	datasetUsed     = `EXISTS (SELECT 1 FROM models m WHERE m.dataset = d.id::text AND m.purged_at IS NULL)`
+	datasetHasChild = `EXISTS (SELECT 1 FROM datasets c WHERE c.parent = d.id AND c.deleted_at IS NULL)`
+	modelHasChild   = `EXISTS (SELECT 1 FROM models c WHERE c.parent = m.id AND c.deleted_at IS NULL)`
+)
+
+const (
+	datasetVersionsQuery = `SELECT
+  d.id,
+  d.name,
+  COALESCE(d.tenant, ''),
+  d.created_at,
+  CASE
+    WHEN ` + datasetHeld + ` THEN '` + protectedByHold + `'
+    WHEN ` + datasetUsed + ` THEN '` + protectedByModel + `'
+    WHEN ` + datasetHasChild + ` THEN '` + protectedByChild + `'
+    ELSE ''
+  END
+FROM datasets d
+WHERE d.deleted_at IS NULL
+ORDER BY d.created_at DESC, d.id`
+	modelVersionsQuery = `SELECT
+  m.id,
+  m.name,
+  COALESCE(m.tenant, ''),
+  m.created_at,
+  CASE
+    WHEN ` + modelHeld + ` THEN '` + protectedByHold + `'
+    WHEN m.stage IN ('staging', 'production') THEN '` + protectedByStage + `'
+    WHEN ` + modelHasChild + ` THEN '` + protectedByChild + `'
+    ELSE ''
+  END
+FROM models m
+WHERE m.deleted_at IS NULL
+ORDER BY m.created_at DESC, m.id`
+
+	// The delete and purge queries check the version is still unprotected,
+	// in case it has been used, promoted, held or derived from since it was
+	// listed.
+	deleteDatasetQuery = `UPDATE datasets d SET deleted_at = now()
+WHERE d.id = $1 AND d.deleted_at IS NULL
+  AND NOT ` + datasetUsed + `
+  AND NOT ` + datasetHasChild + `
+  AND NOT ` + datasetHeld
+	deleteModelQuery = `UPDATE models m SET deleted_at = now()
+WHERE m.id = $1 AND m.deleted_at IS NULL AND m.stage NOT IN ('staging', 'production')
+  AND NOT ` + modelHasChild + `
+  AND NOT ` + modelHeld
+	// Held versions, and those with live children, are left in the trash
+	// until they're released or their children are deleted.
+	purgeableDatasetsQuery = `SELECT d.id, COALESCE(d.tenant, '')
+FROM datasets d
+WHERE d.deleted_at < $1 AND d.purged_at IS NULL
+  AND NOT ` + datasetHasChild + `
+  AND NOT ` + datasetHeld + `
+ORDER BY d.deleted_at, d.id`
+	purgeableModelsQuery = `SELECT m.id, COALESCE(m.tenant, '')
+FROM models m
+WHERE m.deleted_at < $1 AND m.purged_at IS NULL
+  AND NOT ` + modelHasChild + `
+  AND NOT ` + modelHeld + `
+ORDER BY m.deleted_at, m.id`
+	purgeDatasetQuery = `UPDATE datasets d SET purged_at = now()
+WHERE d.id = $1 AND d.deleted_at IS NOT NULL AND d.purged_at IS NULL
+  AND NOT ` + datasetUsed + `
+  AND NOT ` + datasetHasChild + `
+  AND NOT ` + datasetHeld
+	purgeModelQuery = `UPDATE models m SET purged_at = now()
+WHERE m.id = $1 AND m.deleted_at IS NOT NULL AND m.purged_at IS NULL AND m.stage NOT IN ('staging', 'production')
+  AND NOT ` + modelHasChild + `
+  AND NOT ` + modelHeld
+	datasetFilesQuery = `SELECT files FROM uploads WHERE dataset_id = $1`
+	modelFilesQuery   = `SELECT files FROM uploads WHERE model_id = $1`
+)
+
+/*
+queries are the statements for each kind of version.
+*/
+type queries struct {
+	versions, delete, purgeable, purge, files string
+}
+
+var kindQueries = map[Kind]queries{
+	KindDataset: {datasetVersionsQuery, deleteDatasetQuery, purgeableDatasetsQuery, purgeDatasetQuery, datasetFilesQuery},
+	KindModel:   {modelVersionsQuery, deleteModelQuery, purgeableModelsQuery, purgeModelQuery, modelFilesQuery},
+}
+
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+type Store struct {
+	q Querier
+}
+
+func NewStore(q Querier) *Store {
+	return &Store{
+		q: q,
+	}
+}
+
+/*
+Versions returns every version of kind which hasn't been deleted, newest
+first.
+*/
+func (s *Store) Versions(ctx context.Context, kind Kind) ([]Version, error) {
+	rows, err := s.q.Query(ctx, kindQueries[kind].versions)
+	if err != nil {
+		return nil, fmt.Errorf("could not query %ss: %s", kind, err)
+	}
+	defer rows.Close()
+
+	vs := []Version{}
+	for rows.Next() {
+		v := Version{Kind: kind}
+		if err := rows.Scan(&v.ID, &v.Name, &v.Tenant, &v.CreatedAt, &v.Protected); err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+	}
+	return vs, rows.Err()
+}
+
+/*
+Purgeable returns the versions of kind deleted before deletedBefore whose
+artefacts haven't been purged yet.
+*/
+func (s *Store) Purgeable(ctx context.Context, kind Kind, deletedBefore time.Time) ([]Version, error) {
+	rows, err := s.q.Query(ctx, kindQueries[kind].purgeable, deletedBefore)
+	if err != nil {
+		return nil, fmt.Errorf("could not query deleted %ss: %s", kind, err)
+	}
+	defer rows.Close()
+
+	vs := []Version{}
+	for rows.Next() {
+		v := Version{Kind: kind}
+		if err := rows.Scan(&v.ID, &v.Tenant); err != nil {
+			return nil, err
+		}
+		vs = append(vs, v)
+	}
+	return vs, rows.Err()
+}
+
+// Don't export, deleting is up to the job, which records it.
+func (s *Store) deleteWithQuerier(ctx context.Context, q Querier, kind Kind, id string) (bool, error) {
+	tag, err := q.Exec(ctx, kindQueries[kind].delete, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// Don't export, purging is up to the job, which removes the artefacts.
+func (s *Store) purgeWithQuerier(ctx context.Context, q Querier, kind Kind, id string) (bool, error) {
+	tag, err := q.Exec(ctx, kindQueries[kind].purge, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (s *Store) filesWithQuerier(ctx context.Context, q Querier, kind Kind, id string) ([]uploads.FileRef, error) {
+	rows, err := q.Query(ctx, kindQueries[kind].files, id)
+	if err != nil {
+		return nil, fmt.Errorf("could not query artefacts: %w", err)
+	}
+	defer rows.Close()
+
+	var refs []uploads.FileRef
+	for rows.Next() {
+		var files map[string]uploads.FileRef
+		if err := rows.Scan(&files); err != nil {
+			return nil, err
+		}
+		for _, f := range files {
+			refs = append(refs, f)
+		}
+	}
+	return refs, rows.Err()
+}
@@ -0,0 +1,144 @@
+package retention
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/uploads"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func TestVersions(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2025, 7, 6, 9, 0, 0, 0, time.UTC)
+	db.ExpectQuery(regexp.QuoteMeta(datasetVersionsQuery)).
+		WillReturnRows(db.NewRows([]string{"id", "name", "tenant", "created_at", "protected"}).
+			AddRow("1", "prices", "acme", createdAt, protectedByModel).
+			AddRow("2", "prices", "acme", createdAt, ""))
+	db.ExpectQuery(regexp.QuoteMeta(modelVersionsQuery)).
+		WillReturnRows(db.NewRows([]string{"id", "name", "tenant", "created_at", "protected"}).
+			AddRow("3", "regressor", "", createdAt, protectedByHold))
+
+	store := NewStore(db)
+
+	ds, err := store.Versions(context.Background(), KindDataset)
+	if err != nil {
+		t.Fatalf("could not list datasets: %s", err)
+	}
+	expected := []Version{
+		{Kind: KindDataset, ID: "1", Name: "prices", Tenant: "acme", CreatedAt: createdAt, Protected: protectedByModel},
+		{Kind: KindDataset, ID: "2", Name: "prices", Tenant: "acme", CreatedAt: createdAt},
+	}
+	if !reflect.DeepEqual(ds, expected) {
+		t.Errorf("datasets %+v, want %+v", ds, expected)
+	}
+
+	ms, err := store.Versions(context.Background(), KindModel)
+	if err != nil {
+		t.Fatalf("could not list models: %s", err)
+	}
+	if len(ms) != 1 || ms[0].Kind != KindModel || ms[0].Protected != protectedByHold {
+		t.Errorf("unexpected models: %+v", ms)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPurgeable(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	before := time.Date(2025, 6, 6, 9, 0, 0, 0, time.UTC)
+	db.ExpectQuery(regexp.QuoteMeta(purgeableModelsQuery)).
+		WithArgs(before).
+		WillReturnRows(db.NewRows([]string{"id", "tenant"}).AddRow("3", "acme"))
+
+	vs, err := NewStore(db).Purgeable(context.Background(), KindModel, before)
+	if err != nil {
+		t.Fatalf("could not list: %s", err)
+	}
+	expected := []Version{{Kind: KindModel, ID: "3", Tenant: "acme"}}
+	if !reflect.DeepEqual(vs, expected) {
+		t.Errorf("purgeable %+v, want %+v", vs, expected)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestDeleteAndPurge(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.ExpectExec(regexp.QuoteMeta(deleteDatasetQuery)).
+		WithArgs("1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	db.ExpectExec(regexp.QuoteMeta(deleteModelQuery)).
+		WithArgs("2").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	db.ExpectExec(regexp.QuoteMeta(purgeDatasetQuery)).
+		WithArgs("1").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	store := NewStore(db)
+	ctx := context.Background()
+
+	if deleted, err := store.deleteWithQuerier(ctx, db, KindDataset, "1"); err != nil || !deleted {
+		t.Errorf("expected dataset to be deleted, got %t, %v", deleted, err)
+	}
+	if deleted, err := store.deleteWithQuerier(ctx, db, KindModel, "2"); err != nil || deleted {
+		t.Errorf("expected protected model to be kept, got %t, %v", deleted, err)
+	}
+	if purged, err := store.purgeWithQuerier(ctx, db, KindDataset, "1"); err != nil || !purged {
+		t.Errorf("expected dataset to be purged, got %t, %v", purged, err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.ExpectQuery(regexp.QuoteMeta(modelFilesQuery)).
+		WithArgs("3").
+		WillReturnRows(db.NewRows([]string{"files"}).
+			AddRow(map[string]uploads.FileRef{
+				"trained_model": {Provider: uploads.ProviderFileSystem, FileName: "model.pkl", Path: "models/3"},
+			}))
+
+	files, err := NewStore(db).filesWithQuerier(context.Background(), db, KindModel, "3")
+	if err != nil {
+		t.Fatalf("could not list files: %s", err)
+	}
+	expected := []uploads.FileRef{{Provider: uploads.ProviderFileSystem, FileName: "model.pkl", Path: "models/3"}}
+	if !reflect.DeepEqual(files, expected) {
+		t.Errorf("files %+v, want %+v", files, expected)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -46,8 +46,21 @@ func authMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		identity, err := auth.IdentityFromToken(userInfo)
+		if err != nil {
+			log.Printf("invalid token: %s\n", err.Error())
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(&internal.Error{
+				Code:    http.StatusUnauthorized,
+				Message: "Unauthorized",
+				Reason:  fmt.Sprintf("invalid token: %s", err.Error()),
+			})
+			return
+		}
+
 		// Store user info in context
 		ctx := context.WithValue(r.Context(), CtxKeyUser, userInfo)
+		ctx = auth.NewContext(ctx, identity)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
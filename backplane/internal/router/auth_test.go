@@ -0,0 +1,96 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/heldtogether/traintrack/internal/devidp"
+)
+
+func TestAuthMiddleware(t *testing.T) {
+	idp := devidp.NewTestServer(t, devidp.DefaultConfig())
+	idp.SetEnv(t)
+
+	tests := []struct {
+		name           string
+		header         string
+		expectedStatus int
+		expectedUser   string
+	}{
+		{
+			name:           "valid token",
+			header:         "Bearer " + idp.MustAccessToken(t, "dev|1"),
+			expectedStatus: http.StatusOK,
+			expectedUser:   "dev|1",
+		},
+		{
+			name:           "missing header",
+			header:         "",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "not a bearer token",
+			header:         "Basic abc",
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "garbage token",
+			header:         "Bearer not-a-jwt",
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotUser string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if token, ok := r.Context().Value(CtxKeyUser).(*oidc.IDToken); ok {
+					gotUser = token.Subject
+				}
+				if id, ok := auth.IdentityFromContext(r.Context()); ok && id.Subject != gotUser {
+					t.Errorf("identity %q doesn't match token subject %q", id.Subject, gotUser)
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/datasets", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rr := httptest.NewRecorder()
+
+			authMiddleware(next).ServeHTTP(rr, req)
+
+			if rr.Code != tc.expectedStatus {
+				t.Errorf("status mismatch - wanted %d, got %d", tc.expectedStatus, rr.Code)
+			}
+			if gotUser != tc.expectedUser {
+				t.Errorf("user mismatch - wanted %q, got %q", tc.expectedUser, gotUser)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareRejectsWrongAudience(t *testing.T) {
+	conf := devidp.DefaultConfig()
+	idp := devidp.NewTestServer(t, conf)
+	idp.SetEnv(t)
+	t.Setenv("TRAINTRACK_AUTH_NAME", "https://some-other-api")
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/datasets", nil)
+	req.Header.Set("Authorization", "Bearer "+idp.MustAccessToken(t, "dev|1"))
+	rr := httptest.NewRecorder()
+
+	authMiddleware(next).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for token with wrong audience, got %d", rr.Code)
+	}
+}
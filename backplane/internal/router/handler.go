@@ -1,16 +1,33 @@
 package router
 
 import (
+	"context"
+	"crypto/ed25519"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/audit"
 	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/heldtogether/traintrack/internal/compare"
 	"github.com/heldtogether/traintrack/internal/datasets"
+	"github.com/heldtogether/traintrack/internal/diff"
+	"github.com/heldtogether/traintrack/internal/erasure"
+	"github.com/heldtogether/traintrack/internal/evaluations"
+	"github.com/heldtogether/traintrack/internal/holds"
+	"github.com/heldtogether/traintrack/internal/kms"
+	"github.com/heldtogether/traintrack/internal/leaderboard"
+	"github.com/heldtogether/traintrack/internal/metrics"
 	"github.com/heldtogether/traintrack/internal/models"
+	"github.com/heldtogether/traintrack/internal/provenance"
+	"github.com/heldtogether/traintrack/internal/retention"
+	"github.com/heldtogether/traintrack/internal/runs"
+	"github.com/heldtogether/traintrack/internal/signing"
 	"github.com/heldtogether/traintrack/internal/uploads"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -25,16 +42,19 @@ func Setup(conn *pgxpool.Pool) http.Handler {
 	datasetsStore := datasets.NewStore(conn)
 	uploadsStore := uploads.NewStore(conn)
 	modelsStore := models.NewStore(conn)
+	auditStore := audit.NewStore(conn)
 
-	fs := &uploads.FileSystemStore{
-		BaseDir: "./files/",
-	}
+	holdsStore := holds.NewStore(conn)
+
+	fs := artefactStore("./files/", holdsStore)
+	startRetention(conn, fs, auditStore)
 
 	datasetsCreator := datasets.NewCreator(
 		datasetsStore,
 		uploadsStore,
 		fs,
 		conn,
+		auditStore,
 	)
 
 	modelsCreator := models.NewCreator(
@@ -42,16 +62,105 @@ func Setup(conn *pgxpool.Pool) http.Handler {
 		uploadsStore,
 		fs,
 		conn,
+		auditStore,
+	)
+	datasetsHandler := datasets.NewHandler(
+		datasetsCreator,
+		datasetsStore,
+		datasets.NewVerifier(datasetsStore, fs),
+		datasets.NewDeleter(datasetsStore, fs, conn, auditStore),
 	)
-	datasetsHandler := datasets.NewHandler(datasetsCreator, datasetsStore)
 	mux.Handle("/datasets", authMiddleware(http.HandlerFunc(datasetsHandler.Datasets)))
+	diffHandler := diff.NewHandler(diff.NewService(datasetsStore.Record, datasetsStore.Profiles, fs))
+	// Registered before /datasets/{id}, so "trash" and "diff" aren't taken
+	// for ids.
+	mux.Handle("/datasets/trash", authMiddleware(http.HandlerFunc(datasetsHandler.Trash)))
+	mux.Handle("/datasets/diff", authMiddleware(http.HandlerFunc(diffHandler.Diff)))
+	mux.Handle("/datasets/{id}", authMiddleware(http.HandlerFunc(datasetsHandler.Dataset)))
+	mux.Handle("/datasets/{id}/verify", authMiddleware(http.HandlerFunc(datasetsHandler.Verify)))
+	mux.Handle("/datasets/{id}/restore", authMiddleware(http.HandlerFunc(datasetsHandler.Restore)))
+	mux.Handle("/datasets/{id}/purge", authMiddleware(http.HandlerFunc(datasetsHandler.Purge)))
+
+	leaderboardHandler := leaderboard.NewHandler(leaderboard.NewStore(conn))
+	mux.Handle("/datasets/{id}/leaderboard", authMiddleware(http.HandlerFunc(leaderboardHandler.Leaderboard)))
 
-	uploadsHandler := uploads.NewHandler(uploadsStore, fs, nil)
+	uploadsHandler := uploads.NewHandler(uploadsStore, fs, scanPolicy(), auditStore, nil)
 	mux.Handle("/uploads", authMiddleware(http.HandlerFunc(uploadsHandler.Uploads)))
 	mux.Handle("/uploads/{id}/{filename}", authMiddleware(http.HandlerFunc(uploadsHandler.Upload)))
 
-	modelsHandler := models.NewHandler(modelsCreator, modelsStore)
+	signingService := signing.NewService(
+		signing.NewStore(conn),
+		modelsStore,
+		conn,
+		auditStore,
+	)
+
+	var guards []models.PromotionGuard
+	if os.Getenv("TRAINTRACK_REQUIRE_SIGNED_PROMOTION") == "true" {
+		guards = append(guards, signing.NewGuard(signingService))
+	}
+	if os.Getenv("TRAINTRACK_BLOCK_FLAGGED_PROMOTION") == "true" {
+		guards = append(guards, models.NewScanGuard())
+	}
+
+	modelsHandler := models.NewHandler(
+		modelsCreator,
+		modelsStore,
+		models.NewVerifier(modelsStore, fs),
+		models.NewPromoter(modelsStore, conn, auditStore, guards...),
+		models.NewDeleter(modelsStore, fs, conn, auditStore),
+	)
 	mux.Handle("/models", authMiddleware(http.HandlerFunc(modelsHandler.Models)))
+	mux.Handle("/models/trash", authMiddleware(http.HandlerFunc(modelsHandler.Trash)))
+	compareHandler := compare.NewHandler(compare.NewService(modelsStore.Record, datasetsStore.Record))
+	mux.Handle("/models/compare", authMiddleware(http.HandlerFunc(compareHandler.Compare)))
+	mux.Handle("/models/{id}", authMiddleware(http.HandlerFunc(modelsHandler.Model)))
+	mux.Handle("/models/{id}/verify", authMiddleware(http.HandlerFunc(modelsHandler.Verify)))
+	mux.Handle("/models/{id}/promote", authMiddleware(http.HandlerFunc(modelsHandler.Promote)))
+	mux.Handle("/models/{id}/restore", authMiddleware(http.HandlerFunc(modelsHandler.Restore)))
+	mux.Handle("/models/{id}/purge", authMiddleware(http.HandlerFunc(modelsHandler.Purge)))
+
+	signingHandler := signing.NewHandler(signingService, signingService)
+	mux.Handle("/models/{id}/signatures", authMiddleware(http.HandlerFunc(signingHandler.Signatures)))
+	mux.Handle("/keys", authMiddleware(http.HandlerFunc(signingHandler.Keys)))
+	mux.Handle("/keys/{id}", authMiddleware(http.HandlerFunc(signingHandler.Key)))
+
+	provenanceHandler := provenance.NewHandler(provenance.NewService(
+		modelsStore.Record,
+		datasetsStore.Record,
+		attestationKey(),
+	))
+	mux.Handle("/models/{id}/provenance", authMiddleware(http.HandlerFunc(provenanceHandler.Provenance)))
+	mux.Handle("/provenance/key", authMiddleware(http.HandlerFunc(provenanceHandler.Key)))
+
+	erasureHandler := erasure.NewHandler(erasure.NewService(erasure.NewStore(conn), fs, conn, auditStore))
+	mux.Handle("/erasures", authMiddleware(http.HandlerFunc(erasureHandler.Erasures)))
+	mux.Handle("/erasures/{id}", authMiddleware(http.HandlerFunc(erasureHandler.Erasure)))
+
+	holdsHandler := holds.NewHandler(holds.NewService(holdsStore, conn, auditStore))
+	mux.Handle("/holds", authMiddleware(http.HandlerFunc(holdsHandler.Holds)))
+	mux.Handle("/holds/{id}", authMiddleware(http.HandlerFunc(holdsHandler.Hold)))
+
+	runsHandler := runs.NewHandler(runs.NewService(runs.NewStore(conn), modelsCreator, conn, auditStore))
+	mux.Handle("/runs", authMiddleware(http.HandlerFunc(runsHandler.Runs)))
+	mux.Handle("/runs/{id}", authMiddleware(http.HandlerFunc(runsHandler.Run)))
+	mux.Handle("/runs/{id}/heartbeat", authMiddleware(http.HandlerFunc(runsHandler.Heartbeat)))
+	mux.Handle("/runs/{id}/artefacts", authMiddleware(http.HandlerFunc(runsHandler.Artefacts)))
+	mux.Handle("/runs/{id}/finalize", authMiddleware(http.HandlerFunc(runsHandler.Finalize)))
+
+	metricsHandler := metrics.NewHandler(metrics.NewStore(conn))
+	mux.Handle("/models/{id}/metrics", authMiddleware(http.HandlerFunc(metricsHandler.ModelMetrics)))
+	mux.Handle("/runs/{id}/metrics", authMiddleware(http.HandlerFunc(metricsHandler.RunMetrics)))
+
+	evaluationsHandler := evaluations.NewHandler(evaluations.NewStore(conn))
+	mux.Handle("/models/{id}/evaluations", authMiddleware(http.HandlerFunc(evaluationsHandler.ModelEvaluations)))
+	mux.Handle("/datasets/{id}/evaluations", authMiddleware(http.HandlerFunc(evaluationsHandler.DatasetEvaluations)))
+	mux.Handle("/evaluations/matrix", authMiddleware(http.HandlerFunc(evaluationsHandler.Matrix)))
+
+	mux.Handle("/me", authMiddleware(http.HandlerFunc(auth.HandleMe)))
+
+	auditHandler := audit.NewHandler(auditStore)
+	mux.Handle("/audit", authMiddleware(http.HandlerFunc(auditHandler.Audit)))
 
 	// mux.HandleFunc("/auth/{provider}/login", auth.HandleLogin)
 	// mux.HandleFunc("/auth/{provider}/callback", auth.HandleCallback)
@@ -72,6 +181,105 @@ func Setup(conn *pgxpool.Pool) http.Handler {
 		json.NewEncoder(w).Encode(config)
 	})
 
-	loggedMux := loggingMiddleware(mux)
+	loggedMux := loggingMiddleware(requestInfoMiddleware(mux))
 	return loggedMux
 }
+
+/*
+attestationKey loads the private key provenance is signed with from the
+file named by TRAINTRACK_ATTESTATION_KEY. Without one, provenance is
+served unsigned.
+*/
+func attestationKey() ed25519.PrivateKey {
+	file := os.Getenv("TRAINTRACK_ATTESTATION_KEY")
+	if file == "" {
+		log.Printf("TRAINTRACK_ATTESTATION_KEY is not set, provenance will not be signed")
+		return nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		log.Fatalf("could not read attestation key: %s", err)
+	}
+	key, err := signing.ParsePrivateKey(data)
+	if err != nil {
+		log.Fatalf("could not load attestation key: %s", err)
+	}
+	return key
+}
+
+/*
+artefactStore returns the store for artefacts under baseDir, encrypting
+them at rest if a master key is configured with TRAINTRACK_MASTER_KEYS or
+TRAINTRACK_KMS_PLUGIN. It is only ever handed out behind a holds.Guard, so
+every purge, whether by hand, by retention or by an erasure, refuses to
+remove a held version's artefacts.
+*/
+func artefactStore(baseDir string, holdsStore *holds.Store) uploads.FileStore {
+	fs := &uploads.FileSystemStore{
+		BaseDir: baseDir,
+	}
+
+	keys, err := kms.FromEnv()
+	if err != nil {
+		log.Fatalf("could not load master keys: %s", err)
+	}
+	if keys == nil {
+		log.Printf("no master key is configured, artefacts will be stored unencrypted")
+		return holds.NewGuard(holdsStore, fs)
+	}
+
+	log.Printf("encrypting artefacts with master key %s", keys.CurrentKeyID())
+	return holds.NewGuard(holdsStore, &uploads.EncryptedStore{FileSystemStore: fs, Keys: keys})
+}
+
+/*
+scanPolicy loads the scanners uploads are run past from the config file
+named by TRAINTRACK_SCANNERS. Without one, only pickles are scanned.
+*/
+func scanPolicy() *uploads.ScanPolicy {
+	file := os.Getenv("TRAINTRACK_SCANNERS")
+	if file == "" {
+		return uploads.DefaultScanPolicy()
+	}
+
+	p, err := uploads.LoadScanPolicy(file)
+	if err != nil {
+		log.Fatalf("could not load scanners: %s", err)
+	}
+	return p
+}
+
+/*
+startRetention runs the retention policy in the file named by
+TRAINTRACK_RETENTION_POLICY in the background, every
+TRAINTRACK_RETENTION_INTERVAL or daily. Without a policy, nothing is
+ever deleted.
+*/
+func startRetention(conn *pgxpool.Pool, fs uploads.FileStore, a retention.AuditRecorder) {
+	file := os.Getenv("TRAINTRACK_RETENTION_POLICY")
+	if file == "" {
+		return
+	}
+
+	policy, err := retention.LoadPolicy(file)
+	if err != nil {
+		log.Fatalf("could not load retention policy: %s", err)
+	}
+
+	interval := 24 * time.Hour
+	if s := os.Getenv("TRAINTRACK_RETENTION_INTERVAL"); s != "" {
+		if interval, err = time.ParseDuration(s); err != nil || interval <= 0 {
+			log.Fatalf("invalid TRAINTRACK_RETENTION_INTERVAL %q", s)
+		}
+	}
+
+	job := retention.NewJob(policy, retention.NewStore(conn), fs, conn, a)
+	job.DryRun = os.Getenv("TRAINTRACK_RETENTION_DRY_RUN") == "true"
+	if job.DryRun {
+		log.Printf("retention is in dry run mode, nothing will be deleted")
+	}
+
+	log.Printf("applying %d retention rules every %s", len(policy.Rules), interval)
+	go job.Schedule(context.Background(), interval)
+}
@@ -0,0 +1,55 @@
+package router
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/heldtogether/traintrack/internal/audit"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+/*
+requestInfoMiddleware gives every request an ID, echoed back in the
+X-Request-ID header, and stores it in the context alongside the caller's
+IP address and user agent for the audit log. An incoming X-Request-ID is
+kept so requests can be traced across proxies.
+*/
+func requestInfoMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if requestID == "" || len(requestID) > 128 {
+			requestID = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+
+		ctx := audit.WithRequestInfo(r.Context(), audit.RequestInfo{
+			RequestID: requestID,
+			IP:        clientIP(r),
+			UserAgent: r.UserAgent(),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+/*
+clientIP returns the caller's address. X-Forwarded-For is only believed
+when TRAINTRACK_TRUST_PROXY_HEADERS is set, as otherwise anyone could
+forge it.
+*/
+func clientIP(r *http.Request) string {
+	if os.Getenv("TRAINTRACK_TRUST_PROXY_HEADERS") == "true" {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,76 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/heldtogether/traintrack/internal/audit"
+)
+
+func TestRequestInfoMiddleware(t *testing.T) {
+	tests := []struct {
+		name          string
+		requestID     string
+		forwardedFor  string
+		trustProxy    bool
+		wantIP        string
+		wantRequestID string
+	}{
+		{
+			name:          "keeps incoming request id",
+			requestID:     "abc-123",
+			wantIP:        "192.0.2.1",
+			wantRequestID: "abc-123",
+		},
+		{
+			name:         "ignores forwarded for by default",
+			forwardedFor: "203.0.113.9",
+			wantIP:       "192.0.2.1",
+		},
+		{
+			name:         "uses forwarded for when trusted",
+			forwardedFor: "203.0.113.9, 10.0.0.1",
+			trustProxy:   true,
+			wantIP:       "203.0.113.9",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.trustProxy {
+				t.Setenv("TRAINTRACK_TRUST_PROXY_HEADERS", "true")
+			}
+
+			var got audit.RequestInfo
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = audit.RequestInfoFromContext(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/datasets", nil)
+			req.Header.Set("User-Agent", "traintrack-test")
+			if tc.requestID != "" {
+				req.Header.Set("X-Request-ID", tc.requestID)
+			}
+			if tc.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+			}
+			rr := httptest.NewRecorder()
+
+			requestInfoMiddleware(next).ServeHTTP(rr, req)
+
+			if got.IP != tc.wantIP {
+				t.Errorf("ip mismatch - wanted %q, got %q", tc.wantIP, got.IP)
+			}
+			if got.UserAgent != "traintrack-test" {
+				t.Errorf("user agent not captured, got %q", got.UserAgent)
+			}
+			if got.RequestID == "" || rr.Header().Get("X-Request-ID") != got.RequestID {
+				t.Errorf("request id not echoed: header %q, context %q", rr.Header().Get("X-Request-ID"), got.RequestID)
+			}
+			if tc.wantRequestID != "" && got.RequestID != tc.wantRequestID {
+				t.Errorf("request id mismatch - wanted %q, got %q", tc.wantRequestID, got.RequestID)
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+/*
+Package runs records training runs as they happen.
+
+A model version is only created once training has produced something
+worth keeping, so on its own the registry can't show runs which are
+still going or which failed. A Run is started when training starts, with
+its params and tags:
+
+	r, err := service.Start(ctx, &Run{Name: "prices-xgb", Params: params})
+
+While it runs, the trainer sends heartbeats, so a run which died without
+saying so can be spotted, and attaches artefacts from uploads as they
+are written. It then ends the run as finished or failed. A run which
+didn't fail can be finalized into a model version, which takes the run's
+artefacts and, unless others are given, its params as the model's
+config:
+
+	m, err := service.Finalize(ctx, tenant, r.ID, &models.Model{Name: "prices", Version: "1.0.0", ...})
+
+Starting, ending, attaching to and finalizing a run are recorded in the
+audit log. Heartbeats aren't.
+*/
+package runs
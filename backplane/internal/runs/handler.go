@@ -0,0 +1,346 @@
+package runs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/heldtogether/traintrack/internal/models"
+	"github.com/heldtogether/traintrack/internal/uploads"
+)
+
+/*
+Runner allows runs to be listed, started, changed and finalized.
+*/
+type Runner interface {
+	Runs(ctx context.Context, tenant string, statuses []Status) ([]*Run, error)
+	Run(ctx context.Context, tenant string, id string) (*Run, error)
+	Start(ctx context.Context, r *Run) (*Run, error)
+	Heartbeat(ctx context.Context, tenant string, id string) (*Run, error)
+	End(ctx context.Context, tenant string, id string, status Status) (*Run, error)
+	Attach(ctx context.Context, tenant string, id string, uploadID string) (*Run, error)
+	Finalize(ctx context.Context, tenant string, id string, m *models.Model) (*models.Model, error)
+}
+
+type Handler struct {
+	r Runner
+}
+
+func NewHandler(r Runner) *Handler {
+	return &Handler{
+		r: r,
+	}
+}
+
+/*
+Runs routes and handles requests for the caller's tenant's runs. It
+should be registered on the router under something sensible, like /runs.
+*/
+func (h *Handler) Runs(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.List(w, r)
+	case http.MethodPost:
+		h.Start(w, r)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+/*
+Run handles requests for a single run, registered under /runs/{id}. A
+run is ended by patching its status.
+*/
+func (h *Handler) Run(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.Get(w, r)
+	case http.MethodPatch:
+		h.End(w, r)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+/*
+List returns the caller's tenant's runs, optionally only those with one
+of the statuses given by the status query parameter, which may be
+repeated or comma separated.
+*/
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) {
+	var statuses []Status
+	for _, param := range r.URL.Query()["status"] {
+		for _, s := range strings.Split(param, ",") {
+			status := Status(strings.TrimSpace(s))
+			if !status.Valid() {
+				log.Printf("failed to list runs: unknown status %q", status)
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(&internal.Error{
+					Code:    http.StatusBadRequest,
+					Message: "Failed to list runs",
+					Reason:  fmt.Sprintf("unknown status %q", status),
+				})
+				return
+			}
+			statuses = append(statuses, status)
+		}
+	}
+
+	rs, err := h.r.Runs(r.Context(), auth.TenantFromContext(r.Context()), statuses)
+	if err != nil {
+		log.Printf("failed to list runs: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to list runs",
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(rs)
+}
+
+func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	run, err := h.r.Run(r.Context(), auth.TenantFromContext(r.Context()), id)
+	if err != nil {
+		writeError(w, "Failed to fetch run", id, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(run)
+}
+
+func (h *Handler) Start(w http.ResponseWriter, r *http.Request) {
+	var run Run
+	if !decode(w, r, "Failed to start run", &run) {
+		return
+	}
+
+	details := map[string]string{}
+	if run.Name == "" {
+		details["name"] = "name is a required field"
+	}
+	if len(run.Params) > 0 && !isObject(run.Params) {
+		details["params"] = "params must be an object"
+	}
+	if len(details) > 0 {
+		badInput(w, "Failed to start run", details)
+		return
+	}
+
+	started, err := h.r.Start(r.Context(), &run)
+	if err != nil {
+		log.Printf("failed to start run: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to start run",
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(started)
+}
+
+/*
+End finishes or fails a run, from a body like {"status": "failed"}.
+*/
+func (h *Handler) End(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var body struct {
+		Status Status `json:"status"`
+	}
+	if !decode(w, r, "Failed to end run", &body) {
+		return
+	}
+	if body.Status != StatusFinished && body.Status != StatusFailed {
+		badInput(w, "Failed to end run", map[string]string{"status": "status must be finished or failed"})
+		return
+	}
+
+	run, err := h.r.End(r.Context(), auth.TenantFromContext(r.Context()), id, body.Status)
+	if err != nil {
+		writeError(w, "Failed to end run", id, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(run)
+}
+
+/*
+Heartbeat records that a run is still going. It is registered under
+/runs/{id}/heartbeat.
+*/
+func (h *Handler) Heartbeat(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	run, err := h.r.Heartbeat(r.Context(), auth.TenantFromContext(r.Context()), id)
+	if err != nil {
+		writeError(w, "Failed to record heartbeat", id, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(run)
+}
+
+/*
+Artefacts attaches an upload to a run, from a body like
+{"upload_id": "..."}. It is registered under /runs/{id}/artefacts.
+*/
+func (h *Handler) Artefacts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	var body struct {
+		UploadID string `json:"upload_id"`
+	}
+	if !decode(w, r, "Failed to attach artefact", &body) {
+		return
+	}
+	if body.UploadID == "" {
+		badInput(w, "Failed to attach artefact", map[string]string{"upload_id": "upload_id is a required field"})
+		return
+	}
+
+	run, err := h.r.Attach(r.Context(), auth.TenantFromContext(r.Context()), id, body.UploadID)
+	if err != nil {
+		writeError(w, "Failed to attach artefact", id, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(run)
+}
+
+/*
+Finalize creates a model version from a run, from a body holding the
+model's fields, and answers with the model. It is registered under
+/runs/{id}/finalize.
+*/
+func (h *Handler) Finalize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w)
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	var m models.Model
+	if !decode(w, r, "Failed to finalize run", &m) {
+		return
+	}
+
+	details := map[string]string{}
+	if m.Name == "" {
+		details["name"] = "name is a required field"
+	}
+	if m.Version == "" {
+		details["version"] = "version is a required field"
+	}
+	if m.Description == "" {
+		details["description"] = "description is a required field"
+	}
+	if len(details) > 0 {
+		badInput(w, "Failed to finalize run", details)
+		return
+	}
+
+	created, err := h.r.Finalize(r.Context(), auth.TenantFromContext(r.Context()), id, &m)
+	if err != nil {
+		writeError(w, "Failed to finalize run", id, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(created)
+}
+
+/*
+decode reads the request body into v, answering with a 400 and returning
+false if it can't.
+*/
+func decode(w http.ResponseWriter, r *http.Request, message string, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		log.Printf("failed to decode body: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: message,
+			Reason:  fmt.Sprintf("could not parse body: %s", err),
+		})
+		return false
+	}
+	return true
+}
+
+func badInput(w http.ResponseWriter, message string, details map[string]string) {
+	log.Printf("failed to validate input: %v", details)
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    http.StatusBadRequest,
+		Message: message,
+		Reason:  "bad input",
+		Details: details,
+	})
+}
+
+func isObject(data json.RawMessage) bool {
+	var v map[string]any
+	return json.Unmarshal(data, &v) == nil && v != nil
+}
+
+/*
+writeError logs err and answers with it, as a 404 if the run or upload
+wasn't found or a 409 if the run can't be changed that way.
+*/
+func writeError(w http.ResponseWriter, message string, id string, err error) {
+	log.Printf("%s %s: %s", strings.ToLower(message), id, err)
+
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, ErrEnded),
+		errors.Is(err, ErrFinalized),
+		errors.Is(err, ErrFailed),
+		errors.Is(err, ErrAttached),
+		errors.Is(err, uploads.ErrQuarantined):
+		code = http.StatusConflict
+	}
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    code,
+		Message: message,
+		Reason:  err.Error(),
+	})
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    http.StatusMethodNotAllowed,
+		Message: "Method not allowed",
+		Reason:  "",
+	})
+}
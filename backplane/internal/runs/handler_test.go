@@ -0,0 +1,295 @@
+package runs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/models"
+)
+
+type mockRunner struct {
+	RunsFn      func(ctx context.Context, tenant string, statuses []Status) ([]*Run, error)
+	RunFn       func(ctx context.Context, tenant string, id string) (*Run, error)
+	StartFn     func(ctx context.Context, r *Run) (*Run, error)
+	HeartbeatFn func(ctx context.Context, tenant string, id string) (*Run, error)
+	EndFn       func(ctx context.Context, tenant string, id string, status Status) (*Run, error)
+	AttachFn    func(ctx context.Context, tenant string, id string, uploadID string) (*Run, error)
+	FinalizeFn  func(ctx context.Context, tenant string, id string, m *models.Model) (*models.Model, error)
+}
+
+func (m *mockRunner) Runs(ctx context.Context, tenant string, statuses []Status) ([]*Run, error) {
+	return m.RunsFn(ctx, tenant, statuses)
+}
+
+func (m *mockRunner) Run(ctx context.Context, tenant string, id string) (*Run, error) {
+	return m.RunFn(ctx, tenant, id)
+}
+
+func (m *mockRunner) Start(ctx context.Context, r *Run) (*Run, error) {
+	return m.StartFn(ctx, r)
+}
+
+func (m *mockRunner) Heartbeat(ctx context.Context, tenant string, id string) (*Run, error) {
+	return m.HeartbeatFn(ctx, tenant, id)
+}
+
+func (m *mockRunner) End(ctx context.Context, tenant string, id string, status Status) (*Run, error) {
+	return m.EndFn(ctx, tenant, id, status)
+}
+
+func (m *mockRunner) Attach(ctx context.Context, tenant string, id string, uploadID string) (*Run, error) {
+	return m.AttachFn(ctx, tenant, id, uploadID)
+}
+
+func (m *mockRunner) Finalize(ctx context.Context, tenant string, id string, model *models.Model) (*models.Model, error) {
+	return m.FinalizeFn(ctx, tenant, id, model)
+}
+
+var handlerTime = time.Date(2025, 7, 9, 9, 0, 0, 0, time.UTC)
+
+func TestRunsHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		body             string
+		runner           *mockRunner
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "GET success",
+			method: http.MethodGet,
+			path:   "/runs?status=running,failed",
+			runner: &mockRunner{RunsFn: func(_ context.Context, tenant string, statuses []Status) ([]*Run, error) {
+				if !reflect.DeepEqual(statuses, []Status{StatusRunning, StatusFailed}) {
+					return nil, fmt.Errorf("unexpected statuses %v", statuses)
+				}
+				return []*Run{}, nil
+			}},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `[]`,
+		},
+		{
+			name:             "GET failure - unknown status",
+			method:           http.MethodGet,
+			path:             "/runs?status=paused",
+			runner:           &mockRunner{},
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to list runs", "reason": "unknown status \"paused\""}`,
+		},
+		{
+			name:   "POST success",
+			method: http.MethodPost,
+			path:   "/runs",
+			body:   `{"name": "prices-xgb", "params": {"depth": 6}, "tags": {"team": "pricing"}}`,
+			runner: &mockRunner{StartFn: func(_ context.Context, r *Run) (*Run, error) {
+				started := *r
+				started.ID = "r1"
+				started.Status = StatusRunning
+				started.UploadIds = map[string]string{}
+				started.StartedAt = handlerTime
+				started.HeartbeatAt = handlerTime
+				started.CreatedBy = "dev|1"
+				return &started, nil
+			}},
+			expectedStatus:   http.StatusCreated,
+			expectedContains: `{"id": "r1", "name": "prices-xgb", "status": "running", "params": {"depth": 6}, "tags": {"team": "pricing"}, "artefacts": {}, "started_at": "2025-07-09T09:00:00Z", "heartbeat_at": "2025-07-09T09:00:00Z", "created_by": "dev|1"}`,
+		},
+		{
+			name:             "POST failure - failed validation",
+			method:           http.MethodPost,
+			path:             "/runs",
+			body:             `{"params": [1, 2]}`,
+			runner:           &mockRunner{},
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to start run", "reason": "bad input", "details": {"name": "name is a required field", "params": "params must be an object"}}`,
+		},
+		{
+			name:             "METHOD failure",
+			method:           http.MethodDelete,
+			path:             "/runs",
+			runner:           &mockRunner{},
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+			req := httptest.NewRequest(tc.method, tc.path, body)
+			rr := httptest.NewRecorder()
+
+			NewHandler(tc.runner).Runs(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func TestRunHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		route            func(h *Handler) http.HandlerFunc
+		body             string
+		runner           *mockRunner
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "GET not found",
+			method: http.MethodGet,
+			route:  func(h *Handler) http.HandlerFunc { return h.Run },
+			runner: &mockRunner{RunFn: func(_ context.Context, tenant string, id string) (*Run, error) {
+				return nil, fmt.Errorf("run %s: %w", id, internal.ErrNotFound)
+			}},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Failed to fetch run", "reason": "run r1: not found"}`,
+		},
+		{
+			name:   "PATCH success",
+			method: http.MethodPatch,
+			route:  func(h *Handler) http.HandlerFunc { return h.Run },
+			body:   `{"status": "failed"}`,
+			runner: &mockRunner{EndFn: func(_ context.Context, tenant string, id string, status Status) (*Run, error) {
+				return &Run{ID: id, Name: "prices-xgb", Status: status, UploadIds: map[string]string{}, StartedAt: handlerTime, EndedAt: &handlerTime, HeartbeatAt: handlerTime}, nil
+			}},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"id": "r1", "name": "prices-xgb", "status": "failed", "artefacts": {}, "started_at": "2025-07-09T09:00:00Z", "ended_at": "2025-07-09T09:00:00Z", "heartbeat_at": "2025-07-09T09:00:00Z", "created_by": ""}`,
+		},
+		{
+			name:             "PATCH failure - bad status",
+			method:           http.MethodPatch,
+			route:            func(h *Handler) http.HandlerFunc { return h.Run },
+			body:             `{"status": "running"}`,
+			runner:           &mockRunner{},
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to end run", "reason": "bad input", "details": {"status": "status must be finished or failed"}}`,
+		},
+		{
+			name:   "POST heartbeat failure - ended",
+			method: http.MethodPost,
+			route:  func(h *Handler) http.HandlerFunc { return h.Heartbeat },
+			runner: &mockRunner{HeartbeatFn: func(_ context.Context, tenant string, id string) (*Run, error) {
+				return nil, fmt.Errorf("run %s has %w", id, ErrEnded)
+			}},
+			expectedStatus:   http.StatusConflict,
+			expectedContains: `{"code": 409, "error": "Failed to record heartbeat", "reason": "run r1 has already ended"}`,
+		},
+		{
+			name:   "POST artefact failure - attached",
+			method: http.MethodPost,
+			route:  func(h *Handler) http.HandlerFunc { return h.Artefacts },
+			body:   `{"upload_id": "u1"}`,
+			runner: &mockRunner{AttachFn: func(_ context.Context, tenant string, id string, uploadID string) (*Run, error) {
+				return nil, fmt.Errorf("upload %s is %w", uploadID, ErrAttached)
+			}},
+			expectedStatus:   http.StatusConflict,
+			expectedContains: `{"code": 409, "error": "Failed to attach artefact", "reason": "upload u1 is already attached"}`,
+		},
+		{
+			name:   "POST finalize success",
+			method: http.MethodPost,
+			route:  func(h *Handler) http.HandlerFunc { return h.Finalize },
+			body:   `{"name": "prices", "version": "1.0.0", "description": "xgb", "dataset": "d1"}`,
+			runner: &mockRunner{FinalizeFn: func(_ context.Context, tenant string, id string, m *models.Model) (*models.Model, error) {
+				if m.DatasetId != "d1" {
+					return nil, fmt.Errorf("unexpected dataset %q", m.DatasetId)
+				}
+				return &models.Model{ID: "m1", Name: m.Name, Version: m.Version, Description: m.Description, DatasetId: m.DatasetId, CreatedAt: handlerTime, Stage: models.StageDevelopment}, nil
+			}},
+			expectedStatus:   http.StatusCreated,
+			expectedContains: `{"id": "m1", "name": "prices", "parent": null, "version": "1.0.0", "description": "xgb", "artefacts": null, "dataset": "d1", "config": null, "metadata": null, "environment": null, "evaluation": null, "created_at": "2025-07-09T09:00:00Z", "created_by": "", "stage": "development"}`,
+		},
+		{
+			name:             "POST finalize failure - failed validation",
+			method:           http.MethodPost,
+			route:            func(h *Handler) http.HandlerFunc { return h.Finalize },
+			body:             `{}`,
+			runner:           &mockRunner{},
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to finalize run", "reason": "bad input", "details": {"name": "name is a required field", "version": "version is a required field", "description": "description is a required field"}}`,
+		},
+		{
+			name:   "POST finalize failure - failed run",
+			method: http.MethodPost,
+			route:  func(h *Handler) http.HandlerFunc { return h.Finalize },
+			body:   `{"name": "prices", "version": "1.0.0", "description": "xgb"}`,
+			runner: &mockRunner{FinalizeFn: func(_ context.Context, tenant string, id string, m *models.Model) (*models.Model, error) {
+				return nil, fmt.Errorf("run %s %w, so it can't be finalized", id, ErrFailed)
+			}},
+			expectedStatus:   http.StatusConflict,
+			expectedContains: `{"code": 409, "error": "Failed to finalize run", "reason": "run r1 failed, so it can't be finalized"}`,
+		},
+		{
+			name:             "METHOD failure",
+			method:           http.MethodGet,
+			route:            func(h *Handler) http.HandlerFunc { return h.Finalize },
+			runner:           &mockRunner{},
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var body io.Reader
+			if tc.body != "" {
+				body = strings.NewReader(tc.body)
+			}
+			req := httptest.NewRequest(tc.method, "/runs/r1", body)
+			req = mux.SetURLVars(req, map[string]string{"id": "r1"})
+			rr := httptest.NewRecorder()
+
+			tc.route(NewHandler(tc.runner))(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func checkResponse(t *testing.T, got *http.Response, expectedStatus int, expected string) {
+	defer got.Body.Close()
+
+	body, err := io.ReadAll(got.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %s", err)
+	}
+
+	if expectedStatus != got.StatusCode {
+		t.Errorf("status mismatch - wanted %d, got %d", expectedStatus, got.StatusCode)
+	}
+
+	var gotData any
+	if err := json.Unmarshal(body, &gotData); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v\nbody: %s", err, string(body))
+	}
+
+	var expectedData any
+	if err := json.Unmarshal([]byte(expected), &expectedData); err != nil {
+		t.Fatalf("failed to unmarshal expected value: %v\njson: %s", err, expected)
+	}
+
+	if !reflect.DeepEqual(expectedData, gotData) {
+		t.Errorf("JSON mismatch:\nexpected: %+v\ngot: %+v", expectedData, gotData)
+	}
+}
@@ -0,0 +1,59 @@
+package runs
+
+import (
+	"encoding/json"
+	"time"
+)
+
+/*
+Status is where a run is. Runs start StatusRunning and end either
+StatusFinished or StatusFailed, after which they can't change.
+*/
+type Status string
+
+const (
+	StatusRunning  Status = "running"
+	StatusFinished Status = "finished"
+	StatusFailed   Status = "failed"
+)
+
+func (s Status) Valid() bool {
+	switch s {
+	case StatusRunning, StatusFinished, StatusFailed:
+		return true
+	}
+	return false
+}
+
+type Run struct {
+	ID     string            `json:"id"`
+	Name   string            `json:"name"`
+	Status Status            `json:"status"`
+	Params json.RawMessage   `json:"params,omitempty"`
+	Tags   map[string]string `json:"tags,omitempty"`
+
+	// UploadIds are the uploads attached to the run, keyed by artefact
+	// name, as they are for models.
+	UploadIds map[string]string `json:"artefacts"`
+
+	// StartedAt, EndedAt and HeartbeatAt are set by the server. A running
+	// run whose last heartbeat is long ago has probably died.
+	StartedAt   time.Time  `json:"started_at"`
+	EndedAt     *time.Time `json:"ended_at,omitempty"`
+	HeartbeatAt time.Time  `json:"heartbeat_at"`
+
+	// ModelID is the model version the run was finalized into, if any.
+	ModelID *string `json:"model,omitempty"`
+
+	// CreatedBy and Tenant are set by the server from the verified token.
+	// Any values sent by the client are ignored.
+	CreatedBy string `json:"created_by"`
+	Tenant    string `json:"tenant,omitempty"`
+}
+
+/*
+Ended reports whether the run has finished or failed.
+*/
+func (r *Run) Ended() bool {
+	return r.Status != StatusRunning
+}
@@ -0,0 +1,309 @@
+package runs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/heldtogether/traintrack/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrEnded is wrapped when changing a run which has already finished
+	// or failed.
+	ErrEnded = errors.New("already ended")
+	// ErrFinalized is wrapped when finalizing a run a second time.
+	ErrFinalized = errors.New("already finalized")
+	// ErrFailed is wrapped when finalizing a run which failed.
+	ErrFailed = errors.New("failed")
+	// ErrAttached is wrapped when attaching an upload which already
+	// belongs to a dataset, a model or another run.
+	ErrAttached = errors.New("already attached")
+)
+
+type runsStore interface {
+	Runs(ctx context.Context, tenant string, statuses []Status) ([]*Run, error)
+	Run(ctx context.Context, tenant string, id string) (*Run, error)
+	runWithQuerier(ctx context.Context, q Querier, tenant string, id string) (*Run, error)
+	lockWithQuerier(ctx context.Context, q Querier, tenant string, id string) (Status, error)
+	createWithQuerier(ctx context.Context, q Querier, r *Run) error
+	heartbeatWithQuerier(ctx context.Context, q Querier, id string) error
+	endWithQuerier(ctx context.Context, q Querier, id string, status Status) error
+	finalizeWithQuerier(ctx context.Context, q Querier, id string, modelID string) error
+	attachWithQuerier(ctx context.Context, q Querier, id string, uploadID string) error
+}
+
+/*
+ModelCreator creates a model version using the provided Querier, which
+may be a transaction.
+*/
+type ModelCreator interface {
+	CreateWithQuerier(ctx context.Context, q models.Querier, m *models.Model) (*models.Model, error)
+}
+
+/*
+AuditRecorder records an audit event using the provided Querier, which
+may be a transaction.
+*/
+type AuditRecorder interface {
+	RecordWithQuerier(ctx context.Context, q audit.Querier, e *audit.Event) error
+}
+
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type Service struct {
+	s       runsStore
+	creator ModelCreator
+	db      TxBeginner
+	audit   AuditRecorder
+}
+
+func NewService(s *Store, c ModelCreator, db TxBeginner, a AuditRecorder) *Service {
+	return &Service{
+		s:       s,
+		creator: c,
+		db:      db,
+		audit:   a,
+	}
+}
+
+/*
+Runs returns tenant's runs, optionally only those with one of statuses.
+*/
+func (s *Service) Runs(ctx context.Context, tenant string, statuses []Status) ([]*Run, error) {
+	return s.s.Runs(ctx, tenant, statuses)
+}
+
+/*
+Run returns tenant's run id.
+*/
+func (s *Service) Run(ctx context.Context, tenant string, id string) (*Run, error) {
+	return s.s.Run(ctx, tenant, id)
+}
+
+/*
+Start records a new run with r's name, params and tags. The creator and
+tenant are taken from the verified identity on ctx.
+*/
+func (s *Service) Start(ctx context.Context, r *Run) (started *Run, err error) {
+	run := &Run{
+		Name:   r.Name,
+		Params: r.Params,
+		Tags:   r.Tags,
+	}
+	if id, ok := auth.IdentityFromContext(ctx); ok {
+		run.CreatedBy = id.Subject
+		run.Tenant = id.Tenant
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err = s.s.createWithQuerier(ctx, tx, run); err != nil {
+		return nil, err
+	}
+
+	if started, err = s.s.runWithQuerier(ctx, tx, run.Tenant, run.ID); err != nil {
+		return nil, err
+	}
+
+	if err = s.record(ctx, tx, audit.ActionCreate, started, map[string]any{"name": started.Name}); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return started, nil
+}
+
+/*
+Heartbeat records that tenant's run id is still going.
+*/
+func (s *Service) Heartbeat(ctx context.Context, tenant string, id string) (*Run, error) {
+	return s.change(ctx, tenant, id, func(q Querier, status Status) (audit.Action, map[string]any, error) {
+		if status != StatusRunning {
+			return "", nil, fmt.Errorf("run %s has %w", id, ErrEnded)
+		}
+		return "", nil, s.s.heartbeatWithQuerier(ctx, q, id)
+	})
+}
+
+/*
+End marks tenant's run id as finished or failed.
+*/
+func (s *Service) End(ctx context.Context, tenant string, id string, status Status) (*Run, error) {
+	return s.change(ctx, tenant, id, func(q Querier, current Status) (audit.Action, map[string]any, error) {
+		if current != StatusRunning {
+			return "", nil, fmt.Errorf("run %s has %w", id, ErrEnded)
+		}
+		if err := s.s.endWithQuerier(ctx, q, id, status); err != nil {
+			return "", nil, err
+		}
+		return audit.ActionUpdate, map[string]any{"status": status}, nil
+	})
+}
+
+/*
+Attach attaches the upload uploadID to tenant's run id, which must still
+be running.
+*/
+func (s *Service) Attach(ctx context.Context, tenant string, id string, uploadID string) (*Run, error) {
+	return s.change(ctx, tenant, id, func(q Querier, status Status) (audit.Action, map[string]any, error) {
+		if status != StatusRunning {
+			return "", nil, fmt.Errorf("run %s has %w", id, ErrEnded)
+		}
+		if err := s.s.attachWithQuerier(ctx, q, id, uploadID); err != nil {
+			return "", nil, err
+		}
+		return audit.ActionUpdate, map[string]any{"upload": uploadID}, nil
+	})
+}
+
+/*
+Finalize creates the model version m from tenant's run id, marking the
+run as finished. The model's artefacts are the run's, whatever m has, and
+its config is the run's params unless m has one. The model is created,
+and the run updated, in one transaction.
+*/
+func (s *Service) Finalize(ctx context.Context, tenant string, id string, m *models.Model) (created *models.Model, err error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	status, err := s.s.lockWithQuerier(ctx, tx, tenant, id)
+	if err != nil {
+		return nil, err
+	}
+	if status == StatusFailed {
+		return nil, fmt.Errorf("run %s %w, so it can't be finalized", id, ErrFailed)
+	}
+
+	r, err := s.s.runWithQuerier(ctx, tx, tenant, id)
+	if err != nil {
+		return nil, err
+	}
+	if r.ModelID != nil {
+		return nil, fmt.Errorf("run %s is %w as model %s", id, ErrFinalized, *r.ModelID)
+	}
+
+	m.UploadIds = artefacts(r)
+	if len(m.Config) == 0 {
+		m.Config = r.Params
+	}
+
+	if created, err = s.creator.CreateWithQuerier(ctx, tx, m); err != nil {
+		return nil, err
+	}
+
+	if err = s.s.finalizeWithQuerier(ctx, tx, id, created.ID); err != nil {
+		return nil, err
+	}
+
+	details := map[string]any{"model": created.ID, "name": created.Name, "version": created.Version}
+	if err = s.record(ctx, tx, audit.ActionFinalize, r, details); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+/*
+change locks tenant's run id and changes it with fn, which is given the
+run's status and returns what to record in the audit log, if anything,
+all in one transaction.
+*/
+func (s *Service) change(ctx context.Context, tenant string, id string, fn func(q Querier, status Status) (audit.Action, map[string]any, error)) (changed *Run, err error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	status, err := s.s.lockWithQuerier(ctx, tx, tenant, id)
+	if err != nil {
+		return nil, err
+	}
+
+	action, details, err := fn(tx, status)
+	if err != nil {
+		return nil, err
+	}
+
+	if changed, err = s.s.runWithQuerier(ctx, tx, tenant, id); err != nil {
+		return nil, err
+	}
+
+	if action != "" {
+		if err = s.record(ctx, tx, action, changed, details); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
+
+func (s *Service) record(ctx context.Context, q Querier, action audit.Action, r *Run, details map[string]any) error {
+	if s.audit == nil {
+		return nil
+	}
+
+	e := audit.NewEvent(ctx, action, audit.ResourceRun, r.ID).WithDetails(details)
+	if err := s.audit.RecordWithQuerier(ctx, q, e); err != nil {
+		return fmt.Errorf("record audit event: %w", err)
+	}
+	return nil
+}
+
+/*
+artefacts returns the run's uploads for a model, once each. An upload
+with several files is listed under each of their names, but must only be
+moved once, so it's kept under the first of them.
+*/
+func artefacts(r *Run) map[string]string {
+	names := make([]string, 0, len(r.UploadIds))
+	for name := range r.UploadIds {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ids := map[string]string{}
+	seen := map[string]bool{}
+	for _, name := range names {
+		id := r.UploadIds[name]
+		if !seen[id] {
+			seen[id] = true
+			ids[name] = id
+		}
+	}
+	return ids
+}
@@ -0,0 +1,282 @@
+package runs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/heldtogether/traintrack/internal/models"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+type mockStore struct {
+	run   *Run
+	calls *[]string
+}
+
+func (m *mockStore) Runs(_ context.Context, tenant string, statuses []Status) ([]*Run, error) {
+	return nil, nil
+}
+
+func (m *mockStore) Run(_ context.Context, tenant string, id string) (*Run, error) {
+	return m.run, nil
+}
+
+func (m *mockStore) runWithQuerier(_ context.Context, _ Querier, tenant string, id string) (*Run, error) {
+	return m.run, nil
+}
+
+func (m *mockStore) lockWithQuerier(_ context.Context, _ Querier, tenant string, id string) (Status, error) {
+	*m.calls = append(*m.calls, "lock "+id)
+	return m.run.Status, nil
+}
+
+func (m *mockStore) createWithQuerier(_ context.Context, _ Querier, r *Run) error {
+	*m.calls = append(*m.calls, "create "+r.Tenant+" "+r.CreatedBy)
+	r.ID = "r1"
+	r.Status = StatusRunning
+	m.run = r
+	return nil
+}
+
+func (m *mockStore) heartbeatWithQuerier(_ context.Context, _ Querier, id string) error {
+	*m.calls = append(*m.calls, "heartbeat "+id)
+	return nil
+}
+
+func (m *mockStore) endWithQuerier(_ context.Context, _ Querier, id string, status Status) error {
+	*m.calls = append(*m.calls, "end "+id+" "+string(status))
+	return nil
+}
+
+func (m *mockStore) finalizeWithQuerier(_ context.Context, _ Querier, id string, modelID string) error {
+	*m.calls = append(*m.calls, "finalize "+id+" "+modelID)
+	return nil
+}
+
+func (m *mockStore) attachWithQuerier(_ context.Context, _ Querier, id string, uploadID string) error {
+	*m.calls = append(*m.calls, "attach "+id+" "+uploadID)
+	return nil
+}
+
+type mockCreator struct {
+	calls *[]string
+	got   *models.Model
+}
+
+func (m *mockCreator) CreateWithQuerier(_ context.Context, _ models.Querier, model *models.Model) (*models.Model, error) {
+	*m.calls = append(*m.calls, "create model "+model.Name)
+	m.got = model
+	created := *model
+	created.ID = "m1"
+	return &created, nil
+}
+
+type mockAudit struct {
+	events []*audit.Event
+}
+
+func (m *mockAudit) RecordWithQuerier(_ context.Context, _ audit.Querier, e *audit.Event) error {
+	m.events = append(m.events, e)
+	return nil
+}
+
+type mockDB struct {
+	calls *[]string
+}
+
+func (m *mockDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	conn, _ := pgxmock.NewConn()
+	tx, _ := conn.Begin(ctx)
+	return &loggingTx{Tx: tx, log: m.calls}, nil
+}
+
+type loggingTx struct {
+	pgx.Tx
+	log *[]string
+}
+
+func (l *loggingTx) Commit(ctx context.Context) error {
+	*l.log = append(*l.log, "commit")
+	return nil
+}
+
+func (l *loggingTx) Rollback(ctx context.Context) error {
+	*l.log = append(*l.log, "rollback")
+	return nil
+}
+
+func TestService(t *testing.T) {
+	modelID := "m0"
+
+	tests := []struct {
+		name          string
+		do            func(s *Service, ctx context.Context) error
+		run           *Run
+		expectedCalls []string
+		wantErr       error
+		wantActions   []audit.Action
+	}{
+		{
+			name: "start",
+			do: func(s *Service, ctx context.Context) error {
+				_, err := s.Start(ctx, &Run{Name: "prices-xgb", CreatedBy: "spoofed"})
+				return err
+			},
+			expectedCalls: []string{"create acme dev|1", "commit"},
+			wantActions:   []audit.Action{audit.ActionCreate},
+		},
+		{
+			name: "heartbeat",
+			do: func(s *Service, ctx context.Context) error {
+				_, err := s.Heartbeat(ctx, "acme", "r1")
+				return err
+			},
+			run:           &Run{ID: "r1", Status: StatusRunning},
+			expectedCalls: []string{"lock r1", "heartbeat r1", "commit"},
+		},
+		{
+			name: "heartbeat after failing",
+			do: func(s *Service, ctx context.Context) error {
+				_, err := s.Heartbeat(ctx, "acme", "r1")
+				return err
+			},
+			run:           &Run{ID: "r1", Status: StatusFailed},
+			expectedCalls: []string{"lock r1", "rollback"},
+			wantErr:       ErrEnded,
+		},
+		{
+			name: "fail",
+			do: func(s *Service, ctx context.Context) error {
+				_, err := s.End(ctx, "acme", "r1", StatusFailed)
+				return err
+			},
+			run:           &Run{ID: "r1", Status: StatusRunning},
+			expectedCalls: []string{"lock r1", "end r1 failed", "commit"},
+			wantActions:   []audit.Action{audit.ActionUpdate},
+		},
+		{
+			name: "attach",
+			do: func(s *Service, ctx context.Context) error {
+				_, err := s.Attach(ctx, "acme", "r1", "u1")
+				return err
+			},
+			run:           &Run{ID: "r1", Status: StatusRunning},
+			expectedCalls: []string{"lock r1", "attach r1 u1", "commit"},
+			wantActions:   []audit.Action{audit.ActionUpdate},
+		},
+		{
+			name: "attach after finishing",
+			do: func(s *Service, ctx context.Context) error {
+				_, err := s.Attach(ctx, "acme", "r1", "u1")
+				return err
+			},
+			run:           &Run{ID: "r1", Status: StatusFinished},
+			expectedCalls: []string{"lock r1", "rollback"},
+			wantErr:       ErrEnded,
+		},
+		{
+			name: "finalize",
+			do: func(s *Service, ctx context.Context) error {
+				_, err := s.Finalize(ctx, "acme", "r1", &models.Model{Name: "prices", Version: "1.0.0", Description: "xgb"})
+				return err
+			},
+			run:           &Run{ID: "r1", Status: StatusRunning, Params: json.RawMessage(`{"depth": 6}`)},
+			expectedCalls: []string{"lock r1", "create model prices", "finalize r1 m1", "commit"},
+			wantActions:   []audit.Action{audit.ActionFinalize},
+		},
+		{
+			name: "finalize failed",
+			do: func(s *Service, ctx context.Context) error {
+				_, err := s.Finalize(ctx, "acme", "r1", &models.Model{Name: "prices"})
+				return err
+			},
+			run:           &Run{ID: "r1", Status: StatusFailed},
+			expectedCalls: []string{"lock r1", "rollback"},
+			wantErr:       ErrFailed,
+		},
+		{
+			name: "finalize twice",
+			do: func(s *Service, ctx context.Context) error {
+				_, err := s.Finalize(ctx, "acme", "r1", &models.Model{Name: "prices"})
+				return err
+			},
+			run:           &Run{ID: "r1", Status: StatusFinished, ModelID: &modelID},
+			expectedCalls: []string{"lock r1", "rollback"},
+			wantErr:       ErrFinalized,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			recorder := &mockAudit{}
+			s := &Service{
+				s:       &mockStore{run: tc.run, calls: &calls},
+				creator: &mockCreator{calls: &calls},
+				db:      &mockDB{calls: &calls},
+				audit:   recorder,
+			}
+
+			ctx := auth.NewContext(context.Background(), &auth.Identity{Subject: "dev|1", Tenant: "acme"})
+			err := tc.do(s, ctx)
+			if !reflect.DeepEqual(calls, tc.expectedCalls) {
+				t.Errorf("calls %v, want %v", calls, tc.expectedCalls)
+			}
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("got error %v, wanted %v", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			var actions []audit.Action
+			for _, e := range recorder.events {
+				if e.ResourceType != audit.ResourceRun || e.ResourceID != "r1" || e.Actor != "dev|1" {
+					t.Errorf("unexpected event: %+v", e)
+				}
+				actions = append(actions, e.Action)
+			}
+			if !reflect.DeepEqual(actions, tc.wantActions) {
+				t.Errorf("audit actions %v, want %v", actions, tc.wantActions)
+			}
+		})
+	}
+}
+
+func TestFinalizeUsesRun(t *testing.T) {
+	var calls []string
+	creator := &mockCreator{calls: &calls}
+	s := &Service{
+		s: &mockStore{calls: &calls, run: &Run{
+			ID:        "r1",
+			Status:    StatusFinished,
+			Params:    json.RawMessage(`{"depth": 6}`),
+			UploadIds: map[string]string{"model": "u1", "weights": "u1", "report": "u2"},
+		}},
+		creator: creator,
+		db:      &mockDB{calls: &calls},
+	}
+
+	m := &models.Model{Name: "prices", Version: "1.0.0", Description: "xgb", UploadIds: map[string]string{"other": "u9"}}
+	if _, err := s.Finalize(context.Background(), "acme", "r1", m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Each upload is moved once, and anything sent is replaced by the run's.
+	wantUploads := map[string]string{"model": "u1", "report": "u2"}
+	if !reflect.DeepEqual(creator.got.UploadIds, wantUploads) {
+		t.Errorf("got artefacts %v, wanted %v", creator.got.UploadIds, wantUploads)
+	}
+	if string(creator.got.Config) != `{"depth": 6}` {
+		t.Errorf("expected the run's params as config, got %s", creator.got.Config)
+	}
+}
@@ -0,0 +1,246 @@
+package runs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/uploads"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	runsQuery = `SELECT
+  r.id::text,
+  r.name,
+  r.status,
+  r.params,
+  r.tags,
+  COALESCE(
+    jsonb_object_agg(file_key, u.id) FILTER (WHERE file_key IS NOT NULL),
+    '{}'::jsonb
+  ) AS artefacts,
+  r.started_at,
+  r.ended_at,
+  r.heartbeat_at,
+  r.model_id::text,
+  COALESCE(r.created_by, ''),
+  r.tenant
+FROM runs r
+LEFT JOIN uploads u ON u.run_id = r.id
+LEFT JOIN LATERAL jsonb_object_keys(u.files) AS file_key ON true
+WHERE r.tenant = $1`
+	statusClause = ` AND r.status = ANY($2)`
+	runClause    = ` AND r.id = $2`
+	runsGroupBy  = `
+GROUP BY r.id
+ORDER BY r.started_at, r.id`
+
+	createQuery = `INSERT INTO runs (name, params, tags, created_by, tenant)
+VALUES ($1, $2, $3, NULLIF($4, ''), $5) RETURNING id::text`
+	lockQuery      = `SELECT status FROM runs WHERE tenant = $1 AND id = $2 FOR UPDATE`
+	heartbeatQuery = `UPDATE runs SET heartbeat_at = now() WHERE id = $1`
+	endQuery       = `UPDATE runs SET status = $2, ended_at = now() WHERE id = $1`
+	finalizeQuery  = `UPDATE runs SET status = 'finished', ended_at = COALESCE(ended_at, now()), model_id = $2
+WHERE id = $1`
+
+	// An upload can only belong to one thing, so one already attached to
+	// a dataset, a model or another run can't be attached.
+	uploadQuery = `SELECT
+  quarantined,
+  dataset_id IS NOT NULL OR model_id IS NOT NULL,
+  COALESCE(run_id::text, '')
+FROM uploads WHERE id = $1 FOR UPDATE`
+	attachQuery = `UPDATE uploads SET run_id = $1 WHERE id = $2`
+)
+
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+type Store struct {
+	q Querier
+}
+
+func NewStore(q Querier) *Store {
+	return &Store{
+		q: q,
+	}
+}
+
+/*
+Runs returns tenant's runs, oldest first, optionally only those with one
+of statuses.
+*/
+func (s *Store) Runs(ctx context.Context, tenant string, statuses []Status) ([]*Run, error) {
+	query := runsQuery + runsGroupBy
+	args := []any{tenant}
+	if len(statuses) > 0 {
+		query = runsQuery + statusClause + runsGroupBy
+		names := make([]string, len(statuses))
+		for i, status := range statuses {
+			names[i] = string(status)
+		}
+		args = append(args, names)
+	}
+
+	rows, err := s.q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not query runs: %w", err)
+	}
+	defer rows.Close()
+
+	rs := []*Run{}
+	for rows.Next() {
+		r, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, r)
+	}
+	return rs, rows.Err()
+}
+
+/*
+Run returns tenant's run id, or an error wrapping internal.ErrNotFound if
+there is no such run.
+*/
+func (s *Store) Run(ctx context.Context, tenant string, id string) (*Run, error) {
+	return s.runWithQuerier(ctx, s.q, tenant, id)
+}
+
+func (s *Store) runWithQuerier(ctx context.Context, q Querier, tenant string, id string) (*Run, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, fmt.Errorf("run %s: %w", id, internal.ErrNotFound)
+	}
+
+	r, err := scanRun(q.QueryRow(ctx, runsQuery+runClause+runsGroupBy, tenant, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("run %s: %w", id, internal.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not query run: %w", err)
+	}
+	return r, nil
+}
+
+func scanRun(row pgx.Row) (*Run, error) {
+	r := &Run{}
+	if err := row.Scan(
+		&r.ID,
+		&r.Name,
+		&r.Status,
+		&r.Params,
+		&r.Tags,
+		&r.UploadIds,
+		&r.StartedAt,
+		&r.EndedAt,
+		&r.HeartbeatAt,
+		&r.ModelID,
+		&r.CreatedBy,
+		&r.Tenant,
+	); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+/*
+lockWithQuerier locks tenant's run id until the end of the transaction
+and returns its status.
+*/
+func (s *Store) lockWithQuerier(ctx context.Context, q Querier, tenant string, id string) (Status, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return "", fmt.Errorf("run %s: %w", id, internal.ErrNotFound)
+	}
+
+	var status Status
+	if err := q.QueryRow(ctx, lockQuery, tenant, id).Scan(&status); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", fmt.Errorf("run %s: %w", id, internal.ErrNotFound)
+		}
+		return "", fmt.Errorf("could not lock run: %w", err)
+	}
+	return status, nil
+}
+
+/*
+createWithQuerier stores the new run r, setting its ID.
+*/
+func (s *Store) createWithQuerier(ctx context.Context, q Querier, r *Run) error {
+	params := r.Params
+	if len(params) == 0 {
+		params = json.RawMessage(`{}`)
+	}
+	tags := r.Tags
+	if tags == nil {
+		tags = map[string]string{}
+	}
+
+	if err := q.QueryRow(ctx, createQuery, r.Name, params, tags, r.CreatedBy, r.Tenant).Scan(&r.ID); err != nil {
+		return fmt.Errorf("could not create run: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) heartbeatWithQuerier(ctx context.Context, q Querier, id string) error {
+	if _, err := q.Exec(ctx, heartbeatQuery, id); err != nil {
+		return fmt.Errorf("could not record heartbeat: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) endWithQuerier(ctx context.Context, q Querier, id string, status Status) error {
+	if _, err := q.Exec(ctx, endQuery, id, status); err != nil {
+		return fmt.Errorf("could not end run: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) finalizeWithQuerier(ctx context.Context, q Querier, id string, modelID string) error {
+	if _, err := q.Exec(ctx, finalizeQuery, id, modelID); err != nil {
+		return fmt.Errorf("could not finalize run: %w", err)
+	}
+	return nil
+}
+
+/*
+attachWithQuerier attaches the upload uploadID to the run id. It wraps
+internal.ErrNotFound if there is no such upload, uploads.ErrQuarantined
+if it is quarantined and ErrAttached if it belongs to something else.
+Attaching an upload to the run it is already attached to does nothing.
+*/
+func (s *Store) attachWithQuerier(ctx context.Context, q Querier, id string, uploadID string) error {
+	if _, err := uuid.Parse(uploadID); err != nil {
+		return fmt.Errorf("upload %s: %w", uploadID, internal.ErrNotFound)
+	}
+
+	var quarantined, attached bool
+	var runID string
+	if err := q.QueryRow(ctx, uploadQuery, uploadID).Scan(&quarantined, &attached, &runID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("upload %s: %w", uploadID, internal.ErrNotFound)
+		}
+		return fmt.Errorf("could not query upload: %w", err)
+	}
+
+	switch {
+	case quarantined:
+		return fmt.Errorf("upload %s: %w", uploadID, uploads.ErrQuarantined)
+	case runID == id:
+		return nil
+	case attached || runID != "":
+		return fmt.Errorf("upload %s is %w", uploadID, ErrAttached)
+	}
+
+	if _, err := q.Exec(ctx, attachQuery, id, uploadID); err != nil {
+		return fmt.Errorf("could not attach upload: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,210 @@
+package runs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/uploads"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+const (
+	runID    = "5d3c1a2b-0000-4000-8000-000000000001"
+	uploadID = "5d3c1a2b-0000-4000-8000-000000000002"
+	modelID  = "5d3c1a2b-0000-4000-8000-000000000003"
+)
+
+var runColumns = []string{"id", "name", "status", "params", "tags", "artefacts", "started_at", "ended_at", "heartbeat_at", "model_id", "created_by", "tenant"}
+
+func TestRuns(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	startedAt := time.Date(2025, 7, 9, 9, 0, 0, 0, time.UTC)
+	endedAt := startedAt.Add(time.Hour)
+
+	db.ExpectQuery(regexp.QuoteMeta(runsQuery+statusClause+runsGroupBy)).
+		WithArgs("acme", []string{"running", "failed"}).
+		WillReturnRows(db.NewRows(runColumns).
+			AddRow(runID, "prices-xgb", "running", json.RawMessage(`{"depth": 6}`), map[string]string{"team": "pricing"}, map[string]string{"model": uploadID}, startedAt, nil, startedAt, nil, "dev|1", "acme"))
+	db.ExpectQuery(regexp.QuoteMeta(runsQuery + runsGroupBy)).
+		WithArgs("acme").
+		WillReturnRows(db.NewRows(runColumns).
+			AddRow(runID, "prices-xgb", "finished", json.RawMessage(`{}`), map[string]string{}, map[string]string{}, startedAt, &endedAt, startedAt, stringPtr(modelID), "dev|1", "acme"))
+
+	store := NewStore(db)
+
+	rs, err := store.Runs(context.Background(), "acme", []Status{StatusRunning, StatusFailed})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []*Run{{
+		ID:          runID,
+		Name:        "prices-xgb",
+		Status:      StatusRunning,
+		Params:      json.RawMessage(`{"depth": 6}`),
+		Tags:        map[string]string{"team": "pricing"},
+		UploadIds:   map[string]string{"model": uploadID},
+		StartedAt:   startedAt,
+		HeartbeatAt: startedAt,
+		CreatedBy:   "dev|1",
+		Tenant:      "acme",
+	}}
+	if !reflect.DeepEqual(rs, want) {
+		t.Errorf("got runs %+v, wanted %+v", rs, want)
+	}
+
+	rs, err = store.Runs(context.Background(), "acme", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(rs) != 1 || !rs[0].Ended() || rs[0].ModelID == nil || *rs[0].ModelID != modelID {
+		t.Errorf("expected a finalized run, got %+v", rs)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRunNotFound(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.ExpectQuery(regexp.QuoteMeta(runsQuery+runClause+runsGroupBy)).
+		WithArgs("acme", runID).
+		WillReturnError(pgx.ErrNoRows)
+	db.ExpectQuery(regexp.QuoteMeta(lockQuery)).
+		WithArgs("acme", runID).
+		WillReturnError(pgx.ErrNoRows)
+
+	store := NewStore(db)
+	ctx := context.Background()
+
+	if _, err := store.Run(ctx, "acme", runID); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := store.lockWithQuerier(ctx, db, "acme", runID); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := store.Run(ctx, "acme", "not-a-uuid"); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for malformed id, got %v", err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRunWrites(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	db.ExpectQuery(regexp.QuoteMeta(createQuery)).
+		WithArgs("prices-xgb", json.RawMessage(`{}`), map[string]string{}, "dev|1", "acme").
+		WillReturnRows(db.NewRows([]string{"id"}).AddRow(runID))
+	db.ExpectExec(regexp.QuoteMeta(heartbeatQuery)).
+		WithArgs(runID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	db.ExpectExec(regexp.QuoteMeta(endQuery)).
+		WithArgs(runID, StatusFailed).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	db.ExpectExec(regexp.QuoteMeta(finalizeQuery)).
+		WithArgs(runID, modelID).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	store := NewStore(db)
+	ctx := context.Background()
+
+	r := &Run{Name: "prices-xgb", CreatedBy: "dev|1", Tenant: "acme"}
+	if err := store.createWithQuerier(ctx, db, r); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if r.ID != runID {
+		t.Errorf("expected the run's id to be set, got %+v", r)
+	}
+	if err := store.heartbeatWithQuerier(ctx, db, runID); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := store.endWithQuerier(ctx, db, runID, StatusFailed); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := store.finalizeWithQuerier(ctx, db, runID, modelID); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAttach(t *testing.T) {
+	otherRun := "5d3c1a2b-0000-4000-8000-000000000004"
+
+	tests := []struct {
+		name        string
+		quarantined bool
+		attached    bool
+		runID       string
+		expectWrite bool
+		wantErr     error
+	}{
+		{name: "unattached", expectWrite: true},
+		{name: "already attached to this run", runID: runID},
+		{name: "attached to another run", runID: otherRun, wantErr: ErrAttached},
+		{name: "attached to a model", attached: true, wantErr: ErrAttached},
+		{name: "quarantined", quarantined: true, wantErr: uploads.ErrQuarantined},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := pgxmock.NewPool()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			db.ExpectQuery(regexp.QuoteMeta(uploadQuery)).
+				WithArgs(uploadID).
+				WillReturnRows(db.NewRows([]string{"quarantined", "attached", "run_id"}).AddRow(tc.quarantined, tc.attached, tc.runID))
+			if tc.expectWrite {
+				db.ExpectExec(regexp.QuoteMeta(attachQuery)).
+					WithArgs(runID, uploadID).
+					WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+			}
+
+			err = NewStore(db).attachWithQuerier(context.Background(), db, runID, uploadID)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("got error %v, wanted %v", err, tc.wantErr)
+				}
+			} else if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+
+			if err := db.ExpectationsWereMet(); err != nil {
+				t.Errorf("there were unfulfilled expectations: %s", err)
+			}
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
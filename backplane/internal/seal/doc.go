@@ -0,0 +1,18 @@
+/*
+Package seal makes dataset and model versions tamper-evident.
+
+When a version is created, its metadata and the digests of its artefacts
+are hashed together with the seal of its parent version, giving a chain
+much like git's commits. The resulting seal is stored with the version
+and, once set, the database refuses to change the version.
+
+Verification recomputes every seal along the chain from what is currently
+stored, and re-hashes each artefact, so that any change made behind the
+API's back, whether to a row or to a file on disk, is detected:
+
+	report, err := seal.VerifyChain(get, files, id)
+	if !report.Valid {
+		...
+	}
+*/
+package seal
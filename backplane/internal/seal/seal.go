@@ -0,0 +1,244 @@
+package seal
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/heldtogether/traintrack/internal"
+)
+
+/*
+Algorithm prefixes every seal and artefact digest, so that it can change
+in future without ambiguity.
+*/
+const Algorithm = "sha256"
+
+/*
+Artefact is a file belonging to a sealed version.
+*/
+type Artefact struct {
+	Upload   string `json:"upload"`
+	FileName string `json:"filename"`
+	Digest   string `json:"digest"`
+	Size     int64  `json:"size"`
+
+	// Path is where the file currently lives. It isn't sealed, so that
+	// storage can be reorganised without breaking the chain.
+	Path string `json:"-"`
+}
+
+/*
+Record is everything that is sealed for a single version.
+*/
+type Record struct {
+	// Kind distinguishes datasets from models, so that a record can't
+	// be passed off as the other.
+	Kind string `json:"kind"`
+	ID   string `json:"id"`
+	// Fields holds the version's metadata. Any value that marshals to
+	// JSON will do; it is canonicalised before hashing.
+	Fields    any                 `json:"fields"`
+	Artefacts map[string]Artefact `json:"artefacts"`
+	// ParentSeal chains the record to the version it was derived from.
+	ParentSeal string `json:"parent_seal"`
+
+	// ParentID is followed by VerifyChain, Seal is the value stored with
+	// the version and Purged is set once its artefacts have been removed
+	// from storage. None of them are part of the hash.
+	ParentID string `json:"-"`
+	Seal     string `json:"-"`
+	Purged   bool   `json:"-"`
+}
+
+/*
+Compute returns the seal for r, in the form "sha256:<hex>".
+*/
+func (r *Record) Compute() (string, error) {
+	data, err := canonical(r)
+	if err != nil {
+		return "", fmt.Errorf("canonicalise record: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return Algorithm + ":" + hex.EncodeToString(sum[:]), nil
+}
+
+/*
+DecodeFields decodes the record's fields into v, which should have the
+JSON shape of the version's metadata.
+*/
+func (r *Record) DecodeFields(v any) error {
+	data, err := json.Marshal(r.Fields)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s %s has unexpected fields: %w", r.Kind, r.ID, err)
+	}
+	return nil
+}
+
+/*
+Digest hashes the contents of r, returning the digest in the same form as
+a seal along with the number of bytes read.
+*/
+func Digest(r io.Reader) (string, int64, error) {
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return "", n, err
+	}
+	return Algorithm + ":" + hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+/*
+canonical marshals v to JSON with object keys sorted and no insignificant
+whitespace, whatever order nested raw JSON arrived in.
+*/
+func canonical(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var generic any
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return json.Marshal(generic)
+}
+
+/*
+FileReader reads an artefact back from storage.
+*/
+type FileReader interface {
+	ReadFile(path string) ([]byte, error)
+}
+
+/*
+Getter loads the sealed record for a version, returning an error wrapping
+internal.ErrNotFound if there is no such version.
+*/
+type Getter func(id string) (*Record, error)
+
+/*
+Result is the outcome of verifying a single version.
+*/
+type Result struct {
+	Kind     string   `json:"kind"`
+	ID       string   `json:"id"`
+	Seal     string   `json:"seal"`
+	Computed string   `json:"computed"`
+	Valid    bool     `json:"valid"`
+	Problems []string `json:"problems,omitempty"`
+	// Purged versions have had their artefacts removed, so only their
+	// seal is checked.
+	Purged bool `json:"purged,omitempty"`
+}
+
+/*
+Report is the outcome of verifying a version and all of its ancestors,
+starting with the version asked about.
+*/
+type Report struct {
+	ID    string    `json:"id"`
+	Valid bool      `json:"valid"`
+	Chain []*Result `json:"chain"`
+}
+
+/*
+Verify checks a single record against its stored seal and re-hashes each
+of its artefacts. Files are only read if files is not nil and the version
+hasn't been purged.
+*/
+func Verify(r *Record, files FileReader) *Result {
+	res := &Result{
+		Kind:   r.Kind,
+		ID:     r.ID,
+		Seal:   r.Seal,
+		Purged: r.Purged,
+	}
+
+	computed, err := r.Compute()
+	if err != nil {
+		res.Problems = append(res.Problems, err.Error())
+	}
+	res.Computed = computed
+
+	switch {
+	case r.Seal == "":
+		res.Problems = append(res.Problems, "version has no seal")
+	case computed != "" && computed != r.Seal:
+		res.Problems = append(res.Problems, "stored seal does not match the version's metadata and artefacts")
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(r.Artefacts)) {
+		a := r.Artefacts[name]
+		if a.Digest == "" {
+			res.Problems = append(res.Problems, fmt.Sprintf("artefact %s has no recorded digest", name))
+			continue
+		}
+		if files == nil || r.Purged {
+			continue
+		}
+		content, err := files.ReadFile(filepath.Join(a.Path, a.FileName))
+		if err != nil {
+			res.Problems = append(res.Problems, fmt.Sprintf("artefact %s could not be read: %s", name, err))
+			continue
+		}
+		digest, size, _ := Digest(bytes.NewReader(content))
+		if digest != a.Digest || size != a.Size {
+			res.Problems = append(res.Problems, fmt.Sprintf("artefact %s has been modified: expected %s, found %s", name, a.Digest, digest))
+		}
+	}
+
+	res.Valid = len(res.Problems) == 0
+	return res
+}
+
+/*
+VerifyChain verifies the version id and then each of its ancestors in
+turn. The report is only valid if every version in the chain is.
+*/
+func VerifyChain(get Getter, files FileReader, id string) (*Report, error) {
+	report := &Report{ID: id, Valid: true}
+
+	seen := map[string]bool{}
+	for next := id; next != ""; {
+		if seen[next] {
+			return nil, fmt.Errorf("cycle in version history at %s", next)
+		}
+		seen[next] = true
+
+		r, err := get(next)
+		if err != nil {
+			if next != id && errors.Is(err, internal.ErrNotFound) {
+				report.Valid = false
+				report.Chain = append(report.Chain, &Result{
+					ID:       next,
+					Problems: []string{"parent version is missing"},
+				})
+				break
+			}
+			return nil, err
+		}
+
+		res := Verify(r, files)
+		report.Chain = append(report.Chain, res)
+		report.Valid = report.Valid && res.Valid
+
+		next = r.ParentID
+	}
+
+	return report, nil
+}
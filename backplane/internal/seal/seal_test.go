@@ -0,0 +1,286 @@
+package seal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/heldtogether/traintrack/internal"
+)
+
+type memFiles map[string]string
+
+func (m memFiles) ReadFile(path string) ([]byte, error) {
+	content, ok := m[path]
+	if !ok {
+		return nil, fmt.Errorf("no such file %s", path)
+	}
+	return []byte(content), nil
+}
+
+func digestOf(t *testing.T, s string) string {
+	t.Helper()
+	d, _, err := Digest(strings.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestComputeIsCanonical(t *testing.T) {
+	a := &Record{
+		Kind:   "model",
+		ID:     "1",
+		Fields: map[string]any{"config": json.RawMessage(`{"b": 1, "a": [1.50, 2]}`)},
+	}
+	b := &Record{
+		Kind:   "model",
+		ID:     "1",
+		Fields: map[string]any{"config": json.RawMessage(`{"a":[1.50,2],"b":1}`)},
+	}
+
+	sa, err := a.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sb, err := b.Compute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sa != sb {
+		t.Errorf("expected key order and whitespace to be ignored, got %s and %s", sa, sb)
+	}
+	if !strings.HasPrefix(sa, "sha256:") {
+		t.Errorf("expected algorithm prefix, got %s", sa)
+	}
+
+	b.Fields = map[string]any{"config": json.RawMessage(`{"a":[1.5,2],"b":1}`)}
+	if sc, _ := b.Compute(); sc == sa {
+		t.Errorf("expected a change in value to change the seal")
+	}
+}
+
+func TestComputeChainsParent(t *testing.T) {
+	r := &Record{Kind: "dataset", ID: "1", ParentSeal: "sha256:aaaa"}
+	before, _ := r.Compute()
+
+	r.ParentSeal = "sha256:bbbb"
+	after, _ := r.Compute()
+
+	if before == after {
+		t.Errorf("expected the parent's seal to be part of the hash")
+	}
+
+	r.ParentID = "other"
+	r.Seal = "whatever"
+	if again, _ := r.Compute(); again != after {
+		t.Errorf("expected ParentID and Seal to be excluded from the hash")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	files := memFiles{"datasets/1/train.csv": "a,b\n1,2\n"}
+
+	sealed := func() *Record {
+		r := &Record{
+			Kind:   "dataset",
+			ID:     "1",
+			Fields: map[string]string{"name": "prices"},
+			Artefacts: map[string]Artefact{
+				"train": {Upload: "u1", FileName: "train.csv", Digest: digestOf(t, "a,b\n1,2\n"), Size: 8, Path: "datasets/1"},
+			},
+		}
+		r.Seal, _ = r.Compute()
+		return r
+	}
+
+	tests := []struct {
+		name         string
+		tamper       func(r *Record, f memFiles)
+		wantValid    bool
+		wantProblems string
+	}{
+		{
+			name:      "untouched",
+			tamper:    func(r *Record, f memFiles) {},
+			wantValid: true,
+		},
+		{
+			name: "metadata changed",
+			tamper: func(r *Record, f memFiles) {
+				r.Fields = map[string]string{"name": "other"}
+			},
+			wantProblems: "stored seal does not match",
+		},
+		{
+			name: "file changed",
+			tamper: func(r *Record, f memFiles) {
+				f["datasets/1/train.csv"] = "a,b\n1,3\n"
+			},
+			wantProblems: "artefact train has been modified",
+		},
+		{
+			name: "file missing",
+			tamper: func(r *Record, f memFiles) {
+				delete(f, "datasets/1/train.csv")
+			},
+			wantProblems: "artefact train could not be read",
+		},
+		{
+			name: "unsealed",
+			tamper: func(r *Record, f memFiles) {
+				r.Seal = ""
+			},
+			wantProblems: "version has no seal",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			r := sealed()
+			f := memFiles{}
+			for k, v := range files {
+				f[k] = v
+			}
+			tc.tamper(r, f)
+
+			res := Verify(r, f)
+			if res.Valid != tc.wantValid {
+				t.Errorf("valid = %v, want %v (problems: %v)", res.Valid, tc.wantValid, res.Problems)
+			}
+			if tc.wantProblems != "" && !strings.Contains(strings.Join(res.Problems, "\n"), tc.wantProblems) {
+				t.Errorf("expected a problem containing %q, got %v", tc.wantProblems, res.Problems)
+			}
+		})
+	}
+}
+
+func TestVerifyChain(t *testing.T) {
+	records := map[string]*Record{}
+
+	root := &Record{Kind: "dataset", ID: "a", Fields: map[string]string{"version": "1"}}
+	root.Seal, _ = root.Compute()
+	records["a"] = root
+
+	child := &Record{Kind: "dataset", ID: "b", Fields: map[string]string{"version": "2"}, ParentID: "a", ParentSeal: root.Seal}
+	child.Seal, _ = child.Compute()
+	records["b"] = child
+
+	get := func(id string) (*Record, error) {
+		r, ok := records[id]
+		if !ok {
+			return nil, internal.ErrNotFound
+		}
+		copied := *r
+		return &copied, nil
+	}
+
+	report, err := VerifyChain(get, nil, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid || len(report.Chain) != 2 {
+		t.Fatalf("expected a valid chain of 2, got %+v", report)
+	}
+
+	// Rewriting the root, even with a freshly computed seal, breaks the
+	// child because it was sealed against the old one.
+	root.Fields = map[string]string{"version": "1-tampered"}
+	root.Seal, _ = root.Compute()
+	records["b"].ParentSeal = root.Seal
+
+	report, err = VerifyChain(get, nil, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Valid || report.Chain[0].Valid || !report.Chain[1].Valid {
+		t.Errorf("expected only the child to fail verification, got %+v", report.Chain)
+	}
+
+	if _, err := VerifyChain(get, nil, "missing"); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestVerifyChainPurgedParent(t *testing.T) {
+	files := memFiles{"datasets/b/train.csv": "a,b\n1,2\n"}
+
+	// The root's artefacts have been removed from storage.
+	root := &Record{
+		Kind:   "dataset",
+		ID:     "a",
+		Fields: map[string]string{"version": "1"},
+		Artefacts: map[string]Artefact{
+			"train": {Upload: "u1", FileName: "train.csv", Digest: digestOf(t, "a\n1\n"), Size: 4, Path: "datasets/a"},
+		},
+		Purged: true,
+	}
+	root.Seal, _ = root.Compute()
+
+	child := &Record{
+		Kind:   "dataset",
+		ID:     "b",
+		Fields: map[string]string{"version": "2"},
+		Artefacts: map[string]Artefact{
+			"train": {Upload: "u2", FileName: "train.csv", Digest: digestOf(t, "a,b\n1,2\n"), Size: 8, Path: "datasets/b"},
+		},
+		ParentID:   "a",
+		ParentSeal: root.Seal,
+	}
+	child.Seal, _ = child.Compute()
+
+	records := map[string]*Record{"a": root, "b": child}
+	get := func(id string) (*Record, error) {
+		r, ok := records[id]
+		if !ok {
+			return nil, internal.ErrNotFound
+		}
+		copied := *r
+		return &copied, nil
+	}
+
+	report, err := VerifyChain(get, files, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Valid || len(report.Chain) != 2 {
+		t.Fatalf("expected a valid chain of 2, got %+v", report.Chain)
+	}
+	if report.Chain[0].Purged || !report.Chain[1].Purged {
+		t.Errorf("expected only the parent to be reported as purged, got %+v", report.Chain)
+	}
+
+	// A purged version's metadata is still sealed.
+	root.Fields = map[string]string{"version": "1-tampered"}
+	report, err = VerifyChain(get, files, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Valid || report.Chain[1].Valid {
+		t.Errorf("expected the tampered parent to fail verification, got %+v", report.Chain[1])
+	}
+}
+
+func TestDecodeFields(t *testing.T) {
+	r := &Record{Kind: "dataset", ID: "a", Fields: map[string]any{"name": "prices", "version": "1.0.0"}}
+
+	var f struct {
+		Name    string `json:"name"`
+		Version string `json:"version"`
+	}
+	if err := r.DecodeFields(&f); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if f.Name != "prices" || f.Version != "1.0.0" {
+		t.Errorf("unexpected fields: %+v", f)
+	}
+
+	var wrong struct {
+		Name int `json:"name"`
+	}
+	if err := r.DecodeFields(&wrong); err == nil || !strings.HasPrefix(err.Error(), "dataset a has unexpected fields") {
+		t.Errorf("expected an unexpected fields error, got %v", err)
+	}
+}
@@ -0,0 +1,20 @@
+/*
+Package signing lets model versions be signed, and lets those signatures
+be checked, by keys trusted per tenant.
+
+A signature is a detached ed25519 signature over a canonical payload
+listing a model's artefacts and their digests, in the spirit of Sigstore
+bundles. Signatures are made on the client with a private key that never
+leaves it, and stored by the backplane only once they verify against one
+of the tenant's trusted public keys:
+
+	sig, err := signing.Sign(priv, bundle.Artefacts)
+	...
+
+Everything needed to check a model's signatures is gathered into a
+Bundle, which can be saved and verified later without talking to the
+backplane:
+
+	valid, problems := bundle.Verify(trusted)
+*/
+package signing
@@ -0,0 +1,53 @@
+package signing
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/heldtogether/traintrack/internal/models"
+)
+
+type bundler interface {
+	Bundle(ctx context.Context, id string) (*Bundle, error)
+}
+
+/*
+Guard is a models.PromotionGuard which refuses to promote a model to any
+of its stages unless the model carries a valid signature from a key its
+tenant trusts.
+*/
+type Guard struct {
+	b      bundler
+	stages []models.Stage
+}
+
+/*
+NewGuard guards promotion to stages, or to staging and production if
+none are given.
+*/
+func NewGuard(s *Service, stages ...models.Stage) *Guard {
+	if len(stages) == 0 {
+		stages = []models.Stage{models.StageStaging, models.StageProduction}
+	}
+	return &Guard{
+		b:      s,
+		stages: stages,
+	}
+}
+
+func (g *Guard) CheckPromotion(ctx context.Context, m *models.Model, to models.Stage) error {
+	if !slices.Contains(g.stages, to) {
+		return nil
+	}
+
+	b, err := g.b.Bundle(ctx, m.ID)
+	if err != nil {
+		return fmt.Errorf("could not check signatures: %w", err)
+	}
+
+	if valid, _ := b.Verify(b.Keys); len(valid) == 0 {
+		return fmt.Errorf("%w: model %s has no valid signature from a trusted key", models.ErrPromotionRefused, m.ID)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package signing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/auth"
+)
+
+/*
+KeyManager allows a tenant's trusted keys to be listed, added and revoked.
+*/
+type KeyManager interface {
+	Keys(ctx context.Context, tenant string) ([]*Key, error)
+	AddKey(ctx context.Context, k *Key) (*Key, error)
+	RevokeKey(ctx context.Context, tenant string, id string) (*Key, error)
+}
+
+/*
+Signer allows a model's signatures to be fetched and added.
+*/
+type Signer interface {
+	Bundle(ctx context.Context, id string) (*Bundle, error)
+	Sign(ctx context.Context, id string, sig *Signature) (*Signature, error)
+}
+
+type Handler struct {
+	k KeyManager
+	s Signer
+}
+
+func NewHandler(k KeyManager, s Signer) *Handler {
+	return &Handler{
+		k: k,
+		s: s,
+	}
+}
+
+/*
+Keys routes and handles requests for the caller's tenant's trusted keys.
+It should be registered on the router under something sensible, like
+/keys.
+*/
+func (h *Handler) Keys(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.ListKeys(w, r)
+	case http.MethodPost:
+		h.AddKey(w, r)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+/*
+Key handles requests for a single key, registered under /keys/{id}. Keys
+can only be revoked, never removed.
+*/
+func (h *Handler) Key(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodDelete:
+		h.RevokeKey(w, r)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+/*
+Signatures routes and handles requests for a model's signatures. It
+should be registered under /models/{id}/signatures.
+*/
+func (h *Handler) Signatures(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.Bundle(w, r)
+	case http.MethodPost:
+		h.Sign(w, r)
+	default:
+		methodNotAllowed(w)
+	}
+}
+
+func (h *Handler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	ks, err := h.k.Keys(r.Context(), auth.TenantFromContext(r.Context()))
+	if err != nil {
+		log.Printf("failed to list signing keys: %s", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusInternalServerError,
+			Message: "Failed to list keys",
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(ks)
+}
+
+/*
+AddKey trusts a new key for the caller's tenant. Only admins can add keys.
+*/
+func (h *Handler) AddKey(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, "Failed to add key") {
+		return
+	}
+
+	var k Key
+	if err := json.NewDecoder(r.Body).Decode(&k); err != nil {
+		log.Printf("failed to decode body: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to add key",
+			Reason:  fmt.Sprintf("could not parse body: %s", err),
+		})
+		return
+	}
+
+	details := map[string]string{}
+	if k.Name == "" {
+		details["name"] = "name is a required field"
+	}
+	if k.PublicKey == "" {
+		details["public_key"] = "public_key is a required field"
+	}
+	if len(details) > 0 {
+		log.Printf("failed to validate input: %v", details)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to add key",
+			Reason:  "bad input",
+			Details: details,
+		})
+		return
+	}
+
+	added, err := h.k.AddKey(r.Context(), &k)
+	if err != nil {
+		code := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrInvalidKey):
+			code = http.StatusBadRequest
+		case errors.Is(err, ErrExists):
+			code = http.StatusConflict
+		}
+		log.Printf("failed to add signing key: %s", err)
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    code,
+			Message: "Failed to add key",
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(added)
+}
+
+/*
+RevokeKey stops the caller's tenant trusting a key. Only admins can revoke
+keys.
+*/
+func (h *Handler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	if !requireAdmin(w, r, "Failed to revoke key") {
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+
+	revoked, err := h.k.RevokeKey(r.Context(), auth.TenantFromContext(r.Context()), id)
+	if err != nil {
+		code := http.StatusInternalServerError
+		message := "Failed to revoke key"
+		if errors.Is(err, internal.ErrNotFound) {
+			code = http.StatusNotFound
+			message = "Key not found"
+		}
+		log.Printf("failed to revoke signing key %s: %s", id, err)
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    code,
+			Message: message,
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(revoked)
+}
+
+/*
+Bundle returns everything needed to check the model's signatures offline.
+*/
+func (h *Handler) Bundle(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	b, err := h.s.Bundle(r.Context(), id)
+	if err != nil {
+		code := http.StatusInternalServerError
+		message := "Failed to fetch signatures"
+		if errors.Is(err, internal.ErrNotFound) {
+			code = http.StatusNotFound
+			message = "Model not found"
+		}
+		log.Printf("failed to fetch signatures for model %s: %s", id, err)
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    code,
+			Message: message,
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(b)
+}
+
+/*
+Sign stores a signature for the model, rejecting any that don't verify
+against a key the model's tenant trusts.
+*/
+func (h *Handler) Sign(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var sig Signature
+	if err := json.NewDecoder(r.Body).Decode(&sig); err != nil {
+		log.Printf("failed to decode body: %s", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusBadRequest,
+			Message: "Failed to sign model",
+			Reason:  fmt.Sprintf("could not parse body: %s", err),
+		})
+		return
+	}
+
+	signed, err := h.s.Sign(r.Context(), id, &sig)
+	if err != nil {
+		code := http.StatusInternalServerError
+		message := "Failed to sign model"
+		switch {
+		case errors.Is(err, internal.ErrNotFound):
+			code = http.StatusNotFound
+			message = "Model not found"
+		case errors.Is(err, ErrInvalidSignature), errors.Is(err, ErrUntrustedKey):
+			code = http.StatusBadRequest
+		case errors.Is(err, ErrExists):
+			code = http.StatusConflict
+		}
+		log.Printf("failed to sign model %s: %s", id, err)
+		w.WriteHeader(code)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    code,
+			Message: message,
+			Reason:  err.Error(),
+		})
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(signed)
+}
+
+/*
+requireAdmin reports whether the caller has the admin role, writing a 403
+with message if they don't.
+*/
+func requireAdmin(w http.ResponseWriter, r *http.Request, message string) bool {
+	if id, ok := auth.IdentityFromContext(r.Context()); ok && id.HasRole(auth.RoleAdmin) {
+		return true
+	}
+
+	log.Printf("refused to change signing keys: caller isn't an admin")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    http.StatusForbidden,
+		Message: message,
+		Reason:  "requires the admin role",
+	})
+	return false
+}
+
+func methodNotAllowed(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusMethodNotAllowed)
+	json.NewEncoder(w).Encode(&internal.Error{
+		Code:    http.StatusMethodNotAllowed,
+		Message: "Method not allowed",
+		Reason:  "",
+	})
+}
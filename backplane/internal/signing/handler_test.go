@@ -0,0 +1,321 @@
+package signing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/auth"
+)
+
+type mockHandlerService struct {
+	KeysFn      func(ctx context.Context, tenant string) ([]*Key, error)
+	AddKeyFn    func(ctx context.Context, k *Key) (*Key, error)
+	RevokeKeyFn func(ctx context.Context, tenant string, id string) (*Key, error)
+	BundleFn    func(ctx context.Context, id string) (*Bundle, error)
+	SignFn      func(ctx context.Context, id string, sig *Signature) (*Signature, error)
+}
+
+func (m *mockHandlerService) Keys(ctx context.Context, tenant string) ([]*Key, error) {
+	return m.KeysFn(ctx, tenant)
+}
+
+func (m *mockHandlerService) AddKey(ctx context.Context, k *Key) (*Key, error) {
+	return m.AddKeyFn(ctx, k)
+}
+
+func (m *mockHandlerService) RevokeKey(ctx context.Context, tenant string, id string) (*Key, error) {
+	return m.RevokeKeyFn(ctx, tenant, id)
+}
+
+func (m *mockHandlerService) Bundle(ctx context.Context, id string) (*Bundle, error) {
+	return m.BundleFn(ctx, id)
+}
+
+func (m *mockHandlerService) Sign(ctx context.Context, id string, sig *Signature) (*Signature, error) {
+	return m.SignFn(ctx, id, sig)
+}
+
+var handlerTime = time.Date(2025, 7, 4, 9, 0, 0, 0, time.UTC)
+
+var (
+	admin   = &auth.Identity{Subject: "dev|1", Tenant: "acme", Roles: []string{"admin"}}
+	trainer = &auth.Identity{Subject: "dev|2", Tenant: "acme", Roles: []string{"trainer"}}
+)
+
+func TestKeysHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		path             string
+		body             string
+		identity         *auth.Identity
+		service          *mockHandlerService
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "GET success",
+			method: http.MethodGet,
+			path:   "/keys",
+			service: &mockHandlerService{KeysFn: func(ctx context.Context, tenant string) ([]*Key, error) {
+				return []*Key{{ID: "abc", Name: "ci", PublicKey: "PEM", CreatedAt: handlerTime, CreatedBy: "dev|1"}}, nil
+			}},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `[{"id": "abc", "name": "ci", "public_key": "PEM", "created_at": "2025-07-04T09:00:00Z", "created_by": "dev|1"}]`,
+		},
+		{
+			name:     "POST success",
+			method:   http.MethodPost,
+			path:     "/keys",
+			identity: admin,
+			body:     `{"name": "ci", "public_key": "PEM"}`,
+			service: &mockHandlerService{AddKeyFn: func(ctx context.Context, k *Key) (*Key, error) {
+				return &Key{ID: "abc", Name: k.Name, PublicKey: k.PublicKey, CreatedAt: handlerTime}, nil
+			}},
+			expectedStatus:   http.StatusCreated,
+			expectedContains: `{"id": "abc", "name": "ci", "public_key": "PEM", "created_at": "2025-07-04T09:00:00Z", "created_by": ""}`,
+		},
+		{
+			name:             "POST failure - missing fields",
+			method:           http.MethodPost,
+			path:             "/keys",
+			identity:         admin,
+			body:             `{}`,
+			service:          &mockHandlerService{},
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to add key", "reason": "bad input", "details": {"name": "name is a required field", "public_key": "public_key is a required field"}}`,
+		},
+		{
+			name:     "POST failure - invalid key",
+			method:   http.MethodPost,
+			path:     "/keys",
+			identity: admin,
+			body:     `{"name": "ci", "public_key": "nope"}`,
+			service: &mockHandlerService{AddKeyFn: func(ctx context.Context, k *Key) (*Key, error) {
+				return nil, fmt.Errorf("%w: expected a PEM encoded PUBLIC KEY", ErrInvalidKey)
+			}},
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to add key", "reason": "invalid key: expected a PEM encoded PUBLIC KEY"}`,
+		},
+		{
+			name:     "POST failure - exists",
+			method:   http.MethodPost,
+			path:     "/keys",
+			identity: admin,
+			body:     `{"name": "ci", "public_key": "PEM"}`,
+			service: &mockHandlerService{AddKeyFn: func(ctx context.Context, k *Key) (*Key, error) {
+				return nil, fmt.Errorf("key abc: %w", ErrExists)
+			}},
+			expectedStatus:   http.StatusConflict,
+			expectedContains: `{"code": 409, "error": "Failed to add key", "reason": "key abc: already exists"}`,
+		},
+		{
+			name:     "DELETE success",
+			method:   http.MethodDelete,
+			path:     "/keys/abc",
+			identity: admin,
+			service: &mockHandlerService{RevokeKeyFn: func(ctx context.Context, tenant string, id string) (*Key, error) {
+				return &Key{ID: id, Name: "ci", PublicKey: "PEM", CreatedAt: handlerTime, RevokedAt: &handlerTime}, nil
+			}},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"id": "abc", "name": "ci", "public_key": "PEM", "created_at": "2025-07-04T09:00:00Z", "created_by": "", "revoked_at": "2025-07-04T09:00:00Z"}`,
+		},
+		{
+			name:     "DELETE failure - not found",
+			method:   http.MethodDelete,
+			path:     "/keys/abc",
+			identity: admin,
+			service: &mockHandlerService{RevokeKeyFn: func(ctx context.Context, tenant string, id string) (*Key, error) {
+				return nil, fmt.Errorf("key %s: %w", id, internal.ErrNotFound)
+			}},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Key not found", "reason": "key abc: not found"}`,
+		},
+		{
+			name:             "POST failure - not an admin",
+			method:           http.MethodPost,
+			path:             "/keys",
+			body:             `{"name": "ci", "public_key": "PEM"}`,
+			identity:         trainer,
+			service:          &mockHandlerService{},
+			expectedStatus:   http.StatusForbidden,
+			expectedContains: `{"code": 403, "error": "Failed to add key", "reason": "requires the admin role"}`,
+		},
+		{
+			name:             "DELETE failure - not an admin",
+			method:           http.MethodDelete,
+			path:             "/keys/abc",
+			identity:         trainer,
+			service:          &mockHandlerService{},
+			expectedStatus:   http.StatusForbidden,
+			expectedContains: `{"code": 403, "error": "Failed to revoke key", "reason": "requires the admin role"}`,
+		},
+		{
+			name:             "METHOD failure",
+			method:           http.MethodPut,
+			path:             "/keys",
+			service:          &mockHandlerService{},
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewHandler(tc.service, tc.service)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if tc.identity != nil {
+				req = req.WithContext(auth.NewContext(req.Context(), tc.identity))
+			}
+			rr := httptest.NewRecorder()
+
+			if tc.path == "/keys" {
+				handler.Keys(rr, req)
+			} else {
+				req = mux.SetURLVars(req, map[string]string{"id": strings.TrimPrefix(tc.path, "/keys/")})
+				handler.Key(rr, req)
+			}
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func TestSignaturesHandler(t *testing.T) {
+	tests := []struct {
+		name             string
+		method           string
+		body             string
+		service          *mockHandlerService
+		expectedStatus   int
+		expectedContains string
+	}{
+		{
+			name:   "GET success",
+			method: http.MethodGet,
+			service: &mockHandlerService{BundleFn: func(ctx context.Context, id string) (*Bundle, error) {
+				return &Bundle{
+					ModelID:    id,
+					Artefacts:  map[string]Artefact{"model": {FileName: "model.pkl", Digest: "sha256:aa"}},
+					Signatures: []*Signature{},
+				}, nil
+			}},
+			expectedStatus:   http.StatusOK,
+			expectedContains: `{"model_id": "1", "artefacts": {"model": {"file_name": "model.pkl", "digest": "sha256:aa"}}, "signatures": []}`,
+		},
+		{
+			name:   "GET failure - not found",
+			method: http.MethodGet,
+			service: &mockHandlerService{BundleFn: func(ctx context.Context, id string) (*Bundle, error) {
+				return nil, fmt.Errorf("model %s: %w", id, internal.ErrNotFound)
+			}},
+			expectedStatus:   http.StatusNotFound,
+			expectedContains: `{"code": 404, "error": "Model not found", "reason": "model 1: not found"}`,
+		},
+		{
+			name:   "POST success",
+			method: http.MethodPost,
+			body:   `{"key_id": "abc", "signature": "c2ln"}`,
+			service: &mockHandlerService{SignFn: func(ctx context.Context, id string, sig *Signature) (*Signature, error) {
+				return &Signature{ModelID: id, KeyID: sig.KeyID, Algorithm: Algorithm, Signature: sig.Signature, CreatedAt: handlerTime}, nil
+			}},
+			expectedStatus:   http.StatusCreated,
+			expectedContains: `{"model_id": "1", "key_id": "abc", "algorithm": "ed25519", "signature": "c2ln", "created_at": "2025-07-04T09:00:00Z", "created_by": ""}`,
+		},
+		{
+			name:   "POST failure - untrusted",
+			method: http.MethodPost,
+			body:   `{"key_id": "abc", "signature": "c2ln"}`,
+			service: &mockHandlerService{SignFn: func(ctx context.Context, id string, sig *Signature) (*Signature, error) {
+				return nil, fmt.Errorf("key %s: %w", sig.KeyID, ErrUntrustedKey)
+			}},
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to sign model", "reason": "key abc: key is not trusted"}`,
+		},
+		{
+			name:   "POST failure - already signed",
+			method: http.MethodPost,
+			body:   `{"key_id": "abc", "signature": "c2ln"}`,
+			service: &mockHandlerService{SignFn: func(ctx context.Context, id string, sig *Signature) (*Signature, error) {
+				return nil, fmt.Errorf("model 1 is already signed by abc: %w", ErrExists)
+			}},
+			expectedStatus:   http.StatusConflict,
+			expectedContains: `{"code": 409, "error": "Failed to sign model", "reason": "model 1 is already signed by abc: already exists"}`,
+		},
+		{
+			name:             "POST failure - bad body",
+			method:           http.MethodPost,
+			body:             `{`,
+			service:          &mockHandlerService{},
+			expectedStatus:   http.StatusBadRequest,
+			expectedContains: `{"code": 400, "error": "Failed to sign model", "reason": "could not parse body: unexpected EOF"}`,
+		},
+		{
+			name:             "METHOD failure",
+			method:           http.MethodDelete,
+			service:          &mockHandlerService{},
+			expectedStatus:   http.StatusMethodNotAllowed,
+			expectedContains: `{"code": 405, "error": "Method not allowed", "reason": ""}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := NewHandler(tc.service, tc.service)
+
+			req := httptest.NewRequest(tc.method, "/models/1/signatures", strings.NewReader(tc.body))
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+			rr := httptest.NewRecorder()
+
+			handler.Signatures(rr, req)
+
+			checkResponse(t, rr.Result(), tc.expectedStatus, tc.expectedContains)
+		})
+	}
+}
+
+func checkResponse(t *testing.T, got *http.Response, expectedStatus int, expected string) {
+
+	defer got.Body.Close()
+
+	body, err := io.ReadAll(got.Body)
+	if err != nil {
+		t.Fatalf("failed to read response body: %s", err)
+	}
+
+	if expectedStatus != got.StatusCode {
+		t.Errorf("status mismatch - wanted %d, got %d",
+			expectedStatus,
+			got.StatusCode,
+		)
+	}
+
+	var gotData any
+	if err := json.Unmarshal(body, &gotData); err != nil {
+		t.Fatalf("failed to unmarshal response body: %v\nbody: %s", err, string(body))
+	}
+
+	var expectedData any
+	if err := json.Unmarshal([]byte(expected), &expectedData); err != nil {
+		t.Fatalf("failed to unmarshal expected value: %v\njson: %s", err, string(expected))
+	}
+
+	if !reflect.DeepEqual(expectedData, gotData) {
+		t.Errorf("JSON mismatch:\nexpected: %+v\ngot: %+v", expectedData, gotData)
+	}
+}
@@ -0,0 +1,235 @@
+package signing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/auth"
+	"github.com/heldtogether/traintrack/internal/models"
+	"github.com/heldtogether/traintrack/internal/seal"
+	"github.com/jackc/pgx/v5"
+)
+
+/*
+ModelGetter looks up a model, and what it was sealed with.
+*/
+type ModelGetter interface {
+	Get(id string) (*models.Model, error)
+	Record(id string) (*seal.Record, error)
+}
+
+type signingStore interface {
+	Keys(ctx context.Context, tenant string) ([]*Key, error)
+	Signatures(ctx context.Context, modelID string) ([]*Signature, error)
+	addKeyWithQuerier(ctx context.Context, q Querier, k *Key) error
+	revokeKeyWithQuerier(ctx context.Context, q Querier, tenant string, id string) (*Key, error)
+	addSignatureWithQuerier(ctx context.Context, q Querier, sig *Signature) error
+}
+
+/*
+AuditRecorder records an audit event using the provided Querier, which
+may be a transaction.
+*/
+type AuditRecorder interface {
+	RecordWithQuerier(ctx context.Context, q audit.Querier, e *audit.Event) error
+}
+
+type TxBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type Service struct {
+	s      signingStore
+	models ModelGetter
+	db     TxBeginner
+	audit  AuditRecorder
+}
+
+func NewService(s *Store, m ModelGetter, db TxBeginner, a AuditRecorder) *Service {
+	return &Service{
+		s:      s,
+		models: m,
+		db:     db,
+		audit:  a,
+	}
+}
+
+/*
+Keys returns tenant's keys, including revoked ones.
+*/
+func (s *Service) Keys(ctx context.Context, tenant string) ([]*Key, error) {
+	return s.s.Keys(ctx, tenant)
+}
+
+/*
+AddKey trusts k for the caller's tenant. The key's ID is derived from the
+public key, and its tenant and creator from the verified identity on ctx.
+*/
+func (s *Service) AddKey(ctx context.Context, k *Key) (*Key, error) {
+	pub, err := ParsePublicKey([]byte(k.PublicKey))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidKey, err)
+	}
+	normalised, err := MarshalPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+
+	added := &Key{
+		ID:        KeyID(pub),
+		Name:      k.Name,
+		PublicKey: string(normalised),
+	}
+	if id, ok := auth.IdentityFromContext(ctx); ok {
+		added.Tenant = id.Tenant
+		added.CreatedBy = id.Subject
+	}
+
+	err = s.inTx(ctx, func(tx pgx.Tx) error {
+		if err := s.s.addKeyWithQuerier(ctx, tx, added); err != nil {
+			return err
+		}
+		return s.record(ctx, tx, audit.NewEvent(ctx, audit.ActionCreate, audit.ResourceSigningKey, added.ID).
+			WithDetails(map[string]string{"name": added.Name}))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return added, nil
+}
+
+/*
+RevokeKey stops the key id being trusted by tenant. Signatures it made
+are kept, but no longer count.
+*/
+func (s *Service) RevokeKey(ctx context.Context, tenant string, id string) (revoked *Key, err error) {
+	err = s.inTx(ctx, func(tx pgx.Tx) error {
+		if revoked, err = s.s.revokeKeyWithQuerier(ctx, tx, tenant, id); err != nil {
+			return err
+		}
+		return s.record(ctx, tx, audit.NewEvent(ctx, audit.ActionRevoke, audit.ResourceSigningKey, id))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return revoked, nil
+}
+
+/*
+Bundle gathers the model id's artefact digests, its signatures and the
+keys its tenant trusts.
+*/
+func (s *Service) Bundle(ctx context.Context, id string) (*Bundle, error) {
+	m, err := s.models.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := s.models.Record(id)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Bundle{
+		ModelID:   m.ID,
+		Seal:      m.Seal,
+		Artefacts: make(map[string]Artefact, len(r.Artefacts)),
+	}
+	for name, a := range r.Artefacts {
+		b.Artefacts[name] = Artefact{FileName: a.FileName, Digest: a.Digest}
+	}
+
+	if b.Signatures, err = s.s.Signatures(ctx, m.ID); err != nil {
+		return nil, err
+	}
+	if b.Keys, err = s.s.Keys(ctx, m.Tenant); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+/*
+Sign stores sig against the model id, provided it was made over the
+model's artefacts by a key the model's tenant trusts.
+*/
+func (s *Service) Sign(ctx context.Context, id string, sig *Signature) (*Signature, error) {
+	b, err := s.Bundle(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	signed := &Signature{
+		ModelID:   b.ModelID,
+		KeyID:     sig.KeyID,
+		Algorithm: sig.Algorithm,
+		Signature: sig.Signature,
+	}
+	if signed.Algorithm == "" {
+		signed.Algorithm = Algorithm
+	}
+	if identity, ok := auth.IdentityFromContext(ctx); ok {
+		signed.CreatedBy = identity.Subject
+	}
+
+	k := findKey(b.Keys, signed.KeyID)
+	if k == nil || k.Revoked() {
+		return nil, fmt.Errorf("key %s: %w", signed.KeyID, ErrUntrustedKey)
+	}
+	if err := signed.Verify(k, b.Artefacts); err != nil {
+		return nil, err
+	}
+
+	err = s.inTx(ctx, func(tx pgx.Tx) error {
+		if err := s.s.addSignatureWithQuerier(ctx, tx, signed); err != nil {
+			return err
+		}
+		return s.record(ctx, tx, audit.NewEvent(ctx, audit.ActionSign, audit.ResourceModel, b.ModelID).
+			WithDetails(map[string]string{"key_id": signed.KeyID}))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return signed, nil
+}
+
+func (s *Service) inTx(ctx context.Context, fn func(tx pgx.Tx) error) (err error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *Service) record(ctx context.Context, tx pgx.Tx, e *audit.Event) error {
+	if s.audit == nil {
+		return nil
+	}
+	if err := s.audit.RecordWithQuerier(ctx, tx, e); err != nil {
+		return fmt.Errorf("record audit event: %w", err)
+	}
+	return nil
+}
+
+func findKey(keys []*Key, id string) *Key {
+	for _, k := range keys {
+		if k.ID == id {
+			return k
+		}
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package signing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/models"
+	"github.com/heldtogether/traintrack/internal/seal"
+	"github.com/jackc/pgx/v5"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+type mockModels struct{}
+
+func (m *mockModels) Get(id string) (*models.Model, error) {
+	return &models.Model{ID: id, Tenant: "acme", Seal: "sha256:ee"}, nil
+}
+
+func (m *mockModels) Record(id string) (*seal.Record, error) {
+	r := &seal.Record{ID: id, Artefacts: map[string]seal.Artefact{}}
+	for name, a := range testArtefacts() {
+		r.Artefacts[name] = seal.Artefact{FileName: a.FileName, Digest: a.Digest}
+	}
+	return r, nil
+}
+
+type mockStore struct {
+	keys   []*Key
+	sigs   []*Signature
+	called []string
+}
+
+func (m *mockStore) Keys(_ context.Context, tenant string) ([]*Key, error) {
+	return m.keys, nil
+}
+
+func (m *mockStore) Signatures(_ context.Context, modelID string) ([]*Signature, error) {
+	return m.sigs, nil
+}
+
+func (m *mockStore) addKeyWithQuerier(_ context.Context, _ Querier, k *Key) error {
+	m.called = append(m.called, "add-key")
+	m.keys = append(m.keys, k)
+	return nil
+}
+
+func (m *mockStore) revokeKeyWithQuerier(_ context.Context, _ Querier, tenant string, id string) (*Key, error) {
+	m.called = append(m.called, "revoke-key")
+	return &Key{ID: id, Tenant: tenant}, nil
+}
+
+func (m *mockStore) addSignatureWithQuerier(_ context.Context, _ Querier, sig *Signature) error {
+	m.called = append(m.called, "add-signature")
+	m.sigs = append(m.sigs, sig)
+	return nil
+}
+
+type mockAudit struct {
+	events []*audit.Event
+}
+
+func (m *mockAudit) RecordWithQuerier(_ context.Context, _ audit.Querier, e *audit.Event) error {
+	m.events = append(m.events, e)
+	return nil
+}
+
+type mockDB struct{}
+
+func (m *mockDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	conn, err := pgxmock.NewConn()
+	if err != nil {
+		return nil, err
+	}
+	conn.ExpectBegin()
+	conn.ExpectCommit()
+	conn.ExpectRollback()
+	return conn.Begin(ctx)
+}
+
+func newService(store *mockStore, a *mockAudit) *Service {
+	return &Service{s: store, models: &mockModels{}, db: &mockDB{}, audit: a}
+}
+
+func TestService_Sign(t *testing.T) {
+	trusted, trustedPriv := newKey(t)
+	revoked, revokedPriv := newKey(t)
+	_, strangerPriv := newKey(t)
+	revokedAt := time.Now()
+	revoked.RevokedAt = &revokedAt
+
+	sign := func(priv ed25519.PrivateKey, artefacts map[string]Artefact) *Signature {
+		sig, err := Sign(priv, artefacts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sig
+	}
+
+	tampered := testArtefacts()
+	tampered["model"] = Artefact{Digest: "sha256:ff"}
+
+	tests := []struct {
+		name    string
+		sig     *Signature
+		wantErr error
+	}{
+		{name: "trusted", sig: sign(trustedPriv, testArtefacts())},
+		{name: "revoked key", sig: sign(revokedPriv, testArtefacts()), wantErr: ErrUntrustedKey},
+		{name: "unknown key", sig: sign(strangerPriv, testArtefacts()), wantErr: ErrUntrustedKey},
+		{name: "wrong artefacts", sig: sign(trustedPriv, tampered), wantErr: ErrInvalidSignature},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &mockStore{keys: []*Key{trusted, revoked}}
+			a := &mockAudit{}
+			s := newService(store, a)
+
+			signed, err := s.Sign(context.Background(), "1", tc.sig)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Errorf("got error %v, wanted %v", err, tc.wantErr)
+				}
+				if len(store.called) != 0 || len(a.events) != 0 {
+					t.Errorf("expected nothing to be stored, got %v", store.called)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if signed.ModelID != "1" || len(store.sigs) != 1 {
+				t.Errorf("signature not stored: %+v", signed)
+			}
+			if len(a.events) != 1 || a.events[0].Action != audit.ActionSign {
+				t.Errorf("expected a sign audit event, got %+v", a.events)
+			}
+		})
+	}
+}
+
+func TestService_AddKey(t *testing.T) {
+	k, _ := newKey(t)
+
+	store := &mockStore{}
+	s := newService(store, &mockAudit{})
+
+	added, err := s.AddKey(context.Background(), &Key{ID: "ignored", Name: "ci", PublicKey: k.PublicKey})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if added.ID != k.ID {
+		t.Errorf("expected id to be the fingerprint %s, got %s", k.ID, added.ID)
+	}
+
+	if _, err := s.AddKey(context.Background(), &Key{Name: "ci", PublicKey: "nope"}); !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
+	}
+}
+
+func TestGuard(t *testing.T) {
+	trusted, priv := newKey(t)
+	sig, err := Sign(priv, testArtefacts())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		sigs        []*Signature
+		to          models.Stage
+		wantRefused bool
+	}{
+		{name: "signed", sigs: []*Signature{sig}, to: models.StageProduction},
+		{name: "unsigned", to: models.StageProduction, wantRefused: true},
+		{name: "unsigned to unguarded stage", to: models.StageArchived},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &mockStore{keys: []*Key{trusted}, sigs: tc.sigs}
+			g := NewGuard(newService(store, nil))
+
+			err := g.CheckPromotion(context.Background(), &models.Model{ID: "1"}, tc.to)
+			if errors.Is(err, models.ErrPromotionRefused) != tc.wantRefused {
+				t.Errorf("got %v, wanted refused: %v", err, tc.wantRefused)
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	Algorithm   = "ed25519"
+	PayloadType = "application/vnd.traintrack.artefacts.v1+json"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrInvalidKey       = errors.New("invalid key")
+	ErrUntrustedKey     = errors.New("key is not trusted")
+	ErrExists           = errors.New("already exists")
+)
+
+/*
+Key is a public key trusted to sign a tenant's models. Its ID is a
+fingerprint of the key itself, so the same key always has the same ID.
+*/
+type Key struct {
+	ID        string     `json:"id"`
+	Tenant    string     `json:"tenant,omitempty"`
+	Name      string     `json:"name"`
+	PublicKey string     `json:"public_key"`
+	CreatedAt time.Time  `json:"created_at"`
+	CreatedBy string     `json:"created_by"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+func (k *Key) Revoked() bool { return k.RevokedAt != nil }
+
+/*
+Signature is a detached signature over a model's artefacts.
+*/
+type Signature struct {
+	ModelID   string    `json:"model_id"`
+	KeyID     string    `json:"key_id"`
+	Algorithm string    `json:"algorithm"`
+	Signature string    `json:"signature"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+}
+
+/*
+Artefact is what's signed for each of a model's artefacts. Only the digest
+goes into the payload; the file name is there to find the file again when
+verifying a local copy.
+*/
+type Artefact struct {
+	FileName string `json:"file_name,omitempty"`
+	Digest   string `json:"digest"`
+}
+
+/*
+Bundle holds everything needed to check a model's signatures offline.
+*/
+type Bundle struct {
+	ModelID    string              `json:"model_id"`
+	Seal       string              `json:"seal,omitempty"`
+	Artefacts  map[string]Artefact `json:"artefacts"`
+	Signatures []*Signature        `json:"signatures"`
+	Keys       []*Key              `json:"keys,omitempty"`
+}
+
+/*
+Payload returns the canonical bytes signed for a set of artefacts. Map
+keys are sorted when marshalled, so the payload doesn't depend on the
+order the artefacts were listed in.
+*/
+func Payload(artefacts map[string]Artefact) ([]byte, error) {
+	digests := make(map[string]string, len(artefacts))
+	for name, a := range artefacts {
+		if a.Digest == "" {
+			return nil, fmt.Errorf("artefact %s has no digest", name)
+		}
+		digests[name] = a.Digest
+	}
+
+	return json.Marshal(struct {
+		Type      string            `json:"_type"`
+		Artefacts map[string]string `json:"artefacts"`
+	}{
+		Type:      PayloadType,
+		Artefacts: digests,
+	})
+}
+
+/*
+KeyID returns the fingerprint used to identify pub.
+*/
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+/*
+Sign signs artefacts with priv.
+*/
+func Sign(priv ed25519.PrivateKey, artefacts map[string]Artefact) (*Signature, error) {
+	payload, err := Payload(artefacts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Signature{
+		KeyID:     KeyID(priv.Public().(ed25519.PublicKey)),
+		Algorithm: Algorithm,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(priv, payload)),
+	}, nil
+}
+
+/*
+Verify checks s was made over artefacts by k. It doesn't consider whether
+k has been revoked.
+*/
+func (s *Signature) Verify(k *Key, artefacts map[string]Artefact) error {
+	if s.Algorithm != Algorithm {
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidSignature, s.Algorithm)
+	}
+
+	pub, err := ParsePublicKey([]byte(k.PublicKey))
+	if err != nil {
+		return err
+	}
+	if KeyID(pub) != s.KeyID {
+		return fmt.Errorf("%w: made by key %s, not %s", ErrInvalidSignature, s.KeyID, KeyID(pub))
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(s.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidSignature, err)
+	}
+
+	payload, err := Payload(artefacts)
+	if err != nil {
+		return err
+	}
+
+	if !ed25519.Verify(pub, payload, raw) {
+		return fmt.Errorf("%w: does not match the artefacts", ErrInvalidSignature)
+	}
+	return nil
+}
+
+/*
+Verify returns the signatures in b made by one of the trusted keys, along
+with a description of every signature that couldn't be trusted. Revoked
+keys aren't trusted.
+*/
+func (b *Bundle) Verify(trusted []*Key) ([]*Signature, []string) {
+	keys := make(map[string]*Key, len(trusted))
+	for _, k := range trusted {
+		keys[k.ID] = k
+	}
+
+	var valid []*Signature
+	var problems []string
+	for _, s := range b.Signatures {
+		k, ok := keys[s.KeyID]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("signature by %s: %s", s.KeyID, ErrUntrustedKey))
+			continue
+		}
+		if k.Revoked() {
+			problems = append(problems, fmt.Sprintf("signature by %s: key was revoked at %s", s.KeyID, k.RevokedAt.Format(time.RFC3339)))
+			continue
+		}
+		if err := s.Verify(k, b.Artefacts); err != nil {
+			problems = append(problems, fmt.Sprintf("signature by %s: %s", s.KeyID, err))
+			continue
+		}
+		valid = append(valid, s)
+	}
+
+	return valid, problems
+}
+
+/*
+ParsePublicKey reads a PEM encoded (PKIX) ed25519 public key.
+*/
+func ParsePublicKey(data []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, errors.New("expected a PEM encoded PUBLIC KEY")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse public key: %w", err)
+	}
+
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an %s public key, got %T", Algorithm, key)
+	}
+	return pub, nil
+}
+
+/*
+MarshalPublicKey PEM encodes pub.
+*/
+func MarshalPublicKey(pub ed25519.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+/*
+ParsePrivateKey reads a PEM encoded (PKCS #8) ed25519 private key.
+*/
+func ParsePrivateKey(data []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("expected a PEM encoded PRIVATE KEY")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse private key: %w", err)
+	}
+
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an %s private key, got %T", Algorithm, key)
+	}
+	return priv, nil
+}
+
+/*
+MarshalPrivateKey PEM encodes priv.
+*/
+func MarshalPrivateKey(priv ed25519.PrivateKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
@@ -0,0 +1,148 @@
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newKey(t *testing.T) (*Key, ed25519.PrivateKey) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pemBytes, err := MarshalPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Key{ID: KeyID(pub), Name: "ci", PublicKey: string(pemBytes)}, priv
+}
+
+func testArtefacts() map[string]Artefact {
+	return map[string]Artefact{
+		"model":   {FileName: "model.pkl", Digest: "sha256:aa"},
+		"weights": {FileName: "weights.bin", Digest: "sha256:bb"},
+	}
+}
+
+func TestPayloadIsCanonical(t *testing.T) {
+	a, err := Payload(testArtefacts())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	renamed := testArtefacts()
+	renamed["model"] = Artefact{FileName: "elsewhere.pkl", Digest: "sha256:aa"}
+	b, err := Payload(renamed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"_type":"` + PayloadType + `","artefacts":{"model":"sha256:aa","weights":"sha256:bb"}}`
+	if string(a) != want || string(b) != want {
+		t.Errorf("got %s and %s, wanted %s", a, b, want)
+	}
+
+	if _, err := Payload(map[string]Artefact{"model": {FileName: "model.pkl"}}); err == nil {
+		t.Errorf("expected an artefact without a digest to be rejected")
+	}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	k, priv := newKey(t)
+
+	sig, err := Sign(priv, testArtefacts())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig.KeyID != k.ID || sig.Algorithm != Algorithm {
+		t.Errorf("unexpected signature: %+v", sig)
+	}
+
+	if err := sig.Verify(k, testArtefacts()); err != nil {
+		t.Errorf("expected signature to verify: %s", err)
+	}
+
+	tampered := testArtefacts()
+	tampered["weights"] = Artefact{Digest: "sha256:cc"}
+	if err := sig.Verify(k, tampered); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected tampered artefacts to fail, got %v", err)
+	}
+
+	other, _ := newKey(t)
+	if err := sig.Verify(other, testArtefacts()); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected a different key to fail, got %v", err)
+	}
+}
+
+func TestBundleVerify(t *testing.T) {
+	trusted, trustedPriv := newKey(t)
+	revoked, revokedPriv := newKey(t)
+	_, strangerPriv := newKey(t)
+
+	revokedAt := time.Date(2025, 7, 4, 9, 0, 0, 0, time.UTC)
+	revoked.RevokedAt = &revokedAt
+
+	var sigs []*Signature
+	for _, priv := range []ed25519.PrivateKey{trustedPriv, revokedPriv, strangerPriv} {
+		sig, err := Sign(priv, testArtefacts())
+		if err != nil {
+			t.Fatal(err)
+		}
+		sigs = append(sigs, sig)
+	}
+
+	b := &Bundle{ModelID: "1", Artefacts: testArtefacts(), Signatures: sigs}
+
+	valid, problems := b.Verify([]*Key{trusted, revoked})
+	if len(valid) != 1 || valid[0].KeyID != trusted.ID {
+		t.Errorf("expected only the trusted signature to be valid, got %+v", valid)
+	}
+	if len(problems) != 2 {
+		t.Fatalf("expected 2 problems, got %v", problems)
+	}
+	if !strings.Contains(problems[0], "revoked") || !strings.Contains(problems[1], ErrUntrustedKey.Error()) {
+		t.Errorf("unexpected problems: %v", problems)
+	}
+}
+
+func TestKeyPEMRoundTrip(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privPEM, err := MarshalPrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotPriv, err := ParsePrivateKey(privPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gotPriv.Equal(priv) {
+		t.Errorf("private key did not round trip")
+	}
+
+	pubPEM, err := MarshalPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gotPub, err := ParsePublicKey(pubPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gotPub.Equal(pub) {
+		t.Errorf("public key did not round trip")
+	}
+
+	if _, err := ParsePublicKey(privPEM); err == nil {
+		t.Errorf("expected a private key to be rejected as a public key")
+	}
+}
@@ -0,0 +1,147 @@
+package signing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	addKeyQuery = `INSERT INTO signing_keys
+(id, tenant, name, public_key, created_by)
+VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+RETURNING created_at`
+	keysQuery = `SELECT
+  id,
+  tenant,
+  name,
+  public_key,
+  created_at,
+  COALESCE(created_by, ''),
+  revoked_at
+FROM signing_keys
+WHERE tenant = $1
+ORDER BY created_at, id`
+	revokeKeyQuery = `UPDATE signing_keys SET revoked_at = now()
+WHERE tenant = $1 AND id = $2 AND revoked_at IS NULL
+RETURNING name, public_key, created_at, COALESCE(created_by, ''), revoked_at`
+	addSignatureQuery = `INSERT INTO model_signatures
+(model_id, key_id, algorithm, signature, created_by)
+VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+RETURNING created_at`
+	signaturesQuery = `SELECT
+  model_id::text,
+  key_id,
+  algorithm,
+  signature,
+  created_at,
+  COALESCE(created_by, '')
+FROM model_signatures
+WHERE model_id = $1
+ORDER BY created_at, id`
+
+	uniqueViolation = "23505"
+)
+
+type Querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+type Store struct {
+	q Querier
+}
+
+func NewStore(q Querier) *Store {
+	return &Store{
+		q: q,
+	}
+}
+
+/*
+Keys returns every key, revoked or not, belonging to tenant.
+*/
+func (s *Store) Keys(ctx context.Context, tenant string) ([]*Key, error) {
+	rows, err := s.q.Query(ctx, keysQuery, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("could not query signing keys: %s", err)
+	}
+	defer rows.Close()
+
+	ks := []*Key{}
+	for rows.Next() {
+		k := &Key{}
+		if err := rows.Scan(&k.ID, &k.Tenant, &k.Name, &k.PublicKey, &k.CreatedAt, &k.CreatedBy, &k.RevokedAt); err != nil {
+			return nil, err
+		}
+		ks = append(ks, k)
+	}
+
+	return ks, rows.Err()
+}
+
+func (s *Store) addKeyWithQuerier(ctx context.Context, q Querier, k *Key) error {
+	row := q.QueryRow(ctx, addKeyQuery, k.ID, k.Tenant, k.Name, k.PublicKey, k.CreatedBy)
+	if err := row.Scan(&k.CreatedAt); err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("key %s: %w", k.ID, ErrExists)
+		}
+		return fmt.Errorf("could not add signing key: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) revokeKeyWithQuerier(ctx context.Context, q Querier, tenant string, id string) (*Key, error) {
+	k := &Key{ID: id, Tenant: tenant}
+	row := q.QueryRow(ctx, revokeKeyQuery, tenant, id)
+	if err := row.Scan(&k.Name, &k.PublicKey, &k.CreatedAt, &k.CreatedBy, &k.RevokedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("key %s: %w", id, internal.ErrNotFound)
+		}
+		return nil, fmt.Errorf("could not revoke signing key: %w", err)
+	}
+	return k, nil
+}
+
+/*
+Signatures returns every signature stored for the model id.
+*/
+func (s *Store) Signatures(ctx context.Context, modelID string) ([]*Signature, error) {
+	rows, err := s.q.Query(ctx, signaturesQuery, modelID)
+	if err != nil {
+		return nil, fmt.Errorf("could not query signatures: %s", err)
+	}
+	defer rows.Close()
+
+	ss := []*Signature{}
+	for rows.Next() {
+		sig := &Signature{}
+		if err := rows.Scan(&sig.ModelID, &sig.KeyID, &sig.Algorithm, &sig.Signature, &sig.CreatedAt, &sig.CreatedBy); err != nil {
+			return nil, err
+		}
+		ss = append(ss, sig)
+	}
+
+	return ss, rows.Err()
+}
+
+func (s *Store) addSignatureWithQuerier(ctx context.Context, q Querier, sig *Signature) error {
+	row := q.QueryRow(ctx, addSignatureQuery, sig.ModelID, sig.KeyID, sig.Algorithm, sig.Signature, sig.CreatedBy)
+	if err := row.Scan(&sig.CreatedAt); err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("model %s is already signed by %s: %w", sig.ModelID, sig.KeyID, ErrExists)
+		}
+		return fmt.Errorf("could not add signature: %w", err)
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == uniqueViolation
+}
@@ -0,0 +1,147 @@
+package signing
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/heldtogether/traintrack/internal"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+)
+
+func TestKeys(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2025, 7, 4, 9, 0, 0, 0, time.UTC)
+	db.ExpectQuery(regexp.QuoteMeta(keysQuery)).
+		WithArgs("acme").
+		WillReturnRows(db.NewRows([]string{"id", "tenant", "name", "public_key", "created_at", "created_by", "revoked_at"}).
+			AddRow("abc", "acme", "ci", "PEM", createdAt, "dev|1", nil))
+
+	service := NewStore(db)
+
+	ks, err := service.Keys(context.Background(), "acme")
+	if err != nil {
+		t.Fatalf("could not list: %s", err)
+	}
+	if len(ks) != 1 || ks[0].ID != "abc" || ks[0].Revoked() {
+		t.Errorf("unexpected keys: %+v", ks)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestAddKey(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2025, 7, 4, 9, 0, 0, 0, time.UTC)
+	db.ExpectQuery(regexp.QuoteMeta(addKeyQuery)).
+		WithArgs("abc", "acme", "ci", "PEM", "dev|1").
+		WillReturnRows(db.NewRows([]string{"created_at"}).AddRow(createdAt))
+	db.ExpectQuery(regexp.QuoteMeta(addKeyQuery)).
+		WithArgs("abc", "acme", "ci", "PEM", "dev|1").
+		WillReturnError(&pgconn.PgError{Code: uniqueViolation})
+
+	service := NewStore(db)
+
+	k := &Key{ID: "abc", Tenant: "acme", Name: "ci", PublicKey: "PEM", CreatedBy: "dev|1"}
+	if err := service.addKeyWithQuerier(context.Background(), db, k); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !k.CreatedAt.Equal(createdAt) {
+		t.Errorf("created_at not set: %+v", k)
+	}
+
+	if err := service.addKeyWithQuerier(context.Background(), db, k); !errors.Is(err, ErrExists) {
+		t.Errorf("expected ErrExists, got %v", err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRevokeKey(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	revokedAt := time.Date(2025, 7, 5, 9, 0, 0, 0, time.UTC)
+	db.ExpectQuery(regexp.QuoteMeta(revokeKeyQuery)).
+		WithArgs("acme", "abc").
+		WillReturnRows(db.NewRows([]string{"name", "public_key", "created_at", "created_by", "revoked_at"}).
+			AddRow("ci", "PEM", time.Time{}, "dev|1", &revokedAt))
+	db.ExpectQuery(regexp.QuoteMeta(revokeKeyQuery)).
+		WithArgs("acme", "abc").
+		WillReturnError(pgx.ErrNoRows)
+
+	service := NewStore(db)
+
+	k, err := service.revokeKeyWithQuerier(context.Background(), db, "acme", "abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !k.Revoked() || k.Name != "ci" {
+		t.Errorf("unexpected key: %+v", k)
+	}
+
+	if _, err := service.revokeKeyWithQuerier(context.Background(), db, "acme", "abc"); !errors.Is(err, internal.ErrNotFound) {
+		t.Errorf("expected ErrNotFound revoking twice, got %v", err)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSignatures(t *testing.T) {
+	db, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	createdAt := time.Date(2025, 7, 4, 9, 0, 0, 0, time.UTC)
+	db.ExpectQuery(regexp.QuoteMeta(addSignatureQuery)).
+		WithArgs("1", "abc", Algorithm, "c2ln", "dev|1").
+		WillReturnRows(db.NewRows([]string{"created_at"}).AddRow(createdAt))
+	db.ExpectQuery(regexp.QuoteMeta(signaturesQuery)).
+		WithArgs("1").
+		WillReturnRows(db.NewRows([]string{"model_id", "key_id", "algorithm", "signature", "created_at", "created_by"}).
+			AddRow("1", "abc", Algorithm, "c2ln", createdAt, "dev|1"))
+
+	service := NewStore(db)
+
+	sig := &Signature{ModelID: "1", KeyID: "abc", Algorithm: Algorithm, Signature: "c2ln", CreatedBy: "dev|1"}
+	if err := service.addSignatureWithQuerier(context.Background(), db, sig); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	ss, err := service.Signatures(context.Background(), "1")
+	if err != nil {
+		t.Fatalf("could not list: %s", err)
+	}
+	if len(ss) != 1 || *ss[0] != *sig {
+		t.Errorf("got %+v, wanted %+v", ss, sig)
+	}
+
+	if err := db.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
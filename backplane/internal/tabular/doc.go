@@ -0,0 +1,19 @@
+/*
+Package tabular reads CSV and Parquet artefacts into memory as tables of
+text, so that they can be compared whatever format they were saved in.
+
+Columns are given a type: "integer", "float", "boolean" or "string" for
+CSV, where it is inferred from the values, and the same or a logical
+type such as "timestamp" for Parquet, where it is read from the schema.
+Nested Parquet columns are named by their path, joined with dots:
+
+	if tabular.Supported(filename) {
+		t, err := tabular.Read(filename, content)
+	}
+
+A table's Profile summarises it, with its row and column counts and each
+column's nulls, smallest and largest values and an estimate of how many
+distinct values it has. Uploads are profiled as they arrive, so the
+profile of a dataset's artefacts can be given without reading them again.
+*/
+package tabular
@@ -0,0 +1,188 @@
+package tabular
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"math"
+	"math/bits"
+	"strconv"
+)
+
+const (
+	// exactDistinct is how many distinct values of a column are counted
+	// exactly before the count is estimated.
+	exactDistinct = 1000
+	// precision is the log2 of the number of registers used to estimate
+	// distinct values, which are within about 1.6% of the true count.
+	precision = 12
+)
+
+/*
+Profile summarises a table: how big it is and, for each column, its type
+and statistics.
+*/
+type Profile struct {
+	Format  Format          `json:"format"`
+	Rows    int             `json:"rows"`
+	Columns int             `json:"columns"`
+	Schema  []ColumnProfile `json:"schema"`
+}
+
+/*
+ColumnProfile is the statistics of a column. Nulls counts empty values,
+which are left out of the rest. Min and Max are numbers for numeric
+columns and strings otherwise, and null if every value is. Distinct is
+exact up to 1000 values and estimated beyond that.
+*/
+type ColumnProfile struct {
+	Name     string          `json:"name"`
+	Type     string          `json:"type"`
+	Nulls    int             `json:"nulls"`
+	Min      json.RawMessage `json:"min"`
+	Max      json.RawMessage `json:"max"`
+	Distinct int             `json:"distinct"`
+}
+
+/*
+Profile works out the statistics of every column of t.
+*/
+func (t *Table) Profile() *Profile {
+	p := &Profile{
+		Format:  t.Format,
+		Rows:    len(t.Rows),
+		Columns: len(t.Columns),
+		Schema:  make([]ColumnProfile, len(t.Columns)),
+	}
+	for i, c := range t.Columns {
+		p.Schema[i] = t.profileColumn(i, c)
+	}
+	return p
+}
+
+func (t *Table) profileColumn(i int, c Column) ColumnProfile {
+	cp := ColumnProfile{Name: c.Name, Type: c.Type}
+	numeric := c.Type == "integer" || c.Type == "float" || c.Type == "decimal"
+
+	var min, max string
+	var minNum, maxNum float64
+	seen := false
+	distinct := newDistinctCounter()
+	for _, row := range t.Rows {
+		v := row[i]
+		if v == "" {
+			cp.Nulls++
+			continue
+		}
+		distinct.add(v)
+
+		if numeric {
+			n, err := strconv.ParseFloat(v, 64)
+			if err != nil || math.IsNaN(n) {
+				continue
+			}
+			if !seen || n < minNum {
+				min, minNum = v, n
+			}
+			if !seen || n > maxNum {
+				max, maxNum = v, n
+			}
+		} else {
+			if !seen || v < min {
+				min = v
+			}
+			if !seen || v > max {
+				max = v
+			}
+		}
+		seen = true
+	}
+
+	cp.Distinct = distinct.count()
+	cp.Min, cp.Max = json.RawMessage("null"), json.RawMessage("null")
+	if seen {
+		cp.Min, cp.Max = jsonValue(min, numeric), jsonValue(max, numeric)
+	}
+	return cp
+}
+
+/*
+jsonValue encodes v as a number if it's numeric and written as one JSON
+can hold, such as 250000.5 but not Inf or .5, or else as a string.
+*/
+func jsonValue(v string, numeric bool) json.RawMessage {
+	if numeric && json.Valid([]byte(v)) {
+		return json.RawMessage(v)
+	}
+	data, _ := json.Marshal(v)
+	return data
+}
+
+/*
+distinctCounter counts distinct values exactly until there are too many
+to hold, and estimates the count with a HyperLogLog after that.
+*/
+type distinctCounter struct {
+	exact     map[string]struct{}
+	registers []uint8
+}
+
+func newDistinctCounter() *distinctCounter {
+	return &distinctCounter{
+		exact:     map[string]struct{}{},
+		registers: make([]uint8, 1<<precision),
+	}
+}
+
+func (d *distinctCounter) add(v string) {
+	if d.exact != nil {
+		d.exact[v] = struct{}{}
+		if len(d.exact) > exactDistinct {
+			d.exact = nil
+		}
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(v))
+	x := mix(h.Sum64())
+
+	i := x >> (64 - precision)
+	rank := uint8(bits.LeadingZeros64(x<<precision|1<<(precision-1))) + 1
+	if rank > d.registers[i] {
+		d.registers[i] = rank
+	}
+}
+
+func (d *distinctCounter) count() int {
+	if d.exact != nil {
+		return len(d.exact)
+	}
+
+	m := float64(len(d.registers))
+	sum, zeros := 0.0, 0
+	for _, r := range d.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := 0.7213 / (1 + 1.079/m) * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 {
+		// Few registers are set, so count the empty ones instead.
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return int(math.Round(estimate))
+}
+
+/*
+mix spreads the bits of an FNV hash, whose high bits vary too little
+between similar values to be used as they are.
+*/
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,48 @@
+package tabular
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	data := []byte("id,price,city,sold,empty\n1,250000.5,Leeds,true,\n2,,York,false,\n10,9.9e4,Hull,,\n3,Inf,York,true,\n")
+
+	table, err := Read("train.csv", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	got, err := json.Marshal(table.Profile())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"format":"csv","rows":4,"columns":5,"schema":[` +
+		`{"name":"id","type":"integer","nulls":0,"min":1,"max":10,"distinct":4},` +
+		`{"name":"price","type":"float","nulls":1,"min":9.9e4,"max":"Inf","distinct":3},` +
+		`{"name":"city","type":"string","nulls":0,"min":"Hull","max":"York","distinct":3},` +
+		`{"name":"sold","type":"boolean","nulls":1,"min":"false","max":"true","distinct":2},` +
+		`{"name":"empty","type":"string","nulls":4,"min":null,"max":null,"distinct":0}]}`
+	if string(got) != want {
+		t.Errorf("got profile\n%s\nwanted\n%s", got, want)
+	}
+}
+
+func TestDistinctEstimate(t *testing.T) {
+	for _, n := range []int{exactDistinct + 1, 20000, 200000} {
+		d := newDistinctCounter()
+		for i := range n {
+			// Every value twice, which shouldn't change the count.
+			d.add(fmt.Sprintf("value-%d", i))
+			d.add(fmt.Sprintf("value-%d", i))
+		}
+
+		got := d.count()
+		if diff := math.Abs(float64(got-n)) / float64(n); diff > 0.05 {
+			t.Errorf("estimated %d distinct values for %d, off by %.1f%%", got, n, diff*100)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package tabular
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+/*
+Format is the file format a table was read from.
+*/
+type Format string
+
+const (
+	FormatCSV     Format = "csv"
+	FormatParquet Format = "parquet"
+)
+
+// ErrUnsupported is returned by Read for files which aren't CSV or
+// Parquet.
+var ErrUnsupported = errors.New("not a CSV or Parquet file")
+
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+/*
+Table is a file read into memory. Each row has one value per column, as
+text, with missing and null values empty.
+*/
+type Table struct {
+	Format  Format
+	Columns []Column
+	Rows    [][]string
+}
+
+/*
+Supported reports whether Read can read filename, going by its
+extension.
+*/
+func Supported(filename string) bool {
+	_, ok := formatOf(filename)
+	return ok
+}
+
+/*
+Read reads data, the contents of filename, as a table. It returns
+ErrUnsupported unless filename ends in .csv or .parquet.
+*/
+func Read(filename string, data []byte) (*Table, error) {
+	format, ok := formatOf(filename)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+
+	var t *Table
+	var err error
+	switch format {
+	case FormatCSV:
+		t, err = readCSV(data)
+	case FormatParquet:
+		t, err = readParquet(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s as %s: %w", filename, format, err)
+	}
+	return t, nil
+}
+
+func formatOf(filename string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv":
+		return FormatCSV, true
+	case ".parquet":
+		return FormatParquet, true
+	}
+	return "", false
+}
+
+func readCSV(data []byte) (*Table, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	// Ragged rows are padded or cut to the header rather than refused.
+	r.FieldsPerRecord = -1
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return &Table{Format: FormatCSV, Columns: []Column{}, Rows: [][]string{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Table{Format: FormatCSV, Columns: make([]Column, len(header)), Rows: [][]string{}}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make([]string, len(header))
+		copy(row, record)
+		t.Rows = append(t.Rows, row)
+	}
+
+	for i, name := range header {
+		t.Columns[i] = Column{Name: name, Type: inferType(t.Rows, i)}
+	}
+	return t, nil
+}
+
+/*
+inferType returns the narrowest type which fits every value in column i,
+ignoring empty ones. A column with no values is a string.
+*/
+func inferType(rows [][]string, i int) string {
+	integer, float, boolean, seen := true, true, true, false
+	for _, row := range rows {
+		v := row[i]
+		if v == "" {
+			continue
+		}
+		seen = true
+		if integer {
+			_, err := strconv.ParseInt(v, 10, 64)
+			integer = err == nil
+		}
+		if float {
+			_, err := strconv.ParseFloat(v, 64)
+			float = err == nil
+		}
+		if boolean {
+			_, err := strconv.ParseBool(v)
+			// 0 and 1 parse as booleans, but they're integers.
+			boolean = err == nil && v != "0" && v != "1"
+		}
+	}
+
+	switch {
+	case !seen:
+		return "string"
+	case integer:
+		return "integer"
+	case float:
+		return "float"
+	case boolean:
+		return "boolean"
+	}
+	return "string"
+}
+
+func readParquet(data []byte) (*Table, error) {
+	f, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	schema := f.Schema()
+	paths := schema.Columns()
+	t := &Table{Format: FormatParquet, Columns: make([]Column, len(paths)), Rows: [][]string{}}
+	for i, path := range paths {
+		leaf, _ := schema.Lookup(path...)
+		t.Columns[i] = Column{Name: strings.Join(path, "."), Type: parquetType(leaf.Node.Type())}
+	}
+
+	buf := make([]parquet.Row, 128)
+	for _, rg := range f.RowGroups() {
+		rows := rg.Rows()
+		for {
+			n, err := rows.ReadRows(buf)
+			for _, r := range buf[:n] {
+				t.Rows = append(t.Rows, parquetRow(r, len(paths)))
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				rows.Close()
+				return nil, err
+			}
+		}
+		rows.Close()
+	}
+	return t, nil
+}
+
+/*
+parquetRow lays the values of r out by column. Repeated values are joined
+with commas.
+*/
+func parquetRow(r parquet.Row, columns int) []string {
+	row := make([]string, columns)
+	repeated := make([]bool, columns)
+	for _, v := range r {
+		c := v.Column()
+		if c < 0 || c >= columns || v.IsNull() {
+			continue
+		}
+		if repeated[c] {
+			row[c] += ","
+		}
+		row[c] += v.String()
+		repeated[c] = true
+	}
+	return row
+}
+
+func parquetType(t parquet.Type) string {
+	if lt := t.LogicalType(); lt != nil {
+		switch {
+		case lt.UTF8 != nil, lt.Enum != nil, lt.Json != nil, lt.UUID != nil:
+			return "string"
+		case lt.Integer != nil:
+			return "integer"
+		case lt.Decimal != nil:
+			return "decimal"
+		case lt.Date != nil:
+			return "date"
+		case lt.Time != nil:
+			return "time"
+		case lt.Timestamp != nil:
+			return "timestamp"
+		}
+	}
+
+	switch t.Kind() {
+	case parquet.Boolean:
+		return "boolean"
+	case parquet.Int32, parquet.Int64, parquet.Int96:
+		return "integer"
+	case parquet.Float, parquet.Double:
+		return "float"
+	}
+	return "binary"
+}
@@ -0,0 +1,87 @@
+package tabular
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func TestReadCSV(t *testing.T) {
+	data := []byte("\ufeffid,price,city,sold,flag\n1,250000.5,Leeds,true,0\n2,,York,false,1\n3,99000,Hull\n")
+
+	got, err := Read("train.CSV", data)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := &Table{
+		Format: FormatCSV,
+		Columns: []Column{
+			{Name: "id", Type: "integer"},
+			{Name: "price", Type: "float"},
+			{Name: "city", Type: "string"},
+			{Name: "sold", Type: "boolean"},
+			{Name: "flag", Type: "integer"},
+		},
+		Rows: [][]string{
+			{"1", "250000.5", "Leeds", "true", "0"},
+			{"2", "", "York", "false", "1"},
+			{"3", "99000", "Hull", "", ""},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got table %+v, wanted %+v", got, want)
+	}
+}
+
+func TestReadParquet(t *testing.T) {
+	type row struct {
+		ID    int64   `parquet:"id"`
+		Price float64 `parquet:"price"`
+		City  string  `parquet:"city"`
+		Rooms *int32  `parquet:"rooms,optional"`
+	}
+	rooms := int32(3)
+
+	var buf bytes.Buffer
+	if err := parquet.Write(&buf, []row{{1, 250000.5, "Leeds", &rooms}, {2, 99000, "Hull", nil}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Read("train.parquet", buf.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	want := &Table{
+		Format: FormatParquet,
+		Columns: []Column{
+			{Name: "id", Type: "integer"},
+			{Name: "price", Type: "float"},
+			{Name: "city", Type: "string"},
+			{Name: "rooms", Type: "integer"},
+		},
+		Rows: [][]string{
+			{"1", "250000.5", "Leeds", "3"},
+			{"2", "99000", "Hull", ""},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got table %+v, wanted %+v", got, want)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	if _, err := Read("model.pkl", []byte("data")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported for a pickle, got %v", err)
+	}
+	if _, err := Read("train.parquet", []byte("id,price\n")); err == nil {
+		t.Error("expected an error reading a CSV as Parquet")
+	}
+	if Supported("notes.txt") || !Supported("train.csv") || !Supported("train.parquet") {
+		t.Error("Supported should go by extension")
+	}
+}
@@ -1,8 +1,10 @@
 package uploads
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"mime/multipart"
 	"net/http"
@@ -11,8 +13,16 @@ import (
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/heldtogether/traintrack/internal"
+	"github.com/heldtogether/traintrack/internal/audit"
+	"github.com/heldtogether/traintrack/internal/seal"
+	"github.com/heldtogether/traintrack/internal/tabular"
 )
 
+// MaxProfileSize is the largest CSV or Parquet file, in bytes, which is
+// profiled when it's uploaded, as the whole table is read into memory.
+// It's the same bound as diff.MaxTableSize.
+const MaxProfileSize = 64 << 20
+
 /*
 CreateGetter allows an Upload to be created or got from the store.
 */
@@ -29,6 +39,13 @@ type ReadSaver interface {
 	ReadFile(path string) ([]byte, error)
 }
 
+/*
+AuditRecorder records an audit event.
+*/
+type AuditRecorder interface {
+	Record(ctx context.Context, e *audit.Event) error
+}
+
 /*
 UUIDGenerator is a type alias for something that returns unique IDs.
 */
@@ -37,10 +54,12 @@ type UUIDGenerator func() string
 type Handler struct {
 	store   CreateGetter
 	storage ReadSaver
+	scans   *ScanPolicy
+	audit   AuditRecorder
 	newUUID UUIDGenerator
 }
 
-func NewHandler(c CreateGetter, r ReadSaver, uuidGen UUIDGenerator) *Handler {
+func NewHandler(c CreateGetter, r ReadSaver, p *ScanPolicy, a AuditRecorder, uuidGen UUIDGenerator) *Handler {
 	if uuidGen == nil {
 		uuidGen = func() string {
 			return uuid.NewString()
@@ -49,6 +68,8 @@ func NewHandler(c CreateGetter, r ReadSaver, uuidGen UUIDGenerator) *Handler {
 	return &Handler{
 		store:   c,
 		storage: r,
+		scans:   p,
+		audit:   a,
 		newUUID: uuidGen,
 	}
 }
@@ -93,7 +114,12 @@ func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) {
 /*
 Create accepts a multipart form request consisting of one or more files. It
 will store the files in a temporary location on the ReadSaver. We expect
-other handlers to later move the files to their forever home.
+other handlers to later move the files to their forever home. Each file's
+digest is recorded so that it can be sealed into a version later, and each
+file is run past the scan policy. An upload with a file which a
+quarantining scanner didn't pass is quarantined, and can't be attached to
+a dataset or model. CSV and Parquet files are profiled too (see package
+tabular).
 */
 func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	err := r.ParseMultipartForm(32 << 20) // 32MB chunks
@@ -114,6 +140,7 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	basePath := fmt.Sprintf("tmp/uploads/%s/", uploadID)
 
 	fileRefs := make(map[string]FileRef)
+	quarantined := false
 	for artefactName, fileHeaders := range form.File {
 		if len(fileHeaders) == 0 {
 			continue
@@ -135,6 +162,18 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		}
 		defer file.Close()
 
+		digest, size, err := digestFile(file)
+		if err != nil {
+			log.Printf("failed to create upload: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(&internal.Error{
+				Code:    http.StatusInternalServerError,
+				Message: "Failed to create upload",
+				Reason:  err.Error(),
+			})
+			return
+		}
+
 		dst := basePath + fileHeader.Filename
 		err = h.storage.SaveFile(dst, file)
 		if err != nil {
@@ -148,11 +187,31 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		fileRefs[artefactName] = FileRef{
+		ref := FileRef{
 			Provider: ProviderFileSystem,
 			FileName: fileHeader.Filename,
 			Path:     basePath,
+			Digest:   digest,
+			Size:     size,
+		}
+		results, quarantine := h.scans.Scan(r.Context(), &ScanFile{
+			Artefact: artefactName,
+			FileName: fileHeader.Filename,
+			Content:  file,
+			Size:     size,
+		})
+		for _, result := range results {
+			if result.Verdict == ScanFlagged {
+				log.Printf("upload %s: %s was flagged by %s: %v", uploadID, fileHeader.Filename, result.Scanner, result.Findings)
+			}
 		}
+		if quarantine {
+			log.Printf("upload %s: quarantined because of %s", uploadID, fileHeader.Filename)
+			quarantined = true
+		}
+		ref.Scans = results
+		ref.Profile = profileFile(fileHeader.Filename, file, size)
+		fileRefs[artefactName] = ref
 	}
 
 	if len(fileRefs) == 0 {
@@ -167,7 +226,8 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 	}
 
 	upload := &Upload{
-		Files: fileRefs,
+		Files:       fileRefs,
+		Quarantined: quarantined,
 	}
 
 	upload, err = h.store.Create(upload)
@@ -182,6 +242,14 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.audit != nil {
+		e := audit.NewEvent(r.Context(), audit.ActionCreate, audit.ResourceUpload, upload.ID)
+		if err := h.audit.Record(r.Context(), e); err != nil {
+			// The upload is only staged at this point, so don't fail it.
+			log.Printf("failed to record upload: %s", err)
+		}
+	}
+
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(upload)
 }
@@ -189,7 +257,9 @@ func (h *Handler) Create(w http.ResponseWriter, r *http.Request) {
 /*
 Get returns the file `filename` associated with the upload indicated by
 `id` in the URL. The file contents is returned, with the correct
-Content-Disposition header for details like the filename.
+Content-Disposition header for details like the filename. Every download
+is recorded in the audit log. Nothing is handed out from a quarantined
+upload.
 */
 func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -207,6 +277,18 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if upload.Quarantined {
+		err := fmt.Errorf("upload %s: %w", upload.ID, ErrQuarantined)
+		log.Printf("refused to download %s: %s", filename, err)
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(&internal.Error{
+			Code:    http.StatusConflict,
+			Message: "Could not download file",
+			Reason:  err.Error(),
+		})
+		return
+	}
+
 	var filePath string
 	var fileName string
 
@@ -238,7 +320,69 @@ func (h *Handler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.audit != nil {
+		e := audit.NewEvent(r.Context(), audit.ActionDownload, audit.ResourceUpload, upload.ID).
+			WithDetails(map[string]any{
+				"artefact":   filename,
+				"dataset_id": upload.DatasetID,
+				"model_id":   upload.ModelID,
+			})
+		if err := h.audit.Record(r.Context(), e); err != nil {
+			// Downloads must be accounted for, so refuse rather than
+			// hand out a file nobody will know about.
+			log.Printf("failed to record download: %s", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(&internal.Error{
+				Code:    http.StatusInternalServerError,
+				Message: "Could not record download",
+				Reason:  err.Error(),
+			})
+			return
+		}
+	}
+
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
 	w.WriteHeader(http.StatusOK)
 	w.Write(content)
 }
+
+/*
+profileFile summarises a CSV or Parquet file. It returns nil for other
+files, and for those too big or malformed to read as a table, which are
+still accepted.
+*/
+func profileFile(name string, content io.ReaderAt, size int64) *tabular.Profile {
+	if !tabular.Supported(name) {
+		return nil
+	}
+	if size > MaxProfileSize {
+		log.Printf("not profiling %s: %d bytes is over the limit of %d", name, size, MaxProfileSize)
+		return nil
+	}
+
+	data, err := io.ReadAll(io.NewSectionReader(content, 0, size))
+	if err != nil {
+		log.Printf("failed to profile %s: %s", name, err)
+		return nil
+	}
+	t, err := tabular.Read(name, data)
+	if err != nil {
+		log.Printf("failed to profile %s: %s", name, err)
+		return nil
+	}
+	return t.Profile()
+}
+
+/*
+digestFile hashes file and rewinds it, ready to be saved.
+*/
+func digestFile(file multipart.File) (string, int64, error) {
+	digest, size, err := seal.Digest(file)
+	if err != nil {
+		return "", 0, fmt.Errorf("could not hash file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", 0, fmt.Errorf("could not rewind file: %w", err)
+	}
+	return digest, size, nil
+}
@@ -2,6 +2,7 @@ package uploads
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -13,6 +14,7 @@ import (
 	"testing"
 
 	"github.com/gorilla/mux"
+	"github.com/heldtogether/traintrack/internal/audit"
 )
 
 type mockRepo struct {
@@ -34,6 +36,14 @@ func (m *mockRepo) Get(id string) (*Upload, error) {
 	return nil, errors.New("mock: no file available")
 }
 
+type mockRecorder struct {
+	recordFn func(e *audit.Event) error
+}
+
+func (m *mockRecorder) Record(_ context.Context, e *audit.Event) error {
+	return m.recordFn(e)
+}
+
 type mockStorage struct {
 	saveFileFn func(dst string, file multipart.File) error
 	readFileFn func(path string) ([]byte, error)
@@ -75,6 +85,8 @@ func TestUploadsHandler(t *testing.T) {
 		getUploadFn      func(id string) (*Upload, error)
 		saveFileFn       func(dst string, file multipart.File) error
 		readFileFn       func(id string) ([]byte, error)
+		recordFn         func(e *audit.Event) error
+		scanPolicy       *ScanPolicy
 		expectedStatus   int
 		expectedContains string
 		expectRaw        *bool
@@ -94,7 +106,82 @@ func TestUploadsHandler(t *testing.T) {
 				return nil
 			},
 			expectedStatus:   http.StatusCreated,
-			expectedContains: `{"id": "1", "files": {"artefact": {"provider": "filesystem", "filename": "test.txt", "path": "tmp/uploads/mock-id/"}}}`,
+			expectedContains: `{"id": "1", "files": {"artefact": {"provider": "filesystem", "filename": "test.txt", "path": "tmp/uploads/mock-id/", "digest": "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", "size": 5}}}`,
+		},
+		{
+			name:   "POST scans pickles",
+			method: http.MethodPost,
+			requestSetup: func(t *testing.T) *http.Request {
+				req, _ := newMultipartForm(t, "trained_model", "model.pkl", "cos\nsystem\n(S'id'\ntR.")
+				return req
+			},
+			createUploadFn: func(upload *Upload) (*Upload, error) {
+				upload.ID = "1"
+				return upload, nil
+			},
+			saveFileFn: func(dst string, file multipart.File) error {
+				return nil
+			},
+			expectedStatus:   http.StatusCreated,
+			expectedContains: `{"id": "1", "files": {"trained_model": {"provider": "filesystem", "filename": "model.pkl", "path": "tmp/uploads/mock-id/", "digest": "sha256:8cc73ceecc3fb6787129f6639c5171a97fae9256e36d20d1ca7e4916aee299a4", "size": 21, "scans": [{"scanner": "pickle", "verdict": "flagged", "format": "pickle", "findings": ["os.system: can run shell commands and change files"]}]}}}`,
+		},
+		{
+			name:   "POST profiles tables",
+			method: http.MethodPost,
+			requestSetup: func(t *testing.T) *http.Request {
+				req, _ := newMultipartForm(t, "X_train", "X_train.csv", "id,city\n1,Leeds\n2,\n")
+				return req
+			},
+			createUploadFn: func(upload *Upload) (*Upload, error) {
+				upload.ID = "1"
+				return upload, nil
+			},
+			saveFileFn: func(dst string, file multipart.File) error {
+				return nil
+			},
+			expectedStatus: http.StatusCreated,
+			expectedContains: `{"id": "1", "files": {"X_train": {"provider": "filesystem", "filename": "X_train.csv", "path": "tmp/uploads/mock-id/", "digest": "sha256:a9f15f48acf878e660cd224a9ed143fabea74f1b1593474d251f3431d468df54", "size": 19,
+				"profile": {"format": "csv", "rows": 2, "columns": 2, "schema": [
+					{"name": "id", "type": "integer", "nulls": 0, "min": 1, "max": 2, "distinct": 2},
+					{"name": "city", "type": "string", "nulls": 1, "min": "Leeds", "max": "Leeds", "distinct": 1}]}}}}`,
+		},
+		{
+			name:   "POST accepts malformed tables",
+			method: http.MethodPost,
+			requestSetup: func(t *testing.T) *http.Request {
+				req, _ := newMultipartForm(t, "X_train", "X_train.parquet", "hello")
+				return req
+			},
+			createUploadFn: func(upload *Upload) (*Upload, error) {
+				upload.ID = "1"
+				return upload, nil
+			},
+			saveFileFn: func(dst string, file multipart.File) error {
+				return nil
+			},
+			expectedStatus:   http.StatusCreated,
+			expectedContains: `{"id": "1", "files": {"X_train": {"provider": "filesystem", "filename": "X_train.parquet", "path": "tmp/uploads/mock-id/", "digest": "sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", "size": 5}}}`,
+		},
+		{
+			name:   "POST quarantines",
+			method: http.MethodPost,
+			requestSetup: func(t *testing.T) *http.Request {
+				req, _ := newMultipartForm(t, "trained_model", "model.pkl", "cos\nsystem\n(S'id'\ntR.")
+				return req
+			},
+			scanPolicy: &ScanPolicy{Rules: []ScanRule{{Name: "pickle", Scanner: &PickleScanner{}, Quarantine: true}}},
+			createUploadFn: func(upload *Upload) (*Upload, error) {
+				if !upload.Quarantined {
+					return nil, errors.New("expected upload to be quarantined")
+				}
+				upload.ID = "1"
+				return upload, nil
+			},
+			saveFileFn: func(dst string, file multipart.File) error {
+				return nil
+			},
+			expectedStatus:   http.StatusCreated,
+			expectedContains: `{"id": "1", "quarantined": true, "files": {"trained_model": {"provider": "filesystem", "filename": "model.pkl", "path": "tmp/uploads/mock-id/", "digest": "sha256:8cc73ceecc3fb6787129f6639c5171a97fae9256e36d20d1ca7e4916aee299a4", "size": 21, "scans": [{"scanner": "pickle", "verdict": "flagged", "format": "pickle", "findings": ["os.system: can run shell commands and change files"], "quarantine": true}]}}}`,
 		},
 		{
 			name:   "POST failure - parse error",
@@ -162,6 +249,89 @@ func TestUploadsHandler(t *testing.T) {
 			expectedContains: "hello world",
 			expectRaw:        pointerTo(true),
 		},
+		{
+			name:   "GET records download",
+			method: http.MethodGet,
+			requestSetup: func(t *testing.T) *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/uploads/mock-id/artefact", nil)
+				return req
+			},
+			getUploadFn: func(id string) (*Upload, error) {
+				return &Upload{
+					ID: id,
+					Files: map[string]FileRef{
+						"artefact": {
+							FileName: "test.txt",
+							Path:     "mock-id",
+						},
+					},
+				}, nil
+			},
+			readFileFn: func(path string) ([]byte, error) {
+				return []byte("hello world"), nil
+			},
+			recordFn: func(e *audit.Event) error {
+				if e.Action != audit.ActionDownload || e.ResourceID != "mock-id" {
+					return errors.New("unexpected event")
+				}
+				return nil
+			},
+			expectedStatus:   http.StatusOK,
+			expectedContains: "hello world",
+			expectRaw:        pointerTo(true),
+		},
+		{
+			name:   "GET refuses download when it can't be recorded",
+			method: http.MethodGet,
+			requestSetup: func(t *testing.T) *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/uploads/mock-id/artefact", nil)
+				return req
+			},
+			getUploadFn: func(id string) (*Upload, error) {
+				return &Upload{
+					ID: id,
+					Files: map[string]FileRef{
+						"artefact": {
+							FileName: "test.txt",
+							Path:     "mock-id",
+						},
+					},
+				}, nil
+			},
+			readFileFn: func(path string) ([]byte, error) {
+				return []byte("hello world"), nil
+			},
+			recordFn: func(e *audit.Event) error {
+				return errors.New("audit down")
+			},
+			expectedStatus:   http.StatusInternalServerError,
+			expectedContains: `{"code": 500, "error": "Could not record download", "reason": "audit down"}`,
+		},
+		{
+			name:   "GET refuses download from a quarantined upload",
+			method: http.MethodGet,
+			requestSetup: func(t *testing.T) *http.Request {
+				req := httptest.NewRequest(http.MethodGet, "/uploads/mock-id/artefact", nil)
+				return req
+			},
+			getUploadFn: func(id string) (*Upload, error) {
+				return &Upload{
+					ID:          id,
+					Quarantined: true,
+					Files: map[string]FileRef{
+						"artefact": {
+							FileName: "model.pkl",
+							Path:     "mock-id",
+						},
+					},
+				}, nil
+			},
+			readFileFn: func(path string) ([]byte, error) {
+				return nil, errors.New("quarantined file was read")
+			},
+			expectedStatus:   http.StatusConflict,
+			expectedContains: `{"code": 409, "error": "Could not download file", "reason": "upload mock-id: upload is quarantined"}`,
+		},
 		{
 			name:   "GET to unknown upload returns error",
 			method: http.MethodGet,
@@ -270,12 +440,24 @@ func TestUploadsHandler(t *testing.T) {
 
 			storage = &mockStorage{saveFileFn: saveFileFn, readFileFn: readFileFn}
 
+			var recorder AuditRecorder
+			if tc.recordFn != nil {
+				recorder = &mockRecorder{recordFn: tc.recordFn}
+			}
+
+			policy := tc.scanPolicy
+			if policy == nil {
+				policy = DefaultScanPolicy()
+			}
+
 			handler := NewHandler(
 				&mockRepo{
 					createFunc: tc.createUploadFn,
 					getFunc:    tc.getUploadFn,
 				},
 				storage,
+				policy,
+				recorder,
 				func() string {
 					return "mock-id"
 				},
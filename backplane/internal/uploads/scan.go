@@ -0,0 +1,229 @@
+package uploads
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"time"
+)
+
+var ErrQuarantined = errors.New("upload is quarantined")
+
+/*
+ScanVerdict is the outcome of scanning an uploaded file.
+*/
+type ScanVerdict string
+
+const (
+	ScanClean       ScanVerdict = "clean"
+	ScanFlagged     ScanVerdict = "flagged"
+	ScanUnscannable ScanVerdict = "unscannable"
+)
+
+/*
+ScanResult records what a scanner made of a file when it was uploaded.
+*/
+type ScanResult struct {
+	Scanner  string      `json:"scanner"`
+	Verdict  ScanVerdict `json:"verdict"`
+	Format   string      `json:"format,omitempty"`
+	Findings []string    `json:"findings,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	// Quarantine is set when this result quarantined the upload.
+	Quarantine bool `json:"quarantine,omitempty"`
+}
+
+/*
+Blocking reports whether a file with this result shouldn't be trusted:
+either something dangerous was found or the scanner couldn't tell.
+*/
+func (r ScanResult) Blocking() bool {
+	return r.Verdict == ScanFlagged || r.Verdict == ScanUnscannable
+}
+
+/*
+ScanFile is an uploaded file as it's handed to a Scanner.
+*/
+type ScanFile struct {
+	// Artefact is the name the file was uploaded as.
+	Artefact string
+	FileName string
+	Content  io.ReaderAt
+	Size     int64
+}
+
+func (f *ScanFile) reader() io.Reader {
+	return io.NewSectionReader(f.Content, 0, f.Size)
+}
+
+/*
+Scanner inspects uploaded files, such as for unsafe pickles, viruses or
+personal data. It returns a nil result for files it doesn't apply to,
+and an error if it couldn't scan the file.
+*/
+type Scanner interface {
+	Scan(ctx context.Context, f *ScanFile) (*ScanResult, error)
+}
+
+/*
+ScanRule runs a Scanner on the files it matches. Match holds patterns,
+as for path.Match, for the file names and Artefacts for the artefact
+names to scan; either left empty matches everything.
+*/
+type ScanRule struct {
+	Name       string
+	Scanner    Scanner
+	Match      []string
+	Artefacts  []string
+	Quarantine bool
+}
+
+func (r *ScanRule) matches(f *ScanFile) bool {
+	return matchAny(r.Match, f.FileName) && matchAny(r.Artefacts, f.Artefact)
+}
+
+func matchAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+ScanPolicy is the set of rules every uploaded file is scanned against.
+*/
+type ScanPolicy struct {
+	Rules []ScanRule
+}
+
+/*
+DefaultScanPolicy scans pickle and joblib files, without quarantining
+them.
+*/
+func DefaultScanPolicy() *ScanPolicy {
+	return &ScanPolicy{Rules: []ScanRule{{Name: "pickle", Scanner: &PickleScanner{}}}}
+}
+
+/*
+Scan runs every matching rule on f. A scanner which fails gives an
+unscannable result rather than failing the upload. The upload should be
+quarantined if a rule which quarantines gave a blocking result.
+*/
+func (p *ScanPolicy) Scan(ctx context.Context, f *ScanFile) (results []ScanResult, quarantine bool) {
+	if p == nil {
+		return nil, false
+	}
+
+	for _, rule := range p.Rules {
+		if !rule.matches(f) {
+			continue
+		}
+
+		result, err := rule.Scanner.Scan(ctx, f)
+		if err != nil {
+			log.Printf("scanner %s failed on %s: %s", rule.Name, f.FileName, err)
+			result = &ScanResult{Verdict: ScanUnscannable, Error: err.Error()}
+		}
+		if result == nil {
+			continue
+		}
+
+		result.Scanner = rule.Name
+		if rule.Quarantine && result.Blocking() {
+			result.Quarantine = true
+			quarantine = true
+		}
+		results = append(results, *result)
+	}
+	return results, quarantine
+}
+
+/*
+scanRuleConfig is how a rule is written in a scanner config file.
+*/
+type scanRuleConfig struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Command    []string `json:"command"`
+	Socket     string   `json:"socket"`
+	Timeout    string   `json:"timeout"`
+	Match      []string `json:"match"`
+	Artefacts  []string `json:"artefacts"`
+	Quarantine bool     `json:"quarantine"`
+}
+
+/*
+LoadScanPolicy reads a scanner config file, a JSON object holding a list
+of "scanners". Each has a "type": "pickle", "exec", with the "command" to
+run, or "clamd", with the clamd "socket" to use.
+*/
+func LoadScanPolicy(file string) (*ScanPolicy, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var config struct {
+		Scanners []scanRuleConfig `json:"scanners"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid scanner config %s: %w", file, err)
+	}
+
+	p := &ScanPolicy{}
+	for i, c := range config.Scanners {
+		if c.Name == "" {
+			c.Name = c.Type
+		}
+
+		var timeout time.Duration
+		if c.Timeout != "" {
+			if timeout, err = time.ParseDuration(c.Timeout); err != nil {
+				return nil, fmt.Errorf("scanner %d (%s): invalid timeout: %w", i, c.Name, err)
+			}
+		}
+
+		for _, m := range append(c.Match, c.Artefacts...) {
+			if _, err := path.Match(m, ""); err != nil {
+				return nil, fmt.Errorf("scanner %d (%s): invalid pattern %q", i, c.Name, m)
+			}
+		}
+
+		var s Scanner
+		switch c.Type {
+		case "pickle":
+			s = &PickleScanner{}
+		case "exec":
+			if len(c.Command) == 0 {
+				return nil, fmt.Errorf("scanner %d (%s): exec scanners need a command", i, c.Name)
+			}
+			s = &ExecScanner{Command: c.Command, Timeout: timeout}
+		case "clamd":
+			if c.Socket == "" {
+				return nil, fmt.Errorf("scanner %d (%s): clamd scanners need a socket", i, c.Name)
+			}
+			s = &ClamdScanner{Network: "unix", Address: c.Socket, Timeout: timeout}
+		default:
+			return nil, fmt.Errorf("scanner %d (%s): unknown type %q", i, c.Name, c.Type)
+		}
+
+		p.Rules = append(p.Rules, ScanRule{
+			Name:       c.Name,
+			Scanner:    s,
+			Match:      c.Match,
+			Artefacts:  c.Artefacts,
+			Quarantine: c.Quarantine,
+		})
+	}
+	return p, nil
+}
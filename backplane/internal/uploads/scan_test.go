@@ -0,0 +1,200 @@
+package uploads
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockScanner struct {
+	result *ScanResult
+	err    error
+	called int
+}
+
+func (m *mockScanner) Scan(_ context.Context, _ *ScanFile) (*ScanResult, error) {
+	m.called++
+	if m.result == nil {
+		return nil, m.err
+	}
+	r := *m.result
+	return &r, m.err
+}
+
+func newScanFile(artefact, filename, content string) *ScanFile {
+	return &ScanFile{
+		Artefact: artefact,
+		FileName: filename,
+		Content:  strings.NewReader(content),
+		Size:     int64(len(content)),
+	}
+}
+
+func TestScanPolicy_Scan(t *testing.T) {
+	tests := []struct {
+		name               string
+		rule               ScanRule
+		file               *ScanFile
+		expectedResults    []ScanResult
+		expectedQuarantine bool
+	}{
+		{
+			name:            "clean",
+			rule:            ScanRule{Name: "pii", Scanner: &mockScanner{result: &ScanResult{Verdict: ScanClean}}},
+			file:            newScanFile("training_data", "train.csv", "a,b"),
+			expectedResults: []ScanResult{{Scanner: "pii", Verdict: ScanClean}},
+		},
+		{
+			name:            "flagged without quarantine",
+			rule:            ScanRule{Name: "pii", Scanner: &mockScanner{result: &ScanResult{Verdict: ScanFlagged, Findings: []string{"email"}}}},
+			file:            newScanFile("training_data", "train.csv", "a,b"),
+			expectedResults: []ScanResult{{Scanner: "pii", Verdict: ScanFlagged, Findings: []string{"email"}}},
+		},
+		{
+			name:               "flagged with quarantine",
+			rule:               ScanRule{Name: "pii", Scanner: &mockScanner{result: &ScanResult{Verdict: ScanFlagged}}, Quarantine: true},
+			file:               newScanFile("training_data", "train.csv", "a,b"),
+			expectedResults:    []ScanResult{{Scanner: "pii", Verdict: ScanFlagged, Quarantine: true}},
+			expectedQuarantine: true,
+		},
+		{
+			name:            "clean with quarantine",
+			rule:            ScanRule{Name: "pii", Scanner: &mockScanner{result: &ScanResult{Verdict: ScanClean}}, Quarantine: true},
+			file:            newScanFile("training_data", "train.csv", "a,b"),
+			expectedResults: []ScanResult{{Scanner: "pii", Verdict: ScanClean}},
+		},
+		{
+			name:               "scanner fails",
+			rule:               ScanRule{Name: "pii", Scanner: &mockScanner{err: errors.New("boom")}, Quarantine: true},
+			file:               newScanFile("training_data", "train.csv", "a,b"),
+			expectedResults:    []ScanResult{{Scanner: "pii", Verdict: ScanUnscannable, Error: "boom", Quarantine: true}},
+			expectedQuarantine: true,
+		},
+		{
+			name: "scanner doesn't apply",
+			rule: ScanRule{Name: "pii", Scanner: &mockScanner{}},
+			file: newScanFile("training_data", "train.csv", "a,b"),
+		},
+		{
+			name: "file name not matched",
+			rule: ScanRule{Name: "pii", Scanner: &mockScanner{result: &ScanResult{Verdict: ScanFlagged}}, Match: []string{"*.parquet"}},
+			file: newScanFile("training_data", "train.csv", "a,b"),
+		},
+		{
+			name: "artefact not matched",
+			rule: ScanRule{Name: "pii", Scanner: &mockScanner{result: &ScanResult{Verdict: ScanFlagged}}, Artefacts: []string{"training_*"}},
+			file: newScanFile("trained_model", "train.csv", "a,b"),
+		},
+		{
+			name:            "matched",
+			rule:            ScanRule{Name: "pii", Scanner: &mockScanner{result: &ScanResult{Verdict: ScanClean}}, Match: []string{"*.parquet", "*.csv"}, Artefacts: []string{"training_*"}},
+			file:            newScanFile("training_data", "train.csv", "a,b"),
+			expectedResults: []ScanResult{{Scanner: "pii", Verdict: ScanClean}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := &ScanPolicy{Rules: []ScanRule{tc.rule}}
+
+			results, quarantine := p.Scan(context.Background(), tc.file)
+
+			if !reflect.DeepEqual(results, tc.expectedResults) {
+				t.Errorf("results %+v, want %+v", results, tc.expectedResults)
+			}
+			if quarantine != tc.expectedQuarantine {
+				t.Errorf("quarantine %t, want %t", quarantine, tc.expectedQuarantine)
+			}
+		})
+	}
+}
+
+func TestScanPolicy_ScanNil(t *testing.T) {
+	var p *ScanPolicy
+	results, quarantine := p.Scan(context.Background(), newScanFile("a", "b", "c"))
+	if results != nil || quarantine {
+		t.Errorf("expected a nil policy to scan nothing, got %+v, %t", results, quarantine)
+	}
+}
+
+func TestLoadScanPolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        string
+		expected      []ScanRule
+		expectedError string
+	}{
+		{
+			name: "all types",
+			config: `{"scanners": [
+				{"type": "pickle", "quarantine": true},
+				{"name": "pii", "type": "exec", "command": ["pii-scan", "--strict"], "timeout": "30s", "match": ["*.csv"], "artefacts": ["training_*"]},
+				{"name": "av", "type": "clamd", "socket": "/run/clamd.sock"}
+			]}`,
+			expected: []ScanRule{
+				{Name: "pickle", Scanner: &PickleScanner{}, Quarantine: true},
+				{Name: "pii", Scanner: &ExecScanner{Command: []string{"pii-scan", "--strict"}, Timeout: 30 * time.Second}, Match: []string{"*.csv"}, Artefacts: []string{"training_*"}},
+				{Name: "av", Scanner: &ClamdScanner{Network: "unix", Address: "/run/clamd.sock"}},
+			},
+		},
+		{
+			name:          "invalid json",
+			config:        `{"scanners": [`,
+			expectedError: "invalid scanner config",
+		},
+		{
+			name:          "unknown type",
+			config:        `{"scanners": [{"name": "x", "type": "magic"}]}`,
+			expectedError: `scanner 0 (x): unknown type "magic"`,
+		},
+		{
+			name:          "exec without command",
+			config:        `{"scanners": [{"type": "exec"}]}`,
+			expectedError: "scanner 0 (exec): exec scanners need a command",
+		},
+		{
+			name:          "clamd without socket",
+			config:        `{"scanners": [{"type": "clamd"}]}`,
+			expectedError: "scanner 0 (clamd): clamd scanners need a socket",
+		},
+		{
+			name:          "invalid timeout",
+			config:        `{"scanners": [{"type": "exec", "command": ["x"], "timeout": "soon"}]}`,
+			expectedError: "scanner 0 (exec): invalid timeout",
+		},
+		{
+			name:          "invalid pattern",
+			config:        `{"scanners": [{"type": "pickle", "match": ["[a"]}]}`,
+			expectedError: `scanner 0 (pickle): invalid pattern "[a"`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "scanners.json")
+			if err := os.WriteFile(file, []byte(tc.config), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			p, err := LoadScanPolicy(file)
+
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(p.Rules, tc.expected) {
+				t.Errorf("rules %+v, want %+v", p.Rules, tc.expected)
+			}
+		})
+	}
+}
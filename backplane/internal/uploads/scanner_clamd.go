@@ -0,0 +1,103 @@
+package uploads
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+/*
+clamdChunkSize is how much of a file is sent to clamd at a time; it must
+be below clamd's StreamMaxLength.
+*/
+const clamdChunkSize = 64 * 1024
+
+/*
+ClamdScanner sends each file to a ClamAV daemon, usually listening on a
+local Unix socket, with its INSTREAM command.
+*/
+type ClamdScanner struct {
+	Network string
+	Address string
+	Timeout time.Duration
+}
+
+func (s *ClamdScanner) Scan(ctx context.Context, f *ScanFile) (*ScanResult, error) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = defaultScanTimeout
+	}
+
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, s.Network, s.Address)
+	if err != nil {
+		return nil, fmt.Errorf("could not reach clamd: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if err := s.stream(conn, f.reader()); err != nil {
+		return nil, fmt.Errorf("could not send file to clamd: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return nil, fmt.Errorf("no reply from clamd: %w", err)
+	}
+	return parseClamdReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+func (s *ClamdScanner) stream(w io.Writer, r io.Reader) error {
+	if _, err := io.WriteString(w, "zINSTREAM\x00"); err != nil {
+		return err
+	}
+
+	buf := make([]byte, clamdChunkSize)
+	var size [4]byte
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			if _, err := w.Write(append(size[:], buf[:n]...)); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// A zero length chunk ends the stream.
+	_, err := w.Write([]byte{0, 0, 0, 0})
+	return err
+}
+
+/*
+parseClamdReply reads clamd's "stream: OK", "stream: <signature> FOUND" or
+"<reason> ERROR" reply.
+*/
+func parseClamdReply(reply string) (*ScanResult, error) {
+	reply = strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case reply == "OK":
+		return &ScanResult{Verdict: ScanClean}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return &ScanResult{
+			Verdict:  ScanFlagged,
+			Findings: []string{strings.TrimSuffix(reply, " FOUND")},
+		}, nil
+	case strings.HasSuffix(reply, " ERROR"):
+		return nil, fmt.Errorf("clamd: %s", strings.TrimSuffix(reply, " ERROR"))
+	default:
+		return nil, fmt.Errorf("unexpected reply from clamd: %q", reply)
+	}
+}
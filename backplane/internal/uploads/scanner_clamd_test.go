@@ -0,0 +1,126 @@
+package uploads
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+/*
+fakeClamd answers INSTREAM commands on a Unix socket, replying with
+reply(content) once the whole stream has been read.
+*/
+func fakeClamd(t *testing.T, reply func(content []byte) string) string {
+	t.Helper()
+	socket := filepath.Join(t.TempDir(), "clamd.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				if cmd, err := r.ReadString(0); err != nil || cmd != "zINSTREAM\x00" {
+					io.WriteString(conn, "UNKNOWN COMMAND\x00")
+					return
+				}
+				var content bytes.Buffer
+				for {
+					var size uint32
+					if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					if _, err := io.CopyN(&content, r, int64(size)); err != nil {
+						return
+					}
+				}
+				io.WriteString(conn, reply(content.Bytes())+"\x00")
+			}()
+		}
+	}()
+	return socket
+}
+
+func TestClamdScanner(t *testing.T) {
+	socket := fakeClamd(t, func(content []byte) string {
+		switch {
+		case bytes.Contains(content, []byte("EICAR")):
+			return "stream: Eicar-Test-Signature FOUND"
+		case len(content) > 100*1024:
+			return "INSTREAM size limit exceeded. ERROR"
+		default:
+			return "stream: OK"
+		}
+	})
+
+	tests := []struct {
+		name          string
+		content       string
+		expected      *ScanResult
+		expectedError string
+	}{
+		{
+			name:     "clean",
+			content:  "a,b\n1,2\n",
+			expected: &ScanResult{Verdict: ScanClean},
+		},
+		{
+			name:     "flagged across chunks",
+			content:  strings.Repeat("x", clamdChunkSize-2) + "EICAR",
+			expected: &ScanResult{Verdict: ScanFlagged, Findings: []string{"Eicar-Test-Signature"}},
+		},
+		{
+			name:          "error",
+			content:       strings.Repeat("x", 200*1024),
+			expectedError: "clamd: INSTREAM size limit exceeded.",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &ClamdScanner{Network: "unix", Address: socket}
+
+			result, err := s.Scan(context.Background(), newScanFile("training_data", "train.csv", tc.content))
+
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("result %+v, want %+v", result, tc.expected)
+			}
+		})
+	}
+}
+
+func TestClamdScanner_Unreachable(t *testing.T) {
+	s := &ClamdScanner{Network: "unix", Address: filepath.Join(t.TempDir(), "missing.sock")}
+
+	_, err := s.Scan(context.Background(), newScanFile("training_data", "train.csv", "a"))
+	if err == nil || !strings.Contains(err.Error(), "could not reach clamd") {
+		t.Fatalf("expected unreachable error, got %v", err)
+	}
+}
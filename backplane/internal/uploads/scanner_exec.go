@@ -0,0 +1,78 @@
+package uploads
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultScanTimeout = time.Minute
+
+/*
+ExecScanner runs a local program on each file, such as a PII detector or
+licence checker. The file is written to its stdin, with its name, artefact
+name and size in the TRAINTRACK_SCAN_FILENAME, TRAINTRACK_SCAN_ARTEFACT
+and TRAINTRACK_SCAN_SIZE environment variables.
+
+As with clamscan, exiting 0 means the file is clean and 1 that it was
+flagged, with each line the program printed a finding. Any other exit is
+a failure to scan.
+*/
+type ExecScanner struct {
+	Command []string
+	Timeout time.Duration
+}
+
+func (s *ExecScanner) Scan(ctx context.Context, f *ScanFile) (*ScanResult, error) {
+	timeout := s.Timeout
+	if timeout <= 0 {
+		timeout = defaultScanTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, s.Command[0], s.Command[1:]...)
+	cmd.Stdin = f.reader()
+	cmd.Env = append(os.Environ(),
+		"TRAINTRACK_SCAN_FILENAME="+f.FileName,
+		"TRAINTRACK_SCAN_ARTEFACT="+f.Artefact,
+		"TRAINTRACK_SCAN_SIZE="+strconv.FormatInt(f.Size, 10),
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return &ScanResult{Verdict: ScanClean}, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		return &ScanResult{Verdict: ScanFlagged, Findings: lines(stdout.String())}, nil
+	case ctx.Err() != nil:
+		return nil, fmt.Errorf("timed out after %s", timeout)
+	default:
+		if reason := strings.TrimSpace(stderr.String()); reason != "" {
+			return nil, fmt.Errorf("%w: %s", err, reason)
+		}
+		return nil, err
+	}
+}
+
+func lines(s string) []string {
+	var out []string
+	for _, l := range strings.Split(s, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			out = append(out, l)
+		}
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package uploads
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	script := filepath.Join(t.TempDir(), "scan.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"+body), 0700); err != nil {
+		t.Fatal(err)
+	}
+	return script
+}
+
+func TestExecScanner(t *testing.T) {
+	tests := []struct {
+		name          string
+		script        string
+		timeout       time.Duration
+		expected      *ScanResult
+		expectedError string
+	}{
+		{
+			name:     "clean",
+			script:   "cat > /dev/null\nexit 0\n",
+			expected: &ScanResult{Verdict: ScanClean},
+		},
+		{
+			name: "flagged",
+			script: `grep -q "@" && echo "email address in $TRAINTRACK_SCAN_ARTEFACT/$TRAINTRACK_SCAN_FILENAME ($TRAINTRACK_SCAN_SIZE bytes)"
+echo
+echo "second finding"
+exit 1
+`,
+			expected: &ScanResult{Verdict: ScanFlagged, Findings: []string{"email address in training_data/train.csv (19 bytes)", "second finding"}},
+		},
+		{
+			name:          "fails",
+			script:        "echo 'licence db missing' >&2\nexit 2\n",
+			expectedError: "exit status 2: licence db missing",
+		},
+		{
+			name:          "times out",
+			script:        "exec sleep 5\n",
+			timeout:       100 * time.Millisecond,
+			expectedError: "timed out after 100ms",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &ExecScanner{Command: []string{writeScript(t, tc.script)}, Timeout: tc.timeout}
+
+			result, err := s.Scan(context.Background(), newScanFile("training_data", "train.csv", "name,email\na,a@b.c\n"))
+
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Fatalf("expected error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Errorf("result %+v, want %+v", result, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package uploads
+
+import (
+	"context"
+
+	"github.com/heldtogether/traintrack/internal/picklescan"
+)
+
+/*
+PickleScanner statically scans pickle and joblib files for code which
+would run when they're unpickled. See package picklescan.
+*/
+type PickleScanner struct{}
+
+func (s *PickleScanner) Scan(ctx context.Context, f *ScanFile) (*ScanResult, error) {
+	head := make([]byte, 2)
+	n, _ := f.Content.ReadAt(head, 0)
+	if !picklescan.Applies(f.FileName, head[:n]) {
+		return nil, nil
+	}
+
+	report := picklescan.Scan(f.Content, f.Size)
+	result := &ScanResult{
+		Verdict: ScanVerdict(report.Verdict()),
+		Format:  report.Format,
+	}
+	for _, finding := range report.Findings {
+		result.Findings = append(result.Findings, finding.String())
+	}
+	if report.Err != nil {
+		result.Error = report.Err.Error()
+	}
+	return result, nil
+}
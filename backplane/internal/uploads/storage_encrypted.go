@@ -0,0 +1,364 @@
+package uploads
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+
+	"github.com/heldtogether/traintrack/internal/kms"
+)
+
+const (
+	encryptionMagic     = "TTENC1\n"
+	encryptionAlgorithm = "AES-256-GCM-STREAM"
+
+	// DefaultChunkSize is how much plain text is sealed at a time.
+	DefaultChunkSize = 64 * 1024
+
+	noncePrefixSize = 7
+	maxHeaderSize   = 64 * 1024
+)
+
+var ErrCorruptArtefact = errors.New("encrypted artefact is corrupt")
+
+/*
+EncryptedStore is a FileSystemStore which encrypts artefacts at rest. It
+implements ReadSaver.
+
+Every file gets its own data key, which is wrapped by the key provider's
+current master key and kept in a header at the start of the file. The
+plain text follows as a stream of AES-GCM sealed chunks, so a file can be
+written without holding it in memory and can't be truncated, reordered or
+altered without ReadFile noticing.
+
+Files written before encryption was turned on have no header and are read
+as they are. Rewrap encrypts them, and re-wraps data keys after the master
+key has been rotated, without changing what ReadFile returns.
+*/
+type EncryptedStore struct {
+	*FileSystemStore
+	Keys      kms.KeyProvider
+	ChunkSize int
+}
+
+type encryptionHeader struct {
+	Algorithm   string `json:"alg"`
+	KeyID       string `json:"key_id"`
+	WrappedKey  []byte `json:"wrapped_key"`
+	NoncePrefix []byte `json:"nonce_prefix"`
+	ChunkSize   int    `json:"chunk_size"`
+}
+
+/*
+RewrapResult says what Rewrap did to a file.
+*/
+type RewrapResult string
+
+const (
+	RewrapSkipped   RewrapResult = "current"
+	RewrapRewrapped RewrapResult = "rewrapped"
+	RewrapEncrypted RewrapResult = "encrypted"
+)
+
+func (e *EncryptedStore) SaveFile(dstPath string, file multipart.File) error {
+	return e.save(dstPath, file)
+}
+
+func (e *EncryptedStore) save(dstPath string, r io.Reader) error {
+	header, dataKey, err := e.newHeader()
+	if err != nil {
+		return err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(encrypt(pw, r, header, dataKey))
+	}()
+
+	err = e.FileSystemStore.save(dstPath, pr)
+	pr.Close()
+	return err
+}
+
+func (e *EncryptedStore) ReadFile(path string) ([]byte, error) {
+	data, err := e.FileSystemStore.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	header, body, err := parseHeader(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if header == nil {
+		return data, nil
+	}
+
+	dataKey, err := e.Keys.Unwrap(header.KeyID, header.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	plaintext, err := decrypt(body, header, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return plaintext, nil
+}
+
+/*
+Rewrap makes sure the file at path is encrypted under the current master
+key. Encrypted files only have their header rewritten; the data key and
+cipher text stay the same.
+*/
+func (e *EncryptedStore) Rewrap(path string) (RewrapResult, error) {
+	data, err := e.FileSystemStore.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	header, body, err := parseHeader(data)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+
+	if header == nil {
+		h, dataKey, err := e.newHeader()
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		if err := encrypt(&buf, bytes.NewReader(data), h, dataKey); err != nil {
+			return "", err
+		}
+		return RewrapEncrypted, e.replace(path, &buf)
+	}
+
+	if header.KeyID == e.Keys.CurrentKeyID() {
+		return RewrapSkipped, nil
+	}
+
+	dataKey, err := e.Keys.Unwrap(header.KeyID, header.WrappedKey)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", path, err)
+	}
+	if header.KeyID, header.WrappedKey, err = e.Keys.Wrap(dataKey); err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := writeHeader(&buf, header); err != nil {
+		return "", err
+	}
+	return RewrapRewrapped, e.replace(path, io.MultiReader(&buf, bytes.NewReader(body)))
+}
+
+/*
+RewrapAll calls Rewrap on every file in the store, reporting each result
+to fn. It carries on past failures and returns the first one.
+*/
+func (e *EncryptedStore) RewrapAll(fn func(path string, result RewrapResult, err error)) error {
+	var first error
+	walkErr := filepath.WalkDir(e.BaseDir, func(full string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+
+		path, err := filepath.Rel(e.BaseDir, full)
+		if err != nil {
+			return err
+		}
+
+		result, err := e.Rewrap(path)
+		if err != nil && first == nil {
+			first = err
+		}
+		fn(path, result, err)
+		return nil
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+	return first
+}
+
+func (e *EncryptedStore) newHeader() (*encryptionHeader, []byte, error) {
+	dataKey := make([]byte, kms.DataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, err
+	}
+
+	keyID, wrapped, err := e.Keys.Wrap(dataKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not wrap data key: %w", err)
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, nil, err
+	}
+
+	chunkSize := e.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	return &encryptionHeader{
+		Algorithm:   encryptionAlgorithm,
+		KeyID:       keyID,
+		WrappedKey:  wrapped,
+		NoncePrefix: prefix,
+		ChunkSize:   chunkSize,
+	}, dataKey, nil
+}
+
+func writeHeader(w io.Writer, h *encryptionHeader) error {
+	body, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+
+	buf := make([]byte, 0, len(encryptionMagic)+4+len(body))
+	buf = append(buf, encryptionMagic...)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(body)))
+	buf = append(buf, body...)
+
+	_, err = w.Write(buf)
+	return err
+}
+
+/*
+parseHeader splits an encrypted file into its header and cipher text. It
+returns a nil header for files which aren't encrypted.
+*/
+func parseHeader(data []byte) (*encryptionHeader, []byte, error) {
+	rest, ok := bytes.CutPrefix(data, []byte(encryptionMagic))
+	if !ok {
+		return nil, data, nil
+	}
+	if len(rest) < 4 {
+		return nil, nil, ErrCorruptArtefact
+	}
+
+	n := binary.BigEndian.Uint32(rest)
+	rest = rest[4:]
+	if n > maxHeaderSize || int(n) > len(rest) {
+		return nil, nil, ErrCorruptArtefact
+	}
+
+	var h encryptionHeader
+	if err := json.Unmarshal(rest[:n], &h); err != nil {
+		return nil, nil, fmt.Errorf("%w: %s", ErrCorruptArtefact, err)
+	}
+	if h.Algorithm != encryptionAlgorithm {
+		return nil, nil, fmt.Errorf("unsupported encryption %q", h.Algorithm)
+	}
+	if h.ChunkSize <= 0 || len(h.NoncePrefix) != noncePrefixSize {
+		return nil, nil, ErrCorruptArtefact
+	}
+
+	return &h, rest[n:], nil
+}
+
+/*
+encrypt writes the header followed by the plain text read from r, sealed
+in chunks. Each chunk's nonce is the header's prefix, the chunk's index
+and a flag marking the last chunk, so chunks can't be dropped or moved.
+*/
+func encrypt(w io.Writer, r io.Reader, h *encryptionHeader, dataKey []byte) error {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return err
+	}
+	if err := writeHeader(w, h); err != nil {
+		return err
+	}
+
+	br := bufio.NewReaderSize(r, h.ChunkSize)
+	chunk := make([]byte, h.ChunkSize)
+	var sealed []byte
+
+	for i := uint32(0); ; i++ {
+		n, err := io.ReadFull(br, chunk)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			return err
+		}
+
+		last := n < h.ChunkSize
+		if !last {
+			if _, err := br.Peek(1); err == io.EOF {
+				last = true
+			} else if err != nil {
+				return err
+			}
+		}
+
+		sealed = aead.Seal(sealed[:0], chunkNonce(h.NoncePrefix, i, last), chunk[:n], nil)
+		if _, err := w.Write(sealed); err != nil {
+			return err
+		}
+		if last {
+			return nil
+		}
+		if i == ^uint32(0) {
+			return errors.New("artefact is too large to encrypt")
+		}
+	}
+}
+
+func decrypt(body []byte, h *encryptionHeader, dataKey []byte) ([]byte, error) {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	size := h.ChunkSize + aead.Overhead()
+	plaintext := make([]byte, 0, len(body))
+
+	for i := uint32(0); ; i++ {
+		n := min(size, len(body))
+		last := n == len(body)
+
+		plaintext, err = aead.Open(plaintext, chunkNonce(h.NoncePrefix, i, last), body[:n], nil)
+		if err != nil {
+			return nil, ErrCorruptArtefact
+		}
+		body = body[n:]
+
+		if last {
+			return plaintext, nil
+		}
+	}
+}
+
+func chunkNonce(prefix []byte, i uint32, last bool) []byte {
+	nonce := make([]byte, 0, 12)
+	nonce = append(nonce, prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, i)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,196 @@
+package uploads
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/heldtogether/traintrack/internal/kms"
+)
+
+func newKeyring(t *testing.T, files ...string) *kms.Keyring {
+	t.Helper()
+
+	keys, err := kms.LoadKeyring(files...)
+	if err != nil {
+		t.Fatalf("LoadKeyring failed: %v", err)
+	}
+	return keys
+}
+
+func newKeyFile(t *testing.T) string {
+	t.Helper()
+
+	key, _ := kms.GenerateKey()
+	file := filepath.Join(t.TempDir(), "master.key")
+	if err := os.WriteFile(file, key, 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return file
+}
+
+func TestEncryptedStore_RoundTrip(t *testing.T) {
+	keys := newKeyring(t, newKeyFile(t))
+
+	tests := []struct {
+		name string
+		size int
+	}{
+		{name: "empty", size: 0},
+		{name: "less than a chunk", size: 10},
+		{name: "exactly one chunk", size: 64},
+		{name: "several chunks", size: 64*3 + 5},
+		{name: "whole chunks", size: 64 * 4},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store := &EncryptedStore{FileSystemStore: &FileSystemStore{BaseDir: dir}, Keys: keys, ChunkSize: 64}
+
+			content := make([]byte, tc.size)
+			rand.Read(content)
+
+			if err := store.SaveFile("a/file.bin", newMockMultipartFile(content)); err != nil {
+				t.Fatalf("SaveFile failed: %v", err)
+			}
+
+			raw, _ := os.ReadFile(filepath.Join(dir, "a/file.bin"))
+			if tc.size > 0 && bytes.Contains(raw, content) {
+				t.Error("artefact was stored in plain text")
+			}
+
+			got, err := store.ReadFile("a/file.bin")
+			if err != nil {
+				t.Fatalf("ReadFile failed: %v", err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("read back %d bytes which differ from the %d saved", len(got), len(content))
+			}
+		})
+	}
+}
+
+func TestEncryptedStore_MoveFile(t *testing.T) {
+	store := &EncryptedStore{FileSystemStore: &FileSystemStore{BaseDir: t.TempDir()}, Keys: newKeyring(t, newKeyFile(t))}
+
+	store.SaveFile("uploads/1", newMockMultipartFile([]byte("model weights")))
+	if err := store.MoveFile("uploads/1", "models/1/weights.bin"); err != nil {
+		t.Fatalf("MoveFile failed: %v", err)
+	}
+
+	got, err := store.ReadFile("models/1/weights.bin")
+	if err != nil || string(got) != "model weights" {
+		t.Errorf("got %q, %v", got, err)
+	}
+}
+
+func TestEncryptedStore_ReadsPlainText(t *testing.T) {
+	fs := &FileSystemStore{BaseDir: t.TempDir()}
+	fs.SaveFile("legacy.csv", newMockMultipartFile([]byte("a,b\n1,2\n")))
+
+	store := &EncryptedStore{FileSystemStore: fs, Keys: newKeyring(t, newKeyFile(t))}
+	got, err := store.ReadFile("legacy.csv")
+	if err != nil || string(got) != "a,b\n1,2\n" {
+		t.Errorf("got %q, %v", got, err)
+	}
+}
+
+func TestEncryptedStore_DetectsTampering(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 200)
+
+	tests := []struct {
+		name   string
+		tamper func(raw []byte) []byte
+	}{
+		{name: "flipped bit", tamper: func(raw []byte) []byte {
+			raw[len(raw)-20] ^= 1
+			return raw
+		}},
+		{name: "truncated at a chunk", tamper: func(raw []byte) []byte {
+			return raw[:len(raw)-(200-3*64)-16]
+		}},
+		{name: "truncated header", tamper: func(raw []byte) []byte {
+			return raw[:len(encryptionMagic)+2]
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			store := &EncryptedStore{FileSystemStore: &FileSystemStore{BaseDir: dir}, Keys: newKeyring(t, newKeyFile(t)), ChunkSize: 64}
+			store.SaveFile("f", newMockMultipartFile(content))
+
+			full := filepath.Join(dir, "f")
+			raw, _ := os.ReadFile(full)
+			os.Chmod(full, 0644)
+			os.WriteFile(full, tc.tamper(raw), 0644)
+
+			if _, err := store.ReadFile("f"); !errors.Is(err, ErrCorruptArtefact) {
+				t.Errorf("expected ErrCorruptArtefact, got %v", err)
+			}
+		})
+	}
+}
+
+func TestEncryptedStore_Rewrap(t *testing.T) {
+	dir := t.TempDir()
+	oldKey, newKey := newKeyFile(t), newKeyFile(t)
+
+	fs := &FileSystemStore{BaseDir: dir}
+	fs.SaveFile("legacy.csv", newMockMultipartFile([]byte("plain")))
+
+	before := &EncryptedStore{FileSystemStore: fs, Keys: newKeyring(t, oldKey)}
+	before.SaveFile("models/1/weights.bin", newMockMultipartFile([]byte("weights")))
+	cipherBefore, _ := os.ReadFile(filepath.Join(dir, "models/1/weights.bin"))
+
+	after := &EncryptedStore{FileSystemStore: fs, Keys: newKeyring(t, newKey, oldKey)}
+	after.SaveFile("models/2/weights.bin", newMockMultipartFile([]byte("current")))
+
+	results := map[string]RewrapResult{}
+	err := after.RewrapAll(func(path string, result RewrapResult, err error) {
+		if err != nil {
+			t.Errorf("rewrap %s failed: %v", path, err)
+		}
+		results[path] = result
+	})
+	if err != nil {
+		t.Fatalf("RewrapAll failed: %v", err)
+	}
+
+	expected := map[string]RewrapResult{
+		"legacy.csv":           RewrapEncrypted,
+		"models/1/weights.bin": RewrapRewrapped,
+		"models/2/weights.bin": RewrapSkipped,
+	}
+	for path, want := range expected {
+		if results[path] != want {
+			t.Errorf("%s: got %q, want %q", path, results[path], want)
+		}
+	}
+
+	// The old master key can now be retired.
+	retired := &EncryptedStore{FileSystemStore: fs, Keys: newKeyring(t, newKey)}
+	for path, want := range map[string]string{"legacy.csv": "plain", "models/1/weights.bin": "weights", "models/2/weights.bin": "current"} {
+		got, err := retired.ReadFile(path)
+		if err != nil || string(got) != want {
+			t.Errorf("%s: got %q, %v", path, got, err)
+		}
+	}
+
+	// Only the header changes; the cipher text is untouched.
+	cipherAfter, _ := os.ReadFile(filepath.Join(dir, "models/1/weights.bin"))
+	_, bodyBefore, _ := parseHeader(cipherBefore)
+	_, bodyAfter, _ := parseHeader(cipherAfter)
+	if !bytes.Equal(bodyBefore, bodyAfter) {
+		t.Error("rewrap changed the cipher text")
+	}
+
+	info, _ := os.Stat(filepath.Join(dir, "models/1/weights.bin"))
+	if info.Mode().Perm() != 0444 {
+		t.Errorf("rewrapped file has mode %v, want read-only", info.Mode().Perm())
+	}
+}
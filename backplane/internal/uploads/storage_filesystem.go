@@ -1,34 +1,54 @@
 package uploads
 
 import (
+	"fmt"
 	"io"
 	"mime/multipart"
 	"os"
 	"path/filepath"
 )
 
+/*
+FileStore is what the backplane needs from artefact storage.
+*/
+type FileStore interface {
+	ReadSaver
+	MoveFile(srcPath string, dstPath string) error
+	RemoveFile(path string) error
+}
+
 /*
 FileSystemStore is a local file system storage provider. It implements ReadSaver.
+
+Artefacts are immutable: files are written read-only and neither SaveFile
+nor MoveFile will replace a file which already exists.
 */
 type FileSystemStore struct {
 	BaseDir string
 }
 
 func (f *FileSystemStore) SaveFile(dstPath string, file multipart.File) error {
+	return f.save(dstPath, file)
+}
+
+func (f *FileSystemStore) save(dstPath string, r io.Reader) error {
 	fullPath := filepath.Join(f.BaseDir, dstPath)
 
 	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
 		return err
 	}
 
-	dst, err := os.Create(fullPath)
+	dst, err := os.OpenFile(fullPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0444)
 	if err != nil {
 		return err
 	}
-	defer dst.Close()
 
-	_, err = io.Copy(dst, file)
-	return err
+	if _, err := io.Copy(dst, r); err != nil {
+		dst.Close()
+		os.Remove(fullPath)
+		return err
+	}
+	return dst.Close()
 }
 
 func (f *FileSystemStore) MoveFile(srcPath string, dstPath string) error {
@@ -39,10 +59,62 @@ func (f *FileSystemStore) MoveFile(srcPath string, dstPath string) error {
 		return err
 	}
 
-	return os.Rename(fullSrcPath, fullDstPath)
+	// Link fails if the destination exists, where Rename would silently
+	// replace it.
+	if err := os.Link(fullSrcPath, fullDstPath); err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("will not overwrite %s: %w", dstPath, err)
+		}
+		return err
+	}
+	return os.Remove(fullSrcPath)
 }
 
 func (f *FileSystemStore) ReadFile(path string) ([]byte, error) {
 	fullPath := filepath.Join(f.BaseDir, path)
 	return os.ReadFile(fullPath)
 }
+
+/*
+RemoveFile permanently removes a file, along with its directory if that
+leaves it empty. Removing a file which doesn't exist isn't an error, so a
+purge which failed part way can be retried.
+*/
+func (f *FileSystemStore) RemoveFile(path string) error {
+	fullPath := filepath.Join(f.BaseDir, path)
+
+	if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	// Fails harmlessly if other files are still there.
+	os.Remove(filepath.Dir(fullPath))
+	return nil
+}
+
+/*
+replace atomically swaps the contents of an existing file for what is read
+from r. It is only for re-encoding a file; its plain text must not change.
+*/
+func (f *FileSystemStore) replace(path string, r io.Reader) error {
+	fullPath := filepath.Join(f.BaseDir, path)
+
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), "."+filepath.Base(fullPath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0444); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fullPath)
+}
@@ -128,3 +128,87 @@ func TestFileSystemStorage_MoveFile_FileCreationFails(t *testing.T) {
 		t.Fatal("expected file creation error due to read-only directory, got nil")
 	}
 }
+
+func TestFileSystemStorage_SaveFile_ReadOnly(t *testing.T) {
+	tmpDir := t.TempDir()
+	storage := &FileSystemStore{BaseDir: tmpDir}
+
+	if err := storage.SaveFile("file.txt", newMockMultipartFile([]byte("v1"))); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+
+	info, err := os.Stat(filepath.Join(tmpDir, "file.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm()&0222 != 0 {
+		t.Errorf("expected file to be read-only, got %s", info.Mode().Perm())
+	}
+}
+
+func TestFileSystemStorage_SaveFile_RefusesOverwrite(t *testing.T) {
+	tmpDir := t.TempDir()
+	storage := &FileSystemStore{BaseDir: tmpDir}
+
+	if err := storage.SaveFile("file.txt", newMockMultipartFile([]byte("v1"))); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+	if err := storage.SaveFile("file.txt", newMockMultipartFile([]byte("v2"))); err == nil {
+		t.Fatal("expected overwrite to be refused, got nil")
+	}
+
+	data, _ := storage.ReadFile("file.txt")
+	if string(data) != "v1" {
+		t.Errorf("file was modified: got %q", string(data))
+	}
+}
+
+func TestFileSystemStorage_MoveFile_RefusesOverwrite(t *testing.T) {
+	tmpDir := t.TempDir()
+	storage := &FileSystemStore{BaseDir: tmpDir}
+
+	if err := storage.SaveFile("src.txt", newMockMultipartFile([]byte("new"))); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+	if err := storage.SaveFile("dst.txt", newMockMultipartFile([]byte("sealed"))); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+
+	if err := storage.MoveFile("src.txt", "dst.txt"); err == nil {
+		t.Fatal("expected overwrite to be refused, got nil")
+	}
+
+	data, _ := storage.ReadFile("dst.txt")
+	if string(data) != "sealed" {
+		t.Errorf("file was modified: got %q", string(data))
+	}
+}
+
+func TestFileSystemStorage_RemoveFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	storage := &FileSystemStore{BaseDir: tmpDir}
+
+	for _, p := range []string{"datasets/1/a.csv", "datasets/1/b.csv"} {
+		if err := storage.SaveFile(p, newMockMultipartFile([]byte("x"))); err != nil {
+			t.Fatalf("SaveFile failed: %v", err)
+		}
+	}
+
+	if err := storage.RemoveFile("datasets/1/a.csv"); err != nil {
+		t.Fatalf("RemoveFile failed: %v", err)
+	}
+	if _, err := storage.ReadFile("datasets/1/b.csv"); err != nil {
+		t.Errorf("expected the other file to be kept: %v", err)
+	}
+
+	if err := storage.RemoveFile("datasets/1/b.csv"); err != nil {
+		t.Fatalf("RemoveFile failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "datasets/1")); !os.IsNotExist(err) {
+		t.Errorf("expected the empty directory to be removed: %v", err)
+	}
+
+	if err := storage.RemoveFile("datasets/1/b.csv"); err != nil {
+		t.Errorf("expected removing a missing file to succeed, got %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 
+	"github.com/heldtogether/traintrack/internal/tabular"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 )
@@ -14,6 +15,9 @@ type Upload struct {
 	Files     map[string]FileRef `json:"files"`
 	DatasetID *string            `json:"dataset_id,omitempty"`
 	ModelID   *string            `json:"model_id,omitempty"`
+	// Quarantined uploads failed a scan which quarantines and can't be
+	// attached to a dataset or model.
+	Quarantined bool `json:"quarantined,omitempty"`
 }
 
 /*
@@ -30,12 +34,22 @@ type FileRef struct {
 	Provider Provider `json:"provider"`
 	FileName string   `json:"filename"`
 	Path     string   `json:"path"`
+	// Digest is the hash of the file's contents as it was uploaded, in
+	// the form "sha256:<hex>", and Size its length in bytes.
+	Digest string `json:"digest,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+	// Scans are the results of scanning the file when it was uploaded.
+	// They aren't covered by any seal.
+	Scans []ScanResult `json:"scans,omitempty"`
+	// Profile summarises a CSV or Parquet file, worked out when it was
+	// uploaded. Like Scans, it isn't covered by any seal.
+	Profile *tabular.Profile `json:"profile,omitempty"`
 }
 
 const (
-	createQuery = `INSERT INTO uploads (files) VALUES ($1) RETURNING id`
+	createQuery = `INSERT INTO uploads (files, quarantined) VALUES ($1, $2) RETURNING id`
 	updateQuery = `UPDATE uploads SET files = $1, dataset_id = $2, model_id = $3 WHERE id = $4`
-	getQuery    = `SELECT id, files FROM uploads WHERE id = $1`
+	getQuery    = `SELECT id, files, dataset_id, model_id, quarantined FROM uploads WHERE id = $1`
 )
 
 type Querier interface {
@@ -66,6 +80,7 @@ func (s *Store) Create(u *Upload) (*Upload, error) {
 		context.Background(),
 		query,
 		filesJSON,
+		u.Quarantined,
 	)
 
 	var id string
@@ -74,8 +89,9 @@ func (s *Store) Create(u *Upload) (*Upload, error) {
 	}
 
 	return &Upload{
-		ID:    id,
-		Files: u.Files,
+		ID:          id,
+		Files:       u.Files,
+		Quarantined: u.Quarantined,
 	}, nil
 }
 
@@ -88,7 +104,7 @@ func (s *Store) Get(id string) (*Upload, error) {
 	)
 
 	var upload Upload
-	if err := row.Scan(&upload.ID, &upload.Files); err != nil {
+	if err := row.Scan(&upload.ID, &upload.Files, &upload.DatasetID, &upload.ModelID, &upload.Quarantined); err != nil {
 		return nil, err
 	}
 
@@ -124,7 +140,7 @@ func (s *Store) GetByIDWithQuerier(q Querier, id string) (*Upload, error) {
 	var upload Upload
 	var filesJSON []byte
 
-	if err := row.Scan(&upload.ID, &filesJSON); err != nil {
+	if err := row.Scan(&upload.ID, &filesJSON, &upload.DatasetID, &upload.ModelID, &upload.Quarantined); err != nil {
 		return nil, fmt.Errorf("scan upload: %w", err)
 	}
 
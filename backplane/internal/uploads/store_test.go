@@ -36,7 +36,7 @@ func TestCreate(t *testing.T) {
 	db.ExpectQuery(
 		regexp.QuoteMeta(createQuery),
 	).
-		WithArgs(filesJSON).
+		WithArgs(filesJSON, false).
 		WillReturnRows(pgxmock.NewRows([]string{"id"}).AddRow("1"))
 
 	service := NewStore(db)
@@ -76,7 +76,7 @@ func TestCreate_ScanFails(t *testing.T) {
 	}
 
 	db.ExpectQuery(regexp.QuoteMeta(createQuery)).
-		WithArgs(filesJSON).
+		WithArgs(filesJSON, false).
 		WillReturnError(errors.New("scan failed"))
 
 	repo := NewStore(db)
@@ -142,8 +142,8 @@ func TestGetByIDWithQuerier(t *testing.T) {
 
 	db.ExpectQuery(regexp.QuoteMeta(getQuery)).
 		WithArgs("123").
-		WillReturnRows(pgxmock.NewRows([]string{"id", "files"}).
-			AddRow(want.ID, filesJSON),
+		WillReturnRows(pgxmock.NewRows([]string{"id", "files", "dataset_id", "model_id", "quarantined"}).
+			AddRow(want.ID, filesJSON, nil, nil, false),
 		)
 
 	repo := NewStore(nil)
@@ -190,8 +190,8 @@ func TestGetByIDWithQuerier_UnmarshalError(t *testing.T) {
 
 	db.ExpectQuery(regexp.QuoteMeta(getQuery)).
 		WithArgs("123").
-		WillReturnRows(pgxmock.NewRows([]string{"id", "files"}).
-			AddRow("123", invalidJSON),
+		WillReturnRows(pgxmock.NewRows([]string{"id", "files", "dataset_id", "model_id", "quarantined"}).
+			AddRow("123", invalidJSON, nil, nil, false),
 		)
 
 	repo := NewStore(nil)
@@ -213,8 +213,8 @@ func TestRepository_Get_Success(t *testing.T) {
 		"artefact": {Provider: "filesystem", FileName: "file1.txt", Path: "uploads/abc-123"},
 	}
 
-	rows := pgxmock.NewRows([]string{"id", "files"}).
-		AddRow(expectedID, expectedFiles)
+	rows := pgxmock.NewRows([]string{"id", "files", "dataset_id", "model_id", "quarantined"}).
+		AddRow(expectedID, expectedFiles, nil, nil, false)
 
 	db.ExpectQuery(regexp.QuoteMeta(getQuery)).
 		WithArgs(expectedID).
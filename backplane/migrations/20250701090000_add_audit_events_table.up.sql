@@ -0,0 +1,33 @@
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    occurred_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    action TEXT NOT NULL,
+    resource_type TEXT NOT NULL,
+    resource_id TEXT,
+    actor TEXT,
+    tenant TEXT,
+    ip TEXT,
+    user_agent TEXT,
+    request_id TEXT,
+    details JSONB
+);
+
+CREATE INDEX audit_events_occurred_at_idx ON audit_events (occurred_at);
+CREATE INDEX audit_events_resource_idx ON audit_events (resource_type, resource_id);
+CREATE INDEX audit_events_actor_idx ON audit_events (actor);
+
+-- The audit log is append-only: nothing may change or remove an event
+-- once it has been written.
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_no_update_or_delete
+BEFORE UPDATE OR DELETE ON audit_events
+FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+BEFORE TRUNCATE ON audit_events
+FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
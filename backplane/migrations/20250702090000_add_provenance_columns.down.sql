@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS datasets_created_by_idx;
+DROP INDEX IF EXISTS datasets_created_at_idx;
+DROP INDEX IF EXISTS models_created_by_idx;
+DROP INDEX IF EXISTS models_created_at_idx;
+
+ALTER TABLE datasets
+DROP COLUMN created_at,
+DROP COLUMN created_by,
+DROP COLUMN tenant;
+
+ALTER TABLE models
+DROP COLUMN created_at,
+DROP COLUMN created_by,
+DROP COLUMN tenant;
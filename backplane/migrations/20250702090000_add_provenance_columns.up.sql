@@ -0,0 +1,14 @@
+ALTER TABLE datasets
+ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+ADD COLUMN created_by TEXT,
+ADD COLUMN tenant TEXT;
+
+ALTER TABLE models
+ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+ADD COLUMN created_by TEXT,
+ADD COLUMN tenant TEXT;
+
+CREATE INDEX datasets_created_by_idx ON datasets (created_by);
+CREATE INDEX datasets_created_at_idx ON datasets (created_at);
+CREATE INDEX models_created_by_idx ON models (created_by);
+CREATE INDEX models_created_at_idx ON models (created_at);
@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS uploads_sealed_immutable ON uploads;
+DROP TRIGGER IF EXISTS models_sealed_immutable ON models;
+DROP TRIGGER IF EXISTS datasets_sealed_immutable ON datasets;
+
+DROP FUNCTION IF EXISTS uploads_sealed_immutable();
+DROP FUNCTION IF EXISTS models_sealed_immutable();
+DROP FUNCTION IF EXISTS datasets_sealed_immutable();
+
+ALTER TABLE models DROP COLUMN seal;
+ALTER TABLE datasets DROP COLUMN seal;
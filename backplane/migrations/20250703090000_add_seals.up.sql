@@ -0,0 +1,71 @@
+ALTER TABLE datasets ADD COLUMN seal TEXT;
+ALTER TABLE models ADD COLUMN seal TEXT;
+
+-- Once a version is sealed, nothing that went into its seal may change
+-- and it may not be removed. Columns added later which aren't part of
+-- the seal remain writable.
+CREATE FUNCTION datasets_sealed_immutable() RETURNS trigger AS $$
+BEGIN
+    IF OLD.seal IS NULL THEN
+        IF TG_OP = 'DELETE' THEN
+            RETURN OLD;
+        END IF;
+        RETURN NEW;
+    END IF;
+    IF TG_OP = 'DELETE' THEN
+        RAISE EXCEPTION 'dataset % is sealed and cannot be deleted', OLD.id;
+    END IF;
+    IF ROW(NEW.id, NEW.name, NEW.version, NEW.parent, NEW.description, NEW.created_at, NEW.created_by, NEW.tenant, NEW.seal)
+        IS DISTINCT FROM
+       ROW(OLD.id, OLD.name, OLD.version, OLD.parent, OLD.description, OLD.created_at, OLD.created_by, OLD.tenant, OLD.seal) THEN
+        RAISE EXCEPTION 'dataset % is sealed and cannot be modified', OLD.id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER datasets_sealed_immutable
+BEFORE UPDATE OR DELETE ON datasets
+FOR EACH ROW EXECUTE FUNCTION datasets_sealed_immutable();
+
+CREATE FUNCTION models_sealed_immutable() RETURNS trigger AS $$
+BEGIN
+    IF OLD.seal IS NULL THEN
+        IF TG_OP = 'DELETE' THEN
+            RETURN OLD;
+        END IF;
+        RETURN NEW;
+    END IF;
+    IF TG_OP = 'DELETE' THEN
+        RAISE EXCEPTION 'model % is sealed and cannot be deleted', OLD.id;
+    END IF;
+    IF ROW(NEW.id, NEW.name, NEW.version, NEW.parent, NEW.description, NEW.dataset, NEW.config, NEW.metadata, NEW.environment, NEW.evaluation, NEW.created_at, NEW.created_by, NEW.tenant, NEW.seal)
+        IS DISTINCT FROM
+       ROW(OLD.id, OLD.name, OLD.version, OLD.parent, OLD.description, OLD.dataset, OLD.config, OLD.metadata, OLD.environment, OLD.evaluation, OLD.created_at, OLD.created_by, OLD.tenant, OLD.seal) THEN
+        RAISE EXCEPTION 'model % is sealed and cannot be modified', OLD.id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER models_sealed_immutable
+BEFORE UPDATE OR DELETE ON models
+FOR EACH ROW EXECUTE FUNCTION models_sealed_immutable();
+
+-- Uploads attached to a sealed version are part of it.
+CREATE FUNCTION uploads_sealed_immutable() RETURNS trigger AS $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM datasets WHERE id = OLD.dataset_id AND seal IS NOT NULL)
+        OR EXISTS (SELECT 1 FROM models WHERE id::text = OLD.model_id AND seal IS NOT NULL) THEN
+        RAISE EXCEPTION 'upload % belongs to a sealed version and cannot be changed', OLD.id;
+    END IF;
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER uploads_sealed_immutable
+BEFORE UPDATE OR DELETE ON uploads
+FOR EACH ROW EXECUTE FUNCTION uploads_sealed_immutable();
@@ -0,0 +1,8 @@
+DROP TRIGGER IF EXISTS model_signatures_no_update_or_delete ON model_signatures;
+DROP FUNCTION IF EXISTS model_signatures_append_only();
+
+DROP TABLE IF EXISTS model_signatures;
+DROP TABLE IF EXISTS signing_keys;
+
+DROP INDEX IF EXISTS models_stage_idx;
+ALTER TABLE models DROP COLUMN stage;
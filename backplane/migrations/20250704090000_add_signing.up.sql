@@ -0,0 +1,40 @@
+-- Stage isn't part of a model's seal, so it stays writable once sealed.
+ALTER TABLE models ADD COLUMN stage TEXT NOT NULL DEFAULT 'development';
+
+CREATE INDEX models_stage_idx ON models (stage);
+
+-- Public keys trusted to sign models, per tenant. The id is the key's
+-- fingerprint. Keys are revoked rather than deleted so that old
+-- signatures can still be explained.
+CREATE TABLE signing_keys (
+    id TEXT NOT NULL,
+    tenant TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL,
+    public_key TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_by TEXT,
+    revoked_at TIMESTAMPTZ,
+    PRIMARY KEY (tenant, id)
+);
+
+CREATE TABLE model_signatures (
+    id BIGSERIAL PRIMARY KEY,
+    model_id UUID NOT NULL REFERENCES models (id),
+    key_id TEXT NOT NULL,
+    algorithm TEXT NOT NULL,
+    signature TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_by TEXT,
+    UNIQUE (model_id, key_id)
+);
+
+-- A signature is evidence: once recorded it may not be changed or removed.
+CREATE FUNCTION model_signatures_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'model_signatures is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER model_signatures_no_update_or_delete
+BEFORE UPDATE OR DELETE ON model_signatures
+FOR EACH ROW EXECUTE FUNCTION model_signatures_append_only();
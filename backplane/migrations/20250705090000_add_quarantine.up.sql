@@ -0,0 +1 @@
+ALTER TABLE uploads ADD COLUMN quarantined BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS models_dataset_idx;
+DROP INDEX IF EXISTS models_deleted_at_idx;
+DROP INDEX IF EXISTS datasets_deleted_at_idx;
+
+ALTER TABLE models
+DROP COLUMN IF EXISTS purged_at,
+DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE datasets
+DROP COLUMN IF EXISTS purged_at,
+DROP COLUMN IF EXISTS deleted_at;
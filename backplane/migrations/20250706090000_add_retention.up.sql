@@ -0,0 +1,14 @@
+-- Neither column is part of a seal, so sealed versions can still be
+-- soft-deleted and purged. A purged version's row and uploads are kept
+-- as a record of what was there; only its artefacts are removed.
+ALTER TABLE datasets
+ADD COLUMN deleted_at TIMESTAMPTZ,
+ADD COLUMN purged_at TIMESTAMPTZ;
+
+ALTER TABLE models
+ADD COLUMN deleted_at TIMESTAMPTZ,
+ADD COLUMN purged_at TIMESTAMPTZ;
+
+CREATE INDEX datasets_deleted_at_idx ON datasets (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX models_deleted_at_idx ON models (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX models_dataset_idx ON models (dataset);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS models_parent_idx;
+
+ALTER TABLE models DROP COLUMN IF EXISTS tainted_by;
+ALTER TABLE datasets DROP COLUMN IF EXISTS tainted_by;
+
+DROP TRIGGER IF EXISTS erasure_requests_no_update_or_delete ON erasure_requests;
+DROP FUNCTION IF EXISTS erasure_requests_append_only();
+DROP TABLE IF EXISTS erasure_requests;
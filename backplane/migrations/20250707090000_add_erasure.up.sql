@@ -0,0 +1,34 @@
+-- An erasure request is kept as the tombstone of what was erased: which
+-- dataset version held the data subject's data, every version found to
+-- be derived from it, and whether their artefacts were purged. Like
+-- signatures, it is evidence, so it may not be changed or removed. The
+-- reason is free text and shouldn't identify the data subject.
+CREATE TABLE erasure_requests (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    dataset_id UUID NOT NULL REFERENCES datasets (id),
+    reason TEXT NOT NULL,
+    purge BOOLEAN NOT NULL DEFAULT false,
+    impact JSONB NOT NULL,
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    requested_by TEXT,
+    tenant TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX erasure_requests_tenant_idx ON erasure_requests (tenant, requested_at);
+
+CREATE FUNCTION erasure_requests_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'erasure_requests is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER erasure_requests_no_update_or_delete
+BEFORE UPDATE OR DELETE ON erasure_requests
+FOR EACH ROW EXECUTE FUNCTION erasure_requests_append_only();
+
+-- The first erasure request a version was affected by. Neither column is
+-- part of a seal, so sealed versions can still be tainted.
+ALTER TABLE datasets ADD COLUMN tainted_by UUID REFERENCES erasure_requests (id);
+ALTER TABLE models ADD COLUMN tainted_by UUID REFERENCES erasure_requests (id);
+
+CREATE INDEX models_parent_idx ON models (parent);
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS models_legal_hold ON models;
+DROP TRIGGER IF EXISTS datasets_legal_hold ON datasets;
+DROP FUNCTION IF EXISTS legal_hold_enforce();
+
+DROP TRIGGER IF EXISTS legal_holds_no_delete ON legal_holds;
+DROP FUNCTION IF EXISTS legal_holds_no_delete();
+DROP TABLE IF EXISTS legal_holds;
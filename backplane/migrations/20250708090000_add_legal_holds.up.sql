@@ -0,0 +1,53 @@
+-- A legal hold freezes a dataset or model version: while it is active,
+-- the version can't be deleted or purged by anyone, including the
+-- retention job. Holds are released rather than removed, so the table is
+-- a record of every hold there has been.
+CREATE TABLE legal_holds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    resource_type TEXT NOT NULL CHECK (resource_type IN ('dataset', 'model')),
+    resource_id UUID NOT NULL,
+    reason TEXT NOT NULL,
+    holder TEXT NOT NULL,
+    placed_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    placed_by TEXT,
+    released_at TIMESTAMPTZ,
+    released_by TEXT,
+    tenant TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX legal_holds_active_idx ON legal_holds (resource_type, resource_id) WHERE released_at IS NULL;
+CREATE INDEX legal_holds_tenant_idx ON legal_holds (tenant, placed_at);
+
+CREATE FUNCTION legal_holds_no_delete() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'legal holds can only be released';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER legal_holds_no_delete
+BEFORE DELETE ON legal_holds
+FOR EACH ROW EXECUTE FUNCTION legal_holds_no_delete();
+
+-- Backs up the checks made in code: a held version can't be moved to the
+-- trash or purged, whichever path the update comes from.
+CREATE FUNCTION legal_hold_enforce() RETURNS trigger AS $$
+BEGIN
+    IF ((NEW.deleted_at IS NOT NULL AND OLD.deleted_at IS NULL)
+        OR (NEW.purged_at IS NOT NULL AND OLD.purged_at IS NULL))
+        AND EXISTS (
+            SELECT 1 FROM legal_holds
+            WHERE resource_type = TG_ARGV[0] AND resource_id = NEW.id AND released_at IS NULL
+        ) THEN
+        RAISE EXCEPTION '% % is under legal hold', TG_ARGV[0], NEW.id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER datasets_legal_hold
+BEFORE UPDATE OF deleted_at, purged_at ON datasets
+FOR EACH ROW EXECUTE FUNCTION legal_hold_enforce('dataset');
+
+CREATE TRIGGER models_legal_hold
+BEFORE UPDATE OF deleted_at, purged_at ON models
+FOR EACH ROW EXECUTE FUNCTION legal_hold_enforce('model');
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS uploads_run_id_idx;
+ALTER TABLE uploads DROP COLUMN IF EXISTS run_id;
+
+DROP TABLE IF EXISTS runs;
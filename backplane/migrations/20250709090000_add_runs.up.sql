@@ -0,0 +1,25 @@
+-- A run is a single training attempt, recorded from when it starts rather
+-- than only once it produces a model, so runs which fail or never finish
+-- are visible too. A finished run may be finalized into a model version.
+CREATE TABLE runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'running'
+        CHECK (status IN ('running', 'finished', 'failed')),
+    params JSONB NOT NULL DEFAULT '{}'::jsonb,
+    tags JSONB NOT NULL DEFAULT '{}'::jsonb,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ended_at TIMESTAMPTZ,
+    heartbeat_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    model_id UUID REFERENCES models (id),
+    created_by TEXT,
+    tenant TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX runs_tenant_status_idx ON runs (tenant, status, started_at);
+
+-- Artefacts are attached to a run as it goes, and become the model's when
+-- the run is finalized.
+ALTER TABLE uploads ADD COLUMN run_id UUID REFERENCES runs (id);
+
+CREATE INDEX uploads_run_id_idx ON uploads (run_id);
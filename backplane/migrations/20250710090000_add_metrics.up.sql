@@ -0,0 +1,16 @@
+-- Step-wise metrics logged while a model trains, such as its loss after
+-- every batch. There can be millions of points per run, so rows are kept
+-- narrow, without a surrogate key, and are only ever appended in bulk.
+-- resource_id can be a model or a run, so it can't be a foreign key.
+CREATE TABLE metrics (
+    resource_type TEXT NOT NULL CHECK (resource_type IN ('model', 'run')),
+    resource_id UUID NOT NULL,
+    key TEXT NOT NULL,
+    step BIGINT NOT NULL,
+    logged_at TIMESTAMPTZ NOT NULL,
+    value DOUBLE PRECISION NOT NULL,
+    tenant TEXT NOT NULL DEFAULT ''
+);
+
+-- Every read is of one or more whole series, in step order.
+CREATE INDEX metrics_series_idx ON metrics (resource_type, resource_id, key, step);
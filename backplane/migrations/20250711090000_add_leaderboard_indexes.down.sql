@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS runs_model_id_idx;
+DROP INDEX IF EXISTS models_evaluation_idx;
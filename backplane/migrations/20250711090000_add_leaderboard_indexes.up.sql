@@ -0,0 +1,9 @@
+-- Leaderboards rank the models trained on a dataset by a key in their
+-- evaluation. models_dataset_idx narrows the models down to the dataset,
+-- and this lets models which never recorded the key be skipped without
+-- reading their evaluations.
+CREATE INDEX models_evaluation_idx ON models USING GIN (evaluation);
+
+-- Leaderboards can be filtered by the tags of the run each model was
+-- finalized from.
+CREATE INDEX runs_model_id_idx ON runs (model_id) WHERE model_id IS NOT NULL;
@@ -0,0 +1,19 @@
+-- A model's evaluation column only holds how it did on the dataset it was
+-- trained on, when it was created. Evaluations record how it did on any
+-- dataset, such as a holdout set or a slice of production traffic, as
+-- often as it's evaluated. evaluator holds the code which produced the
+-- metrics, so that results from different evaluators aren't mistaken
+-- for one another.
+CREATE TABLE evaluations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    model_id UUID NOT NULL REFERENCES models (id),
+    dataset_id UUID NOT NULL REFERENCES datasets (id),
+    metrics JSONB NOT NULL,
+    evaluator JSONB NOT NULL DEFAULT '{}'::jsonb,
+    evaluated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_by TEXT,
+    tenant TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX evaluations_model_idx ON evaluations (model_id, evaluated_at);
+CREATE INDEX evaluations_dataset_idx ON evaluations (dataset_id, evaluated_at);
@@ -1,4 +1,5 @@
 from .datasets import Dataset, list_datasets
 from .models import Model, list_models
+from .runs import Run, list_runs
 
-__all__ = ["Dataset", "Model", "list_datasets", "list_models"]
\ No newline at end of file
+__all__ = ["Dataset", "Model", "Run", "list_datasets", "list_models", "list_runs"]
\ No newline at end of file
@@ -34,3 +34,6 @@ class TraintrackClient:
 
     def post(self, path, **kwargs):
         return self.session.post(f"{self.base_url}{path}", **kwargs)
+
+    def patch(self, path, **kwargs):
+        return self.session.patch(f"{self.base_url}{path}", **kwargs)
@@ -7,7 +7,7 @@ import os
 import io
 
 class Dataset:
-    def __init__(self, id, name, version, description, parent=None, artefacts=None):
+    def __init__(self, id, name, version, description, parent=None, artefacts=None, created_at=None, created_by=None, tenant=None, seal=None, deleted_at=None, purged_at=None, tainted_by=None, held=False, profiles=None):
         self.id = id
         self.name = name
         self.version = version
@@ -15,6 +15,18 @@ class Dataset:
         self.parent = parent
         self.artefacts = artefacts or {}
 
+        # Set by the server on creation, never sent.
+        self.created_at = created_at
+        self.created_by = created_by
+        self.tenant = tenant
+        self.seal = seal
+        self.deleted_at = deleted_at
+        self.purged_at = purged_at
+        self.tainted_by = tainted_by
+        self.held = held
+        # Schema and column statistics of CSV and Parquet artefacts, by name.
+        self.profiles = profiles or {}
+
     def __repr__(self):
         return f"<Dataset {self.name}:{self.version}>"
 
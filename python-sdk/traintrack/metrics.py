@@ -0,0 +1,50 @@
+import datetime
+import threading
+
+
+class MetricLogger:
+    """
+    Buffers step-wise metrics logged against a model or run and sends
+    them in batches, so logging after every training step stays cheap.
+    """
+
+    def __init__(self, client, path, batch_size=1000):
+        self.client = client
+        self.path = path
+        self.batch_size = batch_size
+        self._points = []
+        self._lock = threading.Lock()
+
+    def log(self, key, value, step, timestamp=None):
+        timestamp = timestamp or datetime.datetime.now(datetime.timezone.utc)
+        with self._lock:
+            self._points.append({
+                "key": key,
+                "step": step,
+                "value": float(value),
+                "timestamp": timestamp.isoformat(),
+                })
+            full = len(self._points) >= self.batch_size
+        if full:
+            self.flush()
+
+    def flush(self):
+        with self._lock:
+            points, self._points = self._points, []
+        if points:
+            resp = self.client.post(self.path, json=points)
+            resp.raise_for_status()
+
+
+def get_metrics(client, path, keys=None, points=500):
+    """
+    Return each metric's series, downsampled to at most points points,
+    as a dict of key to a list of {"step", "timestamp", "value"} dicts.
+    Downsampled points also have the "min" and "max" of what they cover.
+    """
+    params = {"points": points}
+    if keys:
+        params["key"] = ",".join(keys)
+    resp = client.get(path, params=params)
+    resp.raise_for_status()
+    return {s["key"]: s["points"] for s in resp.json()}
@@ -8,9 +8,10 @@ import subprocess
 import sys
 import tempfile
 from .client import TraintrackClient
+from .metrics import get_metrics
 
 class Model:
-    def __init__(self, id, name, version, description, parent=None, dataset=None, config=None, artefacts=None, metadata=None, environment=None, evaluation=None):
+    def __init__(self, id, name, version, description, parent=None, dataset=None, config=None, artefacts=None, metadata=None, environment=None, evaluation=None, created_at=None, created_by=None, tenant=None, seal=None, stage=None, scans=None, deleted_at=None, purged_at=None, tainted_by=None, held=False):
         self.id = id
         self.name = name
         self.version = version
@@ -25,6 +26,18 @@ class Model:
         self.evaluation = evaluation or None
         self.artefacts = artefacts or {}
 
+        # Set by the server on creation, never sent.
+        self.created_at = created_at
+        self.created_by = created_by
+        self.tenant = tenant
+        self.seal = seal
+        self.stage = stage
+        self.scans = scans or {}
+        self.deleted_at = deleted_at
+        self.purged_at = purged_at
+        self.tainted_by = tainted_by
+        self.held = held
+
         self._trained_model = None
 
     @property
@@ -98,6 +111,52 @@ class Model:
         resp.raise_for_status()
         return Model(**resp.json())
 
+    def log_metrics(self, metrics, step):
+        """
+        Log a dict of metric values at step against the saved model, such
+        as the loss after each epoch when training outside of a run.
+        """
+        if self.id is None:
+            raise Exception("the model must be saved before metrics can be logged against it")
+        client = TraintrackClient()
+        points = [{"key": key, "step": step, "value": float(value)} for key, value in metrics.items()]
+        resp = client.post(f"/models/{self.id}/metrics", json=points)
+        resp.raise_for_status()
+
+    def metrics(self, keys=None, points=500):
+        """Return the model's metrics, downsampled for charting."""
+        return get_metrics(TraintrackClient(), f"/models/{self.id}/metrics", keys, points)
+
+    def evaluate_on(self, dataset, eval_fn):
+        """
+        Evaluate the saved model on another dataset, such as a holdout set,
+        and record the result against both.
+        E.g., eval_fn(trained_model, dataset) -> dict
+        """
+        if self.id is None:
+            raise Exception("the model must be saved before it can be evaluated on other datasets")
+        metrics = eval_fn(self.trained_model, dataset)
+        evaluator = {"name": getattr(eval_fn, "__name__", ""), "source": get_fn_source(eval_fn)}
+        return self.add_evaluation(dataset, metrics, evaluator)
+
+    def add_evaluation(self, dataset, metrics, evaluator=None):
+        """Record metrics the saved model scored on dataset, evaluated elsewhere."""
+        client = TraintrackClient()
+        resp = client.post(f"/models/{self.id}/evaluations", json={
+            "dataset": getattr(dataset, "id", dataset),
+            "metrics": metrics,
+            "evaluator": evaluator or {},
+        })
+        resp.raise_for_status()
+        return resp.json()
+
+    def evaluations(self):
+        """Return every evaluation of the model, oldest first."""
+        client = TraintrackClient()
+        resp = client.get(f"/models/{self.id}/evaluations")
+        resp.raise_for_status()
+        return resp.json()
+
     @contextlib.contextmanager
     def _marshal_model(self, obj):
         try:
@@ -0,0 +1,175 @@
+import os
+import threading
+from .client import TraintrackClient
+from .metrics import MetricLogger, get_metrics
+from .models import Model
+
+
+class Run:
+    """
+    A training run, recorded from when it starts so that runs which fail
+    or never finish are visible too. Use it as a context manager to send
+    heartbeats while training and end the run when the block exits:
+
+        with Run.start("prices-xgb", params={"depth": 6}) as run:
+            ...
+            for step, loss in enumerate(train()):
+                run.log_metric("loss", loss, step)
+            run.log_artefact("model", "model.joblib")
+        model = run.finalize(name="prices", version="1.0.0", description="xgb", dataset=dataset)
+    """
+
+    def __init__(self, id, name, status, params=None, tags=None, artefacts=None, started_at=None, ended_at=None, heartbeat_at=None, model=None, created_by=None, tenant=None, client=None):
+        self.id = id
+        self.name = name
+        self.status = status
+        self.params = params or {}
+        self.tags = tags or {}
+        self.artefacts = artefacts or {}
+
+        # Set by the server, never sent.
+        self.started_at = started_at
+        self.ended_at = ended_at
+        self.heartbeat_at = heartbeat_at
+        self.model = model
+        self.created_by = created_by
+        self.tenant = tenant
+
+        self._client = client
+        self._stop = None
+        self._metrics = None
+
+    @classmethod
+    def start(cls, name, params=None, tags=None, client=None):
+        client = client or TraintrackClient()
+        resp = client.post("/runs", json={"name": name, "params": params or {}, "tags": tags or {}})
+        resp.raise_for_status()
+        return cls(**resp.json(), client=client)
+
+    @property
+    def client(self):
+        if self._client is None:
+            self._client = TraintrackClient()
+        return self._client
+
+    def heartbeat(self):
+        """Tell the server the run is still going."""
+        self._update(self.client.post(f"/runs/{self.id}/heartbeat"))
+
+    def log_artefact(self, name, file_path):
+        """Upload the file at file_path and attach it to the run as name."""
+        with open(file_path, "rb") as f:
+            upload_resp = self.client.post("/uploads", files={name: (os.path.basename(file_path), f)})
+            upload_resp.raise_for_status()
+        upload_id = upload_resp.json()["id"]
+
+        self._update(self.client.post(f"/runs/{self.id}/artefacts", json={"upload_id": upload_id}))
+
+    def log_metric(self, key, value, step):
+        """Log the value of the metric key at step. Metrics are sent in batches."""
+        if self._metrics is None:
+            self._metrics = MetricLogger(self.client, f"/runs/{self.id}/metrics")
+        self._metrics.log(key, value, step)
+
+    def log_metrics(self, metrics, step):
+        """Log a dict of metric values at step."""
+        for key, value in metrics.items():
+            self.log_metric(key, value, step)
+
+    def flush_metrics(self):
+        if self._metrics is not None:
+            self._metrics.flush()
+
+    def metrics(self, keys=None, points=500):
+        """Return the run's metrics, downsampled for charting."""
+        self.flush_metrics()
+        return get_metrics(self.client, f"/runs/{self.id}/metrics", keys, points)
+
+    def finish(self):
+        self._end("finished")
+
+    def fail(self):
+        self._end("failed")
+
+    def finalize(self, name, version, description, dataset=None, parent=None, config=None, metadata=None, environment=None, evaluation=None):
+        """
+        Create a model version from the run, with the run's artefacts and,
+        unless config is given, its params as the model's config.
+        """
+        data = {
+                "name": name,
+                "version": version,
+                "description": description,
+                "parent": parent,
+                "config": config,
+                "metadata": metadata,
+                "environment": environment,
+                "evaluation": evaluation,
+                "dataset": getattr(dataset, "id", dataset),
+                }
+        self.flush_metrics()
+        resp = self.client.post(f"/runs/{self.id}/finalize", json=data)
+        resp.raise_for_status()
+        model = Model(**resp.json())
+        self.status = "finished"
+        self.model = model.id
+        return model
+
+    def start_heartbeat(self, interval=60):
+        """Send a heartbeat every interval seconds until the run ends."""
+        if self._stop is not None:
+            return
+        self._stop = threading.Event()
+
+        def beat():
+            while not self._stop.wait(interval):
+                try:
+                    self.heartbeat()
+                except Exception:
+                    pass
+
+        threading.Thread(target=beat, daemon=True).start()
+
+    def __enter__(self):
+        self.start_heartbeat()
+        return self
+
+    def __exit__(self, exc_type, exc, tb):
+        self._stop_heartbeat()
+        if self.status == "running":
+            if exc_type is None:
+                self.finish()
+            else:
+                self.fail()
+        return False
+
+    def _end(self, status):
+        self._stop_heartbeat()
+        self.flush_metrics()
+        self._update(self.client.patch(f"/runs/{self.id}", json={"status": status}))
+
+    def _stop_heartbeat(self):
+        if self._stop is not None:
+            self._stop.set()
+            self._stop = None
+
+    def _update(self, resp):
+        resp.raise_for_status()
+        data = resp.json()
+        self.status = data["status"]
+        self.artefacts = data.get("artefacts") or {}
+        self.ended_at = data.get("ended_at")
+        self.heartbeat_at = data.get("heartbeat_at")
+        self.model = data.get("model")
+
+    def __repr__(self):
+        return f"<Run {self.name} {self.status}>"
+
+
+def list_runs(status=None, client=None):
+    """List runs, optionally only those with one of the given statuses."""
+    client = client or TraintrackClient()
+    params = {"status": ",".join(status)} if status else None
+    resp = client.get("/runs", params=params)
+    resp.raise_for_status()
+    return [Run(**r, client=client) for r in resp.json()]